@@ -0,0 +1,39 @@
+package main
+
+import (
+	"time"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// maxDBConnectBackoff caps the wait between database connection attempts.
+const maxDBConnectBackoff = 30 * time.Second
+
+// connectWithRetry calls connect until it succeeds or retries are exhausted,
+// waiting backoff before the first retry and doubling the wait up to
+// maxDBConnectBackoff. It lets the service start alongside its database
+// instead of exiting while the database is still booting.
+func connectWithRetry(connect func() (*gorm.DB, error), retries int, backoff time.Duration, sleep func(time.Duration), logger *zap.Logger) (*gorm.DB, error) {
+	for attempt := 1; ; attempt++ {
+		db, err := connect()
+		if err == nil {
+			if attempt > 1 {
+				logger.Info("connected to database", zap.Int("attempt", attempt))
+			}
+			return db, nil
+		}
+		if attempt > retries {
+			return nil, err
+		}
+
+		logger.Warn("failed to connect to database, retrying",
+			zap.Int("attempt", attempt),
+			zap.Int("max_attempts", retries+1),
+			zap.Duration("backoff", backoff),
+			zap.Error(err),
+		)
+		sleep(backoff)
+		backoff = min(backoff*2, maxDBConnectBackoff)
+	}
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+func TestConnectWithRetry(t *testing.T) {
+	refused := errors.New("connection refused")
+	ready := &gorm.DB{}
+
+	tests := []struct {
+		name      string
+		failures  int
+		retries   int
+		wantErr   bool
+		wantCalls int
+		wantSleep []time.Duration
+	}{
+		{"first attempt", 0, 3, false, 1, nil},
+		{"ready after retries", 2, 3, false, 3, []time.Duration{time.Second, 2 * time.Second}},
+		{"gives up", 10, 3, true, 4, []time.Duration{time.Second, 2 * time.Second, 4 * time.Second}},
+		{"no retries", 1, 0, true, 1, nil},
+		{"backoff is capped", 7, 7, false, 8, []time.Duration{
+			time.Second, 2 * time.Second, 4 * time.Second, 8 * time.Second, 16 * time.Second, 30 * time.Second, 30 * time.Second,
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			calls := 0
+			var slept []time.Duration
+			db, err := connectWithRetry(func() (*gorm.DB, error) {
+				calls++
+				if calls <= tt.failures {
+					return nil, refused
+				}
+				return ready, nil
+			}, tt.retries, time.Second, func(d time.Duration) { slept = append(slept, d) }, zap.NewNop())
+
+			if tt.wantErr {
+				assert.ErrorIs(t, err, refused)
+				assert.Nil(t, db)
+			} else {
+				require.NoError(t, err)
+				assert.Same(t, ready, db)
+			}
+			assert.Equal(t, tt.wantCalls, calls)
+			assert.Equal(t, tt.wantSleep, slept)
+		})
+	}
+}
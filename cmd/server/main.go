@@ -15,16 +15,23 @@ import (
 	"github.com/Kilat-Pet-Delivery/lib-common/kafka"
 	"github.com/Kilat-Pet-Delivery/lib-common/logger"
 	"github.com/Kilat-Pet-Delivery/lib-common/middleware"
+	"github.com/Kilat-Pet-Delivery/lib-proto/events"
 	"github.com/Kilat-Pet-Delivery/service-payment/internal/adapter"
 	"github.com/Kilat-Pet-Delivery/service-payment/internal/application"
 	"github.com/Kilat-Pet-Delivery/service-payment/internal/config"
+	"github.com/Kilat-Pet-Delivery/service-payment/internal/domain/payment"
 	paymentEvents "github.com/Kilat-Pet-Delivery/service-payment/internal/events"
+	"github.com/Kilat-Pet-Delivery/service-payment/internal/feature"
 	"github.com/Kilat-Pet-Delivery/service-payment/internal/handler"
 	"github.com/Kilat-Pet-Delivery/service-payment/internal/rail"
+	"github.com/Kilat-Pet-Delivery/service-payment/internal/ratelimit"
 	"github.com/Kilat-Pet-Delivery/service-payment/internal/repository"
 	"github.com/Kilat-Pet-Delivery/service-payment/internal/saga"
+	"github.com/Kilat-Pet-Delivery/service-payment/internal/tracing"
 	"github.com/gin-gonic/gin"
+	kafkago "github.com/segmentio/kafka-go"
 	"go.uber.org/zap"
+	"gorm.io/gorm"
 )
 
 func main() {
@@ -33,6 +40,9 @@ func main() {
 	if err != nil {
 		log.Fatalf("failed to load configuration: %v", err)
 	}
+	if err := cfg.Validate(); err != nil {
+		log.Fatalf("refusing to start: %v", err)
+	}
 
 	// Initialize logger
 	zapLogger, err := logger.NewNamed(cfg.AppEnv, "service-payment")
@@ -45,6 +55,12 @@ func main() {
 		zap.String("port", cfg.Port),
 	)
 
+	// Initialize tracing; a no-op unless TRACING_ENABLED is set
+	shutdownTracing, err := tracing.Init(context.Background(), cfg.Tracing, "service-payment")
+	if err != nil {
+		zapLogger.Fatal("failed to initialize tracing", zap.Error(err))
+	}
+
 	// Connect to database
 	dbConfig := database.PostgresConfig{
 		Host:     cfg.DBConfig.Host,
@@ -55,7 +71,9 @@ func main() {
 		SSLMode:  cfg.DBConfig.SSLMode,
 	}
 
-	db, err := database.Connect(dbConfig, zapLogger)
+	db, err := connectWithRetry(func() (*gorm.DB, error) {
+		return database.Connect(dbConfig, zapLogger)
+	}, cfg.DBConnectRetries, cfg.DBConnectBackoff, time.Sleep, zapLogger)
 	if err != nil {
 		zapLogger.Fatal("failed to connect to database", zap.Error(err))
 	}
@@ -64,10 +82,21 @@ func main() {
 	if cfg.AppEnv == "development" {
 		if err := db.AutoMigrate(
 			&repository.PaymentModel{},
+			&repository.PaymentDiscountModel{},
+			&repository.PaymentStatusHistoryModel{},
+			&repository.PaymentAmountAdjustmentModel{},
+			&repository.FeeScheduleModel{},
 			&repository.PromoModel{},
 			&repository.PromoUsageModel{},
 			&repository.SubscriptionModel{},
 			&repository.CashOutModel{},
+			&repository.UserCreditModel{},
+			&repository.RunnerAccountModel{},
+			&repository.FeatureFlagModel{},
+			&repository.CallbackDeliveryModel{},
+			&repository.CallbackAttemptModel{},
+			&repository.BookingTotalModel{},
+			&repository.RefundRequestModel{},
 		); err != nil {
 			zapLogger.Fatal("failed to auto-migrate", zap.Error(err))
 		}
@@ -82,8 +111,8 @@ func main() {
 	// Initialize JWT manager
 	jwtManager := auth.NewJWTManager(
 		cfg.JWTConfig.Secret,
-		15*time.Minute,
-		7*24*time.Hour,
+		cfg.JWTAccessTTL,
+		cfg.JWTRefreshTTL,
 	)
 
 	// Initialize Kafka producer
@@ -95,29 +124,135 @@ func main() {
 
 	// Initialize repositories
 	paymentRepo := repository.NewPaymentRepository(db)
+	promoRepo := repository.NewGormPromoRepository(db)
+	subRepo := repository.NewGormSubscriptionRepository(db)
+	feeScheduleRepo := repository.NewGormFeeScheduleRepository(db)
+	creditRepo := repository.NewGormCreditRepository(db)
+	runnerAccountRepo := repository.NewGormRunnerAccountRepository(db)
+	callbackRepo := repository.NewGormCallbackRepository(db)
+	refundRequestRepo := repository.NewGormRefundRequestRepository(db)
+	bookingTotalRepo := repository.NewGormBookingTotalRepository(db)
+
+	// Initialize fee schedule cache; falls back to PLATFORM_FEE_PERCENT when empty
+	feeScheduleCache := application.NewFeeScheduleCache(feeScheduleRepo, cfg.FeeScheduleRefreshInterval, zapLogger)
+	if err := feeScheduleCache.Refresh(context.Background()); err != nil {
+		zapLogger.Warn("failed to load fee schedules, using default platform fee", zap.Error(err))
+	}
+
+	// Initialize feature flags; rows in feature_flags override FEATURE_FLAGS at runtime
+	featureFlags := feature.New(cfg.FeatureFlags, repository.NewGormFeatureFlagRepository(db), cfg.FeatureFlagRefreshInterval, zapLogger)
+	if err := featureFlags.Refresh(context.Background()); err != nil {
+		zapLogger.Warn("failed to load feature flag overrides, using environment values", zap.Error(err))
+	}
+
+	// Initialize payment callbacks; they stay off until a signing secret is configured
+	callbackService := application.NewCallbackService(callbackRepo, zapLogger)
+	var callbacks saga.CallbackScheduler
+	if cfg.CallbackSigningSecret != "" {
+		callbacks = callbackService
+	} else {
+		zapLogger.Warn("CALLBACK_SIGNING_SECRET is not set, payment callbacks are disabled")
+	}
 
 	// Initialize saga service
-	sagaService := saga.NewPaymentSagaService(paymentRepo, stripeAdapter, kafkaProducer, cfg.PlatformFeePercent, zapLogger)
+	payoutFloors := payment.PayoutFloors{
+		MinRunnerPayoutCents: cfg.MinRunnerPayoutCents,
+		MinPlatformFeeCents:  cfg.MinPlatformFeeCents,
+	}
+	sagaService := saga.NewPaymentSagaService(paymentRepo, stripeAdapter, kafkaProducer, feeScheduleCache, creditRepo, runnerAccountRepo, callbacks, featureFlags, cfg.PlatformFeePercent, payoutFloors, cfg.EscrowAutoReleaseAfter, cfg.RefundWindow, zapLogger)
+
+	// Initialize discount engine
+	discountEngine := application.NewDiscountEngine(application.DiscountPolicy{
+		Stacking:                application.StackingPolicy(cfg.DiscountStackingPolicy),
+		MaxTotalDiscountPercent: cfg.MaxTotalDiscountPercent,
+		MaxPromoPercentOfTotal:  cfg.MaxDiscountPercentOfTotal,
+	})
 
 	// Initialize application service
-	paymentService := application.NewPaymentService(paymentRepo, sagaService, zapLogger)
+	currencies := application.NewCurrencyAllowlist(cfg.SupportedCurrencies)
+	paymentService := application.NewPaymentService(paymentRepo, promoRepo, subRepo, creditRepo, sagaService, discountEngine, currencies, kafkaProducer, zapLogger)
+	paymentService.SetBookingTotals(bookingTotalRepo, cfg.BookingAmountToleranceCents, featureFlags)
+	paymentService.SetTestPayments(cfg.StripeConfig.TestMode(), cfg.AppEnv != "production")
+	paymentService.SetCallbackDeliveries(callbackRepo)
+	paymentService.SetRefundApprovals(refundRequestRepo, cfg.RefundApprovalThresholdCents)
+
+	// Wait for the topics the service consumes and publishes to before
+	// starting consumers, so a cluster still being provisioned doesn't make
+	// the service flap.
+	requiredTopics := append(append([]string{}, cfg.BookingEventTopics...), events.TopicPaymentEvents)
+	topicChecker := paymentEvents.NewTopicChecker(cfg.KafkaConfig.Brokers, cfg.KafkaTopicCheckRetries, cfg.KafkaTopicCheckBackoff, cfg.KafkaAutoCreateTopics, zapLogger)
+	if err := topicChecker.Ensure(context.Background(), requiredTopics...); err != nil {
+		zapLogger.Fatal("required kafka topics unavailable", zap.Error(err))
+	}
 
 	// Initialize Kafka consumer for booking events
 	consumerGroupID := cfg.KafkaConfig.GroupPrefix + "payment-service"
+	bookingDLQ := paymentEvents.NewDeadLetterQueue(cfg.KafkaConfig.Brokers, cfg.BookingEventsDLQTopic)
+	defer bookingDLQ.Close()
 	bookingConsumer := paymentEvents.NewBookingEventConsumer(
 		cfg.KafkaConfig.Brokers,
 		consumerGroupID,
+		cfg.BookingEventTopics,
 		paymentService,
+		bookingDLQ,
+		cfg.KafkaConsumerConcurrency,
+		cfg.KafkaStartOffset,
 		zapLogger,
 	)
 	defer bookingConsumer.Close()
+	bookingConsumer.SetEventTypes(cfg.BookingEventTypes)
+	dlqReplayer := paymentEvents.NewDeadLetterReplayer(cfg.KafkaConfig.Brokers, consumerGroupID+"-dlq-replay", bookingConsumer, bookingDLQ, zapLogger)
+	if cfg.KafkaStartOffset != kafkago.FirstOffset && cfg.KafkaConsumerConcurrency <= 1 {
+		zapLogger.Warn("KAFKA_START_OFFSET only applies when KAFKA_CONSUMER_CONCURRENCY > 1; the serial consumer starts from the earliest offset")
+	}
+
+	// Initialize Kafka consumer for runner payout account links
+	runnerAccountConsumer := paymentEvents.NewRunnerAccountConsumer(
+		cfg.KafkaConfig.Brokers,
+		consumerGroupID+"-runner-accounts",
+		cfg.RunnerEventsTopic,
+		runnerAccountRepo,
+		zapLogger,
+	)
+	defer runnerAccountConsumer.Close()
+
+	// Initialize Kafka consumer for disputes raised by payments ops
+	disputeConsumer := paymentEvents.NewDisputeConsumer(
+		cfg.KafkaConfig.Brokers,
+		consumerGroupID+"-disputes",
+		cfg.PaymentsOpsTopic,
+		paymentService,
+		zapLogger,
+	)
+	defer disputeConsumer.Close()
 
 	// Start Kafka consumer in a goroutine
 	consumerCtx, consumerCancel := context.WithCancel(context.Background())
 	defer consumerCancel()
 
+	go feeScheduleCache.Start(consumerCtx)
+	go featureFlags.Start(consumerCtx)
+
+	// Start escrow auto-release worker
+	autoReleaseWorker := application.NewAutoReleaseWorker(paymentRepo, sagaService, featureFlags, cfg.EscrowAutoReleaseInterval, zapLogger)
+	go autoReleaseWorker.Start(consumerCtx)
+
+	// Start a consumer lag reporter for each booking events topic
+	for _, topic := range cfg.BookingEventTopics {
+		lagReporter := paymentEvents.NewLagReporter(cfg.KafkaConfig.Brokers, consumerGroupID, topic,
+			cfg.KafkaLagReportInterval, zapLogger)
+		go lagReporter.Start(consumerCtx)
+	}
+
+	// Start payment callback worker
+	if callbacks != nil {
+		callbackWorker := application.NewCallbackWorker(callbackRepo, cfg.CallbackSigningSecret, cfg.CallbackMaxAttempts,
+			cfg.CallbackRetryBackoff, cfg.CallbackTimeout, cfg.CallbackWorkerInterval, zapLogger)
+		go callbackWorker.Start(consumerCtx)
+	}
+
 	go func() {
-		zapLogger.Info("starting booking event consumer")
+		zapLogger.Info("starting booking event consumer", zap.Strings("topics", cfg.BookingEventTopics))
 		if err := bookingConsumer.Start(consumerCtx); err != nil {
 			if consumerCtx.Err() == nil {
 				zapLogger.Error("booking event consumer failed", zap.Error(err))
@@ -125,15 +260,63 @@ func main() {
 		}
 	}()
 
+	go func() {
+		zapLogger.Info("starting runner account consumer", zap.String("topic", cfg.RunnerEventsTopic))
+		if err := runnerAccountConsumer.Start(consumerCtx); err != nil {
+			if consumerCtx.Err() == nil {
+				zapLogger.Error("runner account consumer failed", zap.Error(err))
+			}
+		}
+	}()
+
+	go func() {
+		zapLogger.Info("starting dispute consumer", zap.String("topic", cfg.PaymentsOpsTopic))
+		if err := disputeConsumer.Start(consumerCtx); err != nil {
+			if consumerCtx.Err() == nil {
+				zapLogger.Error("dispute consumer failed", zap.Error(err))
+			}
+		}
+	}()
+
 	// Initialize promo service and handler
-	promoRepo := repository.NewGormPromoRepository(db)
-	promoService := application.NewPromoService(promoRepo, zapLogger)
-	promoHandler := handler.NewPromoHandler(promoService)
+	promoService := application.NewPromoService(promoRepo, paymentRepo, subRepo, cfg.MaxDiscountPercentOfTotal, zapLogger)
+	// In-memory limiter; swap for a shared store when running multiple replicas
+	promoValidateLimiter := ratelimit.NewMemoryStore(ratelimit.PerMinute(cfg.PromoValidatePerMinute, cfg.PromoValidateBurst))
+	promoHandler := handler.NewPromoHandler(promoService, promoValidateLimiter)
 
 	// Initialize subscription service and handler
-	subRepo := repository.NewGormSubscriptionRepository(db)
-	subService := application.NewSubscriptionService(subRepo, zapLogger)
-	subHandler := handler.NewSubscriptionHandler(subService)
+	subService := application.NewSubscriptionService(subRepo, nil, zapLogger)
+	if err := subService.SetCommitment(cfg.SubscriptionCommitment, cfg.SubscriptionCommitmentPlans); err != nil {
+		zapLogger.Fatal("invalid SUBSCRIPTION_COMMITMENT_PLANS", zap.Error(err))
+	}
+	subHandler := handler.NewSubscriptionHandler(subService, cfg.Pagination)
+
+	// Start subscription renewals, on a timer or on ticks from an external scheduler
+	renewalWorker := application.NewSubscriptionRenewalWorker(subService, cfg.SubscriptionRenewalLead, cfg.SubscriptionRenewalInterval, zapLogger)
+	if cfg.SubscriptionRenewalMode == config.RenewalModeEvent {
+		schedulerConsumer := paymentEvents.NewSchedulerConsumer(
+			cfg.KafkaConfig.Brokers,
+			consumerGroupID+"-scheduler",
+			cfg.SchedulerTopic,
+			renewalWorker,
+			zapLogger,
+		)
+		defer schedulerConsumer.Close()
+		go func() {
+			zapLogger.Info("starting scheduler consumer", zap.String("topic", cfg.SchedulerTopic))
+			if err := schedulerConsumer.Start(consumerCtx); err != nil {
+				if consumerCtx.Err() == nil {
+					zapLogger.Error("scheduler consumer failed", zap.Error(err))
+				}
+			}
+		}()
+	} else {
+		go renewalWorker.Start(consumerCtx)
+	}
+
+	// Initialize credit service and handler
+	creditService := application.NewCreditService(creditRepo, zapLogger)
+	creditHandler := handler.NewCreditHandler(creditService)
 
 	// Initialize cash-out rail and handler
 	simulatedRail := rail.NewSimulatedRail(cfg.CashOutRailDelay, zapLogger, rail.RealClock{})
@@ -143,8 +326,15 @@ func main() {
 	destinationOwnership := adapter.NewInMemoryDestinationOwnership(nil)
 	cashOutHandler := handler.NewCashOutHandler(cashOutRepo, destinationOwnership, simulatedRail, cfg.CashOutRailDelay, zapLogger)
 
+	// Initialize fee schedule service and handler
+	feeScheduleService := application.NewFeeScheduleService(feeScheduleRepo, feeScheduleCache, currencies, cfg.PlatformFeePercent, payoutFloors, zapLogger)
+	feeScheduleHandler := handler.NewFeeScheduleHandler(feeScheduleService)
+	dlqHandler := handler.NewDLQHandler(dlqReplayer)
+
 	// Initialize HTTP handler
-	paymentHandler := handler.NewPaymentHandler(paymentService)
+	// Up to RECEIPT_RESENDS_PER_HOUR resends per payment; in-memory like the promo limiter
+	receiptResendLimiter := ratelimit.NewMemoryStore(ratelimit.PerHour(cfg.ReceiptResendsPerHour, cfg.ReceiptResendsPerHour))
+	paymentHandler := handler.NewPaymentHandler(paymentService, receiptResendLimiter)
 
 	// Setup Gin router
 	gin.SetMode(gin.ReleaseMode)
@@ -152,6 +342,7 @@ func main() {
 
 	// Apply global middleware
 	router.Use(middleware.RecoveryMiddleware(zapLogger))
+	router.Use(tracing.GinMiddleware())
 	router.Use(middleware.LoggerMiddleware(zapLogger))
 	router.Use(middleware.CORSMiddleware())
 	router.Use(middleware.RequestIDMiddleware())
@@ -166,11 +357,31 @@ func main() {
 	paymentHandler.RegisterRoutes(apiV1, jwtManager)
 	promoHandler.RegisterRoutes(apiV1, jwtManager)
 	subHandler.RegisterRoutes(apiV1, jwtManager)
+	creditHandler.RegisterRoutes(apiV1, jwtManager)
 	cashOutHandler.RegisterRoutes(apiV1, jwtManager)
 
 	// Register admin handler routes
-	adminPaymentHandler := handler.NewAdminPaymentHandler(paymentService, promoService)
+	replayPublisher := paymentEvents.NewKafkaReplayPublisher(cfg.KafkaConfig.Brokers)
+	defer replayPublisher.Close()
+	replayService := application.NewReplayService(paymentRepo, replayPublisher, zapLogger)
+	adminPaymentHandler := handler.NewAdminPaymentHandler(paymentService, promoService, replayService, callbackService, cfg.Pagination)
 	adminPaymentHandler.RegisterRoutes(apiV1, jwtManager)
+	feeScheduleHandler.RegisterRoutes(apiV1, jwtManager)
+	dlqHandler.RegisterRoutes(apiV1, jwtManager)
+
+	// Register service-to-service routes, authenticated by a shared token
+	if cfg.InternalServiceToken == "" {
+		zapLogger.Warn("INTERNAL_SERVICE_TOKEN is not set; internal routes will reject all requests")
+	}
+	internalHandler := handler.NewInternalPaymentHandler(paymentService, cfg.InternalServiceToken)
+	internalHandler.RegisterRoutes(router.Group("/internal"))
+
+	// Register Stripe webhooks, authenticated by their signature
+	if cfg.StripeConfig.WebhookSecret == "" {
+		zapLogger.Warn("STRIPE_WEBHOOK_SECRET is not set; Stripe webhooks will be rejected")
+	}
+	webhookHandler := handler.NewStripeWebhookHandler(paymentService, cfg.StripeConfig.WebhookSecret)
+	webhookHandler.RegisterRoutes(apiV1)
 
 	// Create HTTP server
 	srv := &http.Server{
@@ -207,5 +418,16 @@ func main() {
 		zapLogger.Error("server forced to shutdown", zap.Error(err))
 	}
 
+	// Let sagas started by Kafka events or HTTP requests finish before exiting
+	if !sagaService.Drain(cfg.SagaDrainTimeout) {
+		zapLogger.Warn("in-flight sagas did not finish before the drain deadline",
+			zap.Duration("timeout", cfg.SagaDrainTimeout))
+	}
+
+	// Flush buffered spans
+	if err := shutdownTracing(shutdownCtx); err != nil {
+		zapLogger.Warn("failed to flush traces", zap.Error(err))
+	}
+
 	zapLogger.Info("service-payment stopped")
 }
@@ -7,7 +7,9 @@ import (
 	"testing"
 	"time"
 
+	"github.com/Kilat-Pet-Delivery/lib-common/kafka"
 	"github.com/Kilat-Pet-Delivery/lib-proto/events"
+	paymentEvents "github.com/Kilat-Pet-Delivery/service-payment/internal/events"
 	"github.com/Kilat-Pet-Delivery/service-payment/internal/repository"
 	"github.com/google/uuid"
 	"github.com/stretchr/testify/assert"
@@ -29,7 +31,7 @@ func TestDeliveryConfirmed_ReleasesEscrow(t *testing.T) {
 	bookingID := uuid.New()
 	ownerID := uuid.New()
 	runnerID := uuid.New()
-	seedPaymentInHeldState(t, infra.DB, bookingID, ownerID)
+	seedPaymentInHeldState(t, infra.DB, stack.Stripe, bookingID, ownerID)
 
 	// Start the consumer.
 	ctx, cancel := context.WithCancel(context.Background())
@@ -80,7 +82,7 @@ func TestBookingCancelled_RefundsEscrow(t *testing.T) {
 
 	bookingID := uuid.New()
 	ownerID := uuid.New()
-	seedPaymentInHeldState(t, infra.DB, bookingID, ownerID)
+	seedPaymentInHeldState(t, infra.DB, stack.Stripe, bookingID, ownerID)
 
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
@@ -184,3 +186,250 @@ func TestBookingCancelled_PendingPayment_NoRefund(t *testing.T) {
 	require.NoError(t, infra.DB.Where("booking_id = ?", bookingID).First(&model).Error)
 	assert.Equal(t, "pending", model.EscrowStatus, "payment should remain pending")
 }
+
+// TestDeliveryConfirmedAfterCancel_StaysRefunded verifies that a late
+// DeliveryConfirmed event for a booking whose escrow was already refunded is
+// skipped rather than attempting a release.
+func TestDeliveryConfirmedAfterCancel_StaysRefunded(t *testing.T) {
+	infra := setupContainers(t)
+	defer infra.Cleanup()
+
+	stack := setupPaymentStack(t, infra.DB, infra.KafkaBrokers)
+	defer stack.CleanupProducer()
+	defer func() { _ = stack.Consumer.Close() }()
+
+	bookingID := uuid.New()
+	ownerID := uuid.New()
+	seedPaymentInHeldState(t, infra.DB, stack.Stripe, bookingID, ownerID)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() { _ = stack.Consumer.Start(ctx) }()
+	time.Sleep(3 * time.Second)
+
+	cancelled := events.BookingCancelledEvent{
+		BookingID:     bookingID,
+		BookingNumber: "BK-INTTEST05",
+		CancelledBy:   ownerID,
+		Reason:        "cancel before delivery",
+		OccurredAt:    time.Now().UTC(),
+	}
+	publishTestEvent(t, infra.KafkaBrokers, events.TopicBookingEvents,
+		"service-booking", events.BookingCancelled, cancelled)
+	waitForDBStatus(t, infra.DB, bookingID, "refunded", 15*time.Second)
+
+	delivered := events.DeliveryConfirmedEvent{
+		BookingID:     bookingID,
+		BookingNumber: "BK-INTTEST05",
+		RunnerID:      uuid.New(),
+		OwnerID:       ownerID,
+		DeliveredAt:   time.Now().UTC(),
+		OccurredAt:    time.Now().UTC(),
+	}
+	publishTestEvent(t, infra.KafkaBrokers, events.TopicBookingEvents,
+		"service-booking", events.BookingDeliveryConfirmed, delivered)
+
+	// Wait and verify the payment was not touched by the late release.
+	time.Sleep(5 * time.Second)
+	var model repository.PaymentModel
+	require.NoError(t, infra.DB.Where("booking_id = ?", bookingID).First(&model).Error)
+	assert.Equal(t, "refunded", model.EscrowStatus, "payment should remain refunded")
+	assert.Nil(t, model.RunnerID, "runner_id should not be set")
+	assert.Nil(t, model.EscrowReleasedAt, "escrow_released_at should not be set")
+}
+
+// TestDeliveryConfirmed_Duplicate_ReleasesOnce verifies that a redelivered
+// DeliveryConfirmed event for an already-released payment is a no-op.
+func TestDeliveryConfirmed_Duplicate_ReleasesOnce(t *testing.T) {
+	infra := setupContainers(t)
+	defer infra.Cleanup()
+
+	stack := setupPaymentStack(t, infra.DB, infra.KafkaBrokers)
+	defer stack.CleanupProducer()
+	defer func() { _ = stack.Consumer.Close() }()
+
+	bookingID := uuid.New()
+	ownerID := uuid.New()
+	runnerID := uuid.New()
+	seedPaymentInHeldState(t, infra.DB, stack.Stripe, bookingID, ownerID)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() { _ = stack.Consumer.Start(ctx) }()
+	time.Sleep(3 * time.Second)
+
+	evt := events.DeliveryConfirmedEvent{
+		BookingID:     bookingID,
+		BookingNumber: "BK-INTTEST06",
+		RunnerID:      runnerID,
+		OwnerID:       ownerID,
+		DeliveredAt:   time.Now().UTC(),
+		OccurredAt:    time.Now().UTC(),
+	}
+	publishTestEvent(t, infra.KafkaBrokers, events.TopicBookingEvents,
+		"service-booking", events.BookingDeliveryConfirmed, evt)
+	waitForDBStatus(t, infra.DB, bookingID, "released", 15*time.Second)
+
+	publishTestEvent(t, infra.KafkaBrokers, events.TopicBookingEvents,
+		"service-booking", events.BookingDeliveryConfirmed, evt)
+
+	// Give the consumer time to process the duplicate.
+	time.Sleep(5 * time.Second)
+
+	released := countEvents(t, infra.KafkaBrokers, events.TopicPaymentEvents,
+		events.PaymentEscrowReleased, 10*time.Second)
+	assert.Equal(t, 1, released, "exactly one release event should be published")
+
+	failed := countEvents(t, infra.KafkaBrokers, events.TopicPaymentEvents,
+		events.PaymentFailed, 10*time.Second)
+	assert.Equal(t, 0, failed, "duplicate should not publish a failure event")
+}
+
+// TestBookingExpired_VoidsHeldAuthorization verifies that a BookingExpired
+// event voids a held payment with an expiry-specific reason.
+func TestBookingExpired_VoidsHeldAuthorization(t *testing.T) {
+	infra := setupContainers(t)
+	defer infra.Cleanup()
+
+	stack := setupPaymentStack(t, infra.DB, infra.KafkaBrokers)
+	defer stack.CleanupProducer()
+	defer func() { _ = stack.Consumer.Close() }()
+
+	bookingID := uuid.New()
+	ownerID := uuid.New()
+	seedPaymentInHeldState(t, infra.DB, stack.Stripe, bookingID, ownerID)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() { _ = stack.Consumer.Start(ctx) }()
+	time.Sleep(3 * time.Second)
+
+	evt := paymentEvents.BookingExpiredEvent{
+		BookingID:     bookingID,
+		BookingNumber: "BK-INTTEST07",
+		ExpiredAt:     time.Now().UTC(),
+		OccurredAt:    time.Now().UTC(),
+	}
+	publishTestEvent(t, infra.KafkaBrokers, events.TopicBookingEvents,
+		"service-booking", paymentEvents.BookingExpired, evt)
+
+	model := waitForDBStatus(t, infra.DB, bookingID, "refunded", 15*time.Second)
+	assert.Equal(t, "booking expired without delivery", model.RefundReason)
+
+	ce := consumeOneEvent(t, infra.KafkaBrokers, events.TopicPaymentEvents,
+		events.PaymentEscrowRefunded, 15*time.Second)
+	var refunded events.EscrowRefundedEvent
+	require.NoError(t, ce.ParseData(&refunded))
+	assert.Equal(t, bookingID, refunded.BookingID)
+	assert.Equal(t, "booking expired without delivery", refunded.RefundReason)
+}
+
+// TestBookingExpired_AfterCancel_NoOp verifies that an expiry arriving after
+// the payment was already refunded leaves it unchanged.
+func TestBookingExpired_AfterCancel_NoOp(t *testing.T) {
+	infra := setupContainers(t)
+	defer infra.Cleanup()
+
+	stack := setupPaymentStack(t, infra.DB, infra.KafkaBrokers)
+	defer stack.CleanupProducer()
+	defer func() { _ = stack.Consumer.Close() }()
+
+	bookingID := uuid.New()
+	ownerID := uuid.New()
+	seedPaymentInHeldState(t, infra.DB, stack.Stripe, bookingID, ownerID)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() { _ = stack.Consumer.Start(ctx) }()
+	time.Sleep(3 * time.Second)
+
+	cancelled := events.BookingCancelledEvent{
+		BookingID:     bookingID,
+		BookingNumber: "BK-INTTEST08",
+		CancelledBy:   ownerID,
+		Reason:        "owner cancelled",
+		OccurredAt:    time.Now().UTC(),
+	}
+	publishTestEvent(t, infra.KafkaBrokers, events.TopicBookingEvents,
+		"service-booking", events.BookingCancelled, cancelled)
+	waitForDBStatus(t, infra.DB, bookingID, "refunded", 15*time.Second)
+
+	expired := paymentEvents.BookingExpiredEvent{
+		BookingID:     bookingID,
+		BookingNumber: "BK-INTTEST08",
+		ExpiredAt:     time.Now().UTC(),
+		OccurredAt:    time.Now().UTC(),
+	}
+	publishTestEvent(t, infra.KafkaBrokers, events.TopicBookingEvents,
+		"service-booking", paymentEvents.BookingExpired, expired)
+
+	time.Sleep(5 * time.Second)
+	var model repository.PaymentModel
+	require.NoError(t, infra.DB.Where("booking_id = ?", bookingID).First(&model).Error)
+	assert.Equal(t, "refunded", model.EscrowStatus)
+	assert.Contains(t, model.RefundReason, "booking cancelled", "the cancellation reason should be kept")
+}
+
+// TestBookingUpdated_AdjustsHeldEscrow verifies that a BookingUpdated event
+// with a new total authorizes the new amount and splits it again, and that a
+// redelivery of the same event is applied once.
+func TestBookingUpdated_AdjustsHeldEscrow(t *testing.T) {
+	infra := setupContainers(t)
+	defer infra.Cleanup()
+
+	stack := setupPaymentStack(t, infra.DB, infra.KafkaBrokers)
+	defer stack.CleanupProducer()
+	defer func() { _ = stack.Consumer.Close() }()
+
+	bookingID := uuid.New()
+	ownerID := uuid.New()
+	paymentID := seedPaymentInHeldState(t, infra.DB, stack.Stripe, bookingID, ownerID)
+	var seeded repository.PaymentModel
+	require.NoError(t, infra.DB.Where("id = ?", paymentID).First(&seeded).Error)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() { _ = stack.Consumer.Start(ctx) }()
+	time.Sleep(3 * time.Second)
+
+	ce, err := kafka.NewCloudEvent("service-booking", paymentEvents.BookingUpdated, paymentEvents.BookingUpdatedEvent{
+		BookingID:     bookingID,
+		BookingNumber: "BK-INTTEST09",
+		TotalCents:    180000,
+		Currency:      "MYR",
+		OccurredAt:    time.Now().UTC(),
+	})
+	require.NoError(t, err)
+	ce.ID = uuid.NewString()
+	publishCloudEvent(t, infra.KafkaBrokers, events.TopicBookingEvents, ce)
+	publishCloudEvent(t, infra.KafkaBrokers, events.TopicBookingEvents, ce)
+
+	var model repository.PaymentModel
+	require.Eventually(t, func() bool {
+		return infra.DB.Where("id = ?", paymentID).First(&model).Error == nil && model.AmountCents == 180000
+	}, 15*time.Second, 200*time.Millisecond, "payment amount was not adjusted")
+	assert.Equal(t, "held", model.EscrowStatus)
+	assert.Equal(t, int64(27000), model.PlatformFeeCents)
+	assert.Equal(t, int64(153000), model.RunnerPayoutCents)
+	assert.NotEqual(t, seeded.StripePaymentID, model.StripePaymentID, "the new amount is authorized with a new intent")
+
+	intent, err := stack.Stripe.GetPaymentIntent(context.Background(), model.StripePaymentID)
+	require.NoError(t, err)
+	assert.Equal(t, int64(180000), intent.AmountCents)
+
+	// An update that leaves the amount unchanged is ignored.
+	publishTestEvent(t, infra.KafkaBrokers, events.TopicBookingEvents, "service-booking", paymentEvents.BookingUpdated,
+		paymentEvents.BookingUpdatedEvent{BookingID: bookingID, TotalCents: 180000, Currency: "MYR", OccurredAt: time.Now().UTC()})
+
+	time.Sleep(5 * time.Second)
+	var adjustments []repository.PaymentAmountAdjustmentModel
+	require.NoError(t, infra.DB.Where("payment_id = ?", paymentID).Find(&adjustments).Error)
+	require.Len(t, adjustments, 1, "the redelivered event is applied once")
+	assert.Equal(t, ce.ID, adjustments[0].EventID)
+	assert.Equal(t, int64(150000), adjustments[0].PreviousAmountCents)
+	assert.Equal(t, int64(180000), adjustments[0].GrossAmountCents)
+	assert.Equal(t, seeded.StripePaymentID, adjustments[0].PreviousPaymentIntentID)
+
+	require.NoError(t, infra.DB.Where("id = ?", paymentID).First(&model).Error)
+	assert.Equal(t, int64(180000), model.AmountCents)
+}
@@ -3,6 +3,9 @@ package adapter
 import (
 	"context"
 	"fmt"
+	"maps"
+	"strings"
+	"sync"
 
 	"github.com/google/uuid"
 	"go.uber.org/zap"
@@ -10,50 +13,174 @@ import (
 
 // StripeAdapter defines the Anti-Corruption Layer interface for Stripe payment operations.
 // This abstraction decouples the domain from the external Stripe API.
+// Implementations wrap Stripe failures in *ProviderError with Stripe's request ID.
 type StripeAdapter interface {
 	// CreatePaymentIntent creates a Stripe PaymentIntent with manual capture (authorize only).
-	CreatePaymentIntent(ctx context.Context, amountCents int64, currency, customerEmail string) (paymentIntentID, clientSecret string, err error)
+	// metadata is attached to the intent so charges can be traced back in the Stripe dashboard.
+	// idempotencyKey is sent as Stripe's Idempotency-Key, so a retried call with
+	// the same key returns the intent created by the first one.
+	// The intent's Status is IntentRequiresAction when the customer must complete 3-D Secure.
+	CreatePaymentIntent(ctx context.Context, amountCents int64, currency, customerEmail string, metadata map[string]string, idempotencyKey string) (*PaymentIntent, error)
+
+	// GetPaymentIntent retrieves a PaymentIntent, including its authorized amount.
+	GetPaymentIntent(ctx context.Context, paymentIntentID string) (*PaymentIntent, error)
 
 	// CapturePaymentIntent captures a previously authorized PaymentIntent.
-	CapturePaymentIntent(ctx context.Context, paymentIntentID string) error
+	// amountCents below the authorization captures only that much and Stripe
+	// releases the remainder; zero captures the full authorization.
+	CapturePaymentIntent(ctx context.Context, paymentIntentID string, amountCents int64) error
 
 	// CancelPaymentIntent cancels an uncaptured PaymentIntent.
 	CancelPaymentIntent(ctx context.Context, paymentIntentID string) error
 
 	// CreateRefund refunds a captured PaymentIntent.
 	CreateRefund(ctx context.Context, paymentIntentID string, amountCents int64) error
+
+	// CreateTransfer moves funds from the platform to a connected account.
+	CreateTransfer(ctx context.Context, destinationAccountID string, amountCents int64, currency string) (transferID string, err error)
+
+	// ReverseTransfer returns the funds of a transfer to the platform.
+	ReverseTransfer(ctx context.Context, transferID string) error
+}
+
+// PaymentIntent statuses the service acts on.
+const (
+	// IntentRequiresAction means the customer must complete 3-D Secure.
+	IntentRequiresAction = "requires_action"
+	// IntentRequiresCapture means the card is authorized and can be captured.
+	IntentRequiresCapture = "requires_capture"
+)
+
+// PaymentIntent is the provider's view of a PaymentIntent.
+type PaymentIntent struct {
+	ID string
+	// AmountCents is the authorized amount in the currency's minor unit.
+	AmountCents int64
+	// Currency is the ISO 4217 code; Stripe returns it in lowercase.
+	Currency string
+	Status   string
+	// ClientSecret lets the frontend confirm the intent. It must not be stored
+	// or returned outside the response that created the payment.
+	ClientSecret string
 }
 
 // MockStripeAdapter is a development/testing implementation of StripeAdapter.
 // It simulates Stripe behavior without requiring a real Stripe account.
 type MockStripeAdapter struct {
 	logger *zap.Logger
+
+	mu       sync.Mutex
+	metadata map[string]map[string]string
+	intents  map[string]PaymentIntent
+	// byIdempotencyKey maps idempotency keys to the intent they created.
+	byIdempotencyKey map[string]string
+	// requireAction makes new intents need 3-D Secure, like Stripe's test cards.
+	requireAction bool
 }
 
 // NewMockStripeAdapter creates a new mock Stripe adapter for development.
 func NewMockStripeAdapter(logger *zap.Logger) *MockStripeAdapter {
-	return &MockStripeAdapter{logger: logger}
+	return &MockStripeAdapter{
+		logger:           logger,
+		metadata:         make(map[string]map[string]string),
+		intents:          make(map[string]PaymentIntent),
+		byIdempotencyKey: make(map[string]string),
+	}
 }
 
 // CreatePaymentIntent simulates creating a PaymentIntent and returns mock IDs.
-func (m *MockStripeAdapter) CreatePaymentIntent(ctx context.Context, amountCents int64, currency, customerEmail string) (string, string, error) {
+// A repeated idempotencyKey returns the intent the key first created.
+func (m *MockStripeAdapter) CreatePaymentIntent(ctx context.Context, amountCents int64, currency, customerEmail string, metadata map[string]string, idempotencyKey string) (*PaymentIntent, error) {
+	m.mu.Lock()
+	if id, ok := m.byIdempotencyKey[idempotencyKey]; ok && idempotencyKey != "" {
+		intent := m.intents[id]
+		m.mu.Unlock()
+		m.logger.Info("[MOCK STRIPE] PaymentIntent reused for idempotency key",
+			zap.String("payment_intent_id", id),
+			zap.String("idempotency_key", idempotencyKey),
+		)
+		return &intent, nil
+	}
+
 	paymentIntentID := fmt.Sprintf("pi_mock_%s", uuid.New().String()[:8])
-	clientSecret := fmt.Sprintf("%s_secret_mock", paymentIntentID)
+	status := IntentRequiresCapture
+	if m.requireAction {
+		status = IntentRequiresAction
+	}
+	intent := PaymentIntent{
+		ID:           paymentIntentID,
+		AmountCents:  amountCents,
+		Currency:     strings.ToLower(currency),
+		Status:       status,
+		ClientSecret: fmt.Sprintf("%s_secret_mock", paymentIntentID),
+	}
+
+	if idempotencyKey != "" {
+		m.byIdempotencyKey[idempotencyKey] = paymentIntentID
+	}
+	m.metadata[paymentIntentID] = maps.Clone(metadata)
+	m.intents[paymentIntentID] = intent
+	m.mu.Unlock()
 
 	m.logger.Info("[MOCK STRIPE] PaymentIntent created",
 		zap.String("payment_intent_id", paymentIntentID),
 		zap.Int64("amount_cents", amountCents),
 		zap.String("currency", currency),
+		zap.String("status", status),
 		zap.String("customer_email", customerEmail),
+		zap.Any("metadata", metadata),
+		zap.String("idempotency_key", idempotencyKey),
 	)
 
-	return paymentIntentID, clientSecret, nil
+	return &intent, nil
+}
+
+// RequireAuthentication makes intents created afterwards need 3-D Secure.
+func (m *MockStripeAdapter) RequireAuthentication(required bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.requireAction = required
+}
+
+// CompleteAuthentication simulates the customer completing 3-D Secure on an
+// intent, which leaves it authorized and capturable.
+func (m *MockStripeAdapter) CompleteAuthentication(paymentIntentID string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	intent, ok := m.intents[paymentIntentID]
+	if !ok {
+		return &ProviderError{Op: "complete_authentication", Err: fmt.Errorf("no such payment_intent: %s", paymentIntentID)}
+	}
+	intent.Status = IntentRequiresCapture
+	m.intents[paymentIntentID] = intent
+	return nil
+}
+
+// Metadata returns a copy of the metadata recorded for a PaymentIntent, or nil
+// if the intent was not created by this mock.
+func (m *MockStripeAdapter) Metadata(paymentIntentID string) map[string]string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return maps.Clone(m.metadata[paymentIntentID])
+}
+
+// GetPaymentIntent returns a PaymentIntent created by this mock. Like Stripe,
+// it fails for unknown IDs.
+func (m *MockStripeAdapter) GetPaymentIntent(ctx context.Context, paymentIntentID string) (*PaymentIntent, error) {
+	m.mu.Lock()
+	intent, ok := m.intents[paymentIntentID]
+	m.mu.Unlock()
+	if !ok {
+		return nil, &ProviderError{Op: "get_payment_intent", Err: fmt.Errorf("no such payment_intent: %s", paymentIntentID)}
+	}
+	return &intent, nil
 }
 
 // CapturePaymentIntent simulates capturing a PaymentIntent.
-func (m *MockStripeAdapter) CapturePaymentIntent(ctx context.Context, paymentIntentID string) error {
+func (m *MockStripeAdapter) CapturePaymentIntent(ctx context.Context, paymentIntentID string, amountCents int64) error {
 	m.logger.Info("[MOCK STRIPE] PaymentIntent captured",
 		zap.String("payment_intent_id", paymentIntentID),
+		zap.Int64("amount_cents", amountCents),
 	)
 	return nil
 }
@@ -74,3 +201,25 @@ func (m *MockStripeAdapter) CreateRefund(ctx context.Context, paymentIntentID st
 	)
 	return nil
 }
+
+// CreateTransfer simulates transferring funds to a connected account.
+func (m *MockStripeAdapter) CreateTransfer(ctx context.Context, destinationAccountID string, amountCents int64, currency string) (string, error) {
+	transferID := fmt.Sprintf("tr_mock_%s", uuid.New().String()[:8])
+
+	m.logger.Info("[MOCK STRIPE] Transfer created",
+		zap.String("transfer_id", transferID),
+		zap.String("destination", destinationAccountID),
+		zap.Int64("amount_cents", amountCents),
+		zap.String("currency", currency),
+	)
+
+	return transferID, nil
+}
+
+// ReverseTransfer simulates reversing a transfer.
+func (m *MockStripeAdapter) ReverseTransfer(ctx context.Context, transferID string) error {
+	m.logger.Info("[MOCK STRIPE] Transfer reversed",
+		zap.String("transfer_id", transferID),
+	)
+	return nil
+}
@@ -0,0 +1,69 @@
+package adapter
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestMockStripeAdapter_RecordsMetadata(t *testing.T) {
+	m := NewMockStripeAdapter(zap.NewNop())
+	metadata := map[string]string{"booking_id": "b-1", "payment_id": "p-1", "owner_id": "o-1"}
+
+	intent, err := m.CreatePaymentIntent(context.Background(), 1000, "MYR", "owner@example.com", metadata, "")
+	require.NoError(t, err)
+	id := intent.ID
+
+	metadata["booking_id"] = "mutated"
+	assert.Equal(t, map[string]string{"booking_id": "b-1", "payment_id": "p-1", "owner_id": "o-1"}, m.Metadata(id))
+	assert.Nil(t, m.Metadata("pi_unknown"))
+}
+
+func TestMockStripeAdapter_GetPaymentIntent(t *testing.T) {
+	m := NewMockStripeAdapter(zap.NewNop())
+
+	created, err := m.CreatePaymentIntent(context.Background(), 1500, "MYR", "owner@example.com", nil, "")
+	require.NoError(t, err)
+
+	intent, err := m.GetPaymentIntent(context.Background(), created.ID)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1500), intent.AmountCents)
+	assert.Equal(t, "myr", intent.Currency)
+
+	_, err = m.GetPaymentIntent(context.Background(), "pi_unknown")
+	var pe *ProviderError
+	assert.ErrorAs(t, err, &pe)
+}
+
+func TestMockStripeAdapter_IdempotencyKeyReusesIntent(t *testing.T) {
+	m := NewMockStripeAdapter(zap.NewNop())
+	ctx := context.Background()
+
+	first, err := m.CreatePaymentIntent(ctx, 1500, "MYR", "owner@example.com", nil, "payment-1")
+	require.NoError(t, err)
+	again, err := m.CreatePaymentIntent(ctx, 1500, "MYR", "owner@example.com", nil, "payment-1")
+	require.NoError(t, err)
+	other, err := m.CreatePaymentIntent(ctx, 1500, "MYR", "owner@example.com", nil, "payment-2")
+	require.NoError(t, err)
+
+	assert.Equal(t, first.ID, again.ID)
+	assert.NotEqual(t, first.ID, other.ID)
+}
+
+func TestMockStripeAdapter_RequireAuthentication(t *testing.T) {
+	m := NewMockStripeAdapter(zap.NewNop())
+	ctx := context.Background()
+	m.RequireAuthentication(true)
+
+	created, err := m.CreatePaymentIntent(ctx, 1500, "MYR", "owner@example.com", nil, "")
+	require.NoError(t, err)
+	assert.Equal(t, IntentRequiresAction, created.Status)
+
+	require.NoError(t, m.CompleteAuthentication(created.ID))
+	intent, err := m.GetPaymentIntent(ctx, created.ID)
+	require.NoError(t, err)
+	assert.Equal(t, IntentRequiresCapture, intent.Status)
+}
@@ -0,0 +1,36 @@
+package adapter
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ProviderError is a failed call to the payment provider. StripeAdapter
+// implementations return it so Stripe's request ID, which Stripe support
+// asks for, reaches logs and failure events.
+type ProviderError struct {
+	// Op is the adapter operation, e.g. "capture_payment_intent".
+	Op string
+	// RequestID is Stripe's Request-Id for the failed call, if Stripe responded.
+	RequestID string
+	Err       error
+}
+
+func (e *ProviderError) Error() string {
+	if e.RequestID == "" {
+		return fmt.Sprintf("stripe %s: %v", e.Op, e.Err)
+	}
+	return fmt.Sprintf("stripe %s (request_id %s): %v", e.Op, e.RequestID, e.Err)
+}
+
+func (e *ProviderError) Unwrap() error { return e.Err }
+
+// ProviderRequestID returns the provider request ID carried by err, or "" if
+// err does not wrap a ProviderError.
+func ProviderRequestID(err error) string {
+	var pe *ProviderError
+	if errors.As(err, &pe) {
+		return pe.RequestID
+	}
+	return ""
+}
@@ -0,0 +1,23 @@
+package adapter
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestProviderRequestID(t *testing.T) {
+	cause := errors.New("card_declined")
+	err := fmt.Errorf("saga 'create_escrow' failed at step 'create_payment_intent': %w",
+		&ProviderError{Op: "create_payment_intent", RequestID: "req_123", Err: cause})
+
+	assert.Equal(t, "req_123", ProviderRequestID(err))
+	assert.ErrorIs(t, err, cause)
+	assert.Contains(t, err.Error(), "request_id req_123")
+
+	assert.Empty(t, ProviderRequestID(cause))
+	assert.Equal(t, "stripe capture_payment_intent: timeout",
+		(&ProviderError{Op: "capture_payment_intent", Err: errors.New("timeout")}).Error())
+}
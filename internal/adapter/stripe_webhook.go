@@ -0,0 +1,166 @@
+package adapter
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrInvalidWebhookSignature is returned when a webhook's Stripe-Signature
+// header is missing, malformed, stale or does not match its payload.
+var ErrInvalidWebhookSignature = errors.New("invalid stripe webhook signature")
+
+// WebhookTolerance is how old a signed webhook may be before it is rejected as a replay.
+const WebhookTolerance = 5 * time.Minute
+
+// Webhook event types the service acts on.
+const (
+	// WebhookIntentAuthorized is sent when a manual-capture intent becomes
+	// capturable, e.g. after the customer completes 3-D Secure.
+	WebhookIntentAuthorized = "payment_intent.amount_capturable_updated"
+)
+
+// WebhookEvent is a verified Stripe event whose object is a PaymentIntent.
+type WebhookEvent struct {
+	ID     string
+	Type   string
+	Intent WebhookIntent
+}
+
+// WebhookIntent is the part of a PaymentIntent webhook object the service reads.
+type WebhookIntent struct {
+	ID       string
+	Status   string
+	Metadata map[string]string
+	// PaymentMethod describes how the customer paid, e.g. "visa ****4242" or
+	// "apple_pay visa ****4242". It is empty if the event carries no charge.
+	PaymentMethod string
+}
+
+// webhookPaymentMethodDetails is a charge's payment_method_details object.
+type webhookPaymentMethodDetails struct {
+	Type string `json:"type"`
+	Card *struct {
+		Brand  string `json:"brand"`
+		Last4  string `json:"last4"`
+		Wallet *struct {
+			Type string `json:"type"`
+		} `json:"wallet"`
+	} `json:"card"`
+}
+
+// describe renders the details as a short label: the wallet type if any,
+// then the card brand and last four digits, or just the method type for
+// non-card methods such as FPX.
+func (d webhookPaymentMethodDetails) describe() string {
+	if d.Card == nil {
+		return d.Type
+	}
+	var parts []string
+	if d.Card.Wallet != nil && d.Card.Wallet.Type != "" {
+		parts = append(parts, d.Card.Wallet.Type)
+	}
+	if d.Card.Brand != "" {
+		parts = append(parts, d.Card.Brand)
+	}
+	if d.Card.Last4 != "" {
+		parts = append(parts, "****"+d.Card.Last4)
+	}
+	if len(parts) == 0 {
+		return d.Type
+	}
+	return strings.Join(parts, " ")
+}
+
+// ParseWebhookEvent verifies payload against the Stripe-Signature header using
+// the endpoint's signing secret and decodes it. Signatures are HMAC-SHA256 of
+// "<timestamp>.<payload>"; any v1 entry may match, which allows secret rotation.
+func ParseWebhookEvent(payload []byte, signatureHeader, secret string, now time.Time) (*WebhookEvent, error) {
+	if secret == "" {
+		return nil, fmt.Errorf("%w: no signing secret configured", ErrInvalidWebhookSignature)
+	}
+
+	var timestamp string
+	var signatures []string
+	for _, part := range strings.Split(signatureHeader, ",") {
+		key, value, ok := strings.Cut(strings.TrimSpace(part), "=")
+		if !ok {
+			continue
+		}
+		switch key {
+		case "t":
+			timestamp = value
+		case "v1":
+			signatures = append(signatures, value)
+		}
+	}
+	sentAt, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil || len(signatures) == 0 {
+		return nil, fmt.Errorf("%w: malformed header", ErrInvalidWebhookSignature)
+	}
+	if age := now.Sub(time.Unix(sentAt, 0)); age > WebhookTolerance || age < -WebhookTolerance {
+		return nil, fmt.Errorf("%w: timestamp outside tolerance", ErrInvalidWebhookSignature)
+	}
+
+	expected := SignWebhook(payload, secret, sentAt)
+	matched := false
+	for _, sig := range signatures {
+		if hmac.Equal([]byte(sig), []byte(expected)) {
+			matched = true
+			break
+		}
+	}
+	if !matched {
+		return nil, fmt.Errorf("%w: no matching signature", ErrInvalidWebhookSignature)
+	}
+
+	var raw struct {
+		ID   string `json:"id"`
+		Type string `json:"type"`
+		Data struct {
+			Object struct {
+				ID       string            `json:"id"`
+				Status   string            `json:"status"`
+				Metadata map[string]string `json:"metadata"`
+				Charges  struct {
+					Data []struct {
+						PaymentMethodDetails webhookPaymentMethodDetails `json:"payment_method_details"`
+					} `json:"data"`
+				} `json:"charges"`
+			} `json:"object"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(payload, &raw); err != nil {
+		return nil, fmt.Errorf("decode webhook event: %w", err)
+	}
+	var method string
+	if charges := raw.Data.Object.Charges.Data; len(charges) > 0 {
+		// Stripe lists the intent's charges newest first.
+		method = charges[0].PaymentMethodDetails.describe()
+	}
+	return &WebhookEvent{
+		ID:   raw.ID,
+		Type: raw.Type,
+		Intent: WebhookIntent{
+			ID:            raw.Data.Object.ID,
+			Status:        raw.Data.Object.Status,
+			Metadata:      raw.Data.Object.Metadata,
+			PaymentMethod: method,
+		},
+	}, nil
+}
+
+// SignWebhook returns the v1 signature Stripe sends for payload at timestamp.
+func SignWebhook(payload []byte, secret string, timestamp int64) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10)))
+	mac.Write([]byte("."))
+	mac.Write(payload)
+	return hex.EncodeToString(mac.Sum(nil))
+}
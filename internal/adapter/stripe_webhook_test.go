@@ -0,0 +1,76 @@
+package adapter
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseWebhookEvent(t *testing.T) {
+	const secret = "whsec_test"
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	payload := []byte(`{"id":"evt_1","type":"payment_intent.amount_capturable_updated","data":{"object":{"id":"pi_1","status":"requires_capture","metadata":{"payment_id":"p-1"}}}}`)
+	header := func(ts time.Time, body []byte) string {
+		return fmt.Sprintf("t=%d,v1=%s", ts.Unix(), SignWebhook(body, secret, ts.Unix()))
+	}
+
+	t.Run("valid signature", func(t *testing.T) {
+		event, err := ParseWebhookEvent(payload, header(now, payload), secret, now)
+		require.NoError(t, err)
+		assert.Equal(t, "evt_1", event.ID)
+		assert.Equal(t, WebhookIntentAuthorized, event.Type)
+		assert.Equal(t, "pi_1", event.Intent.ID)
+		assert.Equal(t, IntentRequiresCapture, event.Intent.Status)
+		assert.Equal(t, "p-1", event.Intent.Metadata["payment_id"])
+	})
+
+	t.Run("any v1 signature may match", func(t *testing.T) {
+		h := fmt.Sprintf("t=%d,v1=%s,v1=%s", now.Unix(), SignWebhook(payload, "whsec_old", now.Unix()), SignWebhook(payload, secret, now.Unix()))
+		_, err := ParseWebhookEvent(payload, h, secret, now)
+		require.NoError(t, err)
+	})
+
+	t.Run("payment method from the latest charge", func(t *testing.T) {
+		cases := []struct {
+			name    string
+			details string
+			want    string
+		}{
+			{"card", `{"type":"card","card":{"brand":"visa","last4":"4242"}}`, "visa ****4242"},
+			{"wallet", `{"type":"card","card":{"brand":"mastercard","last4":"4444","wallet":{"type":"apple_pay"}}}`, "apple_pay mastercard ****4444"},
+			{"non-card method", `{"type":"fpx","fpx":{"bank":"maybank2u"}}`, "fpx"},
+		}
+		for _, tc := range cases {
+			t.Run(tc.name, func(t *testing.T) {
+				body := []byte(`{"id":"evt_3","type":"payment_intent.amount_capturable_updated","data":{"object":{"id":"pi_1","status":"requires_capture","charges":{"data":[{"payment_method_details":` + tc.details + `}]}}}}`)
+				event, err := ParseWebhookEvent(body, header(now, body), secret, now)
+				require.NoError(t, err)
+				assert.Equal(t, tc.want, event.Intent.PaymentMethod)
+			})
+		}
+
+		event, err := ParseWebhookEvent(payload, header(now, payload), secret, now)
+		require.NoError(t, err)
+		assert.Empty(t, event.Intent.PaymentMethod, "an event without charges has no method")
+	})
+
+	rejected := []struct {
+		name   string
+		header string
+		secret string
+	}{
+		{"tampered payload", header(now, []byte(`{"id":"evt_2"}`)), secret},
+		{"stale timestamp", header(now.Add(-WebhookTolerance-time.Second), payload), secret},
+		{"malformed header", "v1=abc", secret},
+		{"no secret configured", header(now, payload), ""},
+	}
+	for _, tc := range rejected {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := ParseWebhookEvent(payload, tc.header, tc.secret, now)
+			assert.ErrorIs(t, err, ErrInvalidWebhookSignature)
+		})
+	}
+}
@@ -0,0 +1,110 @@
+package application
+
+import (
+	"strings"
+
+	"github.com/Kilat-Pet-Delivery/service-payment/internal/domain/payment"
+)
+
+// AmountFormat formats minor-unit amounts for display in one locale. Formatted
+// amounts are a convenience for frontends; the *_cents fields stay authoritative.
+type AmountFormat struct {
+	decimal string
+	group   string
+	// symbolAfter places the currency symbol after the number, separated by a
+	// no-break space, as in "1.500,00 €".
+	symbolAfter bool
+}
+
+// localeFormats are the number conventions of the supported languages.
+var localeFormats = map[string]AmountFormat{
+	"en": {decimal: ".", group: ","},
+	"ms": {decimal: ".", group: ","},
+	"zh": {decimal: ".", group: ","},
+	"ja": {decimal: ".", group: ","},
+	"th": {decimal: ".", group: ","},
+	"id": {decimal: ",", group: "."},
+	"de": {decimal: ",", group: ".", symbolAfter: true},
+	"fr": {decimal: ",", group: "\u202f", symbolAfter: true},
+}
+
+// nbsp separates a currency code or trailing symbol from the number.
+const nbsp = "\u00a0"
+
+// currencySymbols are the display symbols of common currencies; others are
+// shown by their ISO 4217 code.
+var currencySymbols = map[string]string{
+	"MYR": "RM", "SGD": "S$", "USD": "US$", "JPY": "¥", "IDR": "Rp",
+	"THB": "฿", "PHP": "₱", "EUR": "€", "GBP": "£", "AUD": "A$",
+}
+
+// AmountFormatForLanguage picks the format for an Accept-Language header. The
+// first supported language wins, ignoring quality values; English is the default.
+func AmountFormatForLanguage(acceptLanguage string) AmountFormat {
+	for _, tag := range strings.Split(acceptLanguage, ",") {
+		tag, _, _ = strings.Cut(tag, ";")
+		lang, _, _ := strings.Cut(strings.TrimSpace(tag), "-")
+		if f, ok := localeFormats[strings.ToLower(lang)]; ok {
+			return f
+		}
+	}
+	return localeFormats["en"]
+}
+
+// Format renders amount minor units of currency, e.g. "RM1,500.00",
+// "¥1,500" or "1.500,00 €".
+func (f AmountFormat) Format(amount int64, currency string) string {
+	number := payment.NewMoney(amount, currency).Decimal()
+	sign := ""
+	if strings.HasPrefix(number, "-") {
+		sign, number = "-", number[1:]
+	}
+	whole, frac, hasFrac := strings.Cut(number, ".")
+
+	var b strings.Builder
+	for i, d := range whole {
+		if i > 0 && (len(whole)-i)%3 == 0 {
+			b.WriteString(f.group)
+		}
+		b.WriteRune(d)
+	}
+	if hasFrac {
+		b.WriteString(f.decimal)
+		b.WriteString(frac)
+	}
+
+	symbol, ok := currencySymbols[strings.ToUpper(currency)]
+	if !ok {
+		if f.symbolAfter {
+			return sign + b.String() + " " + currency
+		}
+		return sign + currency + " " + b.String()
+	}
+	if f.symbolAfter {
+		return sign + b.String() + " " + symbol
+	}
+	return sign + symbol + b.String()
+}
+
+// AddFormatted fills d.Formatted with its amounts in f.
+func (d *PaymentDTO) AddFormatted(f AmountFormat) {
+	d.Formatted = map[string]string{
+		"amount":        f.Format(d.AmountCents, d.Currency),
+		"platform_fee":  f.Format(d.PlatformFeeCents, d.Currency),
+		"runner_payout": f.Format(d.RunnerPayoutCents, d.Currency),
+	}
+	if d.CreditAppliedCents != 0 {
+		d.Formatted["credit_applied"] = f.Format(d.CreditAppliedCents, d.Currency)
+	}
+}
+
+// AddFormatted fills r.Formatted with its amounts in f.
+func (r *ReceiptDTO) AddFormatted(f AmountFormat) {
+	r.Formatted = map[string]string{
+		"gross_amount":   f.Format(r.GrossAmountCents, r.Currency),
+		"total_discount": f.Format(r.TotalDiscountCents, r.Currency),
+		"amount_paid":    f.Format(r.AmountPaidCents, r.Currency),
+		"platform_fee":   f.Format(r.PlatformFeeCents, r.Currency),
+		"runner_payout":  f.Format(r.RunnerPayoutCents, r.Currency),
+	}
+}
@@ -0,0 +1,52 @@
+package application
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAmountFormat_Format(t *testing.T) {
+	tests := []struct {
+		name     string
+		language string
+		amount   int64
+		currency string
+		want     string
+	}{
+		{"MYR in English", "en-MY", 150000, "MYR", "RM1,500.00"},
+		{"MYR in Malay", "ms", 5, "MYR", "RM0.05"},
+		{"JPY has no decimals", "ja-JP", 1500, "JPY", "¥1,500"},
+		{"JPY below a thousand", "en", 999, "JPY", "¥999"},
+		{"KWD has three decimals", "en", 1234567, "KWD", "KWD\u00a01,234.567"},
+		{"IDR in Indonesian", "id-ID", 150000000, "IDR", "Rp1.500.000,00"},
+		{"EUR in German", "de-DE", 150000, "EUR", "1.500,00\u00a0€"},
+		{"EUR in French", "fr", 123456789, "EUR", "1\u202f234\u202f567,89\u00a0€"},
+		{"unknown currency after the number", "de", 150000, "CHF", "1.500,00\u00a0CHF"},
+		{"negative amount", "en", -150000, "MYR", "-RM1,500.00"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, AmountFormatForLanguage(tt.language).Format(tt.amount, tt.currency))
+		})
+	}
+}
+
+func TestAmountFormatForLanguage(t *testing.T) {
+	assert.Equal(t, localeFormats["fr"], AmountFormatForLanguage("fr-FR,fr;q=0.9,en;q=0.8"))
+	assert.Equal(t, localeFormats["de"], AmountFormatForLanguage("xx-YY, DE;q=0.5"), "unsupported languages are skipped")
+	assert.Equal(t, localeFormats["en"], AmountFormatForLanguage(""))
+	assert.Equal(t, localeFormats["en"], AmountFormatForLanguage("pt-BR"))
+}
+
+func TestPaymentDTO_AddFormattedKeepsRawAmounts(t *testing.T) {
+	dto := PaymentDTO{AmountCents: 150000, PlatformFeeCents: 22500, RunnerPayoutCents: 127500, Currency: "MYR"}
+	dto.AddFormatted(AmountFormatForLanguage("en"))
+
+	assert.Equal(t, map[string]string{
+		"amount":        "RM1,500.00",
+		"platform_fee":  "RM225.00",
+		"runner_payout": "RM1,275.00",
+	}, dto.Formatted)
+	assert.Equal(t, int64(150000), dto.AmountCents)
+}
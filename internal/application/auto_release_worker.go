@@ -0,0 +1,81 @@
+package application
+
+import (
+	"context"
+	"time"
+
+	"github.com/Kilat-Pet-Delivery/service-payment/internal/domain/payment"
+	"github.com/Kilat-Pet-Delivery/service-payment/internal/feature"
+	"github.com/Kilat-Pet-Delivery/service-payment/internal/saga"
+	"go.uber.org/zap"
+)
+
+// autoReleaseBatchSize bounds how many escrows are released per tick.
+const autoReleaseBatchSize = 50
+
+// AutoReleaseWorker releases held escrows to the runner once their hold window
+// has elapsed without a delivery confirmation or cancellation.
+type AutoReleaseWorker struct {
+	repo     payment.PaymentRepository
+	sagaSvc  *saga.PaymentSagaService
+	flags    *feature.Flags
+	interval time.Duration
+	logger   *zap.Logger
+}
+
+// NewAutoReleaseWorker creates a new AutoReleaseWorker. Each run is skipped
+// while the auto_release flag is off.
+func NewAutoReleaseWorker(repo payment.PaymentRepository, sagaSvc *saga.PaymentSagaService, flags *feature.Flags, interval time.Duration, logger *zap.Logger) *AutoReleaseWorker {
+	return &AutoReleaseWorker{repo: repo, sagaSvc: sagaSvc, flags: flags, interval: interval, logger: logger}
+}
+
+// Start runs the worker every interval. It blocks until the context is cancelled.
+func (w *AutoReleaseWorker) Start(ctx context.Context) {
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			w.RunOnce(ctx)
+		}
+	}
+}
+
+// RunOnce releases every escrow that is currently eligible. A concurrent
+// DeliveryConfirmedEvent is resolved by the version-checked update in the
+// release saga: whichever writer loses sees a conflict or a non-held status.
+func (w *AutoReleaseWorker) RunOnce(ctx context.Context) {
+	if !w.flags.Enabled(feature.AutoRelease) {
+		w.logger.Debug("auto-release is disabled by feature flag, skipping run")
+		return
+	}
+	ctx = payment.WithActor(ctx, "system:auto-release")
+
+	payments, err := w.repo.FindReleaseEligible(ctx, time.Now().UTC(), autoReleaseBatchSize)
+	if err != nil {
+		if ctx.Err() == nil {
+			w.logger.Error("failed to load release-eligible payments", zap.Error(err))
+		}
+		return
+	}
+
+	for _, p := range payments {
+		if ctx.Err() != nil {
+			return
+		}
+
+		w.logger.Info("auto-releasing escrow after hold window",
+			zap.String("payment_id", p.ID().String()),
+			zap.String("booking_id", p.BookingID().String()),
+		)
+		if err := w.sagaSvc.ReleaseEscrowSaga(ctx, p.ID(), *p.RunnerID(), nil); err != nil {
+			w.logger.Warn("auto-release failed",
+				zap.String("payment_id", p.ID().String()),
+				zap.Error(err),
+			)
+		}
+	}
+}
@@ -0,0 +1,47 @@
+package application
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/Kilat-Pet-Delivery/service-payment/internal/domain/payment"
+	"github.com/Kilat-Pet-Delivery/service-payment/internal/feature"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+)
+
+// eligibleCountingRepo counts FindReleaseEligible calls and returns no payments.
+type eligibleCountingRepo struct {
+	payment.PaymentRepository
+
+	calls int
+}
+
+func (r *eligibleCountingRepo) FindReleaseEligible(context.Context, time.Time, int) ([]*payment.Payment, error) {
+	r.calls++
+	return nil, nil
+}
+
+func TestAutoReleaseWorker_RunOnce_GatedByFlag(t *testing.T) {
+	tests := []struct {
+		name      string
+		flags     *feature.Flags
+		wantCalls int
+	}{
+		{"enabled by default", nil, 1},
+		{"enabled explicitly", feature.Static(map[feature.Flag]bool{feature.AutoRelease: true}), 1},
+		{"disabled skips the run", feature.Static(map[feature.Flag]bool{feature.AutoRelease: false}), 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := &eligibleCountingRepo{}
+			w := NewAutoReleaseWorker(repo, nil, tt.flags, time.Minute, zap.NewNop())
+
+			w.RunOnce(context.Background())
+
+			assert.Equal(t, tt.wantCalls, repo.calls)
+		})
+	}
+}
@@ -0,0 +1,74 @@
+package application
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	bookingDomain "github.com/Kilat-Pet-Delivery/service-payment/internal/domain/booking"
+	"github.com/Kilat-Pet-Delivery/service-payment/internal/feature"
+	"go.uber.org/zap"
+)
+
+// ErrBookingAmountMismatch is returned when a payment's amount or currency
+// differs from the booking's total by more than the configured tolerance.
+var ErrBookingAmountMismatch = errors.New("amount does not match booking total")
+
+// ErrBookingTotalUnknown is returned while the require_booking_total flag is
+// on and no total has been received for the booking.
+var ErrBookingTotalUnknown = errors.New("booking total is not known")
+
+// SetBookingTotals makes InitiatePayment check the requested amount against
+// the booking's cached total, allowing toleranceCents in the payment
+// currency's minor unit either way. Bookings without a cached total are let
+// through unless flags enable require_booking_total. flags may be nil.
+func (s *PaymentService) SetBookingTotals(totals bookingDomain.TotalRepository, toleranceCents int64, flags *feature.Flags) {
+	s.bookingTotals = totals
+	s.amountToleranceCents = toleranceCents
+	s.flags = flags
+}
+
+// HandleBookingCreated caches the total of a newly created booking. It does
+// nothing unless SetBookingTotals was called.
+func (s *PaymentService) HandleBookingCreated(ctx context.Context, total bookingDomain.Total) error {
+	if s.bookingTotals == nil {
+		return nil
+	}
+	total.Currency = strings.ToUpper(total.Currency)
+	if err := s.bookingTotals.Save(ctx, total); err != nil {
+		return fmt.Errorf("failed to cache booking total: %w", err)
+	}
+	return nil
+}
+
+// checkBookingAmount compares req with the booking's cached total. req.Currency
+// must already be normalized.
+func (s *PaymentService) checkBookingAmount(ctx context.Context, req InitiatePaymentRequest) error {
+	if s.bookingTotals == nil {
+		return nil
+	}
+	total, err := s.bookingTotals.FindByBookingID(ctx, req.BookingID)
+	if err != nil {
+		return fmt.Errorf("failed to load booking total: %w", err)
+	}
+	if total == nil {
+		if s.flags.Enabled(feature.RequireBookingTotal) {
+			return fmt.Errorf("%w for booking %s", ErrBookingTotalUnknown, req.BookingID)
+		}
+		s.logger.Warn("no booking total cached, skipping amount check",
+			zap.String("booking_id", req.BookingID.String()),
+		)
+		return nil
+	}
+
+	diff := req.AmountCents - total.AmountCents
+	if diff < 0 {
+		diff = -diff
+	}
+	if total.Currency != req.Currency || diff > s.amountToleranceCents {
+		return fmt.Errorf("%w: got %d %s, booking total is %d %s",
+			ErrBookingAmountMismatch, req.AmountCents, req.Currency, total.AmountCents, total.Currency)
+	}
+	return nil
+}
@@ -0,0 +1,96 @@
+package application
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/Kilat-Pet-Delivery/lib-common/domain"
+	"github.com/Kilat-Pet-Delivery/service-payment/internal/domain/payment"
+	"github.com/Kilat-Pet-Delivery/service-payment/internal/saga"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+// HandleBookingUpdated adjusts the held escrow of a booking whose price
+// changed before delivery. totalCents is the booking's new total; discounts
+// applied to the payment are kept, so the payment amount becomes the total
+// less those discounts. eventID identifies the update, which is applied at
+// most once. Updates that leave the amount unchanged and payments that are
+// not held are skipped. An adjustment that would need the customer to
+// authenticate again is not applied and the payment is flagged for review.
+func (s *PaymentService) HandleBookingUpdated(ctx context.Context, eventID string, bookingID uuid.UUID, totalCents int64, currency string) error {
+	s.logger.Info("handling booking updated event",
+		zap.String("event_id", eventID),
+		zap.String("booking_id", bookingID.String()),
+		zap.Int64("total_cents", totalCents),
+	)
+
+	p, err := s.repo.FindByBookingID(ctx, bookingID)
+	if err != nil {
+		if domErr, ok := err.(*domain.DomainError); ok && domErr.Err == domain.ErrNotFound {
+			s.logger.Warn("no payment found for updated booking, skipping adjustment",
+				zap.String("booking_id", bookingID.String()),
+			)
+			return nil
+		}
+		return err
+	}
+
+	if p.EscrowStatus() != payment.EscrowHeld {
+		s.logger.Info("payment not in held state, skipping adjustment",
+			zap.String("payment_id", p.ID().String()),
+			zap.String("escrow_status", string(p.EscrowStatus())),
+		)
+		return nil
+	}
+	if !strings.EqualFold(currency, p.Currency()) {
+		s.logger.Warn("booking update changes currency, skipping adjustment",
+			zap.String("payment_id", p.ID().String()),
+			zap.String("payment_currency", p.Currency()),
+			zap.String("booking_currency", currency),
+		)
+		return nil
+	}
+
+	discounts, err := s.repo.FindDiscounts(ctx, p.ID())
+	if err != nil {
+		return fmt.Errorf("failed to load payment discounts: %w", err)
+	}
+	amountCents := totalCents
+	for _, d := range discounts {
+		amountCents -= d.AmountCents
+	}
+
+	if amountCents == p.AmountCents() {
+		s.logger.Info("booking update leaves payment amount unchanged, skipping adjustment",
+			zap.String("payment_id", p.ID().String()),
+		)
+		return nil
+	}
+	if p.StripePaymentID() == "" || amountCents <= p.CreditAppliedCents() {
+		// Without a card part there is nothing to authorize again, and credit
+		// is not topped up or returned here.
+		s.logger.Warn("payment has no card authorization for the adjusted amount, skipping adjustment",
+			zap.String("payment_id", p.ID().String()),
+			zap.Int64("amount_cents", amountCents),
+			zap.Int64("credit_applied_cents", p.CreditAppliedCents()),
+		)
+		return nil
+	}
+
+	err = s.sagaSvc.AdjustEscrowSaga(ctx, p.ID(), amountCents, totalCents, eventID)
+	if errors.Is(err, saga.ErrAdjustmentNeedsAuthentication) {
+		reason := fmt.Sprintf("booking price changed to %d but the adjusted amount needs customer authentication", amountCents)
+		if flagErr := s.repo.FlagForReview(ctx, p.ID(), reason); flagErr != nil {
+			return fmt.Errorf("failed to flag payment for review: %w", flagErr)
+		}
+		s.logger.Warn("escrow adjustment needs customer authentication, flagged for review",
+			zap.String("payment_id", p.ID().String()),
+			zap.String("event_id", eventID),
+		)
+		return nil
+	}
+	return err
+}
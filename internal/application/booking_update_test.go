@@ -0,0 +1,127 @@
+package application
+
+import (
+	"context"
+	"testing"
+
+	"github.com/Kilat-Pet-Delivery/service-payment/internal/adapter"
+	"github.com/Kilat-Pet-Delivery/service-payment/internal/domain/payment"
+	"github.com/Kilat-Pet-Delivery/service-payment/internal/saga"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+// adjustingPaymentRepo is a memoryPaymentRepo that also stores discounts,
+// amount adjustments and review flags.
+type adjustingPaymentRepo struct {
+	*memoryPaymentRepo
+	discounts   map[uuid.UUID][]payment.DiscountLine
+	adjustments map[string]payment.AmountAdjustment
+	flagged     map[uuid.UUID]string
+}
+
+func (r *adjustingPaymentRepo) FindDiscounts(_ context.Context, paymentID uuid.UUID) ([]payment.DiscountLine, error) {
+	return r.discounts[paymentID], nil
+}
+
+func (r *adjustingPaymentRepo) HasAmountAdjustment(_ context.Context, eventID string) (bool, error) {
+	_, ok := r.adjustments[eventID]
+	return ok, nil
+}
+
+func (r *adjustingPaymentRepo) AdjustAmount(_ context.Context, p *payment.Payment, adj payment.AmountAdjustment) error {
+	if _, ok := r.adjustments[adj.EventID]; ok {
+		return payment.ErrAdjustmentApplied
+	}
+	r.adjustments[adj.EventID] = adj
+	r.payments[p.ID()] = p
+	return nil
+}
+
+func (r *adjustingPaymentRepo) FindLatestAmountAdjustment(_ context.Context, paymentID uuid.UUID) (*payment.AmountAdjustment, error) {
+	var latest *payment.AmountAdjustment
+	for _, adj := range r.adjustments {
+		if adj.PaymentID == paymentID && (latest == nil || adj.AdjustedAt.After(latest.AdjustedAt)) {
+			latest = &adj
+		}
+	}
+	return latest, nil
+}
+
+func (r *adjustingPaymentRepo) FlagForReview(_ context.Context, paymentID uuid.UUID, reason string) error {
+	r.flagged[paymentID] = reason
+	return nil
+}
+
+// newBookingUpdateService returns a service with one payment of 10000 MYR
+// held for bookingID, 1000 of which came from a promo discount.
+func newBookingUpdateService(t *testing.T) (*PaymentService, *adjustingPaymentRepo, *adapter.MockStripeAdapter, *payment.Payment) {
+	t.Helper()
+	repo := &adjustingPaymentRepo{
+		memoryPaymentRepo: &memoryPaymentRepo{payments: map[uuid.UUID]*payment.Payment{}},
+		discounts:         map[uuid.UUID][]payment.DiscountLine{},
+		adjustments:       map[string]payment.AmountAdjustment{},
+		flagged:           map[uuid.UUID]string{},
+	}
+	p, err := payment.NewPayment(uuid.New(), uuid.New(), 10000, "MYR", 15.0, payment.PayoutFloors{})
+	require.NoError(t, err)
+	require.NoError(t, p.HoldEscrow("pi_original", 0))
+	repo.payments[p.ID()] = p
+	repo.discounts[p.ID()] = []payment.DiscountLine{{Source: "promo", Code: "WELCOME", AmountCents: 1000}}
+
+	stripe := adapter.NewMockStripeAdapter(zap.NewNop())
+	sagaSvc := saga.NewPaymentSagaService(repo, stripe, &recordingPublisher{}, nil, nil, nil, nil, nil, 15.0, payment.PayoutFloors{}, 0, 0, zap.NewNop())
+	svc := NewPaymentService(repo, nil, nil, nil, sagaSvc, nil, nil, nil, zap.NewNop())
+	return svc, repo, stripe, p
+}
+
+func TestHandleBookingUpdated_AdjustsHeldEscrowOnce(t *testing.T) {
+	svc, repo, stripe, p := newBookingUpdateService(t)
+	ctx := context.Background()
+
+	require.NoError(t, svc.HandleBookingUpdated(ctx, "evt-1", p.BookingID(), 11000, "MYR"))
+	assert.Empty(t, repo.adjustments, "a total that leaves the discounted amount unchanged is ignored")
+	assert.Equal(t, "pi_original", p.StripePaymentID())
+
+	require.NoError(t, svc.HandleBookingUpdated(ctx, "evt-2", p.BookingID(), 13000, "myr"))
+	assert.Equal(t, int64(12000), p.AmountCents(), "the promo discount still applies")
+	assert.Equal(t, int64(1800), p.PlatformFeeCents())
+	assert.Equal(t, int64(10200), p.RunnerPayoutCents())
+	intent, err := stripe.GetPaymentIntent(ctx, p.StripePaymentID())
+	require.NoError(t, err)
+	assert.Equal(t, int64(12000), intent.AmountCents, "the card is authorized for the adjusted amount")
+
+	receipt, err := svc.GetReceipt(ctx, p.ID())
+	require.NoError(t, err)
+	assert.Equal(t, int64(13000), receipt.GrossAmountCents, "the receipt shows the new booking total")
+	assert.Equal(t, int64(1000), receipt.TotalDiscountCents)
+	assert.Equal(t, int64(12000), receipt.AmountPaidCents)
+
+	require.NoError(t, svc.HandleBookingUpdated(ctx, "evt-2", p.BookingID(), 14000, "MYR"))
+	assert.Equal(t, int64(12000), p.AmountCents(), "a redelivered event is applied once")
+	assert.Len(t, repo.adjustments, 1)
+}
+
+func TestHandleBookingUpdated_SkipsPaymentsNotHeld(t *testing.T) {
+	svc, repo, _, p := newBookingUpdateService(t)
+	require.NoError(t, p.ReleaseToRunner(uuid.New()))
+
+	require.NoError(t, svc.HandleBookingUpdated(context.Background(), "evt-1", p.BookingID(), 15000, "MYR"))
+	assert.Empty(t, repo.adjustments)
+	assert.Equal(t, int64(10000), p.AmountCents())
+
+	require.NoError(t, svc.HandleBookingUpdated(context.Background(), "evt-2", uuid.New(), 15000, "MYR"),
+		"bookings without a payment are skipped")
+}
+
+func TestHandleBookingUpdated_FlagsAdjustmentNeedingAuthentication(t *testing.T) {
+	svc, repo, stripe, p := newBookingUpdateService(t)
+	stripe.RequireAuthentication(true)
+
+	require.NoError(t, svc.HandleBookingUpdated(context.Background(), "evt-1", p.BookingID(), 15000, "MYR"))
+	assert.Equal(t, int64(10000), p.AmountCents(), "the payment keeps its authorized amount")
+	assert.Equal(t, "pi_original", p.StripePaymentID())
+	assert.Contains(t, repo.flagged[p.ID()], "needs customer authentication")
+}
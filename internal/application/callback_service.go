@@ -0,0 +1,205 @@
+package application
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/netip"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/Kilat-Pet-Delivery/service-payment/internal/domain/callback"
+	"github.com/Kilat-Pet-Delivery/service-payment/internal/domain/payment"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+// ErrInvalidCallbackURL is returned when a callback URL is not an absolute
+// http or https URL, or names a host that is not public.
+var ErrInvalidCallbackURL = errors.New("invalid callback_url")
+
+// validateCallbackURL checks that raw is an absolute http(s) URL with a host.
+// Hosts that are obviously internal, localhost or a non-public IP literal,
+// are rejected up front; the callback worker checks resolved addresses too.
+func validateCallbackURL(raw string) error {
+	u, err := url.Parse(raw)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" || u.Hostname() == "" {
+		return fmt.Errorf("%w: must be an absolute http or https URL", ErrInvalidCallbackURL)
+	}
+	host := strings.ToLower(strings.TrimSuffix(u.Hostname(), "."))
+	if host == "localhost" || strings.HasSuffix(host, ".localhost") {
+		return fmt.Errorf("%w: host must be public", ErrInvalidCallbackURL)
+	}
+	if ip, err := netip.ParseAddr(host); err == nil && !isPublicAddr(ip) {
+		return fmt.Errorf("%w: host must be public", ErrInvalidCallbackURL)
+	}
+	return nil
+}
+
+// nonPublicPrefixes are ranges that are global unicast by the net/netip
+// predicates but still not reachable on, or meant for, the public internet.
+var nonPublicPrefixes = []netip.Prefix{
+	netip.MustParsePrefix("0.0.0.0/8"),      // "this network"
+	netip.MustParsePrefix("100.64.0.0/10"),  // carrier-grade NAT
+	netip.MustParsePrefix("192.0.0.0/24"),   // IETF protocol assignments
+	netip.MustParsePrefix("198.18.0.0/15"),  // benchmarking
+	netip.MustParsePrefix("240.0.0.0/4"),    // reserved
+	netip.MustParsePrefix("64:ff9b::/96"),   // NAT64, may embed an internal IPv4 address
+	netip.MustParsePrefix("64:ff9b:1::/48"), // local-use NAT64
+	netip.MustParsePrefix("2002::/16"),      // 6to4, may embed an internal IPv4 address
+}
+
+// isPublicAddr reports whether callbacks may be sent to ip. Loopback, private
+// (RFC 1918 and unique local), link-local including cloud metadata endpoints
+// such as 169.254.169.254, multicast, unspecified and reserved addresses are
+// not public.
+func isPublicAddr(ip netip.Addr) bool {
+	ip = ip.Unmap()
+	if !ip.IsGlobalUnicast() || ip.IsPrivate() {
+		return false
+	}
+	for _, prefix := range nonPublicPrefixes {
+		if prefix.Contains(ip) {
+			return false
+		}
+	}
+	return true
+}
+
+// CallbackPayload is the JSON body POSTed to a payment's callback URL.
+type CallbackPayload struct {
+	ID                uuid.UUID  `json:"id"`
+	Type              string     `json:"type"`
+	PaymentID         uuid.UUID  `json:"payment_id"`
+	BookingID         uuid.UUID  `json:"booking_id"`
+	OwnerID           uuid.UUID  `json:"owner_id"`
+	RunnerID          *uuid.UUID `json:"runner_id,omitempty"`
+	EscrowStatus      string     `json:"escrow_status"`
+	AmountCents       int64      `json:"amount_cents"`
+	PlatformFeeCents  int64      `json:"platform_fee_cents"`
+	RunnerPayoutCents int64      `json:"runner_payout_cents"`
+	Currency          string     `json:"currency"`
+	RefundReason      string     `json:"refund_reason,omitempty"`
+	OccurredAt        time.Time  `json:"occurred_at"`
+}
+
+// CallbackAttemptDTO is one delivery attempt of a callback.
+type CallbackAttemptDTO struct {
+	Number      int       `json:"number"`
+	StatusCode  int       `json:"status_code,omitempty"`
+	Error       string    `json:"error,omitempty"`
+	AttemptedAt time.Time `json:"attempted_at"`
+}
+
+// CallbackDeliveryDTO is a callback and its delivery attempts.
+type CallbackDeliveryDTO struct {
+	ID            uuid.UUID            `json:"id"`
+	EventType     string               `json:"event_type"`
+	URL           string               `json:"url"`
+	Status        string               `json:"status"`
+	Attempts      int                  `json:"attempts"`
+	NextAttemptAt *time.Time           `json:"next_attempt_at,omitempty"`
+	LastError     string               `json:"last_error,omitempty"`
+	DeliveredAt   *time.Time           `json:"delivered_at,omitempty"`
+	CreatedAt     time.Time            `json:"created_at"`
+	AttemptLog    []CallbackAttemptDTO `json:"attempt_log"`
+}
+
+// CallbackService queues HTTP callbacks for payments and reports on their delivery.
+type CallbackService struct {
+	repo   callback.DeliveryRepository
+	logger *zap.Logger
+}
+
+// NewCallbackService creates a new CallbackService.
+func NewCallbackService(repo callback.DeliveryRepository, logger *zap.Logger) *CallbackService {
+	return &CallbackService{repo: repo, logger: logger}
+}
+
+// Schedule queues a callback for eventType to p's callback URL. The payload
+// is built now so it reflects the payment as of the event.
+func (s *CallbackService) Schedule(ctx context.Context, p *payment.Payment, eventType string) error {
+	if p.CallbackURL() == "" {
+		return nil
+	}
+
+	now := time.Now().UTC()
+	d := callback.NewDelivery(p.ID(), p.CallbackURL(), eventType, nil, now)
+	payload, err := json.Marshal(CallbackPayload{
+		ID:                d.ID,
+		Type:              eventType,
+		PaymentID:         p.ID(),
+		BookingID:         p.BookingID(),
+		OwnerID:           p.OwnerID(),
+		RunnerID:          p.RunnerID(),
+		EscrowStatus:      string(p.EscrowStatus()),
+		AmountCents:       p.AmountCents(),
+		PlatformFeeCents:  p.PlatformFeeCents(),
+		RunnerPayoutCents: p.RunnerPayoutCents(),
+		Currency:          p.Currency(),
+		RefundReason:      p.RefundReason(),
+		OccurredAt:        now,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to marshal callback payload: %w", err)
+	}
+	d.Payload = payload
+
+	if err := s.repo.Save(ctx, d); err != nil {
+		return fmt.Errorf("failed to save callback delivery: %w", err)
+	}
+
+	s.logger.Info("payment callback scheduled",
+		zap.String("payment_id", p.ID().String()),
+		zap.String("delivery_id", d.ID.String()),
+		zap.String("event_type", eventType),
+	)
+	return nil
+}
+
+// GetPaymentCallbacks returns a payment's callbacks and their attempts.
+func (s *CallbackService) GetPaymentCallbacks(ctx context.Context, paymentID uuid.UUID) ([]CallbackDeliveryDTO, error) {
+	deliveries, err := s.repo.FindByPaymentID(ctx, paymentID)
+	if err != nil {
+		return nil, err
+	}
+
+	dtos := make([]CallbackDeliveryDTO, len(deliveries))
+	for i, d := range deliveries {
+		attempts, err := s.repo.FindAttempts(ctx, d.ID)
+		if err != nil {
+			return nil, err
+		}
+		dtos[i] = toCallbackDeliveryDTO(d, attempts)
+	}
+	return dtos, nil
+}
+
+func toCallbackDeliveryDTO(d *callback.Delivery, attempts []callback.Attempt) CallbackDeliveryDTO {
+	dto := CallbackDeliveryDTO{
+		ID:          d.ID,
+		EventType:   d.EventType,
+		URL:         d.URL,
+		Status:      string(d.Status),
+		Attempts:    d.Attempts,
+		LastError:   d.LastError,
+		DeliveredAt: d.DeliveredAt,
+		CreatedAt:   d.CreatedAt,
+		AttemptLog:  make([]CallbackAttemptDTO, len(attempts)),
+	}
+	if d.Status == callback.StatusPending {
+		next := d.NextAttemptAt
+		dto.NextAttemptAt = &next
+	}
+	for i, a := range attempts {
+		dto.AttemptLog[i] = CallbackAttemptDTO{
+			Number:      a.Number,
+			StatusCode:  a.StatusCode,
+			Error:       a.Error,
+			AttemptedAt: a.AttemptedAt,
+		}
+	}
+	return dto
+}
@@ -0,0 +1,181 @@
+package application
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/netip"
+	"strconv"
+	"syscall"
+	"time"
+
+	"github.com/Kilat-Pet-Delivery/service-payment/internal/domain/callback"
+	"go.uber.org/zap"
+)
+
+// callbackBatchSize bounds how many callbacks are attempted per tick.
+const callbackBatchSize = 50
+
+// Headers sent with every callback.
+const (
+	CallbackIDHeader        = "X-Callback-ID"
+	CallbackEventHeader     = "X-Callback-Event"
+	CallbackTimestampHeader = "X-Callback-Timestamp"
+	// CallbackSignatureHeader carries "sha256=" followed by callback.Sign of
+	// the timestamp header and the raw body.
+	CallbackSignatureHeader = "X-Callback-Signature"
+)
+
+// CallbackWorker delivers queued payment callbacks, retrying failures with
+// exponential backoff until they succeed or run out of attempts.
+type CallbackWorker struct {
+	repo        callback.DeliveryRepository
+	secret      []byte
+	client      *http.Client
+	maxAttempts int
+	baseBackoff time.Duration
+	interval    time.Duration
+	logger      *zap.Logger
+}
+
+// errCallbackAddressNotPublic is returned when a callback host resolves to an
+// address isPublicAddr rejects.
+var errCallbackAddressNotPublic = errors.New("callback address is not public")
+
+// NewCallbackWorker creates a new CallbackWorker. Payloads are signed with
+// secret, and each attempt is abandoned after timeout.
+func NewCallbackWorker(repo callback.DeliveryRepository, secret string, maxAttempts int, baseBackoff, timeout, interval time.Duration, logger *zap.Logger) *CallbackWorker {
+	return &CallbackWorker{
+		repo:        repo,
+		secret:      []byte(secret),
+		client:      newCallbackClient(timeout),
+		maxAttempts: maxAttempts,
+		baseBackoff: baseBackoff,
+		interval:    interval,
+		logger:      logger,
+	}
+}
+
+// newCallbackClient returns the client callbacks are sent with. Callback URLs
+// come from API callers, so it only connects to public addresses, checked on
+// the resolved address of every connection so a hostname cannot point it at
+// internal services, and it does not follow redirects: a 3xx response is a
+// failed attempt.
+func newCallbackClient(timeout time.Duration) *http.Client {
+	dialer := &net.Dialer{Timeout: timeout, Control: refuseNonPublicAddress}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil // a proxy would connect to the callback host unchecked
+	transport.DialContext = dialer.DialContext
+	return &http.Client{
+		Timeout:   timeout,
+		Transport: transport,
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+}
+
+// refuseNonPublicAddress is a net.Dialer Control function that refuses to
+// connect to an address that is not public.
+func refuseNonPublicAddress(_, address string, _ syscall.RawConn) error {
+	addrPort, err := netip.ParseAddrPort(address)
+	if err != nil {
+		return fmt.Errorf("%w: %s", errCallbackAddressNotPublic, address)
+	}
+	if !isPublicAddr(addrPort.Addr()) {
+		return fmt.Errorf("%w: %s", errCallbackAddressNotPublic, addrPort.Addr())
+	}
+	return nil
+}
+
+// Start runs the worker every interval. It blocks until the context is cancelled.
+func (w *CallbackWorker) Start(ctx context.Context) {
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			w.RunOnce(ctx)
+		}
+	}
+}
+
+// RunOnce attempts every callback that is currently due. Claimed callbacks
+// are leased for twice the HTTP timeout so another instance does not pick
+// them up while an attempt is in flight.
+func (w *CallbackWorker) RunOnce(ctx context.Context) {
+	deliveries, err := w.repo.ClaimDue(ctx, time.Now().UTC(), 2*w.client.Timeout, callbackBatchSize)
+	if err != nil {
+		if ctx.Err() == nil {
+			w.logger.Error("failed to claim due callbacks", zap.Error(err))
+		}
+		return
+	}
+
+	for _, d := range deliveries {
+		if ctx.Err() != nil {
+			return
+		}
+		w.deliver(ctx, d)
+	}
+}
+
+// deliver makes one attempt and records its outcome.
+func (w *CallbackWorker) deliver(ctx context.Context, d *callback.Delivery) {
+	statusCode, err := w.post(ctx, d)
+	now := time.Now().UTC()
+
+	var attempt callback.Attempt
+	if err == nil {
+		attempt = d.RecordSuccess(statusCode, now)
+	} else {
+		attempt = d.RecordFailure(statusCode, err.Error(), now, w.maxAttempts, w.baseBackoff)
+		w.logger.Warn("payment callback attempt failed",
+			zap.String("delivery_id", d.ID.String()),
+			zap.String("payment_id", d.PaymentID.String()),
+			zap.Int("attempt", attempt.Number),
+			zap.String("status", string(d.Status)),
+			zap.Error(err),
+		)
+	}
+
+	if err := w.repo.RecordAttempt(ctx, d, attempt); err != nil {
+		w.logger.Error("failed to record callback attempt",
+			zap.String("delivery_id", d.ID.String()),
+			zap.Error(err),
+		)
+	}
+}
+
+// post sends the signed payload. Any non-2xx response is an error.
+func (w *CallbackWorker) post(ctx context.Context, d *callback.Delivery) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, d.URL, bytes.NewReader(d.Payload))
+	if err != nil {
+		return 0, err
+	}
+	timestamp := time.Now().Unix()
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(CallbackIDHeader, d.ID.String())
+	req.Header.Set(CallbackEventHeader, d.EventType)
+	req.Header.Set(CallbackTimestampHeader, strconv.FormatInt(timestamp, 10))
+	req.Header.Set(CallbackSignatureHeader, "sha256="+callback.Sign(w.secret, timestamp, d.Payload))
+
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 4096))
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+	return resp.StatusCode, nil
+}
@@ -0,0 +1,208 @@
+package application
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Kilat-Pet-Delivery/service-payment/internal/domain/callback"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+// memoryCallbackRepo keeps deliveries and attempts in memory.
+type memoryCallbackRepo struct {
+	callback.DeliveryRepository
+
+	mu         sync.Mutex
+	deliveries []*callback.Delivery
+	attempts   []callback.Attempt
+}
+
+func (r *memoryCallbackRepo) ClaimDue(_ context.Context, now time.Time, lease time.Duration, _ int) ([]*callback.Delivery, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var due []*callback.Delivery
+	for _, d := range r.deliveries {
+		if d.Status == callback.StatusPending && !d.NextAttemptAt.After(now) {
+			d.NextAttemptAt = now.Add(lease)
+			due = append(due, d)
+		}
+	}
+	return due, nil
+}
+
+func (r *memoryCallbackRepo) RecordAttempt(_ context.Context, _ *callback.Delivery, a callback.Attempt) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.attempts = append(r.attempts, a)
+	return nil
+}
+
+func (r *memoryCallbackRepo) FindByPaymentID(_ context.Context, paymentID uuid.UUID) ([]*callback.Delivery, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var found []*callback.Delivery
+	for _, d := range r.deliveries {
+		if d.PaymentID == paymentID {
+			found = append(found, d)
+		}
+	}
+	return found, nil
+}
+
+func (r *memoryCallbackRepo) FindAttempts(_ context.Context, deliveryID uuid.UUID) ([]callback.Attempt, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var found []callback.Attempt
+	for _, a := range r.attempts {
+		if a.DeliveryID == deliveryID {
+			found = append(found, a)
+		}
+	}
+	return found, nil
+}
+
+// allowLoopback lets w reach an httptest server, which listens on loopback,
+// by replacing the transport that refuses non-public addresses.
+func allowLoopback(w *CallbackWorker) {
+	w.client.Transport = http.DefaultTransport
+}
+
+func TestCallbackWorker_DeliversSignedPayload(t *testing.T) {
+	const secret = "callback-secret"
+	payload := []byte(`{"type":"payment.escrow_released"}`)
+
+	var got *http.Request
+	var body []byte
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r
+		body, _ = io.ReadAll(r.Body)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer srv.Close()
+
+	d := callback.NewDelivery(uuid.New(), srv.URL, "payment.escrow_released", payload, time.Now().UTC())
+	repo := &memoryCallbackRepo{deliveries: []*callback.Delivery{d}}
+	w := NewCallbackWorker(repo, secret, 3, time.Minute, time.Second, time.Minute, zap.NewNop())
+	allowLoopback(w)
+
+	w.RunOnce(context.Background())
+
+	require.NotNil(t, got)
+	assert.Equal(t, payload, body)
+	assert.Equal(t, d.ID.String(), got.Header.Get(CallbackIDHeader))
+	assert.Equal(t, "payment.escrow_released", got.Header.Get(CallbackEventHeader))
+	ts, err := strconv.ParseInt(got.Header.Get(CallbackTimestampHeader), 10, 64)
+	require.NoError(t, err)
+	assert.Equal(t, "sha256="+callback.Sign([]byte(secret), ts, payload), got.Header.Get(CallbackSignatureHeader))
+
+	assert.Equal(t, callback.StatusDelivered, d.Status)
+	require.Len(t, repo.attempts, 1)
+	assert.Equal(t, http.StatusNoContent, repo.attempts[0].StatusCode)
+}
+
+func TestCallbackWorker_RetriesWithBackoffUntilFailed(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer srv.Close()
+
+	d := callback.NewDelivery(uuid.New(), srv.URL, "payment.escrow_refunded", []byte(`{}`), time.Now().UTC())
+	repo := &memoryCallbackRepo{deliveries: []*callback.Delivery{d}}
+	w := NewCallbackWorker(repo, "secret", 2, time.Minute, time.Second, time.Minute, zap.NewNop())
+	allowLoopback(w)
+
+	w.RunOnce(context.Background())
+	assert.Equal(t, callback.StatusPending, d.Status)
+	assert.True(t, d.NextAttemptAt.After(time.Now().UTC().Add(50*time.Second)), "retry is backed off")
+	assert.Equal(t, "unexpected status 500", d.LastError)
+
+	w.RunOnce(context.Background())
+	assert.Len(t, repo.attempts, 1, "not retried before the backoff elapses")
+
+	d.NextAttemptAt = time.Now().UTC()
+	w.RunOnce(context.Background())
+	assert.Equal(t, callback.StatusFailed, d.Status)
+	require.Len(t, repo.attempts, 2)
+	assert.Equal(t, 2, repo.attempts[1].Number)
+}
+
+func TestCallbackWorker_RefusesNonPublicAddresses(t *testing.T) {
+	var hits int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		hits++
+	}))
+	defer srv.Close()
+
+	// The URL names a hostname, so only the resolved address gives it away.
+	u := strings.Replace(srv.URL, "127.0.0.1", "localhost", 1)
+	d := callback.NewDelivery(uuid.New(), u, "payment.escrow_released", []byte(`{}`), time.Now().UTC())
+	repo := &memoryCallbackRepo{deliveries: []*callback.Delivery{d}}
+	w := NewCallbackWorker(repo, "secret", 3, time.Minute, time.Second, time.Minute, zap.NewNop())
+
+	w.RunOnce(context.Background())
+
+	assert.Zero(t, hits, "nothing is sent to a loopback address")
+	assert.Equal(t, callback.StatusPending, d.Status)
+	assert.Contains(t, d.LastError, errCallbackAddressNotPublic.Error())
+}
+
+func TestCallbackWorker_DoesNotFollowRedirects(t *testing.T) {
+	var redirected bool
+	mux := http.NewServeMux()
+	mux.HandleFunc("/cb", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/internal", http.StatusFound)
+	})
+	mux.HandleFunc("/internal", func(http.ResponseWriter, *http.Request) {
+		redirected = true
+	})
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	d := callback.NewDelivery(uuid.New(), srv.URL+"/cb", "payment.escrow_released", []byte(`{}`), time.Now().UTC())
+	repo := &memoryCallbackRepo{deliveries: []*callback.Delivery{d}}
+	w := NewCallbackWorker(repo, "secret", 3, time.Minute, time.Second, time.Minute, zap.NewNop())
+	allowLoopback(w)
+
+	w.RunOnce(context.Background())
+
+	assert.False(t, redirected)
+	assert.Equal(t, "unexpected status 302", d.LastError)
+}
+
+func TestIsPublicAddr(t *testing.T) {
+	for _, addr := range []string{"93.184.216.34", "8.8.8.8", "2606:4700::1111"} {
+		assert.True(t, isPublicAddr(netip.MustParseAddr(addr)), addr)
+	}
+	for _, addr := range []string{
+		"127.0.0.1", "10.1.2.3", "172.16.0.1", "192.168.1.1", "169.254.169.254",
+		"100.64.0.1", "0.0.0.0", "0.1.2.3", "224.0.0.1", "255.255.255.255",
+		"::1", "::", "fd00::1", "fe80::1", "::ffff:127.0.0.1", "64:ff9b::a00:1",
+	} {
+		assert.False(t, isPublicAddr(netip.MustParseAddr(addr)), addr)
+	}
+}
+
+func TestValidateCallbackURL(t *testing.T) {
+	assert.NoError(t, validateCallbackURL("https://partner.example.com/hooks/payments"))
+	assert.NoError(t, validateCallbackURL("https://93.184.216.34/cb"))
+	assert.ErrorIs(t, validateCallbackURL("ftp://example.com/cb"), ErrInvalidCallbackURL)
+	assert.ErrorIs(t, validateCallbackURL("/relative/path"), ErrInvalidCallbackURL)
+	assert.ErrorIs(t, validateCallbackURL("https://"), ErrInvalidCallbackURL)
+	for _, internal := range []string{
+		"http://10.0.0.5:8080/cb", "http://127.0.0.1/cb", "http://[::1]/cb",
+		"http://169.254.169.254/latest/meta-data", "http://localhost:8080/cb", "http://api.localhost/cb",
+	} {
+		assert.ErrorIs(t, validateCallbackURL(internal), ErrInvalidCallbackURL, internal)
+	}
+}
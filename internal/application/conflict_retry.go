@@ -0,0 +1,31 @@
+package application
+
+import (
+	"context"
+	"errors"
+
+	"github.com/Kilat-Pet-Delivery/lib-common/domain"
+	"go.uber.org/zap"
+)
+
+// maxConflictAttempts bounds how many times retryOnConflict runs an operation.
+const maxConflictAttempts = 3
+
+// retryOnConflict runs fn again when it fails with an optimistic-lock
+// conflict, e.g. because another event updated the same payment first, up to
+// maxConflictAttempts times in all. fn must load the aggregate it changes, so
+// each attempt starts from the latest version. Other errors, and the conflict
+// of the last attempt, are returned as is.
+func (s *PaymentService) retryOnConflict(ctx context.Context, op string, fn func(ctx context.Context) error) error {
+	for attempt := 1; ; attempt++ {
+		err := fn(ctx)
+		if err == nil || !errors.Is(err, domain.ErrConflict) || attempt >= maxConflictAttempts || ctx.Err() != nil {
+			return err
+		}
+		s.logger.Info("optimistic lock conflict, reloading and retrying",
+			zap.String("op", op),
+			zap.Int("attempt", attempt),
+			zap.Error(err),
+		)
+	}
+}
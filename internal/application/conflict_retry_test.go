@@ -0,0 +1,69 @@
+package application
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/Kilat-Pet-Delivery/lib-common/domain"
+	"github.com/Kilat-Pet-Delivery/service-payment/internal/domain/payment"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+// racingPaymentRepo loads a fresh copy of one held payment on every FindByID,
+// as a database would, and fails the first conflicts updates as if another
+// event had updated the payment first.
+type racingPaymentRepo struct {
+	payment.PaymentRepository
+	id        uuid.UUID
+	conflicts int
+	loads     int
+	saved     *payment.Payment
+}
+
+func (r *racingPaymentRepo) FindByID(context.Context, uuid.UUID) (*payment.Payment, error) {
+	r.loads++
+	now := time.Now().UTC()
+	return payment.Reconstitute(r.id, uuid.New(), uuid.New(), nil, payment.EscrowHeld,
+		payment.NewMoney(10000, "MYR"), payment.NewMoney(1500, "MYR"), payment.NewMoney(8500, "MYR"), 0,
+		"card", "pi_1", &now, nil, nil, nil, "", "", "", "", "", "", false, false, false, int64(r.loads), now, now), nil
+}
+
+func (r *racingPaymentRepo) Update(_ context.Context, p *payment.Payment) error {
+	if r.conflicts > 0 {
+		r.conflicts--
+		return domain.NewConflictError("payment was modified by another transaction")
+	}
+	r.saved = p
+	return nil
+}
+
+func TestOpenDispute_RetriesAfterConflict(t *testing.T) {
+	repo := &racingPaymentRepo{id: uuid.New(), conflicts: 1}
+	svc := NewPaymentService(repo, nil, nil, nil, nil, nil, nil, nil, zap.NewNop())
+
+	require.NoError(t, svc.OpenDispute(context.Background(), repo.id, "item not delivered"))
+	assert.Equal(t, 2, repo.loads, "the payment is reloaded before the retry")
+	require.NotNil(t, repo.saved)
+	assert.Equal(t, payment.EscrowDisputed, repo.saved.EscrowStatus())
+	assert.Equal(t, int64(3), repo.saved.Version(), "the retry builds on the reloaded version")
+}
+
+func TestOverridePlatformFee_GivesUpAfterRepeatedConflicts(t *testing.T) {
+	repo := &racingPaymentRepo{id: uuid.New(), conflicts: maxConflictAttempts}
+	svc := NewPaymentService(repo, nil, nil, nil, nil, nil, nil, nil, zap.NewNop())
+
+	_, err := svc.OverridePlatformFee(context.Background(), repo.id, 1000)
+	assert.ErrorIs(t, err, domain.ErrConflict)
+	assert.Equal(t, maxConflictAttempts, repo.loads)
+	assert.Nil(t, repo.saved)
+
+	repo.conflicts, repo.loads = 1, 0
+	dto, err := svc.OverridePlatformFee(context.Background(), repo.id, 1000)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1000), dto.PlatformFeeCents)
+	assert.Equal(t, 2, repo.loads)
+}
@@ -0,0 +1,72 @@
+package application
+
+import (
+	"context"
+	"time"
+
+	creditDomain "github.com/Kilat-Pet-Delivery/service-payment/internal/domain/credit"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+// CreditBalanceDTO is the API response for a user's credit balance.
+type CreditBalanceDTO struct {
+	UserID       uuid.UUID  `json:"user_id"`
+	BalanceCents int64      `json:"balance_cents"`
+	Currency     string     `json:"currency,omitempty"`
+	UpdatedAt    *time.Time `json:"updated_at,omitempty"`
+}
+
+// CreditService handles in-app credit use cases.
+type CreditService struct {
+	repo   creditDomain.CreditRepository
+	logger *zap.Logger
+}
+
+// NewCreditService creates a new CreditService.
+func NewCreditService(repo creditDomain.CreditRepository, logger *zap.Logger) *CreditService {
+	return &CreditService{repo: repo, logger: logger}
+}
+
+// Grant adds credit to a user's balance.
+func (s *CreditService) Grant(ctx context.Context, userID uuid.UUID, currency string, amountCents int64) error {
+	if err := s.repo.Grant(ctx, userID, currency, amountCents); err != nil {
+		return err
+	}
+	s.logger.Info("credit granted",
+		zap.String("user_id", userID.String()),
+		zap.Int64("amount_cents", amountCents),
+		zap.String("currency", currency),
+	)
+	return nil
+}
+
+// Deduct removes credit from a user's balance.
+func (s *CreditService) Deduct(ctx context.Context, userID uuid.UUID, currency string, amountCents int64) error {
+	if err := s.repo.Deduct(ctx, userID, currency, amountCents); err != nil {
+		return err
+	}
+	s.logger.Info("credit deducted",
+		zap.String("user_id", userID.String()),
+		zap.Int64("amount_cents", amountCents),
+		zap.String("currency", currency),
+	)
+	return nil
+}
+
+// Balance returns a user's credit balance; users who were never granted credit have zero.
+func (s *CreditService) Balance(ctx context.Context, userID uuid.UUID) (*CreditBalanceDTO, error) {
+	balance, err := s.repo.FindByUserID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if balance == nil {
+		return &CreditBalanceDTO{UserID: userID}, nil
+	}
+	return &CreditBalanceDTO{
+		UserID:       balance.UserID,
+		BalanceCents: balance.BalanceCents,
+		Currency:     balance.Currency,
+		UpdatedAt:    &balance.UpdatedAt,
+	}, nil
+}
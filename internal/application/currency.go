@@ -0,0 +1,40 @@
+package application
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ErrUnsupportedCurrency is returned when a currency is not in the configured allowlist.
+var ErrUnsupportedCurrency = errors.New("unsupported currency")
+
+// CurrencyAllowlist is the set of ISO 4217 codes the service accepts.
+// A nil allowlist accepts any currency.
+type CurrencyAllowlist map[string]struct{}
+
+// NewCurrencyAllowlist builds an allowlist from currency codes, ignoring case.
+func NewCurrencyAllowlist(codes []string) CurrencyAllowlist {
+	a := make(CurrencyAllowlist, len(codes))
+	for _, code := range codes {
+		a[normalizeCurrency(code)] = struct{}{}
+	}
+	return a
+}
+
+// Normalize returns the upper-cased currency, or ErrUnsupportedCurrency if it
+// is not allowed.
+func (a CurrencyAllowlist) Normalize(currency string) (string, error) {
+	code := normalizeCurrency(currency)
+	if a == nil {
+		return code, nil
+	}
+	if _, ok := a[code]; !ok {
+		return "", fmt.Errorf("%w: %q", ErrUnsupportedCurrency, currency)
+	}
+	return code, nil
+}
+
+func normalizeCurrency(code string) string {
+	return strings.ToUpper(strings.TrimSpace(code))
+}
@@ -0,0 +1,34 @@
+package application
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCurrencyAllowlist_Normalize(t *testing.T) {
+	allow := NewCurrencyAllowlist([]string{"MYR", "sgd"})
+
+	got, err := allow.Normalize("myr")
+	require.NoError(t, err)
+	assert.Equal(t, "MYR", got)
+
+	got, err = allow.Normalize(" SGD ")
+	require.NoError(t, err)
+	assert.Equal(t, "SGD", got)
+
+	_, err = allow.Normalize("USD")
+	assert.True(t, errors.Is(err, ErrUnsupportedCurrency))
+
+	_, err = allow.Normalize("MYRR")
+	assert.True(t, errors.Is(err, ErrUnsupportedCurrency), "typos are rejected")
+}
+
+func TestCurrencyAllowlist_NilAcceptsAny(t *testing.T) {
+	var allow CurrencyAllowlist
+	got, err := allow.Normalize("usd")
+	require.NoError(t, err)
+	assert.Equal(t, "USD", got)
+}
@@ -0,0 +1,135 @@
+package application
+
+import (
+	"fmt"
+
+	promoDomain "github.com/Kilat-Pet-Delivery/service-payment/internal/domain/promo"
+	subDomain "github.com/Kilat-Pet-Delivery/service-payment/internal/domain/subscription"
+)
+
+// StackingPolicy controls how a promo discount combines with a subscription discount.
+type StackingPolicy string
+
+const (
+	// StackingBestOf applies only the larger of the promo and subscription discounts.
+	StackingBestOf StackingPolicy = "best_of"
+	// StackingAdditive applies both discounts, each calculated against the base amount.
+	StackingAdditive StackingPolicy = "additive"
+)
+
+// Discount sources reported in a DiscountLineDTO.
+const (
+	DiscountSourcePromo        = "promo"
+	DiscountSourceSubscription = "subscription"
+)
+
+// DiscountPolicy is the configurable policy used by the DiscountEngine.
+type DiscountPolicy struct {
+	Stacking StackingPolicy
+	// MaxTotalDiscountPercent caps the combined discount as a percentage of the
+	// base amount. Zero means no cap beyond the base amount itself.
+	MaxTotalDiscountPercent int64
+	// MaxPromoPercentOfTotal caps a single promo's discount as a percentage of
+	// the base amount. Zero disables the cap.
+	MaxPromoPercentOfTotal int64
+}
+
+// DiscountLineDTO is a single applied discount in a breakdown.
+type DiscountLineDTO struct {
+	Source      string `json:"source"`
+	Code        string `json:"code,omitempty"`
+	AmountCents int64  `json:"amount_cents"`
+	// Clamped reports that the promo percent-of-total cap reduced the line.
+	Clamped bool `json:"clamped,omitempty"`
+}
+
+// DiscountBreakdownDTO is the itemized result of applying discounts to a base amount.
+type DiscountBreakdownDTO struct {
+	BaseAmountCents    int64             `json:"base_amount_cents"`
+	TotalDiscountCents int64             `json:"total_discount_cents"`
+	FinalAmountCents   int64             `json:"final_amount_cents"`
+	Lines              []DiscountLineDTO `json:"lines"`
+}
+
+// DiscountEngine combines promo and subscription discounts according to a DiscountPolicy.
+type DiscountEngine struct {
+	policy DiscountPolicy
+}
+
+// NewDiscountEngine creates a new DiscountEngine. An unknown stacking policy
+// falls back to best-of so customers are never over-discounted by misconfiguration.
+func NewDiscountEngine(policy DiscountPolicy) *DiscountEngine {
+	if policy.Stacking != StackingAdditive {
+		policy.Stacking = StackingBestOf
+	}
+	return &DiscountEngine{policy: policy}
+}
+
+// Calculate returns the final amount and itemized breakdown for baseCents.
+// promo may be nil and plan may be empty when the respective discount does not apply.
+func (e *DiscountEngine) Calculate(baseCents int64, promo *promoDomain.PromoCode, plan subDomain.PlanType) (*DiscountBreakdownDTO, error) {
+	lines := make([]DiscountLineDTO, 0, 2)
+
+	if plan != "" {
+		info, ok := subDomain.FindPlan(plan)
+		if !ok {
+			return nil, fmt.Errorf("invalid plan: %s", plan)
+		}
+		if amount := promoDomain.PercentOf(baseCents, int64(info.DiscountPct)); amount > 0 {
+			lines = append(lines, DiscountLineDTO{Source: DiscountSourceSubscription, Code: string(plan), AmountCents: amount})
+		}
+	}
+
+	if promo != nil {
+		discount, err := promo.CalculateDiscount(baseCents, e.policy.MaxPromoPercentOfTotal)
+		if err != nil {
+			return nil, err
+		}
+		if discount.AmountCents > 0 {
+			lines = append(lines, DiscountLineDTO{
+				Source:      DiscountSourcePromo,
+				Code:        promo.Code(),
+				AmountCents: discount.AmountCents,
+				Clamped:     discount.Clamped,
+			})
+		}
+	}
+
+	if e.policy.Stacking == StackingBestOf && len(lines) > 1 {
+		best := lines[0]
+		for _, l := range lines[1:] {
+			if l.AmountCents > best.AmountCents {
+				best = l
+			}
+		}
+		lines = []DiscountLineDTO{best}
+	}
+
+	maxDiscount := baseCents
+	if e.policy.MaxTotalDiscountPercent > 0 {
+		if capped := promoDomain.PercentOf(baseCents, e.policy.MaxTotalDiscountPercent); capped < maxDiscount {
+			maxDiscount = capped
+		}
+	}
+
+	// Trim lines in order so the itemized amounts always sum to the total.
+	var total int64
+	applied := lines[:0]
+	for _, l := range lines {
+		if total+l.AmountCents > maxDiscount {
+			l.AmountCents = maxDiscount - total
+		}
+		if l.AmountCents <= 0 {
+			break
+		}
+		total += l.AmountCents
+		applied = append(applied, l)
+	}
+
+	return &DiscountBreakdownDTO{
+		BaseAmountCents:    baseCents,
+		TotalDiscountCents: total,
+		FinalAmountCents:   baseCents - total,
+		Lines:              applied,
+	}, nil
+}
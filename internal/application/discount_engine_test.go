@@ -0,0 +1,158 @@
+package application
+
+import (
+	"testing"
+	"time"
+
+	promoDomain "github.com/Kilat-Pet-Delivery/service-payment/internal/domain/promo"
+	subDomain "github.com/Kilat-Pet-Delivery/service-payment/internal/domain/subscription"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newTestPromo returns a promo code that is valid for the next hour.
+func newTestPromo(t *testing.T, discountType promoDomain.DiscountType, value int64) *promoDomain.PromoCode {
+	t.Helper()
+	now := time.Now().UTC()
+	p, err := promoDomain.NewPromoCode("TEST", discountType, value, 0, 0, 0, now.Add(-time.Hour), now.Add(time.Hour), uuid.New())
+	require.NoError(t, err)
+	return p
+}
+
+func TestDiscountEngine_Calculate(t *testing.T) {
+	tests := []struct {
+		name      string
+		policy    DiscountPolicy
+		base      int64
+		promo     *promoDomain.PromoCode
+		plan      subDomain.PlanType
+		wantTotal int64
+		wantLines []DiscountLineDTO
+	}{
+		{
+			name:      "no discounts",
+			policy:    DiscountPolicy{Stacking: StackingBestOf},
+			base:      10000,
+			wantTotal: 0,
+			wantLines: []DiscountLineDTO{},
+		},
+		{
+			name:      "best-of picks the larger subscription discount",
+			policy:    DiscountPolicy{Stacking: StackingBestOf},
+			base:      10000,
+			promo:     newTestPromo(t, promoDomain.DiscountTypeFixed, 500),
+			plan:      subDomain.PlanPremium,
+			wantTotal: 1500,
+			wantLines: []DiscountLineDTO{{Source: DiscountSourceSubscription, Code: "premium", AmountCents: 1500}},
+		},
+		{
+			name:      "best-of picks the larger promo discount",
+			policy:    DiscountPolicy{Stacking: StackingBestOf},
+			base:      10000,
+			promo:     newTestPromo(t, promoDomain.DiscountTypePercentage, 20),
+			plan:      subDomain.PlanBasic,
+			wantTotal: 2000,
+			wantLines: []DiscountLineDTO{{Source: DiscountSourcePromo, Code: "TEST", AmountCents: 2000}},
+		},
+		{
+			name:      "additive sums both discounts",
+			policy:    DiscountPolicy{Stacking: StackingAdditive},
+			base:      10000,
+			promo:     newTestPromo(t, promoDomain.DiscountTypeFixed, 500),
+			plan:      subDomain.PlanPremium,
+			wantTotal: 2000,
+			wantLines: []DiscountLineDTO{
+				{Source: DiscountSourceSubscription, Code: "premium", AmountCents: 1500},
+				{Source: DiscountSourcePromo, Code: "TEST", AmountCents: 500},
+			},
+		},
+		{
+			name:      "additive respects the total cap by trimming the last line",
+			policy:    DiscountPolicy{Stacking: StackingAdditive, MaxTotalDiscountPercent: 20},
+			base:      10000,
+			promo:     newTestPromo(t, promoDomain.DiscountTypePercentage, 10),
+			plan:      subDomain.PlanPremium,
+			wantTotal: 2000,
+			wantLines: []DiscountLineDTO{
+				{Source: DiscountSourceSubscription, Code: "premium", AmountCents: 1500},
+				{Source: DiscountSourcePromo, Code: "TEST", AmountCents: 500},
+			},
+		},
+		{
+			name:      "best-of respects the total cap",
+			policy:    DiscountPolicy{Stacking: StackingBestOf, MaxTotalDiscountPercent: 10},
+			base:      10000,
+			plan:      subDomain.PlanPremium,
+			wantTotal: 1000,
+			wantLines: []DiscountLineDTO{{Source: DiscountSourceSubscription, Code: "premium", AmountCents: 1000}},
+		},
+		{
+			name:      "additive never discounts below zero",
+			policy:    DiscountPolicy{Stacking: StackingAdditive},
+			base:      1000,
+			promo:     newTestPromo(t, promoDomain.DiscountTypeFixed, 5000),
+			plan:      subDomain.PlanPremium,
+			wantTotal: 1000,
+			wantLines: []DiscountLineDTO{
+				{Source: DiscountSourceSubscription, Code: "premium", AmountCents: 150},
+				{Source: DiscountSourcePromo, Code: "TEST", AmountCents: 850},
+			},
+		},
+		{
+			name:      "unknown policy falls back to best-of",
+			policy:    DiscountPolicy{Stacking: "bogus"},
+			base:      10000,
+			promo:     newTestPromo(t, promoDomain.DiscountTypeFixed, 500),
+			plan:      subDomain.PlanBasic,
+			wantTotal: 500,
+			wantLines: []DiscountLineDTO{{Source: DiscountSourceSubscription, Code: "basic", AmountCents: 500}},
+		},
+		{
+			name:      "promo percent-of-total cap clamps a fixed promo on a small booking",
+			policy:    DiscountPolicy{Stacking: StackingBestOf, MaxPromoPercentOfTotal: 50},
+			base:      1200,
+			promo:     newTestPromo(t, promoDomain.DiscountTypeFixed, 1000),
+			wantTotal: 600,
+			wantLines: []DiscountLineDTO{{Source: DiscountSourcePromo, Code: "TEST", AmountCents: 600, Clamped: true}},
+		},
+		{
+			name:      "subscription percentage rounds to the nearest cent",
+			policy:    DiscountPolicy{Stacking: StackingBestOf},
+			base:      1999,
+			plan:      subDomain.PlanBasic,
+			wantTotal: 100,
+			wantLines: []DiscountLineDTO{{Source: DiscountSourceSubscription, Code: "basic", AmountCents: 100}},
+		},
+		{
+			name:      "total cap rounds like the discounts it caps",
+			policy:    DiscountPolicy{Stacking: StackingAdditive, MaxTotalDiscountPercent: 15},
+			base:      1999,
+			promo:     newTestPromo(t, promoDomain.DiscountTypePercentage, 10),
+			plan:      subDomain.PlanBasic,
+			wantTotal: 300,
+			wantLines: []DiscountLineDTO{
+				{Source: DiscountSourceSubscription, Code: "basic", AmountCents: 100},
+				{Source: DiscountSourcePromo, Code: "TEST", AmountCents: 200},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			engine := NewDiscountEngine(tt.policy)
+			got, err := engine.Calculate(tt.base, tt.promo, tt.plan)
+			require.NoError(t, err)
+			assert.Equal(t, tt.base, got.BaseAmountCents)
+			assert.Equal(t, tt.wantTotal, got.TotalDiscountCents)
+			assert.Equal(t, tt.base-tt.wantTotal, got.FinalAmountCents)
+			assert.Equal(t, tt.wantLines, got.Lines)
+		})
+	}
+}
+
+func TestDiscountEngine_Calculate_InvalidPlan(t *testing.T) {
+	engine := NewDiscountEngine(DiscountPolicy{Stacking: StackingBestOf})
+	_, err := engine.Calculate(10000, nil, "gold")
+	require.Error(t, err)
+}
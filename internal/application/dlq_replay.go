@@ -0,0 +1,70 @@
+package application
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/Kilat-Pet-Delivery/lib-common/domain"
+)
+
+// DefaultDLQReplayLimit is how many dead-lettered events a replay reads when
+// no limit is given; MaxDLQReplayLimit caps the limit.
+const (
+	DefaultDLQReplayLimit = 100
+	MaxDLQReplayLimit     = 1000
+)
+
+// Outcomes of replaying one dead-lettered event.
+const (
+	DLQEventReplayed = "replayed"
+	DLQEventFailed   = "failed"
+	DLQEventSkipped  = "skipped"
+)
+
+// ErrInvalidDLQReplayRequest is returned when a dead-letter replay's limit is invalid.
+var ErrInvalidDLQReplayRequest = errors.New("invalid dead-letter replay request")
+
+// ErrDLQReplayInProgress is returned when a dead-letter replay is requested
+// while another is running.
+var ErrDLQReplayInProgress = domain.NewConflictError("a dead-letter replay is already running")
+
+// DLQReplayRequest selects the dead-lettered booking events to replay. An
+// empty Type replays every type; Limit bounds how many events are read.
+type DLQReplayRequest struct {
+	Type  string
+	Limit int
+}
+
+// Validate checks the limit and defaults it when unset.
+func (r *DLQReplayRequest) Validate() error {
+	if r.Limit == 0 {
+		r.Limit = DefaultDLQReplayLimit
+	}
+	if r.Limit < 0 || r.Limit > MaxDLQReplayLimit {
+		return fmt.Errorf("%w: limit must be between 1 and %d", ErrInvalidDLQReplayRequest, MaxDLQReplayLimit)
+	}
+	return nil
+}
+
+// DLQEventDTO is the outcome of one dead-lettered event read by a replay.
+// Partition and Offset locate the event on the DLQ topic; Topic is the topic
+// it was originally consumed from.
+type DLQEventDTO struct {
+	Topic     string `json:"topic"`
+	Partition int    `json:"partition"`
+	Offset    int64  `json:"offset"`
+	EventID   string `json:"event_id,omitempty"`
+	Type      string `json:"type,omitempty"`
+	Status    string `json:"status"`
+	Error     string `json:"error,omitempty"`
+}
+
+// DLQReplayResultDTO summarizes a dead-letter replay.
+type DLQReplayResultDTO struct {
+	Type     string        `json:"type,omitempty"`
+	Scanned  int           `json:"scanned"`
+	Replayed int           `json:"replayed"`
+	Failed   int           `json:"failed"`
+	Skipped  int           `json:"skipped"`
+	Events   []DLQEventDTO `json:"events"`
+}
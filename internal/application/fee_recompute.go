@@ -0,0 +1,186 @@
+package application
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/Kilat-Pet-Delivery/service-payment/internal/domain/payment"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+// MaxFeeRecomputeRange bounds how many days of payments one recompute covers.
+const MaxFeeRecomputeRange = 31 * 24 * time.Hour
+
+// ErrInvalidRecomputeRequest is returned when a fee recompute's status or
+// range is invalid.
+var ErrInvalidRecomputeRequest = errors.New("invalid fee recompute request")
+
+// RecomputeFeesRequest selects the payments whose fee split is recomputed.
+// Filter.Status must be pending or held, and From and To are required. Without
+// Apply the recompute is a dry run that only reports the changes.
+type RecomputeFeesRequest struct {
+	Filter PaymentListFilter
+	Apply  bool
+}
+
+// FeeChangeDTO is the fee split of one payment before and after a recompute.
+type FeeChangeDTO struct {
+	PaymentID                 uuid.UUID `json:"payment_id"`
+	BookingID                 uuid.UUID `json:"booking_id"`
+	Status                    string    `json:"status"`
+	Currency                  string    `json:"currency"`
+	AmountCents               int64     `json:"amount_cents"`
+	PreviousPlatformFeeCents  int64     `json:"previous_platform_fee_cents"`
+	PlatformFeeCents          int64     `json:"platform_fee_cents"`
+	PreviousRunnerPayoutCents int64     `json:"previous_runner_payout_cents"`
+	RunnerPayoutCents         int64     `json:"runner_payout_cents"`
+	// Error is set when the split could not be recomputed, e.g. the amount is
+	// below the current floors or an admin overrode the fee, or the change
+	// could not be applied. The payment is then unchanged.
+	Error string `json:"error,omitempty"`
+}
+
+// RecomputeFeesResultDTO summarizes a fee recompute. Changes lists only the
+// payments whose split differs under the current fee or could not be recomputed.
+type RecomputeFeesResultDTO struct {
+	DryRun  bool           `json:"dry_run"`
+	Scanned int            `json:"scanned"`
+	Applied int            `json:"applied"`
+	Changes []FeeChangeDTO `json:"changes"`
+}
+
+// validate checks the request and maps its filter to the repository filter.
+func (r RecomputeFeesRequest) validate() (payment.ListFilter, error) {
+	status := payment.EscrowStatus(r.Filter.Status)
+	if status != payment.EscrowPending && status != payment.EscrowHeld {
+		return payment.ListFilter{}, fmt.Errorf("%w: status must be pending or held", ErrInvalidRecomputeRequest)
+	}
+	if r.Filter.From == nil || r.Filter.To == nil {
+		return payment.ListFilter{}, fmt.Errorf("%w: from and to are required", ErrInvalidRecomputeRequest)
+	}
+	filter, err := r.Filter.toDomain()
+	if err != nil {
+		return payment.ListFilter{}, fmt.Errorf("%w: %v", ErrInvalidRecomputeRequest, err)
+	}
+	if r.Filter.To.Sub(*r.Filter.From) > MaxFeeRecomputeRange {
+		return payment.ListFilter{}, fmt.Errorf("%w: range must not exceed %d days", ErrInvalidRecomputeRequest, int(MaxFeeRecomputeRange.Hours()/24))
+	}
+	return filter, nil
+}
+
+// RecomputeFees recomputes the platform fee and runner payout of pending or
+// held payments created in a range under the currently configured fee for
+// their region, e.g. after finance changed the rate (admin). Payments whose
+// fee an admin overrode are reported with an error and left unchanged. A dry
+// run only returns the changes.
+// When applied, each payment is reloaded and updated with optimistic locking,
+// the change is recorded in its status history under the actor on ctx, and
+// payments that fail are reported with their error while the rest proceed.
+func (s *PaymentService) RecomputeFees(ctx context.Context, req RecomputeFeesRequest) (*RecomputeFeesResultDTO, error) {
+	filter, err := req.validate()
+	if err != nil {
+		return nil, err
+	}
+
+	result := &RecomputeFeesResultDTO{DryRun: !req.Apply, Changes: []FeeChangeDTO{}}
+	// Changes are collected first so no update runs while the stream holds
+	// its connection.
+	err = s.repo.StreamAll(ctx, filter, func(p *payment.Payment) error {
+		result.Scanned++
+		if change, changed := s.recomputeFee(p); changed || change.Error != "" {
+			result.Changes = append(result.Changes, change)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	actor := payment.ActorFromContext(ctx)
+	if req.Apply {
+		for i := range result.Changes {
+			if result.Changes[i].Error != "" {
+				continue
+			}
+			if err := s.applyFeeRecompute(ctx, &result.Changes[i]); err != nil {
+				result.Changes[i].Error = err.Error()
+				s.logger.Warn("failed to apply recomputed fee",
+					zap.String("payment_id", result.Changes[i].PaymentID.String()),
+					zap.String("actor", actor),
+					zap.Error(err),
+				)
+				continue
+			}
+			result.Applied++
+		}
+	}
+
+	s.logger.Info("platform fees recomputed",
+		zap.String("actor", actor),
+		zap.Bool("dry_run", result.DryRun),
+		zap.String("status", req.Filter.Status),
+		zap.Time("from", *req.Filter.From),
+		zap.Time("to", *req.Filter.To),
+		zap.Int("scanned", result.Scanned),
+		zap.Int("changed", len(result.Changes)),
+		zap.Int("applied", result.Applied),
+	)
+	return result, nil
+}
+
+// recomputeFee recomputes p's split in memory and describes the change.
+func (s *PaymentService) recomputeFee(p *payment.Payment) (FeeChangeDTO, bool) {
+	change := FeeChangeDTO{
+		PaymentID:                 p.ID(),
+		BookingID:                 p.BookingID(),
+		Status:                    string(p.EscrowStatus()),
+		Currency:                  p.Currency(),
+		AmountCents:               p.AmountCents(),
+		PreviousPlatformFeeCents:  p.PlatformFeeCents(),
+		PreviousRunnerPayoutCents: p.RunnerPayoutCents(),
+	}
+	changed, err := s.sagaSvc.RecomputeFee(p)
+	if err != nil {
+		change.PlatformFeeCents = change.PreviousPlatformFeeCents
+		change.RunnerPayoutCents = change.PreviousRunnerPayoutCents
+		change.Error = err.Error()
+		return change, false
+	}
+	change.PlatformFeeCents = p.PlatformFeeCents()
+	change.RunnerPayoutCents = p.RunnerPayoutCents()
+	return change, changed
+}
+
+// applyFeeRecompute reloads the payment, recomputes its split and persists it.
+// It fails if the payment changed since the dry run computed change.
+func (s *PaymentService) applyFeeRecompute(ctx context.Context, change *FeeChangeDTO) error {
+	p, err := s.repo.FindByID(ctx, change.PaymentID)
+	if err != nil {
+		return err
+	}
+	if p.PlatformFeeCents() != change.PreviousPlatformFeeCents || string(p.EscrowStatus()) != change.Status {
+		return errors.New("payment changed while the recompute ran")
+	}
+	if _, err := s.sagaSvc.RecomputeFee(p); err != nil {
+		return err
+	}
+	p.IncrementVersion()
+	if err := s.repo.Update(ctx, p); err != nil {
+		return err
+	}
+	change.PlatformFeeCents = p.PlatformFeeCents()
+	change.RunnerPayoutCents = p.RunnerPayoutCents()
+
+	s.logger.Info("platform fee recomputed",
+		zap.String("payment_id", p.ID().String()),
+		zap.String("actor", payment.ActorFromContext(ctx)),
+		zap.Int64("previous_fee_cents", change.PreviousPlatformFeeCents),
+		zap.Int64("platform_fee_cents", p.PlatformFeeCents()),
+		zap.Int64("previous_runner_payout_cents", change.PreviousRunnerPayoutCents),
+		zap.Int64("runner_payout_cents", p.RunnerPayoutCents()),
+	)
+	return nil
+}
@@ -0,0 +1,205 @@
+package application
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/Kilat-Pet-Delivery/lib-common/domain"
+	"github.com/Kilat-Pet-Delivery/service-payment/internal/domain/payment"
+	"github.com/Kilat-Pet-Delivery/service-payment/internal/saga"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+// rowPaymentRepo hands out fresh copies of stored payments, as loading from
+// the database would, and counts updates.
+type rowPaymentRepo struct {
+	payment.PaymentRepository
+	rows    []*payment.Payment
+	updates int
+}
+
+func copyPayment(p *payment.Payment) *payment.Payment {
+	return payment.Reconstitute(p.ID(), p.BookingID(), p.OwnerID(), p.RunnerID(), p.EscrowStatus(),
+		p.Amount(), p.PlatformFee(), p.RunnerPayout(), p.CreditAppliedCents(), p.PaymentMethod(), p.StripePaymentID(),
+		p.EscrowHeldAt(), p.EscrowReleasedAt(), p.RefundedAt(), p.ReleaseEligibleAt(),
+		p.RefundReason(), p.CallbackURL(), p.CustomerEmail(), p.RunnerTransferID(), p.ReviewReason(), p.FeeRegion(),
+		p.AwaitingAuthentication(), p.IsTest(), p.FeeOverridden(), p.Version(), p.CreatedAt(), p.UpdatedAt())
+}
+
+func (r *rowPaymentRepo) StreamAll(_ context.Context, filter payment.ListFilter, fn func(*payment.Payment) error) error {
+	for _, p := range r.rows {
+		if p.EscrowStatus() != filter.Status || p.CreatedAt().Before(*filter.From) || !p.CreatedAt().Before(*filter.To) {
+			continue
+		}
+		if err := fn(copyPayment(p)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *rowPaymentRepo) FindByID(_ context.Context, id uuid.UUID) (*payment.Payment, error) {
+	for _, p := range r.rows {
+		if p.ID() == id {
+			return copyPayment(p), nil
+		}
+	}
+	return nil, domain.NewNotFoundError("Payment", id.String())
+}
+
+func (r *rowPaymentRepo) Update(_ context.Context, p *payment.Payment) error {
+	for i, row := range r.rows {
+		if row.ID() == p.ID() {
+			r.rows[i] = p
+			r.updates++
+			return nil
+		}
+	}
+	return domain.NewNotFoundError("Payment", p.ID().String())
+}
+
+// newFeeRecomputeService prices payments at feePercent with a 5.00 minimum
+// platform fee.
+func newFeeRecomputeService(repo payment.PaymentRepository, feePercent float64) *PaymentService {
+	floors := payment.PayoutFloors{MinPlatformFeeCents: 500}
+	sagaSvc := saga.NewPaymentSagaService(repo, nil, nil, nil, nil, nil, nil, nil, feePercent, floors, 0, 0, zap.NewNop())
+	return NewPaymentService(repo, nil, nil, nil, sagaSvc, nil, nil, nil, zap.NewNop())
+}
+
+func TestRecomputeFees_DryRunReportsDiffWithoutUpdating(t *testing.T) {
+	newPayment := func(amountCents int64, currency string, hold bool) *payment.Payment {
+		p, err := payment.NewPayment(uuid.New(), uuid.New(), amountCents, currency, 15.0, payment.PayoutFloors{})
+		require.NoError(t, err)
+		if hold {
+			require.NoError(t, p.HoldEscrow("pi_"+uuid.NewString(), 0))
+		}
+		return p
+	}
+	myr := newPayment(10000, "MYR", true) // fee 15.00 -> 10.00
+	jpy := newPayment(10000, "JPY", true) // fee 1500 yen -> 1000 yen
+	// 15% of 30.00 was raised to the 5.00 floor, which 10% does not reach either.
+	floored, err := payment.NewPayment(uuid.New(), uuid.New(), 3000, "MYR", 15.0, payment.PayoutFloors{MinPlatformFeeCents: 500})
+	require.NoError(t, err)
+	require.NoError(t, floored.HoldEscrow("pi_floored", 0))
+	pending := newPayment(20000, "MYR", false)
+	repo := &rowPaymentRepo{rows: []*payment.Payment{myr, jpy, floored, pending}}
+	svc := newFeeRecomputeService(repo, 10.0)
+
+	from, to := time.Now().Add(-time.Hour), time.Now().Add(time.Hour)
+	result, err := svc.RecomputeFees(context.Background(), RecomputeFeesRequest{
+		Filter: PaymentListFilter{Status: string(payment.EscrowHeld), From: &from, To: &to},
+	})
+	require.NoError(t, err)
+
+	assert.True(t, result.DryRun)
+	assert.Equal(t, 3, result.Scanned, "only held payments in range")
+	assert.Zero(t, result.Applied)
+	require.Len(t, result.Changes, 2, "a split kept by the fee floor is not a change")
+	assert.Equal(t, FeeChangeDTO{
+		PaymentID: myr.ID(), BookingID: myr.BookingID(), Status: "held", Currency: "MYR", AmountCents: 10000,
+		PreviousPlatformFeeCents: 1500, PlatformFeeCents: 1000,
+		PreviousRunnerPayoutCents: 8500, RunnerPayoutCents: 9000,
+	}, result.Changes[0])
+	assert.Equal(t, int64(1500), result.Changes[1].PreviousPlatformFeeCents)
+	assert.Equal(t, int64(1000), result.Changes[1].PlatformFeeCents)
+	assert.Equal(t, int64(9000), result.Changes[1].RunnerPayoutCents)
+
+	assert.Zero(t, repo.updates)
+	stored, err := repo.FindByID(context.Background(), myr.ID())
+	require.NoError(t, err)
+	assert.Equal(t, int64(1500), stored.PlatformFeeCents(), "a dry run leaves payments unchanged")
+}
+
+func TestRecomputeFees_ApplyUpdatesAndRecordsHistory(t *testing.T) {
+	p, err := payment.NewPayment(uuid.New(), uuid.New(), 10000, "MYR", 15.0, payment.PayoutFloors{})
+	require.NoError(t, err)
+	require.NoError(t, p.HoldEscrow("pi_apply", 0))
+	p.ClearStatusChanges()
+	repo := &rowPaymentRepo{rows: []*payment.Payment{p}}
+	svc := newFeeRecomputeService(repo, 12.0)
+
+	from, to := time.Now().Add(-time.Hour), time.Now().Add(time.Hour)
+	ctx := payment.WithActor(context.Background(), "admin:finance")
+	result, err := svc.RecomputeFees(ctx, RecomputeFeesRequest{
+		Filter: PaymentListFilter{Status: string(payment.EscrowHeld), From: &from, To: &to},
+		Apply:  true,
+	})
+	require.NoError(t, err)
+
+	assert.False(t, result.DryRun)
+	assert.Equal(t, 1, result.Applied)
+	assert.Equal(t, 1, repo.updates)
+	stored := repo.rows[0]
+	assert.Equal(t, int64(1200), stored.PlatformFeeCents())
+	assert.Equal(t, int64(8800), stored.RunnerPayoutCents())
+	require.Len(t, stored.StatusChanges(), 1)
+	assert.Equal(t, "platform fee recomputed from 15.00 MYR to 12.00 MYR at 12%", stored.StatusChanges()[0].Reason)
+}
+
+// regionFees resolves the fee of the regions it lists.
+type regionFees map[string]float64
+
+func (f regionFees) ResolveFeePercent(region, _ string, _ time.Time) (float64, bool) {
+	pct, ok := f[region]
+	return pct, ok
+}
+
+func TestRecomputeFees_KeepsOverriddenAndRegionalFees(t *testing.T) {
+	newHeld := func(feePercent float64, region string) *payment.Payment {
+		p, err := payment.NewPayment(uuid.New(), uuid.New(), 10000, "MYR", feePercent, payment.PayoutFloors{})
+		require.NoError(t, err)
+		require.NoError(t, p.SetFeeRegion(region))
+		require.NoError(t, p.HoldEscrow("pi_"+uuid.NewString(), 0))
+		return p
+	}
+	standard := newHeld(15.0, "")
+	overridden := newHeld(15.0, "")
+	require.NoError(t, overridden.OverrideFee(500))
+	regional := newHeld(20.0, "SG")
+	repo := &rowPaymentRepo{rows: []*payment.Payment{standard, overridden, regional}}
+	sagaSvc := saga.NewPaymentSagaService(repo, nil, nil, regionFees{"SG": 20.0}, nil, nil, nil, nil, 10.0, payment.PayoutFloors{}, 0, 0, zap.NewNop())
+	svc := NewPaymentService(repo, nil, nil, nil, sagaSvc, nil, nil, nil, zap.NewNop())
+
+	from, to := time.Now().Add(-time.Hour), time.Now().Add(time.Hour)
+	for _, apply := range []bool{false, true} {
+		result, err := svc.RecomputeFees(context.Background(), RecomputeFeesRequest{
+			Filter: PaymentListFilter{Status: string(payment.EscrowHeld), From: &from, To: &to},
+			Apply:  apply,
+		})
+		require.NoError(t, err)
+
+		require.Len(t, result.Changes, 2, "the regional fee is still its region's fee")
+		assert.Equal(t, standard.ID(), result.Changes[0].PaymentID)
+		assert.Equal(t, int64(1000), result.Changes[0].PlatformFeeCents)
+		assert.Equal(t, overridden.ID(), result.Changes[1].PaymentID)
+		assert.Contains(t, result.Changes[1].Error, payment.ErrFeeOverridden.Error(), "apply=%v", apply)
+		assert.Equal(t, int64(500), result.Changes[1].PlatformFeeCents)
+	}
+
+	assert.Equal(t, 1, repo.updates, "only the standard payment is updated")
+	assert.Equal(t, int64(500), repo.rows[1].PlatformFeeCents())
+	assert.Equal(t, int64(2000), repo.rows[2].PlatformFeeCents())
+}
+
+func TestRecomputeFees_RejectsInvalidRequests(t *testing.T) {
+	svc := newFeeRecomputeService(&rowPaymentRepo{}, 10.0)
+	from := time.Now().Add(-time.Hour)
+	to := from.Add(2 * time.Hour)
+	tooLate := from.Add(MaxFeeRecomputeRange + time.Hour)
+
+	for name, filter := range map[string]PaymentListFilter{
+		"released payments": {Status: string(payment.EscrowReleased), From: &from, To: &to},
+		"no status":         {From: &from, To: &to},
+		"no range":          {Status: string(payment.EscrowHeld)},
+		"range too long":    {Status: string(payment.EscrowHeld), From: &from, To: &tooLate},
+	} {
+		t.Run(name, func(t *testing.T) {
+			_, err := svc.RecomputeFees(context.Background(), RecomputeFeesRequest{Filter: filter})
+			assert.ErrorIs(t, err, ErrInvalidRecomputeRequest)
+		})
+	}
+}
@@ -0,0 +1,299 @@
+package application
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	feeDomain "github.com/Kilat-Pet-Delivery/service-payment/internal/domain/fee"
+	"github.com/Kilat-Pet-Delivery/service-payment/internal/domain/payment"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+// FeeScheduleRequest holds data to create or update a fee schedule.
+type FeeScheduleRequest struct {
+	Region        string   `json:"region"`
+	Currency      string   `json:"currency" binding:"required"`
+	FeePercent    *float64 `json:"fee_percent" binding:"required,gte=0,lte=100"`
+	EffectiveFrom string   `json:"effective_from" binding:"required"`
+}
+
+// FeeScheduleDTO is the API response representation of a fee schedule.
+type FeeScheduleDTO struct {
+	ID            uuid.UUID `json:"id"`
+	Region        string    `json:"region"`
+	Currency      string    `json:"currency"`
+	FeePercent    float64   `json:"fee_percent"`
+	EffectiveFrom time.Time `json:"effective_from"`
+	CreatedAt     time.Time `json:"created_at"`
+	UpdatedAt     time.Time `json:"updated_at"`
+}
+
+// FeeOverrideDTO is a fee schedule currently in effect for a currency, or for
+// a region within it.
+type FeeOverrideDTO struct {
+	FeeScheduleID uuid.UUID `json:"fee_schedule_id"`
+	Region        string    `json:"region,omitempty"`
+	Currency      string    `json:"currency"`
+	FeePercent    float64   `json:"fee_percent"`
+	EffectiveFrom time.Time `json:"effective_from"`
+}
+
+// FeeConfigDTO is the platform fee configuration payments are charged with
+// right now: the configured default, the payout floors and the fee schedules
+// that override the default.
+type FeeConfigDTO struct {
+	DefaultFeePercent    float64          `json:"default_fee_percent"`
+	MinPlatformFeeCents  int64            `json:"min_platform_fee_cents"`
+	MinRunnerPayoutCents int64            `json:"min_runner_payout_cents"`
+	CurrencyOverrides    []FeeOverrideDTO `json:"currency_overrides"`
+	RegionOverrides      []FeeOverrideDTO `json:"region_overrides"`
+}
+
+// FeeScheduleCache is an in-memory, periodically refreshed view of the fee
+// schedules. It implements saga.FeeResolver.
+type FeeScheduleCache struct {
+	repo     feeDomain.FeeScheduleRepository
+	interval time.Duration
+	logger   *zap.Logger
+
+	mu        sync.RWMutex
+	schedules []*feeDomain.FeeSchedule
+}
+
+// NewFeeScheduleCache creates a new FeeScheduleCache.
+func NewFeeScheduleCache(repo feeDomain.FeeScheduleRepository, interval time.Duration, logger *zap.Logger) *FeeScheduleCache {
+	return &FeeScheduleCache{repo: repo, interval: interval, logger: logger}
+}
+
+// Refresh reloads all fee schedules from the repository.
+func (c *FeeScheduleCache) Refresh(ctx context.Context) error {
+	schedules, err := c.repo.FindAll(ctx)
+	if err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	c.schedules = schedules
+	c.mu.Unlock()
+	return nil
+}
+
+// Start refreshes the cache every interval. It blocks until the context is cancelled.
+func (c *FeeScheduleCache) Start(ctx context.Context) {
+	ticker := time.NewTicker(c.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := c.Refresh(ctx); err != nil && ctx.Err() == nil {
+				c.logger.Error("failed to refresh fee schedules", zap.Error(err))
+			}
+		}
+	}
+}
+
+// ResolveFeePercent returns the fee of the most recently effective schedule for
+// the region/currency. A region-specific schedule wins over the currency default.
+func (c *FeeScheduleCache) ResolveFeePercent(region, currency string, at time.Time) (float64, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	var best *feeDomain.FeeSchedule
+	for _, f := range c.schedules {
+		if !f.AppliesTo(region, currency, at) {
+			continue
+		}
+		switch {
+		case best == nil:
+			best = f
+		case (f.Region() != "") != (best.Region() != ""):
+			if f.Region() != "" {
+				best = f
+			}
+		case f.EffectiveFrom().After(best.EffectiveFrom()):
+			best = f
+		}
+	}
+
+	if best == nil {
+		return 0, false
+	}
+	return best.FeePercent(), true
+}
+
+// Effective returns, for each region/currency pair, the schedule in effect at
+// the given time, ordered by currency and then region. ResolveFeePercent picks
+// from these.
+func (c *FeeScheduleCache) Effective(at time.Time) []*feeDomain.FeeSchedule {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	type key struct{ region, currency string }
+	latest := make(map[key]*feeDomain.FeeSchedule)
+	for _, f := range c.schedules {
+		if at.Before(f.EffectiveFrom()) {
+			continue
+		}
+		k := key{f.Region(), f.Currency()}
+		if cur, ok := latest[k]; !ok || f.EffectiveFrom().After(cur.EffectiveFrom()) {
+			latest[k] = f
+		}
+	}
+
+	effective := make([]*feeDomain.FeeSchedule, 0, len(latest))
+	for _, f := range latest {
+		effective = append(effective, f)
+	}
+	sort.Slice(effective, func(i, j int) bool {
+		if effective[i].Currency() != effective[j].Currency() {
+			return effective[i].Currency() < effective[j].Currency()
+		}
+		return effective[i].Region() < effective[j].Region()
+	})
+	return effective
+}
+
+// FeeScheduleService handles fee schedule administration use cases.
+type FeeScheduleService struct {
+	repo              feeDomain.FeeScheduleRepository
+	cache             *FeeScheduleCache
+	currencies        CurrencyAllowlist
+	defaultFeePercent float64
+	floors            payment.PayoutFloors
+	logger            *zap.Logger
+}
+
+// NewFeeScheduleService creates a new FeeScheduleService.
+// currencies may be nil to accept any currency. defaultFeePercent and floors
+// are the values payments use when no schedule applies; they are only reported.
+func NewFeeScheduleService(repo feeDomain.FeeScheduleRepository, cache *FeeScheduleCache, currencies CurrencyAllowlist, defaultFeePercent float64, floors payment.PayoutFloors, logger *zap.Logger) *FeeScheduleService {
+	return &FeeScheduleService{
+		repo: repo, cache: cache, currencies: currencies,
+		defaultFeePercent: defaultFeePercent, floors: floors, logger: logger,
+	}
+}
+
+// GetFeeConfig returns the fee configuration in effect now, as seen by the
+// fee schedule cache new payments are priced from.
+func (s *FeeScheduleService) GetFeeConfig() *FeeConfigDTO {
+	config := &FeeConfigDTO{
+		DefaultFeePercent:    s.defaultFeePercent,
+		MinPlatformFeeCents:  s.floors.MinPlatformFeeCents,
+		MinRunnerPayoutCents: s.floors.MinRunnerPayoutCents,
+		CurrencyOverrides:    []FeeOverrideDTO{},
+		RegionOverrides:      []FeeOverrideDTO{},
+	}
+	for _, f := range s.cache.Effective(time.Now().UTC()) {
+		override := FeeOverrideDTO{
+			FeeScheduleID: f.ID(),
+			Region:        f.Region(),
+			Currency:      f.Currency(),
+			FeePercent:    f.FeePercent(),
+			EffectiveFrom: f.EffectiveFrom(),
+		}
+		if f.Region() == "" {
+			config.CurrencyOverrides = append(config.CurrencyOverrides, override)
+		} else {
+			config.RegionOverrides = append(config.RegionOverrides, override)
+		}
+	}
+	return config
+}
+
+// ListFeeSchedules returns all fee schedules.
+func (s *FeeScheduleService) ListFeeSchedules(ctx context.Context) ([]*FeeScheduleDTO, error) {
+	schedules, err := s.repo.FindAll(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	dtos := make([]*FeeScheduleDTO, len(schedules))
+	for i, f := range schedules {
+		dtos[i] = toFeeScheduleDTO(f)
+	}
+	return dtos, nil
+}
+
+// CreateFeeSchedule creates a new fee schedule (admin only).
+func (s *FeeScheduleService) CreateFeeSchedule(ctx context.Context, req FeeScheduleRequest) (*FeeScheduleDTO, error) {
+	effectiveFrom, err := time.Parse(time.RFC3339, req.EffectiveFrom)
+	if err != nil {
+		return nil, fmt.Errorf("invalid effective_from format (use RFC3339)")
+	}
+
+	currency, err := s.currencies.Normalize(req.Currency)
+	if err != nil {
+		return nil, err
+	}
+
+	schedule, err := feeDomain.NewFeeSchedule(req.Region, currency, *req.FeePercent, effectiveFrom)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.repo.Save(ctx, schedule); err != nil {
+		return nil, fmt.Errorf("failed to save fee schedule: %w", err)
+	}
+
+	s.logger.Info("fee schedule created",
+		zap.String("region", schedule.Region()),
+		zap.String("currency", schedule.Currency()),
+		zap.Float64("fee_percent", schedule.FeePercent()),
+	)
+	s.refreshCache(ctx)
+	return toFeeScheduleDTO(schedule), nil
+}
+
+// UpdateFeeSchedule changes the fee and effective date of an existing schedule (admin only).
+func (s *FeeScheduleService) UpdateFeeSchedule(ctx context.Context, id uuid.UUID, req FeeScheduleRequest) (*FeeScheduleDTO, error) {
+	effectiveFrom, err := time.Parse(time.RFC3339, req.EffectiveFrom)
+	if err != nil {
+		return nil, fmt.Errorf("invalid effective_from format (use RFC3339)")
+	}
+
+	schedule, err := s.repo.FindByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := schedule.Change(*req.FeePercent, effectiveFrom); err != nil {
+		return nil, err
+	}
+
+	if err := s.repo.Update(ctx, schedule); err != nil {
+		return nil, fmt.Errorf("failed to update fee schedule: %w", err)
+	}
+
+	s.logger.Info("fee schedule updated",
+		zap.String("fee_schedule_id", id.String()),
+		zap.Float64("fee_percent", schedule.FeePercent()),
+	)
+	s.refreshCache(ctx)
+	return toFeeScheduleDTO(schedule), nil
+}
+
+// refreshCache reloads the cache so admin changes take effect without waiting for the next tick.
+func (s *FeeScheduleService) refreshCache(ctx context.Context) {
+	if err := s.cache.Refresh(ctx); err != nil {
+		s.logger.Warn("failed to refresh fee schedule cache after write", zap.Error(err))
+	}
+}
+
+func toFeeScheduleDTO(f *feeDomain.FeeSchedule) *FeeScheduleDTO {
+	return &FeeScheduleDTO{
+		ID:            f.ID(),
+		Region:        f.Region(),
+		Currency:      f.Currency(),
+		FeePercent:    f.FeePercent(),
+		EffectiveFrom: f.EffectiveFrom(),
+		CreatedAt:     f.CreatedAt(),
+		UpdatedAt:     f.UpdatedAt(),
+	}
+}
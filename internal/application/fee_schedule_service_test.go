@@ -0,0 +1,72 @@
+package application
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	feeDomain "github.com/Kilat-Pet-Delivery/service-payment/internal/domain/fee"
+	"github.com/Kilat-Pet-Delivery/service-payment/internal/domain/payment"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+// staticFeeScheduleRepo serves FindAll from a fixed list.
+type staticFeeScheduleRepo struct {
+	feeDomain.FeeScheduleRepository
+	schedules []*feeDomain.FeeSchedule
+}
+
+func (r *staticFeeScheduleRepo) FindAll(context.Context) ([]*feeDomain.FeeSchedule, error) {
+	return r.schedules, nil
+}
+
+func TestGetFeeConfig_ReflectsEffectiveSchedules(t *testing.T) {
+	now := time.Now().UTC()
+	newSchedule := func(region, currency string, pct float64, from time.Time) *feeDomain.FeeSchedule {
+		f, err := feeDomain.NewFeeSchedule(region, currency, pct, from)
+		require.NoError(t, err)
+		return f
+	}
+	superseded := newSchedule("", "MYR", 12, now.Add(-48*time.Hour))
+	myr := newSchedule("", "MYR", 10, now.Add(-time.Hour))
+	upcoming := newSchedule("", "MYR", 8, now.Add(time.Hour))
+	sgd := newSchedule("", "SGD", 14, now.Add(-time.Hour))
+	klang := newSchedule("klang", "MYR", 9.5, now.Add(-time.Hour))
+
+	cache := NewFeeScheduleCache(&staticFeeScheduleRepo{schedules: []*feeDomain.FeeSchedule{superseded, myr, upcoming, sgd, klang}}, time.Minute, zap.NewNop())
+	require.NoError(t, cache.Refresh(context.Background()))
+	floors := payment.PayoutFloors{MinRunnerPayoutCents: 500, MinPlatformFeeCents: 100}
+	svc := NewFeeScheduleService(nil, cache, nil, 15, floors, zap.NewNop())
+
+	config := svc.GetFeeConfig()
+
+	assert.Equal(t, 15.0, config.DefaultFeePercent)
+	assert.Equal(t, int64(100), config.MinPlatformFeeCents)
+	assert.Equal(t, int64(500), config.MinRunnerPayoutCents)
+	require.Len(t, config.CurrencyOverrides, 2)
+	assert.Equal(t, FeeOverrideDTO{FeeScheduleID: myr.ID(), Currency: "MYR", FeePercent: 10, EffectiveFrom: myr.EffectiveFrom()}, config.CurrencyOverrides[0])
+	assert.Equal(t, sgd.ID(), config.CurrencyOverrides[1].FeeScheduleID)
+	require.Len(t, config.RegionOverrides, 1)
+	assert.Equal(t, FeeOverrideDTO{FeeScheduleID: klang.ID(), Region: "KLANG", Currency: "MYR", FeePercent: 9.5, EffectiveFrom: klang.EffectiveFrom()}, config.RegionOverrides[0])
+
+	for _, o := range append(config.CurrencyOverrides, config.RegionOverrides...) {
+		pct, ok := cache.ResolveFeePercent(o.Region, o.Currency, now)
+		require.True(t, ok)
+		assert.Equal(t, o.FeePercent, pct, "reported override matches the fee payments are charged")
+	}
+}
+
+func TestGetFeeConfig_NoSchedules(t *testing.T) {
+	cache := NewFeeScheduleCache(&staticFeeScheduleRepo{}, time.Minute, zap.NewNop())
+	svc := NewFeeScheduleService(nil, cache, nil, 15, payment.PayoutFloors{}, zap.NewNop())
+
+	config := svc.GetFeeConfig()
+
+	assert.Equal(t, 15.0, config.DefaultFeePercent)
+	assert.NotNil(t, config.CurrencyOverrides)
+	assert.Empty(t, config.CurrencyOverrides)
+	assert.NotNil(t, config.RegionOverrides)
+	assert.Empty(t, config.RegionOverrides)
+}
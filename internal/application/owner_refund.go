@@ -0,0 +1,132 @@
+package application
+
+import (
+	"context"
+
+	"github.com/Kilat-Pet-Delivery/service-payment/internal/domain/payment"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+// OwnerRefundItemDTO is the outcome of refunding one of an owner's held payments.
+type OwnerRefundItemDTO struct {
+	PaymentID   uuid.UUID `json:"payment_id"`
+	BookingID   uuid.UUID `json:"booking_id"`
+	AmountCents int64     `json:"amount_cents"`
+	Currency    string    `json:"currency"`
+	// Outcome is RefundOutcomeRefunded, RefundOutcomePendingApproval or
+	// RefundOutcomeFailed.
+	Outcome string `json:"outcome"`
+	// RefundRequestID is set when the refund awaits a second admin's approval.
+	RefundRequestID *uuid.UUID `json:"refund_request_id,omitempty"`
+	// Error is set when the refund failed. The payment is then still held.
+	Error string `json:"error,omitempty"`
+}
+
+// OwnerRefundResultDTO summarizes refunding all of an owner's held payments.
+type OwnerRefundResultDTO struct {
+	OwnerID         uuid.UUID            `json:"owner_id"`
+	Reason          string               `json:"reason"`
+	Refunded        int                  `json:"refunded"`
+	PendingApproval int                  `json:"pending_approval"`
+	Failed          int                  `json:"failed"`
+	Payments        []OwnerRefundItemDTO `json:"payments"`
+}
+
+// RefundOwnerPayments refunds every held payment of ownerID, e.g. to clean up
+// after a fraudulent owner (admin). Payments are refunded one at a time as by
+// RequestRefund, so those at or above the approval threshold get a pending
+// request instead. A failed refund is reported with its error while the rest
+// proceed. Each refund is recorded in the payment's status history under the
+// actor on ctx, and the batch and every outcome are logged.
+func (s *PaymentService) RefundOwnerPayments(ctx context.Context, ownerID, requestedBy uuid.UUID, req RefundRequest) (*OwnerRefundResultDTO, error) {
+	reason, err := req.normalize()
+	if err != nil {
+		return nil, err
+	}
+	method, err := req.refundMethod()
+	if err != nil {
+		return nil, err
+	}
+
+	result := &OwnerRefundResultDTO{OwnerID: ownerID, Reason: reason, Payments: []OwnerRefundItemDTO{}}
+	// Payments are collected first so no refund runs while the stream holds
+	// its connection.
+	filter := payment.ListFilter{Status: payment.EscrowHeld, OwnerID: &ownerID, IncludeTest: true}
+	err = s.repo.StreamAll(ctx, filter, func(p *payment.Payment) error {
+		result.Payments = append(result.Payments, OwnerRefundItemDTO{
+			PaymentID:   p.ID(),
+			BookingID:   p.BookingID(),
+			AmountCents: p.AmountCents(),
+			Currency:    p.Currency(),
+		})
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	actor := payment.ActorFromContext(ctx)
+	s.logger.Info("refunding all held payments of owner",
+		zap.String("owner_id", ownerID.String()),
+		zap.String("actor", actor),
+		zap.String("reason", reason),
+		zap.String("method", string(method)),
+		zap.Int("payments", len(result.Payments)),
+	)
+
+	for i := range result.Payments {
+		item := &result.Payments[i]
+		if err := s.refundOwnerPayment(ctx, item, requestedBy, reason, method); err != nil {
+			item.Outcome = RefundOutcomeFailed
+			item.Error = err.Error()
+			result.Failed++
+			s.logger.Warn("failed to refund owner payment",
+				zap.String("owner_id", ownerID.String()),
+				zap.String("payment_id", item.PaymentID.String()),
+				zap.String("actor", actor),
+				zap.Error(err),
+			)
+			continue
+		}
+		if item.Outcome == RefundOutcomePendingApproval {
+			result.PendingApproval++
+		} else {
+			result.Refunded++
+		}
+		s.logger.Info("owner payment refunded",
+			zap.String("owner_id", ownerID.String()),
+			zap.String("payment_id", item.PaymentID.String()),
+			zap.String("actor", actor),
+			zap.String("outcome", item.Outcome),
+			zap.Int64("amount_cents", item.AmountCents),
+			zap.String("currency", item.Currency),
+		)
+	}
+
+	s.logger.Info("owner refund finished",
+		zap.String("owner_id", ownerID.String()),
+		zap.String("actor", actor),
+		zap.Int("refunded", result.Refunded),
+		zap.Int("pending_approval", result.PendingApproval),
+		zap.Int("failed", result.Failed),
+	)
+	return result, nil
+}
+
+// refundOwnerPayment refunds one payment of a RefundOwnerPayments batch and
+// records the outcome on item.
+func (s *PaymentService) refundOwnerPayment(ctx context.Context, item *OwnerRefundItemDTO, requestedBy uuid.UUID, reason string, method payment.RefundMethod) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	outcome, err := s.requestRefund(ctx, item.PaymentID, requestedBy, reason, method)
+	if err != nil {
+		return err
+	}
+	item.Outcome = outcome.Outcome
+	if outcome.Request != nil {
+		item.RefundRequestID = &outcome.Request.ID
+	}
+	return nil
+}
@@ -0,0 +1,116 @@
+package application
+
+import (
+	"context"
+	"testing"
+
+	"github.com/Kilat-Pet-Delivery/service-payment/internal/adapter"
+	"github.com/Kilat-Pet-Delivery/service-payment/internal/domain/payment"
+	refundDomain "github.com/Kilat-Pet-Delivery/service-payment/internal/domain/refund"
+	"github.com/Kilat-Pet-Delivery/service-payment/internal/saga"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+// orderedPaymentRepo is a memoryPaymentRepo that streams its payments in the
+// order they were added, applying the status and owner filters.
+type orderedPaymentRepo struct {
+	memoryPaymentRepo
+	order []uuid.UUID
+}
+
+func (r *orderedPaymentRepo) add(p *payment.Payment) {
+	r.payments[p.ID()] = p
+	r.order = append(r.order, p.ID())
+}
+
+func (r *orderedPaymentRepo) StreamAll(_ context.Context, filter payment.ListFilter, fn func(*payment.Payment) error) error {
+	for _, id := range r.order {
+		p := r.payments[id]
+		if filter.Status != "" && p.EscrowStatus() != filter.Status {
+			continue
+		}
+		if filter.OwnerID != nil && p.OwnerID() != *filter.OwnerID {
+			continue
+		}
+		if err := fn(p); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func TestRefundOwnerPayments_RefundsOnlyHeldAndIsolatesFailures(t *testing.T) {
+	repo := &orderedPaymentRepo{memoryPaymentRepo: memoryPaymentRepo{payments: map[uuid.UUID]*payment.Payment{}}}
+	ownerID := uuid.New()
+	newPayment := func(owner uuid.UUID, amount, creditCents int64, status payment.EscrowStatus) *payment.Payment {
+		p, err := payment.NewPayment(uuid.New(), owner, amount, "MYR", 15.0, payment.PayoutFloors{})
+		require.NoError(t, err)
+		if creditCents > 0 {
+			require.NoError(t, p.ApplyCredit(creditCents))
+		}
+		if status != payment.EscrowPending {
+			require.NoError(t, p.HoldEscrow("pi_"+p.ID().String(), 0))
+		}
+		if status == payment.EscrowReleased {
+			require.NoError(t, p.ReleaseToRunner(uuid.New()))
+		}
+		repo.add(p)
+		return p
+	}
+	small := newPayment(ownerID, 5000, 0, payment.EscrowHeld)
+	// Credit cannot be returned without a credit service, so this refund fails.
+	withCredit := newPayment(ownerID, 6000, 1000, payment.EscrowHeld)
+	large := newPayment(ownerID, 25000, 0, payment.EscrowHeld)
+	released := newPayment(ownerID, 7000, 0, payment.EscrowReleased)
+	pending := newPayment(ownerID, 8000, 0, payment.EscrowPending)
+	otherOwners := newPayment(uuid.New(), 4000, 0, payment.EscrowHeld)
+
+	sagaSvc := saga.NewPaymentSagaService(repo, adapter.NewMockStripeAdapter(zap.NewNop()), &recordingPublisher{}, nil, nil, nil, nil, nil, 15.0, payment.PayoutFloors{}, 0, 0, zap.NewNop())
+	svc := NewPaymentService(repo, nil, nil, nil, sagaSvc, nil, nil, nil, zap.NewNop())
+	requests := &memoryRefundRequestRepo{requests: map[uuid.UUID]*refundDomain.Request{}}
+	svc.SetRefundApprovals(requests, 10000)
+
+	admin := uuid.New()
+	ctx := payment.WithActor(context.Background(), "admin:"+admin.String())
+	result, err := svc.RefundOwnerPayments(ctx, ownerID, admin, RefundRequest{ReasonCode: "fraudulent", Reason: "fraudulent owner"})
+	require.NoError(t, err)
+
+	assert.Equal(t, ownerID, result.OwnerID)
+	assert.Equal(t, "fraudulent: fraudulent owner", result.Reason)
+	assert.Equal(t, 1, result.Refunded)
+	assert.Equal(t, 1, result.PendingApproval)
+	assert.Equal(t, 1, result.Failed)
+	require.Len(t, result.Payments, 3, "only the owner's held payments are part of the batch")
+
+	assert.Equal(t, small.ID(), result.Payments[0].PaymentID)
+	assert.Equal(t, RefundOutcomeRefunded, result.Payments[0].Outcome)
+	assert.Empty(t, result.Payments[0].Error)
+
+	assert.Equal(t, withCredit.ID(), result.Payments[1].PaymentID)
+	assert.Equal(t, RefundOutcomeFailed, result.Payments[1].Outcome)
+	assert.Contains(t, result.Payments[1].Error, "credit is not available")
+
+	assert.Equal(t, large.ID(), result.Payments[2].PaymentID, "a failure does not stop the batch")
+	assert.Equal(t, RefundOutcomePendingApproval, result.Payments[2].Outcome)
+	require.NotNil(t, result.Payments[2].RefundRequestID)
+	assert.Equal(t, admin, requests.requests[*result.Payments[2].RefundRequestID].RequestedBy)
+
+	assert.Equal(t, payment.EscrowRefunded, small.EscrowStatus())
+	assert.Equal(t, "fraudulent: fraudulent owner", small.RefundReason())
+	assert.Equal(t, payment.EscrowHeld, withCredit.EscrowStatus())
+	assert.Equal(t, payment.EscrowHeld, large.EscrowStatus(), "large refunds still need a second admin")
+	assert.Equal(t, payment.EscrowReleased, released.EscrowStatus())
+	assert.Equal(t, payment.EscrowPending, pending.EscrowStatus())
+	assert.Equal(t, payment.EscrowHeld, otherOwners.EscrowStatus())
+}
+
+func TestRefundOwnerPayments_RejectsBlankReason(t *testing.T) {
+	repo := &orderedPaymentRepo{memoryPaymentRepo: memoryPaymentRepo{payments: map[uuid.UUID]*payment.Payment{}}}
+	svc := NewPaymentService(repo, nil, nil, nil, nil, nil, nil, nil, zap.NewNop())
+
+	_, err := svc.RefundOwnerPayments(context.Background(), uuid.New(), uuid.New(), RefundRequest{Reason: "  "})
+	assert.ErrorIs(t, err, ErrInvalidRefundReason)
+}
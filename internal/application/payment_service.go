@@ -2,25 +2,105 @@ package application
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
 	"time"
+	"unicode/utf8"
 
-	"github.com/Kilat-Pet-Delivery/lib-proto/events"
 	"github.com/Kilat-Pet-Delivery/lib-common/domain"
+	"github.com/Kilat-Pet-Delivery/lib-common/kafka"
+	"github.com/Kilat-Pet-Delivery/lib-proto/events"
+	"github.com/Kilat-Pet-Delivery/service-payment/internal/adapter"
+	bookingDomain "github.com/Kilat-Pet-Delivery/service-payment/internal/domain/booking"
+	"github.com/Kilat-Pet-Delivery/service-payment/internal/domain/callback"
+	creditDomain "github.com/Kilat-Pet-Delivery/service-payment/internal/domain/credit"
 	"github.com/Kilat-Pet-Delivery/service-payment/internal/domain/payment"
+	promoDomain "github.com/Kilat-Pet-Delivery/service-payment/internal/domain/promo"
+	refundDomain "github.com/Kilat-Pet-Delivery/service-payment/internal/domain/refund"
+	subDomain "github.com/Kilat-Pet-Delivery/service-payment/internal/domain/subscription"
+	"github.com/Kilat-Pet-Delivery/service-payment/internal/feature"
 	"github.com/Kilat-Pet-Delivery/service-payment/internal/saga"
 	"github.com/google/uuid"
 	"go.uber.org/zap"
 )
 
 // InitiatePaymentRequest is the DTO for initiating a new escrow payment.
+// Exactly one of Amount and AmountCents must be set.
 type InitiatePaymentRequest struct {
-	BookingID     uuid.UUID `json:"booking_id" binding:"required"`
-	AmountCents   int64     `json:"amount_cents" binding:"required,gt=0"`
-	Currency      string    `json:"currency" binding:"required"`
-	CustomerEmail string    `json:"customer_email" binding:"required,email"`
+	BookingID uuid.UUID `json:"booking_id" binding:"required"`
+	// AmountCents is the amount in the currency's minor unit, e.g. 1990 for
+	// 19.90 MYR but 1990 for 1990 JPY.
+	AmountCents int64 `json:"amount_cents" binding:"omitempty,gt=0"`
+	// Amount is the amount as a decimal string in major units, e.g. "19.90".
+	Amount        string `json:"amount,omitempty"`
+	Currency      string `json:"currency" binding:"required"`
+	CustomerEmail string `json:"customer_email" binding:"required,email"`
+	PromoCode     string `json:"promo_code,omitempty"`
+	Region        string `json:"region,omitempty"`
+	// RunnerID is the assigned runner, if known. Required for automatic release.
+	RunnerID *uuid.UUID `json:"runner_id,omitempty"`
+	// AutoReleaseAfterHours overrides the default escrow hold window; 0 disables auto-release.
+	AutoReleaseAfterHours *int `json:"auto_release_after_hours,omitempty" binding:"omitempty,gte=0"`
+	// CallbackURL, if set, receives a signed HTTP callback on release and refund.
+	CallbackURL string `json:"callback_url,omitempty"`
+	// Test marks the payment as a test payment; see SetTestPayments.
+	Test bool `json:"test,omitempty"`
+}
+
+// QuotePaymentRequest is the request DTO for previewing a payment's price.
+// Like InitiatePaymentRequest, it takes exactly one of Amount and AmountCents.
+type QuotePaymentRequest struct {
+	BookingID   uuid.UUID `json:"booking_id" binding:"required"`
+	AmountCents int64     `json:"amount_cents" binding:"omitempty,gt=0"`
+	Amount      string    `json:"amount,omitempty"`
+	Currency    string    `json:"currency" binding:"required"`
+	PromoCode   string    `json:"promo_code,omitempty"`
+	Region      string    `json:"region,omitempty"`
 }
 
-// PaymentDTO is the API response DTO for payment data.
+// ErrAmountFields is returned when a request sets both or neither of amount
+// and amount_cents.
+var ErrAmountFields = errors.New("exactly one of amount and amount_cents is required")
+
+// resolveAmountCents returns the requested amount in currency's minor unit,
+// converting amount when it is given instead of amountCents.
+func resolveAmountCents(amount string, amountCents int64, currency string) (int64, error) {
+	if (amount == "") == (amountCents == 0) {
+		return 0, ErrAmountFields
+	}
+	if amount == "" {
+		return amountCents, nil
+	}
+	m, err := payment.ParseDecimal(amount, currency)
+	if err != nil {
+		return 0, err
+	}
+	if m.Amount() <= 0 {
+		return 0, fmt.Errorf("%w %q: must be greater than zero", payment.ErrInvalidAmount, amount)
+	}
+	return m.Amount(), nil
+}
+
+// QuoteDTO itemizes what InitiatePayment would charge. FinalChargeCents is the
+// amount charged to the card after discounts and credit.
+type QuoteDTO struct {
+	BookingID                 uuid.UUID             `json:"booking_id"`
+	Currency                  string                `json:"currency"`
+	MinorUnits                int                   `json:"minor_units"`
+	GrossCents                int64                 `json:"gross_cents"`
+	PromoDiscountCents        int64                 `json:"promo_discount_cents"`
+	SubscriptionDiscountCents int64                 `json:"subscription_discount_cents"`
+	PlatformFeeCents          int64                 `json:"platform_fee_cents"`
+	RunnerPayoutCents         int64                 `json:"runner_payout_cents"`
+	CreditAppliedCents        int64                 `json:"credit_applied_cents"`
+	FinalChargeCents          int64                 `json:"final_charge_cents"`
+	Discount                  *DiscountBreakdownDTO `json:"discount"`
+}
+
+// PaymentDTO is the API response DTO for payment data. Amounts are in the
+// currency's minor unit; MinorUnits is its number of decimal places.
 type PaymentDTO struct {
 	ID                uuid.UUID  `json:"id"`
 	BookingID         uuid.UUID  `json:"booking_id"`
@@ -31,52 +111,474 @@ type PaymentDTO struct {
 	PlatformFeeCents  int64      `json:"platform_fee_cents"`
 	RunnerPayoutCents int64      `json:"runner_payout_cents"`
 	Currency          string     `json:"currency"`
+	MinorUnits        int        `json:"minor_units"`
 	PaymentMethod     string     `json:"payment_method,omitempty"`
 	StripePaymentID   string     `json:"stripe_payment_id,omitempty"`
 	EscrowHeldAt      *time.Time `json:"escrow_held_at,omitempty"`
 	EscrowReleasedAt  *time.Time `json:"escrow_released_at,omitempty"`
 	RefundedAt        *time.Time `json:"refunded_at,omitempty"`
+	ReleaseEligibleAt *time.Time `json:"release_eligible_at,omitempty"`
 	RefundReason      string     `json:"refund_reason,omitempty"`
 	Version           int64      `json:"version"`
 	CreatedAt         time.Time  `json:"created_at"`
 	UpdatedAt         time.Time  `json:"updated_at"`
+
+	CreditAppliedCents int64                 `json:"credit_applied_cents,omitempty"`
+	CallbackURL        string                `json:"callback_url,omitempty"`
+	Discount           *DiscountBreakdownDTO `json:"discount,omitempty"`
+	// AwaitingAuthentication means the customer must complete 3-D Secure with
+	// ClientSecret before the escrow is held; the payment stays pending until then.
+	AwaitingAuthentication bool `json:"awaiting_authentication,omitempty"`
+	// ClientSecret confirms the Stripe PaymentIntent from the frontend. It is
+	// only set in the response to InitiatePayment and is never stored.
+	ClientSecret string `json:"client_secret,omitempty"`
+	// Formatted holds the amounts formatted for display, keyed by field name
+	// without the _cents suffix, when the caller asked for them.
+	Formatted map[string]string `json:"formatted,omitempty"`
+}
+
+// AdminPaymentDTO is a PaymentDTO with the fields only admins may see. Owner-
+// and runner-facing endpoints must return PaymentDTO instead.
+type AdminPaymentDTO struct {
+	PaymentDTO
+	CustomerEmail string `json:"customer_email,omitempty"`
+	// ReviewReason is set when the payment needs manual reconciliation.
+	ReviewReason string `json:"review_reason,omitempty"`
+	IsTest       bool   `json:"is_test"`
+}
+
+// promoEventTimeout bounds the background publish of promo analytics events.
+const promoEventTimeout = 5 * time.Second
+
+// EventPublisher publishes CloudEvents to a Kafka topic.
+type EventPublisher interface {
+	PublishEvent(ctx context.Context, topic string, ce kafka.CloudEvent) error
+}
+
+// maxRefundReasonLength bounds the free-text refund reason, in characters.
+const maxRefundReasonLength = 500
+
+// refundReasonCodes are the known refund categories used for reporting.
+var refundReasonCodes = map[string]bool{
+	"customer_request":  true,
+	"duplicate":         true,
+	"fraudulent":        true,
+	"service_issue":     true,
+	"booking_cancelled": true,
+	"other":             true,
+}
+
+// ErrInvalidRefundReason is returned when a refund reason fails validation.
+var ErrInvalidRefundReason = errors.New("invalid refund reason")
+
+// ErrInvalidRefundMethod is returned when a refund names an unknown method.
+var ErrInvalidRefundMethod = errors.New("invalid refund method")
+
+// RefundRequest is the DTO for a manual refund.
+type RefundRequest struct {
+	// ReasonCode optionally categorises the refund; it must be a known code when set.
+	ReasonCode string `json:"reason_code,omitempty"`
+	Reason     string `json:"reason" binding:"required"`
+	// Method is "card" (default) or "credit".
+	Method string `json:"method,omitempty"`
+}
+
+// normalize trims the request and returns the reason to store, prefixed with
+// the reason code when one is given.
+func (r RefundRequest) normalize() (string, error) {
+	reason := strings.TrimSpace(r.Reason)
+	if reason == "" {
+		return "", fmt.Errorf("%w: reason must not be blank", ErrInvalidRefundReason)
+	}
+	if utf8.RuneCountInString(reason) > maxRefundReasonLength {
+		return "", fmt.Errorf("%w: reason must be at most %d characters", ErrInvalidRefundReason, maxRefundReasonLength)
+	}
+
+	code := strings.ToLower(strings.TrimSpace(r.ReasonCode))
+	if code == "" {
+		return reason, nil
+	}
+	if !refundReasonCodes[code] {
+		return "", fmt.Errorf("%w: unknown reason_code %q", ErrInvalidRefundReason, r.ReasonCode)
+	}
+	return code + ": " + reason, nil
+}
+
+// refundMethod validates the requested refund method, defaulting to card.
+func (r RefundRequest) refundMethod() (payment.RefundMethod, error) {
+	switch method := payment.RefundMethod(strings.ToLower(strings.TrimSpace(r.Method))); method {
+	case "":
+		return payment.RefundToCard, nil
+	case payment.RefundToCard, payment.RefundToCredit:
+		return method, nil
+	}
+	return "", fmt.Errorf("%w: unknown method %q", ErrInvalidRefundMethod, r.Method)
+}
+
+// RetryPaymentRequest is the DTO for retrying escrow creation on a failed payment.
+type RetryPaymentRequest struct {
+	CustomerEmail string `json:"customer_email" binding:"required,email"`
 }
 
 // PaymentService is the application service that orchestrates payment use cases.
 type PaymentService struct {
-	repo      payment.PaymentRepository
-	sagaSvc   *saga.PaymentSagaService
-	logger    *zap.Logger
+	repo       payment.PaymentRepository
+	promoRepo  promoDomain.PromoRepository
+	subRepo    subDomain.SubscriptionRepository
+	credits    creditDomain.CreditRepository
+	sagaSvc    *saga.PaymentSagaService
+	discounts  *DiscountEngine
+	currencies CurrencyAllowlist
+	publisher  EventPublisher
+	logger     *zap.Logger
+
+	// bookingTotals, when set, checks initiated amounts; see SetBookingTotals.
+	bookingTotals        bookingDomain.TotalRepository
+	amountToleranceCents int64
+	flags                *feature.Flags
+
+	// stripeTestMode and allowTestFlag decide which payments are marked as
+	// test payments; see SetTestPayments.
+	stripeTestMode bool
+	allowTestFlag  bool
+
+	// callbackDeliveries, when set, adds callbacks to payment timelines; see
+	// SetCallbackDeliveries.
+	callbackDeliveries callback.DeliveryRepository
+
+	// refundRequests, when set, holds large refunds for a second admin's
+	// approval; see SetRefundApprovals.
+	refundRequests               refundDomain.RequestRepository
+	refundApprovalThresholdCents int64
 }
 
 // NewPaymentService creates a new PaymentService.
+// credits may be nil, in which case no credit is applied to new payments.
+// currencies may be nil to accept any currency.
 func NewPaymentService(
 	repo payment.PaymentRepository,
+	promoRepo promoDomain.PromoRepository,
+	subRepo subDomain.SubscriptionRepository,
+	credits creditDomain.CreditRepository,
 	sagaSvc *saga.PaymentSagaService,
+	discounts *DiscountEngine,
+	currencies CurrencyAllowlist,
+	publisher EventPublisher,
 	logger *zap.Logger,
 ) *PaymentService {
 	return &PaymentService{
-		repo:    repo,
-		sagaSvc: sagaSvc,
-		logger:  logger,
+		repo:       repo,
+		promoRepo:  promoRepo,
+		subRepo:    subRepo,
+		credits:    credits,
+		sagaSvc:    sagaSvc,
+		discounts:  discounts,
+		currencies: currencies,
+		publisher:  publisher,
+		logger:     logger,
 	}
 }
 
+// ErrTestPaymentsDisabled is returned when a request asks for a test payment
+// where test payments are not allowed.
+var ErrTestPaymentsDisabled = errors.New("test payments are not allowed in this environment")
+
+// SetTestPayments configures which new payments are marked as test payments.
+// With stripeTestMode, i.e. Stripe test keys, every payment is; otherwise
+// only requests that set Test, which are rejected unless allowTestFlag.
+func (s *PaymentService) SetTestPayments(stripeTestMode, allowTestFlag bool) {
+	s.stripeTestMode = stripeTestMode
+	s.allowTestFlag = allowTestFlag
+}
+
+// SetCallbackDeliveries makes payment timelines include the payment's
+// callback deliveries and their attempts.
+func (s *PaymentService) SetCallbackDeliveries(repo callback.DeliveryRepository) {
+	s.callbackDeliveries = repo
+}
+
 // InitiatePayment starts the escrow payment process for a booking.
 func (s *PaymentService) InitiatePayment(ctx context.Context, ownerID uuid.UUID, req InitiatePaymentRequest) (*PaymentDTO, error) {
+	currency, err := s.currencies.Normalize(req.Currency)
+	if err != nil {
+		return nil, err
+	}
+	req.Currency = currency
+
+	req.AmountCents, err = resolveAmountCents(req.Amount, req.AmountCents, currency)
+	if err != nil {
+		return nil, err
+	}
+
 	s.logger.Info("initiating payment",
 		zap.String("booking_id", req.BookingID.String()),
 		zap.String("owner_id", ownerID.String()),
 		zap.Int64("amount_cents", req.AmountCents),
 	)
 
-	p, err := s.sagaSvc.CreateEscrowSaga(ctx, req.BookingID, ownerID, req.AmountCents, req.Currency, req.CustomerEmail)
+	if err := s.checkBookingAmount(ctx, req); err != nil {
+		return nil, err
+	}
+
+	if req.CallbackURL != "" {
+		if err := validateCallbackURL(req.CallbackURL); err != nil {
+			return nil, err
+		}
+	}
+	if req.Test && !s.stripeTestMode && !s.allowTestFlag {
+		return nil, ErrTestPaymentsDisabled
+	}
+
+	promo, breakdown, err := s.priceBooking(ctx, ownerID, req.AmountCents, req.PromoCode)
+	if err != nil {
+		return nil, err
+	}
+
+	params := saga.CreateEscrowParams{
+		BookingID:     req.BookingID,
+		OwnerID:       ownerID,
+		RunnerID:      req.RunnerID,
+		AmountCents:   breakdown.FinalAmountCents,
+		Currency:      req.Currency,
+		Region:        req.Region,
+		CustomerEmail: req.CustomerEmail,
+		CallbackURL:   req.CallbackURL,
+		CreditCents:   s.availableCredit(ctx, ownerID, req.Currency, breakdown.FinalAmountCents),
+		Test:          s.stripeTestMode || req.Test,
+	}
+	if req.AutoReleaseAfterHours != nil {
+		window := time.Duration(*req.AutoReleaseAfterHours) * time.Hour
+		params.AutoReleaseAfter = &window
+	}
+
+	p, clientSecret, err := s.sagaSvc.CreateEscrowSaga(ctx, params)
 	if err != nil {
 		s.logger.Error("failed to initiate payment", zap.Error(err))
 		return nil, err
 	}
 
+	if err := s.repo.SaveDiscounts(ctx, p.ID(), toDiscountLines(breakdown.Lines)); err != nil {
+		s.logger.Error("failed to save payment discounts",
+			zap.String("payment_id", p.ID().String()),
+			zap.Error(err),
+		)
+	}
+
+	if promo != nil {
+		s.recordPromoUsage(ctx, promo, ownerID, req.BookingID, breakdown)
+	}
+
 	dto := toPaymentDTO(p)
+	dto.Discount = breakdown
+	dto.ClientSecret = clientSecret
+	return &dto, nil
+}
+
+// QuotePayment returns the price InitiatePayment would charge for req, with
+// discounts, fee split and credit itemized, without persisting anything or
+// contacting Stripe.
+func (s *PaymentService) QuotePayment(ctx context.Context, ownerID uuid.UUID, req QuotePaymentRequest) (*QuoteDTO, error) {
+	currency, err := s.currencies.Normalize(req.Currency)
+	if err != nil {
+		return nil, err
+	}
+	req.AmountCents, err = resolveAmountCents(req.Amount, req.AmountCents, currency)
+	if err != nil {
+		return nil, err
+	}
+
+	_, breakdown, err := s.priceBooking(ctx, ownerID, req.AmountCents, req.PromoCode)
+	if err != nil {
+		return nil, err
+	}
+
+	fee, payout, err := s.sagaSvc.QuoteSplit(req.BookingID, ownerID, breakdown.FinalAmountCents, currency, req.Region)
+	if err != nil {
+		return nil, err
+	}
+	credit := s.availableCredit(ctx, ownerID, currency, breakdown.FinalAmountCents)
+
+	quote := &QuoteDTO{
+		BookingID:          req.BookingID,
+		Currency:           currency,
+		MinorUnits:         payment.NewMoney(0, currency).MinorUnits(),
+		GrossCents:         breakdown.BaseAmountCents,
+		PlatformFeeCents:   fee,
+		RunnerPayoutCents:  payout,
+		CreditAppliedCents: credit,
+		FinalChargeCents:   breakdown.FinalAmountCents - credit,
+		Discount:           breakdown,
+	}
+	for _, l := range breakdown.Lines {
+		switch l.Source {
+		case DiscountSourcePromo:
+			quote.PromoDiscountCents = l.AmountCents
+		case DiscountSourceSubscription:
+			quote.SubscriptionDiscountCents = l.AmountCents
+		}
+	}
+	return quote, nil
+}
+
+// priceBooking applies the owner's promo code and subscription discount to
+// amountCents. InitiatePayment and QuotePayment share it so quotes match charges.
+func (s *PaymentService) priceBooking(ctx context.Context, ownerID uuid.UUID, amountCents int64, promoCode string) (*promoDomain.PromoCode, *DiscountBreakdownDTO, error) {
+	plan := activePlan(ctx, s.subRepo, ownerID)
+
+	var promo *promoDomain.PromoCode
+	if promoCode != "" {
+		var err error
+		promo, err = s.promoRepo.FindByCode(ctx, promoDomain.NormalizeCode(promoCode))
+		if err != nil {
+			return nil, nil, fmt.Errorf("promo code not found")
+		}
+		used, err := s.promoRepo.HasUserUsedPromo(ctx, promo.ID(), ownerID)
+		if err != nil {
+			return nil, nil, err
+		}
+		if used {
+			return nil, nil, fmt.Errorf("you have already used this promo code")
+		}
+		if err := checkFirstBooking(ctx, s.repo, promo, ownerID); err != nil {
+			return nil, nil, err
+		}
+		if err := promo.CheckPlan(string(plan)); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	breakdown, err := s.discounts.Calculate(amountCents, promo, plan)
+	if err != nil {
+		return nil, nil, err
+	}
+	return promo, breakdown, nil
+}
+
+// availableCredit returns how much of amountCents the owner's credit balance
+// can cover. Lookup failures are logged and the payment is charged to the card.
+func (s *PaymentService) availableCredit(ctx context.Context, ownerID uuid.UUID, currency string, amountCents int64) int64 {
+	if s.credits == nil {
+		return 0
+	}
+	balance, err := s.credits.FindByUserID(ctx, ownerID)
+	if err != nil {
+		s.logger.Warn("failed to load credit balance, charging card",
+			zap.String("owner_id", ownerID.String()),
+			zap.Error(err),
+		)
+		return 0
+	}
+	if balance == nil || balance.Currency != currency {
+		return 0
+	}
+	return min(balance.BalanceCents, amountCents)
+}
+
+// recordPromoUsage stores the redemption of a promo applied to a payment. The
+// escrow is already held at this point, so failures are logged rather than returned.
+func (s *PaymentService) recordPromoUsage(ctx context.Context, promo *promoDomain.PromoCode, ownerID, bookingID uuid.UUID, breakdown *DiscountBreakdownDTO) {
+	var discountCents int64
+	for _, l := range breakdown.Lines {
+		if l.Source == DiscountSourcePromo {
+			discountCents = l.AmountCents
+		}
+	}
+	if discountCents == 0 {
+		// The promo lost out under the stacking policy; it was not redeemed.
+		return
+	}
+
+	usage := &promoDomain.PromoUsage{
+		ID:            uuid.New(),
+		PromoID:       promo.ID(),
+		UserID:        ownerID,
+		BookingID:     bookingID,
+		DiscountCents: discountCents,
+		UsedAt:        time.Now().UTC(),
+	}
+	if err := s.promoRepo.SaveUsage(ctx, usage); err != nil {
+		s.logger.Error("failed to save promo usage",
+			zap.String("promo_code", promo.Code()),
+			zap.String("booking_id", bookingID.String()),
+			zap.Error(err),
+		)
+		return
+	}
+
+	promo.IncrementUses()
+	if err := s.promoRepo.Update(ctx, promo); err != nil {
+		s.logger.Error("failed to increment promo uses",
+			zap.String("promo_code", promo.Code()),
+			zap.Error(err),
+		)
+	}
+
+	s.publishPromoRedeemed(ctx, usage, promo.Code())
+}
+
+// publishPromoRedeemed emits a PromoRedeemedEvent for analytics. It runs in the
+// background so a slow or unavailable broker never delays the payment; failures are logged.
+func (s *PaymentService) publishPromoRedeemed(ctx context.Context, usage *promoDomain.PromoUsage, code string) {
+	if s.publisher == nil {
+		return
+	}
+
+	cloudEvent, err := kafka.NewCloudEvent("service-payment", promoDomain.PromoRedeemed, promoDomain.PromoRedeemedEvent{
+		PromoID:       usage.PromoID,
+		Code:          code,
+		UserID:        usage.UserID,
+		BookingID:     usage.BookingID,
+		DiscountCents: usage.DiscountCents,
+		OccurredAt:    usage.UsedAt,
+	})
+	if err != nil {
+		s.logger.Error("failed to create promo redeemed event", zap.Error(err))
+		return
+	}
+
+	// Detach from the request so the publish survives the HTTP response.
+	pubCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), promoEventTimeout)
+	go func() {
+		defer cancel()
+		if err := s.publisher.PublishEvent(pubCtx, promoDomain.TopicPromoEvents, cloudEvent); err != nil {
+			s.logger.Warn("failed to publish promo redeemed event",
+				zap.String("promo_code", code),
+				zap.String("booking_id", usage.BookingID.String()),
+				zap.Error(err),
+			)
+		}
+	}()
+}
+
+// RetryPayment re-runs escrow creation for a failed payment against the same
+// booking. Payments that are not failed are refused with a conflict error.
+func (s *PaymentService) RetryPayment(ctx context.Context, paymentID uuid.UUID, req RetryPaymentRequest) (*PaymentDTO, error) {
+	p, err := s.repo.FindByID(ctx, paymentID)
+	if err != nil {
+		return nil, err
+	}
+
+	// A booking has one payment, so it is already paid, or being paid,
+	// unless that payment failed. Never start a second escrow for it.
+	if p.EscrowStatus() != payment.EscrowFailed {
+		return nil, domain.NewConflictError(fmt.Sprintf("booking already has a %s payment", p.EscrowStatus()))
+	}
+
+	s.logger.Info("retrying escrow creation",
+		zap.String("payment_id", paymentID.String()),
+		zap.String("booking_id", p.BookingID().String()),
+	)
+
+	retried, err := s.sagaSvc.RetryEscrowSaga(ctx, paymentID, req.CustomerEmail)
+	if err != nil {
+		s.logger.Error("escrow retry failed",
+			zap.String("payment_id", paymentID.String()),
+			zap.Error(err),
+		)
+		return nil, err
+	}
+
+	dto := toPaymentDTO(retried)
 	return &dto, nil
 }
 
@@ -102,14 +604,254 @@ func (s *PaymentService) GetPaymentByBooking(ctx context.Context, bookingID uuid
 	return &dto, nil
 }
 
-// RefundPayment initiates a refund for a held escrow payment.
-func (s *PaymentService) RefundPayment(ctx context.Context, paymentID uuid.UUID, reason string) (*PaymentDTO, error) {
+// MaxBatchBookingIDs caps how many bookings one batch status request may ask for.
+const MaxBatchBookingIDs = 100
+
+// PaymentStatusDTO is a trimmed view of a payment's escrow status and amounts.
+type PaymentStatusDTO struct {
+	PaymentID         uuid.UUID  `json:"payment_id"`
+	BookingID         uuid.UUID  `json:"booking_id"`
+	EscrowStatus      string     `json:"escrow_status"`
+	AmountCents       int64      `json:"amount_cents"`
+	PlatformFeeCents  int64      `json:"platform_fee_cents"`
+	RunnerPayoutCents int64      `json:"runner_payout_cents"`
+	Currency          string     `json:"currency"`
+	MinorUnits        int        `json:"minor_units"`
+	UpdatedAt         time.Time  `json:"updated_at"`
+	RunnerID          *uuid.UUID `json:"runner_id,omitempty"`
+}
+
+// BatchPaymentStatusRequest lists the bookings to look up.
+type BatchPaymentStatusRequest struct {
+	BookingIDs []uuid.UUID `json:"booking_ids" binding:"required,min=1,max=100"`
+}
+
+// GetPaymentStatusesByBookings returns the payment status for each booking,
+// keyed by booking ID. Unless all is set, only payments owned by ownerID are
+// included; bookings without a visible payment are omitted.
+func (s *PaymentService) GetPaymentStatusesByBookings(ctx context.Context, ownerID uuid.UUID, all bool, bookingIDs []uuid.UUID) (map[uuid.UUID]PaymentStatusDTO, error) {
+	if len(bookingIDs) > MaxBatchBookingIDs {
+		return nil, fmt.Errorf("at most %d booking IDs may be requested at once", MaxBatchBookingIDs)
+	}
+
+	payments, err := s.repo.FindByBookingIDs(ctx, bookingIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	statuses := make(map[uuid.UUID]PaymentStatusDTO, len(payments))
+	for _, p := range payments {
+		if !all && p.OwnerID() != ownerID {
+			continue
+		}
+		statuses[p.BookingID()] = toPaymentStatusDTO(p)
+	}
+	return statuses, nil
+}
+
+// GetPaymentStatusByBooking returns the trimmed status view of a booking's payment.
+func (s *PaymentService) GetPaymentStatusByBooking(ctx context.Context, bookingID uuid.UUID) (*PaymentStatusDTO, error) {
+	p, err := s.repo.FindByBookingID(ctx, bookingID)
+	if err != nil {
+		return nil, err
+	}
+	dto := toPaymentStatusDTO(p)
+	return &dto, nil
+}
+
+func toPaymentStatusDTO(p *payment.Payment) PaymentStatusDTO {
+	return PaymentStatusDTO{
+		PaymentID:         p.ID(),
+		BookingID:         p.BookingID(),
+		EscrowStatus:      string(p.EscrowStatus()),
+		AmountCents:       p.AmountCents(),
+		PlatformFeeCents:  p.PlatformFeeCents(),
+		RunnerPayoutCents: p.RunnerPayoutCents(),
+		Currency:          p.Currency(),
+		MinorUnits:        p.Amount().MinorUnits(),
+		UpdatedAt:         p.UpdatedAt(),
+		RunnerID:          p.RunnerID(),
+	}
+}
+
+// ReceiptDTO is an itemized receipt for a payment.
+type ReceiptDTO struct {
+	PaymentID          uuid.UUID         `json:"payment_id"`
+	BookingID          uuid.UUID         `json:"booking_id"`
+	OwnerID            uuid.UUID         `json:"owner_id"`
+	EscrowStatus       string            `json:"escrow_status"`
+	Currency           string            `json:"currency"`
+	MinorUnits         int               `json:"minor_units"`
+	GrossAmountCents   int64             `json:"gross_amount_cents"`
+	Discounts          []DiscountLineDTO `json:"discounts"`
+	TotalDiscountCents int64             `json:"total_discount_cents"`
+	AmountPaidCents    int64             `json:"amount_paid_cents"`
+	PlatformFeeCents   int64             `json:"platform_fee_cents"`
+	RunnerPayoutCents  int64             `json:"runner_payout_cents"`
+	CreatedAt          time.Time         `json:"created_at"`
+	EscrowHeldAt       *time.Time        `json:"escrow_held_at,omitempty"`
+	EscrowReleasedAt   *time.Time        `json:"escrow_released_at,omitempty"`
+	RefundedAt         *time.Time        `json:"refunded_at,omitempty"`
+	RefundReason       string            `json:"refund_reason,omitempty"`
+	PaymentMethod      string            `json:"payment_method,omitempty"`
+	// Formatted holds the amounts formatted for display, keyed by field name
+	// without the _cents suffix, when the caller asked for them.
+	Formatted map[string]string `json:"formatted,omitempty"`
+}
+
+// GetReceipt builds an itemized receipt for a payment.
+func (s *PaymentService) GetReceipt(ctx context.Context, paymentID uuid.UUID) (*ReceiptDTO, error) {
+	p, err := s.repo.FindByID(ctx, paymentID)
+	if err != nil {
+		return nil, err
+	}
+
+	lines, err := s.repo.FindDiscounts(ctx, paymentID)
+	if err != nil {
+		return nil, err
+	}
+
+	discounts := make([]DiscountLineDTO, len(lines))
+	var totalDiscount int64
+	for i, l := range lines {
+		discounts[i] = DiscountLineDTO{Source: l.Source, Code: l.Code, AmountCents: l.AmountCents}
+		totalDiscount += l.AmountCents
+	}
+
+	// A booking price change keeps the discounts but changes the total, so
+	// the gross comes from the adjustment while it still describes the amount.
+	gross := p.AmountCents() + totalDiscount
+	adj, err := s.repo.FindLatestAmountAdjustment(ctx, paymentID)
+	if err != nil {
+		return nil, err
+	}
+	if adj != nil && adj.GrossAmountCents > 0 && adj.AmountCents == p.AmountCents() {
+		gross = adj.GrossAmountCents
+	}
+
+	return &ReceiptDTO{
+		PaymentID:          p.ID(),
+		BookingID:          p.BookingID(),
+		OwnerID:            p.OwnerID(),
+		EscrowStatus:       string(p.EscrowStatus()),
+		Currency:           p.Currency(),
+		MinorUnits:         p.Amount().MinorUnits(),
+		GrossAmountCents:   gross,
+		Discounts:          discounts,
+		TotalDiscountCents: totalDiscount,
+		AmountPaidCents:    p.AmountCents(),
+		PlatformFeeCents:   p.PlatformFeeCents(),
+		RunnerPayoutCents:  p.RunnerPayoutCents(),
+		CreatedAt:          p.CreatedAt(),
+		EscrowHeldAt:       p.EscrowHeldAt(),
+		EscrowReleasedAt:   p.EscrowReleasedAt(),
+		RefundedAt:         p.RefundedAt(),
+		RefundReason:       p.RefundReason(),
+		PaymentMethod:      p.PaymentMethod(),
+	}, nil
+}
+
+// PaymentReceiptRequested is the CloudEvent type published when a receipt is
+// to be (re)sent to the payer. The notification service delivers it.
+const PaymentReceiptRequested = "payment.receipt_requested"
+
+// PaymentReceiptRequestedEvent asks for Receipt to be emailed to Email. It is
+// defined here until the contract is added to lib-proto.
+type PaymentReceiptRequestedEvent struct {
+	PaymentID   uuid.UUID  `json:"payment_id"`
+	OwnerID     uuid.UUID  `json:"owner_id"`
+	Email       string     `json:"email"`
+	Receipt     ReceiptDTO `json:"receipt"`
+	RequestedBy uuid.UUID  `json:"requested_by"`
+	OccurredAt  time.Time  `json:"occurred_at"`
+}
+
+// ErrNoCustomerEmail is returned when a receipt is requested for a payment
+// that has no customer email to send it to.
+var ErrNoCustomerEmail = errors.New("payment has no customer email")
+
+// ResendReceipt publishes a PaymentReceiptRequestedEvent so the payment's
+// receipt is emailed to the customer again. Pending and failed payments have
+// nothing to receipt and are rejected with an invalid state error.
+func (s *PaymentService) ResendReceipt(ctx context.Context, paymentID, requestedBy uuid.UUID) error {
+	if s.publisher == nil {
+		return errors.New("receipt notifications are not configured")
+	}
+
+	p, err := s.repo.FindByID(ctx, paymentID)
+	if err != nil {
+		return err
+	}
+	switch p.EscrowStatus() {
+	case payment.EscrowPending, payment.EscrowFailed:
+		return domain.NewInvalidStateError(string(p.EscrowStatus()), "receipt_sent")
+	}
+	if p.CustomerEmail() == "" {
+		return ErrNoCustomerEmail
+	}
+
+	receipt, err := s.GetReceipt(ctx, paymentID)
+	if err != nil {
+		return err
+	}
+	cloudEvent, err := kafka.NewCloudEvent("service-payment", PaymentReceiptRequested, PaymentReceiptRequestedEvent{
+		PaymentID:   p.ID(),
+		OwnerID:     p.OwnerID(),
+		Email:       p.CustomerEmail(),
+		Receipt:     *receipt,
+		RequestedBy: requestedBy,
+		OccurredAt:  time.Now().UTC(),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create receipt requested event: %w", err)
+	}
+	if err := s.publisher.PublishEvent(ctx, events.TopicPaymentEvents, cloudEvent); err != nil {
+		return fmt.Errorf("failed to publish receipt requested event: %w", err)
+	}
+
+	s.logger.Info("receipt resend requested",
+		zap.String("payment_id", p.ID().String()),
+		zap.String("requested_by", requestedBy.String()),
+	)
+	return nil
+}
+
+// RefundPayment initiates a refund for a held escrow payment. While refund
+// approvals are on, payments at or above the threshold are refused with
+// ErrRefundNeedsApproval; see RequestRefund.
+func (s *PaymentService) RefundPayment(ctx context.Context, paymentID uuid.UUID, req RefundRequest) (*PaymentDTO, error) {
+	reason, err := req.normalize()
+	if err != nil {
+		return nil, err
+	}
+	method, err := req.refundMethod()
+	if err != nil {
+		return nil, err
+	}
+
+	if s.refundRequests != nil {
+		p, err := s.repo.FindByID(ctx, paymentID)
+		if err != nil {
+			return nil, err
+		}
+		if s.needsRefundApproval(p) {
+			return nil, fmt.Errorf("%w: %d is at or above the %d threshold",
+				ErrRefundNeedsApproval, p.AmountCents(), s.refundApprovalThreshold(p.Currency()))
+		}
+	}
+
+	return s.refund(ctx, paymentID, reason, method)
+}
+
+// refund runs the refund saga and returns the refunded payment.
+func (s *PaymentService) refund(ctx context.Context, paymentID uuid.UUID, reason string, method payment.RefundMethod) (*PaymentDTO, error) {
 	s.logger.Info("refunding payment",
 		zap.String("payment_id", paymentID.String()),
 		zap.String("reason", reason),
+		zap.String("method", string(method)),
 	)
 
-	if err := s.sagaSvc.RefundEscrowSaga(ctx, paymentID, reason); err != nil {
+	if err := s.sagaSvc.RefundEscrowSaga(ctx, paymentID, reason, method); err != nil {
 		s.logger.Error("failed to refund payment", zap.Error(err))
 		return nil, err
 	}
@@ -124,6 +866,117 @@ func (s *PaymentService) RefundPayment(ctx context.Context, paymentID uuid.UUID,
 	return &dto, nil
 }
 
+// ownerCancelReason is recorded on escrows the owner cancels themselves.
+const ownerCancelReason = "owner cancelled before delivery"
+
+// CancelByOwner refunds a held escrow at the owner's request, provided no
+// runner has been assigned yet. Ownership is checked by the caller.
+func (s *PaymentService) CancelByOwner(ctx context.Context, paymentID uuid.UUID) (*PaymentDTO, error) {
+	p, err := s.repo.FindByID(ctx, paymentID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := p.EnsureOwnerCancellable(); err != nil {
+		return nil, err
+	}
+
+	s.logger.Info("owner cancelling escrow",
+		zap.String("payment_id", paymentID.String()),
+		zap.String("owner_id", p.OwnerID().String()),
+	)
+
+	if err := s.sagaSvc.RefundEscrowSaga(ctx, paymentID, ownerCancelReason, payment.RefundToCard); err != nil {
+		s.logger.Error("failed to cancel payment", zap.Error(err))
+		return nil, err
+	}
+
+	// Reload after saga completes
+	p, err = s.repo.FindByID(ctx, paymentID)
+	if err != nil {
+		return nil, err
+	}
+
+	dto := toPaymentDTO(p)
+	return &dto, nil
+}
+
+// OverrideFeeRequest is the DTO for an admin override of a payment's platform fee.
+type OverrideFeeRequest struct {
+	PlatformFeeCents *int64 `json:"platform_fee_cents" binding:"required"`
+}
+
+// OverridePlatformFee sets the platform fee of a held payment and recomputes
+// the runner payout. The override is recorded in the status history under the
+// actor on ctx. A concurrent update of the payment is retried on the reloaded
+// payment.
+func (s *PaymentService) OverridePlatformFee(ctx context.Context, paymentID uuid.UUID, feeCents int64) (*AdminPaymentDTO, error) {
+	var p *payment.Payment
+	var previousFee int64
+	err := s.retryOnConflict(ctx, "override_platform_fee", func(ctx context.Context) error {
+		var err error
+		p, err = s.repo.FindByID(ctx, paymentID)
+		if err != nil {
+			return err
+		}
+
+		previousFee = p.PlatformFeeCents()
+		if err := p.OverrideFee(feeCents); err != nil {
+			return err
+		}
+		p.IncrementVersion()
+		return s.repo.Update(ctx, p)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	s.logger.Info("platform fee overridden",
+		zap.String("payment_id", paymentID.String()),
+		zap.String("actor", payment.ActorFromContext(ctx)),
+		zap.Int64("previous_fee_cents", previousFee),
+		zap.Int64("platform_fee_cents", p.PlatformFeeCents()),
+		zap.Int64("runner_payout_cents", p.RunnerPayoutCents()),
+	)
+
+	dto := toAdminPaymentDTO(p)
+	return &dto, nil
+}
+
+// ClawbackRequest is the DTO for an admin clawback of a released payment.
+type ClawbackRequest struct {
+	Reason string `json:"reason" binding:"required"`
+}
+
+// ClawbackPayment reverses a released payment after a dispute is decided in
+// the owner's favour (admin): the owner is refunded and the runner's payout is
+// reversed. The reason follows the same rules as a refund reason.
+func (s *PaymentService) ClawbackPayment(ctx context.Context, paymentID uuid.UUID, req ClawbackRequest) (*AdminPaymentDTO, error) {
+	reason, err := RefundRequest{Reason: req.Reason}.normalize()
+	if err != nil {
+		return nil, err
+	}
+
+	s.logger.Info("clawing back payment",
+		zap.String("payment_id", paymentID.String()),
+		zap.String("actor", payment.ActorFromContext(ctx)),
+		zap.String("reason", reason),
+	)
+
+	if err := s.sagaSvc.ClawbackSaga(ctx, paymentID, reason); err != nil {
+		s.logger.Error("failed to claw back payment", zap.Error(err))
+		return nil, err
+	}
+
+	p, err := s.repo.FindByID(ctx, paymentID)
+	if err != nil {
+		return nil, err
+	}
+
+	dto := toAdminPaymentDTO(p)
+	return &dto, nil
+}
+
 // HandleDeliveryConfirmed handles the DeliveryConfirmedEvent from the booking service.
 // It releases the escrow to the runner.
 func (s *PaymentService) HandleDeliveryConfirmed(ctx context.Context, event events.DeliveryConfirmedEvent) error {
@@ -143,7 +996,33 @@ func (s *PaymentService) HandleDeliveryConfirmed(ctx context.Context, event even
 		return err
 	}
 
-	return s.sagaSvc.ReleaseEscrowSaga(ctx, p.ID(), event.RunnerID)
+	// A cancellation can refund the escrow before a late delivery confirmation
+	// arrives; releasing would fail the state transition and stall the partition.
+	switch p.EscrowStatus() {
+	case payment.EscrowRefunded, payment.EscrowFailed:
+		s.logger.Warn("payment already closed, skipping release",
+			zap.String("payment_id", p.ID().String()),
+			zap.String("escrow_status", string(p.EscrowStatus())),
+		)
+		return nil
+	case payment.EscrowReleased:
+		// At-least-once delivery can redeliver the same confirmation.
+		if p.RunnerID() != nil && *p.RunnerID() == event.RunnerID {
+			s.logger.Info("payment already released to runner, ignoring duplicate",
+				zap.String("payment_id", p.ID().String()),
+				zap.String("runner_id", event.RunnerID.String()),
+			)
+			return nil
+		}
+		return domain.NewConflictError("payment already released to a different runner")
+	case payment.EscrowDisputed:
+		s.logger.Warn("payment is disputed, skipping release",
+			zap.String("payment_id", p.ID().String()),
+		)
+		return nil
+	}
+
+	return s.sagaSvc.ReleaseEscrowSaga(ctx, p.ID(), event.RunnerID, nil)
 }
 
 // HandleBookingCancelled handles the BookingCancelledEvent from the booking service.
@@ -168,7 +1047,7 @@ func (s *PaymentService) HandleBookingCancelled(ctx context.Context, event event
 	// Only refund if the escrow is currently held
 	if p.EscrowStatus() == payment.EscrowHeld {
 		reason := "booking cancelled: " + event.Reason
-		return s.sagaSvc.RefundEscrowSaga(ctx, p.ID(), reason)
+		return s.sagaSvc.RefundEscrowSaga(ctx, p.ID(), reason, payment.RefundToCard)
 	}
 
 	s.logger.Info("payment not in held state, skipping refund",
@@ -178,6 +1057,200 @@ func (s *PaymentService) HandleBookingCancelled(ctx context.Context, event event
 	return nil
 }
 
+// HandleBookingExpired voids the held authorization of a booking that lapsed
+// without delivery, so the owner's card funds are freed before Stripe's own
+// authorization expiry. Payments that are not held are left alone.
+func (s *PaymentService) HandleBookingExpired(ctx context.Context, bookingID uuid.UUID) error {
+	s.logger.Info("handling booking expired event",
+		zap.String("booking_id", bookingID.String()),
+	)
+
+	p, err := s.repo.FindByBookingID(ctx, bookingID)
+	if err != nil {
+		if domErr, ok := err.(*domain.DomainError); ok && domErr.Err == domain.ErrNotFound {
+			s.logger.Warn("no payment found for expired booking, skipping void",
+				zap.String("booking_id", bookingID.String()),
+			)
+			return nil
+		}
+		return err
+	}
+
+	if p.EscrowStatus() != payment.EscrowHeld {
+		s.logger.Info("payment not in held state, skipping void",
+			zap.String("payment_id", p.ID().String()),
+			zap.String("escrow_status", string(p.EscrowStatus())),
+		)
+		return nil
+	}
+	return s.sagaSvc.RefundEscrowSaga(ctx, p.ID(), "booking expired without delivery", payment.RefundToCard)
+}
+
+// HandleStripeWebhook applies a verified Stripe webhook event. When a
+// PaymentIntent awaiting 3-D Secure becomes capturable, its escrow is held.
+// Other events, unknown payments, intents that are not the payment's and
+// payments that no longer await authentication are acknowledged and ignored
+// so Stripe stops redelivering them.
+func (s *PaymentService) HandleStripeWebhook(ctx context.Context, event *adapter.WebhookEvent) error {
+	if event.Type != adapter.WebhookIntentAuthorized {
+		return nil
+	}
+	ctx = payment.WithActor(ctx, "system:stripe-webhook")
+
+	paymentID, err := uuid.Parse(event.Intent.Metadata["payment_id"])
+	if err != nil {
+		s.logger.Warn("stripe webhook intent has no payment ID, ignoring",
+			zap.String("event_id", event.ID),
+			zap.String("payment_intent_id", event.Intent.ID),
+		)
+		return nil
+	}
+
+	_, err = s.sagaSvc.CompleteAuthenticationSaga(ctx, paymentID, event.Intent.ID, event.Intent.PaymentMethod)
+	if err != nil {
+		domErr, ok := err.(*domain.DomainError)
+		if errors.Is(err, saga.ErrAuthorizationMismatch) || ok && (domErr.Err == domain.ErrNotFound || domErr.Err == domain.ErrInvalidState) {
+			s.logger.Warn("stripe webhook does not apply to payment, ignoring",
+				zap.String("event_id", event.ID),
+				zap.String("payment_id", paymentID.String()),
+				zap.Error(err),
+			)
+			return nil
+		}
+		return err
+	}
+	return nil
+}
+
+// OpenDispute moves a held or released payment into dispute when payments ops
+// reports one. It is idempotent: a payment already in dispute is left alone,
+// and unknown payments or ones that cannot be disputed are skipped. A
+// concurrent update of the payment is retried on the reloaded payment.
+func (s *PaymentService) OpenDispute(ctx context.Context, paymentID uuid.UUID, reason string) error {
+	return s.retryOnConflict(ctx, "open_dispute", func(ctx context.Context) error {
+		return s.openDispute(ctx, paymentID, reason)
+	})
+}
+
+func (s *PaymentService) openDispute(ctx context.Context, paymentID uuid.UUID, reason string) error {
+	p, err := s.repo.FindByID(ctx, paymentID)
+	if err != nil {
+		if domErr, ok := err.(*domain.DomainError); ok && domErr.Err == domain.ErrNotFound {
+			s.logger.Warn("no payment found for dispute, skipping",
+				zap.String("payment_id", paymentID.String()),
+			)
+			return nil
+		}
+		return err
+	}
+
+	switch p.EscrowStatus() {
+	case payment.EscrowDisputed:
+		s.logger.Info("payment already disputed, ignoring duplicate",
+			zap.String("payment_id", p.ID().String()),
+		)
+		return nil
+	case payment.EscrowHeld, payment.EscrowReleased:
+	default:
+		s.logger.Warn("payment not in a disputable state, skipping dispute",
+			zap.String("payment_id", p.ID().String()),
+			zap.String("escrow_status", string(p.EscrowStatus())),
+		)
+		return nil
+	}
+
+	if err := p.OpenDispute(reason); err != nil {
+		return err
+	}
+	p.IncrementVersion()
+	if err := s.repo.Update(ctx, p); err != nil {
+		return err
+	}
+
+	s.logger.Info("payment dispute opened",
+		zap.String("payment_id", p.ID().String()),
+		zap.String("reason", reason),
+	)
+	return nil
+}
+
+// DecideDisputeForOwner settles a dispute payments ops decided in the owner's
+// favour: a payment disputed after release is clawed back, taking the
+// runner's payout back, and one disputed before release is refunded to the
+// card. It is idempotent: a payment already charged back or refunded is left
+// alone, and unknown payments or ones not in dispute are skipped.
+func (s *PaymentService) DecideDisputeForOwner(ctx context.Context, paymentID uuid.UUID, reason string) error {
+	p, err := s.repo.FindByID(ctx, paymentID)
+	if err != nil {
+		if domErr, ok := err.(*domain.DomainError); ok && domErr.Err == domain.ErrNotFound {
+			s.logger.Warn("no payment found for dispute decision, skipping",
+				zap.String("payment_id", paymentID.String()),
+			)
+			return nil
+		}
+		return err
+	}
+
+	switch p.EscrowStatus() {
+	case payment.EscrowChargedBack, payment.EscrowRefunded:
+		s.logger.Info("disputed payment already returned to owner, ignoring duplicate",
+			zap.String("payment_id", p.ID().String()),
+		)
+		return nil
+	case payment.EscrowDisputed:
+	default:
+		s.logger.Warn("payment not in dispute, skipping dispute decision",
+			zap.String("payment_id", p.ID().String()),
+			zap.String("escrow_status", string(p.EscrowStatus())),
+		)
+		return nil
+	}
+
+	if p.EscrowReleasedAt() != nil {
+		return s.sagaSvc.ClawbackSaga(ctx, paymentID, reason)
+	}
+	return s.sagaSvc.RefundEscrowSaga(ctx, paymentID, reason, payment.RefundToCard)
+}
+
+// ResolveDisputeRequest is the DTO for closing a dispute in the runner's favour.
+type ResolveDisputeRequest struct {
+	Note string `json:"note" binding:"required"`
+}
+
+// ResolveDispute closes a dispute decided in the runner's favour (admin): the
+// payment goes back to held, or to released if it was released before the
+// dispute opened. The note is recorded in the status history under the actor
+// on ctx. A concurrent update of the payment is retried on the reloaded
+// payment.
+func (s *PaymentService) ResolveDispute(ctx context.Context, paymentID uuid.UUID, req ResolveDisputeRequest) (*AdminPaymentDTO, error) {
+	note := strings.TrimSpace(req.Note)
+	var p *payment.Payment
+	err := s.retryOnConflict(ctx, "resolve_dispute", func(ctx context.Context) error {
+		var err error
+		p, err = s.repo.FindByID(ctx, paymentID)
+		if err != nil {
+			return err
+		}
+		if err := p.ResolveDispute(note); err != nil {
+			return err
+		}
+		p.IncrementVersion()
+		return s.repo.Update(ctx, p)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	s.logger.Info("payment dispute resolved",
+		zap.String("payment_id", paymentID.String()),
+		zap.String("actor", payment.ActorFromContext(ctx)),
+		zap.String("escrow_status", string(p.EscrowStatus())),
+	)
+
+	dto := toAdminPaymentDTO(p)
+	return &dto, nil
+}
+
 // --- Admin methods ---
 
 // PaymentStatsDTO holds payment statistics for the admin dashboard.
@@ -187,23 +1260,210 @@ type PaymentStatsDTO struct {
 	ByStatus          map[string]int64 `json:"by_status"`
 }
 
-// ListAllPayments returns a paginated list of all payments (admin).
-func (s *PaymentService) ListAllPayments(ctx context.Context, page, limit int) ([]PaymentDTO, int64, error) {
-	payments, total, err := s.repo.ListAll(ctx, page, limit)
+// PaymentListFilter narrows admin payment listings and exports.
+// From is inclusive and To is exclusive; nil means unbounded.
+type PaymentListFilter struct {
+	Status string
+	From   *time.Time
+	To     *time.Time
+	// IncludeTest includes test payments, which are left out by default.
+	IncludeTest bool
+}
+
+// toDomain validates the filter and maps it to the repository filter.
+func (f PaymentListFilter) toDomain() (payment.ListFilter, error) {
+	filter := payment.ListFilter{From: f.From, To: f.To, IncludeTest: f.IncludeTest}
+	if f.Status != "" {
+		status := payment.EscrowStatus(f.Status)
+		switch status {
+		case payment.EscrowPending, payment.EscrowHeld, payment.EscrowReleased, payment.EscrowRefunded, payment.EscrowFailed, payment.EscrowDisputed, payment.EscrowChargedBack:
+			filter.Status = status
+		default:
+			return payment.ListFilter{}, fmt.Errorf("invalid status filter: %s", f.Status)
+		}
+	}
+	if f.From != nil && f.To != nil && !f.To.After(*f.From) {
+		return payment.ListFilter{}, fmt.Errorf("to must be after from")
+	}
+	return filter, nil
+}
+
+// ListAllPayments returns a paginated, filtered list of all payments (admin).
+func (s *PaymentService) ListAllPayments(ctx context.Context, f PaymentListFilter, page, limit int) ([]AdminPaymentDTO, int64, error) {
+	filter, err := f.toDomain()
+	if err != nil {
+		return nil, 0, err
+	}
+
+	payments, total, err := s.repo.ListAll(ctx, filter, page, limit)
 	if err != nil {
 		return nil, 0, err
 	}
 
-	dtos := make([]PaymentDTO, len(payments))
+	dtos := make([]AdminPaymentDTO, len(payments))
 	for i, p := range payments {
-		dtos[i] = toPaymentDTO(p)
+		dtos[i] = toAdminPaymentDTO(p)
 	}
 	return dtos, total, nil
 }
 
-// GetPaymentStats returns aggregate payment statistics (admin).
-func (s *PaymentService) GetPaymentStats(ctx context.Context) (*PaymentStatsDTO, error) {
-	revenue, counts, err := s.repo.GetRevenueStats(ctx)
+// ErrInvalidSearchQuery is returned when a payment search query is blank or too short.
+var ErrInvalidSearchQuery = errors.New("invalid search query")
+
+const (
+	// minPaymentSearchLength keeps a search from matching every payment, e.g.
+	// a bare "pi_".
+	minPaymentSearchLength = 6
+	// maxPaymentSearchResults caps how many payments a search returns.
+	maxPaymentSearchResults = 20
+)
+
+// SearchPayments finds payments for support (admin). q matches payments whose
+// Stripe payment ID starts with it and, if it is a UUID, the payment of that
+// booking. At most maxPaymentSearchResults are returned, newest first.
+func (s *PaymentService) SearchPayments(ctx context.Context, q string) ([]AdminPaymentDTO, error) {
+	q = strings.TrimSpace(q)
+	if len(q) < minPaymentSearchLength {
+		return nil, fmt.Errorf("%w: q must be at least %d characters", ErrInvalidSearchQuery, minPaymentSearchLength)
+	}
+
+	var bookingID *uuid.UUID
+	if id, err := uuid.Parse(q); err == nil {
+		bookingID = &id
+	}
+
+	payments, err := s.repo.Search(ctx, q, bookingID, maxPaymentSearchResults)
+	if err != nil {
+		return nil, err
+	}
+
+	dtos := make([]AdminPaymentDTO, len(payments))
+	for i, p := range payments {
+		dtos[i] = toAdminPaymentDTO(p)
+	}
+	return dtos, nil
+}
+
+// ExportPayments streams every payment matching the filter to fn, oldest first (admin).
+// Filter validation errors are returned before fn is ever called.
+func (s *PaymentService) ExportPayments(ctx context.Context, f PaymentListFilter, fn func(AdminPaymentDTO) error) error {
+	filter, err := f.toDomain()
+	if err != nil {
+		return err
+	}
+
+	return s.repo.StreamAll(ctx, filter, func(p *payment.Payment) error {
+		return fn(toAdminPaymentDTO(p))
+	})
+}
+
+// InitiatorDTO identifies who or what made a change: an actor type such as
+// owner, admin or system, and the user ID or system component.
+type InitiatorDTO struct {
+	Type string `json:"type"`
+	ID   string `json:"id,omitempty"`
+}
+
+// StatusChangeDTO is the API representation of one escrow status transition.
+type StatusChangeDTO struct {
+	FromStatus  string       `json:"from_status,omitempty"`
+	ToStatus    string       `json:"to_status"`
+	Reason      string       `json:"reason,omitempty"`
+	Actor       string       `json:"actor"`
+	InitiatedBy InitiatorDTO `json:"initiated_by"`
+	OccurredAt  time.Time    `json:"occurred_at"`
+}
+
+// GetPaymentHistory returns the status transition history of a payment, oldest first (admin).
+func (s *PaymentService) GetPaymentHistory(ctx context.Context, paymentID uuid.UUID) ([]StatusChangeDTO, error) {
+	if _, err := s.repo.FindByID(ctx, paymentID); err != nil {
+		return nil, err
+	}
+
+	changes, err := s.repo.FindStatusHistory(ctx, paymentID)
+	if err != nil {
+		return nil, err
+	}
+
+	dtos := make([]StatusChangeDTO, len(changes))
+	for i, c := range changes {
+		dtos[i] = toStatusChangeDTO(c)
+	}
+	return dtos, nil
+}
+
+func toStatusChangeDTO(c payment.StatusChange) StatusChangeDTO {
+	initiator := c.InitiatedBy()
+	return StatusChangeDTO{
+		FromStatus:  string(c.From),
+		ToStatus:    string(c.To),
+		Reason:      c.Reason,
+		Actor:       c.Actor,
+		InitiatedBy: InitiatorDTO{Type: initiator.Type, ID: initiator.ID},
+		OccurredAt:  c.OccurredAt,
+	}
+}
+
+// AgingBucketDTO totals held escrow of one currency within an age bucket.
+type AgingBucketDTO struct {
+	Bucket     string `json:"bucket"`
+	Currency   string `json:"currency"`
+	Count      int64  `json:"count"`
+	TotalCents int64  `json:"total_cents"`
+}
+
+// GetEscrowAging returns held escrow grouped by time since it was held, youngest
+// bucket first, then by currency (admin).
+func (s *PaymentService) GetEscrowAging(ctx context.Context) ([]AgingBucketDTO, error) {
+	rows, err := s.repo.GetEscrowAging(ctx, time.Now().UTC())
+	if err != nil {
+		return nil, err
+	}
+
+	order := make(map[string]int, len(payment.AgingBuckets))
+	for i, b := range payment.AgingBuckets {
+		order[b] = i
+	}
+	sort.Slice(rows, func(i, j int) bool {
+		if rows[i].Bucket != rows[j].Bucket {
+			return order[rows[i].Bucket] < order[rows[j].Bucket]
+		}
+		return rows[i].Currency < rows[j].Currency
+	})
+
+	dtos := make([]AgingBucketDTO, len(rows))
+	for i, r := range rows {
+		dtos[i] = AgingBucketDTO{Bucket: r.Bucket, Currency: r.Currency, Count: r.Count, TotalCents: r.TotalCents}
+	}
+	return dtos, nil
+}
+
+// EscrowBalanceDTO is the total held escrow in one currency.
+type EscrowBalanceDTO struct {
+	Currency   string `json:"currency"`
+	TotalCents int64  `json:"total_cents"`
+}
+
+// GetEscrowBalance returns the funds currently held in escrow per currency,
+// ordered by currency (admin).
+func (s *PaymentService) GetEscrowBalance(ctx context.Context) ([]EscrowBalanceDTO, error) {
+	totals, err := s.repo.SumHeldByCurrency(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	dtos := make([]EscrowBalanceDTO, 0, len(totals))
+	for currency, cents := range totals {
+		dtos = append(dtos, EscrowBalanceDTO{Currency: currency, TotalCents: cents})
+	}
+	sort.Slice(dtos, func(i, j int) bool { return dtos[i].Currency < dtos[j].Currency })
+	return dtos, nil
+}
+
+// GetPaymentStats returns aggregate payment statistics (admin). Test
+// payments are left out unless includeTest is set.
+func (s *PaymentService) GetPaymentStats(ctx context.Context, includeTest bool) (*PaymentStatsDTO, error) {
+	revenue, counts, err := s.repo.GetRevenueStats(ctx, includeTest)
 	if err != nil {
 		return nil, err
 	}
@@ -220,6 +1480,15 @@ func (s *PaymentService) GetPaymentStats(ctx context.Context) (*PaymentStatsDTO,
 	}, nil
 }
 
+// toDiscountLines maps breakdown lines to domain discount lines for persistence.
+func toDiscountLines(lines []DiscountLineDTO) []payment.DiscountLine {
+	out := make([]payment.DiscountLine, len(lines))
+	for i, l := range lines {
+		out[i] = payment.DiscountLine{Source: l.Source, Code: l.Code, AmountCents: l.AmountCents}
+	}
+	return out
+}
+
 // toPaymentDTO maps a domain Payment to a PaymentDTO.
 func toPaymentDTO(p *payment.Payment) PaymentDTO {
 	return PaymentDTO{
@@ -232,14 +1501,24 @@ func toPaymentDTO(p *payment.Payment) PaymentDTO {
 		PlatformFeeCents:  p.PlatformFeeCents(),
 		RunnerPayoutCents: p.RunnerPayoutCents(),
 		Currency:          p.Currency(),
+		MinorUnits:        p.Amount().MinorUnits(),
 		PaymentMethod:     p.PaymentMethod(),
 		StripePaymentID:   p.StripePaymentID(),
 		EscrowHeldAt:      p.EscrowHeldAt(),
 		EscrowReleasedAt:  p.EscrowReleasedAt(),
 		RefundedAt:        p.RefundedAt(),
+		ReleaseEligibleAt: p.ReleaseEligibleAt(),
 		RefundReason:      p.RefundReason(),
 		Version:           p.Version(),
 		CreatedAt:         p.CreatedAt(),
 		UpdatedAt:         p.UpdatedAt(),
+
+		CreditAppliedCents:     p.CreditAppliedCents(),
+		CallbackURL:            p.CallbackURL(),
+		AwaitingAuthentication: p.AwaitingAuthentication(),
 	}
 }
+
+func toAdminPaymentDTO(p *payment.Payment) AdminPaymentDTO {
+	return AdminPaymentDTO{PaymentDTO: toPaymentDTO(p), CustomerEmail: p.CustomerEmail(), ReviewReason: p.ReviewReason(), IsTest: p.IsTest()}
+}
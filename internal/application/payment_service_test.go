@@ -0,0 +1,779 @@
+package application
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Kilat-Pet-Delivery/lib-common/domain"
+	"github.com/Kilat-Pet-Delivery/lib-common/kafka"
+	"github.com/Kilat-Pet-Delivery/lib-proto/events"
+	"github.com/Kilat-Pet-Delivery/service-payment/internal/adapter"
+	bookingDomain "github.com/Kilat-Pet-Delivery/service-payment/internal/domain/booking"
+	"github.com/Kilat-Pet-Delivery/service-payment/internal/domain/payment"
+	promoDomain "github.com/Kilat-Pet-Delivery/service-payment/internal/domain/promo"
+	subDomain "github.com/Kilat-Pet-Delivery/service-payment/internal/domain/subscription"
+	"github.com/Kilat-Pet-Delivery/service-payment/internal/feature"
+	"github.com/Kilat-Pet-Delivery/service-payment/internal/saga"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestRefundRequest_Normalize(t *testing.T) {
+	tests := []struct {
+		name    string
+		req     RefundRequest
+		want    string
+		wantErr bool
+	}{
+		{name: "trims free text", req: RefundRequest{Reason: "  driver no-show \n"}, want: "driver no-show"},
+		{name: "prefixes known code", req: RefundRequest{ReasonCode: "Duplicate", Reason: "charged twice"}, want: "duplicate: charged twice"},
+		{name: "blank after trim", req: RefundRequest{Reason: "   "}, wantErr: true},
+		{name: "unknown code", req: RefundRequest{ReasonCode: "because", Reason: "x"}, wantErr: true},
+		{name: "at max length", req: RefundRequest{Reason: strings.Repeat("a", maxRefundReasonLength)}, want: strings.Repeat("a", maxRefundReasonLength)},
+		{name: "over max length", req: RefundRequest{Reason: strings.Repeat("a", maxRefundReasonLength+1)}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.req.normalize()
+			if tt.wantErr {
+				require.Error(t, err)
+				assert.True(t, errors.Is(err, ErrInvalidRefundReason))
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestRefundRequest_RefundMethod(t *testing.T) {
+	method, err := RefundRequest{}.refundMethod()
+	require.NoError(t, err)
+	assert.Equal(t, payment.RefundToCard, method, "card is the default")
+
+	method, err = RefundRequest{Method: " Credit "}.refundMethod()
+	require.NoError(t, err)
+	assert.Equal(t, payment.RefundToCredit, method)
+
+	_, err = RefundRequest{Method: "cash"}.refundMethod()
+	assert.True(t, errors.Is(err, ErrInvalidRefundMethod))
+}
+
+// bookingsRepo serves FindByBookingIDs from a fixed set of payments.
+type bookingsRepo struct {
+	payment.PaymentRepository
+
+	payments []*payment.Payment
+}
+
+func (r *bookingsRepo) FindByBookingIDs(_ context.Context, ids []uuid.UUID) ([]*payment.Payment, error) {
+	var found []*payment.Payment
+	for _, p := range r.payments {
+		for _, id := range ids {
+			if p.BookingID() == id {
+				found = append(found, p)
+			}
+		}
+	}
+	return found, nil
+}
+
+func TestGetPaymentStatusesByBookings(t *testing.T) {
+	owner, other := uuid.New(), uuid.New()
+	mine, err := payment.NewPayment(uuid.New(), owner, 10000, "MYR", 15.0, payment.PayoutFloors{})
+	require.NoError(t, err)
+	theirs, err := payment.NewPayment(uuid.New(), other, 20000, "MYR", 15.0, payment.PayoutFloors{})
+	require.NoError(t, err)
+
+	svc := NewPaymentService(&bookingsRepo{payments: []*payment.Payment{mine, theirs}}, nil, nil, nil, nil, nil, nil, nil, zap.NewNop())
+	ids := []uuid.UUID{mine.BookingID(), theirs.BookingID(), uuid.New()}
+
+	t.Run("owner sees only own payments", func(t *testing.T) {
+		statuses, err := svc.GetPaymentStatusesByBookings(context.Background(), owner, false, ids)
+		require.NoError(t, err)
+		require.Len(t, statuses, 1)
+		assert.Equal(t, int64(10000), statuses[mine.BookingID()].AmountCents)
+		assert.Equal(t, "pending", statuses[mine.BookingID()].EscrowStatus)
+	})
+
+	t.Run("admin sees all payments", func(t *testing.T) {
+		statuses, err := svc.GetPaymentStatusesByBookings(context.Background(), owner, true, ids)
+		require.NoError(t, err)
+		assert.Len(t, statuses, 2)
+	})
+
+	t.Run("too many booking IDs", func(t *testing.T) {
+		_, err := svc.GetPaymentStatusesByBookings(context.Background(), owner, true, make([]uuid.UUID, MaxBatchBookingIDs+1))
+		assert.Error(t, err)
+	})
+}
+
+// singlePaymentRepo holds one payment and counts updates.
+type singlePaymentRepo struct {
+	payment.PaymentRepository
+
+	p       *payment.Payment
+	updates int
+}
+
+func (r *singlePaymentRepo) FindByID(_ context.Context, id uuid.UUID) (*payment.Payment, error) {
+	if r.p.ID() != id {
+		return nil, domain.NewNotFoundError("Payment", id.String())
+	}
+	return r.p, nil
+}
+
+func (r *singlePaymentRepo) Update(context.Context, *payment.Payment) error {
+	r.updates++
+	return nil
+}
+
+func TestOpenDispute_Idempotent(t *testing.T) {
+	p, err := payment.NewPayment(uuid.New(), uuid.New(), 10000, "MYR", 15.0, payment.PayoutFloors{})
+	require.NoError(t, err)
+	require.NoError(t, p.HoldEscrow("pi_1", 0))
+	repo := &singlePaymentRepo{p: p}
+	svc := NewPaymentService(repo, nil, nil, nil, nil, nil, nil, nil, zap.NewNop())
+
+	require.NoError(t, svc.OpenDispute(context.Background(), p.ID(), "item not delivered"))
+	assert.Equal(t, payment.EscrowDisputed, p.EscrowStatus())
+	assert.Equal(t, 1, repo.updates)
+
+	require.NoError(t, svc.OpenDispute(context.Background(), p.ID(), "item not delivered"), "a redelivered request is ignored")
+	assert.Equal(t, 1, repo.updates)
+
+	assert.NoError(t, svc.OpenDispute(context.Background(), uuid.New(), "unknown"), "unknown payments are skipped")
+}
+
+func TestOpenDispute_SkipsNonDisputableStates(t *testing.T) {
+	p, err := payment.NewPayment(uuid.New(), uuid.New(), 10000, "MYR", 15.0, payment.PayoutFloors{})
+	require.NoError(t, err)
+	repo := &singlePaymentRepo{p: p}
+	svc := NewPaymentService(repo, nil, nil, nil, nil, nil, nil, nil, zap.NewNop())
+
+	require.NoError(t, svc.OpenDispute(context.Background(), p.ID(), "too early"))
+	assert.Equal(t, payment.EscrowPending, p.EscrowStatus())
+	assert.Zero(t, repo.updates)
+}
+
+// ownerCountRepo reports a fixed number of prior payments for every owner.
+type ownerCountRepo struct {
+	payment.PaymentRepository
+	count int64
+}
+
+func (r *ownerCountRepo) CountPaymentsByOwner(context.Context, uuid.UUID) (int64, error) {
+	return r.count, nil
+}
+
+func TestInitiatePayment_RejectsFirstBookingPromoForReturningOwner(t *testing.T) {
+	promos := &codePromoRepo{promos: map[string]*promoDomain.PromoCode{"WELCOME": newFirstBookingPromo(t)}}
+	svc := NewPaymentService(&ownerCountRepo{count: 1}, promos, nil, nil, nil, nil, nil, nil, zap.NewNop())
+
+	_, err := svc.InitiatePayment(context.Background(), uuid.New(), InitiatePaymentRequest{
+		BookingID:   uuid.New(),
+		AmountCents: 5000,
+		Currency:    "MYR",
+		PromoCode:   "welcome",
+	})
+	assert.ErrorIs(t, err, promoDomain.ErrFirstBookingOnly)
+}
+
+// activeSubRepo reports the same active subscription for every user.
+type activeSubRepo struct {
+	subDomain.SubscriptionRepository
+	sub *subDomain.Subscription
+}
+
+func (r *activeSubRepo) FindActiveByUserID(context.Context, uuid.UUID) (*subDomain.Subscription, error) {
+	return r.sub, nil
+}
+
+func TestQuotePayment_PremiumOnlyPromo(t *testing.T) {
+	for _, plan := range []subDomain.PlanType{subDomain.PlanBasic, subDomain.PlanPremium} {
+		t.Run(string(plan), func(t *testing.T) {
+			owner := uuid.New()
+			sub, err := subDomain.NewSubscription(owner, plan)
+			require.NoError(t, err)
+			promos := &codePromoRepo{promos: map[string]*promoDomain.PromoCode{"PREMIUM20": newPremiumOnlyPromo(t)}}
+			repo := &ownerCountRepo{}
+			sagaSvc := saga.NewPaymentSagaService(repo, nil, nil, nil, nil, nil, nil, nil, 15.0, payment.PayoutFloors{}, 0, 0, zap.NewNop())
+			svc := NewPaymentService(repo, promos, &activeSubRepo{sub: sub}, nil, sagaSvc,
+				NewDiscountEngine(DiscountPolicy{Stacking: StackingAdditive}), NewCurrencyAllowlist([]string{"MYR"}), nil, zap.NewNop())
+
+			quote, err := svc.QuotePayment(context.Background(), owner, QuotePaymentRequest{
+				BookingID:   uuid.New(),
+				AmountCents: 10000,
+				Currency:    "MYR",
+				PromoCode:   "PREMIUM20",
+			})
+			if plan == subDomain.PlanBasic {
+				assert.ErrorIs(t, err, promoDomain.ErrPlanNotEligible)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, int64(2000), quote.PromoDiscountCents)
+		})
+	}
+}
+
+func TestQuotePayment_AcceptsDecimalOrMinorUnitAmount(t *testing.T) {
+	tests := []struct {
+		name        string
+		currency    string
+		amount      string
+		amountCents int64
+		wantGross   int64
+		wantErr     error
+	}{
+		{"decimal MYR", "MYR", "19.90", 0, 1990, nil},
+		{"minor units MYR", "MYR", "", 1990, 1990, nil},
+		{"decimal JPY", "JPY", "1990", 0, 1990, nil},
+		{"minor units JPY", "JPY", "", 1990, 1990, nil},
+		{"decimal KWD", "KWD", "1.990", 0, 1990, nil},
+		{"too many decimals for JPY", "JPY", "19.90", 0, 0, payment.ErrInvalidAmount},
+		{"zero", "MYR", "0.00", 0, 0, payment.ErrInvalidAmount},
+		{"both fields", "MYR", "19.90", 1990, 0, ErrAmountFields},
+		{"neither field", "MYR", "", 0, 0, ErrAmountFields},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := &ownerCountRepo{}
+			sagaSvc := saga.NewPaymentSagaService(repo, nil, nil, nil, nil, nil, nil, nil, 15.0, payment.PayoutFloors{}, 0, 0, zap.NewNop())
+			svc := NewPaymentService(repo, nil, &activeSubRepo{}, nil, sagaSvc,
+				NewDiscountEngine(DiscountPolicy{}), NewCurrencyAllowlist([]string{"MYR", "JPY", "KWD"}), nil, zap.NewNop())
+
+			quote, err := svc.QuotePayment(context.Background(), uuid.New(), QuotePaymentRequest{
+				BookingID:   uuid.New(),
+				Amount:      tt.amount,
+				AmountCents: tt.amountCents,
+				Currency:    tt.currency,
+			})
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantGross, quote.GrossCents)
+			assert.Equal(t, tt.wantGross, quote.FinalChargeCents)
+		})
+	}
+}
+
+func TestQuotePayment_ItemizesWithoutPersisting(t *testing.T) {
+	now := time.Now().UTC()
+	owner := uuid.New()
+	promo, err := promoDomain.NewPromoCode("SAVE10", promoDomain.DiscountTypeFixed, 1000, 0, 0, 0, now.Add(-time.Hour), now.Add(time.Hour), uuid.New())
+	require.NoError(t, err)
+	promos := &codePromoRepo{promos: map[string]*promoDomain.PromoCode{"SAVE10": promo}}
+	sub, err := subDomain.NewSubscription(owner, subDomain.PlanPremium)
+	require.NoError(t, err)
+
+	// The embedded repositories and nil Stripe adapter panic if the quote
+	// tries to persist or authorize anything.
+	repo := &ownerCountRepo{}
+	sagaSvc := saga.NewPaymentSagaService(repo, nil, nil, nil, nil, nil, nil, nil, 15.0, payment.PayoutFloors{}, 0, 0, zap.NewNop())
+	svc := NewPaymentService(repo, promos, &activeSubRepo{sub: sub}, nil, sagaSvc,
+		NewDiscountEngine(DiscountPolicy{Stacking: StackingAdditive}), NewCurrencyAllowlist([]string{"MYR"}), nil, zap.NewNop())
+
+	quote, err := svc.QuotePayment(context.Background(), owner, QuotePaymentRequest{
+		BookingID:   uuid.New(),
+		AmountCents: 10000,
+		Currency:    "myr",
+		PromoCode:   "save10",
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, "MYR", quote.Currency)
+	assert.Equal(t, int64(10000), quote.GrossCents)
+	assert.Equal(t, int64(1000), quote.PromoDiscountCents)
+	assert.Equal(t, int64(1500), quote.SubscriptionDiscountCents)
+	assert.Equal(t, int64(7500), quote.FinalChargeCents)
+	assert.Equal(t, int64(1125), quote.PlatformFeeCents)
+	assert.Equal(t, int64(6375), quote.RunnerPayoutCents)
+	assert.Equal(t, quote.FinalChargeCents, quote.PlatformFeeCents+quote.RunnerPayoutCents)
+	assert.Zero(t, promo.CurrentUses(), "a quote does not redeem the promo")
+}
+
+// memoryPaymentRepo keeps payments in memory for sagas run end to end.
+type memoryPaymentRepo struct {
+	payment.PaymentRepository
+	payments map[uuid.UUID]*payment.Payment
+}
+
+func (r *memoryPaymentRepo) Save(_ context.Context, p *payment.Payment) error {
+	r.payments[p.ID()] = p
+	return nil
+}
+
+func (r *memoryPaymentRepo) Update(_ context.Context, p *payment.Payment) error {
+	r.payments[p.ID()] = p
+	return nil
+}
+
+func (r *memoryPaymentRepo) FindByID(_ context.Context, id uuid.UUID) (*payment.Payment, error) {
+	if p, ok := r.payments[id]; ok {
+		return p, nil
+	}
+	return nil, domain.NewNotFoundError("Payment", id.String())
+}
+
+func (r *memoryPaymentRepo) FindByBookingID(_ context.Context, bookingID uuid.UUID) (*payment.Payment, error) {
+	for _, p := range r.payments {
+		if p.BookingID() == bookingID {
+			return p, nil
+		}
+	}
+	return nil, domain.NewNotFoundError("Payment", bookingID.String())
+}
+
+func (r *memoryPaymentRepo) SaveDiscounts(context.Context, uuid.UUID, []payment.DiscountLine) error {
+	return nil
+}
+
+func (r *memoryPaymentRepo) FindDiscounts(context.Context, uuid.UUID) ([]payment.DiscountLine, error) {
+	return nil, nil
+}
+
+func (r *memoryPaymentRepo) FindLatestAmountAdjustment(context.Context, uuid.UUID) (*payment.AmountAdjustment, error) {
+	return nil, nil
+}
+
+func TestInitiatePayment_ReturnsClientSecretOnlyOnCreation(t *testing.T) {
+	repo := &memoryPaymentRepo{payments: map[uuid.UUID]*payment.Payment{}}
+	sagaSvc := saga.NewPaymentSagaService(repo, adapter.NewMockStripeAdapter(zap.NewNop()), nil, nil, nil, nil, nil, nil, 15.0, payment.PayoutFloors{}, 0, 0, zap.NewNop())
+	svc := NewPaymentService(repo, nil, &activeSubRepo{}, nil, sagaSvc,
+		NewDiscountEngine(DiscountPolicy{}), NewCurrencyAllowlist([]string{"MYR"}), nil, zap.NewNop())
+
+	created, err := svc.InitiatePayment(context.Background(), uuid.New(), InitiatePaymentRequest{
+		BookingID:     uuid.New(),
+		AmountCents:   5000,
+		Currency:      "MYR",
+		CustomerEmail: "owner@example.com",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, created.StripePaymentID+"_secret_mock", created.ClientSecret)
+
+	fetched, err := svc.GetPayment(context.Background(), created.ID)
+	require.NoError(t, err)
+	assert.Empty(t, fetched.ClientSecret, "the client secret is never returned after creation")
+	byBooking, err := svc.GetPaymentByBooking(context.Background(), created.BookingID)
+	require.NoError(t, err)
+	assert.Empty(t, byBooking.ClientSecret)
+}
+
+func TestRetryPayment_OnlyRetriesFailedPayments(t *testing.T) {
+	repo := &memoryPaymentRepo{payments: map[uuid.UUID]*payment.Payment{}}
+	stripe := adapter.NewMockStripeAdapter(zap.NewNop())
+	sagaSvc := saga.NewPaymentSagaService(repo, stripe, nil, nil, nil, nil, nil, nil, 15.0, payment.PayoutFloors{}, 0, 0, zap.NewNop())
+	svc := NewPaymentService(repo, nil, nil, nil, sagaSvc, nil, nil, nil, zap.NewNop())
+
+	held, err := payment.NewPayment(uuid.New(), uuid.New(), 5000, "MYR", 15.0, payment.PayoutFloors{})
+	require.NoError(t, err)
+	require.NoError(t, held.HoldEscrow("pi_held", 0))
+	repo.payments[held.ID()] = held
+
+	_, err = svc.RetryPayment(context.Background(), held.ID(), RetryPaymentRequest{})
+	assert.ErrorIs(t, err, domain.ErrConflict, "the booking already has a successful payment")
+	assert.Equal(t, "pi_held", held.StripePaymentID(), "no new escrow is started")
+
+	failed, err := payment.NewPayment(uuid.New(), uuid.New(), 5000, "MYR", 15.0, payment.PayoutFloors{})
+	require.NoError(t, err)
+	require.NoError(t, failed.Fail("card declined"))
+	repo.payments[failed.ID()] = failed
+
+	retried, err := svc.RetryPayment(context.Background(), failed.ID(), RetryPaymentRequest{})
+	require.NoError(t, err)
+	assert.Equal(t, string(payment.EscrowHeld), retried.EscrowStatus)
+
+	_, err = svc.RetryPayment(context.Background(), failed.ID(), RetryPaymentRequest{})
+	assert.ErrorIs(t, err, domain.ErrConflict, "a retried payment is not retried again")
+}
+
+func TestDecideDisputeForOwner_RefundsPaymentDisputedBeforeRelease(t *testing.T) {
+	repo := &memoryPaymentRepo{payments: map[uuid.UUID]*payment.Payment{}}
+	stripe := adapter.NewMockStripeAdapter(zap.NewNop())
+	sagaSvc := saga.NewPaymentSagaService(repo, stripe, nil, nil, nil, nil, nil, nil, 15.0, payment.PayoutFloors{}, 0, 0, zap.NewNop())
+	svc := NewPaymentService(repo, nil, nil, nil, sagaSvc, nil, nil, nil, zap.NewNop())
+
+	p, err := payment.NewPayment(uuid.New(), uuid.New(), 5000, "MYR", 15.0, payment.PayoutFloors{})
+	require.NoError(t, err)
+	require.NoError(t, p.HoldEscrow("pi_1", 0))
+	repo.payments[p.ID()] = p
+
+	require.NoError(t, svc.DecideDisputeForOwner(context.Background(), p.ID(), "chargeback lost"), "a payment not in dispute is skipped")
+	assert.Equal(t, payment.EscrowHeld, p.EscrowStatus())
+
+	require.NoError(t, svc.OpenDispute(context.Background(), p.ID(), "item not delivered"))
+	require.NoError(t, svc.DecideDisputeForOwner(context.Background(), p.ID(), "chargeback lost"))
+	assert.Equal(t, payment.EscrowRefunded, p.EscrowStatus())
+	assert.Equal(t, "chargeback lost", p.RefundReason())
+
+	require.NoError(t, svc.DecideDisputeForOwner(context.Background(), p.ID(), "chargeback lost"), "a redelivered decision is ignored")
+	assert.NoError(t, svc.DecideDisputeForOwner(context.Background(), uuid.New(), "unknown"), "unknown payments are skipped")
+}
+
+func TestResolveDispute_ReturnsPaymentToHeld(t *testing.T) {
+	p, err := payment.NewPayment(uuid.New(), uuid.New(), 10000, "MYR", 15.0, payment.PayoutFloors{})
+	require.NoError(t, err)
+	require.NoError(t, p.HoldEscrow("pi_1", 0))
+	repo := &singlePaymentRepo{p: p}
+	svc := NewPaymentService(repo, nil, nil, nil, nil, nil, nil, nil, zap.NewNop())
+
+	_, err = svc.ResolveDispute(context.Background(), p.ID(), ResolveDisputeRequest{Note: "delivery confirmed"})
+	assert.ErrorIs(t, err, domain.ErrInvalidState, "only a disputed payment can be resolved")
+
+	require.NoError(t, svc.OpenDispute(context.Background(), p.ID(), "item not delivered"))
+	dto, err := svc.ResolveDispute(context.Background(), p.ID(), ResolveDisputeRequest{Note: " delivery confirmed "})
+	require.NoError(t, err)
+	assert.Equal(t, string(payment.EscrowHeld), dto.EscrowStatus)
+	assert.Equal(t, 2, repo.updates)
+}
+
+func TestInitiatePayment_MarksTestPayments(t *testing.T) {
+	tests := []struct {
+		name           string
+		stripeTestMode bool
+		allowTestFlag  bool
+		requestTest    bool
+		wantTest       bool
+		wantErr        error
+	}{
+		{"live keys", false, false, false, false, nil},
+		{"stripe test keys", true, false, false, true, nil},
+		{"flag outside production", false, true, true, true, nil},
+		{"flag in production", false, false, true, false, ErrTestPaymentsDisabled},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := &memoryPaymentRepo{payments: map[uuid.UUID]*payment.Payment{}}
+			sagaSvc := saga.NewPaymentSagaService(repo, adapter.NewMockStripeAdapter(zap.NewNop()), nil, nil, nil, nil, nil, nil, 15.0, payment.PayoutFloors{}, 0, 0, zap.NewNop())
+			svc := NewPaymentService(repo, nil, &activeSubRepo{}, nil, sagaSvc,
+				NewDiscountEngine(DiscountPolicy{}), NewCurrencyAllowlist([]string{"MYR"}), nil, zap.NewNop())
+			svc.SetTestPayments(tt.stripeTestMode, tt.allowTestFlag)
+
+			created, err := svc.InitiatePayment(context.Background(), uuid.New(), InitiatePaymentRequest{
+				BookingID:     uuid.New(),
+				AmountCents:   5000,
+				Currency:      "MYR",
+				CustomerEmail: "owner@example.com",
+				Test:          tt.requestTest,
+			})
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				assert.Empty(t, repo.payments)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantTest, repo.payments[created.ID].IsTest())
+		})
+	}
+}
+
+// redeemingPromoRepo is a codePromoRepo that records redemptions.
+type redeemingPromoRepo struct {
+	codePromoRepo
+
+	usages []*promoDomain.PromoUsage
+}
+
+func (r *redeemingPromoRepo) SaveUsage(_ context.Context, usage *promoDomain.PromoUsage) error {
+	r.usages = append(r.usages, usage)
+	return nil
+}
+
+func (r *redeemingPromoRepo) Update(context.Context, *promoDomain.PromoCode) error {
+	return nil
+}
+
+func TestInitiatePayment_FullDiscountHoldsFreePayment(t *testing.T) {
+	now := time.Now().UTC()
+	free, err := promoDomain.NewPromoCode("FREERIDE", promoDomain.DiscountTypePercentage, 100, 0, 0, 0, now.Add(-time.Hour), now.Add(time.Hour), uuid.New())
+	require.NoError(t, err)
+	promos := &redeemingPromoRepo{codePromoRepo: codePromoRepo{promos: map[string]*promoDomain.PromoCode{"FREERIDE": free}}}
+	repo := &memoryPaymentRepo{payments: map[uuid.UUID]*payment.Payment{}}
+	publisher := &recordingPublisher{}
+	// Stripe is nil: a free booking must never reach it.
+	sagaSvc := saga.NewPaymentSagaService(repo, nil, publisher, nil, nil, nil, nil, nil, 15.0,
+		payment.PayoutFloors{MinRunnerPayoutCents: 500, MinPlatformFeeCents: 100}, 0, 0, zap.NewNop())
+	svc := NewPaymentService(repo, promos, &activeSubRepo{}, nil, sagaSvc,
+		NewDiscountEngine(DiscountPolicy{}), NewCurrencyAllowlist([]string{"MYR"}), nil, zap.NewNop())
+
+	created, err := svc.InitiatePayment(context.Background(), uuid.New(), InitiatePaymentRequest{
+		BookingID:     uuid.New(),
+		AmountCents:   5000,
+		Currency:      "MYR",
+		CustomerEmail: "owner@example.com",
+		PromoCode:     "FREERIDE",
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, string(payment.EscrowHeld), created.EscrowStatus)
+	assert.Zero(t, created.AmountCents)
+	assert.Zero(t, created.PlatformFeeCents)
+	assert.Zero(t, created.RunnerPayoutCents)
+	assert.Empty(t, created.StripePaymentID)
+	assert.Empty(t, created.ClientSecret)
+	assert.Equal(t, int64(5000), created.Discount.BaseAmountCents)
+	assert.Zero(t, created.Discount.FinalAmountCents)
+	assert.True(t, repo.payments[created.ID].IsFree())
+
+	require.Len(t, promos.usages, 1)
+	assert.Equal(t, int64(5000), promos.usages[0].DiscountCents)
+
+	held := publisher.events[events.TopicPaymentEvents]
+	require.NotEmpty(t, held)
+	assert.Equal(t, events.PaymentEscrowHeld, held[len(held)-1].Type)
+	var event events.EscrowHeldEvent
+	require.NoError(t, held[len(held)-1].ParseData(&event))
+	assert.Equal(t, created.BookingID, event.BookingID)
+	assert.Zero(t, event.AmountCents)
+}
+
+func TestStripeWebhook_CompletesEscrowAfterAuthentication(t *testing.T) {
+	repo := &memoryPaymentRepo{payments: map[uuid.UUID]*payment.Payment{}}
+	stripe := adapter.NewMockStripeAdapter(zap.NewNop())
+	stripe.RequireAuthentication(true)
+	sagaSvc := saga.NewPaymentSagaService(repo, stripe, nil, nil, nil, nil, nil, nil, 15.0, payment.PayoutFloors{}, 0, 0, zap.NewNop())
+	svc := NewPaymentService(repo, nil, &activeSubRepo{}, nil, sagaSvc,
+		NewDiscountEngine(DiscountPolicy{}), NewCurrencyAllowlist([]string{"MYR"}), nil, zap.NewNop())
+
+	created, err := svc.InitiatePayment(context.Background(), uuid.New(), InitiatePaymentRequest{
+		BookingID:     uuid.New(),
+		AmountCents:   5000,
+		Currency:      "MYR",
+		CustomerEmail: "owner@example.com",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, string(payment.EscrowPending), created.EscrowStatus)
+	assert.True(t, created.AwaitingAuthentication)
+	assert.NotEmpty(t, created.ClientSecret, "the client needs the secret to run 3-D Secure")
+
+	require.NoError(t, stripe.CompleteAuthentication(created.StripePaymentID))
+	event := &adapter.WebhookEvent{
+		ID:   "evt_1",
+		Type: adapter.WebhookIntentAuthorized,
+		Intent: adapter.WebhookIntent{
+			ID:            created.StripePaymentID,
+			Status:        adapter.IntentRequiresCapture,
+			Metadata:      map[string]string{"payment_id": created.ID.String()},
+			PaymentMethod: "visa ****4242",
+		},
+	}
+	require.NoError(t, svc.HandleStripeWebhook(context.Background(), event))
+
+	held, err := svc.GetPayment(context.Background(), created.ID)
+	require.NoError(t, err)
+	assert.Equal(t, string(payment.EscrowHeld), held.EscrowStatus)
+	assert.False(t, held.AwaitingAuthentication)
+	assert.Equal(t, "visa ****4242", held.PaymentMethod)
+	receipt, err := svc.GetReceipt(context.Background(), created.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "visa ****4242", receipt.PaymentMethod)
+
+	// Stripe redelivers webhooks; a second delivery changes nothing.
+	require.NoError(t, svc.HandleStripeWebhook(context.Background(), event))
+
+	// An event for a different intent is ignored rather than failing the delivery.
+	event.Intent.ID = "pi_other"
+	require.NoError(t, svc.HandleStripeWebhook(context.Background(), event))
+	again, err := svc.GetPayment(context.Background(), created.ID)
+	require.NoError(t, err)
+	assert.Equal(t, string(payment.EscrowHeld), again.EscrowStatus)
+}
+
+func TestCustomerEmail_OnlyInAdminDTOs(t *testing.T) {
+	repo := &memoryPaymentRepo{payments: map[uuid.UUID]*payment.Payment{}}
+	sagaSvc := saga.NewPaymentSagaService(repo, adapter.NewMockStripeAdapter(zap.NewNop()), nil, nil, nil, nil, nil, nil, 15.0, payment.PayoutFloors{}, 0, 0, zap.NewNop())
+	svc := NewPaymentService(repo, nil, &activeSubRepo{}, nil, sagaSvc,
+		NewDiscountEngine(DiscountPolicy{}), NewCurrencyAllowlist([]string{"MYR"}), nil, zap.NewNop())
+
+	created, err := svc.InitiatePayment(context.Background(), uuid.New(), InitiatePaymentRequest{
+		BookingID:     uuid.New(),
+		AmountCents:   5000,
+		Currency:      "MYR",
+		CustomerEmail: "owner@example.com",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "owner@example.com", repo.payments[created.ID].CustomerEmail())
+
+	owner, err := svc.GetPayment(context.Background(), created.ID)
+	require.NoError(t, err)
+	raw, err := json.Marshal(owner)
+	require.NoError(t, err)
+	assert.NotContains(t, string(raw), "owner@example.com", "owner-facing payments never carry the email")
+
+	admin, err := svc.OverridePlatformFee(context.Background(), created.ID, 500)
+	require.NoError(t, err)
+	assert.Equal(t, "owner@example.com", admin.CustomerEmail)
+}
+
+// Search matches like the GORM repository: Stripe ID prefix or exact booking.
+func (r *memoryPaymentRepo) Search(_ context.Context, stripeIDPrefix string, bookingID *uuid.UUID, limit int) ([]*payment.Payment, error) {
+	var found []*payment.Payment
+	for _, p := range r.payments {
+		if strings.HasPrefix(p.StripePaymentID(), stripeIDPrefix) || (bookingID != nil && p.BookingID() == *bookingID) {
+			found = append(found, p)
+		}
+	}
+	if len(found) > limit {
+		found = found[:limit]
+	}
+	return found, nil
+}
+
+func TestSearchPayments(t *testing.T) {
+	repo := &memoryPaymentRepo{payments: map[uuid.UUID]*payment.Payment{}}
+	svc := NewPaymentService(repo, nil, nil, nil, nil, NewDiscountEngine(DiscountPolicy{}), nil, nil, zap.NewNop())
+	seed := func(stripeID string) *payment.Payment {
+		p, err := payment.NewPayment(uuid.New(), uuid.New(), 5000, "MYR", 15, payment.PayoutFloors{})
+		require.NoError(t, err)
+		require.NoError(t, p.HoldEscrow(stripeID, 0))
+		repo.payments[p.ID()] = p
+		return p
+	}
+	a := seed("pi_3Nabc111")
+	b := seed("pi_3Nabc222")
+	c := seed("pi_9Zqrs333")
+	ids := func(dtos []AdminPaymentDTO) []uuid.UUID {
+		out := make([]uuid.UUID, len(dtos))
+		for i, d := range dtos {
+			out[i] = d.ID
+		}
+		return out
+	}
+
+	found, err := svc.SearchPayments(context.Background(), "  pi_3Nabc ")
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []uuid.UUID{a.ID(), b.ID()}, ids(found))
+
+	found, err = svc.SearchPayments(context.Background(), c.BookingID().String())
+	require.NoError(t, err)
+	assert.Equal(t, []uuid.UUID{c.ID()}, ids(found), "a UUID matches the booking exactly")
+
+	for _, q := range []string{"", "pi_", "  pi_3  "} {
+		_, err := svc.SearchPayments(context.Background(), q)
+		assert.ErrorIs(t, err, ErrInvalidSearchQuery, q)
+	}
+}
+
+// memoryBookingTotals keeps booking totals in a map.
+type memoryBookingTotals map[uuid.UUID]bookingDomain.Total
+
+func (m memoryBookingTotals) Save(_ context.Context, total bookingDomain.Total) error {
+	if _, ok := m[total.BookingID]; !ok {
+		m[total.BookingID] = total
+	}
+	return nil
+}
+
+func (m memoryBookingTotals) FindByBookingID(_ context.Context, bookingID uuid.UUID) (*bookingDomain.Total, error) {
+	total, ok := m[bookingID]
+	if !ok {
+		return nil, nil
+	}
+	return &total, nil
+}
+
+func TestInitiatePayment_ChecksAmountAgainstBookingTotal(t *testing.T) {
+	newService := func(flags *feature.Flags) (*PaymentService, memoryBookingTotals) {
+		repo := &memoryPaymentRepo{payments: map[uuid.UUID]*payment.Payment{}}
+		sagaSvc := saga.NewPaymentSagaService(repo, adapter.NewMockStripeAdapter(zap.NewNop()), nil, nil, nil, nil, nil, nil, 15.0, payment.PayoutFloors{}, 0, 0, zap.NewNop())
+		svc := NewPaymentService(repo, nil, &activeSubRepo{}, nil, sagaSvc,
+			NewDiscountEngine(DiscountPolicy{}), NewCurrencyAllowlist([]string{"MYR", "SGD"}), nil, zap.NewNop())
+		totals := memoryBookingTotals{}
+		svc.SetBookingTotals(totals, 50, flags)
+		return svc, totals
+	}
+	initiate := func(svc *PaymentService, bookingID uuid.UUID, amountCents int64, currency string) error {
+		_, err := svc.InitiatePayment(context.Background(), uuid.New(), InitiatePaymentRequest{
+			BookingID:     bookingID,
+			AmountCents:   amountCents,
+			Currency:      currency,
+			CustomerEmail: "owner@example.com",
+		})
+		return err
+	}
+
+	svc, _ := newService(nil)
+	bookingID := uuid.New()
+	require.NoError(t, svc.HandleBookingCreated(context.Background(), bookingDomain.Total{
+		BookingID: bookingID, OwnerID: uuid.New(), AmountCents: 5000, Currency: "myr", CreatedAt: time.Now(),
+	}))
+
+	assert.ErrorIs(t, initiate(svc, bookingID, 2500, "MYR"), ErrBookingAmountMismatch, "underpayment is rejected")
+	assert.ErrorIs(t, initiate(svc, bookingID, 5051, "MYR"), ErrBookingAmountMismatch, "just outside the tolerance")
+	assert.ErrorIs(t, initiate(svc, bookingID, 5000, "SGD"), ErrBookingAmountMismatch, "the currency must match too")
+	assert.NoError(t, initiate(svc, bookingID, 4950, "MYR"), "within the tolerance")
+
+	t.Run("unknown booking", func(t *testing.T) {
+		lenient, _ := newService(nil)
+		assert.NoError(t, initiate(lenient, uuid.New(), 5000, "MYR"), "unknown bookings keep the old behavior by default")
+
+		strict, _ := newService(feature.Static(map[feature.Flag]bool{feature.RequireBookingTotal: true}))
+		assert.ErrorIs(t, initiate(strict, uuid.New(), 5000, "MYR"), ErrBookingTotalUnknown)
+	})
+}
+
+// recordingPublisher records published events by topic.
+type recordingPublisher struct {
+	mu     sync.Mutex
+	events map[string][]kafka.CloudEvent
+}
+
+func (p *recordingPublisher) PublishEvent(_ context.Context, topic string, ce kafka.CloudEvent) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.events == nil {
+		p.events = map[string][]kafka.CloudEvent{}
+	}
+	p.events[topic] = append(p.events[topic], ce)
+	return nil
+}
+
+func TestResendReceipt_PublishesReceiptRequested(t *testing.T) {
+	repo := &memoryPaymentRepo{payments: map[uuid.UUID]*payment.Payment{}}
+	sagaSvc := saga.NewPaymentSagaService(repo, adapter.NewMockStripeAdapter(zap.NewNop()), nil, nil, nil, nil, nil, nil, 15.0, payment.PayoutFloors{}, 0, 0, zap.NewNop())
+	publisher := &recordingPublisher{}
+	svc := NewPaymentService(repo, nil, &activeSubRepo{}, nil, sagaSvc,
+		NewDiscountEngine(DiscountPolicy{}), NewCurrencyAllowlist([]string{"MYR"}), publisher, zap.NewNop())
+
+	initiate := func(email string) *PaymentDTO {
+		created, err := svc.InitiatePayment(context.Background(), uuid.New(), InitiatePaymentRequest{
+			BookingID:     uuid.New(),
+			AmountCents:   5000,
+			Currency:      "MYR",
+			CustomerEmail: email,
+		})
+		require.NoError(t, err)
+		require.Equal(t, string(payment.EscrowHeld), created.EscrowStatus)
+		return created
+	}
+
+	created := initiate("owner@example.com")
+	admin := uuid.New()
+	require.NoError(t, svc.ResendReceipt(context.Background(), created.ID, admin))
+
+	published := publisher.events[events.TopicPaymentEvents]
+	require.Len(t, published, 1)
+	assert.Equal(t, PaymentReceiptRequested, published[0].Type)
+	var event PaymentReceiptRequestedEvent
+	require.NoError(t, published[0].ParseData(&event))
+	assert.Equal(t, created.ID, event.PaymentID)
+	assert.Equal(t, created.OwnerID, event.OwnerID)
+	assert.Equal(t, "owner@example.com", event.Email)
+	assert.Equal(t, admin, event.RequestedBy)
+	assert.Equal(t, int64(5000), event.Receipt.AmountPaidCents)
+
+	noEmail := initiate("")
+	assert.ErrorIs(t, svc.ResendReceipt(context.Background(), noEmail.ID, noEmail.OwnerID), ErrNoCustomerEmail)
+	assert.Len(t, publisher.events[events.TopicPaymentEvents], 1)
+}
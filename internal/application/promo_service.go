@@ -2,10 +2,13 @@ package application
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"strings"
 	"time"
 
 	promoDomain "github.com/Kilat-Pet-Delivery/service-payment/internal/domain/promo"
+	subDomain "github.com/Kilat-Pet-Delivery/service-payment/internal/domain/subscription"
 	"github.com/google/uuid"
 	"go.uber.org/zap"
 )
@@ -18,6 +21,10 @@ type CreatePromoRequest struct {
 	MinAmountCents   int64  `json:"min_amount_cents"`
 	MaxDiscountCents int64  `json:"max_discount_cents"`
 	MaxUses          int    `json:"max_uses"`
+	// FirstBookingOnly limits the promo to users with no prior payment.
+	FirstBookingOnly bool   `json:"first_booking_only"`
+	// ApplicablePlans limits the promo to subscribers of these plans.
+	ApplicablePlans []string `json:"applicable_plans,omitempty"`
 	ValidFrom        string `json:"valid_from" binding:"required"`
 	ValidUntil       string `json:"valid_until" binding:"required"`
 }
@@ -38,6 +45,8 @@ type PromoDTO struct {
 	MaxDiscountCents int64     `json:"max_discount_cents"`
 	MaxUses          int       `json:"max_uses"`
 	CurrentUses      int       `json:"current_uses"`
+	FirstBookingOnly bool      `json:"first_booking_only"`
+	ApplicablePlans  []string  `json:"applicable_plans,omitempty"`
 	ValidFrom        time.Time `json:"valid_from"`
 	ValidUntil       time.Time `json:"valid_until"`
 	CreatedAt        time.Time `json:"created_at"`
@@ -49,17 +58,69 @@ type PromoValidationDTO struct {
 	Code          string `json:"code"`
 	DiscountCents int64  `json:"discount_cents"`
 	Message       string `json:"message,omitempty"`
+	// Clamped reports that the percent-of-total cap reduced the discount.
+	Clamped bool `json:"clamped,omitempty"`
+}
+
+// PromoUsageStatsDTO summarizes how a promo code has been used.
+type PromoUsageStatsDTO struct {
+	Uses               int64 `json:"uses"`
+	UniqueUsers        int64 `json:"unique_users"`
+	TotalDiscountCents int64 `json:"total_discount_cents"`
+}
+
+// PromoWithUsageDTO is a promo code together with its usage stats.
+type PromoWithUsageDTO struct {
+	PromoDTO
+	Usage PromoUsageStatsDTO `json:"usage"`
+}
+
+// PaymentCounter counts a user's payments to enforce first-booking-only promos.
+type PaymentCounter interface {
+	CountPaymentsByOwner(ctx context.Context, ownerID uuid.UUID) (int64, error)
+}
+
+// checkFirstBooking returns promo.ErrFirstBookingOnly if promo is limited to a
+// first booking and userID has paid before. Unrestricted promos are not counted.
+func checkFirstBooking(ctx context.Context, payments PaymentCounter, promo *promoDomain.PromoCode, userID uuid.UUID) error {
+	if !promo.FirstBookingOnly() {
+		return nil
+	}
+	count, err := payments.CountPaymentsByOwner(ctx, userID)
+	if err != nil {
+		return err
+	}
+	return promo.CheckFirstBooking(count)
+}
+
+// activePlan returns the plan of userID's active subscription, or "" if they
+// have none or it cannot be loaded.
+func activePlan(ctx context.Context, subs subDomain.SubscriptionRepository, userID uuid.UUID) subDomain.PlanType {
+	if subs == nil {
+		return ""
+	}
+	sub, err := subs.FindActiveByUserID(ctx, userID)
+	if err != nil || sub == nil || !sub.IsActive() {
+		return ""
+	}
+	return sub.Plan()
 }
 
 // PromoService handles promo code use cases.
 type PromoService struct {
-	repo   promoDomain.PromoRepository
-	logger *zap.Logger
+	repo              promoDomain.PromoRepository
+	payments          PaymentCounter
+	subs              subDomain.SubscriptionRepository
+	maxPercentOfTotal int64
+	logger            *zap.Logger
 }
 
-// NewPromoService creates a new PromoService.
-func NewPromoService(repo promoDomain.PromoRepository, logger *zap.Logger) *PromoService {
-	return &PromoService{repo: repo, logger: logger}
+// NewPromoService creates a new PromoService. payments is used to check
+// first-booking-only promos and subs to check plan-restricted ones; a nil subs
+// treats every user as unsubscribed. maxPercentOfTotal caps quoted discounts at that
+// share of the amount; zero disables the cap.
+func NewPromoService(repo promoDomain.PromoRepository, payments PaymentCounter, subs subDomain.SubscriptionRepository, maxPercentOfTotal int64, logger *zap.Logger) *PromoService {
+	return &PromoService{repo: repo, payments: payments, subs: subs, maxPercentOfTotal: maxPercentOfTotal, logger: logger}
 }
 
 // CreatePromo creates a new promo code (admin only).
@@ -87,6 +148,17 @@ func (s *PromoService) CreatePromo(ctx context.Context, createdBy uuid.UUID, req
 	if err != nil {
 		return nil, err
 	}
+	if req.FirstBookingOnly {
+		promo.RestrictToFirstBooking()
+	}
+	if len(req.ApplicablePlans) > 0 {
+		for _, plan := range req.ApplicablePlans {
+			if _, ok := subDomain.FindPlan(subDomain.PlanType(strings.ToLower(strings.TrimSpace(plan)))); !ok {
+				return nil, fmt.Errorf("%w %q in applicable_plans", subDomain.ErrInvalidPlan, plan)
+			}
+		}
+		promo.RestrictToPlans(req.ApplicablePlans)
+	}
 
 	if err := s.repo.Save(ctx, promo); err != nil {
 		return nil, fmt.Errorf("failed to save promo: %w", err)
@@ -97,7 +169,13 @@ func (s *PromoService) CreatePromo(ctx context.Context, createdBy uuid.UUID, req
 }
 
 // ValidatePromo checks if a promo code is valid and calculates the discount.
+// The code is matched the way it is stored, ignoring case and surrounding spaces.
 func (s *PromoService) ValidatePromo(ctx context.Context, userID uuid.UUID, req ValidatePromoRequest) (*PromoValidationDTO, error) {
+	req.Code = promoDomain.NormalizeCode(req.Code)
+	if req.Code == "" {
+		return &PromoValidationDTO{Valid: false, Code: req.Code, Message: "promo code is required"}, nil
+	}
+
 	promo, err := s.repo.FindByCode(ctx, req.Code)
 	if err != nil {
 		return &PromoValidationDTO{Valid: false, Code: req.Code, Message: "promo code not found"}, nil
@@ -115,7 +193,18 @@ func (s *PromoService) ValidatePromo(ctx context.Context, userID uuid.UUID, req
 		return &PromoValidationDTO{Valid: false, Code: req.Code, Message: "you have already used this promo code"}, nil
 	}
 
-	discount, err := promo.CalculateDiscount(req.AmountCents)
+	if err := checkFirstBooking(ctx, s.payments, promo, userID); err != nil {
+		if errors.Is(err, promoDomain.ErrFirstBookingOnly) {
+			return &PromoValidationDTO{Valid: false, Code: req.Code, Message: err.Error()}, nil
+		}
+		return nil, err
+	}
+
+	if err := promo.CheckPlan(string(activePlan(ctx, s.subs, userID))); err != nil {
+		return &PromoValidationDTO{Valid: false, Code: req.Code, Message: err.Error()}, nil
+	}
+
+	discount, err := promo.CalculateDiscount(req.AmountCents, s.maxPercentOfTotal)
 	if err != nil {
 		return &PromoValidationDTO{Valid: false, Code: req.Code, Message: err.Error()}, nil
 	}
@@ -123,7 +212,8 @@ func (s *PromoService) ValidatePromo(ctx context.Context, userID uuid.UUID, req
 	return &PromoValidationDTO{
 		Valid:         true,
 		Code:          promo.Code(),
-		DiscountCents: discount,
+		DiscountCents: discount.AmountCents,
+		Clamped:       discount.Clamped,
 	}, nil
 }
 
@@ -141,6 +231,69 @@ func (s *PromoService) GetActivePromos(ctx context.Context) ([]*PromoDTO, error)
 	return dtos, nil
 }
 
+// GetUpcomingPromos returns promo codes scheduled to become active in the future.
+func (s *PromoService) GetUpcomingPromos(ctx context.Context) ([]*PromoDTO, error) {
+	promos, err := s.repo.FindUpcoming(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	dtos := make([]*PromoDTO, len(promos))
+	for i, p := range promos {
+		dtos[i] = toPromoDTO(p)
+	}
+	return dtos, nil
+}
+
+// ListPromosByCreator returns a page of the promo codes created by createdBy,
+// with usage stats, and the total number of them.
+func (s *PromoService) ListPromosByCreator(ctx context.Context, createdBy uuid.UUID, page, limit int) ([]*PromoWithUsageDTO, int64, error) {
+	promos, total, err := s.repo.FindByCreator(ctx, createdBy, page, limit)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	ids := make([]uuid.UUID, len(promos))
+	for i, p := range promos {
+		ids[i] = p.ID()
+	}
+	stats, err := s.repo.UsageStats(ctx, ids)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	dtos := make([]*PromoWithUsageDTO, len(promos))
+	for i, p := range promos {
+		dtos[i] = toPromoWithUsageDTO(p, stats[p.ID()])
+	}
+	return dtos, total, nil
+}
+
+// GetPromoStats returns a promo code's configured limits together with its
+// redemptions, total discount given and unique users (admin).
+func (s *PromoService) GetPromoStats(ctx context.Context, id uuid.UUID) (*PromoWithUsageDTO, error) {
+	promo, err := s.repo.FindByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	stats, err := s.repo.UsageStats(ctx, []uuid.UUID{id})
+	if err != nil {
+		return nil, err
+	}
+	return toPromoWithUsageDTO(promo, stats[id]), nil
+}
+
+func toPromoWithUsageDTO(p *promoDomain.PromoCode, usage promoDomain.UsageStats) *PromoWithUsageDTO {
+	return &PromoWithUsageDTO{
+		PromoDTO: *toPromoDTO(p),
+		Usage: PromoUsageStatsDTO{
+			Uses:               usage.Uses,
+			UniqueUsers:        usage.UniqueUsers,
+			TotalDiscountCents: usage.TotalDiscountCents,
+		},
+	}
+}
+
 func toPromoDTO(p *promoDomain.PromoCode) *PromoDTO {
 	return &PromoDTO{
 		ID:               p.ID(),
@@ -151,6 +304,8 @@ func toPromoDTO(p *promoDomain.PromoCode) *PromoDTO {
 		MaxDiscountCents: p.MaxDiscountCents(),
 		MaxUses:          p.MaxUses(),
 		CurrentUses:      p.CurrentUses(),
+		FirstBookingOnly: p.FirstBookingOnly(),
+		ApplicablePlans:  p.ApplicablePlans(),
 		ValidFrom:        p.ValidFrom(),
 		ValidUntil:       p.ValidUntil(),
 		CreatedAt:        p.CreatedAt(),
@@ -0,0 +1,253 @@
+package application
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/Kilat-Pet-Delivery/lib-common/domain"
+	promoDomain "github.com/Kilat-Pet-Delivery/service-payment/internal/domain/promo"
+	subDomain "github.com/Kilat-Pet-Delivery/service-payment/internal/domain/subscription"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+// creatorPromoRepo serves FindByCreator and UsageStats from fixed data.
+type creatorPromoRepo struct {
+	promoDomain.PromoRepository
+
+	promos []*promoDomain.PromoCode
+	stats  map[uuid.UUID]promoDomain.UsageStats
+}
+
+func (r *creatorPromoRepo) FindByCreator(_ context.Context, createdBy uuid.UUID, _, _ int) ([]*promoDomain.PromoCode, int64, error) {
+	var found []*promoDomain.PromoCode
+	for _, p := range r.promos {
+		if p.CreatedBy() == createdBy {
+			found = append(found, p)
+		}
+	}
+	return found, int64(len(found)), nil
+}
+
+func (r *creatorPromoRepo) FindByID(_ context.Context, id uuid.UUID) (*promoDomain.PromoCode, error) {
+	for _, p := range r.promos {
+		if p.ID() == id {
+			return p, nil
+		}
+	}
+	return nil, domain.NewNotFoundError("PromoCode", id.String())
+}
+
+func (r *creatorPromoRepo) UsageStats(_ context.Context, ids []uuid.UUID) (map[uuid.UUID]promoDomain.UsageStats, error) {
+	stats := make(map[uuid.UUID]promoDomain.UsageStats)
+	for _, id := range ids {
+		if s, ok := r.stats[id]; ok {
+			stats[id] = s
+		}
+	}
+	return stats, nil
+}
+
+func TestListPromosByCreator_IncludesUsageStats(t *testing.T) {
+	admin := uuid.New()
+	now := time.Now().UTC()
+	newPromo := func(code string, createdBy uuid.UUID) *promoDomain.PromoCode {
+		p, err := promoDomain.NewPromoCode(code, promoDomain.DiscountTypeFixed, 500, 0, 0, 0, now, now.Add(time.Hour), createdBy)
+		require.NoError(t, err)
+		return p
+	}
+	used, unused := newPromo("USED", admin), newPromo("UNUSED", admin)
+	repo := &creatorPromoRepo{
+		promos: []*promoDomain.PromoCode{used, unused, newPromo("OTHER", uuid.New())},
+		stats: map[uuid.UUID]promoDomain.UsageStats{
+			used.ID(): {Uses: 3, UniqueUsers: 2, TotalDiscountCents: 1500},
+		},
+	}
+	svc := NewPromoService(repo, nil, nil, 0, zap.NewNop())
+
+	promos, total, err := svc.ListPromosByCreator(context.Background(), admin, 1, 20)
+	require.NoError(t, err)
+
+	assert.Equal(t, int64(2), total)
+	require.Len(t, promos, 2)
+	assert.Equal(t, "USED", promos[0].Code)
+	assert.Equal(t, PromoUsageStatsDTO{Uses: 3, UniqueUsers: 2, TotalDiscountCents: 1500}, promos[0].Usage)
+	assert.Equal(t, "UNUSED", promos[1].Code)
+	assert.Zero(t, promos[1].Usage, "a promo that was never used reports zero usage")
+}
+
+func TestGetPromoStats(t *testing.T) {
+	now := time.Now().UTC()
+	promo, err := promoDomain.NewPromoCode("LAUNCH", promoDomain.DiscountTypePercentage, 10, 2000, 1500, 100, now, now.Add(time.Hour), uuid.New())
+	require.NoError(t, err)
+	repo := &creatorPromoRepo{
+		promos: []*promoDomain.PromoCode{promo},
+		stats: map[uuid.UUID]promoDomain.UsageStats{
+			promo.ID(): {Uses: 4, UniqueUsers: 3, TotalDiscountCents: 4200},
+		},
+	}
+	svc := NewPromoService(repo, nil, nil, 0, zap.NewNop())
+
+	stats, err := svc.GetPromoStats(context.Background(), promo.ID())
+	require.NoError(t, err)
+	assert.Equal(t, PromoUsageStatsDTO{Uses: 4, UniqueUsers: 3, TotalDiscountCents: 4200}, stats.Usage)
+	assert.Equal(t, 100, stats.MaxUses, "configured limits are returned for context")
+	assert.Equal(t, int64(1500), stats.MaxDiscountCents)
+	assert.Equal(t, int64(2000), stats.MinAmountCents)
+
+	_, err = svc.GetPromoStats(context.Background(), uuid.New())
+	assert.ErrorIs(t, err, domain.ErrNotFound)
+}
+
+// codePromoRepo serves FindByCode by exact match against stored codes.
+type codePromoRepo struct {
+	promoDomain.PromoRepository
+
+	promos  map[string]*promoDomain.PromoCode
+	lookups []string
+}
+
+func (r *codePromoRepo) FindByCode(_ context.Context, code string) (*promoDomain.PromoCode, error) {
+	r.lookups = append(r.lookups, code)
+	if p, ok := r.promos[code]; ok {
+		return p, nil
+	}
+	return nil, errors.New("record not found")
+}
+
+func (r *codePromoRepo) HasUserUsedPromo(context.Context, uuid.UUID, uuid.UUID) (bool, error) {
+	return false, nil
+}
+
+func TestValidatePromo_NormalizesCode(t *testing.T) {
+	now := time.Now().UTC()
+	stored, err := promoDomain.NewPromoCode("SAVE10", promoDomain.DiscountTypeFixed, 1000, 0, 0, 0, now.Add(-time.Hour), now.Add(time.Hour), uuid.New())
+	require.NoError(t, err)
+	repo := &codePromoRepo{promos: map[string]*promoDomain.PromoCode{"SAVE10": stored}}
+	svc := NewPromoService(repo, nil, nil, 0, zap.NewNop())
+
+	for _, input := range []string{"SAVE10", "save10 ", "  Save10", "\tsAvE10\n"} {
+		t.Run(input, func(t *testing.T) {
+			result, err := svc.ValidatePromo(context.Background(), uuid.New(), ValidatePromoRequest{Code: input, AmountCents: 5000})
+			require.NoError(t, err)
+			assert.True(t, result.Valid, result.Message)
+			assert.Equal(t, "SAVE10", result.Code)
+			assert.Equal(t, int64(1000), result.DiscountCents)
+		})
+	}
+
+	for _, lookup := range repo.lookups {
+		assert.Equal(t, "SAVE10", lookup, "the repository only sees the stored form")
+	}
+}
+
+func TestValidatePromo_BlankCode(t *testing.T) {
+	repo := &codePromoRepo{}
+	svc := NewPromoService(repo, nil, nil, 0, zap.NewNop())
+
+	result, err := svc.ValidatePromo(context.Background(), uuid.New(), ValidatePromoRequest{Code: "   ", AmountCents: 5000})
+	require.NoError(t, err)
+	assert.False(t, result.Valid)
+	assert.Empty(t, repo.lookups, "blank codes never reach the repository")
+}
+
+// fixedPaymentCounter reports the same payment count for every user.
+type fixedPaymentCounter int64
+
+func (c fixedPaymentCounter) CountPaymentsByOwner(context.Context, uuid.UUID) (int64, error) {
+	return int64(c), nil
+}
+
+func newFirstBookingPromo(t *testing.T) *promoDomain.PromoCode {
+	t.Helper()
+	now := time.Now().UTC()
+	p, err := promoDomain.NewPromoCode("WELCOME", promoDomain.DiscountTypeFixed, 1000, 0, 0, 0, now.Add(-time.Hour), now.Add(time.Hour), uuid.New())
+	require.NoError(t, err)
+	p.RestrictToFirstBooking()
+	return p
+}
+
+func TestValidatePromo_FirstBookingOnly(t *testing.T) {
+	tests := []struct {
+		name          string
+		priorPayments fixedPaymentCounter
+		wantValid     bool
+	}{
+		{"no prior payment", 0, true},
+		{"one prior payment", 1, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := &codePromoRepo{promos: map[string]*promoDomain.PromoCode{"WELCOME": newFirstBookingPromo(t)}}
+			svc := NewPromoService(repo, tt.priorPayments, nil, 0, zap.NewNop())
+
+			result, err := svc.ValidatePromo(context.Background(), uuid.New(), ValidatePromoRequest{Code: "WELCOME", AmountCents: 5000})
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantValid, result.Valid)
+			if !tt.wantValid {
+				assert.Equal(t, promoDomain.ErrFirstBookingOnly.Error(), result.Message)
+			}
+		})
+	}
+}
+
+func newPremiumOnlyPromo(t *testing.T) *promoDomain.PromoCode {
+	t.Helper()
+	now := time.Now().UTC()
+	p, err := promoDomain.NewPromoCode("PREMIUM20", promoDomain.DiscountTypePercentage, 20, 0, 0, 0, now.Add(-time.Hour), now.Add(time.Hour), uuid.New())
+	require.NoError(t, err)
+	p.RestrictToPlans([]string{"premium"})
+	return p
+}
+
+func TestValidatePromo_PlanRestricted(t *testing.T) {
+	tests := []struct {
+		name      string
+		plan      subDomain.PlanType
+		wantValid bool
+	}{
+		{"no subscription", "", false},
+		{"basic subscriber", subDomain.PlanBasic, false},
+		{"premium subscriber", subDomain.PlanPremium, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			user := uuid.New()
+			subs := &activeSubRepo{}
+			if tt.plan != "" {
+				sub, err := subDomain.NewSubscription(user, tt.plan)
+				require.NoError(t, err)
+				subs.sub = sub
+			}
+			repo := &codePromoRepo{promos: map[string]*promoDomain.PromoCode{"PREMIUM20": newPremiumOnlyPromo(t)}}
+			svc := NewPromoService(repo, nil, subs, 0, zap.NewNop())
+
+			result, err := svc.ValidatePromo(context.Background(), user, ValidatePromoRequest{Code: "PREMIUM20", AmountCents: 5000})
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantValid, result.Valid)
+			if tt.wantValid {
+				assert.Equal(t, int64(1000), result.DiscountCents)
+			} else {
+				assert.Equal(t, promoDomain.ErrPlanNotEligible.Error(), result.Message)
+			}
+		})
+	}
+}
+
+func TestCreatePromo_RejectsUnknownApplicablePlan(t *testing.T) {
+	svc := NewPromoService(&codePromoRepo{}, nil, nil, 0, zap.NewNop())
+
+	_, err := svc.CreatePromo(context.Background(), uuid.New(), CreatePromoRequest{
+		Code:            "GOLD",
+		DiscountType:    string(promoDomain.DiscountTypeFixed),
+		DiscountValue:   500,
+		ApplicablePlans: []string{"premium", "gold"},
+		ValidFrom:       time.Now().UTC().Format(time.RFC3339),
+		ValidUntil:      time.Now().UTC().Add(time.Hour).Format(time.RFC3339),
+	})
+	assert.ErrorIs(t, err, subDomain.ErrInvalidPlan)
+}
@@ -0,0 +1,169 @@
+package application
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/Kilat-Pet-Delivery/lib-common/domain"
+	"github.com/Kilat-Pet-Delivery/service-payment/internal/domain/payment"
+	refundDomain "github.com/Kilat-Pet-Delivery/service-payment/internal/domain/refund"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+// ErrRefundNeedsApproval is returned when a direct refund is at or above the
+// approval threshold. Such refunds go through RequestRefund instead.
+var ErrRefundNeedsApproval = errors.New("refund requires a second admin's approval")
+
+// Refund outcomes reported by RequestRefund.
+const (
+	RefundOutcomeRefunded        = "refunded"
+	RefundOutcomePendingApproval = "pending_approval"
+	// RefundOutcomeFailed is only reported per payment by RefundOwnerPayments.
+	RefundOutcomeFailed = "failed"
+)
+
+// RefundRequestDTO is the API representation of a refund request.
+type RefundRequestDTO struct {
+	ID          uuid.UUID  `json:"id"`
+	PaymentID   uuid.UUID  `json:"payment_id"`
+	AmountCents int64      `json:"amount_cents"`
+	Reason      string     `json:"reason"`
+	Method      string     `json:"method"`
+	Status      string     `json:"status"`
+	RequestedBy uuid.UUID  `json:"requested_by"`
+	ApprovedBy  *uuid.UUID `json:"approved_by,omitempty"`
+	ApprovedAt  *time.Time `json:"approved_at,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+}
+
+// RefundOutcomeDTO is the result of an admin refund request: the refunded
+// payment when it was below the threshold, otherwise the pending request.
+type RefundOutcomeDTO struct {
+	Outcome string            `json:"outcome"`
+	Payment *PaymentDTO       `json:"payment,omitempty"`
+	Request *RefundRequestDTO `json:"request,omitempty"`
+}
+
+// SetRefundApprovals holds refunds of payments whose amount is at least
+// thresholdCents until an admin other than the requester approves them. The
+// threshold is in two-decimal cents and is scaled to each payment currency's
+// minor unit, like the payout floors. A zero threshold refunds everything directly.
+func (s *PaymentService) SetRefundApprovals(repo refundDomain.RequestRepository, thresholdCents int64) {
+	s.refundRequests = repo
+	s.refundApprovalThresholdCents = thresholdCents
+}
+
+// refundApprovalThreshold returns the approval threshold in currency's minor unit.
+func (s *PaymentService) refundApprovalThreshold(currency string) int64 {
+	return payment.FromCents(s.refundApprovalThresholdCents, currency).Amount()
+}
+
+// needsRefundApproval reports whether refunding p needs a second admin.
+func (s *PaymentService) needsRefundApproval(p *payment.Payment) bool {
+	return s.refundRequests != nil && s.refundApprovalThresholdCents > 0 &&
+		p.AmountCents() >= s.refundApprovalThreshold(p.Currency())
+}
+
+// RequestRefund refunds a payment below the approval threshold directly and
+// records a pending request for anything larger.
+func (s *PaymentService) RequestRefund(ctx context.Context, paymentID, requestedBy uuid.UUID, req RefundRequest) (*RefundOutcomeDTO, error) {
+	reason, err := req.normalize()
+	if err != nil {
+		return nil, err
+	}
+	method, err := req.refundMethod()
+	if err != nil {
+		return nil, err
+	}
+	return s.requestRefund(ctx, paymentID, requestedBy, reason, method)
+}
+
+// requestRefund is RequestRefund with a validated reason and method.
+func (s *PaymentService) requestRefund(ctx context.Context, paymentID, requestedBy uuid.UUID, reason string, method payment.RefundMethod) (*RefundOutcomeDTO, error) {
+	p, err := s.repo.FindByID(ctx, paymentID)
+	if err != nil {
+		return nil, err
+	}
+	if !s.needsRefundApproval(p) {
+		dto, err := s.refund(ctx, paymentID, reason, method)
+		if err != nil {
+			return nil, err
+		}
+		return &RefundOutcomeDTO{Outcome: RefundOutcomeRefunded, Payment: dto}, nil
+	}
+
+	// Refuse up front what could never be approved; the refund window is
+	// checked again when the refund runs.
+	if status := p.EscrowStatus(); status != payment.EscrowHeld && status != payment.EscrowReleased {
+		return nil, domain.NewInvalidStateError(string(status), string(payment.EscrowRefunded))
+	}
+
+	request := refundDomain.NewRequest(paymentID, p.AmountCents(), reason, string(method), requestedBy, time.Now().UTC())
+	if err := s.refundRequests.Save(ctx, request); err != nil {
+		return nil, err
+	}
+
+	s.logger.Info("refund awaiting approval",
+		zap.String("refund_request_id", request.ID.String()),
+		zap.String("payment_id", paymentID.String()),
+		zap.Int64("amount_cents", request.AmountCents),
+		zap.String("requested_by", requestedBy.String()),
+	)
+	dto := toRefundRequestDTO(request)
+	return &RefundOutcomeDTO{Outcome: RefundOutcomePendingApproval, Request: &dto}, nil
+}
+
+// ApproveRefundRequest runs the refund of a pending request on approver's
+// approval. Requesters cannot approve their own requests. The request stays
+// pending if the refund fails, so it can be approved again.
+func (s *PaymentService) ApproveRefundRequest(ctx context.Context, requestID, approver uuid.UUID) (*RefundRequestDTO, error) {
+	if s.refundRequests == nil {
+		return nil, errors.New("refund approvals are not configured")
+	}
+
+	request, err := s.refundRequests.FindByID(ctx, requestID)
+	if err != nil {
+		return nil, err
+	}
+	if err := request.CanApprove(approver); err != nil {
+		return nil, err
+	}
+
+	if _, err := s.refund(ctx, request.PaymentID, request.Reason, payment.RefundMethod(request.Method)); err != nil {
+		return nil, err
+	}
+
+	if err := request.Approve(approver, time.Now().UTC()); err != nil {
+		return nil, err
+	}
+	if err := s.refundRequests.MarkApproved(ctx, request); err != nil {
+		return nil, fmt.Errorf("payment refunded but failed to record approval: %w", err)
+	}
+
+	s.logger.Info("refund request approved",
+		zap.String("refund_request_id", request.ID.String()),
+		zap.String("payment_id", request.PaymentID.String()),
+		zap.String("requested_by", request.RequestedBy.String()),
+		zap.String("approved_by", approver.String()),
+	)
+	dto := toRefundRequestDTO(request)
+	return &dto, nil
+}
+
+func toRefundRequestDTO(r *refundDomain.Request) RefundRequestDTO {
+	return RefundRequestDTO{
+		ID:          r.ID,
+		PaymentID:   r.PaymentID,
+		AmountCents: r.AmountCents,
+		Reason:      r.Reason,
+		Method:      r.Method,
+		Status:      string(r.Status),
+		RequestedBy: r.RequestedBy,
+		ApprovedBy:  r.ApprovedBy,
+		ApprovedAt:  r.ApprovedAt,
+		CreatedAt:   r.CreatedAt,
+	}
+}
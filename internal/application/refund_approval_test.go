@@ -0,0 +1,160 @@
+package application
+
+import (
+	"context"
+	"testing"
+
+	"github.com/Kilat-Pet-Delivery/lib-common/domain"
+	"github.com/Kilat-Pet-Delivery/service-payment/internal/adapter"
+	"github.com/Kilat-Pet-Delivery/service-payment/internal/domain/payment"
+	refundDomain "github.com/Kilat-Pet-Delivery/service-payment/internal/domain/refund"
+	"github.com/Kilat-Pet-Delivery/service-payment/internal/saga"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+// memoryRefundRequestRepo keeps refund requests in memory, enforcing one
+// pending request per payment.
+type memoryRefundRequestRepo struct {
+	requests map[uuid.UUID]*refundDomain.Request
+}
+
+func (r *memoryRefundRequestRepo) Save(_ context.Context, req *refundDomain.Request) error {
+	for _, existing := range r.requests {
+		if existing.PaymentID == req.PaymentID && existing.Status == refundDomain.StatusPending {
+			return domain.NewConflictError("payment already has a pending refund request")
+		}
+	}
+	stored := *req
+	r.requests[req.ID] = &stored
+	return nil
+}
+
+func (r *memoryRefundRequestRepo) FindByID(_ context.Context, id uuid.UUID) (*refundDomain.Request, error) {
+	req, ok := r.requests[id]
+	if !ok {
+		return nil, domain.NewNotFoundError("RefundRequest", id.String())
+	}
+	loaded := *req
+	return &loaded, nil
+}
+
+func (r *memoryRefundRequestRepo) MarkApproved(_ context.Context, req *refundDomain.Request) error {
+	if r.requests[req.ID].Status != refundDomain.StatusPending {
+		return domain.NewConflictError("refund request is no longer pending")
+	}
+	stored := *req
+	r.requests[req.ID] = &stored
+	return nil
+}
+
+// newRefundApprovalService returns a service holding refunds of 10000 cents
+// or more for approval, with one held payment of each given amount.
+func newRefundApprovalService(t *testing.T, amounts ...int64) (*PaymentService, *memoryRefundRequestRepo, []*payment.Payment) {
+	t.Helper()
+	return newRefundApprovalServiceIn(t, "MYR", amounts...)
+}
+
+// newRefundApprovalServiceIn is newRefundApprovalService with payments in currency.
+func newRefundApprovalServiceIn(t *testing.T, currency string, amounts ...int64) (*PaymentService, *memoryRefundRequestRepo, []*payment.Payment) {
+	t.Helper()
+	repo := &memoryPaymentRepo{payments: map[uuid.UUID]*payment.Payment{}}
+	var payments []*payment.Payment
+	for _, amount := range amounts {
+		p, err := payment.NewPayment(uuid.New(), uuid.New(), amount, currency, 15.0, payment.PayoutFloors{})
+		require.NoError(t, err)
+		require.NoError(t, p.HoldEscrow("pi_"+p.ID().String(), 0))
+		repo.payments[p.ID()] = p
+		payments = append(payments, p)
+	}
+
+	sagaSvc := saga.NewPaymentSagaService(repo, adapter.NewMockStripeAdapter(zap.NewNop()), &recordingPublisher{}, nil, nil, nil, nil, nil, 15.0, payment.PayoutFloors{}, 0, 0, zap.NewNop())
+	svc := NewPaymentService(repo, nil, nil, nil, sagaSvc, nil, nil, nil, zap.NewNop())
+	requests := &memoryRefundRequestRepo{requests: map[uuid.UUID]*refundDomain.Request{}}
+	svc.SetRefundApprovals(requests, 10000)
+	return svc, requests, payments
+}
+
+func TestRequestRefund_RoutesByThreshold(t *testing.T) {
+	svc, requests, payments := newRefundApprovalService(t, 9999, 10000)
+	small, large := payments[0], payments[1]
+	admin := uuid.New()
+	req := RefundRequest{Reason: "runner no-show"}
+
+	outcome, err := svc.RequestRefund(context.Background(), small.ID(), admin, req)
+	require.NoError(t, err)
+	assert.Equal(t, RefundOutcomeRefunded, outcome.Outcome)
+	require.NotNil(t, outcome.Payment)
+	assert.Equal(t, string(payment.EscrowRefunded), outcome.Payment.EscrowStatus)
+	assert.Empty(t, requests.requests, "refunds below the threshold are not queued")
+
+	outcome, err = svc.RequestRefund(context.Background(), large.ID(), admin, req)
+	require.NoError(t, err)
+	assert.Equal(t, RefundOutcomePendingApproval, outcome.Outcome)
+	require.NotNil(t, outcome.Request)
+	assert.Equal(t, string(refundDomain.StatusPending), outcome.Request.Status)
+	assert.Equal(t, int64(10000), outcome.Request.AmountCents)
+	assert.Equal(t, payment.EscrowHeld, large.EscrowStatus(), "the refund waits for approval")
+
+	_, err = svc.RequestRefund(context.Background(), large.ID(), admin, req)
+	assert.Error(t, err, "a payment has one pending request at a time")
+
+	_, err = svc.RefundPayment(context.Background(), large.ID(), req)
+	assert.ErrorIs(t, err, ErrRefundNeedsApproval, "the direct refund endpoint cannot bypass approval")
+}
+
+func TestRequestRefund_ScalesThresholdToCurrency(t *testing.T) {
+	// The 10000-cent threshold is 100 JPY and 100.000 KWD.
+	for _, tc := range []struct {
+		currency     string
+		below, above int64
+	}{
+		{currency: "JPY", below: 99, above: 100},
+		{currency: "KWD", below: 99999, above: 100000},
+	} {
+		t.Run(tc.currency, func(t *testing.T) {
+			svc, requests, payments := newRefundApprovalServiceIn(t, tc.currency, tc.below, tc.above)
+			small, large := payments[0], payments[1]
+			admin := uuid.New()
+			req := RefundRequest{Reason: "runner no-show"}
+
+			outcome, err := svc.RequestRefund(context.Background(), small.ID(), admin, req)
+			require.NoError(t, err)
+			assert.Equal(t, RefundOutcomeRefunded, outcome.Outcome)
+			assert.Empty(t, requests.requests)
+
+			outcome, err = svc.RequestRefund(context.Background(), large.ID(), admin, req)
+			require.NoError(t, err)
+			assert.Equal(t, RefundOutcomePendingApproval, outcome.Outcome)
+			assert.Equal(t, payment.EscrowHeld, large.EscrowStatus())
+		})
+	}
+}
+
+func TestApproveRefundRequest_RejectsSelfApproval(t *testing.T) {
+	svc, requests, payments := newRefundApprovalService(t, 25000)
+	p := payments[0]
+	requester, approver := uuid.New(), uuid.New()
+
+	outcome, err := svc.RequestRefund(context.Background(), p.ID(), requester, RefundRequest{Reason: "damaged carrier"})
+	require.NoError(t, err)
+	requestID := outcome.Request.ID
+
+	_, err = svc.ApproveRefundRequest(context.Background(), requestID, requester)
+	assert.ErrorIs(t, err, refundDomain.ErrSelfApproval)
+	assert.Equal(t, payment.EscrowHeld, p.EscrowStatus())
+	assert.Equal(t, refundDomain.StatusPending, requests.requests[requestID].Status)
+
+	approved, err := svc.ApproveRefundRequest(context.Background(), requestID, approver)
+	require.NoError(t, err)
+	assert.Equal(t, string(refundDomain.StatusApproved), approved.Status)
+	require.NotNil(t, approved.ApprovedBy)
+	assert.Equal(t, approver, *approved.ApprovedBy)
+	assert.Equal(t, payment.EscrowRefunded, p.EscrowStatus())
+	assert.Equal(t, "damaged carrier", p.RefundReason())
+
+	_, err = svc.ApproveRefundRequest(context.Background(), requestID, uuid.New())
+	assert.ErrorIs(t, err, refundDomain.ErrNotPending)
+}
@@ -0,0 +1,196 @@
+package application
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync/atomic"
+	"time"
+
+	"github.com/Kilat-Pet-Delivery/lib-common/domain"
+	"github.com/Kilat-Pet-Delivery/lib-common/kafka"
+	"github.com/Kilat-Pet-Delivery/lib-proto/events"
+	"github.com/Kilat-Pet-Delivery/service-payment/internal/domain/payment"
+	"go.uber.org/zap"
+)
+
+// MaxReplayRange bounds how much history a single replay may republish.
+const MaxReplayRange = 31 * 24 * time.Hour
+
+// ErrInvalidReplayRequest is returned when a replay's range or event types are invalid.
+var ErrInvalidReplayRequest = errors.New("invalid replay request")
+
+// ErrReplayInProgress is returned when a replay is requested while another is running.
+var ErrReplayInProgress = domain.NewConflictError("a payment event replay is already running")
+
+// replayableTypes are the payment event types that can be rebuilt from persisted timestamps.
+var replayableTypes = []string{
+	events.PaymentEscrowHeld,
+	events.PaymentEscrowReleased,
+	events.PaymentEscrowRefunded,
+}
+
+// ReplayPublisher republishes a reconstructed event, marking it as a replay.
+type ReplayPublisher interface {
+	PublishReplay(ctx context.Context, topic, key string, ce kafka.CloudEvent) error
+}
+
+// ReplayRequest selects the payment events to republish. From is inclusive and
+// To is exclusive. An empty Types replays every replayable type.
+type ReplayRequest struct {
+	From  time.Time
+	To    time.Time
+	Types []string
+}
+
+// ReplayResultDTO summarizes a completed replay.
+type ReplayResultDTO struct {
+	From      time.Time      `json:"from"`
+	To        time.Time      `json:"to"`
+	Published map[string]int `json:"published"`
+	Total     int            `json:"total"`
+}
+
+// ReplayService rebuilds historical payment events from persisted payments
+// and republishes them for downstream consumers that lost data.
+type ReplayService struct {
+	repo      payment.PaymentRepository
+	publisher ReplayPublisher
+	running   atomic.Bool
+	logger    *zap.Logger
+}
+
+// NewReplayService creates a new ReplayService.
+func NewReplayService(repo payment.PaymentRepository, publisher ReplayPublisher, logger *zap.Logger) *ReplayService {
+	return &ReplayService{repo: repo, publisher: publisher, logger: logger}
+}
+
+// Replay republishes every selected event whose occurrence time falls within
+// the requested range. Only one replay runs at a time.
+func (s *ReplayService) Replay(ctx context.Context, req ReplayRequest) (*ReplayResultDTO, error) {
+	types, err := req.validate()
+	if err != nil {
+		return nil, err
+	}
+
+	if !s.running.CompareAndSwap(false, true) {
+		return nil, ErrReplayInProgress
+	}
+	defer s.running.Store(false)
+
+	result := &ReplayResultDTO{From: req.From, To: req.To, Published: make(map[string]int)}
+	err = s.repo.StreamByEventTime(ctx, req.From, req.To, func(p *payment.Payment) error {
+		for _, e := range reconstructEvents(p) {
+			if !types[e.eventType] || e.occurredAt.Before(req.From) || !e.occurredAt.Before(req.To) {
+				continue
+			}
+			ce, err := kafka.NewCloudEvent("service-payment", e.eventType, e.data)
+			if err != nil {
+				return fmt.Errorf("failed to create cloud event: %w", err)
+			}
+			if err := s.publisher.PublishReplay(ctx, events.TopicPaymentEvents, p.BookingID().String(), ce); err != nil {
+				return fmt.Errorf("failed to republish %s for payment %s: %w", e.eventType, p.ID(), err)
+			}
+			result.Published[e.eventType]++
+			result.Total++
+		}
+		return nil
+	})
+
+	s.logger.Info("payment event replay finished",
+		zap.Time("from", req.From),
+		zap.Time("to", req.To),
+		zap.Int("published", result.Total),
+		zap.Error(err),
+	)
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// validate checks the range and returns the selected event types as a set.
+func (r ReplayRequest) validate() (map[string]bool, error) {
+	if r.From.IsZero() || r.To.IsZero() {
+		return nil, fmt.Errorf("%w: from and to are required", ErrInvalidReplayRequest)
+	}
+	if !r.From.Before(r.To) {
+		return nil, fmt.Errorf("%w: from must be before to", ErrInvalidReplayRequest)
+	}
+	if r.To.Sub(r.From) > MaxReplayRange {
+		return nil, fmt.Errorf("%w: range must not exceed %s", ErrInvalidReplayRequest, MaxReplayRange)
+	}
+
+	selected := make(map[string]bool, len(replayableTypes))
+	if len(r.Types) == 0 {
+		for _, t := range replayableTypes {
+			selected[t] = true
+		}
+		return selected, nil
+	}
+	for _, t := range r.Types {
+		if !isReplayableType(t) {
+			allowed := append([]string(nil), replayableTypes...)
+			sort.Strings(allowed)
+			return nil, fmt.Errorf("%w: unknown event type %q, must be one of %v", ErrInvalidReplayRequest, t, allowed)
+		}
+		selected[t] = true
+	}
+	return selected, nil
+}
+
+func isReplayableType(t string) bool {
+	for _, rt := range replayableTypes {
+		if rt == t {
+			return true
+		}
+	}
+	return false
+}
+
+// replayEvent is an event rebuilt from a payment's persisted state.
+type replayEvent struct {
+	eventType  string
+	occurredAt time.Time
+	data       any
+}
+
+// reconstructEvents rebuilds the lifecycle events a payment has emitted,
+// stamped with the times they originally occurred.
+func reconstructEvents(p *payment.Payment) []replayEvent {
+	var out []replayEvent
+	if at := p.EscrowHeldAt(); at != nil {
+		out = append(out, replayEvent{events.PaymentEscrowHeld, *at, events.EscrowHeldEvent{
+			PaymentID:       p.ID(),
+			BookingID:       p.BookingID(),
+			StripePaymentID: p.StripePaymentID(),
+			AmountCents:     p.AmountCents(),
+			Currency:        p.Currency(),
+			OccurredAt:      *at,
+		}})
+	}
+	if at := p.EscrowReleasedAt(); at != nil && p.RunnerID() != nil {
+		out = append(out, replayEvent{events.PaymentEscrowReleased, *at, events.EscrowReleasedEvent{
+			PaymentID:    p.ID(),
+			BookingID:    p.BookingID(),
+			RunnerID:     *p.RunnerID(),
+			RunnerPayout: p.RunnerPayoutCents(),
+			PlatformFee:  p.PlatformFeeCents(),
+			Currency:     p.Currency(),
+			OccurredAt:   *at,
+		}})
+	}
+	if at := p.RefundedAt(); at != nil {
+		out = append(out, replayEvent{events.PaymentEscrowRefunded, *at, events.EscrowRefundedEvent{
+			PaymentID:    p.ID(),
+			BookingID:    p.BookingID(),
+			OwnerID:      p.OwnerID(),
+			AmountCents:  p.AmountCents(),
+			Currency:     p.Currency(),
+			RefundReason: p.RefundReason(),
+			OccurredAt:   *at,
+		}})
+	}
+	return out
+}
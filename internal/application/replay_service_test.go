@@ -0,0 +1,155 @@
+package application
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/Kilat-Pet-Delivery/lib-common/kafka"
+	"github.com/Kilat-Pet-Delivery/lib-proto/events"
+	"github.com/Kilat-Pet-Delivery/service-payment/internal/domain/payment"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+// eventTimeRepo streams a fixed set of payments, mimicking the database's
+// range filter on held/released/refunded timestamps.
+type eventTimeRepo struct {
+	payment.PaymentRepository
+
+	payments []*payment.Payment
+}
+
+func (r *eventTimeRepo) StreamByEventTime(_ context.Context, from, to time.Time, fn func(*payment.Payment) error) error {
+	in := func(at *time.Time) bool { return at != nil && !at.Before(from) && at.Before(to) }
+	for _, p := range r.payments {
+		if in(p.EscrowHeldAt()) || in(p.EscrowReleasedAt()) || in(p.RefundedAt()) {
+			if err := fn(p); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// recordingReplayPublisher captures replayed events.
+type recordingReplayPublisher struct {
+	published []kafka.CloudEvent
+}
+
+func (p *recordingReplayPublisher) PublishReplay(_ context.Context, _, _ string, ce kafka.CloudEvent) error {
+	p.published = append(p.published, ce)
+	return nil
+}
+
+func replayTestPayment(status payment.EscrowStatus, held, released, refunded *time.Time) *payment.Payment {
+	runnerID := uuid.New()
+	return payment.Reconstitute(uuid.New(), uuid.New(), uuid.New(), &runnerID, status,
+		payment.NewMoney(10000, "MYR"), payment.NewMoney(1500, "MYR"), payment.NewMoney(8500, "MYR"), 0, "card", "pi_test",
+		held, released, refunded, nil, "", "", "", "", "", "", false, false, false, 1, *held, *held)
+}
+
+func TestReplayService_RepublishesOnlyMatchingEvents(t *testing.T) {
+	day := time.Date(2026, 5, 10, 0, 0, 0, 0, time.UTC)
+	at := func(h int) *time.Time { t := day.Add(time.Duration(h) * time.Hour); return &t }
+	before := day.Add(-time.Hour)
+
+	heldOnly := replayTestPayment(payment.EscrowHeld, at(1), nil, nil)
+	released := replayTestPayment(payment.EscrowReleased, at(2), at(3), nil)
+	refundedHeldEarlier := replayTestPayment(payment.EscrowRefunded, &before, nil, at(4))
+	outOfRange := replayTestPayment(payment.EscrowHeld, at(30), nil, nil)
+
+	repo := &eventTimeRepo{payments: []*payment.Payment{heldOnly, released, refundedHeldEarlier, outOfRange}}
+	req := ReplayRequest{From: day, To: day.Add(24 * time.Hour)}
+
+	tests := []struct {
+		name      string
+		types     []string
+		wantTypes []string
+	}{
+		{
+			name: "all types within range",
+			wantTypes: []string{
+				events.PaymentEscrowHeld, events.PaymentEscrowHeld, events.PaymentEscrowReleased, events.PaymentEscrowRefunded,
+			},
+		},
+		{
+			name:      "released only",
+			types:     []string{events.PaymentEscrowReleased},
+			wantTypes: []string{events.PaymentEscrowReleased},
+		},
+		{
+			name:      "refunded skips the out-of-range hold",
+			types:     []string{events.PaymentEscrowRefunded},
+			wantTypes: []string{events.PaymentEscrowRefunded},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pub := &recordingReplayPublisher{}
+			svc := NewReplayService(repo, pub, zap.NewNop())
+
+			r := req
+			r.Types = tt.types
+			result, err := svc.Replay(context.Background(), r)
+			require.NoError(t, err)
+
+			got := make([]string, len(pub.published))
+			for i, ce := range pub.published {
+				got[i] = ce.Type
+			}
+			assert.Equal(t, tt.wantTypes, got)
+			assert.Equal(t, len(tt.wantTypes), result.Total)
+		})
+	}
+}
+
+func TestReplayService_PreservesOriginalTimestamps(t *testing.T) {
+	day := time.Date(2026, 5, 10, 0, 0, 0, 0, time.UTC)
+	held, releasedAt := day.Add(time.Hour), day.Add(2*time.Hour)
+	p := replayTestPayment(payment.EscrowReleased, &held, &releasedAt, nil)
+
+	pub := &recordingReplayPublisher{}
+	svc := NewReplayService(&eventTimeRepo{payments: []*payment.Payment{p}}, pub, zap.NewNop())
+
+	_, err := svc.Replay(context.Background(), ReplayRequest{
+		From: day, To: day.Add(24 * time.Hour), Types: []string{events.PaymentEscrowReleased},
+	})
+	require.NoError(t, err)
+	require.Len(t, pub.published, 1)
+
+	var evt events.EscrowReleasedEvent
+	require.NoError(t, pub.published[0].ParseData(&evt))
+	assert.True(t, evt.OccurredAt.Equal(releasedAt))
+	assert.Equal(t, p.ID(), evt.PaymentID)
+	assert.Equal(t, int64(8500), evt.RunnerPayout)
+}
+
+func TestReplayRequest_Validate(t *testing.T) {
+	from := time.Date(2026, 5, 1, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name    string
+		req     ReplayRequest
+		wantErr bool
+	}{
+		{"valid", ReplayRequest{From: from, To: from.Add(24 * time.Hour)}, false},
+		{"missing to", ReplayRequest{From: from}, true},
+		{"inverted range", ReplayRequest{From: from, To: from.Add(-time.Hour)}, true},
+		{"range too long", ReplayRequest{From: from, To: from.Add(MaxReplayRange + time.Hour)}, true},
+		{"unknown type", ReplayRequest{From: from, To: from.Add(time.Hour), Types: []string{"payment.failed"}}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := tt.req.validate()
+			if tt.wantErr {
+				assert.ErrorIs(t, err, ErrInvalidReplayRequest)
+				return
+			}
+			assert.NoError(t, err)
+		})
+	}
+}
@@ -0,0 +1,58 @@
+package application
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ErrInvalidSummaryRange is returned when a payout summary's from is not before its to.
+var ErrInvalidSummaryRange = errors.New("from must be before to")
+
+// RunnerPayoutCurrencyDTO totals a runner's payouts in one currency.
+type RunnerPayoutCurrencyDTO struct {
+	Currency     string `json:"currency"`
+	PayoutCents  int64  `json:"payout_cents"`
+	BookingCount int64  `json:"booking_count"`
+}
+
+// RunnerPayoutSummaryDTO totals a runner's payouts released within [From, To).
+type RunnerPayoutSummaryDTO struct {
+	From       time.Time                 `json:"from"`
+	To         time.Time                 `json:"to"`
+	Currencies []RunnerPayoutCurrencyDTO `json:"currencies"`
+}
+
+// GetRunnerPayoutSummary totals the payouts of runnerID's released payments
+// released within [from, to), per currency. A nil from defaults to the start
+// of the current UTC month and a nil to to the start of the next one.
+func (s *PaymentService) GetRunnerPayoutSummary(ctx context.Context, runnerID uuid.UUID, from, to *time.Time) (*RunnerPayoutSummaryDTO, error) {
+	now := time.Now().UTC()
+	monthStart := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+	summary := &RunnerPayoutSummaryDTO{From: monthStart, To: monthStart.AddDate(0, 1, 0)}
+	if from != nil {
+		summary.From = *from
+	}
+	if to != nil {
+		summary.To = *to
+	}
+	if !summary.From.Before(summary.To) {
+		return nil, ErrInvalidSummaryRange
+	}
+
+	totals, err := s.repo.SumRunnerPayouts(ctx, runnerID, summary.From, summary.To)
+	if err != nil {
+		return nil, err
+	}
+	summary.Currencies = make([]RunnerPayoutCurrencyDTO, 0, len(totals))
+	for _, t := range totals {
+		summary.Currencies = append(summary.Currencies, RunnerPayoutCurrencyDTO{
+			Currency:     t.Currency,
+			PayoutCents:  t.PayoutCents,
+			BookingCount: t.BookingCount,
+		})
+	}
+	return summary, nil
+}
@@ -0,0 +1,53 @@
+package application
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/Kilat-Pet-Delivery/service-payment/internal/domain/payment"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+// payoutTotalsRepo returns fixed payout totals and records the window asked for.
+type payoutTotalsRepo struct {
+	payment.PaymentRepository
+	totals   []payment.RunnerPayoutTotal
+	from, to time.Time
+}
+
+func (r *payoutTotalsRepo) SumRunnerPayouts(_ context.Context, _ uuid.UUID, from, to time.Time) ([]payment.RunnerPayoutTotal, error) {
+	r.from, r.to = from, to
+	return r.totals, nil
+}
+
+func TestGetRunnerPayoutSummary_DefaultsToCurrentMonth(t *testing.T) {
+	repo := &payoutTotalsRepo{totals: []payment.RunnerPayoutTotal{{Currency: "MYR", PayoutCents: 13500, BookingCount: 2}}}
+	svc := NewPaymentService(repo, nil, nil, nil, nil, nil, nil, nil, zap.NewNop())
+
+	summary, err := svc.GetRunnerPayoutSummary(context.Background(), uuid.New(), nil, nil)
+	require.NoError(t, err)
+
+	now := time.Now().UTC()
+	monthStart := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+	assert.Equal(t, monthStart, repo.from)
+	assert.Equal(t, monthStart.AddDate(0, 1, 0), repo.to)
+	assert.Equal(t, []RunnerPayoutCurrencyDTO{{Currency: "MYR", PayoutCents: 13500, BookingCount: 2}}, summary.Currencies)
+}
+
+func TestGetRunnerPayoutSummary_RejectsEmptyRange(t *testing.T) {
+	svc := NewPaymentService(&payoutTotalsRepo{}, nil, nil, nil, nil, nil, nil, nil, zap.NewNop())
+	from := time.Date(2026, time.March, 1, 0, 0, 0, 0, time.UTC)
+
+	_, err := svc.GetRunnerPayoutSummary(context.Background(), uuid.New(), &from, &from)
+	assert.ErrorIs(t, err, ErrInvalidSummaryRange)
+
+	to := from.AddDate(0, 1, 0)
+	summary, err := svc.GetRunnerPayoutSummary(context.Background(), uuid.New(), &from, &to)
+	require.NoError(t, err)
+	assert.NotNil(t, summary.Currencies, "no payouts is an empty list")
+	assert.Empty(t, summary.Currencies)
+}
@@ -0,0 +1,58 @@
+package application
+
+import (
+	"context"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// subscriptionRenewalBatchSize bounds how many subscriptions are renewed per run.
+const subscriptionRenewalBatchSize = 100
+
+// SubscriptionRenewalWorker renews auto-renewing subscriptions shortly before
+// they expire. It runs either on its own timer (Start) or whenever an external
+// scheduler asks it to (RunOnce, driven by the scheduler topic).
+type SubscriptionRenewalWorker struct {
+	svc      *SubscriptionService
+	lead     time.Duration
+	interval time.Duration
+	logger   *zap.Logger
+}
+
+// NewSubscriptionRenewalWorker creates a worker that renews subscriptions
+// expiring within lead. interval is only used by Start.
+func NewSubscriptionRenewalWorker(svc *SubscriptionService, lead, interval time.Duration, logger *zap.Logger) *SubscriptionRenewalWorker {
+	return &SubscriptionRenewalWorker{svc: svc, lead: lead, interval: interval, logger: logger}
+}
+
+// Start runs the worker every interval. It blocks until the context is cancelled.
+func (w *SubscriptionRenewalWorker) Start(ctx context.Context) {
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := w.RunOnce(ctx); err != nil && ctx.Err() == nil {
+				w.logger.Error("subscription renewal run failed", zap.Error(err))
+			}
+		}
+	}
+}
+
+// RunOnce renews one batch of due subscriptions. It is safe to run on several
+// instances at once. An error means the batch could not be loaded; failed
+// renewals of single subscriptions are logged and retried on the next run.
+func (w *SubscriptionRenewalWorker) RunOnce(ctx context.Context) error {
+	renewed, err := w.svc.RenewDueSubscriptions(ctx, w.lead, subscriptionRenewalBatchSize)
+	if err != nil {
+		return err
+	}
+	if renewed > 0 {
+		w.logger.Info("renewed due subscriptions", zap.Int("count", renewed))
+	}
+	return nil
+}
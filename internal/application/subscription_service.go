@@ -2,9 +2,11 @@ package application
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"time"
 
+	"github.com/Kilat-Pet-Delivery/lib-common/domain"
 	subDomain "github.com/Kilat-Pet-Delivery/service-payment/internal/domain/subscription"
 	"github.com/google/uuid"
 	"go.uber.org/zap"
@@ -21,6 +23,18 @@ type SubscriptionDTO struct {
 	Status     string    `json:"status"`
 	AutoRenew  bool      `json:"auto_renew"`
 	CreatedAt  time.Time `json:"created_at"`
+	// PausedAt is set while the subscription is paused.
+	PausedAt *time.Time `json:"paused_at,omitempty"`
+	// Comped subscriptions were granted free by an admin and never renew.
+	Comped     bool       `json:"comped,omitempty"`
+	CompReason string     `json:"comp_reason,omitempty"`
+	GrantedBy  *uuid.UUID `json:"granted_by,omitempty"`
+	// CommitmentEndsAt is set when the plan was taken with a minimum commitment.
+	CommitmentEndsAt *time.Time `json:"commitment_ends_at,omitempty"`
+	CancelledEarly   bool       `json:"cancelled_early,omitempty"`
+	// RefundDue is only set on a cancellation, reporting whether the unused
+	// rest of the paid period is due back.
+	RefundDue *bool `json:"refund_due,omitempty"`
 }
 
 // SubscribeRequest holds data to create a subscription.
@@ -28,15 +42,52 @@ type SubscribeRequest struct {
 	Plan string `json:"plan" binding:"required"`
 }
 
+// GrantSubscriptionRequest holds data for an admin to comp a subscription.
+type GrantSubscriptionRequest struct {
+	UserID uuid.UUID `json:"user_id" binding:"required"`
+	Plan   string    `json:"plan" binding:"required"`
+	// DurationDays defaults to the plan's duration when omitted.
+	DurationDays int    `json:"duration_days" binding:"min=0"`
+	Reason       string `json:"reason" binding:"required"`
+}
+
+// RenewalCharger bills a subscription for a renewal period.
+type RenewalCharger interface {
+	ChargeRenewal(ctx context.Context, sub *subDomain.Subscription) error
+}
+
 // SubscriptionService handles subscription use cases.
 type SubscriptionService struct {
-	repo   subDomain.SubscriptionRepository
-	logger *zap.Logger
+	repo    subDomain.SubscriptionRepository
+	charger RenewalCharger
+	logger  *zap.Logger
+
+	// commitment is the minimum commitment of new subscriptions to
+	// commitmentPlans; see SetCommitment.
+	commitment      time.Duration
+	commitmentPlans map[subDomain.PlanType]bool
+}
+
+// NewSubscriptionService creates a new SubscriptionService. charger may be nil,
+// in which case renewals extend the subscription without billing it.
+func NewSubscriptionService(repo subDomain.SubscriptionRepository, charger RenewalCharger, logger *zap.Logger) *SubscriptionService {
+	return &SubscriptionService{repo: repo, charger: charger, logger: logger}
 }
 
-// NewSubscriptionService creates a new SubscriptionService.
-func NewSubscriptionService(repo subDomain.SubscriptionRepository, logger *zap.Logger) *SubscriptionService {
-	return &SubscriptionService{repo: repo, logger: logger}
+// SetCommitment makes new subscriptions to plans carry a minimum commitment of
+// period; cancelling within it forfeits the refund. A period of zero disables
+// it. An unknown plan returns ErrInvalidPlan.
+func (s *SubscriptionService) SetCommitment(period time.Duration, plans []string) error {
+	commitmentPlans := make(map[subDomain.PlanType]bool, len(plans))
+	for _, p := range plans {
+		plan := subDomain.PlanType(p)
+		if _, ok := subDomain.FindPlan(plan); !ok {
+			return fmt.Errorf("%w %q", subDomain.ErrInvalidPlan, p)
+		}
+		commitmentPlans[plan] = true
+	}
+	s.commitment, s.commitmentPlans = period, commitmentPlans
+	return nil
 }
 
 // GetPlans returns all available subscription plans.
@@ -44,20 +95,54 @@ func (s *SubscriptionService) GetPlans() []subDomain.PlanInfo {
 	return subDomain.AvailablePlans()
 }
 
-// Subscribe creates a new subscription for a user.
-func (s *SubscriptionService) Subscribe(ctx context.Context, userID uuid.UUID, req SubscribeRequest) (*SubscriptionDTO, error) {
-	// Check if user already has an active subscription
+// Subscribe creates a new subscription for a user. idempotencyKey is the
+// client's Idempotency-Key header, or "" if it sent none; a repeated key
+// returns the subscription the first signup created instead of a new one.
+// Signups are not billed: subscriptions carry no payment method to charge.
+func (s *SubscriptionService) Subscribe(ctx context.Context, userID uuid.UUID, req SubscribeRequest, idempotencyKey string) (*SubscriptionDTO, error) {
+	if idempotencyKey != "" {
+		if original, err := s.repo.FindByIdempotencyKey(ctx, userID, idempotencyKey); err == nil {
+			return toSubDTO(original), nil
+		} else if !errors.Is(err, domain.ErrNotFound) {
+			return nil, fmt.Errorf("failed to look up idempotency key: %w", err)
+		}
+	}
+
+	// Lapsed subscriptions still count against the one-active index until expired
+	if err := s.repo.ExpireLapsed(ctx, userID, time.Now().UTC()); err != nil {
+		return nil, fmt.Errorf("failed to expire lapsed subscriptions: %w", err)
+	}
+
+	// Check if user already has an active subscription. The database enforces
+	// this too, for requests that race past the check.
 	existing, err := s.repo.FindActiveByUserID(ctx, userID)
 	if err == nil && existing != nil && existing.IsActive() {
-		return nil, fmt.Errorf("you already have an active %s subscription", existing.Plan())
+		return nil, subDomain.ErrActiveSubscriptionExists
+	}
+	if paused, err := s.repo.FindPausedByUserID(ctx, userID); err == nil && paused != nil {
+		return nil, subDomain.ErrPausedSubscriptionExists
 	}
 
 	sub, err := subDomain.NewSubscription(userID, subDomain.PlanType(req.Plan))
 	if err != nil {
 		return nil, err
 	}
+	sub.SetIdempotencyKey(idempotencyKey)
+	if s.commitmentPlans[sub.Plan()] {
+		sub.SetCommitment(s.commitment)
+	}
 
 	if err := s.repo.Save(ctx, sub); err != nil {
+		// A concurrent signup with the same key may have won the race; which
+		// unique index rejects this one depends on timing.
+		if idempotencyKey != "" && errors.Is(err, domain.ErrConflict) {
+			if original, findErr := s.repo.FindByIdempotencyKey(ctx, userID, idempotencyKey); findErr == nil {
+				return toSubDTO(original), nil
+			}
+		}
+		if errors.Is(err, subDomain.ErrActiveSubscriptionExists) {
+			return nil, err
+		}
 		return nil, fmt.Errorf("failed to save subscription: %w", err)
 	}
 
@@ -69,35 +154,253 @@ func (s *SubscriptionService) Subscribe(ctx context.Context, userID uuid.UUID, r
 	return toSubDTO(sub), nil
 }
 
+// GrantSubscription gives a user a free subscription without charging them
+// (admin). Like Subscribe it fails if the user already has an active or paused
+// subscription; support should cancel that one first.
+func (s *SubscriptionService) GrantSubscription(ctx context.Context, adminID uuid.UUID, req GrantSubscriptionRequest) (*SubscriptionDTO, error) {
+	if err := s.repo.ExpireLapsed(ctx, req.UserID, time.Now().UTC()); err != nil {
+		return nil, fmt.Errorf("failed to expire lapsed subscriptions: %w", err)
+	}
+
+	existing, err := s.repo.FindActiveByUserID(ctx, req.UserID)
+	if err == nil && existing != nil && existing.IsActive() {
+		return nil, subDomain.ErrActiveSubscriptionExists
+	}
+	if paused, err := s.repo.FindPausedByUserID(ctx, req.UserID); err == nil && paused != nil {
+		return nil, subDomain.ErrPausedSubscriptionExists
+	}
+
+	sub, err := subDomain.NewCompedSubscription(req.UserID, subDomain.PlanType(req.Plan), req.DurationDays,
+		subDomain.Grant{GrantedBy: adminID, Reason: req.Reason})
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.repo.Save(ctx, sub); err != nil {
+		if errors.Is(err, subDomain.ErrActiveSubscriptionExists) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("failed to save subscription: %w", err)
+	}
+
+	s.logger.Info("subscription granted",
+		zap.String("subscription_id", sub.ID().String()),
+		zap.String("user_id", req.UserID.String()),
+		zap.String("plan", req.Plan),
+		zap.String("granted_by", adminID.String()),
+		zap.String("reason", req.Reason),
+		zap.Time("expires_at", sub.ExpiresAt()),
+	)
+
+	return toSubDTO(sub), nil
+}
+
 // GetMySubscription returns the user's active subscription.
 func (s *SubscriptionService) GetMySubscription(ctx context.Context, userID uuid.UUID) (*SubscriptionDTO, error) {
-	sub, err := s.repo.FindActiveByUserID(ctx, userID)
+	sub, err := s.findActive(ctx, userID)
 	if err != nil {
-		return nil, fmt.Errorf("no active subscription found")
+		return nil, err
 	}
 	return toSubDTO(sub), nil
 }
 
-// CancelSubscription cancels the user's active subscription.
-func (s *SubscriptionService) CancelSubscription(ctx context.Context, userID uuid.UUID) (*SubscriptionDTO, error) {
+// findActive loads the user's active subscription. A missing subscription is
+// ErrNoActiveSubscription; any other failure is returned wrapped.
+func (s *SubscriptionService) findActive(ctx context.Context, userID uuid.UUID) (*subDomain.Subscription, error) {
 	sub, err := s.repo.FindActiveByUserID(ctx, userID)
 	if err != nil {
-		return nil, fmt.Errorf("no active subscription found")
+		if errors.Is(err, domain.ErrNotFound) {
+			return nil, subDomain.ErrNoActiveSubscription
+		}
+		return nil, fmt.Errorf("failed to load subscription: %w", err)
+	}
+	return sub, nil
+}
+
+// CancelSubscription cancels the user's active subscription and reports
+// whether a refund is due: none is while the subscription's commitment runs.
+func (s *SubscriptionService) CancelSubscription(ctx context.Context, userID uuid.UUID) (*SubscriptionDTO, error) {
+	sub, err := s.findActive(ctx, userID)
+	if err != nil {
+		return nil, err
 	}
 
-	sub.Cancel()
+	refundDue := sub.CancelWithPolicy(time.Now().UTC())
 	if err := s.repo.Update(ctx, sub); err != nil {
 		return nil, fmt.Errorf("failed to cancel subscription: %w", err)
 	}
 
-	s.logger.Info("subscription cancelled", zap.String("user_id", userID.String()))
+	s.logger.Info("subscription cancelled",
+		zap.String("user_id", userID.String()),
+		zap.Bool("cancelled_early", sub.CancelledEarly()),
+		zap.Bool("refund_due", refundDue),
+	)
+	dto := toSubDTO(sub)
+	dto.RefundDue = &refundDue
+	return dto, nil
+}
+
+// PauseSubscription pauses the user's active subscription.
+func (s *SubscriptionService) PauseSubscription(ctx context.Context, userID uuid.UUID) (*SubscriptionDTO, error) {
+	sub, err := s.findActive(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := sub.Pause(time.Now().UTC()); err != nil {
+		return nil, err
+	}
+	if err := s.repo.Update(ctx, sub); err != nil {
+		return nil, fmt.Errorf("failed to pause subscription: %w", err)
+	}
+
+	s.logger.Info("subscription paused", zap.String("user_id", userID.String()))
 	return toSubDTO(sub), nil
 }
 
+// ResumeSubscription resumes the user's paused subscription, extending its
+// expiry by the time it was paused.
+func (s *SubscriptionService) ResumeSubscription(ctx context.Context, userID uuid.UUID) (*SubscriptionDTO, error) {
+	sub, err := s.repo.FindPausedByUserID(ctx, userID)
+	if err != nil {
+		if errors.Is(err, domain.ErrNotFound) {
+			return nil, subDomain.ErrNoPausedSubscription
+		}
+		return nil, fmt.Errorf("failed to load subscription: %w", err)
+	}
+
+	if err := sub.Resume(time.Now().UTC()); err != nil {
+		return nil, err
+	}
+	if err := s.repo.Update(ctx, sub); err != nil {
+		return nil, fmt.Errorf("failed to resume subscription: %w", err)
+	}
+
+	s.logger.Info("subscription resumed",
+		zap.String("user_id", userID.String()),
+		zap.Time("expires_at", sub.ExpiresAt()),
+	)
+	return toSubDTO(sub), nil
+}
+
+// RenewSubscription extends the user's active subscription by one plan period.
+// The renewal is claimed with a versioned update before the charge, so when
+// two renewals race only the winner charges; the loser reloads and returns
+// ErrAlreadyRenewed if the subscription was extended under it.
+func (s *SubscriptionService) RenewSubscription(ctx context.Context, userID uuid.UUID) (*SubscriptionDTO, error) {
+	sub, err := s.findActive(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.renew(ctx, sub); err != nil {
+		return nil, err
+	}
+	return toSubDTO(sub), nil
+}
+
+// renew extends sub by one plan period and charges for it, as described on
+// RenewSubscription.
+func (s *SubscriptionService) renew(ctx context.Context, sub *subDomain.Subscription) error {
+	previousExpiresAt, err := sub.Renew(time.Now().UTC())
+	if err != nil {
+		return err
+	}
+	if err := s.repo.Update(ctx, sub); err != nil {
+		if errors.Is(err, domain.ErrConflict) {
+			if current, findErr := s.repo.FindByID(ctx, sub.ID()); findErr == nil && current.ExpiresAt().After(previousExpiresAt) {
+				return subDomain.ErrAlreadyRenewed
+			}
+		}
+		return fmt.Errorf("failed to renew subscription: %w", err)
+	}
+
+	if s.charger != nil {
+		if err := s.charger.ChargeRenewal(ctx, sub); err != nil {
+			sub.RevertRenewal(previousExpiresAt)
+			if revertErr := s.repo.Update(ctx, sub); revertErr != nil {
+				s.logger.Error("failed to revert unpaid subscription renewal",
+					zap.String("subscription_id", sub.ID().String()),
+					zap.Error(revertErr),
+				)
+			}
+			return fmt.Errorf("failed to charge subscription renewal: %w", err)
+		}
+	}
+
+	s.logger.Info("subscription renewed",
+		zap.String("user_id", sub.UserID().String()),
+		zap.Time("expires_at", sub.ExpiresAt()),
+	)
+	return nil
+}
+
+// RenewDueSubscriptions renews up to limit auto-renewing subscriptions that
+// expire within lead of now and returns how many it renewed. Each renewal is
+// claimed with a versioned update, so instances running the batch at the same
+// time never renew a subscription twice; the ones they lose are skipped.
+func (s *SubscriptionService) RenewDueSubscriptions(ctx context.Context, lead time.Duration, limit int) (int, error) {
+	now := time.Now().UTC()
+	subs, err := s.repo.FindRenewalDue(ctx, now, now.Add(lead), limit)
+	if err != nil {
+		return 0, fmt.Errorf("failed to load subscriptions due for renewal: %w", err)
+	}
+
+	renewed := 0
+	for _, sub := range subs {
+		if ctx.Err() != nil {
+			return renewed, ctx.Err()
+		}
+		if err := s.renew(ctx, sub); err != nil {
+			if errors.Is(err, subDomain.ErrAlreadyRenewed) {
+				s.logger.Debug("subscription renewed by another instance, skipping",
+					zap.String("subscription_id", sub.ID().String()),
+				)
+				continue
+			}
+			s.logger.Warn("scheduled subscription renewal failed",
+				zap.String("subscription_id", sub.ID().String()),
+				zap.Error(err),
+			)
+			continue
+		}
+		renewed++
+	}
+	return renewed, nil
+}
+
+// GetSubscriptionByID returns any subscription by ID (admin).
+func (s *SubscriptionService) GetSubscriptionByID(ctx context.Context, id uuid.UUID) (*SubscriptionDTO, error) {
+	sub, err := s.repo.FindByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return toSubDTO(sub), nil
+}
+
+// ListSubscriptionsByUser returns a page of a user's subscriptions, newest first (admin).
+func (s *SubscriptionService) ListSubscriptionsByUser(ctx context.Context, userID uuid.UUID, page, limit int) ([]*SubscriptionDTO, int64, error) {
+	subs, total, err := s.repo.FindByUserID(ctx, userID, page, limit)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	dtos := make([]*SubscriptionDTO, len(subs))
+	for i, sub := range subs {
+		dtos[i] = toSubDTO(sub)
+	}
+	return dtos, total, nil
+}
+
 func toSubDTO(s *subDomain.Subscription) *SubscriptionDTO {
-	return &SubscriptionDTO{
+	dto := &SubscriptionDTO{
 		ID: s.ID(), UserID: s.UserID(), Plan: string(s.Plan()),
 		PriceCents: s.PriceCents(), StartedAt: s.StartedAt(), ExpiresAt: s.ExpiresAt(),
 		Status: string(s.Status()), AutoRenew: s.AutoRenew(), CreatedAt: s.CreatedAt(),
+		PausedAt: s.PausedAt(), CommitmentEndsAt: s.CommitmentEndsAt(), CancelledEarly: s.CancelledEarly(),
+	}
+	if g := s.Grant(); g != nil {
+		grantedBy := g.GrantedBy
+		dto.Comped, dto.CompReason, dto.GrantedBy = true, g.Reason, &grantedBy
 	}
+	return dto
 }
@@ -0,0 +1,312 @@
+package application
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/Kilat-Pet-Delivery/lib-common/domain"
+	subDomain "github.com/Kilat-Pet-Delivery/service-payment/internal/domain/subscription"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+// versionedSubRepo stores one subscription and rejects stale updates like
+// the GORM repository. Loads wait at loaded until every renewal has read the
+// subscription, so the renewals race on the same version.
+type versionedSubRepo struct {
+	subDomain.SubscriptionRepository
+	mu     sync.Mutex
+	sub    *subDomain.Subscription
+	loaded *sync.WaitGroup
+}
+
+func (r *versionedSubRepo) snapshot() *subDomain.Subscription {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	s := r.sub
+	return subDomain.Reconstruct(s.ID(), s.UserID(), s.Plan(), s.PriceCents(), s.StartedAt(), s.ExpiresAt(),
+		s.Status(), s.AutoRenew(), s.PausedAt(), s.PausedDuration(), s.Grant(), s.IdempotencyKey(),
+		s.CommitmentEndsAt(), s.CancelledEarly(), s.Version(), s.CreatedAt(), s.UpdatedAt())
+}
+
+func (r *versionedSubRepo) FindActiveByUserID(_ context.Context, _ uuid.UUID) (*subDomain.Subscription, error) {
+	s := r.snapshot()
+	r.loaded.Done()
+	r.loaded.Wait()
+	return s, nil
+}
+
+func (r *versionedSubRepo) FindRenewalDue(_ context.Context, _, _ time.Time, _ int) ([]*subDomain.Subscription, error) {
+	s := r.snapshot()
+	r.loaded.Done()
+	r.loaded.Wait()
+	return []*subDomain.Subscription{s}, nil
+}
+
+func (r *versionedSubRepo) FindByID(_ context.Context, _ uuid.UUID) (*subDomain.Subscription, error) {
+	return r.snapshot(), nil
+}
+
+func (r *versionedSubRepo) Update(_ context.Context, s *subDomain.Subscription) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.sub.Version() != s.Version()-1 {
+		return domain.NewConflictError("subscription was modified concurrently")
+	}
+	r.sub = s
+	return nil
+}
+
+type countingCharger struct {
+	charges atomic.Int32
+}
+
+func (c *countingCharger) ChargeRenewal(_ context.Context, _ *subDomain.Subscription) error {
+	c.charges.Add(1)
+	return nil
+}
+
+func TestRenewSubscription_SimultaneousRenewalsChargeOnce(t *testing.T) {
+	sub, err := subDomain.NewSubscription(uuid.New(), subDomain.PlanBasic)
+	require.NoError(t, err)
+	originalExpiry := sub.ExpiresAt()
+
+	const renewals = 2
+	var loaded sync.WaitGroup
+	loaded.Add(renewals)
+	repo := &versionedSubRepo{sub: sub, loaded: &loaded}
+	charger := &countingCharger{}
+	svc := NewSubscriptionService(repo, charger, zap.NewNop())
+
+	var wg sync.WaitGroup
+	errs := make([]error, renewals)
+	for i := 0; i < renewals; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			_, errs[i] = svc.RenewSubscription(context.Background(), sub.UserID())
+		}(i)
+	}
+	wg.Wait()
+
+	var succeeded, alreadyRenewed int
+	for _, e := range errs {
+		switch {
+		case e == nil:
+			succeeded++
+		case errors.Is(e, subDomain.ErrAlreadyRenewed):
+			alreadyRenewed++
+		default:
+			t.Fatalf("unexpected renew error: %v", e)
+		}
+	}
+	assert.Equal(t, 1, succeeded, "exactly one renewal should win")
+	assert.Equal(t, 1, alreadyRenewed, "the loser should see the subscription already renewed")
+	assert.Equal(t, int32(1), charger.charges.Load(), "only the winner should charge")
+	assert.Equal(t, originalExpiry.AddDate(0, 0, 30), repo.snapshot().ExpiresAt(), "subscription should be extended once")
+}
+
+// TestRenewalWorker_ConcurrentRunsRenewOnce runs the renewal batch on two
+// instances that load the same due subscription; only one renews and charges.
+func TestRenewalWorker_ConcurrentRunsRenewOnce(t *testing.T) {
+	sub, err := subDomain.NewSubscription(uuid.New(), subDomain.PlanBasic)
+	require.NoError(t, err)
+	originalExpiry := sub.ExpiresAt()
+
+	const instances = 2
+	var loaded sync.WaitGroup
+	loaded.Add(instances)
+	repo := &versionedSubRepo{sub: sub, loaded: &loaded}
+	charger := &countingCharger{}
+	worker := NewSubscriptionRenewalWorker(NewSubscriptionService(repo, charger, zap.NewNop()), 24*time.Hour, time.Hour, zap.NewNop())
+
+	var wg sync.WaitGroup
+	errs := make([]error, instances)
+	for i := 0; i < instances; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			errs[i] = worker.RunOnce(context.Background())
+		}(i)
+	}
+	wg.Wait()
+
+	for _, e := range errs {
+		require.NoError(t, e, "losing the race is not an error")
+	}
+	assert.Equal(t, int32(1), charger.charges.Load(), "only one instance should charge")
+	assert.Equal(t, originalExpiry.AddDate(0, 0, 30), repo.snapshot().ExpiresAt(), "subscription should be extended once")
+}
+
+type failingCharger struct{}
+
+func (failingCharger) ChargeRenewal(_ context.Context, _ *subDomain.Subscription) error {
+	return errors.New("card declined")
+}
+
+func TestRenewSubscription_ChargeFailureRevertsRenewal(t *testing.T) {
+	sub, err := subDomain.NewSubscription(uuid.New(), subDomain.PlanPremium)
+	require.NoError(t, err)
+	originalExpiry := sub.ExpiresAt()
+
+	var loaded sync.WaitGroup
+	loaded.Add(1)
+	repo := &versionedSubRepo{sub: sub, loaded: &loaded}
+	svc := NewSubscriptionService(repo, failingCharger{}, zap.NewNop())
+
+	_, err = svc.RenewSubscription(context.Background(), sub.UserID())
+	require.Error(t, err)
+
+	stored := repo.snapshot()
+	assert.True(t, stored.ExpiresAt().Equal(originalExpiry), "unpaid renewal should be reverted")
+	assert.Equal(t, int64(3), stored.Version())
+	assert.WithinDuration(t, time.Now(), stored.UpdatedAt(), time.Minute)
+}
+
+// memorySubRepo keeps subscriptions in memory, enforcing one active or paused
+// subscription per user like the database index.
+type memorySubRepo struct {
+	subDomain.SubscriptionRepository
+	subs []*subDomain.Subscription
+}
+
+func (r *memorySubRepo) find(userID uuid.UUID, status subDomain.SubStatus) (*subDomain.Subscription, error) {
+	for _, s := range r.subs {
+		if s.UserID() == userID && s.Status() == status {
+			return s, nil
+		}
+	}
+	if status == subDomain.StatusPaused {
+		return nil, subDomain.ErrNoPausedSubscription
+	}
+	return nil, subDomain.ErrNoActiveSubscription
+}
+
+func (r *memorySubRepo) Save(ctx context.Context, s *subDomain.Subscription) error {
+	if s.IdempotencyKey() != "" {
+		if _, err := r.FindByIdempotencyKey(ctx, s.UserID(), s.IdempotencyKey()); err == nil {
+			return subDomain.ErrDuplicateIdempotencyKey
+		}
+	}
+	if _, err := r.find(s.UserID(), subDomain.StatusActive); err == nil {
+		return subDomain.ErrActiveSubscriptionExists
+	}
+	r.subs = append(r.subs, s)
+	return nil
+}
+
+func (r *memorySubRepo) FindByIdempotencyKey(_ context.Context, userID uuid.UUID, key string) (*subDomain.Subscription, error) {
+	for _, s := range r.subs {
+		if s.UserID() == userID && s.IdempotencyKey() == key {
+			return s, nil
+		}
+	}
+	return nil, domain.NewNotFoundError("Subscription", key)
+}
+
+func (r *memorySubRepo) Update(context.Context, *subDomain.Subscription) error { return nil }
+
+func (r *memorySubRepo) ExpireLapsed(context.Context, uuid.UUID, time.Time) error { return nil }
+
+func (r *memorySubRepo) FindActiveByUserID(_ context.Context, userID uuid.UUID) (*subDomain.Subscription, error) {
+	return r.find(userID, subDomain.StatusActive)
+}
+
+func (r *memorySubRepo) FindPausedByUserID(_ context.Context, userID uuid.UUID) (*subDomain.Subscription, error) {
+	return r.find(userID, subDomain.StatusPaused)
+}
+
+func TestGrantSubscription(t *testing.T) {
+	repo := &memorySubRepo{}
+	charger := &countingCharger{}
+	svc := NewSubscriptionService(repo, charger, zap.NewNop())
+	admin, user := uuid.New(), uuid.New()
+
+	dto, err := svc.GrantSubscription(context.Background(), admin, GrantSubscriptionRequest{
+		UserID: user, Plan: "premium", DurationDays: 14, Reason: "compensation for delayed booking",
+	})
+	require.NoError(t, err)
+	assert.True(t, dto.Comped)
+	assert.Equal(t, "compensation for delayed booking", dto.CompReason)
+	assert.Equal(t, &admin, dto.GrantedBy)
+	assert.Zero(t, dto.PriceCents)
+	assert.False(t, dto.AutoRenew)
+	assert.Equal(t, dto.StartedAt.AddDate(0, 0, 14), dto.ExpiresAt)
+
+	_, err = svc.RenewSubscription(context.Background(), user)
+	assert.ErrorIs(t, err, domain.ErrInvalidState)
+	assert.Zero(t, charger.charges.Load(), "comped subscriptions are never charged")
+}
+
+func TestGrantSubscription_RespectsOneActive(t *testing.T) {
+	user := uuid.New()
+	paid, err := subDomain.NewSubscription(user, subDomain.PlanBasic)
+	require.NoError(t, err)
+	repo := &memorySubRepo{subs: []*subDomain.Subscription{paid}}
+	svc := NewSubscriptionService(repo, nil, zap.NewNop())
+	req := GrantSubscriptionRequest{UserID: user, Plan: "premium", Reason: "VIP"}
+
+	_, err = svc.GrantSubscription(context.Background(), uuid.New(), req)
+	assert.ErrorIs(t, err, subDomain.ErrActiveSubscriptionExists)
+
+	require.NoError(t, paid.Pause(time.Now().UTC()))
+	_, err = svc.GrantSubscription(context.Background(), uuid.New(), req)
+	assert.ErrorIs(t, err, subDomain.ErrPausedSubscriptionExists)
+
+	paid.Cancel()
+	_, err = svc.GrantSubscription(context.Background(), uuid.New(), req)
+	require.NoError(t, err, "a cancelled subscription does not block a grant")
+	assert.Len(t, repo.subs, 2)
+}
+
+func TestSubscribe_RepeatedIdempotencyKeyReturnsOriginal(t *testing.T) {
+	repo := &memorySubRepo{}
+	svc := NewSubscriptionService(repo, nil, zap.NewNop())
+	user := uuid.New()
+	req := SubscribeRequest{Plan: "premium"}
+
+	first, err := svc.Subscribe(context.Background(), user, req, "signup-123")
+	require.NoError(t, err)
+	second, err := svc.Subscribe(context.Background(), user, req, "signup-123")
+	require.NoError(t, err, "a retried signup is not rejected as a second active subscription")
+
+	assert.Equal(t, first, second)
+	assert.Len(t, repo.subs, 1)
+
+	_, err = svc.Subscribe(context.Background(), user, req, "signup-456")
+	assert.ErrorIs(t, err, subDomain.ErrActiveSubscriptionExists, "a new key is a new signup")
+}
+
+func TestCancelSubscription_WithinCommitmentOwesNoRefund(t *testing.T) {
+	repo := &memorySubRepo{}
+	svc := NewSubscriptionService(repo, &countingCharger{}, zap.NewNop())
+	require.NoError(t, svc.SetCommitment(90*24*time.Hour, []string{"premium"}))
+	premium, basic := uuid.New(), uuid.New()
+
+	sub, err := svc.Subscribe(context.Background(), premium, SubscribeRequest{Plan: "premium"}, "signup-1")
+	require.NoError(t, err)
+	require.NotNil(t, sub.CommitmentEndsAt)
+
+	dto, err := svc.CancelSubscription(context.Background(), premium)
+	require.NoError(t, err)
+	assert.True(t, dto.CancelledEarly)
+	require.NotNil(t, dto.RefundDue)
+	assert.False(t, *dto.RefundDue)
+
+	_, err = svc.Subscribe(context.Background(), basic, SubscribeRequest{Plan: "basic"}, "signup-2")
+	require.NoError(t, err)
+	dto, err = svc.CancelSubscription(context.Background(), basic)
+	require.NoError(t, err)
+	assert.Nil(t, dto.CommitmentEndsAt, "only configured plans carry a commitment")
+	assert.False(t, dto.CancelledEarly)
+	require.NotNil(t, dto.RefundDue)
+	assert.True(t, *dto.RefundDue)
+
+	assert.ErrorIs(t, svc.SetCommitment(time.Hour, []string{"platinum"}), subDomain.ErrInvalidPlan)
+}
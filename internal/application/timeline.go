@@ -0,0 +1,112 @@
+package application
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/Kilat-Pet-Delivery/service-payment/internal/domain/payment"
+	"github.com/google/uuid"
+)
+
+// Types of payment timeline entries.
+const (
+	TimelineStatusChange      = "status_change"
+	TimelineRefund            = "refund"
+	TimelineCallbackScheduled = "callback_scheduled"
+	TimelineCallbackAttempt   = "callback_attempt"
+)
+
+// TimelineRefundDTO details a payment's refund. InitiatedBy is who or what
+// refunded it, taken from the status history.
+type TimelineRefundDTO struct {
+	AmountCents        int64         `json:"amount_cents"`
+	CreditAppliedCents int64         `json:"credit_applied_cents,omitempty"`
+	Currency           string        `json:"currency"`
+	Reason             string        `json:"reason,omitempty"`
+	InitiatedBy        *InitiatorDTO `json:"initiated_by,omitempty"`
+}
+
+// TimelineCallbackDTO identifies a callback delivery and, for an attempt, its
+// outcome.
+type TimelineCallbackDTO struct {
+	DeliveryID uuid.UUID `json:"delivery_id"`
+	EventType  string    `json:"event_type"`
+	Attempt    int       `json:"attempt,omitempty"`
+	StatusCode int       `json:"status_code,omitempty"`
+	Error      string    `json:"error,omitempty"`
+}
+
+// TimelineEntryDTO is one entry of a payment's timeline. The detail field
+// matching Type is set.
+type TimelineEntryDTO struct {
+	Type         string               `json:"type"`
+	OccurredAt   time.Time            `json:"occurred_at"`
+	StatusChange *StatusChangeDTO     `json:"status_change,omitempty"`
+	Refund       *TimelineRefundDTO   `json:"refund,omitempty"`
+	Callback     *TimelineCallbackDTO `json:"callback,omitempty"`
+}
+
+// GetPaymentTimeline merges a payment's status transitions, its refund, and
+// its callback deliveries and their attempts into one list, oldest first
+// (admin). Entries at the same instant are kept in that order. Callbacks are
+// only included once SetCallbackDeliveries is called.
+func (s *PaymentService) GetPaymentTimeline(ctx context.Context, paymentID uuid.UUID) ([]TimelineEntryDTO, error) {
+	p, err := s.repo.FindByID(ctx, paymentID)
+	if err != nil {
+		return nil, err
+	}
+
+	changes, err := s.repo.FindStatusHistory(ctx, paymentID)
+	if err != nil {
+		return nil, err
+	}
+	entries := make([]TimelineEntryDTO, 0, len(changes)+1)
+	var refundedBy *InitiatorDTO
+	for _, c := range changes {
+		change := toStatusChangeDTO(c)
+		entries = append(entries, TimelineEntryDTO{Type: TimelineStatusChange, OccurredAt: c.OccurredAt, StatusChange: &change})
+		if c.To == payment.EscrowRefunded || c.To == payment.EscrowChargedBack {
+			refundedBy = &change.InitiatedBy
+		}
+	}
+
+	if refundedAt := p.RefundedAt(); refundedAt != nil {
+		entries = append(entries, TimelineEntryDTO{Type: TimelineRefund, OccurredAt: *refundedAt, Refund: &TimelineRefundDTO{
+			AmountCents:        p.AmountCents(),
+			CreditAppliedCents: p.CreditAppliedCents(),
+			Currency:           p.Currency(),
+			Reason:             p.RefundReason(),
+			InitiatedBy:        refundedBy,
+		}})
+	}
+
+	if s.callbackDeliveries != nil {
+		deliveries, err := s.callbackDeliveries.FindByPaymentID(ctx, paymentID)
+		if err != nil {
+			return nil, err
+		}
+		for _, d := range deliveries {
+			entries = append(entries, TimelineEntryDTO{Type: TimelineCallbackScheduled, OccurredAt: d.CreatedAt, Callback: &TimelineCallbackDTO{
+				DeliveryID: d.ID,
+				EventType:  d.EventType,
+			}})
+			attempts, err := s.callbackDeliveries.FindAttempts(ctx, d.ID)
+			if err != nil {
+				return nil, err
+			}
+			for _, a := range attempts {
+				entries = append(entries, TimelineEntryDTO{Type: TimelineCallbackAttempt, OccurredAt: a.AttemptedAt, Callback: &TimelineCallbackDTO{
+					DeliveryID: d.ID,
+					EventType:  d.EventType,
+					Attempt:    a.Number,
+					StatusCode: a.StatusCode,
+					Error:      a.Error,
+				}})
+			}
+		}
+	}
+
+	sort.SliceStable(entries, func(i, j int) bool { return entries[i].OccurredAt.Before(entries[j].OccurredAt) })
+	return entries, nil
+}
@@ -0,0 +1,136 @@
+package application
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/Kilat-Pet-Delivery/service-payment/internal/clock"
+	"github.com/Kilat-Pet-Delivery/service-payment/internal/domain/callback"
+	"github.com/Kilat-Pet-Delivery/service-payment/internal/domain/payment"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+// historyRepo serves one payment and the transitions it has queued as its
+// persisted history, attributed to actor when set.
+type historyRepo struct {
+	payment.PaymentRepository
+	p     *payment.Payment
+	actor string
+}
+
+func (r *historyRepo) FindByID(context.Context, uuid.UUID) (*payment.Payment, error) {
+	return r.p, nil
+}
+
+func (r *historyRepo) FindStatusHistory(context.Context, uuid.UUID) ([]payment.StatusChange, error) {
+	changes := append([]payment.StatusChange(nil), r.p.StatusChanges()...)
+	for i := range changes {
+		changes[i].Actor = r.actor
+	}
+	return changes, nil
+}
+
+func TestGetPaymentTimeline_OrdersEntriesAcrossSources(t *testing.T) {
+	p, err := payment.NewPayment(uuid.New(), uuid.New(), 10000, "MYR", 15.0, payment.PayoutFloors{})
+	require.NoError(t, err)
+	t0 := time.Now().UTC().Add(time.Minute)
+	fake := clock.NewFake(t0)
+	p.UseClock(fake)
+
+	require.NoError(t, p.HoldEscrow("pi_1", 0))
+	fake.Set(t0.Add(5 * time.Minute))
+	require.NoError(t, p.ReleaseToRunner(uuid.New()))
+	fake.Set(t0.Add(10 * time.Minute))
+	require.NoError(t, p.OpenDispute("item not delivered"))
+	fake.Set(t0.Add(30 * time.Minute))
+	require.NoError(t, p.Clawback("dispute decided for owner"))
+
+	released := callback.NewDelivery(p.ID(), "https://example.com/hook", "payment.escrow_released", nil, t0.Add(5*time.Minute+time.Second))
+	chargedBack := callback.NewDelivery(p.ID(), "https://example.com/hook", "payment.charged_back", nil, t0.Add(30*time.Minute+time.Second))
+	callbacks := &memoryCallbackRepo{
+		deliveries: []*callback.Delivery{released, chargedBack},
+		attempts: []callback.Attempt{
+			{DeliveryID: released.ID, Number: 1, StatusCode: 503, Error: "unexpected status 503", AttemptedAt: t0.Add(5*time.Minute + 2*time.Second)},
+			{DeliveryID: released.ID, Number: 2, StatusCode: 200, AttemptedAt: t0.Add(15 * time.Minute)},
+			{DeliveryID: chargedBack.ID, Number: 1, StatusCode: 200, AttemptedAt: t0.Add(30*time.Minute + 2*time.Second)},
+		},
+	}
+
+	svc := NewPaymentService(&historyRepo{p: p}, nil, nil, nil, nil, nil, nil, nil, zap.NewNop())
+	svc.SetCallbackDeliveries(callbacks)
+
+	timeline, err := svc.GetPaymentTimeline(context.Background(), p.ID())
+	require.NoError(t, err)
+
+	type step struct{ entryType, detail string }
+	var got []step
+	for i, e := range timeline {
+		if i > 0 {
+			assert.False(t, e.OccurredAt.Before(timeline[i-1].OccurredAt), "entry %d is out of order", i)
+		}
+		switch e.Type {
+		case TimelineStatusChange:
+			got = append(got, step{e.Type, e.StatusChange.ToStatus})
+		case TimelineRefund:
+			got = append(got, step{e.Type, e.Refund.Reason})
+		default:
+			got = append(got, step{e.Type, e.Callback.EventType})
+		}
+	}
+	assert.Equal(t, []step{
+		{TimelineStatusChange, "pending"},
+		{TimelineStatusChange, "held"},
+		{TimelineStatusChange, "released"},
+		{TimelineCallbackScheduled, "payment.escrow_released"},
+		{TimelineCallbackAttempt, "payment.escrow_released"},
+		{TimelineStatusChange, "disputed"},
+		{TimelineCallbackAttempt, "payment.escrow_released"},
+		{TimelineStatusChange, "charged_back"},
+		{TimelineRefund, "dispute decided for owner"},
+		{TimelineCallbackScheduled, "payment.charged_back"},
+		{TimelineCallbackAttempt, "payment.charged_back"},
+	}, got)
+
+	assert.Equal(t, 503, timeline[4].Callback.StatusCode)
+	assert.Equal(t, 2, timeline[6].Callback.Attempt)
+	assert.Equal(t, int64(10000), timeline[8].Refund.AmountCents)
+}
+
+func TestGetPaymentHistory_ExposesRefundInitiator(t *testing.T) {
+	adminID := uuid.New()
+	tests := []struct {
+		name  string
+		actor string
+		want  InitiatorDTO
+	}{
+		{"admin refund", "admin:" + adminID.String(), InitiatorDTO{Type: "admin", ID: adminID.String()}},
+		{"booking event auto-refund", "system:booking-events", InitiatorDTO{Type: "system", ID: "booking-events"}},
+		{"unattributed", payment.ActorSystem, InitiatorDTO{Type: "system"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, err := payment.NewPayment(uuid.New(), uuid.New(), 10000, "MYR", 15.0, payment.PayoutFloors{})
+			require.NoError(t, err)
+			require.NoError(t, p.HoldEscrow("pi_1", 0))
+			require.NoError(t, p.Refund("booking cancelled"))
+			svc := NewPaymentService(&historyRepo{p: p, actor: tt.actor}, nil, nil, nil, nil, nil, nil, nil, zap.NewNop())
+
+			history, err := svc.GetPaymentHistory(context.Background(), p.ID())
+			require.NoError(t, err)
+			require.Len(t, history, 3)
+			assert.Equal(t, "refunded", history[2].ToStatus)
+			assert.Equal(t, tt.want, history[2].InitiatedBy)
+
+			timeline, err := svc.GetPaymentTimeline(context.Background(), p.ID())
+			require.NoError(t, err)
+			refund := timeline[len(timeline)-1]
+			require.Equal(t, TimelineRefund, refund.Type)
+			require.NotNil(t, refund.Refund.InitiatedBy)
+			assert.Equal(t, tt.want, *refund.Refund.InitiatedBy)
+		})
+	}
+}
@@ -1,9 +1,16 @@
 package config
 
 import (
+	"fmt"
+	"strings"
 	"time"
 
 	"github.com/Kilat-Pet-Delivery/lib-common/config"
+	"github.com/Kilat-Pet-Delivery/lib-proto/events"
+	"github.com/Kilat-Pet-Delivery/service-payment/internal/feature"
+	"github.com/Kilat-Pet-Delivery/service-payment/internal/pagination"
+	"github.com/Kilat-Pet-Delivery/service-payment/internal/tracing"
+	kafkago "github.com/segmentio/kafka-go"
 	"github.com/spf13/viper"
 )
 
@@ -13,18 +20,144 @@ type StripeConfig struct {
 	WebhookSecret string
 }
 
+// TestMode reports whether SecretKey is a Stripe test key.
+func (c StripeConfig) TestMode() bool {
+	return strings.HasPrefix(c.SecretKey, "sk_test_")
+}
+
+// Subscription renewal modes.
+const (
+	RenewalModeTimer = "timer"
+	RenewalModeEvent = "event"
+)
+
 // ServiceConfig holds all configuration for the payment service.
 type ServiceConfig struct {
-	Port               string
-	AppEnv             string
-	DBConfig           config.DatabaseConfig
+	Port     string
+	AppEnv   string
+	DBConfig config.DatabaseConfig
+	// DBConnectRetries is how many times a failed database connection at
+	// startup is retried, waiting DBConnectBackoff before the first retry and
+	// doubling up to 30s. Zero fails on the first error.
+	DBConnectRetries   int
+	DBConnectBackoff   time.Duration
 	JWTConfig          config.JWTConfig
 	KafkaConfig        config.KafkaConfig
 	StripeConfig       StripeConfig
 	PlatformFeePercent float64
+	// MinRunnerPayoutCents and MinPlatformFeeCents are floors applied to the
+	// fee split of every new payment, in two-decimal cents that are scaled to
+	// the payment currency's minor unit. Zero disables a floor.
+	MinRunnerPayoutCents int64
+	MinPlatformFeeCents  int64
 	// CashOutRailDelay is the simulated DuitNow rail settlement time.
 	// Defaults to 30s (dev). Set CASH_OUT_RAIL_DELAY=1800s for production.
 	CashOutRailDelay time.Duration
+	// EscrowAutoReleaseAfter is the default hold window after which held escrow
+	// is released to the runner without a delivery confirmation. Zero disables it.
+	EscrowAutoReleaseAfter time.Duration
+	// EscrowAutoReleaseInterval is how often the auto-release worker runs.
+	EscrowAutoReleaseInterval time.Duration
+	// RefundWindow is how long after release a payment may still be refunded.
+	RefundWindow time.Duration
+	// RefundApprovalThresholdCents is the payment amount, in two-decimal cents
+	// scaled to each currency's minor unit, from which admin refunds need a
+	// second admin's approval. Zero disables approvals.
+	RefundApprovalThresholdCents int64
+	// SagaDrainTimeout is how long shutdown waits for in-flight sagas to finish.
+	SagaDrainTimeout time.Duration
+	// FeeScheduleRefreshInterval is how often fee schedules are reloaded from the database.
+	FeeScheduleRefreshInterval time.Duration
+	// DiscountStackingPolicy is "best_of" (default) or "additive".
+	DiscountStackingPolicy string
+	// MaxTotalDiscountPercent caps combined promo + subscription discounts.
+	// Zero disables the cap.
+	MaxTotalDiscountPercent int64
+	// BookingAmountToleranceCents is how far, in the payment currency's minor
+	// unit, an initiated amount may differ from the booking's cached total.
+	BookingAmountToleranceCents int64
+	// MaxDiscountPercentOfTotal caps any single promo's discount as a share of
+	// the booking total. Zero disables the cap.
+	MaxDiscountPercentOfTotal int64
+	// KafkaConsumerConcurrency is the number of booking events processed in
+	// parallel. It lives here because KafkaConfig is shared via lib-common.
+	KafkaConsumerConcurrency int
+	// KafkaStartOffset is where the booking consumer starts when its group has
+	// no committed offset: kafkago.FirstOffset or kafkago.LastOffset.
+	KafkaStartOffset int64
+	// KafkaLagReportInterval is how often consumer lag on booking events is
+	// measured and logged.
+	KafkaLagReportInterval time.Duration
+	// KafkaTopicCheckRetries is how many times startup re-checks for missing
+	// Kafka topics before giving up; KafkaTopicCheckBackoff is the first wait
+	// between checks and doubles after each one.
+	KafkaTopicCheckRetries int
+	KafkaTopicCheckBackoff time.Duration
+	// KafkaAutoCreateTopics creates missing topics at startup. It defaults to
+	// on only in development.
+	KafkaAutoCreateTopics bool
+	// BookingEventTopics are the topics the booking event consumer subscribes
+	// to. Events are routed by type, whichever topic they arrive on.
+	BookingEventTopics []string
+	// BookingEventsDLQTopic receives booking events that still fail after
+	// retries, with the error and their original topic in headers.
+	BookingEventsDLQTopic string
+	// BookingEventTypes are the booking event types the consumer handles;
+	// events of other types are skipped. Empty handles every type.
+	BookingEventTypes []string
+	// RunnerEventsTopic carries RunnerAccountLinked events from the runner service.
+	RunnerEventsTopic string
+	// PaymentsOpsTopic carries PaymentDisputeRequested events from payments ops.
+	PaymentsOpsTopic string
+	// SubscriptionRenewalMode is RenewalModeTimer (default) to renew due
+	// subscriptions every SubscriptionRenewalInterval, or RenewalModeEvent to
+	// renew them when a tick arrives on SchedulerTopic.
+	SubscriptionRenewalMode     string
+	SubscriptionRenewalInterval time.Duration
+	// SubscriptionRenewalLead is how long before expiry a subscription is renewed.
+	SubscriptionRenewalLead time.Duration
+	// SubscriptionCommitment is the minimum commitment new subscriptions to
+	// SubscriptionCommitmentPlans are taken with; cancelling within it
+	// forfeits the refund. Zero disables it.
+	SubscriptionCommitment      time.Duration
+	SubscriptionCommitmentPlans []string
+	// SchedulerTopic carries ticks from an external scheduler in event mode.
+	SchedulerTopic string
+	// JWTAccessTTL and JWTRefreshTTL are the token validity windows. They sit
+	// beside JWTConfig because that struct is shared via lib-common.
+	JWTAccessTTL  time.Duration
+	JWTRefreshTTL time.Duration
+	// InternalServiceToken authenticates service-to-service calls on /internal routes.
+	// Internal routes reject every request while it is empty.
+	InternalServiceToken string
+	// PromoValidatePerMinute and PromoValidateBurst bound how often one user may
+	// call the promo validate endpoint.
+	PromoValidatePerMinute int
+	PromoValidateBurst     int
+	// ReceiptResendsPerHour bounds how often one payment's receipt may be resent.
+	ReceiptResendsPerHour int
+	// SupportedCurrencies are the ISO 4217 codes payments and fee schedules may use.
+	SupportedCurrencies []string
+	// FeatureFlags are the FEATURE_FLAGS values; rows in the feature_flags table
+	// override them at runtime and are reloaded every FeatureFlagRefreshInterval.
+	FeatureFlags               map[feature.Flag]bool
+	FeatureFlagRefreshInterval time.Duration
+	// CallbackSigningSecret is the HMAC key for payment callbacks. Callbacks
+	// are disabled while it is empty.
+	CallbackSigningSecret string
+	// CallbackMaxAttempts is how many times a callback is attempted before it
+	// is marked failed. Retries back off from CallbackRetryBackoff, doubling
+	// up to an hour.
+	CallbackMaxAttempts  int
+	CallbackRetryBackoff time.Duration
+	// CallbackTimeout bounds each callback request.
+	CallbackTimeout time.Duration
+	// CallbackWorkerInterval is how often due callbacks are attempted.
+	CallbackWorkerInterval time.Duration
+	// Pagination is the default and maximum page size of list endpoints.
+	Pagination pagination.Limits
+	// Tracing configures OpenTelemetry span export. Disabled by default.
+	Tracing tracing.Config
 }
 
 // Load reads configuration from environment variables and returns a ServiceConfig.
@@ -34,9 +167,21 @@ func Load() (*ServiceConfig, error) {
 		return nil, err
 	}
 
-	feePercent := v.GetFloat64("PLATFORM_FEE_PERCENT")
-	if feePercent <= 0 {
-		feePercent = 15.0
+	feePercent := 15.0
+	if v.IsSet("PLATFORM_FEE_PERCENT") {
+		feePercent = v.GetFloat64("PLATFORM_FEE_PERCENT")
+	}
+
+	dbConnectRetries := 10
+	if v.IsSet("DB_CONNECT_RETRIES") {
+		dbConnectRetries = v.GetInt("DB_CONNECT_RETRIES")
+	}
+	if dbConnectRetries < 0 {
+		return nil, fmt.Errorf("DB_CONNECT_RETRIES must not be negative, got %d", dbConnectRetries)
+	}
+	dbConnectBackoff := v.GetDuration("DB_CONNECT_BACKOFF")
+	if dbConnectBackoff <= 0 {
+		dbConnectBackoff = time.Second
 	}
 
 	railDelay := v.GetDuration("CASH_OUT_RAIL_DELAY")
@@ -44,18 +189,375 @@ func Load() (*ServiceConfig, error) {
 		railDelay = 30 * time.Second
 	}
 
+	feeRefresh := v.GetDuration("FEE_SCHEDULE_REFRESH_INTERVAL")
+	if feeRefresh <= 0 {
+		feeRefresh = time.Minute
+	}
+
+	autoReleaseInterval := v.GetDuration("ESCROW_AUTO_RELEASE_INTERVAL")
+	if autoReleaseInterval <= 0 {
+		autoReleaseInterval = time.Minute
+	}
+
+	consumerConcurrency := v.GetInt("KAFKA_CONSUMER_CONCURRENCY")
+	if consumerConcurrency <= 0 {
+		consumerConcurrency = 1
+	}
+	startOffset, err := parseStartOffset(v.GetString("KAFKA_START_OFFSET"))
+	if err != nil {
+		return nil, err
+	}
+	lagInterval := v.GetDuration("KAFKA_LAG_REPORT_INTERVAL")
+	if lagInterval <= 0 {
+		lagInterval = 30 * time.Second
+	}
+
+	topicCheckRetries := 10
+	if v.IsSet("KAFKA_TOPIC_CHECK_RETRIES") {
+		topicCheckRetries = v.GetInt("KAFKA_TOPIC_CHECK_RETRIES")
+	}
+	if topicCheckRetries < 0 {
+		return nil, fmt.Errorf("KAFKA_TOPIC_CHECK_RETRIES must not be negative, got %d", topicCheckRetries)
+	}
+	topicCheckBackoff := v.GetDuration("KAFKA_TOPIC_CHECK_BACKOFF")
+	if topicCheckBackoff <= 0 {
+		topicCheckBackoff = time.Second
+	}
+	autoCreateTopics := config.GetAppEnv(v) == "development"
+	if v.IsSet("KAFKA_AUTO_CREATE_TOPICS") {
+		autoCreateTopics = v.GetBool("KAFKA_AUTO_CREATE_TOPICS")
+	}
+
+	accessTTL := v.GetDuration("JWT_ACCESS_TTL")
+	if accessTTL <= 0 {
+		accessTTL = 15 * time.Minute
+	}
+	refreshTTL := v.GetDuration("JWT_REFRESH_TTL")
+	if refreshTTL <= 0 {
+		refreshTTL = 7 * 24 * time.Hour
+	}
+	if refreshTTL <= accessTTL {
+		return nil, fmt.Errorf("JWT_REFRESH_TTL (%s) must be longer than JWT_ACCESS_TTL (%s)", refreshTTL, accessTTL)
+	}
+
+	promoPerMinute := v.GetInt("PROMO_VALIDATE_RATE_PER_MINUTE")
+	if promoPerMinute <= 0 {
+		promoPerMinute = 10
+	}
+	promoBurst := v.GetInt("PROMO_VALIDATE_BURST")
+	if promoBurst <= 0 {
+		promoBurst = 5
+	}
+
+	receiptResends := v.GetInt("RECEIPT_RESENDS_PER_HOUR")
+	if receiptResends <= 0 {
+		receiptResends = 3
+	}
+
+	refundWindowDays := 30
+	if v.IsSet("REFUND_WINDOW_DAYS") {
+		refundWindowDays = v.GetInt("REFUND_WINDOW_DAYS")
+	}
+	if refundWindowDays < 0 {
+		return nil, fmt.Errorf("REFUND_WINDOW_DAYS must not be negative, got %d", refundWindowDays)
+	}
+	refundApprovalThreshold := v.GetInt64("REFUND_APPROVAL_THRESHOLD_CENTS")
+	if refundApprovalThreshold < 0 {
+		return nil, fmt.Errorf("REFUND_APPROVAL_THRESHOLD_CENTS must not be negative, got %d", refundApprovalThreshold)
+	}
+
+	currencies, err := parseCurrencies(v.GetString("SUPPORTED_CURRENCIES"))
+	if err != nil {
+		return nil, err
+	}
+
+	bookingEventTopics := parseTopics(v.GetString("BOOKING_EVENT_TOPICS"), events.TopicBookingEvents)
+
+	bookingEventsDLQTopic := v.GetString("BOOKING_EVENTS_DLQ_TOPIC")
+	if bookingEventsDLQTopic == "" {
+		bookingEventsDLQTopic = "booking.events.dlq"
+	}
+
+	bookingEventTypes := parseList(v.GetString("BOOKING_EVENT_TYPES"))
+
+	runnerEventsTopic := v.GetString("RUNNER_EVENTS_TOPIC")
+	if runnerEventsTopic == "" {
+		runnerEventsTopic = "runner.events"
+	}
+	paymentsOpsTopic := v.GetString("PAYMENTS_OPS_TOPIC")
+	if paymentsOpsTopic == "" {
+		paymentsOpsTopic = "payments.ops"
+	}
+
+	renewalMode, err := parseRenewalMode(v.GetString("SUBSCRIPTION_RENEWAL_MODE"))
+	if err != nil {
+		return nil, err
+	}
+	renewalInterval := v.GetDuration("SUBSCRIPTION_RENEWAL_INTERVAL")
+	if renewalInterval <= 0 {
+		renewalInterval = time.Hour
+	}
+	renewalLead := v.GetDuration("SUBSCRIPTION_RENEWAL_LEAD")
+	if renewalLead <= 0 {
+		renewalLead = 24 * time.Hour
+	}
+	commitment := v.GetDuration("SUBSCRIPTION_MIN_COMMITMENT")
+	if commitment < 0 {
+		return nil, fmt.Errorf("SUBSCRIPTION_MIN_COMMITMENT must not be negative, got %s", commitment)
+	}
+	commitmentPlans := parseList(v.GetString("SUBSCRIPTION_COMMITMENT_PLANS"))
+	if len(commitmentPlans) == 0 {
+		commitmentPlans = []string{"premium"}
+	}
+	schedulerTopic := v.GetString("SCHEDULER_TOPIC")
+	if schedulerTopic == "" {
+		schedulerTopic = "scheduler.ticks"
+	}
+
+	amountTolerance := v.GetInt64("BOOKING_AMOUNT_TOLERANCE_CENTS")
+	if amountTolerance < 0 {
+		return nil, fmt.Errorf("BOOKING_AMOUNT_TOLERANCE_CENTS must not be negative, got %d", amountTolerance)
+	}
+
+	maxPromoPercent := v.GetInt64("MAX_DISCOUNT_PERCENT_OF_TOTAL")
+	if maxPromoPercent < 0 || maxPromoPercent > 100 {
+		return nil, fmt.Errorf("MAX_DISCOUNT_PERCENT_OF_TOTAL must be between 0 and 100, got %d", maxPromoPercent)
+	}
+
+	featureFlags, err := feature.Parse(v.GetString("FEATURE_FLAGS"))
+	if err != nil {
+		return nil, fmt.Errorf("FEATURE_FLAGS: %w", err)
+	}
+	flagRefresh := v.GetDuration("FEATURE_FLAG_REFRESH_INTERVAL")
+	if flagRefresh <= 0 {
+		flagRefresh = 30 * time.Second
+	}
+
+	callbackMaxAttempts := v.GetInt("CALLBACK_MAX_ATTEMPTS")
+	if callbackMaxAttempts <= 0 {
+		callbackMaxAttempts = 8
+	}
+	callbackBackoff := v.GetDuration("CALLBACK_RETRY_BACKOFF")
+	if callbackBackoff <= 0 {
+		callbackBackoff = 30 * time.Second
+	}
+	callbackTimeout := v.GetDuration("CALLBACK_TIMEOUT")
+	if callbackTimeout <= 0 {
+		callbackTimeout = 10 * time.Second
+	}
+	callbackInterval := v.GetDuration("CALLBACK_WORKER_INTERVAL")
+	if callbackInterval <= 0 {
+		callbackInterval = 10 * time.Second
+	}
+
+	sagaDrain := v.GetDuration("SAGA_DRAIN_TIMEOUT")
+	if sagaDrain <= 0 {
+		sagaDrain = 30 * time.Second
+	}
+
+	stackingPolicy := v.GetString("DISCOUNT_STACKING_POLICY")
+	if stackingPolicy == "" {
+		stackingPolicy = "best_of"
+	}
+
 	return &ServiceConfig{
 		Port:               config.GetServicePort(v, "SERVICE_PORT"),
 		AppEnv:             config.GetAppEnv(v),
 		DBConfig:           config.LoadDatabaseConfig(v, "DB_NAME"),
+		DBConnectRetries:   dbConnectRetries,
+		DBConnectBackoff:   dbConnectBackoff,
 		JWTConfig:          config.LoadJWTConfig(v),
 		KafkaConfig:        config.LoadKafkaConfig(v),
 		StripeConfig:       loadStripeConfig(v),
 		PlatformFeePercent: feePercent,
 		CashOutRailDelay:   railDelay,
+
+		MinRunnerPayoutCents: v.GetInt64("MIN_RUNNER_PAYOUT_CENTS"),
+		MinPlatformFeeCents:  v.GetInt64("MIN_PLATFORM_FEE_CENTS"),
+
+		EscrowAutoReleaseAfter:     v.GetDuration("ESCROW_AUTO_RELEASE_AFTER"),
+		EscrowAutoReleaseInterval:  autoReleaseInterval,
+		FeeScheduleRefreshInterval: feeRefresh,
+		SagaDrainTimeout:           sagaDrain,
+		RefundWindow:               time.Duration(refundWindowDays) * 24 * time.Hour,
+
+		RefundApprovalThresholdCents: refundApprovalThreshold,
+
+		DiscountStackingPolicy:  stackingPolicy,
+		MaxTotalDiscountPercent: v.GetInt64("MAX_TOTAL_DISCOUNT_PERCENT"),
+
+		MaxDiscountPercentOfTotal:   maxPromoPercent,
+		BookingAmountToleranceCents: amountTolerance,
+
+		KafkaConsumerConcurrency: consumerConcurrency,
+		KafkaStartOffset:         startOffset,
+		KafkaLagReportInterval:   lagInterval,
+		KafkaTopicCheckRetries:   topicCheckRetries,
+		KafkaTopicCheckBackoff:   topicCheckBackoff,
+		KafkaAutoCreateTopics:    autoCreateTopics,
+		BookingEventTopics:       bookingEventTopics,
+		BookingEventsDLQTopic:    bookingEventsDLQTopic,
+		BookingEventTypes:        bookingEventTypes,
+		RunnerEventsTopic:        runnerEventsTopic,
+		PaymentsOpsTopic:         paymentsOpsTopic,
+
+		SubscriptionRenewalMode:     renewalMode,
+		SubscriptionRenewalInterval: renewalInterval,
+		SubscriptionRenewalLead:     renewalLead,
+		SubscriptionCommitment:      commitment,
+		SubscriptionCommitmentPlans: commitmentPlans,
+		SchedulerTopic:              schedulerTopic,
+
+		JWTAccessTTL:  accessTTL,
+		JWTRefreshTTL: refreshTTL,
+
+		InternalServiceToken: v.GetString("INTERNAL_SERVICE_TOKEN"),
+
+		PromoValidatePerMinute: promoPerMinute,
+		PromoValidateBurst:     promoBurst,
+		ReceiptResendsPerHour:  receiptResends,
+
+		SupportedCurrencies: currencies,
+
+		FeatureFlags:               featureFlags,
+		FeatureFlagRefreshInterval: flagRefresh,
+
+		CallbackSigningSecret:  v.GetString("CALLBACK_SIGNING_SECRET"),
+		CallbackMaxAttempts:    callbackMaxAttempts,
+		CallbackRetryBackoff:   callbackBackoff,
+		CallbackTimeout:        callbackTimeout,
+		CallbackWorkerInterval: callbackInterval,
+
+		Pagination: pagination.Limits{
+			Default: v.GetInt("PAGINATION_DEFAULT_LIMIT"),
+			Max:     v.GetInt("PAGINATION_MAX_LIMIT"),
+		}.Normalize(),
+
+		Tracing: tracing.Config{
+			Enabled:     v.GetBool("TRACING_ENABLED"),
+			Endpoint:    v.GetString("TRACING_OTLP_ENDPOINT"),
+			Insecure:    v.GetBool("TRACING_OTLP_INSECURE"),
+			SampleRatio: v.GetFloat64("TRACING_SAMPLE_RATIO"),
+		},
 	}, nil
 }
 
+// Validate checks that the settings the service cannot run without are set
+// and that the platform fee is a sane percentage. It reports every problem in
+// one error, naming the environment variables to fix.
+func (c *ServiceConfig) Validate() error {
+	var problems []string
+	require := func(value, name string) {
+		if strings.TrimSpace(value) == "" {
+			problems = append(problems, name+" is required")
+		}
+	}
+
+	require(c.Port, "SERVICE_PORT")
+	require(c.DBConfig.Host, "DB_HOST")
+	require(c.DBConfig.Port, "DB_PORT")
+	require(c.DBConfig.User, "DB_USER")
+	require(c.DBConfig.DBName, "DB_NAME")
+	require(c.JWTConfig.Secret, "JWT_SECRET")
+
+	if len(c.KafkaConfig.Brokers) == 0 {
+		problems = append(problems, "KAFKA_BROKERS is required")
+	}
+	for _, broker := range c.KafkaConfig.Brokers {
+		if strings.TrimSpace(broker) == "" {
+			problems = append(problems, "KAFKA_BROKERS must not contain blank entries")
+			break
+		}
+	}
+
+	if c.PlatformFeePercent <= 0 || c.PlatformFeePercent >= 100 {
+		problems = append(problems, fmt.Sprintf("PLATFORM_FEE_PERCENT must be greater than 0 and less than 100, got %g", c.PlatformFeePercent))
+	}
+
+	if len(problems) == 0 {
+		return nil
+	}
+	return fmt.Errorf("invalid configuration: %s", strings.Join(problems, "; "))
+}
+
+// parseStartOffset maps KAFKA_START_OFFSET to a kafka-go offset, defaulting to earliest.
+func parseStartOffset(raw string) (int64, error) {
+	switch strings.ToLower(strings.TrimSpace(raw)) {
+	case "", "earliest":
+		return kafkago.FirstOffset, nil
+	case "latest":
+		return kafkago.LastOffset, nil
+	default:
+		return 0, fmt.Errorf("KAFKA_START_OFFSET must be earliest or latest, got %q", raw)
+	}
+}
+
+// parseRenewalMode validates SUBSCRIPTION_RENEWAL_MODE, defaulting to timer.
+func parseRenewalMode(raw string) (string, error) {
+	switch mode := strings.ToLower(strings.TrimSpace(raw)); mode {
+	case "":
+		return RenewalModeTimer, nil
+	case RenewalModeTimer, RenewalModeEvent:
+		return mode, nil
+	default:
+		return "", fmt.Errorf("SUBSCRIPTION_RENEWAL_MODE must be timer or event, got %q", raw)
+	}
+}
+
+// parseTopics splits a comma-separated list of topics, dropping blanks and
+// duplicates, and defaults to fallback.
+func parseTopics(raw, fallback string) []string {
+	var topics []string
+	seen := make(map[string]bool)
+	for _, part := range strings.Split(raw, ",") {
+		topic := strings.TrimSpace(part)
+		if topic == "" || seen[topic] {
+			continue
+		}
+		seen[topic] = true
+		topics = append(topics, topic)
+	}
+	if len(topics) == 0 {
+		return []string{fallback}
+	}
+	return topics
+}
+
+// parseList splits a comma-separated list of case-insensitive names, such as
+// event types or plans, into lower case, dropping blanks and duplicates.
+func parseList(raw string) []string {
+	var names []string
+	seen := make(map[string]bool)
+	for _, part := range strings.Split(raw, ",") {
+		name := strings.ToLower(strings.TrimSpace(part))
+		if name == "" || seen[name] {
+			continue
+		}
+		seen[name] = true
+		names = append(names, name)
+	}
+	return names
+}
+
+// parseCurrencies splits a comma-separated list of ISO 4217 codes, defaulting to MYR.
+func parseCurrencies(raw string) ([]string, error) {
+	var codes []string
+	for _, part := range strings.Split(raw, ",") {
+		code := strings.ToUpper(strings.TrimSpace(part))
+		if code == "" {
+			continue
+		}
+		if len(code) != 3 {
+			return nil, fmt.Errorf("SUPPORTED_CURRENCIES: %q is not a 3-letter ISO 4217 code", code)
+		}
+		codes = append(codes, code)
+	}
+	if len(codes) == 0 {
+		return []string{"MYR"}, nil
+	}
+	return codes, nil
+}
+
 // loadStripeConfig extracts Stripe configuration from Viper.
 func loadStripeConfig(v *viper.Viper) StripeConfig {
 	return StripeConfig{
@@ -0,0 +1,63 @@
+package config
+
+import (
+	"testing"
+
+	"github.com/Kilat-Pet-Delivery/lib-common/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// validConfig returns a ServiceConfig with every validated field set.
+func validConfig() *ServiceConfig {
+	return &ServiceConfig{
+		Port: ":8002",
+		DBConfig: config.DatabaseConfig{
+			Host: "localhost", Port: "5432", User: "postgres", Password: "password", DBName: "payment_db",
+		},
+		JWTConfig:          config.JWTConfig{Secret: "secret"},
+		KafkaConfig:        config.KafkaConfig{Brokers: []string{"localhost:9092"}},
+		PlatformFeePercent: 15,
+	}
+}
+
+func TestValidate_AcceptsCompleteConfig(t *testing.T) {
+	assert.NoError(t, validConfig().Validate())
+}
+
+func TestValidate_RejectsMissingOrInvalidFields(t *testing.T) {
+	tests := []struct {
+		name   string
+		mutate func(*ServiceConfig)
+		want   string
+	}{
+		{"empty port", func(c *ServiceConfig) { c.Port = "" }, "SERVICE_PORT is required"},
+		{"empty DB host", func(c *ServiceConfig) { c.DBConfig.Host = "" }, "DB_HOST is required"},
+		{"empty DB port", func(c *ServiceConfig) { c.DBConfig.Port = "" }, "DB_PORT is required"},
+		{"empty DB user", func(c *ServiceConfig) { c.DBConfig.User = "" }, "DB_USER is required"},
+		{"blank DB name", func(c *ServiceConfig) { c.DBConfig.DBName = "  " }, "DB_NAME is required"},
+		{"empty JWT secret", func(c *ServiceConfig) { c.JWTConfig.Secret = "" }, "JWT_SECRET is required"},
+		{"no Kafka brokers", func(c *ServiceConfig) { c.KafkaConfig.Brokers = nil }, "KAFKA_BROKERS is required"},
+		{"blank Kafka broker", func(c *ServiceConfig) { c.KafkaConfig.Brokers = []string{"localhost:9092", ""} }, "KAFKA_BROKERS must not contain blank entries"},
+		{"zero fee", func(c *ServiceConfig) { c.PlatformFeePercent = 0 }, "PLATFORM_FEE_PERCENT must be greater than 0 and less than 100, got 0"},
+		{"negative fee", func(c *ServiceConfig) { c.PlatformFeePercent = -5 }, "got -5"},
+		{"fee of 100", func(c *ServiceConfig) { c.PlatformFeePercent = 100 }, "got 100"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := validConfig()
+			tt.mutate(cfg)
+			err := cfg.Validate()
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.want)
+		})
+	}
+}
+
+func TestValidate_ReportsEveryProblem(t *testing.T) {
+	err := (&ServiceConfig{Port: ":8002", PlatformFeePercent: 150}).Validate()
+	require.Error(t, err)
+	assert.Equal(t, "invalid configuration: DB_HOST is required; DB_PORT is required; DB_USER is required; "+
+		"DB_NAME is required; JWT_SECRET is required; KAFKA_BROKERS is required; "+
+		"PLATFORM_FEE_PERCENT must be greater than 0 and less than 100, got 150", err.Error())
+}
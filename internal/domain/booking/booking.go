@@ -0,0 +1,18 @@
+package booking
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Total is a booking's price as quoted by the booking service. It is mirrored
+// here from BookingCreated events so initiated payments can be checked
+// against it.
+type Total struct {
+	BookingID   uuid.UUID
+	OwnerID     uuid.UUID
+	AmountCents int64
+	Currency    string
+	CreatedAt   time.Time
+}
@@ -0,0 +1,16 @@
+package booking
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+)
+
+// TotalRepository defines persistence operations for cached booking totals.
+type TotalRepository interface {
+	// Save stores a booking's total. It is idempotent: a total already stored
+	// for the booking is kept.
+	Save(ctx context.Context, total Total) error
+	// FindByBookingID returns the booking's total, or nil if none is cached.
+	FindByBookingID(ctx context.Context, bookingID uuid.UUID) (*Total, error)
+}
@@ -0,0 +1,128 @@
+package callback
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Status is the delivery state of a callback.
+type Status string
+
+const (
+	// StatusPending callbacks are waiting for their first or next attempt.
+	StatusPending Status = "pending"
+	// StatusDelivered callbacks were acknowledged with a 2xx response.
+	StatusDelivered Status = "delivered"
+	// StatusFailed callbacks exhausted their attempts.
+	StatusFailed Status = "failed"
+)
+
+// maxBackoff caps the delay between attempts.
+const maxBackoff = time.Hour
+
+// Delivery is an HTTP callback to a payment's callback URL, notifying an
+// integration that cannot consume Kafka of an escrow release or refund.
+type Delivery struct {
+	ID        uuid.UUID
+	PaymentID uuid.UUID
+	URL       string
+	EventType string
+	// Payload is the JSON body, fixed when the delivery is created so every
+	// attempt sends, and signs, the same bytes.
+	Payload       []byte
+	Status        Status
+	Attempts      int
+	NextAttemptAt time.Time
+	LastError     string
+	DeliveredAt   *time.Time
+	CreatedAt     time.Time
+	UpdatedAt     time.Time
+}
+
+// Attempt records the outcome of one delivery attempt. StatusCode is zero when
+// no response was received.
+type Attempt struct {
+	ID          uuid.UUID
+	DeliveryID  uuid.UUID
+	Number      int
+	StatusCode  int
+	Error       string
+	AttemptedAt time.Time
+}
+
+// NewDelivery creates a pending delivery that is due immediately.
+func NewDelivery(paymentID uuid.UUID, url, eventType string, payload []byte, now time.Time) *Delivery {
+	return &Delivery{
+		ID:            uuid.New(),
+		PaymentID:     paymentID,
+		URL:           url,
+		EventType:     eventType,
+		Payload:       payload,
+		Status:        StatusPending,
+		NextAttemptAt: now,
+		CreatedAt:     now,
+		UpdatedAt:     now,
+	}
+}
+
+// RecordSuccess marks the delivery as delivered and returns the attempt.
+func (d *Delivery) RecordSuccess(statusCode int, at time.Time) Attempt {
+	d.Attempts++
+	d.Status = StatusDelivered
+	d.LastError = ""
+	d.DeliveredAt = &at
+	d.UpdatedAt = at
+	return d.attempt(statusCode, "", at)
+}
+
+// RecordFailure records a failed attempt and returns it. The delivery is
+// retried after an exponential backoff starting at baseBackoff, and is marked
+// failed once maxAttempts have been made.
+func (d *Delivery) RecordFailure(statusCode int, reason string, at time.Time, maxAttempts int, baseBackoff time.Duration) Attempt {
+	d.Attempts++
+	d.LastError = reason
+	d.UpdatedAt = at
+	if d.Attempts >= maxAttempts {
+		d.Status = StatusFailed
+	} else {
+		d.NextAttemptAt = at.Add(Backoff(baseBackoff, d.Attempts))
+	}
+	return d.attempt(statusCode, reason, at)
+}
+
+func (d *Delivery) attempt(statusCode int, reason string, at time.Time) Attempt {
+	return Attempt{
+		ID:          uuid.New(),
+		DeliveryID:  d.ID,
+		Number:      d.Attempts,
+		StatusCode:  statusCode,
+		Error:       reason,
+		AttemptedAt: at,
+	}
+}
+
+// Backoff returns the delay after the given number of failed attempts: base,
+// then doubling each time, capped at one hour.
+func Backoff(base time.Duration, attempts int) time.Duration {
+	delay := base
+	for i := 1; i < attempts && delay < maxBackoff; i++ {
+		delay *= 2
+	}
+	return min(delay, maxBackoff)
+}
+
+// Sign returns the hex-encoded HMAC-SHA256 of "<timestamp>.<payload>" under
+// secret. Receivers recompute it from the X-Callback-Timestamp header and the
+// raw body to authenticate a callback and reject stale replays.
+func Sign(secret []byte, timestamp int64, payload []byte) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10)))
+	mac.Write([]byte("."))
+	mac.Write(payload)
+	return hex.EncodeToString(mac.Sum(nil))
+}
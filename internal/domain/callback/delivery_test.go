@@ -0,0 +1,59 @@
+package callback
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBackoff(t *testing.T) {
+	base := 30 * time.Second
+	assert.Equal(t, 30*time.Second, Backoff(base, 1))
+	assert.Equal(t, time.Minute, Backoff(base, 2))
+	assert.Equal(t, 4*time.Minute, Backoff(base, 4))
+	assert.Equal(t, time.Hour, Backoff(base, 20), "capped at one hour")
+}
+
+func TestDelivery_RecordFailure_RetriesThenFails(t *testing.T) {
+	now := time.Date(2026, 5, 1, 12, 0, 0, 0, time.UTC)
+	d := NewDelivery(uuid.New(), "https://example.com/hook", "payment.escrow_released", []byte(`{}`), now)
+
+	a := d.RecordFailure(500, "unexpected status 500", now, 3, time.Minute)
+	assert.Equal(t, 1, a.Number)
+	assert.Equal(t, 500, a.StatusCode)
+	assert.Equal(t, StatusPending, d.Status)
+	assert.Equal(t, now.Add(time.Minute), d.NextAttemptAt)
+
+	d.RecordFailure(0, "connection refused", now, 3, time.Minute)
+	assert.Equal(t, StatusPending, d.Status)
+	assert.Equal(t, now.Add(2*time.Minute), d.NextAttemptAt)
+
+	a = d.RecordFailure(502, "unexpected status 502", now, 3, time.Minute)
+	assert.Equal(t, 3, a.Number)
+	assert.Equal(t, StatusFailed, d.Status)
+	assert.Equal(t, "unexpected status 502", d.LastError)
+}
+
+func TestDelivery_RecordSuccess(t *testing.T) {
+	now := time.Now().UTC()
+	d := NewDelivery(uuid.New(), "https://example.com/hook", "payment.escrow_refunded", []byte(`{}`), now)
+	d.RecordFailure(503, "unexpected status 503", now, 5, time.Minute)
+
+	a := d.RecordSuccess(204, now)
+	assert.Equal(t, 2, a.Number)
+	assert.Equal(t, StatusDelivered, d.Status)
+	assert.Empty(t, d.LastError)
+	assert.Equal(t, &now, d.DeliveredAt)
+}
+
+func TestSign(t *testing.T) {
+	payload := []byte(`{"id":"1"}`)
+	sig := Sign([]byte("secret"), 1700000000, payload)
+
+	assert.Len(t, sig, 64)
+	assert.Equal(t, sig, Sign([]byte("secret"), 1700000000, payload), "signing is deterministic")
+	assert.NotEqual(t, sig, Sign([]byte("other"), 1700000000, payload), "depends on the secret")
+	assert.NotEqual(t, sig, Sign([]byte("secret"), 1700000001, payload), "depends on the timestamp")
+}
@@ -0,0 +1,23 @@
+package callback
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// DeliveryRepository defines persistence operations for callback deliveries.
+type DeliveryRepository interface {
+	Save(ctx context.Context, d *Delivery) error
+	// ClaimDue returns up to limit pending deliveries due at now and pushes
+	// their next attempt back by lease, so concurrent workers do not send the
+	// same callback twice while an attempt is in flight.
+	ClaimDue(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]*Delivery, error)
+	// RecordAttempt stores the attempt and the delivery's updated state.
+	RecordAttempt(ctx context.Context, d *Delivery, attempt Attempt) error
+	// FindByPaymentID returns a payment's deliveries, oldest first.
+	FindByPaymentID(ctx context.Context, paymentID uuid.UUID) ([]*Delivery, error)
+	// FindAttempts returns a delivery's attempts in order.
+	FindAttempts(ctx context.Context, deliveryID uuid.UUID) ([]Attempt, error)
+}
@@ -0,0 +1,27 @@
+package credit
+
+import (
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ErrInsufficientCredit is returned when a deduction exceeds the available balance.
+var ErrInsufficientCredit = errors.New("insufficient credit balance")
+
+// ErrCurrencyMismatch is returned when credit is granted or deducted in a
+// currency other than the one the user's balance is held in.
+var ErrCurrencyMismatch = errors.New("credit currency does not match balance currency")
+
+// ErrInvalidAmount is returned for non-positive grant or deduction amounts.
+var ErrInvalidAmount = errors.New("credit amount must be positive")
+
+// Balance is a user's in-app credit balance. Each user holds credit in a
+// single currency, fixed by the first grant.
+type Balance struct {
+	UserID       uuid.UUID
+	Currency     string
+	BalanceCents int64
+	UpdatedAt    time.Time
+}
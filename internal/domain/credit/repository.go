@@ -0,0 +1,20 @@
+package credit
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+)
+
+// CreditRepository defines persistence operations for user credit balances.
+// Grant and Deduct are applied atomically in the database.
+type CreditRepository interface {
+	// Grant adds amountCents to the user's balance, creating it if needed.
+	// It returns ErrCurrencyMismatch if the balance is held in another currency.
+	Grant(ctx context.Context, userID uuid.UUID, currency string, amountCents int64) error
+	// Deduct removes amountCents from the user's balance. It returns
+	// ErrInsufficientCredit if the balance is too low.
+	Deduct(ctx context.Context, userID uuid.UUID, currency string, amountCents int64) error
+	// FindByUserID returns the user's balance, or nil if none has been granted.
+	FindByUserID(ctx context.Context, userID uuid.UUID) (*Balance, error)
+}
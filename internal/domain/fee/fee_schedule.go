@@ -0,0 +1,91 @@
+package fee
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// FeeSchedule is the aggregate root for a platform fee that applies to a
+// region/currency pair from a given date. An empty region is the currency-wide default.
+type FeeSchedule struct {
+	id            uuid.UUID
+	region        string
+	currency      string
+	feePercent    float64
+	effectiveFrom time.Time
+	createdAt     time.Time
+	updatedAt     time.Time
+}
+
+// NewFeeSchedule creates a new fee schedule.
+func NewFeeSchedule(region, currency string, feePercent float64, effectiveFrom time.Time) (*FeeSchedule, error) {
+	currency = strings.ToUpper(strings.TrimSpace(currency))
+	if currency == "" {
+		return nil, fmt.Errorf("currency is required")
+	}
+	if err := validateFeePercent(feePercent); err != nil {
+		return nil, err
+	}
+
+	now := time.Now().UTC()
+	return &FeeSchedule{
+		id:            uuid.New(),
+		region:        normalizeRegion(region),
+		currency:      currency,
+		feePercent:    feePercent,
+		effectiveFrom: effectiveFrom.UTC(),
+		createdAt:     now,
+		updatedAt:     now,
+	}, nil
+}
+
+// Reconstruct rebuilds a FeeSchedule from persistence.
+func Reconstruct(id uuid.UUID, region, currency string, feePercent float64, effectiveFrom, createdAt, updatedAt time.Time) *FeeSchedule {
+	return &FeeSchedule{
+		id: id, region: region, currency: currency, feePercent: feePercent,
+		effectiveFrom: effectiveFrom, createdAt: createdAt, updatedAt: updatedAt,
+	}
+}
+
+// Change updates the fee percentage and effective date.
+func (f *FeeSchedule) Change(feePercent float64, effectiveFrom time.Time) error {
+	if err := validateFeePercent(feePercent); err != nil {
+		return err
+	}
+	f.feePercent = feePercent
+	f.effectiveFrom = effectiveFrom.UTC()
+	f.updatedAt = time.Now().UTC()
+	return nil
+}
+
+// AppliesTo reports whether the schedule covers the region/currency at the given time.
+// A schedule with an empty region applies to every region.
+func (f *FeeSchedule) AppliesTo(region, currency string, at time.Time) bool {
+	if !strings.EqualFold(f.currency, currency) || at.Before(f.effectiveFrom) {
+		return false
+	}
+	return f.region == "" || f.region == normalizeRegion(region)
+}
+
+func validateFeePercent(feePercent float64) error {
+	if feePercent < 0 || feePercent > 100 {
+		return fmt.Errorf("fee percent must be between 0 and 100")
+	}
+	return nil
+}
+
+func normalizeRegion(region string) string {
+	return strings.ToUpper(strings.TrimSpace(region))
+}
+
+// Getters.
+func (f *FeeSchedule) ID() uuid.UUID            { return f.id }
+func (f *FeeSchedule) Region() string           { return f.region }
+func (f *FeeSchedule) Currency() string         { return f.currency }
+func (f *FeeSchedule) FeePercent() float64      { return f.feePercent }
+func (f *FeeSchedule) EffectiveFrom() time.Time { return f.effectiveFrom }
+func (f *FeeSchedule) CreatedAt() time.Time     { return f.createdAt }
+func (f *FeeSchedule) UpdatedAt() time.Time     { return f.updatedAt }
@@ -0,0 +1,15 @@
+package fee
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+)
+
+// FeeScheduleRepository defines persistence operations for fee schedules.
+type FeeScheduleRepository interface {
+	Save(ctx context.Context, f *FeeSchedule) error
+	Update(ctx context.Context, f *FeeSchedule) error
+	FindByID(ctx context.Context, id uuid.UUID) (*FeeSchedule, error)
+	FindAll(ctx context.Context) ([]*FeeSchedule, error)
+}
@@ -0,0 +1,79 @@
+package payment
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/Kilat-Pet-Delivery/lib-common/domain"
+	"github.com/google/uuid"
+)
+
+// ErrAdjustmentApplied is returned when the event requesting an amount
+// adjustment has already been applied.
+var ErrAdjustmentApplied = errors.New("amount adjustment already applied")
+
+// ErrInvalidAdjustment is returned when a held payment cannot be adjusted to
+// the requested amount.
+var ErrInvalidAdjustment = errors.New("invalid amount adjustment")
+
+// AmountAdjustment records a change to a held payment's amount after its
+// booking's price changed. EventID identifies the booking event that asked for
+// it, so each event is applied at most once. GrossAmountCents is the booking
+// total the new amount was derived from, before discounts.
+type AmountAdjustment struct {
+	ID                      uuid.UUID
+	PaymentID               uuid.UUID
+	EventID                 string
+	PreviousAmountCents     int64
+	AmountCents             int64
+	GrossAmountCents        int64
+	PreviousPaymentIntentID string
+	PaymentIntentID         string
+	AdjustedAt              time.Time
+}
+
+// AdjustAmount changes the amount of a held payment to amountCents, the booking
+// total grossCents less its discounts, authorized by the new PaymentIntent
+// paymentIntentID. The platform fee keeps the payment's current fee rate, so a
+// regional or overridden fee survives, rounded half up and then adjusted to
+// floors as at creation. Credit applied to the payment is kept, so the card
+// pays the rest. It returns the adjustment to persist with the payment.
+func (p *Payment) AdjustAmount(amountCents, grossCents int64, paymentIntentID, eventID string, floors PayoutFloors) (AmountAdjustment, error) {
+	if p.escrowStatus != EscrowHeld {
+		return AmountAdjustment{}, domain.NewInvalidStateError(string(p.escrowStatus), "amount_adjusted")
+	}
+	if p.stripePaymentID == "" || paymentIntentID == "" {
+		return AmountAdjustment{}, fmt.Errorf("%w: payment has no card authorization to replace", ErrInvalidAdjustment)
+	}
+	if amountCents <= p.creditAppliedCents {
+		return AmountAdjustment{}, fmt.Errorf("%w: %d must exceed the %d paid with credit", ErrInvalidAdjustment, amountCents, p.creditAppliedCents)
+	}
+
+	amount := NewMoney(amountCents, p.Currency())
+	fee, payout, err := splitWithFloors(amount, scaleFee(p.platformFee, p.amount, amount), floors)
+	if err != nil {
+		return AmountAdjustment{}, err
+	}
+
+	now := p.now()
+	adjustment := AmountAdjustment{
+		ID:                      uuid.New(),
+		PaymentID:               p.id,
+		EventID:                 eventID,
+		PreviousAmountCents:     p.amount.Amount(),
+		AmountCents:             amountCents,
+		GrossAmountCents:        grossCents,
+		PreviousPaymentIntentID: p.stripePaymentID,
+		PaymentIntentID:         paymentIntentID,
+		AdjustedAt:              now,
+	}
+	previous := p.amount
+	p.amount = amount
+	p.platformFee = fee
+	p.runnerPayout = payout
+	p.stripePaymentID = paymentIntentID
+	p.updatedAt = now
+	p.recordChange(EscrowHeld, fmt.Sprintf("amount adjusted from %s to %s after the booking changed", previous, amount), now)
+	return adjustment, nil
+}
@@ -0,0 +1,79 @@
+package payment
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAdjustAmount(t *testing.T) {
+	p, err := NewPayment(uuid.New(), uuid.New(), 10000, "MYR", 15, PayoutFloors{})
+	require.NoError(t, err)
+	require.NoError(t, p.ApplyCredit(2000))
+	_, err = p.AdjustAmount(12000, 12000, "pi_2", "evt-1", PayoutFloors{})
+	assert.Error(t, err, "pending payments cannot be adjusted")
+
+	require.NoError(t, p.HoldEscrow("pi_1", 0))
+	p.ClearStatusChanges()
+
+	adj, err := p.AdjustAmount(12000, 12000, "pi_2", "evt-1", PayoutFloors{})
+	require.NoError(t, err)
+	assert.Equal(t, int64(12000), p.AmountCents())
+	assert.Equal(t, int64(1800), p.PlatformFeeCents())
+	assert.Equal(t, int64(10200), p.RunnerPayoutCents())
+	assert.Equal(t, int64(10000), p.CardAmountCents(), "the credit applied is kept")
+	assert.Equal(t, "pi_2", p.StripePaymentID())
+	assert.Equal(t, AmountAdjustment{
+		ID: adj.ID, PaymentID: p.ID(), EventID: "evt-1",
+		PreviousAmountCents: 10000, AmountCents: 12000, GrossAmountCents: 12000,
+		PreviousPaymentIntentID: "pi_1", PaymentIntentID: "pi_2",
+		AdjustedAt: adj.AdjustedAt,
+	}, adj)
+	changes := p.StatusChanges()
+	require.Len(t, changes, 1)
+	assert.Equal(t, EscrowHeld, changes[0].From)
+	assert.Equal(t, EscrowHeld, changes[0].To)
+	assert.Equal(t, "amount adjusted from 100.00 MYR to 120.00 MYR after the booking changed", changes[0].Reason)
+
+	_, err = p.AdjustAmount(2000, 2000, "pi_3", "evt-2", PayoutFloors{})
+	assert.True(t, errors.Is(err, ErrInvalidAdjustment), "the card must still pay part of the amount")
+	_, err = p.AdjustAmount(8000, 8000, "pi_3", "evt-2", PayoutFloors{MinRunnerPayoutCents: 7000, MinPlatformFeeCents: 1500})
+	assert.True(t, errors.Is(err, ErrAmountBelowFloors))
+	assert.Equal(t, int64(12000), p.AmountCents(), "a rejected adjustment leaves the payment unchanged")
+	assert.Equal(t, "pi_2", p.StripePaymentID())
+
+	require.NoError(t, p.ReleaseToRunner(uuid.New()))
+	_, err = p.AdjustAmount(9000, 9000, "pi_3", "evt-2", PayoutFloors{})
+	assert.Error(t, err, "released payments cannot be adjusted")
+}
+
+func TestAdjustAmount_KeepsFeeRate(t *testing.T) {
+	// A 20% fee, overridden by an admin to 12.5%.
+	held := func() *Payment {
+		p, err := NewPayment(uuid.New(), uuid.New(), 10000, "MYR", 20, PayoutFloors{})
+		require.NoError(t, err)
+		require.NoError(t, p.HoldEscrow("pi_1", 0))
+		require.NoError(t, p.OverrideFee(1250))
+		return p
+	}
+
+	p := held()
+	adj, err := p.AdjustAmount(12000, 13000, "pi_2", "evt-1", PayoutFloors{})
+	require.NoError(t, err)
+	assert.Equal(t, int64(1500), p.PlatformFeeCents(), "the overridden rate is kept")
+	assert.Equal(t, int64(10500), p.RunnerPayoutCents())
+	assert.Equal(t, int64(13000), adj.GrossAmountCents)
+
+	// 12.5% of 12345 is 1543.125 and of 12340 exactly 1542.5.
+	p = held()
+	_, err = p.AdjustAmount(12345, 12345, "pi_2", "evt-1", PayoutFloors{})
+	require.NoError(t, err)
+	assert.Equal(t, int64(1543), p.PlatformFeeCents())
+	p = held()
+	_, err = p.AdjustAmount(12340, 12340, "pi_2", "evt-1", PayoutFloors{})
+	require.NoError(t, err)
+	assert.Equal(t, int64(1543), p.PlatformFeeCents(), "an exact half rounds up")
+}
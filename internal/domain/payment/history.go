@@ -0,0 +1,69 @@
+package payment
+
+import (
+	"context"
+	"strings"
+	"time"
+)
+
+// StatusChange records one escrow status transition.
+type StatusChange struct {
+	From       EscrowStatus // empty when the payment was created
+	To         EscrowStatus
+	Reason     string
+	Actor      string
+	OccurredAt time.Time
+}
+
+// ActorSystem is recorded when no caller identity is attached to the context.
+const ActorSystem = "system"
+
+type actorKey struct{}
+
+// WithActor attaches the identity responsible for changes made with ctx,
+// e.g. "owner:<id>", "admin:<id>" or "system:booking-events".
+func WithActor(ctx context.Context, actor string) context.Context {
+	return context.WithValue(ctx, actorKey{}, actor)
+}
+
+// ActorFromContext returns the actor set by WithActor, or ActorSystem.
+func ActorFromContext(ctx context.Context) string {
+	if actor, ok := ctx.Value(actorKey{}).(string); ok && actor != "" {
+		return actor
+	}
+	return ActorSystem
+}
+
+// Initiator is the actor of a change split into its type, e.g. "owner",
+// "admin" or "system", and ID: the caller's user ID, or the component for
+// system actors such as "booking-events". ID is empty for a bare ActorSystem.
+type Initiator struct {
+	Type string
+	ID   string
+}
+
+// ParseInitiator splits an actor set by WithActor into an Initiator.
+func ParseInitiator(actor string) Initiator {
+	actorType, id, _ := strings.Cut(actor, ":")
+	return Initiator{Type: actorType, ID: id}
+}
+
+// InitiatedBy returns who or what made the change.
+func (c StatusChange) InitiatedBy() Initiator { return ParseInitiator(c.Actor) }
+
+// recordChange queues a status transition to be persisted with the aggregate.
+func (p *Payment) recordChange(from EscrowStatus, reason string, at time.Time) {
+	p.statusChanges = append(p.statusChanges, StatusChange{
+		From:       from,
+		To:         p.escrowStatus,
+		Reason:     reason,
+		OccurredAt: at,
+	})
+}
+
+// StatusChanges returns transitions not yet persisted. Actor is left empty;
+// the repository fills it in from the context.
+func (p *Payment) StatusChanges() []StatusChange { return p.statusChanges }
+
+// ClearStatusChanges marks queued transitions as persisted.
+func (p *Payment) ClearStatusChanges() { p.statusChanges = nil }
@@ -0,0 +1,182 @@
+package payment
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// ErrInvalidAmount is returned when a decimal amount cannot be converted to
+// a whole number of minor units.
+var ErrInvalidAmount = errors.New("invalid amount")
+
+// minorUnitExceptions lists ISO 4217 currencies whose minor unit is not the
+// usual two decimal places.
+var minorUnitExceptions = map[string]int{
+	// Zero-decimal currencies.
+	"BIF": 0, "CLP": 0, "DJF": 0, "GNF": 0, "ISK": 0, "JPY": 0, "KMF": 0,
+	"KRW": 0, "PYG": 0, "RWF": 0, "UGX": 0, "VND": 0, "VUV": 0, "XAF": 0,
+	"XOF": 0, "XPF": 0,
+	// Three-decimal currencies.
+	"BHD": 3, "IQD": 3, "JOD": 3, "KWD": 3, "LYD": 3, "OMR": 3, "TND": 3,
+}
+
+// MinorUnits returns the number of decimal places of currency's minor unit,
+// e.g. 2 for MYR, 0 for JPY and 3 for KWD. Unknown currencies default to 2.
+func MinorUnits(currency string) int {
+	if units, ok := minorUnitExceptions[strings.ToUpper(currency)]; ok {
+		return units
+	}
+	return 2
+}
+
+// Money is an amount in a currency's minor unit, e.g. sen for MYR, yen for
+// JPY and fils for KWD.
+type Money struct {
+	amount   int64
+	currency string
+}
+
+// NewMoney creates Money of amount minor units of currency.
+func NewMoney(amount int64, currency string) Money {
+	return Money{amount: amount, currency: currency}
+}
+
+// FromCents converts a two-decimal amount, such as a configured floor, into
+// currency's minor unit. Scaling down to fewer decimals rounds up so a floor
+// is never weakened by the conversion.
+func FromCents(cents int64, currency string) Money {
+	units := MinorUnits(currency)
+	amount := cents
+	for ; units > 2; units-- {
+		amount *= 10
+	}
+	for ; units < 2; units++ {
+		if amount > 0 {
+			amount = (amount + 9) / 10
+		} else {
+			amount /= 10
+		}
+	}
+	return Money{amount: amount, currency: currency}
+}
+
+// ParseDecimal converts a decimal amount in major units, e.g. "19.90" MYR,
+// "1990" JPY or "1.250" KWD, to Money in currency's minor unit. The amount
+// must be unsigned and have at most as many decimals as the minor unit, so it
+// is never rounded.
+func ParseDecimal(amount, currency string) (Money, error) {
+	units := MinorUnits(currency)
+	whole, frac, hasPoint := strings.Cut(amount, ".")
+	if !isDigits(whole) || (hasPoint && !isDigits(frac)) {
+		return Money{}, fmt.Errorf("%w %q: must be a decimal number such as %s", ErrInvalidAmount, amount, NewMoney(1990, currency).Decimal())
+	}
+	if len(frac) > units {
+		return Money{}, fmt.Errorf("%w %q: %s allows %d decimal places", ErrInvalidAmount, amount, currency, units)
+	}
+	minor, err := strconv.ParseInt(whole+frac+strings.Repeat("0", units-len(frac)), 10, 64)
+	if err != nil {
+		return Money{}, fmt.Errorf("%w %q: out of range", ErrInvalidAmount, amount)
+	}
+	return Money{amount: minor, currency: currency}, nil
+}
+
+// isDigits reports whether s is a non-empty run of ASCII digits.
+func isDigits(s string) bool {
+	if s == "" {
+		return false
+	}
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+func (m Money) Amount() int64    { return m.amount }
+func (m Money) Currency() string { return m.currency }
+func (m Money) MinorUnits() int  { return MinorUnits(m.currency) }
+
+// Rounding says which way PercentOf rounds an exact half of a minor unit.
+//
+// The rounding policy for every percentage of an amount is: round to the
+// nearest whole minor unit, and settle an exact half in the platform's favour
+// so the customer is never undercharged. Platform fees therefore round half up
+// and discounts, including percentage caps on them, round half down.
+type Rounding int
+
+const (
+	// RoundHalfUp rounds an exact half up. Used for platform fees.
+	RoundHalfUp Rounding = iota
+	// RoundHalfDown rounds an exact half down. Used for discounts.
+	RoundHalfDown
+)
+
+// percentScale is the precision PercentOf keeps of a percentage: 1e-5 percent.
+const percentScale = 100000
+
+// PercentOf returns pct percent of amount, a non-negative number of minor
+// units, rounded to the nearest whole unit with an exact half rounded as r
+// says. The division is done in integers so halves are detected exactly.
+func PercentOf(amount int64, pct float64, r Rounding) int64 {
+	num := amount * int64(math.Round(pct*percentScale))
+	const den = 100 * percentScale
+	q, rem := num/den, num%den
+	if 2*rem > den || (2*rem == den && r == RoundHalfUp) {
+		q++
+	}
+	return q
+}
+
+// Percent returns pct percent of m as a platform fee, rounded by PercentOf
+// with exact halves rounded up.
+func (m Money) Percent(pct float64) Money {
+	return Money{amount: PercentOf(m.amount, pct, RoundHalfUp), currency: m.currency}
+}
+
+// scaleFee returns fee, charged on the amount from, scaled to the amount to at
+// the same rate and rounded like any platform fee: to the nearest minor unit
+// with an exact half rounded up. A zero from has no rate and scales to zero.
+func scaleFee(fee, from, to Money) Money {
+	if from.amount == 0 {
+		return Money{currency: to.currency}
+	}
+	num := fee.amount * to.amount
+	q, rem := num/from.amount, num%from.amount
+	if 2*rem >= from.amount {
+		q++
+	}
+	return Money{amount: q, currency: to.currency}
+}
+
+// Sub returns m minus o. Both must be in the same currency.
+func (m Money) Sub(o Money) Money {
+	return Money{amount: m.amount - o.amount, currency: m.currency}
+}
+
+// Decimal formats the amount in major units with the currency's number of
+// decimals, e.g. "12.50" for 1250 MYR, "1250" for 1250 JPY and "1.250" for
+// 1250 KWD.
+func (m Money) Decimal() string {
+	units := m.MinorUnits()
+	if units == 0 {
+		return fmt.Sprintf("%d", m.amount)
+	}
+	sign, amount := "", m.amount
+	if amount < 0 {
+		sign, amount = "-", -amount
+	}
+	scale := int64(1)
+	for i := 0; i < units; i++ {
+		scale *= 10
+	}
+	return fmt.Sprintf("%s%d.%0*d", sign, amount/scale, units, amount%scale)
+}
+
+// String formats m as the decimal amount followed by the currency code.
+func (m Money) String() string {
+	return m.Decimal() + " " + m.currency
+}
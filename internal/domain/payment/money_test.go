@@ -0,0 +1,139 @@
+package payment
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMinorUnits(t *testing.T) {
+	assert.Equal(t, 2, MinorUnits("MYR"))
+	assert.Equal(t, 0, MinorUnits("JPY"))
+	assert.Equal(t, 0, MinorUnits("krw"), "lookup ignores case")
+	assert.Equal(t, 3, MinorUnits("KWD"))
+	assert.Equal(t, 2, MinorUnits("XYZ"), "unknown currencies default to two decimals")
+}
+
+func TestMoney_Decimal(t *testing.T) {
+	tests := []struct {
+		money Money
+		want  string
+	}{
+		{NewMoney(1250, "MYR"), "12.50"},
+		{NewMoney(5, "MYR"), "0.05"},
+		{NewMoney(-1250, "MYR"), "-12.50"},
+		{NewMoney(1250, "JPY"), "1250"},
+		{NewMoney(1250, "KWD"), "1.250"},
+		{NewMoney(7, "KWD"), "0.007"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.money.String(), func(t *testing.T) {
+			assert.Equal(t, tt.want, tt.money.Decimal())
+			assert.Equal(t, tt.want+" "+tt.money.Currency(), tt.money.String())
+		})
+	}
+}
+
+func TestFromCents(t *testing.T) {
+	tests := []struct {
+		name     string
+		cents    int64
+		currency string
+		want     int64
+	}{
+		{"two decimals unchanged", 150, "MYR", 150},
+		{"zero decimals whole", 500, "JPY", 5},
+		{"zero decimals rounds up", 501, "JPY", 6},
+		{"zero decimals below one unit", 1, "JPY", 1},
+		{"three decimals scales up", 150, "KWD", 1500},
+		{"zero stays zero", 0, "JPY", 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := FromCents(tt.cents, tt.currency)
+			assert.Equal(t, tt.want, m.Amount())
+			assert.Equal(t, tt.currency, m.Currency())
+		})
+	}
+}
+
+func TestMoney_Percent(t *testing.T) {
+	assert.Equal(t, int64(1500), NewMoney(10000, "MYR").Percent(15).Amount())
+	assert.Equal(t, int64(150), NewMoney(999, "JPY").Percent(15).Amount(), "149.85 rounds to the nearest yen")
+	assert.Equal(t, int64(1875), NewMoney(12499, "KWD").Percent(15).Amount(), "1874.85 rounds to the nearest fils")
+	assert.Equal(t, int64(152), NewMoney(1010, "MYR").Percent(15).Amount(), "a fee of 151.5 rounds the half up")
+}
+
+func TestPercentOf_RoundsHalvesAsAsked(t *testing.T) {
+	tests := []struct {
+		name   string
+		amount int64
+		pct    float64
+		r      Rounding
+		want   int64
+	}{
+		{"fee just below the half", 1003, 15, RoundHalfUp, 150},         // 150.45
+		{"fee exactly at the half", 1010, 15, RoundHalfUp, 152},         // 151.5
+		{"fee just above the half", 1011, 15, RoundHalfUp, 152},         // 151.65
+		{"fractional fee percent at the half", 4, 12.5, RoundHalfUp, 1}, // 0.5
+		{"discount just below the half", 149, 1, RoundHalfDown, 1},      // 1.49
+		{"discount exactly at the half", 1999, 50, RoundHalfDown, 999},  // 999.5
+		{"discount just above the half", 151, 1, RoundHalfDown, 2},      // 1.51
+		{"exact result is not rounded", 2000, 15, RoundHalfDown, 300},
+		{"zero percent", 1999, 0, RoundHalfUp, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, PercentOf(tt.amount, tt.pct, tt.r))
+		})
+	}
+}
+
+func TestScaleFee_RoundsHalfUp(t *testing.T) {
+	from := NewMoney(10000, "MYR")
+	fee := NewMoney(1500, "MYR")
+	assert.Equal(t, NewMoney(1201, "MYR"), scaleFee(fee, from, NewMoney(8009, "MYR")))
+	assert.Equal(t, NewMoney(1202, "MYR"), scaleFee(fee, from, NewMoney(8010, "MYR")), "an exact half rounds up")
+	assert.Equal(t, NewMoney(1800, "MYR"), scaleFee(fee, from, NewMoney(12000, "MYR")))
+	assert.Equal(t, NewMoney(0, "MYR"), scaleFee(NewMoney(0, "MYR"), NewMoney(0, "MYR"), NewMoney(5000, "MYR")))
+}
+
+func TestParseDecimal(t *testing.T) {
+	tests := []struct {
+		amount   string
+		currency string
+		want     int64
+		wantErr  bool
+	}{
+		{"19.90", "MYR", 1990, false},
+		{"19.9", "MYR", 1990, false},
+		{"19", "MYR", 1900, false},
+		{"0.05", "MYR", 5, false},
+		{"1990", "JPY", 1990, false},
+		{"1.250", "KWD", 1250, false},
+		{"1.25", "KWD", 1250, false},
+		{"19.905", "MYR", 0, true},
+		{"1990.5", "JPY", 0, true},
+		{"1990.", "JPY", 0, true},
+		{".50", "MYR", 0, true},
+		{"-1.00", "MYR", 0, true},
+		{"1e3", "MYR", 0, true},
+		{"1,000.00", "MYR", 0, true},
+		{"", "MYR", 0, true},
+		{"99999999999999999.99", "MYR", 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.amount+" "+tt.currency, func(t *testing.T) {
+			m, err := ParseDecimal(tt.amount, tt.currency)
+			if tt.wantErr {
+				assert.ErrorIs(t, err, ErrInvalidAmount)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, NewMoney(tt.want, tt.currency), m)
+		})
+	}
+}
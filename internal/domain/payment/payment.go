@@ -1,9 +1,14 @@
 package payment
 
 import (
+	"errors"
+	"fmt"
+	"net/mail"
+	"strings"
 	"time"
 
 	"github.com/Kilat-Pet-Delivery/lib-common/domain"
+	"github.com/Kilat-Pet-Delivery/service-payment/internal/clock"
 	"github.com/google/uuid"
 )
 
@@ -16,8 +21,66 @@ const (
 	EscrowReleased EscrowStatus = "released"
 	EscrowRefunded EscrowStatus = "refunded"
 	EscrowFailed   EscrowStatus = "failed"
+	// EscrowDisputed blocks release and refund while a dispute is open.
+	EscrowDisputed EscrowStatus = "disputed"
+	// EscrowChargedBack means released funds were clawed back: the owner was
+	// refunded and the runner's payout reversed.
+	EscrowChargedBack EscrowStatus = "charged_back"
 )
 
+// DiscountLine records a single discount that was applied to a payment's gross amount.
+type DiscountLine struct {
+	Source      string
+	Code        string
+	AmountCents int64
+}
+
+// ErrAmountBelowFloors is returned when an amount is too small to satisfy both
+// the minimum runner payout and the minimum platform fee.
+var ErrAmountBelowFloors = errors.New("amount is too small for the minimum runner payout and platform fee")
+
+// ErrInvalidFeeSplit is returned when the fee split would leave the runner with
+// nothing, or the platform fee would not be less than the amount.
+var ErrInvalidFeeSplit = errors.New("invalid fee split")
+
+// ErrInvalidCaptureAmount is returned when a partial capture is not positive
+// or exceeds the card authorization.
+var ErrInvalidCaptureAmount = errors.New("invalid capture amount")
+
+// ErrInvalidCustomerEmail is returned when a payer email is not a bare email address.
+var ErrInvalidCustomerEmail = errors.New("invalid customer email")
+
+// ErrRefundWindowExpired is returned when a released payment is older than the
+// refund window.
+var ErrRefundWindowExpired = errors.New("refund window has expired")
+
+// ErrFeeOverridden is returned when recomputing the fee of a payment whose
+// fee an admin set by hand.
+var ErrFeeOverridden = errors.New("platform fee was overridden")
+
+// RefundMethod is where a refund is paid out.
+type RefundMethod string
+
+const (
+	// RefundToCard returns the card-charged part to the original card.
+	RefundToCard RefundMethod = "card"
+	// RefundToCredit returns the whole amount as in-app credit.
+	RefundToCredit RefundMethod = "credit"
+)
+
+// PayoutFloors are the minimums each side of the fee split must receive,
+// in two-decimal cents regardless of the payment currency.
+// A zero value disables the corresponding floor.
+type PayoutFloors struct {
+	MinRunnerPayoutCents int64
+	MinPlatformFeeCents  int64
+}
+
+// in converts the floors to currency's minor unit.
+func (f PayoutFloors) in(currency string) (runnerPayout, platformFee Money) {
+	return FromCents(f.MinRunnerPayoutCents, currency), FromCents(f.MinPlatformFeeCents, currency)
+}
+
 // Payment is the aggregate root for the escrow payment domain.
 type Payment struct {
 	id                uuid.UUID
@@ -25,41 +88,130 @@ type Payment struct {
 	ownerID           uuid.UUID
 	runnerID          *uuid.UUID
 	escrowStatus      EscrowStatus
-	amountCents       int64
-	platformFeeCents  int64
-	runnerPayoutCents int64
-	currency          string
+	amount            Money
+	platformFee       Money
+	runnerPayout      Money
 	paymentMethod     string
 	stripePaymentID   string
 	escrowHeldAt      *time.Time
 	escrowReleasedAt  *time.Time
 	refundedAt        *time.Time
+	releaseEligibleAt *time.Time
 	refundReason      string
 	version           int64
 	createdAt         time.Time
 	updatedAt         time.Time
+
+	// creditAppliedCents is the part of amount paid from in-app credit
+	// rather than the card, in the same minor unit.
+	creditAppliedCents int64
+
+	// callbackURL, if set, receives an HTTP callback on release and refund.
+	callbackURL string
+
+	// runnerTransferID is the Stripe Connect transfer that paid out the
+	// runner on release, if any. A clawback reverses it.
+	runnerTransferID string
+
+	// reviewReason is set when a failed saga left money in a state an
+	// operator must reconcile by hand, e.g. a capture that could not be
+	// refunded because the runner's transfer was not reversed.
+	reviewReason string
+
+	// customerEmail is the payer's address the card was authorized with. It
+	// is for support and is only exposed to admins.
+	customerEmail string
+
+	// awaitingAuthentication is set while a pending payment waits for the
+	// customer to complete 3-D Secure on its PaymentIntent.
+	awaitingAuthentication bool
+
+	// isTest marks a payment made against Stripe test keys or flagged as a
+	// test outside production. Stats and admin lists leave it out by default.
+	isTest bool
+
+	// feeRegion is the region whose fee schedule priced the payment, or ""
+	// for the currency-wide fee. A fee recompute looks up the same schedule.
+	feeRegion string
+
+	// feeOverridden is set once an admin overrides the platform fee, so a
+	// fee recompute does not replace the fee they chose.
+	feeOverridden bool
+
+	// statusChanges are transitions not yet written to the history table.
+	statusChanges []StatusChange
+
+	// clock stamps transitions; clock.Default is used while it is nil.
+	clock clock.Clock
 }
 
 // NewPayment creates a new Payment aggregate with calculated platform fee and runner payout.
-// feePercent is the platform fee percentage (e.g. 15.0 for 15%).
-func NewPayment(bookingID, ownerID uuid.UUID, amountCents int64, currency string, feePercent float64) *Payment {
-	now := time.Now().UTC()
-	platformFeeCents := int64(float64(amountCents) * feePercent / 100.0)
-	runnerPayoutCents := amountCents - platformFeeCents
+// amountCents is in the minor unit of currency, e.g. yen for JPY or fils for KWD.
+// feePercent is the platform fee percentage (e.g. 15.0 for 15%). The percentage split is
+// adjusted so both floors, converted to the currency's minor unit, are met;
+// ErrAmountBelowFloors is returned if that is impossible.
+// ErrInvalidFeeSplit is returned if the runner payout would not be strictly positive,
+// e.g. when feePercent is misconfigured at 100 or more.
+func NewPayment(bookingID, ownerID uuid.UUID, amountCents int64, currency string, feePercent float64, floors PayoutFloors) (*Payment, error) {
+	amount := NewMoney(amountCents, currency)
+	platformFee, runnerPayout, err := splitWithFloors(amount, amount.Percent(feePercent), floors)
+	if err != nil {
+		return nil, err
+	}
 
-	return &Payment{
-		id:                uuid.New(),
-		bookingID:         bookingID,
-		ownerID:           ownerID,
-		escrowStatus:      EscrowPending,
-		amountCents:       amountCents,
-		platformFeeCents:  platformFeeCents,
-		runnerPayoutCents: runnerPayoutCents,
-		currency:          currency,
-		version:           1,
-		createdAt:         now,
-		updatedAt:         now,
+	now := clock.Default.Now()
+	p := &Payment{
+		id:           uuid.New(),
+		bookingID:    bookingID,
+		ownerID:      ownerID,
+		escrowStatus: EscrowPending,
+		amount:       amount,
+		platformFee:  platformFee,
+		runnerPayout: runnerPayout,
+		version:      1,
+		createdAt:    now,
+		updatedAt:    now,
 	}
+	p.recordChange("", "payment created", now)
+	return p, nil
+}
+
+// splitWithFloors adjusts platformFee so both floors are met and returns the
+// resulting fee and runner payout of amount. A zero amount, a booking that
+// discounts made free, splits into a zero fee and payout; floors do not apply.
+func splitWithFloors(amount, platformFee Money, floors PayoutFloors) (Money, Money, error) {
+	if amount.Amount() == 0 {
+		zero := NewMoney(0, amount.Currency())
+		return zero, zero, nil
+	}
+	minRunnerPayout, minPlatformFee := floors.in(amount.Currency())
+	if minimum := NewMoney(minRunnerPayout.Amount()+minPlatformFee.Amount(), amount.Currency()); amount.Amount() < minimum.Amount() {
+		return Money{}, Money{}, fmt.Errorf("%w: %s is below the minimum of %s", ErrAmountBelowFloors, amount, minimum)
+	}
+
+	if platformFee.Amount() < minPlatformFee.Amount() {
+		platformFee = minPlatformFee
+	}
+	if maxFee := amount.Sub(minRunnerPayout); platformFee.Amount() > maxFee.Amount() {
+		platformFee = maxFee
+	}
+	runnerPayout := amount.Sub(platformFee)
+	if err := validateFeeSplit(amount, platformFee, runnerPayout); err != nil {
+		return Money{}, Money{}, err
+	}
+	return platformFee, runnerPayout, nil
+}
+
+// validateFeeSplit checks that the runner receives something and the platform
+// fee is a non-negative part of the amount.
+func validateFeeSplit(amount, platformFee, runnerPayout Money) error {
+	if runnerPayout.Amount() <= 0 {
+		return fmt.Errorf("%w: runner payout must be positive, got %s", ErrInvalidFeeSplit, runnerPayout)
+	}
+	if platformFee.Amount() < 0 || platformFee.Amount() >= amount.Amount() {
+		return fmt.Errorf("%w: platform fee %s must be between 0 and the amount %s", ErrInvalidFeeSplit, platformFee, amount)
+	}
+	return nil
 }
 
 // --- Getters ---
@@ -69,90 +221,490 @@ func (p *Payment) BookingID() uuid.UUID        { return p.bookingID }
 func (p *Payment) OwnerID() uuid.UUID          { return p.ownerID }
 func (p *Payment) RunnerID() *uuid.UUID        { return p.runnerID }
 func (p *Payment) EscrowStatus() EscrowStatus  { return p.escrowStatus }
-func (p *Payment) AmountCents() int64          { return p.amountCents }
-func (p *Payment) PlatformFeeCents() int64     { return p.platformFeeCents }
-func (p *Payment) RunnerPayoutCents() int64    { return p.runnerPayoutCents }
-func (p *Payment) Currency() string            { return p.currency }
+func (p *Payment) Amount() Money               { return p.amount }
+func (p *Payment) PlatformFee() Money          { return p.platformFee }
+func (p *Payment) RunnerPayout() Money         { return p.runnerPayout }
+func (p *Payment) AmountCents() int64          { return p.amount.Amount() }
+func (p *Payment) PlatformFeeCents() int64     { return p.platformFee.Amount() }
+func (p *Payment) RunnerPayoutCents() int64    { return p.runnerPayout.Amount() }
+func (p *Payment) CreditAppliedCents() int64   { return p.creditAppliedCents }
+func (p *Payment) Currency() string            { return p.amount.Currency() }
 func (p *Payment) PaymentMethod() string       { return p.paymentMethod }
 func (p *Payment) StripePaymentID() string     { return p.stripePaymentID }
 func (p *Payment) EscrowHeldAt() *time.Time    { return p.escrowHeldAt }
 func (p *Payment) EscrowReleasedAt() *time.Time { return p.escrowReleasedAt }
 func (p *Payment) RefundedAt() *time.Time      { return p.refundedAt }
+func (p *Payment) ReleaseEligibleAt() *time.Time { return p.releaseEligibleAt }
 func (p *Payment) RefundReason() string        { return p.refundReason }
+func (p *Payment) CallbackURL() string         { return p.callbackURL }
 func (p *Payment) Version() int64              { return p.version }
 func (p *Payment) CreatedAt() time.Time        { return p.createdAt }
 func (p *Payment) UpdatedAt() time.Time        { return p.updatedAt }
 
+// AwaitingAuthentication reports whether the payment waits for the customer
+// to complete 3-D Secure before the escrow can be held.
+func (p *Payment) AwaitingAuthentication() bool { return p.awaitingAuthentication }
+
+// CustomerEmail returns the payer's email. It must only be shown to admins.
+func (p *Payment) CustomerEmail() string { return p.customerEmail }
+
+// IsTest reports whether the payment is a test payment.
+func (p *Payment) IsTest() bool { return p.isTest }
+
+// FeeRegion returns the region whose fee schedule priced the payment, or "".
+func (p *Payment) FeeRegion() string { return p.feeRegion }
+
+// FeeOverridden reports whether an admin overrode the platform fee.
+func (p *Payment) FeeOverridden() bool { return p.feeOverridden }
+
+// RunnerTransferID returns the Connect transfer of the runner's payout, or "".
+func (p *Payment) RunnerTransferID() string { return p.runnerTransferID }
+
+// ReviewReason returns why the payment was flagged for manual review, or "".
+func (p *Payment) ReviewReason() string { return p.reviewReason }
+
+// CardAmountCents is the part of the amount charged to the card.
+func (p *Payment) CardAmountCents() int64 { return p.amount.Amount() - p.creditAppliedCents }
+
+// IsFree reports whether discounts reduced the amount to zero. Free payments
+// have no PaymentIntent and pay the runner nothing.
+func (p *Payment) IsFree() bool { return p.amount.Amount() == 0 }
+
+// UseClock makes p take transition timestamps from c instead of clock.Default.
+func (p *Payment) UseClock(c clock.Clock) { p.clock = c }
+
+// now returns the current time from p's clock.
+func (p *Payment) now() time.Time {
+	if p.clock == nil {
+		return clock.Default.Now()
+	}
+	return p.clock.Now()
+}
+
 // --- Behavior / State Transitions ---
 
 // HoldEscrow transitions from pending to held after Stripe authorization.
-func (p *Payment) HoldEscrow(stripePaymentID string) error {
-	if p.escrowStatus != EscrowPending {
+// If autoReleaseAfter is positive, the escrow becomes eligible for automatic
+// release to the runner once that window has elapsed.
+func (p *Payment) HoldEscrow(stripePaymentID string, autoReleaseAfter time.Duration) error {
+	if err := p.ensureTransition(EscrowHeld); err != nil {
+		return err
+	}
+	if p.escrowStatus == EscrowDisputed {
 		return domain.NewInvalidStateError(string(p.escrowStatus), string(EscrowHeld))
 	}
-	now := time.Now().UTC()
+	now := p.now()
+	from := p.escrowStatus
 	p.escrowStatus = EscrowHeld
 	p.stripePaymentID = stripePaymentID
+	p.awaitingAuthentication = false
 	p.escrowHeldAt = &now
+	if autoReleaseAfter > 0 {
+		eligibleAt := now.Add(autoReleaseAfter)
+		p.releaseEligibleAt = &eligibleAt
+	}
 	p.updatedAt = now
+	p.recordChange(from, "escrow held", now)
+	return nil
+}
+
+// ApplyCredit pays creditCents of the amount from in-app credit. It is only
+// allowed before the escrow is held.
+func (p *Payment) ApplyCredit(creditCents int64) error {
+	if p.escrowStatus != EscrowPending {
+		return domain.NewInvalidStateError(string(p.escrowStatus), "credit_applied")
+	}
+	if creditCents < 0 || creditCents > p.amount.Amount() {
+		return fmt.Errorf("credit of %s must be between 0 and the amount of %s", NewMoney(creditCents, p.Currency()), p.amount)
+	}
+	p.creditAppliedCents = creditCents
+	p.updatedAt = p.now()
+	return nil
+}
+
+// SetCallbackURL sets the URL notified on release and refund. It is only
+// allowed before the escrow is held.
+func (p *Payment) SetCallbackURL(url string) error {
+	if p.escrowStatus != EscrowPending {
+		return domain.NewInvalidStateError(string(p.escrowStatus), "callback_url_set")
+	}
+	p.callbackURL = url
+	p.updatedAt = p.now()
+	return nil
+}
+
+// SetCustomerEmail records the payer's email. It is only allowed before the
+// escrow is held, and email must be a bare address such as "a@example.com".
+func (p *Payment) SetCustomerEmail(email string) error {
+	if p.escrowStatus != EscrowPending {
+		return domain.NewInvalidStateError(string(p.escrowStatus), "customer_email_set")
+	}
+	if addr, err := mail.ParseAddress(email); err != nil || addr.Address != email {
+		return fmt.Errorf("%w: %q", ErrInvalidCustomerEmail, email)
+	}
+	p.customerEmail = email
+	p.updatedAt = p.now()
+	return nil
+}
+
+// maxPaymentMethodLength is the longest payment method description stored.
+const maxPaymentMethodLength = 50
+
+// SetPaymentMethod records how the customer paid, as reported by Stripe once
+// the intent is confirmed. An empty method leaves the current one in place and
+// longer descriptions are cut to maxPaymentMethodLength.
+func (p *Payment) SetPaymentMethod(method string) {
+	method = strings.TrimSpace(method)
+	if method == "" {
+		return
+	}
+	if len(method) > maxPaymentMethodLength {
+		method = method[:maxPaymentMethodLength]
+	}
+	p.paymentMethod = method
+	p.updatedAt = p.now()
+}
+
+// AttachPaymentIntent records the Stripe PaymentIntent authorizing a pending
+// payment as soon as it exists, so an interrupted escrow creation that is
+// retried reuses it instead of authorizing the card again.
+func (p *Payment) AttachPaymentIntent(stripePaymentID string) error {
+	if p.escrowStatus != EscrowPending {
+		return domain.NewInvalidStateError(string(p.escrowStatus), "payment_intent_attached")
+	}
+	p.stripePaymentID = stripePaymentID
+	p.updatedAt = p.now()
+	return nil
+}
+
+// AwaitAuthentication records a PaymentIntent that needs the customer to
+// complete 3-D Secure. The payment stays pending until the Stripe webhook
+// reports the authorization, at which point HoldEscrow clears the flag.
+func (p *Payment) AwaitAuthentication(stripePaymentID string) error {
+	if p.escrowStatus != EscrowPending {
+		return domain.NewInvalidStateError(string(p.escrowStatus), "awaiting_authentication")
+	}
+	p.stripePaymentID = stripePaymentID
+	p.awaitingAuthentication = true
+	p.updatedAt = p.now()
+	return nil
+}
+
+// AssignRunner records the runner who will receive the payout. It is only
+// allowed before the escrow has been released.
+func (p *Payment) AssignRunner(runnerID uuid.UUID) error {
+	if p.escrowStatus != EscrowPending && p.escrowStatus != EscrowHeld {
+		return domain.NewInvalidStateError(string(p.escrowStatus), "runner_assigned")
+	}
+	p.runnerID = &runnerID
+	p.updatedAt = p.now()
+	return nil
+}
+
+// OverrideFee sets the platform fee to feeCents, e.g. for goodwill or a
+// negotiated rate, and gives the rest of the amount to the runner. It is only
+// allowed while the escrow is held. The fee may be zero but must stay below the
+// amount so the runner is still paid; ErrInvalidFeeSplit is returned otherwise.
+// The override is recorded in the status history, and the payment is marked
+// so RecomputeFee leaves it alone.
+func (p *Payment) OverrideFee(feeCents int64) error {
+	if p.escrowStatus != EscrowHeld {
+		return domain.NewInvalidStateError(string(p.escrowStatus), "fee_overridden")
+	}
+	fee := NewMoney(feeCents, p.Currency())
+	payout := p.amount.Sub(fee)
+	if err := validateFeeSplit(p.amount, fee, payout); err != nil {
+		return err
+	}
+
+	now := p.now()
+	previous := p.platformFee
+	p.platformFee = fee
+	p.runnerPayout = payout
+	p.feeOverridden = true
+	p.updatedAt = now
+	p.recordChange(EscrowHeld, fmt.Sprintf("platform fee overridden from %s to %s", previous, fee), now)
+	return nil
+}
+
+// RecomputeFee splits the amount again at feePercent, adjusted to floors as
+// at creation, e.g. after the platform fee changed. It is only allowed while
+// the payment is pending or held, before the runner is paid, and not after an
+// admin overrode the fee; ErrFeeOverridden is returned then. It reports
+// whether the split changed; a change is recorded in the status history.
+func (p *Payment) RecomputeFee(feePercent float64, floors PayoutFloors) (bool, error) {
+	if p.escrowStatus != EscrowPending && p.escrowStatus != EscrowHeld {
+		return false, domain.NewInvalidStateError(string(p.escrowStatus), "fee_recomputed")
+	}
+	if p.feeOverridden {
+		return false, ErrFeeOverridden
+	}
+	fee, payout, err := splitWithFloors(p.amount, p.amount.Percent(feePercent), floors)
+	if err != nil {
+		return false, err
+	}
+	if fee == p.platformFee {
+		return false, nil
+	}
+
+	now := p.now()
+	previous := p.platformFee
+	p.platformFee = fee
+	p.runnerPayout = payout
+	p.updatedAt = now
+	p.recordChange(p.escrowStatus, fmt.Sprintf("platform fee recomputed from %s to %s at %g%%", previous, fee, feePercent), now)
+	return true, nil
+}
+
+// CapturePartial reduces a held payment to a card capture of capturedCents,
+// e.g. when the delivery cost came in below the authorization. Credit applied
+// is unchanged. The platform fee keeps the payment's current fee rate, with
+// an exact half rounded up, and is then adjusted to floors, as at creation. ErrInvalidCaptureAmount is returned
+// if capturedCents is not positive or exceeds the card amount; capturing the
+// full card amount changes nothing.
+func (p *Payment) CapturePartial(capturedCents int64, floors PayoutFloors) error {
+	if p.escrowStatus != EscrowHeld {
+		return domain.NewInvalidStateError(string(p.escrowStatus), "partially_captured")
+	}
+	authorized := p.CardAmountCents()
+	if p.stripePaymentID == "" || capturedCents <= 0 || capturedCents > authorized {
+		return fmt.Errorf("%w: %d must be between 1 and the card authorization of %d", ErrInvalidCaptureAmount, capturedCents, authorized)
+	}
+	if capturedCents == authorized {
+		return nil
+	}
+
+	amount := NewMoney(capturedCents+p.creditAppliedCents, p.Currency())
+	fee, payout, err := splitWithFloors(amount, scaleFee(p.platformFee, p.amount, amount), floors)
+	if err != nil {
+		return err
+	}
+
+	now := p.now()
+	previous := p.amount
+	p.amount = amount
+	p.platformFee = fee
+	p.runnerPayout = payout
+	p.updatedAt = now
+	p.recordChange(EscrowHeld, fmt.Sprintf("amount reduced from %s to %s by partial capture", previous, amount), now)
 	return nil
 }
 
 // ReleaseToRunner transitions from held to released after delivery confirmation.
 func (p *Payment) ReleaseToRunner(runnerID uuid.UUID) error {
-	if p.escrowStatus != EscrowHeld {
+	if err := p.ensureTransition(EscrowReleased); err != nil {
+		return err
+	}
+	if p.escrowStatus == EscrowDisputed {
 		return domain.NewInvalidStateError(string(p.escrowStatus), string(EscrowReleased))
 	}
-	now := time.Now().UTC()
+	now := p.now()
+	from := p.escrowStatus
 	p.escrowStatus = EscrowReleased
 	p.runnerID = &runnerID
 	p.escrowReleasedAt = &now
 	p.updatedAt = now
+	p.recordChange(from, "released to runner", now)
 	return nil
 }
 
-// Refund transitions from held to refunded when the booking is cancelled.
-func (p *Payment) Refund(reason string) error {
+// RecordRunnerTransfer stores the Connect transfer paying out the runner. It is
+// set while releasing held escrow and persisted with the release.
+func (p *Payment) RecordRunnerTransfer(transferID string) error {
 	if p.escrowStatus != EscrowHeld {
+		return domain.NewInvalidStateError(string(p.escrowStatus), "runner_transfer_recorded")
+	}
+	p.runnerTransferID = transferID
+	return nil
+}
+
+// OpenDispute moves held or released escrow to disputed. The status it left
+// is kept in the status history.
+func (p *Payment) OpenDispute(reason string) error {
+	if err := p.ensureTransition(EscrowDisputed); err != nil {
+		return err
+	}
+	from := p.escrowStatus
+	now := p.now()
+	p.escrowStatus = EscrowDisputed
+	p.updatedAt = now
+	p.recordChange(from, "dispute opened: "+reason, now)
+	return nil
+}
+
+// ResolveDispute closes a dispute decided in the runner's favour: the payment
+// goes back to held, or to released if it was released before the dispute
+// opened, and release or refund proceed as before.
+func (p *Payment) ResolveDispute(note string) error {
+	if p.escrowStatus != EscrowDisputed {
+		return domain.NewInvalidStateError(string(p.escrowStatus), "dispute_resolved")
+	}
+	to := EscrowHeld
+	if p.escrowReleasedAt != nil {
+		to = EscrowReleased
+	}
+	if err := p.ensureTransition(to); err != nil {
+		return err
+	}
+	now := p.now()
+	p.escrowStatus = to
+	p.updatedAt = now
+	p.recordChange(EscrowDisputed, "dispute resolved: "+note, now)
+	return nil
+}
+
+// Refund transitions held or released escrow, or escrow disputed before
+// release, to refunded. Callers refunding a released payment should check
+// EnsureRefundable first; a payment disputed after release is clawed back.
+func (p *Payment) Refund(reason string) error {
+	if err := p.ensureTransition(EscrowRefunded); err != nil {
+		return err
+	}
+	if p.escrowStatus == EscrowDisputed && p.escrowReleasedAt != nil {
 		return domain.NewInvalidStateError(string(p.escrowStatus), string(EscrowRefunded))
 	}
-	now := time.Now().UTC()
+	now := p.now()
+	from := p.escrowStatus
 	p.escrowStatus = EscrowRefunded
 	p.refundedAt = &now
 	p.refundReason = reason
 	p.updatedAt = now
+	p.recordChange(from, reason, now)
 	return nil
 }
 
-// Fail transitions any non-terminal status to failed.
+// Clawback reverses a release after a dispute is decided in the owner's favour:
+// the owner is refunded and the runner's payout is taken back. It is allowed
+// from released escrow, or from disputed escrow whose funds were released
+// before the dispute opened.
+func (p *Payment) Clawback(reason string) error {
+	if err := p.ensureTransition(EscrowChargedBack); err != nil {
+		return err
+	}
+	if p.escrowReleasedAt == nil {
+		// Disputed before release: nothing was paid out, so Refund applies.
+		return domain.NewInvalidStateError(string(p.escrowStatus), string(EscrowChargedBack))
+	}
+	now := p.now()
+	from := p.escrowStatus
+	p.escrowStatus = EscrowChargedBack
+	p.refundedAt = &now
+	p.refundReason = reason
+	p.updatedAt = now
+	p.recordChange(from, "charged back: "+reason, now)
+	return nil
+}
+
+// EnsureRefundable checks that the payment may be refunded at now. Held escrow,
+// and escrow disputed before release, is always refundable; released escrow
+// only until window has passed since release.
+func (p *Payment) EnsureRefundable(window time.Duration, now time.Time) error {
+	switch p.escrowStatus {
+	case EscrowHeld:
+		return nil
+	case EscrowDisputed:
+		if p.escrowReleasedAt == nil {
+			return nil
+		}
+	case EscrowReleased:
+		if p.escrowReleasedAt != nil && now.Sub(*p.escrowReleasedAt) > window {
+			return fmt.Errorf("%w: released at %s, window is %s",
+				ErrRefundWindowExpired, p.escrowReleasedAt.Format(time.RFC3339), window)
+		}
+		return nil
+	}
+	return domain.NewInvalidStateError(string(p.escrowStatus), string(EscrowRefunded))
+}
+
+// EnsureOwnerCancellable checks that the owner may still cancel the escrow
+// themselves: it must be held and no runner may have been assigned yet.
+func (p *Payment) EnsureOwnerCancellable() error {
+	if p.escrowStatus != EscrowHeld {
+		return domain.NewInvalidStateError(string(p.escrowStatus), string(EscrowRefunded))
+	}
+	if p.runnerID != nil {
+		return domain.NewConflictError("payment cannot be cancelled by the owner after a runner has been assigned")
+	}
+	return nil
+}
+
+// Fail transitions pending, held or disputed escrow to failed.
 func (p *Payment) Fail(reason string) error {
-	if p.escrowStatus == EscrowReleased || p.escrowStatus == EscrowRefunded || p.escrowStatus == EscrowFailed {
-		return domain.NewInvalidStateError(string(p.escrowStatus), string(EscrowFailed))
+	if err := p.ensureTransition(EscrowFailed); err != nil {
+		return err
 	}
-	now := time.Now().UTC()
+	now := p.now()
+	from := p.escrowStatus
 	p.escrowStatus = EscrowFailed
 	p.refundReason = reason
+	p.awaitingAuthentication = false
+	p.updatedAt = now
+	p.recordChange(from, reason, now)
+	return nil
+}
+
+// ResetForRetry transitions a failed payment back to pending so escrow creation
+// can be attempted again. Stripe and hold details from the failed attempt are cleared,
+// as is applied credit, which the failed attempt returned to the owner.
+func (p *Payment) ResetForRetry() error {
+	if err := p.ensureTransition(EscrowPending); err != nil {
+		return err
+	}
+	now := p.now()
+	p.escrowStatus = EscrowPending
+	p.stripePaymentID = ""
+	p.awaitingAuthentication = false
+	p.escrowHeldAt = nil
+	p.releaseEligibleAt = nil
+	p.refundReason = ""
+	p.creditAppliedCents = 0
 	p.updatedAt = now
+	p.recordChange(EscrowFailed, "retry requested", now)
 	return nil
 }
 
 // IncrementVersion bumps the version for optimistic locking.
 func (p *Payment) IncrementVersion() {
 	p.version++
-	p.updatedAt = time.Now().UTC()
+	p.updatedAt = p.now()
+}
+
+// MarkTest marks the payment as a test payment. It is only allowed before the
+// escrow is held.
+func (p *Payment) MarkTest() error {
+	if p.escrowStatus != EscrowPending {
+		return domain.NewInvalidStateError(string(p.escrowStatus), "test_marked")
+	}
+	p.isTest = true
+	p.updatedAt = p.now()
+	return nil
+}
+
+// SetFeeRegion records the region whose fee schedule priced the payment. It
+// is only allowed before the escrow is held.
+func (p *Payment) SetFeeRegion(region string) error {
+	if p.escrowStatus != EscrowPending {
+		return domain.NewInvalidStateError(string(p.escrowStatus), "fee_region_set")
+	}
+	p.feeRegion = region
+	p.updatedAt = p.now()
+	return nil
 }
 
 // --- Reconstitution (used by repository to rebuild from persistence) ---
 
-// Reconstitute rebuilds a Payment from persisted data.
+// Reconstitute rebuilds a Payment from persisted data. The payment currency is
+// the currency of amount.
 func Reconstitute(
 	id, bookingID, ownerID uuid.UUID,
 	runnerID *uuid.UUID,
 	escrowStatus EscrowStatus,
-	amountCents, platformFeeCents, runnerPayoutCents int64,
-	currency, paymentMethod, stripePaymentID string,
-	escrowHeldAt, escrowReleasedAt, refundedAt *time.Time,
-	refundReason string,
+	amount, platformFee, runnerPayout Money,
+	creditAppliedCents int64,
+	paymentMethod, stripePaymentID string,
+	escrowHeldAt, escrowReleasedAt, refundedAt, releaseEligibleAt *time.Time,
+	refundReason, callbackURL, customerEmail, runnerTransferID, reviewReason, feeRegion string,
+	awaitingAuthentication, isTest, feeOverridden bool,
 	version int64,
 	createdAt, updatedAt time.Time,
 ) *Payment {
@@ -162,18 +714,28 @@ func Reconstitute(
 		ownerID:           ownerID,
 		runnerID:          runnerID,
 		escrowStatus:      escrowStatus,
-		amountCents:       amountCents,
-		platformFeeCents:  platformFeeCents,
-		runnerPayoutCents: runnerPayoutCents,
-		currency:          currency,
+		amount:            amount,
+		platformFee:       platformFee,
+		runnerPayout:      runnerPayout,
 		paymentMethod:     paymentMethod,
 		stripePaymentID:   stripePaymentID,
 		escrowHeldAt:      escrowHeldAt,
 		escrowReleasedAt:  escrowReleasedAt,
 		refundedAt:        refundedAt,
+		releaseEligibleAt: releaseEligibleAt,
 		refundReason:      refundReason,
 		version:           version,
 		createdAt:         createdAt,
 		updatedAt:         updatedAt,
+
+		creditAppliedCents:     creditAppliedCents,
+		callbackURL:            callbackURL,
+		customerEmail:          customerEmail,
+		runnerTransferID:       runnerTransferID,
+		reviewReason:           reviewReason,
+		awaitingAuthentication: awaitingAuthentication,
+		isTest:                 isTest,
+		feeRegion:              feeRegion,
+		feeOverridden:          feeOverridden,
 	}
 }
@@ -0,0 +1,491 @@
+package payment
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Kilat-Pet-Delivery/lib-common/domain"
+	"github.com/Kilat-Pet-Delivery/service-payment/internal/clock"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewPayment_PayoutFloors(t *testing.T) {
+	tests := []struct {
+		name       string
+		amount     int64
+		feePercent float64
+		floors     PayoutFloors
+		wantFee    int64
+		wantPayout int64
+		wantErr    bool
+	}{
+		{name: "no floors", amount: 10000, feePercent: 15, wantFee: 1500, wantPayout: 8500},
+		{name: "fee floor raises rounded-down fee", amount: 5, feePercent: 15,
+			floors: PayoutFloors{MinPlatformFeeCents: 1}, wantFee: 1, wantPayout: 4},
+		{name: "runner floor lowers fee", amount: 1000, feePercent: 15,
+			floors: PayoutFloors{MinRunnerPayoutCents: 900}, wantFee: 100, wantPayout: 900},
+		{name: "both floors met exactly", amount: 1000, feePercent: 15,
+			floors: PayoutFloors{MinRunnerPayoutCents: 900, MinPlatformFeeCents: 100}, wantFee: 100, wantPayout: 900},
+		{name: "floors conflict by one cent", amount: 999, feePercent: 15,
+			floors: PayoutFloors{MinRunnerPayoutCents: 900, MinPlatformFeeCents: 100}, wantErr: true},
+		{name: "fee floor above percentage on mid amount", amount: 2000, feePercent: 1,
+			floors: PayoutFloors{MinRunnerPayoutCents: 500, MinPlatformFeeCents: 150}, wantFee: 150, wantPayout: 1850},
+		{name: "free booking ignores floors", amount: 0, feePercent: 15,
+			floors: PayoutFloors{MinRunnerPayoutCents: 900, MinPlatformFeeCents: 100}, wantFee: 0, wantPayout: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, err := NewPayment(uuid.New(), uuid.New(), tt.amount, "MYR", tt.feePercent, tt.floors)
+			if tt.wantErr {
+				require.Error(t, err)
+				assert.True(t, errors.Is(err, ErrAmountBelowFloors))
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantFee, p.PlatformFeeCents())
+			assert.Equal(t, tt.wantPayout, p.RunnerPayoutCents())
+			assert.Equal(t, tt.amount, p.PlatformFeeCents()+p.RunnerPayoutCents())
+		})
+	}
+}
+
+func TestNewPayment_FeeSplitInvariants(t *testing.T) {
+	tests := []struct {
+		name       string
+		amount     int64
+		feePercent float64
+		wantPayout int64
+		wantErr    bool
+	}{
+		{name: "zero percent", amount: 10000, feePercent: 0, wantPayout: 10000},
+		{name: "just under 100 percent", amount: 10000, feePercent: 99.99, wantPayout: 1},
+		{name: "exactly 100 percent", amount: 10000, feePercent: 100, wantErr: true},
+		{name: "above 100 percent", amount: 10000, feePercent: 150, wantErr: true},
+		{name: "negative percent clamps to zero fee", amount: 10000, feePercent: -5, wantPayout: 10000},
+		{name: "zero amount is a free booking", amount: 0, feePercent: 15, wantPayout: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, err := NewPayment(uuid.New(), uuid.New(), tt.amount, "MYR", tt.feePercent, PayoutFloors{})
+			if tt.wantErr {
+				require.Error(t, err)
+				assert.True(t, errors.Is(err, ErrInvalidFeeSplit))
+				assert.Nil(t, p)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantPayout, p.RunnerPayoutCents())
+		})
+	}
+}
+
+func TestResetForRetry(t *testing.T) {
+	p, err := NewPayment(uuid.New(), uuid.New(), 10000, "MYR", 15, PayoutFloors{})
+	require.NoError(t, err)
+
+	assert.Error(t, p.ResetForRetry(), "pending payment cannot be retried")
+
+	require.NoError(t, p.HoldEscrow("pi_old", 0))
+	assert.Error(t, p.ResetForRetry(), "held payment cannot be retried")
+
+	p, err = NewPayment(uuid.New(), uuid.New(), 10000, "MYR", 15, PayoutFloors{})
+	require.NoError(t, err)
+	require.NoError(t, p.Fail("stripe declined"))
+	require.NoError(t, p.ResetForRetry())
+	assert.Equal(t, EscrowPending, p.EscrowStatus())
+	assert.Empty(t, p.StripePaymentID())
+	assert.Empty(t, p.RefundReason())
+}
+
+func TestEnsureOwnerCancellable(t *testing.T) {
+	p, err := NewPayment(uuid.New(), uuid.New(), 10000, "MYR", 15, PayoutFloors{})
+	require.NoError(t, err)
+	assert.Error(t, p.EnsureOwnerCancellable(), "pending payment is not cancellable")
+
+	require.NoError(t, p.HoldEscrow("pi_1", 0))
+	assert.NoError(t, p.EnsureOwnerCancellable())
+
+	require.NoError(t, p.AssignRunner(uuid.New()))
+	assert.Error(t, p.EnsureOwnerCancellable(), "runner already assigned")
+}
+
+func TestStatusChanges_RecordedPerTransition(t *testing.T) {
+	p, err := NewPayment(uuid.New(), uuid.New(), 10000, "MYR", 15, PayoutFloors{})
+	require.NoError(t, err)
+	require.NoError(t, p.HoldEscrow("pi_1", 0))
+	require.NoError(t, p.Refund("duplicate: charged twice"))
+
+	changes := p.StatusChanges()
+	require.Len(t, changes, 3)
+	assert.Equal(t, EscrowStatus(""), changes[0].From)
+	assert.Equal(t, EscrowPending, changes[0].To)
+	assert.Equal(t, EscrowPending, changes[1].From)
+	assert.Equal(t, EscrowHeld, changes[1].To)
+	assert.Equal(t, EscrowRefunded, changes[2].To)
+	assert.Equal(t, "duplicate: charged twice", changes[2].Reason)
+
+	p.ClearStatusChanges()
+	assert.Empty(t, p.StatusChanges())
+}
+
+func TestEnsureRefundable_WindowBoundary(t *testing.T) {
+	window := 30 * 24 * time.Hour
+	p, err := NewPayment(uuid.New(), uuid.New(), 10000, "MYR", 15, PayoutFloors{})
+	require.NoError(t, err)
+	require.NoError(t, p.HoldEscrow("pi_1", 0))
+
+	held := time.Now().UTC().Add(365 * 24 * time.Hour)
+	assert.NoError(t, p.EnsureRefundable(window, held), "held escrow is always refundable")
+
+	require.NoError(t, p.ReleaseToRunner(uuid.New()))
+	releasedAt := *p.EscrowReleasedAt()
+
+	assert.NoError(t, p.EnsureRefundable(window, releasedAt.Add(window)), "refund at the window edge is allowed")
+	err = p.EnsureRefundable(window, releasedAt.Add(window+time.Second))
+	assert.True(t, errors.Is(err, ErrRefundWindowExpired), "refund past the window is rejected")
+
+	require.NoError(t, p.Refund("customer_request: late complaint"))
+	assert.Equal(t, EscrowRefunded, p.EscrowStatus())
+	assert.Error(t, p.EnsureRefundable(window, releasedAt), "refunded payment cannot be refunded again")
+}
+
+func TestApplyCredit(t *testing.T) {
+	p, err := NewPayment(uuid.New(), uuid.New(), 10000, "MYR", 15, PayoutFloors{})
+	require.NoError(t, err)
+
+	assert.Error(t, p.ApplyCredit(10001), "credit cannot exceed the amount")
+	require.NoError(t, p.ApplyCredit(2500))
+	assert.Equal(t, int64(2500), p.CreditAppliedCents())
+	assert.Equal(t, int64(7500), p.CardAmountCents())
+	assert.Equal(t, int64(10000), p.AmountCents(), "the fee split still uses the full amount")
+
+	require.NoError(t, p.HoldEscrow("pi_1", 0))
+	assert.Error(t, p.ApplyCredit(100), "credit is fixed once the escrow is held")
+
+	require.NoError(t, p.Fail("stripe declined"))
+	require.NoError(t, p.ResetForRetry())
+	assert.Zero(t, p.CreditAppliedCents(), "a retry is charged to the card")
+}
+
+func TestNewPayment_MinorUnits(t *testing.T) {
+	floors := PayoutFloors{MinRunnerPayoutCents: 500, MinPlatformFeeCents: 150}
+
+	tests := []struct {
+		name       string
+		amount     int64
+		currency   string
+		wantFee    int64
+		wantPayout int64
+		wantErr    bool
+	}{
+		{name: "two decimals MYR", amount: 10000, currency: "MYR", wantFee: 1500, wantPayout: 8500},
+		{name: "two decimals floor applies", amount: 800, currency: "MYR", wantFee: 150, wantPayout: 650},
+		{name: "zero decimals JPY", amount: 10000, currency: "JPY", wantFee: 1500, wantPayout: 8500},
+		{name: "zero decimals floors scale down", amount: 7, currency: "JPY", wantFee: 2, wantPayout: 5},
+		{name: "zero decimals below scaled floors", amount: 6, currency: "JPY", wantErr: true},
+		{name: "three decimals KWD", amount: 10000, currency: "KWD", wantFee: 1500, wantPayout: 8500},
+		{name: "three decimals floors scale up", amount: 8000, currency: "KWD", wantFee: 1500, wantPayout: 6500},
+		{name: "three decimals below scaled floors", amount: 6499, currency: "KWD", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, err := NewPayment(uuid.New(), uuid.New(), tt.amount, tt.currency, 15, floors)
+			if tt.wantErr {
+				assert.True(t, errors.Is(err, ErrAmountBelowFloors))
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantFee, p.PlatformFee().Amount())
+			assert.Equal(t, tt.wantPayout, p.RunnerPayout().Amount())
+			assert.Equal(t, tt.currency, p.Currency())
+			assert.Equal(t, tt.currency, p.PlatformFee().Currency())
+			assert.Equal(t, MinorUnits(tt.currency), p.Amount().MinorUnits())
+		})
+	}
+}
+
+func TestOverrideFee(t *testing.T) {
+	p, err := NewPayment(uuid.New(), uuid.New(), 10000, "MYR", 15, PayoutFloors{})
+	require.NoError(t, err)
+	assert.Error(t, p.OverrideFee(1000), "pending payments cannot be overridden")
+
+	require.NoError(t, p.HoldEscrow("pi_1", 0))
+	p.ClearStatusChanges()
+
+	require.NoError(t, p.OverrideFee(1000))
+	assert.Equal(t, int64(1000), p.PlatformFeeCents())
+	assert.Equal(t, int64(9000), p.RunnerPayoutCents())
+	changes := p.StatusChanges()
+	require.Len(t, changes, 1)
+	assert.Equal(t, EscrowHeld, changes[0].From)
+	assert.Equal(t, EscrowHeld, changes[0].To)
+	assert.Equal(t, "platform fee overridden from 15.00 MYR to 10.00 MYR", changes[0].Reason)
+
+	require.NoError(t, p.OverrideFee(0), "waiving the fee is allowed")
+	assert.Equal(t, int64(10000), p.RunnerPayoutCents())
+
+	assert.True(t, errors.Is(p.OverrideFee(-1), ErrInvalidFeeSplit))
+	assert.True(t, errors.Is(p.OverrideFee(10000), ErrInvalidFeeSplit), "the runner must still be paid")
+	assert.Equal(t, int64(0), p.PlatformFeeCents(), "a rejected override leaves the split unchanged")
+
+	require.NoError(t, p.ReleaseToRunner(uuid.New()))
+	assert.Error(t, p.OverrideFee(500), "released payments cannot be overridden")
+}
+
+func TestRecomputeFee(t *testing.T) {
+	p, err := NewPayment(uuid.New(), uuid.New(), 10000, "MYR", 15, PayoutFloors{})
+	require.NoError(t, err)
+	p.ClearStatusChanges()
+
+	changed, err := p.RecomputeFee(15, PayoutFloors{})
+	require.NoError(t, err)
+	assert.False(t, changed)
+	assert.Empty(t, p.StatusChanges(), "an unchanged split is not recorded")
+
+	changed, err = p.RecomputeFee(10, PayoutFloors{MinPlatformFeeCents: 1200})
+	require.NoError(t, err)
+	assert.True(t, changed)
+	assert.Equal(t, int64(1200), p.PlatformFeeCents(), "floors apply as at creation")
+	assert.Equal(t, int64(8800), p.RunnerPayoutCents())
+	require.Len(t, p.StatusChanges(), 1)
+	assert.Equal(t, EscrowPending, p.StatusChanges()[0].From)
+
+	require.NoError(t, p.HoldEscrow("pi_1", 0))
+	require.NoError(t, p.ReleaseToRunner(uuid.New()))
+	_, err = p.RecomputeFee(5, PayoutFloors{})
+	assert.Error(t, err, "released payments are not recomputed")
+	assert.Equal(t, int64(1200), p.PlatformFeeCents())
+}
+
+func TestRecomputeFee_KeepsOverriddenFee(t *testing.T) {
+	p, err := NewPayment(uuid.New(), uuid.New(), 10000, "MYR", 15, PayoutFloors{})
+	require.NoError(t, err)
+	require.NoError(t, p.HoldEscrow("pi_1", 0))
+	assert.False(t, p.FeeOverridden())
+
+	require.NoError(t, p.OverrideFee(500))
+	assert.True(t, p.FeeOverridden())
+	_, err = p.RecomputeFee(10, PayoutFloors{})
+	assert.ErrorIs(t, err, ErrFeeOverridden)
+	assert.Equal(t, int64(500), p.PlatformFeeCents())
+}
+
+func TestCapturePartial_RecomputesSplit(t *testing.T) {
+	p, err := NewPayment(uuid.New(), uuid.New(), 10000, "MYR", 15, PayoutFloors{})
+	require.NoError(t, err)
+	assert.Error(t, p.CapturePartial(8000, PayoutFloors{}), "pending payments cannot be captured")
+
+	require.NoError(t, p.HoldEscrow("pi_1", 0))
+	p.ClearStatusChanges()
+
+	require.NoError(t, p.CapturePartial(8000, PayoutFloors{}))
+	assert.Equal(t, int64(8000), p.AmountCents())
+	assert.Equal(t, int64(1200), p.PlatformFeeCents(), "the fee keeps the 15% rate")
+	assert.Equal(t, int64(6800), p.RunnerPayoutCents())
+	changes := p.StatusChanges()
+	require.Len(t, changes, 1)
+	assert.Equal(t, "amount reduced from 100.00 MYR to 80.00 MYR by partial capture", changes[0].Reason)
+}
+
+func TestCapturePartial_RoundsFeeHalfUp(t *testing.T) {
+	for _, tc := range []struct {
+		captured, fee int64
+	}{
+		{captured: 8009, fee: 1201}, // 1201.35
+		{captured: 8010, fee: 1202}, // exactly 1201.5
+		{captured: 8011, fee: 1202}, // 1201.65
+	} {
+		p, err := NewPayment(uuid.New(), uuid.New(), 10000, "MYR", 15, PayoutFloors{})
+		require.NoError(t, err)
+		require.NoError(t, p.HoldEscrow("pi_1", 0))
+
+		require.NoError(t, p.CapturePartial(tc.captured, PayoutFloors{}))
+		assert.Equal(t, tc.fee, p.PlatformFeeCents(), "capturing %d", tc.captured)
+		assert.Equal(t, tc.captured-tc.fee, p.RunnerPayoutCents())
+	}
+}
+
+func TestCapturePartial_AppliesFloors(t *testing.T) {
+	floors := PayoutFloors{MinPlatformFeeCents: 500, MinRunnerPayoutCents: 1000}
+	p, err := NewPayment(uuid.New(), uuid.New(), 10000, "MYR", 15, floors)
+	require.NoError(t, err)
+	require.NoError(t, p.HoldEscrow("pi_1", 0))
+
+	require.NoError(t, p.CapturePartial(2000, floors))
+	assert.Equal(t, int64(500), p.PlatformFeeCents(), "15% of 20.00 is below the fee floor")
+	assert.Equal(t, int64(1500), p.RunnerPayoutCents())
+
+	assert.ErrorIs(t, p.CapturePartial(1400, floors), ErrAmountBelowFloors)
+	assert.Equal(t, int64(2000), p.AmountCents(), "a rejected capture leaves the payment unchanged")
+}
+
+func TestCapturePartial_WithCredit(t *testing.T) {
+	p, err := NewPayment(uuid.New(), uuid.New(), 10000, "MYR", 10, PayoutFloors{})
+	require.NoError(t, err)
+	require.NoError(t, p.ApplyCredit(2000))
+	require.NoError(t, p.HoldEscrow("pi_1", 0))
+
+	assert.ErrorIs(t, p.CapturePartial(8001, PayoutFloors{}), ErrInvalidCaptureAmount, "cannot exceed the card authorization")
+	assert.ErrorIs(t, p.CapturePartial(0, PayoutFloors{}), ErrInvalidCaptureAmount)
+
+	require.NoError(t, p.CapturePartial(8000, PayoutFloors{}))
+	assert.Equal(t, int64(10000), p.AmountCents(), "capturing the full authorization changes nothing")
+
+	require.NoError(t, p.CapturePartial(6000, PayoutFloors{}))
+	assert.Equal(t, int64(8000), p.AmountCents(), "credit is kept on top of the captured card amount")
+	assert.Equal(t, int64(6000), p.CardAmountCents())
+	assert.Equal(t, int64(800), p.PlatformFeeCents())
+	assert.Equal(t, int64(7200), p.RunnerPayoutCents())
+}
+
+func TestOpenDispute(t *testing.T) {
+	p, err := NewPayment(uuid.New(), uuid.New(), 10000, "MYR", 15, PayoutFloors{})
+	require.NoError(t, err)
+	assert.Error(t, p.OpenDispute("early"), "pending payments cannot be disputed")
+
+	require.NoError(t, p.HoldEscrow("pi_1", 0))
+	require.NoError(t, p.ReleaseToRunner(uuid.New()))
+	p.ClearStatusChanges()
+
+	require.NoError(t, p.OpenDispute("damaged item"))
+	assert.Equal(t, EscrowDisputed, p.EscrowStatus())
+	changes := p.StatusChanges()
+	require.Len(t, changes, 1)
+	assert.Equal(t, EscrowReleased, changes[0].From)
+	assert.Equal(t, "dispute opened: damaged item", changes[0].Reason)
+
+	assert.Error(t, p.OpenDispute("again"), "a disputed payment cannot be disputed again")
+	assert.Error(t, p.ReleaseToRunner(uuid.New()), "a disputed payment cannot be released")
+	assert.Error(t, p.Refund("refund"), "a disputed payment cannot be refunded")
+}
+
+func TestDispute_BeforeRelease(t *testing.T) {
+	// Decided for the owner: the payment is refunded.
+	refunded := paymentIn(t, EscrowHeld)
+	require.NoError(t, refunded.OpenDispute("item not delivered"))
+	require.NoError(t, refunded.EnsureRefundable(time.Hour, time.Now().UTC()))
+	require.NoError(t, refunded.Refund("dispute decided for owner"))
+	assert.Equal(t, EscrowRefunded, refunded.EscrowStatus())
+	assert.Equal(t, "dispute decided for owner", refunded.RefundReason())
+
+	// Decided for the runner: the payment is held again and can be released.
+	resolved := paymentIn(t, EscrowHeld)
+	require.NoError(t, resolved.OpenDispute("item not delivered"))
+	assert.ErrorIs(t, resolved.HoldEscrow("pi_2", 0), domain.ErrInvalidState, "only ResolveDispute ends a dispute")
+	resolved.ClearStatusChanges()
+	require.NoError(t, resolved.ResolveDispute("delivery photo confirmed"))
+	assert.Equal(t, EscrowHeld, resolved.EscrowStatus())
+	changes := resolved.StatusChanges()
+	require.Len(t, changes, 1)
+	assert.Equal(t, EscrowDisputed, changes[0].From)
+	assert.Equal(t, "dispute resolved: delivery photo confirmed", changes[0].Reason)
+	require.NoError(t, resolved.ReleaseToRunner(uuid.New()))
+}
+
+func TestDispute_AfterRelease(t *testing.T) {
+	// Decided for the owner: the payment is charged back, not refunded.
+	disputed := paymentIn(t, EscrowReleased)
+	require.NoError(t, disputed.OpenDispute("item damaged"))
+	assert.ErrorIs(t, disputed.EnsureRefundable(time.Hour, time.Now().UTC()), domain.ErrInvalidState)
+	assert.ErrorIs(t, disputed.Refund("refund"), domain.ErrInvalidState)
+	require.NoError(t, disputed.Clawback("dispute decided for owner"))
+	assert.Equal(t, EscrowChargedBack, disputed.EscrowStatus())
+
+	// Decided for the runner: the payment is released again.
+	resolved := paymentIn(t, EscrowReleased)
+	releasedAt := *resolved.EscrowReleasedAt()
+	require.NoError(t, resolved.OpenDispute("item damaged"))
+	require.NoError(t, resolved.ResolveDispute("no damage found"))
+	assert.Equal(t, EscrowReleased, resolved.EscrowStatus())
+	assert.Equal(t, releasedAt, *resolved.EscrowReleasedAt(), "the release time is kept")
+	assert.ErrorIs(t, resolved.ResolveDispute("again"), domain.ErrInvalidState, "only a disputed payment can be resolved")
+}
+
+func TestAttachPaymentIntent_OnlyWhilePending(t *testing.T) {
+	p, err := NewPayment(uuid.New(), uuid.New(), 10000, "MYR", 15.0, PayoutFloors{})
+	require.NoError(t, err)
+
+	p.ClearStatusChanges()
+
+	require.NoError(t, p.AttachPaymentIntent("pi_1"))
+	assert.Equal(t, "pi_1", p.StripePaymentID())
+	assert.Equal(t, EscrowPending, p.EscrowStatus())
+	assert.Empty(t, p.StatusChanges(), "attaching an intent is not a status change")
+
+	require.NoError(t, p.HoldEscrow("pi_1", 0))
+	assert.Error(t, p.AttachPaymentIntent("pi_2"))
+}
+
+func TestTransitions_UseInjectedClock(t *testing.T) {
+	heldAt := time.Date(2026, 3, 1, 9, 0, 0, 0, time.UTC)
+	fake := clock.NewFake(heldAt)
+	p, err := NewPayment(uuid.New(), uuid.New(), 10000, "MYR", 15, PayoutFloors{})
+	require.NoError(t, err)
+	p.UseClock(fake)
+
+	require.NoError(t, p.HoldEscrow("pi_1", 72*time.Hour))
+	assert.Equal(t, heldAt, *p.EscrowHeldAt())
+	assert.Equal(t, heldAt.Add(72*time.Hour), *p.ReleaseEligibleAt())
+
+	fake.Advance(2 * time.Hour)
+	require.NoError(t, p.ReleaseToRunner(uuid.New()))
+	assert.Equal(t, heldAt.Add(2*time.Hour), *p.EscrowReleasedAt())
+	assert.Equal(t, heldAt.Add(2*time.Hour), p.UpdatedAt())
+}
+
+func TestSetCustomerEmail(t *testing.T) {
+	p, err := NewPayment(uuid.New(), uuid.New(), 10000, "MYR", 15, PayoutFloors{})
+	require.NoError(t, err)
+
+	for _, bad := range []string{"", "not-an-email", "Owner <owner@example.com>", " owner@example.com"} {
+		assert.ErrorIs(t, p.SetCustomerEmail(bad), ErrInvalidCustomerEmail, bad)
+	}
+	require.NoError(t, p.SetCustomerEmail("owner@example.com"))
+	assert.Equal(t, "owner@example.com", p.CustomerEmail())
+
+	require.NoError(t, p.HoldEscrow("pi_1", 0))
+	assert.Error(t, p.SetCustomerEmail("other@example.com"), "the email is fixed once the escrow is held")
+}
+
+func TestSetPaymentMethod(t *testing.T) {
+	p, err := NewPayment(uuid.New(), uuid.New(), 10000, "MYR", 15, PayoutFloors{})
+	require.NoError(t, err)
+
+	p.SetPaymentMethod("visa ****4242")
+	assert.Equal(t, "visa ****4242", p.PaymentMethod())
+
+	p.SetPaymentMethod("  ")
+	assert.Equal(t, "visa ****4242", p.PaymentMethod(), "an empty method keeps the recorded one")
+
+	p.SetPaymentMethod(strings.Repeat("x", maxPaymentMethodLength+10))
+	assert.Len(t, p.PaymentMethod(), maxPaymentMethodLength)
+}
+
+func TestClawback(t *testing.T) {
+	released := paymentIn(t, EscrowReleased)
+	require.NoError(t, released.Clawback("dispute decided for owner"))
+	assert.Equal(t, EscrowChargedBack, released.EscrowStatus())
+	assert.Equal(t, "dispute decided for owner", released.RefundReason())
+	assert.NotNil(t, released.RefundedAt())
+	assert.Equal(t, int64(8500), released.RunnerPayoutCents(), "the split is kept so the reversal can be reported")
+
+	disputed := paymentIn(t, EscrowDisputed)
+	require.NoError(t, disputed.Clawback("chargeback"))
+	assert.Equal(t, EscrowChargedBack, disputed.EscrowStatus())
+
+	// A payment disputed while held was never paid out.
+	heldThenDisputed := paymentIn(t, EscrowHeld)
+	require.NoError(t, heldThenDisputed.OpenDispute("damaged"))
+	assert.ErrorIs(t, heldThenDisputed.Clawback("chargeback"), domain.ErrInvalidState)
+
+	for _, status := range []EscrowStatus{EscrowPending, EscrowHeld, EscrowRefunded, EscrowFailed, EscrowChargedBack} {
+		assert.ErrorIs(t, paymentIn(t, status).Clawback("chargeback"), domain.ErrInvalidState, status)
+	}
+}
@@ -2,10 +2,49 @@ package payment
 
 import (
 	"context"
+	"time"
 
 	"github.com/google/uuid"
 )
 
+// ListFilter narrows payment listings. Zero values mean no filtering on that field.
+type ListFilter struct {
+	Status EscrowStatus
+	// OwnerID, when set, keeps only that owner's payments.
+	OwnerID *uuid.UUID
+	// From is inclusive and To is exclusive, both applied to created_at.
+	From *time.Time
+	To   *time.Time
+	// IncludeTest includes test payments, which are left out by default.
+	IncludeTest bool
+}
+
+// Escrow aging bucket labels, in ascending age order.
+const (
+	AgingUnder1Day = "0-1d"
+	Aging1To3Days  = "1-3d"
+	Aging3To7Days  = "3-7d"
+	AgingOver7Days = "7d+"
+)
+
+// AgingBuckets lists the escrow aging bucket labels in ascending age order.
+var AgingBuckets = []string{AgingUnder1Day, Aging1To3Days, Aging3To7Days, AgingOver7Days}
+
+// AgingBucket totals held escrows of one currency by time since escrow_held_at.
+type AgingBucket struct {
+	Bucket     string
+	Currency   string
+	Count      int64
+	TotalCents int64
+}
+
+// RunnerPayoutTotal totals one currency of a runner's released payouts.
+type RunnerPayoutTotal struct {
+	Currency     string
+	PayoutCents  int64
+	BookingCount int64
+}
+
 // PaymentRepository defines the persistence contract for Payment aggregates.
 type PaymentRepository interface {
 	// FindByID retrieves a payment by its unique ID.
@@ -14,15 +53,77 @@ type PaymentRepository interface {
 	// FindByBookingID retrieves a payment by the associated booking ID.
 	FindByBookingID(ctx context.Context, bookingID uuid.UUID) (*Payment, error)
 
-	// ListAll retrieves all payments with pagination (admin).
-	ListAll(ctx context.Context, page, limit int) ([]*Payment, int64, error)
+	// FindByBookingIDs retrieves the payments for the given bookings in one query.
+	// Bookings without a payment are omitted.
+	FindByBookingIDs(ctx context.Context, bookingIDs []uuid.UUID) ([]*Payment, error)
+
+	// FindReleaseEligible retrieves held payments with an assigned runner whose
+	// auto-release time is at or before the given time.
+	FindReleaseEligible(ctx context.Context, before time.Time, limit int) ([]*Payment, error)
+
+	// Search returns up to limit payments, newest first, whose Stripe payment ID
+	// starts with stripeIDPrefix or, when bookingID is set, that belong to that
+	// booking (admin).
+	Search(ctx context.Context, stripeIDPrefix string, bookingID *uuid.UUID, limit int) ([]*Payment, error)
+
+	// ListAll retrieves all payments matching the filter with pagination (admin).
+	ListAll(ctx context.Context, filter ListFilter, page, limit int) ([]*Payment, int64, error)
+
+	// FindStatusHistory returns the recorded status transitions of a payment, oldest first.
+	FindStatusHistory(ctx context.Context, paymentID uuid.UUID) ([]StatusChange, error)
 
-	// GetRevenueStats returns payment statistics (admin).
-	GetRevenueStats(ctx context.Context) (totalRevenueCents int64, countByStatus map[string]int64, err error)
+	// StreamAll calls fn for every payment matching the filter, oldest first,
+	// without loading the full result set into memory. Iteration stops at the first error.
+	StreamAll(ctx context.Context, filter ListFilter, fn func(*Payment) error) error
+
+	// StreamByEventTime calls fn for every payment that was held, released or
+	// refunded within [from, to), oldest first. Iteration stops at the first error.
+	StreamByEventTime(ctx context.Context, from, to time.Time, fn func(*Payment) error) error
+
+	// GetEscrowAging aggregates currently held escrows into aging buckets
+	// relative to now, per currency (admin).
+	GetEscrowAging(ctx context.Context, now time.Time) ([]AgingBucket, error)
+
+	// SumHeldByCurrency returns the total cents currently held in escrow,
+	// keyed by currency (admin).
+	SumHeldByCurrency(ctx context.Context) (map[string]int64, error)
+
+	// SumRunnerPayouts totals the payouts of runnerID's released payments that
+	// were released within [from, to), per currency ordered by currency.
+	SumRunnerPayouts(ctx context.Context, runnerID uuid.UUID, from, to time.Time) ([]RunnerPayoutTotal, error)
+
+	// CountPaymentsByOwner returns how many payments ownerID has made, in any status.
+	CountPaymentsByOwner(ctx context.Context, ownerID uuid.UUID) (int64, error)
+
+	// GetRevenueStats returns payment statistics (admin). Test payments are
+	// left out unless includeTest is set.
+	GetRevenueStats(ctx context.Context, includeTest bool) (totalRevenueCents int64, countByStatus map[string]int64, err error)
 
 	// Save persists a new payment aggregate.
 	Save(ctx context.Context, payment *Payment) error
 
 	// Update persists changes to an existing payment aggregate with optimistic locking.
 	Update(ctx context.Context, payment *Payment) error
+
+	// AdjustAmount persists a payment changed by Payment.AdjustAmount together
+	// with the adjustment, with optimistic locking. It returns
+	// ErrAdjustmentApplied if the adjustment's event was already applied.
+	AdjustAmount(ctx context.Context, payment *Payment, adj AmountAdjustment) error
+
+	// HasAmountAdjustment reports whether the event eventID has adjusted a payment.
+	HasAmountAdjustment(ctx context.Context, eventID string) (bool, error)
+
+	// FindLatestAmountAdjustment retrieves the most recent amount adjustment
+	// of a payment, or nil if it was never adjusted.
+	FindLatestAmountAdjustment(ctx context.Context, paymentID uuid.UUID) (*AmountAdjustment, error)
+
+	// FlagForReview records why a payment needs manual review. It only sets
+	// the reason, without a version check, so it works after Update failed.
+	FlagForReview(ctx context.Context, paymentID uuid.UUID, reason string) error
+
+	// SaveDiscounts persists the discounts applied to a payment.
+	SaveDiscounts(ctx context.Context, paymentID uuid.UUID, lines []DiscountLine) error
+
+	// FindDiscounts retrieves the discounts applied to a payment.
+	FindDiscounts(ctx context.Context, paymentID uuid.UUID) ([]DiscountLine, error)
 }
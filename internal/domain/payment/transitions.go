@@ -0,0 +1,46 @@
+package payment
+
+import (
+	"slices"
+
+	"github.com/Kilat-Pet-Delivery/lib-common/domain"
+)
+
+// transitions lists the statuses each escrow status may move to. Every
+// status-changing method checks it, so a new status or transition is added
+// here rather than in the individual methods. A disputed payment goes back to
+// held or released only through ResolveDispute, and only to the status it
+// left; it is refunded only if it was disputed before release and charged
+// back only if after.
+var transitions = map[EscrowStatus][]EscrowStatus{
+	EscrowPending:     {EscrowHeld, EscrowFailed},
+	EscrowHeld:        {EscrowReleased, EscrowRefunded, EscrowDisputed, EscrowFailed},
+	EscrowReleased:    {EscrowRefunded, EscrowDisputed, EscrowChargedBack},
+	EscrowDisputed:    {EscrowHeld, EscrowReleased, EscrowRefunded, EscrowFailed, EscrowChargedBack},
+	EscrowFailed:      {EscrowPending},
+	EscrowRefunded:    {},
+	EscrowChargedBack: {},
+}
+
+// Statuses returns every escrow status.
+func Statuses() []EscrowStatus {
+	return []EscrowStatus{EscrowPending, EscrowHeld, EscrowReleased, EscrowRefunded, EscrowFailed, EscrowDisputed, EscrowChargedBack}
+}
+
+// AllowedTransitions returns the statuses a payment in from may move to.
+func AllowedTransitions(from EscrowStatus) []EscrowStatus {
+	return slices.Clone(transitions[from])
+}
+
+// CanTransition reports whether a payment in from may move to to.
+func CanTransition(from, to EscrowStatus) bool {
+	return slices.Contains(transitions[from], to)
+}
+
+// ensureTransition returns an invalid state error unless p may move to to.
+func (p *Payment) ensureTransition(to EscrowStatus) error {
+	if !CanTransition(p.escrowStatus, to) {
+		return domain.NewInvalidStateError(string(p.escrowStatus), string(to))
+	}
+	return nil
+}
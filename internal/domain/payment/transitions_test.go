@@ -0,0 +1,90 @@
+package payment
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Kilat-Pet-Delivery/lib-common/domain"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// paymentIn returns a payment in status with a held escrow's details. Statuses
+// that follow a release also carry its release time.
+func paymentIn(t *testing.T, status EscrowStatus) *Payment {
+	t.Helper()
+	now := time.Now().UTC()
+	var releasedAt *time.Time
+	switch status {
+	case EscrowReleased, EscrowDisputed, EscrowChargedBack:
+		releasedAt = &now
+	}
+	return Reconstitute(uuid.New(), uuid.New(), uuid.New(), nil, status,
+		NewMoney(10000, "MYR"), NewMoney(1500, "MYR"), NewMoney(8500, "MYR"), 0,
+		"card", "pi_1", &now, releasedAt, nil, nil, "", "", "", "", "", "", false, false, false, 1, now, now)
+}
+
+// transitionTo attempts to move p to status with the method that performs that transition.
+func transitionTo(p *Payment, status EscrowStatus) error {
+	switch status {
+	case EscrowPending:
+		return p.ResetForRetry()
+	case EscrowHeld:
+		if p.EscrowStatus() == EscrowDisputed {
+			return p.ResolveDispute("resolved")
+		}
+		return p.HoldEscrow("pi_1", 0)
+	case EscrowReleased:
+		if p.EscrowStatus() == EscrowDisputed {
+			return p.ResolveDispute("resolved")
+		}
+		return p.ReleaseToRunner(uuid.New())
+	case EscrowRefunded:
+		return p.Refund("refund")
+	case EscrowFailed:
+		return p.Fail("failed")
+	case EscrowDisputed:
+		return p.OpenDispute("dispute")
+	case EscrowChargedBack:
+		return p.Clawback("chargeback")
+	}
+	panic("no transition method for " + status)
+}
+
+func TestTransitionTable_CoversEveryStatus(t *testing.T) {
+	for _, s := range Statuses() {
+		_, ok := transitions[s]
+		assert.True(t, ok, "status %q has no entry in the transition table", s)
+		for _, to := range AllowedTransitions(s) {
+			assert.Contains(t, Statuses(), to)
+		}
+	}
+	assert.Len(t, transitions, len(Statuses()))
+}
+
+func TestTransitionTable_EnforcedByEveryMethod(t *testing.T) {
+	for _, from := range Statuses() {
+		for _, to := range Statuses() {
+			t.Run(string(from)+"->"+string(to), func(t *testing.T) {
+				p := paymentIn(t, from)
+				if from == EscrowDisputed && (to == EscrowHeld || to == EscrowRefunded) {
+					// Only a payment disputed before release goes back to held or is refunded.
+					p.escrowReleasedAt = nil
+				}
+				err := transitionTo(p, to)
+				if CanTransition(from, to) {
+					require.NoError(t, err)
+					assert.Equal(t, to, p.EscrowStatus())
+					changes := p.StatusChanges()
+					require.Len(t, changes, 1)
+					assert.Equal(t, from, changes[0].From)
+					return
+				}
+				assert.ErrorIs(t, err, domain.ErrInvalidState)
+				assert.Equal(t, from, p.EscrowStatus(), "a rejected transition leaves the status unchanged")
+				assert.Empty(t, p.StatusChanges())
+			})
+		}
+	}
+}
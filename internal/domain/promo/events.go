@@ -0,0 +1,25 @@
+package promo
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	// TopicPromoEvents is the Kafka topic for promo code analytics events.
+	TopicPromoEvents = "promo.events"
+
+	// PromoRedeemed is the CloudEvent type published when a promo is applied to a payment.
+	PromoRedeemed = "promo.redeemed"
+)
+
+// PromoRedeemedEvent is published when a promo code is redeemed against a booking.
+type PromoRedeemedEvent struct {
+	PromoID       uuid.UUID `json:"promo_id"`
+	Code          string    `json:"code"`
+	UserID        uuid.UUID `json:"user_id"`
+	BookingID     uuid.UUID `json:"booking_id"`
+	DiscountCents int64     `json:"discount_cents"`
+	OccurredAt    time.Time `json:"occurred_at"`
+}
@@ -1,10 +1,13 @@
 package promo
 
 import (
+	"errors"
 	"fmt"
 	"strings"
 	"time"
 
+	"github.com/Kilat-Pet-Delivery/service-payment/internal/clock"
+	"github.com/Kilat-Pet-Delivery/service-payment/internal/domain/payment"
 	"github.com/google/uuid"
 )
 
@@ -16,6 +19,17 @@ const (
 	DiscountTypeFixed      DiscountType = "fixed"
 )
 
+// ErrInvalidDiscountType is returned when a promo is created with an unknown discount type.
+var ErrInvalidDiscountType = errors.New("invalid discount type")
+
+// ErrFirstBookingOnly is returned when a first-booking-only promo is used by
+// someone who has paid before.
+var ErrFirstBookingOnly = errors.New("this promo code is only valid on your first booking")
+
+// ErrPlanNotEligible is returned when a plan-restricted promo is used by
+// someone without an active subscription on one of its plans.
+var ErrPlanNotEligible = errors.New("this promo code is not available on your subscription plan")
+
 // PromoCode is the aggregate root for promotional codes.
 type PromoCode struct {
 	id               uuid.UUID
@@ -26,21 +40,34 @@ type PromoCode struct {
 	maxDiscountCents int64
 	maxUses          int
 	currentUses      int
+	firstBookingOnly bool
+	// applicablePlans limits the promo to subscribers of these plans; empty
+	// means anyone may use it.
+	applicablePlans []string
 	validFrom        time.Time
 	validUntil       time.Time
 	createdBy        uuid.UUID
 	createdAt        time.Time
 	updatedAt        time.Time
+
+	// clock decides validity; clock.Default is used while it is nil.
+	clock clock.Clock
+}
+
+// NormalizeCode returns code in its stored form: trimmed and upper-cased.
+// Lookups must normalize user input the same way.
+func NormalizeCode(code string) string {
+	return strings.ToUpper(strings.TrimSpace(code))
 }
 
 // NewPromoCode creates a new promo code.
 func NewPromoCode(code string, discountType DiscountType, discountValue, minAmountCents, maxDiscountCents int64, maxUses int, validFrom, validUntil time.Time, createdBy uuid.UUID) (*PromoCode, error) {
-	code = strings.ToUpper(strings.TrimSpace(code))
+	code = NormalizeCode(code)
 	if code == "" {
 		return nil, fmt.Errorf("promo code is required")
 	}
 	if discountType != DiscountTypePercentage && discountType != DiscountTypeFixed {
-		return nil, fmt.Errorf("invalid discount type: %s", discountType)
+		return nil, fmt.Errorf("%w %q: must be one of %s, %s", ErrInvalidDiscountType, discountType, DiscountTypePercentage, DiscountTypeFixed)
 	}
 	if discountValue <= 0 {
 		return nil, fmt.Errorf("discount value must be positive")
@@ -52,7 +79,7 @@ func NewPromoCode(code string, discountType DiscountType, discountValue, minAmou
 		return nil, fmt.Errorf("valid_until must be after valid_from")
 	}
 
-	now := time.Now().UTC()
+	now := clock.Default.Now()
 	return &PromoCode{
 		id:               uuid.New(),
 		code:             code,
@@ -71,35 +98,118 @@ func NewPromoCode(code string, discountType DiscountType, discountValue, minAmou
 }
 
 // Reconstruct rebuilds a PromoCode from persistence.
-func Reconstruct(id uuid.UUID, code string, discountType DiscountType, discountValue, minAmountCents, maxDiscountCents int64, maxUses, currentUses int, validFrom, validUntil time.Time, createdBy uuid.UUID, createdAt, updatedAt time.Time) *PromoCode {
+func Reconstruct(id uuid.UUID, code string, discountType DiscountType, discountValue, minAmountCents, maxDiscountCents int64, maxUses, currentUses int, firstBookingOnly bool, applicablePlans []string, validFrom, validUntil time.Time, createdBy uuid.UUID, createdAt, updatedAt time.Time) *PromoCode {
 	return &PromoCode{
 		id: id, code: code, discountType: discountType, discountValue: discountValue,
 		minAmountCents: minAmountCents, maxDiscountCents: maxDiscountCents,
-		maxUses: maxUses, currentUses: currentUses,
+		maxUses: maxUses, currentUses: currentUses, firstBookingOnly: firstBookingOnly,
+		applicablePlans: applicablePlans,
 		validFrom: validFrom, validUntil: validUntil,
 		createdBy: createdBy, createdAt: createdAt, updatedAt: updatedAt,
 	}
 }
 
+// RestrictToFirstBooking limits the promo to users with no prior payment.
+func (p *PromoCode) RestrictToFirstBooking() {
+	p.firstBookingOnly = true
+	p.updatedAt = p.now()
+}
+
+// CheckFirstBooking returns ErrFirstBookingOnly if the promo is limited to a
+// first booking and the user already has priorPayments.
+func (p *PromoCode) CheckFirstBooking(priorPayments int64) error {
+	if p.firstBookingOnly && priorPayments > 0 {
+		return ErrFirstBookingOnly
+	}
+	return nil
+}
+
+// RestrictToPlans limits the promo to users with an active subscription on
+// one of plans. Names are lower-cased and duplicates dropped; an empty list
+// lifts the restriction.
+func (p *PromoCode) RestrictToPlans(plans []string) {
+	var restricted []string
+	seen := make(map[string]bool)
+	for _, plan := range plans {
+		plan = strings.ToLower(strings.TrimSpace(plan))
+		if plan == "" || seen[plan] {
+			continue
+		}
+		seen[plan] = true
+		restricted = append(restricted, plan)
+	}
+	p.applicablePlans = restricted
+	p.updatedAt = p.now()
+}
+
+// CheckPlan returns ErrPlanNotEligible if the promo is limited to plans and
+// plan, the user's active subscription plan or "" if they have none, is not
+// one of them.
+func (p *PromoCode) CheckPlan(plan string) error {
+	if len(p.applicablePlans) == 0 {
+		return nil
+	}
+	for _, allowed := range p.applicablePlans {
+		if allowed == plan {
+			return nil
+		}
+	}
+	return ErrPlanNotEligible
+}
+
+// UseClock makes p take timestamps from c instead of clock.Default.
+func (p *PromoCode) UseClock(c clock.Clock) { p.clock = c }
+
+// now returns the current time from p's clock.
+func (p *PromoCode) now() time.Time {
+	if p.clock == nil {
+		return clock.Default.Now()
+	}
+	return p.clock.Now()
+}
+
 // IsValid checks if the promo code is currently valid.
 func (p *PromoCode) IsValid() bool {
-	now := time.Now().UTC()
-	return now.After(p.validFrom) && now.Before(p.validUntil) && (p.maxUses == 0 || p.currentUses < p.maxUses)
+	return p.IsValidAt(p.now())
+}
+
+// IsValidAt checks if the promo code is valid at the given time. A promo is
+// valid from validFrom inclusive until validUntil exclusive.
+func (p *PromoCode) IsValidAt(now time.Time) bool {
+	return !now.Before(p.validFrom) && now.Before(p.validUntil) && (p.maxUses == 0 || p.currentUses < p.maxUses)
+}
+
+// Discount is the result of applying a promo code to a total.
+type Discount struct {
+	AmountCents int64
+	// Clamped reports that the percent-of-total cap reduced the discount.
+	Clamped bool
+}
+
+// PercentOf returns pct percent of amountCents as a discount, rounded to the
+// nearest cent with exact halves rounded down, following the rounding policy
+// of payment.PercentOf: a half-cent goes to the platform, so the customer is
+// never undercharged. Every percentage in a discount calculation uses it, so
+// quotes, applied discounts and receipts agree.
+func PercentOf(amountCents, pct int64) int64 {
+	return payment.PercentOf(amountCents, float64(pct), payment.RoundHalfDown)
 }
 
 // CalculateDiscount calculates the discount amount for a given total.
-func (p *PromoCode) CalculateDiscount(totalCents int64) (int64, error) {
+// maxPercentOfTotal caps the discount at that share of the total, on top of
+// maxDiscountCents; zero disables the cap. Percentages are rounded by PercentOf.
+func (p *PromoCode) CalculateDiscount(totalCents, maxPercentOfTotal int64) (Discount, error) {
 	if !p.IsValid() {
-		return 0, fmt.Errorf("promo code is no longer valid")
+		return Discount{}, fmt.Errorf("promo code is no longer valid")
 	}
 	if totalCents < p.minAmountCents {
-		return 0, fmt.Errorf("minimum amount of %d cents required", p.minAmountCents)
+		return Discount{}, fmt.Errorf("minimum amount of %d cents required", p.minAmountCents)
 	}
 
 	var discount int64
 	switch p.discountType {
 	case DiscountTypePercentage:
-		discount = totalCents * p.discountValue / 100
+		discount = PercentOf(totalCents, p.discountValue)
 	case DiscountTypeFixed:
 		discount = p.discountValue
 	}
@@ -111,13 +221,21 @@ func (p *PromoCode) CalculateDiscount(totalCents int64) (int64, error) {
 		discount = totalCents
 	}
 
-	return discount, nil
+	var clamped bool
+	if maxPercentOfTotal > 0 {
+		if capped := PercentOf(totalCents, maxPercentOfTotal); discount > capped {
+			discount = capped
+			clamped = true
+		}
+	}
+
+	return Discount{AmountCents: discount, Clamped: clamped}, nil
 }
 
 // IncrementUses increments the usage count.
 func (p *PromoCode) IncrementUses() {
 	p.currentUses++
-	p.updatedAt = time.Now().UTC()
+	p.updatedAt = p.now()
 }
 
 // Getters.
@@ -129,8 +247,17 @@ func (p *PromoCode) MinAmountCents() int64     { return p.minAmountCents }
 func (p *PromoCode) MaxDiscountCents() int64   { return p.maxDiscountCents }
 func (p *PromoCode) MaxUses() int              { return p.maxUses }
 func (p *PromoCode) CurrentUses() int          { return p.currentUses }
+func (p *PromoCode) FirstBookingOnly() bool     { return p.firstBookingOnly }
 func (p *PromoCode) ValidFrom() time.Time      { return p.validFrom }
 func (p *PromoCode) ValidUntil() time.Time     { return p.validUntil }
 func (p *PromoCode) CreatedBy() uuid.UUID      { return p.createdBy }
 func (p *PromoCode) CreatedAt() time.Time      { return p.createdAt }
 func (p *PromoCode) UpdatedAt() time.Time      { return p.updatedAt }
+
+// ApplicablePlans returns the plans the promo is limited to, or nil if anyone may use it.
+func (p *PromoCode) ApplicablePlans() []string {
+	if len(p.applicablePlans) == 0 {
+		return nil
+	}
+	return append([]string(nil), p.applicablePlans...)
+}
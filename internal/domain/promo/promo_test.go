@@ -0,0 +1,169 @@
+package promo
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Kilat-Pet-Delivery/service-payment/internal/clock"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIsValidAt_Boundaries(t *testing.T) {
+	from := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+	until := from.Add(24 * time.Hour)
+	p, err := NewPromoCode("SPRING", DiscountTypePercentage, 10, 0, 0, 0, from, until, uuid.New())
+	require.NoError(t, err)
+
+	tests := []struct {
+		name string
+		at   time.Time
+		want bool
+	}{
+		{"just before valid_from", from.Add(-time.Nanosecond), false},
+		{"exactly at valid_from", from, true},
+		{"just after valid_from", from.Add(time.Nanosecond), true},
+		{"just before valid_until", until.Add(-time.Nanosecond), true},
+		{"exactly at valid_until", until, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, p.IsValidAt(tt.at))
+		})
+	}
+}
+
+func TestIsValidAt_MaxUses(t *testing.T) {
+	from := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+	p, err := NewPromoCode("ONCE", DiscountTypeFixed, 500, 0, 0, 1, from, from.Add(time.Hour), uuid.New())
+	require.NoError(t, err)
+
+	assert.True(t, p.IsValidAt(from))
+	p.IncrementUses()
+	assert.False(t, p.IsValidAt(from))
+}
+
+func TestNewPromoCode_InvalidDiscountType(t *testing.T) {
+	from := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+	_, err := NewPromoCode("BOGUS", DiscountType("bogo"), 10, 0, 0, 0, from, from.Add(time.Hour), uuid.New())
+	require.Error(t, err)
+	assert.ErrorIs(t, err, ErrInvalidDiscountType)
+	assert.Contains(t, err.Error(), "percentage, fixed")
+}
+
+func TestCalculateDiscount_PercentOfTotalCap(t *testing.T) {
+	now := time.Now().UTC()
+	newPromo := func(discountType DiscountType, value, maxDiscount int64) *PromoCode {
+		p, err := NewPromoCode("CAP", discountType, value, 0, maxDiscount, 0, now.Add(-time.Hour), now.Add(time.Hour), uuid.New())
+		require.NoError(t, err)
+		return p
+	}
+
+	tests := []struct {
+		name        string
+		promo       *PromoCode
+		total       int64
+		maxPercent  int64
+		want        int64
+		wantClamped bool
+	}{
+		{"fixed promo on small booking binds at 50%", newPromo(DiscountTypeFixed, 1000, 0), 1200, 50, 600, true},
+		{"fixed promo on large booking is unaffected", newPromo(DiscountTypeFixed, 1000, 0), 10000, 50, 1000, false},
+		{"fixed promo exactly at the cap", newPromo(DiscountTypeFixed, 1000, 0), 2000, 50, 1000, false},
+		{"fixed promo larger than total", newPromo(DiscountTypeFixed, 5000, 0), 1000, 50, 500, true},
+		{"absolute cap binds before percent cap", newPromo(DiscountTypeFixed, 1000, 300), 1200, 50, 300, false},
+		{"zero disables the percent cap", newPromo(DiscountTypeFixed, 1000, 0), 1200, 0, 1000, false},
+		{"percentage promo above the cap", newPromo(DiscountTypePercentage, 80, 0), 10000, 50, 5000, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.promo.CalculateDiscount(tt.total, tt.maxPercent)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got.AmountCents)
+			assert.Equal(t, tt.wantClamped, got.Clamped)
+		})
+	}
+}
+
+func TestCalculateDiscount_RoundsPercentagesToNearestCent(t *testing.T) {
+	now := time.Now().UTC()
+	tests := []struct {
+		name       string
+		value      int64
+		total      int64
+		maxPercent int64
+		want       int64
+	}{
+		{"10% of 1999 rounds 199.9 up", 10, 1999, 0, 200},
+		{"15% of 1001 rounds 150.15 down", 15, 1001, 0, 150},
+		{"50% of 1999 rounds the half cent down", 50, 1999, 0, 999},
+		{"1% of 150 rounds the half cent down", 1, 150, 0, 1},
+		{"1% of 151 rounds 1.51 up", 1, 151, 0, 2},
+		{"percent-of-total cap rounds the same way", 80, 1999, 50, 999},
+		{"1% of 49 rounds 0.49 down to nothing", 1, 49, 0, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, err := NewPromoCode("ROUND", DiscountTypePercentage, tt.value, 0, 0, 0, now.Add(-time.Hour), now.Add(time.Hour), uuid.New())
+			require.NoError(t, err)
+			got, err := p.CalculateDiscount(tt.total, tt.maxPercent)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got.AmountCents)
+			assert.Equal(t, PercentOf(tt.total, tt.value) > got.AmountCents, got.Clamped)
+		})
+	}
+}
+
+func TestNormalizeCode_MatchesStoredForm(t *testing.T) {
+	now := time.Now().UTC()
+	p, err := NewPromoCode(" save10\t", DiscountTypeFixed, 1000, 0, 0, 0, now, now.Add(time.Hour), uuid.New())
+	require.NoError(t, err)
+
+	for _, input := range []string{"SAVE10", "save10 ", " Save10 "} {
+		assert.Equal(t, p.Code(), NormalizeCode(input), "input %q", input)
+	}
+}
+
+func TestCheckFirstBooking(t *testing.T) {
+	now := time.Now().UTC()
+	p, err := NewPromoCode("WELCOME", DiscountTypeFixed, 500, 0, 0, 0, now, now.Add(time.Hour), uuid.New())
+	require.NoError(t, err)
+	assert.NoError(t, p.CheckFirstBooking(3), "unrestricted promos ignore prior payments")
+
+	p.RestrictToFirstBooking()
+	assert.True(t, p.FirstBookingOnly())
+	assert.NoError(t, p.CheckFirstBooking(0))
+	assert.ErrorIs(t, p.CheckFirstBooking(1), ErrFirstBookingOnly)
+}
+
+func TestCheckPlan(t *testing.T) {
+	now := time.Now().UTC()
+	p, err := NewPromoCode("PREMIUM", DiscountTypeFixed, 500, 0, 0, 0, now, now.Add(time.Hour), uuid.New())
+	require.NoError(t, err)
+	assert.NoError(t, p.CheckPlan(""), "unrestricted promos apply without a subscription")
+	assert.Nil(t, p.ApplicablePlans())
+
+	p.RestrictToPlans([]string{" Premium ", "premium"})
+	assert.Equal(t, []string{"premium"}, p.ApplicablePlans())
+	assert.NoError(t, p.CheckPlan("premium"))
+	assert.ErrorIs(t, p.CheckPlan("basic"), ErrPlanNotEligible)
+	assert.ErrorIs(t, p.CheckPlan(""), ErrPlanNotEligible)
+}
+
+func TestIsValid_UsesInjectedClock(t *testing.T) {
+	from := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	p, err := NewPromoCode("WINDOW", DiscountTypeFixed, 500, 0, 0, 0, from, from.Add(24*time.Hour), uuid.New())
+	require.NoError(t, err)
+	fake := clock.NewFake(from.Add(-time.Minute))
+	p.UseClock(fake)
+
+	assert.False(t, p.IsValid(), "not yet started")
+	fake.Advance(time.Minute)
+	assert.True(t, p.IsValid(), "valid from valid_from")
+	fake.Set(from.Add(24 * time.Hour))
+	assert.False(t, p.IsValid(), "expired at valid_until")
+}
@@ -14,10 +14,26 @@ type PromoRepository interface {
 	FindByCode(ctx context.Context, code string) (*PromoCode, error)
 	FindByID(ctx context.Context, id uuid.UUID) (*PromoCode, error)
 	FindActive(ctx context.Context) ([]*PromoCode, error)
+	// FindUpcoming returns promo codes whose validFrom is still in the future,
+	// soonest first.
+	FindUpcoming(ctx context.Context) ([]*PromoCode, error)
+	// FindByCreator returns a page of promo codes created by createdBy, newest
+	// first, and the total number of them.
+	FindByCreator(ctx context.Context, createdBy uuid.UUID, page, limit int) ([]*PromoCode, int64, error)
+	// UsageStats returns usage totals for each of promoIDs. Promos that were
+	// never used are absent from the result.
+	UsageStats(ctx context.Context, promoIDs []uuid.UUID) (map[uuid.UUID]UsageStats, error)
 	SaveUsage(ctx context.Context, usage *PromoUsage) error
 	HasUserUsedPromo(ctx context.Context, promoID, userID uuid.UUID) (bool, error)
 }
 
+// UsageStats summarizes how a promo code has been used.
+type UsageStats struct {
+	Uses               int64
+	UniqueUsers        int64
+	TotalDiscountCents int64
+}
+
 // PromoUsage tracks each individual promo code usage.
 type PromoUsage struct {
 	ID            uuid.UUID
@@ -0,0 +1,18 @@
+package refund
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+)
+
+// RequestRepository defines persistence operations for refund requests.
+type RequestRepository interface {
+	// Save persists a new request. A payment has at most one pending request;
+	// saving a second returns a conflict error.
+	Save(ctx context.Context, r *Request) error
+	FindByID(ctx context.Context, id uuid.UUID) (*Request, error)
+	// MarkApproved stores r's approval if the request is still pending and
+	// returns a conflict error otherwise.
+	MarkApproved(ctx context.Context, r *Request) error
+}
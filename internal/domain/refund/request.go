@@ -0,0 +1,79 @@
+package refund
+
+import (
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Status is the approval state of a refund request.
+type Status string
+
+const (
+	// StatusPending requests are waiting for a second admin's approval.
+	StatusPending Status = "pending"
+	// StatusApproved requests were approved and their refund completed.
+	StatusApproved Status = "approved"
+)
+
+// ErrSelfApproval is returned when an admin tries to approve their own request.
+var ErrSelfApproval = errors.New("a refund request cannot be approved by its requester")
+
+// ErrNotPending is returned when approving a request that is no longer pending.
+var ErrNotPending = errors.New("refund request is not pending")
+
+// Request is a refund at or above the approval threshold, held until an admin
+// other than the requester approves it.
+type Request struct {
+	ID          uuid.UUID
+	PaymentID   uuid.UUID
+	AmountCents int64
+	// Reason is the normalized refund reason, including any reason code.
+	Reason      string
+	Method      string
+	Status      Status
+	RequestedBy uuid.UUID
+	ApprovedBy  *uuid.UUID
+	ApprovedAt  *time.Time
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+}
+
+// NewRequest creates a pending refund request.
+func NewRequest(paymentID uuid.UUID, amountCents int64, reason, method string, requestedBy uuid.UUID, now time.Time) *Request {
+	return &Request{
+		ID:          uuid.New(),
+		PaymentID:   paymentID,
+		AmountCents: amountCents,
+		Reason:      reason,
+		Method:      method,
+		Status:      StatusPending,
+		RequestedBy: requestedBy,
+		CreatedAt:   now,
+		UpdatedAt:   now,
+	}
+}
+
+// CanApprove reports why approver cannot approve the request, if they cannot.
+func (r *Request) CanApprove(approver uuid.UUID) error {
+	if r.Status != StatusPending {
+		return ErrNotPending
+	}
+	if approver == r.RequestedBy {
+		return ErrSelfApproval
+	}
+	return nil
+}
+
+// Approve records approver's approval.
+func (r *Request) Approve(approver uuid.UUID, at time.Time) error {
+	if err := r.CanApprove(approver); err != nil {
+		return err
+	}
+	r.Status = StatusApproved
+	r.ApprovedBy = &approver
+	r.ApprovedAt = &at
+	r.UpdatedAt = at
+	return nil
+}
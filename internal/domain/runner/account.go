@@ -0,0 +1,19 @@
+package runner
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Account links a runner to the Stripe Connect account their payouts are
+// transferred to. The mapping is owned by the runner service and mirrored here
+// from RunnerAccountLinked events.
+type Account struct {
+	RunnerID        uuid.UUID
+	StripeAccountID string
+	// LinkedAt is when the runner service linked the account. It orders
+	// updates so a redelivered older event cannot overwrite a newer link.
+	LinkedAt  time.Time
+	UpdatedAt time.Time
+}
@@ -0,0 +1,16 @@
+package runner
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+)
+
+// AccountRepository defines persistence operations for runner payout accounts.
+type AccountRepository interface {
+	// Upsert stores the runner's account. It is idempotent: an account whose
+	// LinkedAt is older than the stored one is ignored.
+	Upsert(ctx context.Context, account Account) error
+	// FindByRunnerID returns the runner's account, or nil if none is linked.
+	FindByRunnerID(ctx context.Context, runnerID uuid.UUID) (*Account, error)
+}
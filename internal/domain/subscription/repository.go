@@ -2,14 +2,35 @@ package subscription
 
 import (
 	"context"
+	"time"
 
 	"github.com/google/uuid"
 )
 
 // SubscriptionRepository defines persistence operations for subscriptions.
 type SubscriptionRepository interface {
+	// Save returns ErrActiveSubscriptionExists if the user already has an active
+	// subscription and ErrDuplicateIdempotencyKey if the user already signed up
+	// with the subscription's idempotency key.
 	Save(ctx context.Context, s *Subscription) error
 	Update(ctx context.Context, s *Subscription) error
+	// FindActiveByUserID returns ErrNoActiveSubscription if the user has no
+	// active subscription.
 	FindActiveByUserID(ctx context.Context, userID uuid.UUID) (*Subscription, error)
+	// FindPausedByUserID returns ErrNoPausedSubscription if the user has no
+	// paused subscription.
+	FindPausedByUserID(ctx context.Context, userID uuid.UUID) (*Subscription, error)
 	FindByID(ctx context.Context, id uuid.UUID) (*Subscription, error)
+	// FindByIdempotencyKey returns the user's subscription created by the signup
+	// with Idempotency-Key key, or a not-found error.
+	FindByIdempotencyKey(ctx context.Context, userID uuid.UUID, key string) (*Subscription, error)
+	// FindByUserID returns a page of a user's subscriptions, newest first, and
+	// the user's total subscription count.
+	FindByUserID(ctx context.Context, userID uuid.UUID, page, limit int) ([]*Subscription, int64, error)
+	// FindRenewalDue returns up to limit active, auto-renewing, paid
+	// subscriptions that are still running at now and expire at or before
+	// before, soonest first.
+	FindRenewalDue(ctx context.Context, now, before time.Time, limit int) ([]*Subscription, error)
+	// ExpireLapsed marks the user's active subscriptions that expired before now as expired.
+	ExpireLapsed(ctx context.Context, userID uuid.UUID, now time.Time) error
 }
@@ -1,12 +1,47 @@
 package subscription
 
 import (
+	"errors"
 	"fmt"
+	"strings"
 	"time"
 
+	"github.com/Kilat-Pet-Delivery/lib-common/domain"
+	"github.com/Kilat-Pet-Delivery/service-payment/internal/clock"
 	"github.com/google/uuid"
 )
 
+// ErrActiveSubscriptionExists is returned when a user who already has an active
+// subscription tries to start another one.
+var ErrActiveSubscriptionExists = domain.NewConflictError("user already has an active subscription")
+
+// ErrAlreadyRenewed is returned when a renewal loses the race to a concurrent
+// renewal of the same subscription.
+var ErrAlreadyRenewed = domain.NewConflictError("subscription was already renewed")
+
+// ErrPausedSubscriptionExists is returned when a user with a paused
+// subscription tries to start another one.
+var ErrPausedSubscriptionExists = domain.NewConflictError("user has a paused subscription; resume it instead")
+
+// ErrNoActiveSubscription is returned when an operation needs the user's
+// active subscription and the user has none.
+var ErrNoActiveSubscription = &domain.DomainError{Err: domain.ErrNotFound, Message: "no active subscription found"}
+
+// ErrNoPausedSubscription is returned when a resume finds no paused
+// subscription for the user.
+var ErrNoPausedSubscription = &domain.DomainError{Err: domain.ErrNotFound, Message: "no paused subscription found"}
+
+// ErrDuplicateIdempotencyKey is returned when a subscription is saved with an
+// idempotency key the user already signed up with.
+var ErrDuplicateIdempotencyKey = domain.NewConflictError("subscription already created with this idempotency key")
+
+// ErrInvalidPlan is returned when a subscription is requested for an unknown plan.
+var ErrInvalidPlan = errors.New("invalid plan")
+
+// ErrInvalidGrant is returned when a comped subscription is granted without a
+// reason or with a non-positive duration.
+var ErrInvalidGrant = errors.New("invalid subscription grant")
+
 // PlanType represents the subscription plan.
 type PlanType string
 
@@ -22,6 +57,9 @@ const (
 	StatusActive    SubStatus = "active"
 	StatusCancelled SubStatus = "cancelled"
 	StatusExpired   SubStatus = "expired"
+	// StatusPaused freezes a subscription: it gives no discount, is not
+	// renewed and does not run down until resumed.
+	StatusPaused SubStatus = "paused"
 )
 
 // PlanInfo defines the properties of a subscription plan.
@@ -51,24 +89,66 @@ type Subscription struct {
 	expiresAt  time.Time
 	status     SubStatus
 	autoRenew  bool
+	version    int64
 	createdAt  time.Time
 	updatedAt  time.Time
+
+	// pausedAt is set while the subscription is paused. pausedDuration is
+	// the total time spent paused, excluding any pause in progress.
+	pausedAt       *time.Time
+	pausedDuration time.Duration
+
+	// grant is set when the subscription was comped by an admin instead of paid for.
+	grant *Grant
+
+	// idempotencyKey is the client's Idempotency-Key for the signup that
+	// created the subscription, if it sent one.
+	idempotencyKey string
+
+	// commitmentEndsAt is set when the plan was taken with a minimum
+	// commitment; cancelling before it forfeits the refund. cancelledEarly
+	// records such a cancellation.
+	commitmentEndsAt *time.Time
+	cancelledEarly   bool
+
+	// clock decides expiry; clock.Default is used while it is nil.
+	clock clock.Clock
 }
 
-// NewSubscription creates a new subscription.
-func NewSubscription(userID uuid.UUID, plan PlanType) (*Subscription, error) {
-	var planInfo *PlanInfo
+// Grant records who comped a subscription and why.
+type Grant struct {
+	GrantedBy uuid.UUID
+	Reason    string
+}
+
+// FindPlan returns the plan info for the given plan type.
+func FindPlan(plan PlanType) (*PlanInfo, bool) {
 	for _, p := range AvailablePlans() {
 		if p.Plan == plan {
-			planInfo = &p
-			break
+			return &p, true
 		}
 	}
-	if planInfo == nil {
-		return nil, fmt.Errorf("invalid plan: %s", plan)
+	return nil, false
+}
+
+// planNames lists the available plan types for error messages.
+func planNames() string {
+	plans := AvailablePlans()
+	names := make([]string, len(plans))
+	for i, p := range plans {
+		names[i] = string(p.Plan)
+	}
+	return strings.Join(names, ", ")
+}
+
+// NewSubscription creates a new subscription.
+func NewSubscription(userID uuid.UUID, plan PlanType) (*Subscription, error) {
+	planInfo, ok := FindPlan(plan)
+	if !ok {
+		return nil, fmt.Errorf("%w %q: must be one of %s", ErrInvalidPlan, plan, planNames())
 	}
 
-	now := time.Now().UTC()
+	now := clock.Default.Now()
 	return &Subscription{
 		id:         uuid.New(),
 		userID:     userID,
@@ -78,30 +158,177 @@ func NewSubscription(userID uuid.UUID, plan PlanType) (*Subscription, error) {
 		expiresAt:  now.AddDate(0, 0, planInfo.DurationDays),
 		status:     StatusActive,
 		autoRenew:  true,
+		version:    1,
+		createdAt:  now,
+		updatedAt:  now,
+	}, nil
+}
+
+// NewCompedSubscription creates a free, non-renewing subscription granted by
+// an admin. durationDays of 0 uses the plan's own duration.
+func NewCompedSubscription(userID uuid.UUID, plan PlanType, durationDays int, grant Grant) (*Subscription, error) {
+	planInfo, ok := FindPlan(plan)
+	if !ok {
+		return nil, fmt.Errorf("%w %q: must be one of %s", ErrInvalidPlan, plan, planNames())
+	}
+	if strings.TrimSpace(grant.Reason) == "" {
+		return nil, fmt.Errorf("%w: a reason is required", ErrInvalidGrant)
+	}
+	if durationDays < 0 {
+		return nil, fmt.Errorf("%w: duration_days must be positive", ErrInvalidGrant)
+	}
+	if durationDays == 0 {
+		durationDays = planInfo.DurationDays
+	}
+
+	now := clock.Default.Now()
+	return &Subscription{
+		id:         uuid.New(),
+		userID:     userID,
+		plan:       plan,
+		priceCents: 0,
+		startedAt:  now,
+		expiresAt:  now.AddDate(0, 0, durationDays),
+		status:     StatusActive,
+		autoRenew:  false,
+		grant:      &grant,
+		version:    1,
 		createdAt:  now,
 		updatedAt:  now,
 	}, nil
 }
 
 // Reconstruct rebuilds a Subscription from persistence.
-func Reconstruct(id, userID uuid.UUID, plan PlanType, priceCents int64, startedAt, expiresAt time.Time, status SubStatus, autoRenew bool, createdAt, updatedAt time.Time) *Subscription {
+func Reconstruct(id, userID uuid.UUID, plan PlanType, priceCents int64, startedAt, expiresAt time.Time, status SubStatus, autoRenew bool, pausedAt *time.Time, pausedDuration time.Duration, grant *Grant, idempotencyKey string, commitmentEndsAt *time.Time, cancelledEarly bool, version int64, createdAt, updatedAt time.Time) *Subscription {
 	return &Subscription{
 		id: id, userID: userID, plan: plan, priceCents: priceCents,
 		startedAt: startedAt, expiresAt: expiresAt, status: status,
-		autoRenew: autoRenew, createdAt: createdAt, updatedAt: updatedAt,
+		autoRenew: autoRenew, pausedAt: pausedAt, pausedDuration: pausedDuration,
+		grant: grant, idempotencyKey: idempotencyKey,
+		commitmentEndsAt: commitmentEndsAt, cancelledEarly: cancelledEarly,
+		version: version, createdAt: createdAt, updatedAt: updatedAt,
+	}
+}
+
+// SetIdempotencyKey records the Idempotency-Key of the signup request that is
+// creating the subscription. It must be called before the subscription is saved.
+func (s *Subscription) SetIdempotencyKey(key string) {
+	s.idempotencyKey = key
+}
+
+// SetCommitment binds the subscription to a minimum commitment of period from
+// its start. It must be called before the subscription is saved; a period of
+// zero leaves it without one.
+func (s *Subscription) SetCommitment(period time.Duration) {
+	if period <= 0 {
+		s.commitmentEndsAt = nil
+		return
 	}
+	endsAt := s.startedAt.Add(period)
+	s.commitmentEndsAt = &endsAt
 }
 
 // Cancel cancels the subscription.
 func (s *Subscription) Cancel() {
 	s.status = StatusCancelled
 	s.autoRenew = false
-	s.updatedAt = time.Now().UTC()
+	s.incrementVersion()
+}
+
+// CancelWithPolicy cancels the subscription at now and reports whether the
+// unused rest of its paid period is due back. Cancelling before the commitment
+// ends forfeits the refund and marks the subscription as cancelled early; from
+// the moment it ends, a refund is due. Comped and lapsed subscriptions have
+// nothing to refund.
+func (s *Subscription) CancelWithPolicy(now time.Time) bool {
+	s.Cancel()
+	if s.commitmentEndsAt != nil && now.Before(*s.commitmentEndsAt) {
+		s.cancelledEarly = true
+		return false
+	}
+	return s.grant == nil && s.priceCents > 0 && now.Before(s.expiresAt)
+}
+
+// Pause freezes an active, unexpired subscription.
+func (s *Subscription) Pause(now time.Time) error {
+	if s.status != StatusActive || !now.Before(s.expiresAt) {
+		return domain.NewInvalidStateError(string(s.status), string(StatusPaused))
+	}
+	s.status = StatusPaused
+	s.pausedAt = &now
+	s.incrementVersion()
+	return nil
+}
+
+// Resume reactivates a paused subscription and pushes its expiry back by the
+// time it spent paused.
+func (s *Subscription) Resume(now time.Time) error {
+	if s.status != StatusPaused || s.pausedAt == nil {
+		return domain.NewInvalidStateError(string(s.status), string(StatusActive))
+	}
+	paused := now.Sub(*s.pausedAt)
+	if paused < 0 {
+		paused = 0
+	}
+	s.expiresAt = s.expiresAt.Add(paused)
+	s.pausedDuration += paused
+	s.pausedAt = nil
+	s.status = StatusActive
+	s.incrementVersion()
+	return nil
+}
+
+// Renew extends an active, auto-renewing subscription by one plan period and
+// returns the expiry it replaced. A lapsed subscription is extended from now
+// rather than from its old expiry. Comped subscriptions are never renewed, so
+// they are never charged.
+func (s *Subscription) Renew(now time.Time) (time.Time, error) {
+	if s.status != StatusActive || !s.autoRenew || s.grant != nil {
+		return time.Time{}, domain.NewInvalidStateError(string(s.status), "renewed")
+	}
+	planInfo, ok := FindPlan(s.plan)
+	if !ok {
+		return time.Time{}, fmt.Errorf("%w %q", ErrInvalidPlan, s.plan)
+	}
+
+	previous := s.expiresAt
+	from := s.expiresAt
+	if now.After(from) {
+		from = now
+	}
+	s.expiresAt = from.AddDate(0, 0, planInfo.DurationDays)
+	s.incrementVersion()
+	return previous, nil
+}
+
+// RevertRenewal restores the expiry returned by Renew, e.g. when the renewal
+// charge fails after the renewal was claimed.
+func (s *Subscription) RevertRenewal(previousExpiresAt time.Time) {
+	s.expiresAt = previousExpiresAt
+	s.incrementVersion()
+}
+
+// incrementVersion bumps the version for optimistic locking. Every mutator must
+// call it so the repository can detect concurrent writes.
+func (s *Subscription) incrementVersion() {
+	s.version++
+	s.updatedAt = s.now()
+}
+
+// UseClock makes s take timestamps from c instead of clock.Default.
+func (s *Subscription) UseClock(c clock.Clock) { s.clock = c }
+
+// now returns the current time from s's clock.
+func (s *Subscription) now() time.Time {
+	if s.clock == nil {
+		return clock.Default.Now()
+	}
+	return s.clock.Now()
 }
 
 // IsActive returns true if the subscription is currently active and not expired.
 func (s *Subscription) IsActive() bool {
-	return s.status == StatusActive && time.Now().UTC().Before(s.expiresAt)
+	return s.status == StatusActive && s.now().Before(s.expiresAt)
 }
 
 // Getters.
@@ -113,5 +340,26 @@ func (s *Subscription) StartedAt() time.Time { return s.startedAt }
 func (s *Subscription) ExpiresAt() time.Time { return s.expiresAt }
 func (s *Subscription) Status() SubStatus    { return s.status }
 func (s *Subscription) AutoRenew() bool      { return s.autoRenew }
+func (s *Subscription) Version() int64       { return s.version }
 func (s *Subscription) CreatedAt() time.Time { return s.createdAt }
 func (s *Subscription) UpdatedAt() time.Time { return s.updatedAt }
+
+func (s *Subscription) PausedAt() *time.Time          { return s.pausedAt }
+func (s *Subscription) PausedDuration() time.Duration { return s.pausedDuration }
+
+// Comped reports whether the subscription was granted free by an admin.
+func (s *Subscription) Comped() bool { return s.grant != nil }
+
+// Grant returns who comped the subscription and why, or nil if it was paid for.
+func (s *Subscription) Grant() *Grant { return s.grant }
+
+// CommitmentEndsAt returns when the minimum commitment ends, or nil if there is none.
+func (s *Subscription) CommitmentEndsAt() *time.Time { return s.commitmentEndsAt }
+
+// CancelledEarly reports whether the subscription was cancelled before its
+// commitment ended.
+func (s *Subscription) CancelledEarly() bool { return s.cancelledEarly }
+
+// IdempotencyKey returns the Idempotency-Key of the signup that created the
+// subscription, or "" if none was sent.
+func (s *Subscription) IdempotencyKey() string { return s.idempotencyKey }
@@ -0,0 +1,156 @@
+package subscription
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Kilat-Pet-Delivery/lib-common/domain"
+	"github.com/Kilat-Pet-Delivery/service-payment/internal/clock"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewSubscription_InvalidPlan(t *testing.T) {
+	_, err := NewSubscription(uuid.New(), PlanType("platinum"))
+	require.Error(t, err)
+	assert.ErrorIs(t, err, ErrInvalidPlan)
+	assert.Contains(t, err.Error(), "basic, premium")
+}
+
+func TestRenew(t *testing.T) {
+	now := time.Now().UTC()
+	expiry := now.Add(48 * time.Hour)
+
+	sub := Reconstruct(uuid.New(), uuid.New(), PlanBasic, 1990, now, expiry, StatusActive, true, nil, 0, nil, "", nil, false, 1, now, now)
+	previous, err := sub.Renew(now)
+	require.NoError(t, err)
+	assert.Equal(t, expiry, previous)
+	assert.Equal(t, expiry.AddDate(0, 0, 30), sub.ExpiresAt(), "an active subscription extends from its expiry")
+	assert.Equal(t, int64(2), sub.Version())
+
+	lapsed := Reconstruct(uuid.New(), uuid.New(), PlanBasic, 1990, now, now.Add(-time.Hour), StatusActive, true, nil, 0, nil, "", nil, false, 1, now, now)
+	_, err = lapsed.Renew(now)
+	require.NoError(t, err)
+	assert.Equal(t, now.AddDate(0, 0, 30), lapsed.ExpiresAt(), "a lapsed subscription extends from now")
+
+	cancelled := Reconstruct(uuid.New(), uuid.New(), PlanBasic, 1990, now, expiry, StatusCancelled, false, nil, 0, nil, "", nil, false, 1, now, now)
+	_, err = cancelled.Renew(now)
+	assert.ErrorIs(t, err, domain.ErrInvalidState)
+	assert.Equal(t, expiry, cancelled.ExpiresAt())
+}
+
+func TestPauseResume_ExtendsExpiryByPausedTime(t *testing.T) {
+	start := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+	expiry := start.AddDate(0, 0, 30)
+	sub := Reconstruct(uuid.New(), uuid.New(), PlanPremium, 4990, start, expiry, StatusActive, true, nil, 0, nil, "", nil, false, 1, start, start)
+
+	pausedAt := start.AddDate(0, 0, 10)
+	require.NoError(t, sub.Pause(pausedAt))
+	assert.Equal(t, StatusPaused, sub.Status())
+	assert.Equal(t, &pausedAt, sub.PausedAt())
+	assert.False(t, sub.IsActive(), "a paused subscription gives no discount")
+
+	_, err := sub.Renew(pausedAt)
+	assert.ErrorIs(t, err, domain.ErrInvalidState, "a paused subscription is not renewed")
+
+	require.NoError(t, sub.Resume(pausedAt.Add(7*24*time.Hour)))
+	assert.Equal(t, StatusActive, sub.Status())
+	assert.Nil(t, sub.PausedAt())
+	assert.Equal(t, expiry.Add(7*24*time.Hour), sub.ExpiresAt())
+	assert.Equal(t, 7*24*time.Hour, sub.PausedDuration())
+
+	// A second pause accumulates.
+	secondPause := start.AddDate(0, 0, 20)
+	require.NoError(t, sub.Pause(secondPause))
+	require.NoError(t, sub.Resume(secondPause.Add(48*time.Hour)))
+	assert.Equal(t, expiry.Add(9*24*time.Hour), sub.ExpiresAt())
+	assert.Equal(t, 9*24*time.Hour, sub.PausedDuration())
+	assert.Equal(t, int64(5), sub.Version())
+}
+
+func TestPauseResume_InvalidStates(t *testing.T) {
+	now := time.Now().UTC()
+
+	expired := Reconstruct(uuid.New(), uuid.New(), PlanBasic, 1990, now, now.Add(-time.Hour), StatusActive, true, nil, 0, nil, "", nil, false, 1, now, now)
+	assert.ErrorIs(t, expired.Pause(now), domain.ErrInvalidState, "a lapsed subscription cannot be paused")
+
+	cancelled := Reconstruct(uuid.New(), uuid.New(), PlanBasic, 1990, now, now.Add(time.Hour), StatusCancelled, false, nil, 0, nil, "", nil, false, 1, now, now)
+	assert.ErrorIs(t, cancelled.Pause(now), domain.ErrInvalidState)
+
+	active := Reconstruct(uuid.New(), uuid.New(), PlanBasic, 1990, now, now.Add(time.Hour), StatusActive, true, nil, 0, nil, "", nil, false, 1, now, now)
+	assert.ErrorIs(t, active.Resume(now), domain.ErrInvalidState, "only a paused subscription can be resumed")
+}
+
+func TestIsActive_UsesInjectedClock(t *testing.T) {
+	start := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	expiry := start.Add(30 * 24 * time.Hour)
+	sub := Reconstruct(uuid.New(), uuid.New(), PlanBasic, 1990, start, expiry, StatusActive, true, nil, 0, nil, "", nil, false, 1, start, start)
+	fake := clock.NewFake(expiry.Add(-time.Second))
+	sub.UseClock(fake)
+
+	assert.True(t, sub.IsActive())
+	fake.Advance(time.Second)
+	assert.False(t, sub.IsActive(), "a subscription lapses at its expiry")
+
+	_, err := sub.Renew(fake.Now())
+	require.NoError(t, err)
+	assert.True(t, sub.IsActive(), "renewal extends the expiry past the clock")
+	assert.Equal(t, expiry, sub.UpdatedAt())
+}
+
+func TestNewCompedSubscription(t *testing.T) {
+	admin := uuid.New()
+	sub, err := NewCompedSubscription(uuid.New(), PlanPremium, 0, Grant{GrantedBy: admin, Reason: "late delivery"})
+	require.NoError(t, err)
+	assert.True(t, sub.Comped())
+	assert.Equal(t, admin, sub.Grant().GrantedBy)
+	assert.Zero(t, sub.PriceCents())
+	assert.False(t, sub.AutoRenew())
+	assert.Equal(t, sub.StartedAt().AddDate(0, 0, 30), sub.ExpiresAt(), "duration defaults to the plan's")
+
+	_, err = sub.Renew(time.Now().UTC())
+	assert.ErrorIs(t, err, domain.ErrInvalidState, "comped subscriptions are never renewed")
+
+	custom, err := NewCompedSubscription(uuid.New(), PlanBasic, 90, Grant{GrantedBy: admin, Reason: "VIP"})
+	require.NoError(t, err)
+	assert.Equal(t, custom.StartedAt().AddDate(0, 0, 90), custom.ExpiresAt())
+
+	_, err = NewCompedSubscription(uuid.New(), PlanBasic, 0, Grant{GrantedBy: admin, Reason: " "})
+	assert.ErrorIs(t, err, ErrInvalidGrant)
+	_, err = NewCompedSubscription(uuid.New(), PlanBasic, -1, Grant{GrantedBy: admin, Reason: "VIP"})
+	assert.ErrorIs(t, err, ErrInvalidGrant)
+}
+
+func TestCancelWithPolicy_CommitmentBoundary(t *testing.T) {
+	start := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+	commitment := 14 * 24 * time.Hour
+	endsAt := start.Add(commitment)
+
+	tests := []struct {
+		name       string
+		commitment time.Duration
+		grant      *Grant
+		cancelAt   time.Time
+		wantRefund bool
+		wantEarly  bool
+	}{
+		{"just inside the commitment", commitment, nil, endsAt.Add(-time.Second), false, true},
+		{"when the commitment ends", commitment, nil, endsAt, true, false},
+		{"after the commitment", commitment, nil, endsAt.Add(time.Hour), true, false},
+		{"without a commitment", 0, nil, start.Add(time.Hour), true, false},
+		{"after expiry", 0, nil, start.AddDate(0, 0, 31), false, false},
+		{"comped", 0, &Grant{GrantedBy: uuid.New(), Reason: "support goodwill"}, start.Add(time.Hour), false, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sub := Reconstruct(uuid.New(), uuid.New(), PlanPremium, 4990, start, start.AddDate(0, 0, 30), StatusActive, true, nil, 0, tt.grant, "", nil, false, 1, start, start)
+			sub.SetCommitment(tt.commitment)
+
+			assert.Equal(t, tt.wantRefund, sub.CancelWithPolicy(tt.cancelAt))
+			assert.Equal(t, tt.wantEarly, sub.CancelledEarly())
+			assert.Equal(t, StatusCancelled, sub.Status())
+			assert.False(t, sub.AutoRenew())
+		})
+	}
+}
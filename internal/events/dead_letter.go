@@ -0,0 +1,91 @@
+package events
+
+import (
+	"context"
+
+	"github.com/Kilat-Pet-Delivery/service-payment/internal/tracing"
+	kafkago "github.com/segmentio/kafka-go"
+)
+
+// Headers set on dead-lettered messages. The original message's headers,
+// including trace context, are kept alongside them.
+const (
+	// DLQOriginalTopicHeader is the topic the event was consumed from.
+	DLQOriginalTopicHeader = "x-dlq-original-topic"
+	// DLQErrorHeader is the error the event last failed with.
+	DLQErrorHeader = "x-dlq-error"
+	// dlqReplayRunHeader marks an event a DLQ replay wrote back, with the
+	// replay's run ID.
+	dlqReplayRunHeader = "x-dlq-replay-run"
+)
+
+// DeadLetterQueue writes booking events that could not be handled to the DLQ
+// topic. Messages keep their key so events for one booking stay on one
+// partition.
+type DeadLetterQueue struct {
+	topic  string
+	writer messageWriter
+}
+
+// NewDeadLetterQueue creates a dead-letter queue writing to topic.
+func NewDeadLetterQueue(brokers []string, topic string) *DeadLetterQueue {
+	return &DeadLetterQueue{topic: topic, writer: &kafkago.Writer{
+		Addr:     kafkago.TCP(brokers...),
+		Balancer: &kafkago.Hash{},
+	}}
+}
+
+// Topic returns the DLQ topic.
+func (q *DeadLetterQueue) Topic() string {
+	return q.topic
+}
+
+// Publish dead-letters msg, which failed with cause.
+func (q *DeadLetterQueue) Publish(ctx context.Context, msg kafkago.Message, cause error) error {
+	return q.write(ctx, msg, cause.Error(), "")
+}
+
+// write copies msg to the DLQ with reason as its error. msg may itself come
+// from the DLQ, in which case its original topic is kept. A non-empty runID
+// marks the copy as written back by that replay.
+func (q *DeadLetterQueue) write(ctx context.Context, msg kafkago.Message, reason, runID string) error {
+	originalTopic := msg.Topic
+	headers := make([]kafkago.Header, 0, len(msg.Headers)+3)
+	for _, h := range msg.Headers {
+		switch h.Key {
+		case DLQOriginalTopicHeader:
+			originalTopic = string(h.Value)
+		case DLQErrorHeader, dlqReplayRunHeader:
+		default:
+			headers = append(headers, h)
+		}
+	}
+	headers = append(headers,
+		kafkago.Header{Key: DLQOriginalTopicHeader, Value: []byte(originalTopic)},
+		kafkago.Header{Key: DLQErrorHeader, Value: []byte(reason)},
+	)
+	if runID != "" {
+		headers = append(headers, kafkago.Header{Key: dlqReplayRunHeader, Value: []byte(runID)})
+	}
+
+	return q.writer.WriteMessages(ctx, kafkago.Message{
+		Topic:   q.topic,
+		Key:     msg.Key,
+		Value:   msg.Value,
+		Headers: headers,
+	})
+}
+
+// Close flushes and closes the underlying writer.
+func (q *DeadLetterQueue) Close() error {
+	return q.writer.Close()
+}
+
+// originalMessage returns a dead-lettered msg as it was consumed, so it can be
+// handled again.
+func originalMessage(msg kafkago.Message) kafkago.Message {
+	if topic := tracing.KafkaHeaderCarrier(msg.Headers).Get(DLQOriginalTopicHeader); topic != "" {
+		msg.Topic = topic
+	}
+	return msg
+}
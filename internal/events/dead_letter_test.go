@@ -0,0 +1,155 @@
+package events
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/Kilat-Pet-Delivery/lib-proto/events"
+	"github.com/Kilat-Pet-Delivery/service-payment/internal/application"
+	"github.com/Kilat-Pet-Delivery/service-payment/internal/domain/payment"
+	"github.com/Kilat-Pet-Delivery/service-payment/internal/tracing"
+	"github.com/google/uuid"
+	kafkago "github.com/segmentio/kafka-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+// dlqTopic is an in-memory single-partition DLQ topic read by one consumer
+// group: a new reader resumes after the last committed offset, as a group
+// member would.
+type dlqTopic struct {
+	writes  []kafkago.Message
+	next    int64
+	commits []int64
+}
+
+func (t *dlqTopic) WriteMessages(_ context.Context, msgs ...kafkago.Message) error {
+	for _, m := range msgs {
+		m.Offset = int64(len(t.writes))
+		t.writes = append(t.writes, m)
+	}
+	return nil
+}
+
+func (t *dlqTopic) FetchMessage(ctx context.Context) (kafkago.Message, error) {
+	if t.next < int64(len(t.writes)) {
+		t.next++
+		return t.writes[t.next-1], nil
+	}
+	<-ctx.Done()
+	return kafkago.Message{}, ctx.Err()
+}
+
+func (t *dlqTopic) CommitMessages(_ context.Context, msgs ...kafkago.Message) error {
+	for _, m := range msgs {
+		t.commits = append(t.commits, m.Offset)
+	}
+	return nil
+}
+
+// Close is shared by the writer and the reader; neither holds resources.
+func (t *dlqTopic) Close() error { return nil }
+
+// reader starts a new group member.
+func (t *dlqTopic) reader() messageReader {
+	t.next = 0
+	if n := len(t.commits); n > 0 {
+		t.next = t.commits[n-1] + 1
+	}
+	return t
+}
+
+func newTestReplayer(c *BookingEventConsumer, topic *dlqTopic) *DeadLetterReplayer {
+	return &DeadLetterReplayer{
+		consumer:    c,
+		dlq:         c.dlq,
+		newReader:   topic.reader,
+		idleTimeout: 50 * time.Millisecond,
+		logger:      zap.NewNop(),
+	}
+}
+
+func TestDeadLetter_FailedEventIsReplayedOnceFixed(t *testing.T) {
+	bookingID := uuid.New()
+	p, err := payment.NewPayment(bookingID, uuid.New(), 10000, "MYR", 15.0, payment.PayoutFloors{})
+	require.NoError(t, err)
+	repo := &flakyPaymentRepo{failures: 100, payment: p}
+	topic := &dlqTopic{}
+	c := newTestConsumer(repo)
+	c.dlq = &DeadLetterQueue{topic: "booking.events.dlq", writer: topic}
+
+	msg := cancelledMessage(t, bookingID)
+	msg.Topic, msg.Key = events.TopicBookingEvents, []byte(bookingID.String())
+	msg.Headers = []kafkago.Header{{Key: "traceparent", Value: []byte("00-trace")}}
+	require.NoError(t, c.handleOrDeadLetter(context.Background(), msg), "a dead-lettered event is done with")
+
+	require.Len(t, topic.writes, 1)
+	dead := topic.writes[0]
+	assert.Equal(t, "booking.events.dlq", dead.Topic)
+	assert.Equal(t, msg.Key, dead.Key)
+	assert.Equal(t, msg.Value, dead.Value)
+	headers := tracing.KafkaHeaderCarrier(dead.Headers)
+	assert.Equal(t, events.TopicBookingEvents, headers.Get(DLQOriginalTopicHeader))
+	assert.Contains(t, headers.Get(DLQErrorHeader), "connection reset by peer")
+	assert.Equal(t, "00-trace", headers.Get("traceparent"))
+
+	// The outage is over.
+	repo.failures, repo.calls = 0, 0
+	result, err := newTestReplayer(c, topic).Replay(context.Background(), application.DLQReplayRequest{Type: events.BookingCancelled})
+	require.NoError(t, err)
+
+	assert.Equal(t, 1, result.Scanned)
+	assert.Equal(t, 1, result.Replayed)
+	require.Len(t, result.Events, 1)
+	assert.Equal(t, application.DLQEventReplayed, result.Events[0].Status)
+	assert.Equal(t, events.TopicBookingEvents, result.Events[0].Topic)
+	assert.Equal(t, events.BookingCancelled, result.Events[0].Type)
+	assert.Equal(t, 1, repo.calls, "replayed through the booking handler")
+	assert.Equal(t, []int64{0}, topic.commits)
+	assert.Len(t, topic.writes, 1, "a replayed event is not written back")
+}
+
+func TestDeadLetterReplay_WritesBackFailedAndOtherTypes(t *testing.T) {
+	bookingID := uuid.New()
+	p, err := payment.NewPayment(bookingID, uuid.New(), 10000, "MYR", 15.0, payment.PayoutFloors{})
+	require.NoError(t, err)
+	repo := &flakyPaymentRepo{failures: 100, payment: p}
+	topic := &dlqTopic{}
+	c := newTestConsumer(repo)
+	c.dlq = &DeadLetterQueue{topic: "booking.events.dlq", writer: topic}
+
+	created := createdMessage(t, BookingCreatedEvent{BookingID: uuid.New(), TotalCents: 4500, Currency: "MYR"})
+	created.Topic = events.TopicBookingEvents
+	require.NoError(t, c.dlq.Publish(context.Background(), created, assert.AnError))
+	cancelled := cancelledMessage(t, bookingID)
+	cancelled.Topic = events.TopicBookingEvents
+	require.NoError(t, c.dlq.Publish(context.Background(), cancelled, assert.AnError))
+
+	replayer := newTestReplayer(c, topic)
+	result, err := replayer.Replay(context.Background(), application.DLQReplayRequest{Type: events.BookingCancelled})
+	require.NoError(t, err)
+
+	assert.Equal(t, 2, result.Scanned, "stops at the first event it wrote back")
+	assert.Equal(t, 1, result.Skipped)
+	assert.Equal(t, 1, result.Failed)
+	assert.Equal(t, []int64{0, 1}, topic.commits)
+	require.Len(t, topic.writes, 4)
+	for _, m := range topic.writes[2:] {
+		headers := tracing.KafkaHeaderCarrier(m.Headers)
+		assert.Equal(t, events.TopicBookingEvents, headers.Get(DLQOriginalTopicHeader), "the original topic survives a write-back")
+	}
+	assert.Equal(t, assert.AnError.Error(), tracing.KafkaHeaderCarrier(topic.writes[2].Headers).Get(DLQErrorHeader), "a skipped event keeps its error")
+	assert.Contains(t, tracing.KafkaHeaderCarrier(topic.writes[3].Headers).Get(DLQErrorHeader), "connection reset by peer")
+
+	// The next replay starts at the event the last one stopped at.
+	repo.failures = 0
+	result, err = replayer.Replay(context.Background(), application.DLQReplayRequest{Type: events.BookingCancelled})
+	require.NoError(t, err)
+	assert.Equal(t, 2, result.Scanned)
+	assert.Equal(t, 1, result.Skipped)
+	assert.Equal(t, 1, result.Replayed)
+	assert.Equal(t, int64(3), result.Events[1].Offset)
+	assert.Equal(t, []int64{0, 1, 2, 3}, topic.commits)
+}
@@ -0,0 +1,120 @@
+package events
+
+import (
+	"context"
+	"hash/fnv"
+	"sync"
+
+	kafkago "github.com/segmentio/kafka-go"
+)
+
+// keyedDispatcher runs jobs on a fixed pool of workers. Jobs with the same key
+// always land on the same worker, so events for one booking are handled in
+// order while different bookings are handled in parallel.
+type keyedDispatcher struct {
+	queues []chan func()
+	wg     sync.WaitGroup
+}
+
+func newKeyedDispatcher(workers int) *keyedDispatcher {
+	if workers < 1 {
+		workers = 1
+	}
+	d := &keyedDispatcher{queues: make([]chan func(), workers)}
+	for i := range d.queues {
+		q := make(chan func(), 1)
+		d.queues[i] = q
+		d.wg.Add(1)
+		go func() {
+			defer d.wg.Done()
+			for job := range q {
+				job()
+			}
+		}()
+	}
+	return d
+}
+
+// submit queues job on the worker that owns key. It blocks while that worker is
+// busy and returns the context error if ctx is cancelled first.
+func (d *keyedDispatcher) submit(ctx context.Context, key string, job func()) error {
+	q := d.queues[workerIndex(key, len(d.queues))]
+
+	select {
+	case q <- job:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// workerIndex maps a key to one of n workers.
+func workerIndex(key string, n int) int {
+	h := fnv.New32a()
+	_, _ = h.Write([]byte(key))
+	return int(h.Sum32() % uint32(n))
+}
+
+// close stops accepting jobs and waits for queued jobs to finish.
+func (d *keyedDispatcher) close() {
+	for _, q := range d.queues {
+		close(q)
+	}
+	d.wg.Wait()
+}
+
+// commitTracker decides which offsets are safe to commit when messages from a
+// partition finish out of order. An offset is only committed once every
+// message fetched before it on the same partition has also finished.
+type commitTracker struct {
+	mu         sync.Mutex
+	partitions map[int]*partitionProgress
+}
+
+type partitionProgress struct {
+	inFlight []int64 // fetched offsets in fetch order, oldest first
+	done     map[int64]kafkago.Message
+}
+
+func newCommitTracker() *commitTracker {
+	return &commitTracker{partitions: make(map[int]*partitionProgress)}
+}
+
+// fetched records a message handed to a worker.
+func (t *commitTracker) fetched(msg kafkago.Message) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	p, ok := t.partitions[msg.Partition]
+	if !ok {
+		p = &partitionProgress{done: make(map[int64]kafkago.Message)}
+		t.partitions[msg.Partition] = p
+	}
+	p.inFlight = append(p.inFlight, msg.Offset)
+}
+
+// completed marks msg as finished and returns the newest message whose offset
+// can now be committed, or false if an older message is still running.
+func (t *commitTracker) completed(msg kafkago.Message) (kafkago.Message, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	p := t.partitions[msg.Partition]
+	if p == nil {
+		return kafkago.Message{}, false
+	}
+	p.done[msg.Offset] = msg
+
+	var commit kafkago.Message
+	ready := false
+	for len(p.inFlight) > 0 {
+		m, ok := p.done[p.inFlight[0]]
+		if !ok {
+			break
+		}
+		delete(p.done, p.inFlight[0])
+		p.inFlight = p.inFlight[1:]
+		commit, ready = m, true
+	}
+	return commit, ready
+}
@@ -0,0 +1,281 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Kilat-Pet-Delivery/lib-common/kafka"
+	"github.com/Kilat-Pet-Delivery/lib-proto/events"
+	"github.com/Kilat-Pet-Delivery/service-payment/internal/domain/booking"
+	"github.com/google/uuid"
+	kafkago "github.com/segmentio/kafka-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+// ---- fakes ----
+
+// chanReader serves messages from a channel and records commits.
+type chanReader struct {
+	msgs chan kafkago.Message
+
+	mu      sync.Mutex
+	commits []int64
+}
+
+func (r *chanReader) FetchMessage(ctx context.Context) (kafkago.Message, error) {
+	select {
+	case m := <-r.msgs:
+		return m, nil
+	case <-ctx.Done():
+		return kafkago.Message{}, ctx.Err()
+	}
+}
+
+func (r *chanReader) CommitMessages(_ context.Context, msgs ...kafkago.Message) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, m := range msgs {
+		r.commits = append(r.commits, m.Offset)
+	}
+	return nil
+}
+
+func (r *chanReader) Close() error { return nil }
+
+// barrierHandler blocks each cancellation until release is closed and
+// reports every booking that has entered the handler.
+type barrierHandler struct {
+	entered chan uuid.UUID
+	release chan struct{}
+}
+
+func (h *barrierHandler) HandleDeliveryConfirmed(_ context.Context, _ events.DeliveryConfirmedEvent) error {
+	return nil
+}
+
+func (h *barrierHandler) HandleBookingCancelled(_ context.Context, e events.BookingCancelledEvent) error {
+	h.entered <- e.BookingID
+	<-h.release
+	return nil
+}
+
+func (h *barrierHandler) HandleBookingExpired(_ context.Context, _ uuid.UUID) error {
+	return nil
+}
+
+func (h *barrierHandler) HandleBookingCreated(_ context.Context, _ booking.Total) error {
+	return nil
+}
+
+func (h *barrierHandler) HandleBookingUpdated(_ context.Context, _ string, _ uuid.UUID, _ int64, _ string) error {
+	return nil
+}
+
+// ---- tests ----
+
+// TestStartConcurrent_DifferentBookingsRunInParallel verifies that while one
+// booking's event is still being handled, another booking's event starts.
+func TestStartConcurrent_DifferentBookingsRunInParallel(t *testing.T) {
+	reader := &chanReader{msgs: make(chan kafkago.Message, 2)}
+	h := &barrierHandler{entered: make(chan uuid.UUID, 2), release: make(chan struct{})}
+	c := &BookingEventConsumer{
+		paymentService: h,
+		retryPolicy:    DefaultRetryPolicy(),
+		logger:         zap.NewNop(),
+		topics:         []string{events.TopicBookingEvents},
+		concurrency:    8,
+		newReader:      func(string) messageReader { return reader },
+	}
+
+	// Pick two bookings that hash to different workers.
+	first := uuid.New()
+	second := uuid.New()
+	for workerIndex(first.String(), c.concurrency) == workerIndex(second.String(), c.concurrency) {
+		second = uuid.New()
+	}
+	m1 := cancelledMessage(t, first)
+	m1.Offset = 0
+	m2 := cancelledMessage(t, second)
+	m2.Offset = 1
+	reader.msgs <- m1
+	reader.msgs <- m2
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- c.Start(ctx) }()
+
+	seen := map[uuid.UUID]bool{}
+	for i := 0; i < 2; i++ {
+		select {
+		case id := <-h.entered:
+			seen[id] = true
+		case <-time.After(5 * time.Second):
+			t.Fatal("second booking was not processed while the first was still running")
+		}
+	}
+	assert.True(t, seen[first] && seen[second])
+
+	close(h.release)
+	cancel()
+	require.NoError(t, <-done)
+
+	reader.mu.Lock()
+	defer reader.mu.Unlock()
+	require.NotEmpty(t, reader.commits)
+	assert.Equal(t, int64(1), reader.commits[len(reader.commits)-1])
+}
+
+// TestCommitTracker_WaitsForOlderOffsets verifies a later offset finishing
+// first is not committed until the earlier one on the same partition finishes.
+func TestCommitTracker_WaitsForOlderOffsets(t *testing.T) {
+	tr := newCommitTracker()
+	m0 := kafkago.Message{Partition: 0, Offset: 10}
+	m1 := kafkago.Message{Partition: 0, Offset: 11}
+	tr.fetched(m0)
+	tr.fetched(m1)
+
+	_, ok := tr.completed(m1)
+	assert.False(t, ok)
+
+	commit, ok := tr.completed(m0)
+	require.True(t, ok)
+	assert.Equal(t, int64(11), commit.Offset)
+}
+
+// expiredHandler records expired bookings and blocks cancellations until
+// release is closed.
+type expiredHandler struct {
+	barrierHandler
+	expired chan uuid.UUID
+}
+
+func (h *expiredHandler) HandleBookingExpired(_ context.Context, bookingID uuid.UUID) error {
+	h.expired <- bookingID
+	return nil
+}
+
+// TestStart_ConsumesTopicsIndependently verifies that with two topics, a
+// handler blocked on one topic neither stops the other topic's events from
+// being handled nor its offsets from being committed.
+func TestStart_ConsumesTopicsIndependently(t *testing.T) {
+	readers := map[string]*chanReader{
+		"booking.events":    {msgs: make(chan kafkago.Message, 1)},
+		"booking.lifecycle": {msgs: make(chan kafkago.Message, 1)},
+	}
+	h := &expiredHandler{
+		barrierHandler: barrierHandler{entered: make(chan uuid.UUID, 1), release: make(chan struct{})},
+		expired:        make(chan uuid.UUID, 1),
+	}
+	c := &BookingEventConsumer{
+		topics:         []string{"booking.events", "booking.lifecycle"},
+		paymentService: h,
+		retryPolicy:    DefaultRetryPolicy(),
+		logger:         zap.NewNop(),
+		concurrency:    2,
+		newReader:      func(topic string) messageReader { return readers[topic] },
+	}
+
+	slow := cancelledMessage(t, uuid.New())
+	slow.Topic, slow.Offset = "booking.events", 7
+	readers["booking.events"].msgs <- slow
+
+	expiredBooking := uuid.New()
+	ce, err := kafka.NewCloudEvent("service-booking", BookingExpired, BookingExpiredEvent{BookingID: expiredBooking})
+	require.NoError(t, err)
+	raw, err := json.Marshal(ce)
+	require.NoError(t, err)
+	readers["booking.lifecycle"].msgs <- kafkago.Message{Topic: "booking.lifecycle", Offset: 3, Value: raw}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- c.Start(ctx) }()
+
+	<-h.entered
+	select {
+	case id := <-h.expired:
+		assert.Equal(t, expiredBooking, id)
+	case <-time.After(5 * time.Second):
+		t.Fatal("the second topic was starved by the blocked handler on the first")
+	}
+	assert.Eventually(t, func() bool {
+		r := readers["booking.lifecycle"]
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		return len(r.commits) == 1 && r.commits[0] == 3
+	}, 5*time.Second, 10*time.Millisecond)
+
+	blocked := readers["booking.events"]
+	blocked.mu.Lock()
+	assert.Empty(t, blocked.commits, "the blocked topic has nothing to commit yet")
+	blocked.mu.Unlock()
+
+	close(h.release)
+	cancel()
+	require.NoError(t, <-done)
+
+	blocked.mu.Lock()
+	defer blocked.mu.Unlock()
+	assert.Equal(t, []int64{7}, blocked.commits)
+}
+
+// panickingHandler panics on cancellations of one booking, like a handler
+// meeting a payload it does not expect, and records every other cancellation.
+type panickingHandler struct {
+	barrierHandler
+	poison    uuid.UUID
+	cancelled chan uuid.UUID
+}
+
+func (h *panickingHandler) HandleBookingCancelled(_ context.Context, e events.BookingCancelledEvent) error {
+	if e.BookingID == h.poison {
+		var missing map[string]int
+		missing["boom"]++ // nil map write
+	}
+	h.cancelled <- e.BookingID
+	return nil
+}
+
+func TestStartConcurrent_RecoversFromHandlerPanic(t *testing.T) {
+	reader := &chanReader{msgs: make(chan kafkago.Message, 2)}
+	h := &panickingHandler{poison: uuid.New(), cancelled: make(chan uuid.UUID, 1)}
+	c := &BookingEventConsumer{
+		topics:         []string{"booking.events"},
+		paymentService: h,
+		retryPolicy:    DefaultRetryPolicy(),
+		logger:         zap.NewNop(),
+		concurrency:    2,
+		newReader:      func(string) messageReader { return reader },
+	}
+
+	poison := cancelledMessage(t, h.poison)
+	poison.Offset = 1
+	healthyBooking := uuid.New()
+	healthy := cancelledMessage(t, healthyBooking)
+	healthy.Offset = 2
+	reader.msgs <- poison
+	reader.msgs <- healthy
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- c.Start(ctx) }()
+
+	select {
+	case id := <-h.cancelled:
+		assert.Equal(t, healthyBooking, id)
+	case <-time.After(5 * time.Second):
+		t.Fatal("the consumer stopped after a handler panicked")
+	}
+	assert.Eventually(t, func() bool {
+		reader.mu.Lock()
+		defer reader.mu.Unlock()
+		return len(reader.commits) > 0 && reader.commits[len(reader.commits)-1] == 2
+	}, 5*time.Second, 10*time.Millisecond, "the panicking message is committed, not redelivered forever")
+
+	cancel()
+	require.NoError(t, <-done)
+}
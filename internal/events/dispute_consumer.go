@@ -0,0 +1,142 @@
+package events
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"github.com/Kilat-Pet-Delivery/lib-common/kafka"
+	"github.com/Kilat-Pet-Delivery/service-payment/internal/domain/payment"
+	"github.com/Kilat-Pet-Delivery/service-payment/internal/tracing"
+	"github.com/google/uuid"
+	kafkago "github.com/segmentio/kafka-go"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+)
+
+// PaymentDisputeRequested is the CloudEvent type payments ops publishes when
+// a dispute is raised outside Stripe webhooks.
+const PaymentDisputeRequested = "payment.dispute_requested"
+
+// PaymentDisputeRequestedEvent is the payload of a PaymentDisputeRequested
+// event. It is defined here until the contract is added to lib-proto.
+type PaymentDisputeRequestedEvent struct {
+	PaymentID uuid.UUID `json:"payment_id"`
+	// DisputeID is the ops or provider reference for the dispute, if any.
+	DisputeID string `json:"dispute_id,omitempty"`
+	Reason    string `json:"reason"`
+	// DecidedForOwner is set when the dispute is already decided in the
+	// owner's favour, e.g. a lost chargeback: the payment is refunded, and a
+	// payout already made to the runner is clawed back.
+	DecidedForOwner bool `json:"decided_for_owner,omitempty"`
+}
+
+// disputeHandler is the subset of PaymentService the dispute consumer calls.
+type disputeHandler interface {
+	OpenDispute(ctx context.Context, paymentID uuid.UUID, reason string) error
+	DecideDisputeForOwner(ctx context.Context, paymentID uuid.UUID, reason string) error
+}
+
+// DisputeConsumer opens payment disputes requested on the payments-ops topic.
+type DisputeConsumer struct {
+	consumer    *kafka.Consumer
+	topic       string
+	disputes    disputeHandler
+	retryPolicy RetryPolicy
+	logger      *zap.Logger
+}
+
+// NewDisputeConsumer creates a consumer for PaymentDisputeRequested events on topic.
+func NewDisputeConsumer(
+	brokers []string,
+	groupID string,
+	topic string,
+	disputes disputeHandler,
+	logger *zap.Logger,
+) *DisputeConsumer {
+	return &DisputeConsumer{
+		consumer:    kafka.NewConsumer(brokers, groupID, topic, logger),
+		topic:       topic,
+		disputes:    disputes,
+		retryPolicy: DefaultRetryPolicy(),
+		logger:      logger,
+	}
+}
+
+// Start begins consuming payments-ops events. It blocks until the context is cancelled.
+func (c *DisputeConsumer) Start(ctx context.Context) error {
+	return c.consumer.Consume(ctx, c.handleMessage)
+}
+
+// handleMessage opens the dispute carried by a PaymentDisputeRequested event,
+// settles it for the owner if it was already decided, and ignores every other
+// event type on the topic.
+func (c *DisputeConsumer) handleMessage(ctx context.Context, msg kafkago.Message) error {
+	ctx = otel.GetTextMapPropagator().Extract(ctx, tracing.KafkaHeaderCarrier(msg.Headers))
+	ctx, span := tracer.Start(ctx, c.topic+" process",
+		trace.WithSpanKind(trace.SpanKindConsumer),
+		trace.WithAttributes(
+			attribute.String("messaging.destination.name", msg.Topic),
+			attribute.Int("messaging.kafka.partition", msg.Partition),
+			attribute.Int64("messaging.kafka.offset", msg.Offset),
+		),
+	)
+	defer span.End()
+	ctx = payment.WithActor(ctx, "system:payments-ops")
+
+	ce, err := kafka.ParseCloudEvent(msg.Value)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "unparseable event")
+		c.logger.Error("failed to parse cloud event from payments-ops topic",
+			zap.Error(err),
+			zap.String("raw", string(msg.Value)),
+		)
+		return permanent(err)
+	}
+
+	span.SetAttributes(attribute.String("cloudevents.event_type", ce.Type))
+	if !strings.EqualFold(ce.Type, PaymentDisputeRequested) {
+		c.logger.Debug("ignoring unhandled payments-ops event type", zap.String("type", ce.Type))
+		return nil
+	}
+
+	var event PaymentDisputeRequestedEvent
+	if err := ce.ParseData(&event); err != nil {
+		c.logger.Error("failed to parse PaymentDisputeRequestedEvent data", zap.Error(err))
+		return permanent(err)
+	}
+	if event.PaymentID == uuid.Nil {
+		c.logger.Error("PaymentDisputeRequestedEvent is missing payment ID", zap.String("id", ce.ID))
+		return permanent(errors.New("dispute event is missing payment_id"))
+	}
+
+	reason := event.Reason
+	if event.DisputeID != "" {
+		reason += " (dispute " + event.DisputeID + ")"
+	}
+
+	c.logger.Info("received payment dispute request",
+		zap.String("payment_id", event.PaymentID.String()),
+		zap.String("dispute_id", event.DisputeID),
+		zap.Bool("decided_for_owner", event.DecidedForOwner),
+		zap.String("id", ce.ID),
+	)
+	return retryWithBackoff(ctx, c.retryPolicy, c.logger, ce.Type, func(ctx context.Context) error {
+		if err := c.disputes.OpenDispute(ctx, event.PaymentID, reason); err != nil {
+			return err
+		}
+		if !event.DecidedForOwner {
+			return nil
+		}
+		return c.disputes.DecideDisputeForOwner(ctx, event.PaymentID, reason)
+	})
+}
+
+// Close closes the underlying Kafka consumer.
+func (c *DisputeConsumer) Close() error {
+	return c.consumer.Close()
+}
@@ -0,0 +1,101 @@
+package events
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+type recordedDispute struct {
+	paymentID uuid.UUID
+	reason    string
+}
+
+type recordingDisputeHandler struct {
+	disputes  []recordedDispute
+	decisions []recordedDispute
+}
+
+func (h *recordingDisputeHandler) OpenDispute(_ context.Context, paymentID uuid.UUID, reason string) error {
+	h.disputes = append(h.disputes, recordedDispute{paymentID: paymentID, reason: reason})
+	return nil
+}
+
+func (h *recordingDisputeHandler) DecideDisputeForOwner(_ context.Context, paymentID uuid.UUID, reason string) error {
+	h.decisions = append(h.decisions, recordedDispute{paymentID: paymentID, reason: reason})
+	return nil
+}
+
+func newTestDisputeConsumer(h disputeHandler) *DisputeConsumer {
+	return &DisputeConsumer{
+		topic:    "payments.ops",
+		disputes: h,
+		retryPolicy: RetryPolicy{
+			MaxAttempts:    2,
+			InitialBackoff: time.Millisecond,
+			MaxBackoff:     time.Millisecond,
+		},
+		logger: zap.NewNop(),
+	}
+}
+
+func TestDisputeConsumer_OpensDispute(t *testing.T) {
+	h := &recordingDisputeHandler{}
+	c := newTestDisputeConsumer(h)
+	paymentID := uuid.New()
+
+	msg := runnerEventMessage(t, PaymentDisputeRequested, PaymentDisputeRequestedEvent{
+		PaymentID: paymentID,
+		DisputeID: "dp_123",
+		Reason:    "item not received",
+	})
+	require.NoError(t, c.handleMessage(context.Background(), msg))
+
+	require.Len(t, h.disputes, 1)
+	assert.Equal(t, paymentID, h.disputes[0].paymentID)
+	assert.Equal(t, "item not received (dispute dp_123)", h.disputes[0].reason)
+	assert.Empty(t, h.decisions, "an open dispute claws nothing back")
+}
+
+func TestDisputeConsumer_SettlesDisputeDecidedForOwner(t *testing.T) {
+	h := &recordingDisputeHandler{}
+	c := newTestDisputeConsumer(h)
+	paymentID := uuid.New()
+
+	msg := runnerEventMessage(t, PaymentDisputeRequested, PaymentDisputeRequestedEvent{
+		PaymentID:       paymentID,
+		Reason:          "chargeback lost",
+		DecidedForOwner: true,
+	})
+	require.NoError(t, c.handleMessage(context.Background(), msg))
+
+	require.Len(t, h.disputes, 1, "the dispute is opened first")
+	require.Len(t, h.decisions, 1)
+	assert.Equal(t, paymentID, h.decisions[0].paymentID)
+	assert.Equal(t, "chargeback lost", h.decisions[0].reason)
+}
+
+func TestDisputeConsumer_IgnoresOtherEventTypes(t *testing.T) {
+	h := &recordingDisputeHandler{}
+	c := newTestDisputeConsumer(h)
+
+	msg := runnerEventMessage(t, "payment.payout_reviewed", map[string]string{"payment_id": uuid.NewString()})
+	require.NoError(t, c.handleMessage(context.Background(), msg))
+	assert.Empty(t, h.disputes)
+}
+
+func TestDisputeConsumer_MissingPaymentIDIsPermanent(t *testing.T) {
+	h := &recordingDisputeHandler{}
+	c := newTestDisputeConsumer(h)
+
+	msg := runnerEventMessage(t, PaymentDisputeRequested, PaymentDisputeRequestedEvent{Reason: "fraud"})
+	err := c.handleMessage(context.Background(), msg)
+	require.Error(t, err)
+	assert.False(t, isRetryable(err))
+	assert.Empty(t, h.disputes)
+}
@@ -0,0 +1,180 @@
+package events
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/Kilat-Pet-Delivery/lib-common/kafka"
+	"github.com/Kilat-Pet-Delivery/service-payment/internal/application"
+	"github.com/Kilat-Pet-Delivery/service-payment/internal/tracing"
+	"github.com/google/uuid"
+	kafkago "github.com/segmentio/kafka-go"
+	"go.uber.org/zap"
+)
+
+// dlqIdleTimeout is how long a replay waits for the next dead-lettered event
+// before treating the DLQ as drained. It also covers joining the replay
+// consumer group on the first fetch.
+const dlqIdleTimeout = 10 * time.Second
+
+// DeadLetterReplayer replays dead-lettered booking events once the bug that
+// failed them is fixed. It reads the DLQ with its own consumer group, so
+// offsets record which events have been dealt with.
+type DeadLetterReplayer struct {
+	consumer    *BookingEventConsumer
+	dlq         *DeadLetterQueue
+	newReader   func() messageReader
+	idleTimeout time.Duration
+	logger      *zap.Logger
+
+	running atomic.Bool
+}
+
+// NewDeadLetterReplayer creates a replayer that reads dlq as consumer group
+// groupID and handles events with consumer.
+func NewDeadLetterReplayer(brokers []string, groupID string, consumer *BookingEventConsumer, dlq *DeadLetterQueue, logger *zap.Logger) *DeadLetterReplayer {
+	return &DeadLetterReplayer{
+		consumer: consumer,
+		dlq:      dlq,
+		newReader: func() messageReader {
+			return kafkago.NewReader(kafkago.ReaderConfig{
+				Brokers:     brokers,
+				GroupID:     groupID,
+				Topic:       dlq.Topic(),
+				StartOffset: kafkago.FirstOffset,
+			})
+		},
+		idleTimeout: dlqIdleTimeout,
+		logger:      logger,
+	}
+}
+
+// Replay reads up to req.Limit events from the DLQ and handles those of type
+// req.Type (every type when empty) again, through the same handlers and
+// retries as live consumption. The payment workflows skip events whose effect
+// is already applied, so replaying an event twice does not apply it twice.
+// Events that fail again, and events of other types, are written back to the
+// end of the DLQ. Every event read is committed, so the next replay continues
+// after it. A replay stops early once the DLQ is drained or it reaches an
+// event it wrote back itself.
+func (r *DeadLetterReplayer) Replay(ctx context.Context, req application.DLQReplayRequest) (*application.DLQReplayResultDTO, error) {
+	if err := req.Validate(); err != nil {
+		return nil, err
+	}
+	if !r.running.CompareAndSwap(false, true) {
+		return nil, application.ErrDLQReplayInProgress
+	}
+	defer r.running.Store(false)
+
+	reader := r.newReader()
+	defer func() {
+		if err := reader.Close(); err != nil {
+			r.logger.Warn("failed to close dead-letter reader", zap.Error(err))
+		}
+	}()
+
+	runID := uuid.NewString()
+	result := &application.DLQReplayResultDTO{Type: req.Type, Events: []application.DLQEventDTO{}}
+	for result.Scanned < req.Limit {
+		fetchCtx, cancel := context.WithTimeout(ctx, r.idleTimeout)
+		msg, err := reader.FetchMessage(fetchCtx)
+		cancel()
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
+			if errors.Is(err, context.DeadlineExceeded) {
+				break
+			}
+			return nil, fmt.Errorf("failed to read dead-letter queue: %w", err)
+		}
+		// Left uncommitted, so the next replay starts with it.
+		if tracing.KafkaHeaderCarrier(msg.Headers).Get(dlqReplayRunHeader) == runID {
+			break
+		}
+
+		result.Scanned++
+		event, err := r.replayOne(ctx, msg, req.Type, runID)
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		result.Events = append(result.Events, event)
+		if err != nil {
+			// Not committed: the event is neither handled nor back on the DLQ.
+			r.logger.Error("failed to write dead-lettered event back, stopping replay",
+				zap.Int("partition", msg.Partition),
+				zap.Int64("offset", msg.Offset),
+				zap.Error(err),
+			)
+			break
+		}
+		switch event.Status {
+		case application.DLQEventReplayed:
+			result.Replayed++
+		case application.DLQEventFailed:
+			result.Failed++
+		case application.DLQEventSkipped:
+			result.Skipped++
+		}
+
+		commitCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), commitTimeout)
+		if err := reader.CommitMessages(commitCtx, msg); err != nil {
+			r.logger.Warn("failed to commit dead-letter offset",
+				zap.Int("partition", msg.Partition),
+				zap.Int64("offset", msg.Offset),
+				zap.Error(err),
+			)
+		}
+		cancel()
+	}
+
+	r.logger.Info("dead-lettered booking events replayed",
+		zap.String("run_id", runID),
+		zap.String("type", req.Type),
+		zap.Int("scanned", result.Scanned),
+		zap.Int("replayed", result.Replayed),
+		zap.Int("failed", result.Failed),
+		zap.Int("skipped", result.Skipped),
+	)
+	return result, nil
+}
+
+// replayOne handles one dead-lettered msg, or skips it if it is not of
+// eventType, and writes it back to the DLQ unless it was handled. The error
+// is that of the write-back.
+func (r *DeadLetterReplayer) replayOne(ctx context.Context, msg kafkago.Message, eventType, runID string) (application.DLQEventDTO, error) {
+	original := originalMessage(msg)
+	event := application.DLQEventDTO{Topic: original.Topic, Partition: msg.Partition, Offset: msg.Offset}
+	if ce, err := kafka.ParseCloudEvent(msg.Value); err == nil {
+		event.EventID, event.Type = ce.ID, ce.Type
+	}
+
+	if eventType != "" && !strings.EqualFold(event.Type, eventType) {
+		event.Status = application.DLQEventSkipped
+		reason := tracing.KafkaHeaderCarrier(msg.Headers).Get(DLQErrorHeader)
+		return event, r.dlq.write(ctx, msg, reason, runID)
+	}
+
+	if err := r.consumer.handleMessage(ctx, original); err != nil {
+		event.Status = application.DLQEventFailed
+		event.Error = err.Error()
+		r.logger.Warn("dead-lettered booking event failed again",
+			zap.String("event_id", event.EventID),
+			zap.String("type", event.Type),
+			zap.Error(err),
+		)
+		return event, r.dlq.write(ctx, msg, err.Error(), runID)
+	}
+
+	event.Status = application.DLQEventReplayed
+	r.logger.Info("dead-lettered booking event replayed",
+		zap.String("event_id", event.EventID),
+		zap.String("type", event.Type),
+		zap.String("topic", event.Topic),
+	)
+	return event, nil
+}
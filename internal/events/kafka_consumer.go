@@ -2,54 +2,425 @@ package events
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/Kilat-Pet-Delivery/lib-common/kafka"
 	"github.com/Kilat-Pet-Delivery/lib-proto/events"
 	"github.com/Kilat-Pet-Delivery/service-payment/internal/application"
+	"github.com/Kilat-Pet-Delivery/service-payment/internal/domain/booking"
+	"github.com/Kilat-Pet-Delivery/service-payment/internal/domain/payment"
+	"github.com/Kilat-Pet-Delivery/service-payment/internal/tracing"
+	"github.com/google/uuid"
 	kafkago "github.com/segmentio/kafka-go"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/metric/noop"
+	"go.opentelemetry.io/otel/trace"
 	"go.uber.org/zap"
 )
 
-// BookingEventConsumer listens to booking events and triggers payment workflows.
+var tracer = otel.Tracer("github.com/Kilat-Pet-Delivery/service-payment/internal/events")
+
+// BookingExpired is the CloudEvent type the booking service publishes when a
+// booking lapses without being delivered.
+const BookingExpired = "booking.expired"
+
+// BookingExpiredEvent is the payload of a BookingExpired event. It is defined
+// here until the contract is added to lib-proto.
+type BookingExpiredEvent struct {
+	BookingID     uuid.UUID
+	BookingNumber string
+	ExpiredAt     time.Time
+	OccurredAt    time.Time
+}
+
+// BookingCreated is the CloudEvent type the booking service publishes when a
+// booking is created.
+const BookingCreated = "booking.created"
+
+// BookingCreatedEvent is the payload of a BookingCreated event. It is defined
+// here until the contract is added to lib-proto.
+type BookingCreatedEvent struct {
+	BookingID     uuid.UUID
+	BookingNumber string
+	OwnerID       uuid.UUID
+	TotalCents    int64
+	Currency      string
+	OccurredAt    time.Time
+}
+
+// BookingUpdated is the CloudEvent type the booking service publishes when a
+// booking's details change, including its price before delivery.
+const BookingUpdated = "booking.updated"
+
+// BookingUpdatedEvent is the payload of a BookingUpdated event. TotalCents is
+// the booking's total after the change. It is defined here until the contract
+// is added to lib-proto.
+type BookingUpdatedEvent struct {
+	BookingID     uuid.UUID
+	BookingNumber string
+	TotalCents    int64
+	Currency      string
+	OccurredAt    time.Time
+}
+
+// bookingEventHandler is the subset of PaymentService the consumer dispatches to.
+type bookingEventHandler interface {
+	HandleDeliveryConfirmed(ctx context.Context, event events.DeliveryConfirmedEvent) error
+	HandleBookingCancelled(ctx context.Context, event events.BookingCancelledEvent) error
+	HandleBookingExpired(ctx context.Context, bookingID uuid.UUID) error
+	HandleBookingCreated(ctx context.Context, total booking.Total) error
+	HandleBookingUpdated(ctx context.Context, eventID string, bookingID uuid.UUID, totalCents int64, currency string) error
+}
+
+// commitTimeout bounds an offset commit issued after a message has been handled.
+const commitTimeout = 5 * time.Second
+
+// messageReader is the subset of kafkago.Reader used by the concurrent consumer.
+type messageReader interface {
+	FetchMessage(ctx context.Context) (kafkago.Message, error)
+	CommitMessages(ctx context.Context, msgs ...kafkago.Message) error
+	Close() error
+}
+
+// BookingEventConsumer listens to booking events on one or more topics and
+// triggers payment workflows. Each topic has its own reader, so offsets are
+// committed per topic and a slow topic cannot hold up the others; events are
+// routed by type whichever topic they arrive on.
 type BookingEventConsumer struct {
-	consumer       *kafka.Consumer
-	paymentService *application.PaymentService
+	topics         []string
+	consumers      map[string]*kafka.Consumer
+	paymentService bookingEventHandler
+	retryPolicy    RetryPolicy
 	logger         *zap.Logger
+
+	// dlq receives events that still fail after retries; without one they
+	// are only logged.
+	dlq *DeadLetterQueue
+
+	// eventTypes, when non-nil, holds the lower-cased types of the events
+	// the consumer handles; events of other types are skipped and counted
+	// in disabledEvents.
+	eventTypes     map[string]bool
+	disabledEvents metric.Int64Counter
+
+	// concurrency > 1 enables a worker pool per topic; newReader builds the
+	// reader for a topic.
+	concurrency int
+	newReader   func(topic string) messageReader
+
+	mu      sync.Mutex
+	readers []messageReader
 }
 
-// NewBookingEventConsumer creates a new consumer for booking events.
+// NewBookingEventConsumer creates a new consumer for booking events on topics.
+// concurrency is the number of bookings processed in parallel per topic; 1
+// keeps the original serial behaviour. startOffset (kafkago.FirstOffset or
+// kafkago.LastOffset) applies when the group has no committed offset; the
+// serial consumer from lib-common always starts from the earliest offset.
+// Events that still fail after retries are written to dlq.
 func NewBookingEventConsumer(
 	brokers []string,
 	groupID string,
+	topics []string,
 	paymentService *application.PaymentService,
+	dlq *DeadLetterQueue,
+	concurrency int,
+	startOffset int64,
 	logger *zap.Logger,
 ) *BookingEventConsumer {
-	consumer := kafka.NewConsumer(brokers, groupID, events.TopicBookingEvents, logger)
+	consumers := make(map[string]*kafka.Consumer, len(topics))
+	for _, topic := range topics {
+		consumers[topic] = kafka.NewConsumer(brokers, groupID, topic, logger)
+	}
 	return &BookingEventConsumer{
-		consumer:       consumer,
+		topics:         topics,
+		consumers:      consumers,
 		paymentService: paymentService,
+		retryPolicy:    DefaultRetryPolicy(),
 		logger:         logger,
+		dlq:            dlq,
+		concurrency:    concurrency,
+		newReader: func(topic string) messageReader {
+			return kafkago.NewReader(kafkago.ReaderConfig{
+				Brokers:     brokers,
+				GroupID:     groupID,
+				Topic:       topic,
+				StartOffset: startOffset,
+			})
+		},
 	}
 }
 
-// Start begins consuming booking events. It blocks until the context is cancelled.
+// SetEventTypes limits the booking events the consumer handles to those whose
+// type is in types, matched case-insensitively. Events of other types are
+// committed without being handled or logged and are counted in the
+// booking.events.disabled metric. An empty types handles every event, as
+// without the call.
+func (c *BookingEventConsumer) SetEventTypes(types []string) {
+	c.eventTypes = nil
+	for _, t := range types {
+		if t = strings.ToLower(strings.TrimSpace(t)); t != "" {
+			if c.eventTypes == nil {
+				c.eventTypes = make(map[string]bool, len(types))
+			}
+			c.eventTypes[t] = true
+		}
+	}
+	if c.eventTypes == nil {
+		return
+	}
+
+	counter, err := otel.Meter("github.com/Kilat-Pet-Delivery/service-payment/internal/events").Int64Counter("booking.events.disabled",
+		metric.WithDescription("Booking events skipped because their type is not in the consumer's allowlist."),
+	)
+	if err != nil {
+		c.logger.Warn("failed to register disabled booking events counter", zap.Error(err))
+		counter = noop.Int64Counter{}
+	}
+	c.disabledEvents = counter
+}
+
+// Start begins consuming every topic. It blocks until the context is cancelled
+// or one topic fails, in which case the others are stopped and the first
+// error is returned.
 func (c *BookingEventConsumer) Start(ctx context.Context) error {
-	return c.consumer.Consume(ctx, c.handleMessage)
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	errs := make(chan error, len(c.topics))
+	for _, topic := range c.topics {
+		go func() {
+			err := c.consumeTopic(ctx, topic)
+			if err != nil {
+				err = fmt.Errorf("consume %s: %w", topic, err)
+				cancel()
+			}
+			errs <- err
+		}()
+	}
+
+	var first error
+	for range c.topics {
+		if err := <-errs; err != nil && first == nil {
+			first = err
+		}
+	}
+	return first
+}
+
+// consumeTopic consumes one topic until ctx is cancelled.
+func (c *BookingEventConsumer) consumeTopic(ctx context.Context, topic string) error {
+	if c.concurrency <= 1 {
+		return c.consumers[topic].Consume(ctx, c.handleOrDeadLetter)
+	}
+	return c.startConcurrent(ctx, topic)
+}
+
+// startConcurrent fetches messages from topic and hands them to a keyed worker
+// pool so different bookings are processed in parallel while events for the
+// same booking stay in order. Offsets are committed manually, and only once
+// every earlier message on the partition has finished.
+func (c *BookingEventConsumer) startConcurrent(ctx context.Context, topic string) error {
+	reader := c.newReader(topic)
+	c.mu.Lock()
+	c.readers = append(c.readers, reader)
+	c.mu.Unlock()
+
+	pool := newKeyedDispatcher(c.concurrency)
+	defer pool.close()
+	tracker := newCommitTracker()
+
+	for {
+		msg, err := reader.FetchMessage(ctx)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return err
+		}
+
+		tracker.fetched(msg)
+		if err := pool.submit(ctx, c.orderingKey(msg), func() {
+			c.processAndCommit(ctx, reader, tracker, msg)
+		}); err != nil {
+			return nil
+		}
+	}
 }
 
-// handleMessage routes incoming Kafka messages to the appropriate handler.
-func (c *BookingEventConsumer) handleMessage(ctx context.Context, msg kafkago.Message) error {
+// processAndCommit handles one message and commits the newest safe offset.
+// A message that still fails after retries is dead-lettered and committed so
+// a single bad event cannot stall its partition.
+func (c *BookingEventConsumer) processAndCommit(ctx context.Context, reader messageReader, tracker *commitTracker, msg kafkago.Message) {
+	_ = c.handleOrDeadLetter(ctx, msg)
+
+	commit, ok := tracker.completed(msg)
+	if !ok {
+		return
+	}
+
+	// Commit even during shutdown so finished work is not redelivered.
+	commitCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), commitTimeout)
+	defer cancel()
+	if err := reader.CommitMessages(commitCtx, commit); err != nil {
+		c.logger.Warn("failed to commit booking event offset",
+			zap.String("topic", commit.Topic),
+			zap.Int("partition", commit.Partition),
+			zap.Int64("offset", commit.Offset),
+			zap.Error(err),
+		)
+	}
+}
+
+// orderingKey returns the booking ID of the event so all events for a booking
+// are handled by the same worker. Unparseable or unknown events fall back to
+// their partition, which they are harmless to share.
+func (c *BookingEventConsumer) orderingKey(msg kafkago.Message) string {
+	fallback := fmt.Sprintf("partition-%d", msg.Partition)
+
+	ce, err := kafka.ParseCloudEvent(msg.Value)
+	if err != nil {
+		return fallback
+	}
+
+	switch {
+	case strings.EqualFold(ce.Type, events.BookingDeliveryConfirmed):
+		var event events.DeliveryConfirmedEvent
+		if ce.ParseData(&event) == nil {
+			return event.BookingID.String()
+		}
+	case strings.EqualFold(ce.Type, events.BookingCancelled):
+		var event events.BookingCancelledEvent
+		if ce.ParseData(&event) == nil {
+			return event.BookingID.String()
+		}
+	case strings.EqualFold(ce.Type, BookingExpired):
+		var event BookingExpiredEvent
+		if ce.ParseData(&event) == nil {
+			return event.BookingID.String()
+		}
+	case strings.EqualFold(ce.Type, BookingCreated):
+		var event BookingCreatedEvent
+		if ce.ParseData(&event) == nil {
+			return event.BookingID.String()
+		}
+	case strings.EqualFold(ce.Type, BookingUpdated):
+		var event BookingUpdatedEvent
+		if ce.ParseData(&event) == nil {
+			return event.BookingID.String()
+		}
+	}
+	return fallback
+}
+
+// handleOrDeadLetter handles msg and writes it to the DLQ if it still fails
+// after retries. It returns nil once the message is dead-lettered, and the
+// handling error when there is no DLQ, the write failed or ctx was cancelled
+// mid-handling, which is shutdown rather than a bad event.
+func (c *BookingEventConsumer) handleOrDeadLetter(ctx context.Context, msg kafkago.Message) error {
+	err := c.handleMessage(ctx, msg)
+	if err == nil {
+		return nil
+	}
+	c.logger.Error("failed to handle booking event",
+		zap.String("topic", msg.Topic),
+		zap.Int("partition", msg.Partition),
+		zap.Int64("offset", msg.Offset),
+		zap.Error(err),
+	)
+	if c.dlq == nil || ctx.Err() != nil {
+		return err
+	}
+
+	dlqCtx, cancel := context.WithTimeout(ctx, commitTimeout)
+	defer cancel()
+	if dlqErr := c.dlq.Publish(dlqCtx, msg, err); dlqErr != nil {
+		c.logger.Error("failed to dead-letter booking event",
+			zap.String("topic", msg.Topic),
+			zap.Int("partition", msg.Partition),
+			zap.Int64("offset", msg.Offset),
+			zap.Error(dlqErr),
+		)
+		return err
+	}
+	c.logger.Warn("dead-lettered booking event",
+		zap.String("topic", msg.Topic),
+		zap.Int("partition", msg.Partition),
+		zap.Int64("offset", msg.Offset),
+		zap.String("dlq_topic", c.dlq.Topic()),
+	)
+	return nil
+}
+
+// errHandlerPanicked reports a booking event whose handling panicked.
+var errHandlerPanicked = errors.New("booking event handler panicked")
+
+// handleMessage handles one Kafka message. A panic while handling it is
+// recovered, logged with the raw message and returned as a permanent error,
+// so the message is dead-lettered and committed like any other failure
+// instead of killing the consumer.
+func (c *BookingEventConsumer) handleMessage(ctx context.Context, msg kafkago.Message) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			c.logger.Error("recovered from panic handling booking event",
+				zap.String("topic", msg.Topic),
+				zap.Int("partition", msg.Partition),
+				zap.Int64("offset", msg.Offset),
+				zap.Any("panic", r),
+				zap.String("raw", string(msg.Value)),
+				zap.Stack("stack"),
+			)
+			err = permanent(fmt.Errorf("%w: %v", errHandlerPanicked, r))
+		}
+	}()
+	return c.routeMessage(ctx, msg)
+}
+
+// routeMessage routes incoming Kafka messages to the appropriate handler.
+// Parse errors are returned immediately; handler errors are retried with
+// backoff when they look transient.
+func (c *BookingEventConsumer) routeMessage(ctx context.Context, msg kafkago.Message) error {
+	ctx = otel.GetTextMapPropagator().Extract(ctx, tracing.KafkaHeaderCarrier(msg.Headers))
+	ctx, span := tracer.Start(ctx, msg.Topic+" process",
+		trace.WithSpanKind(trace.SpanKindConsumer),
+		trace.WithAttributes(
+			attribute.String("messaging.destination.name", msg.Topic),
+			attribute.Int("messaging.kafka.partition", msg.Partition),
+			attribute.Int64("messaging.kafka.offset", msg.Offset),
+		),
+	)
+	defer span.End()
+	ctx = payment.WithActor(ctx, "system:booking-events")
+
 	cloudEvent, err := kafka.ParseCloudEvent(msg.Value)
 	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "unparseable event")
 		c.logger.Error("failed to parse cloud event from booking topic",
+			zap.String("topic", msg.Topic),
 			zap.Error(err),
 			zap.String("raw", string(msg.Value)),
 		)
-		return err
+		return permanent(err)
 	}
 
+	span.SetAttributes(attribute.String("cloudevents.event_type", cloudEvent.Type))
+	if c.eventTypes != nil && !c.eventTypes[strings.ToLower(cloudEvent.Type)] {
+		c.disabledEvents.Add(ctx, 1, metric.WithAttributes(
+			attribute.String("topic", msg.Topic),
+			attribute.String("type", cloudEvent.Type),
+		))
+		return nil
+	}
 	c.logger.Info("received booking event",
+		zap.String("topic", msg.Topic),
 		zap.String("type", cloudEvent.Type),
 		zap.String("id", cloudEvent.ID),
 	)
@@ -61,7 +432,22 @@ func (c *BookingEventConsumer) handleMessage(ctx context.Context, msg kafkago.Me
 	case strings.EqualFold(cloudEvent.Type, events.BookingCancelled):
 		return c.handleBookingCancelled(ctx, cloudEvent)
 
+	case strings.EqualFold(cloudEvent.Type, BookingExpired):
+		return c.handleBookingExpired(ctx, cloudEvent)
+
+	case strings.EqualFold(cloudEvent.Type, BookingCreated):
+		return c.handleBookingCreated(ctx, cloudEvent)
+
+	case strings.EqualFold(cloudEvent.Type, BookingUpdated):
+		return c.handleBookingUpdated(ctx, cloudEvent)
+
 	default:
+		if c.eventTypes != nil {
+			c.logger.Info("no handler for allowed booking event type",
+				zap.String("type", cloudEvent.Type),
+			)
+			return nil
+		}
 		c.logger.Debug("ignoring unhandled booking event type",
 			zap.String("type", cloudEvent.Type),
 		)
@@ -74,10 +460,12 @@ func (c *BookingEventConsumer) handleDeliveryConfirmed(ctx context.Context, ce k
 	var event events.DeliveryConfirmedEvent
 	if err := ce.ParseData(&event); err != nil {
 		c.logger.Error("failed to parse DeliveryConfirmedEvent data", zap.Error(err))
-		return err
+		return permanent(err)
 	}
 
-	return c.paymentService.HandleDeliveryConfirmed(ctx, event)
+	return retryWithBackoff(ctx, c.retryPolicy, c.logger, ce.Type, func(ctx context.Context) error {
+		return c.paymentService.HandleDeliveryConfirmed(ctx, event)
+	})
 }
 
 // handleBookingCancelled processes a BookingCancelledEvent.
@@ -85,13 +473,91 @@ func (c *BookingEventConsumer) handleBookingCancelled(ctx context.Context, ce ka
 	var event events.BookingCancelledEvent
 	if err := ce.ParseData(&event); err != nil {
 		c.logger.Error("failed to parse BookingCancelledEvent data", zap.Error(err))
-		return err
+		return permanent(err)
+	}
+
+	return retryWithBackoff(ctx, c.retryPolicy, c.logger, ce.Type, func(ctx context.Context) error {
+		return c.paymentService.HandleBookingCancelled(ctx, event)
+	})
+}
+
+// handleBookingExpired processes a BookingExpiredEvent.
+func (c *BookingEventConsumer) handleBookingExpired(ctx context.Context, ce kafka.CloudEvent) error {
+	var event BookingExpiredEvent
+	if err := ce.ParseData(&event); err != nil {
+		c.logger.Error("failed to parse BookingExpiredEvent data", zap.Error(err))
+		return permanent(err)
+	}
+
+	return retryWithBackoff(ctx, c.retryPolicy, c.logger, ce.Type, func(ctx context.Context) error {
+		return c.paymentService.HandleBookingExpired(ctx, event.BookingID)
+	})
+}
+
+// handleBookingCreated processes a BookingCreatedEvent.
+func (c *BookingEventConsumer) handleBookingCreated(ctx context.Context, ce kafka.CloudEvent) error {
+	var event BookingCreatedEvent
+	if err := ce.ParseData(&event); err != nil {
+		c.logger.Error("failed to parse BookingCreatedEvent data", zap.Error(err))
+		return permanent(err)
+	}
+	if event.BookingID == uuid.Nil || event.TotalCents <= 0 || event.Currency == "" {
+		c.logger.Error("BookingCreatedEvent is missing booking ID, total or currency",
+			zap.String("id", ce.ID),
+		)
+		return permanent(errors.New("booking created event is missing booking_id, total_cents or currency"))
+	}
+	if event.OccurredAt.IsZero() {
+		event.OccurredAt = time.Now().UTC()
 	}
 
-	return c.paymentService.HandleBookingCancelled(ctx, event)
+	return retryWithBackoff(ctx, c.retryPolicy, c.logger, ce.Type, func(ctx context.Context) error {
+		return c.paymentService.HandleBookingCreated(ctx, booking.Total{
+			BookingID:   event.BookingID,
+			OwnerID:     event.OwnerID,
+			AmountCents: event.TotalCents,
+			Currency:    event.Currency,
+			CreatedAt:   event.OccurredAt,
+		})
+	})
 }
 
-// Close closes the underlying Kafka consumer.
+// handleBookingUpdated processes a BookingUpdatedEvent. The CloudEvent ID
+// makes the adjustment idempotent, so events without one are rejected.
+func (c *BookingEventConsumer) handleBookingUpdated(ctx context.Context, ce kafka.CloudEvent) error {
+	var event BookingUpdatedEvent
+	if err := ce.ParseData(&event); err != nil {
+		c.logger.Error("failed to parse BookingUpdatedEvent data", zap.Error(err))
+		return permanent(err)
+	}
+	if ce.ID == "" || event.BookingID == uuid.Nil || event.TotalCents <= 0 || event.Currency == "" {
+		c.logger.Error("BookingUpdatedEvent is missing event ID, booking ID, total or currency",
+			zap.String("id", ce.ID),
+		)
+		return permanent(errors.New("booking updated event is missing id, booking_id, total_cents or currency"))
+	}
+
+	return retryWithBackoff(ctx, c.retryPolicy, c.logger, ce.Type, func(ctx context.Context) error {
+		return c.paymentService.HandleBookingUpdated(ctx, ce.ID, event.BookingID, event.TotalCents, event.Currency)
+	})
+}
+
+// Close closes the underlying Kafka consumers and any concurrent readers.
 func (c *BookingEventConsumer) Close() error {
-	return c.consumer.Close()
+	c.mu.Lock()
+	readers := c.readers
+	c.mu.Unlock()
+
+	for _, reader := range readers {
+		if err := reader.Close(); err != nil {
+			c.logger.Warn("failed to close booking event reader", zap.Error(err))
+		}
+	}
+	var firstErr error
+	for _, consumer := range c.consumers {
+		if err := consumer.Close(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
 }
@@ -0,0 +1,333 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/Kilat-Pet-Delivery/lib-common/kafka"
+	"github.com/Kilat-Pet-Delivery/lib-proto/events"
+	"github.com/Kilat-Pet-Delivery/service-payment/internal/application"
+	"github.com/Kilat-Pet-Delivery/service-payment/internal/domain/booking"
+	"github.com/Kilat-Pet-Delivery/service-payment/internal/domain/payment"
+	"github.com/google/uuid"
+	kafkago "github.com/segmentio/kafka-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/metric/noop"
+	"go.uber.org/zap"
+)
+
+// ---- fakes ----
+
+// flakyPaymentRepo fails FindByBookingID a fixed number of times before
+// returning a pending payment, simulating a transient DB outage.
+type flakyPaymentRepo struct {
+	payment.PaymentRepository
+
+	failures int
+	calls    int
+	payment  *payment.Payment
+}
+
+func (f *flakyPaymentRepo) FindByBookingID(_ context.Context, _ uuid.UUID) (*payment.Payment, error) {
+	f.calls++
+	if f.calls <= f.failures {
+		return nil, errors.New("connection reset by peer")
+	}
+	return f.payment, nil
+}
+
+// ---- helpers ----
+
+func newTestConsumer(repo payment.PaymentRepository) *BookingEventConsumer {
+	logger := zap.NewNop()
+	svc := application.NewPaymentService(repo, nil, nil, nil, nil, nil, nil, nil, logger)
+	return &BookingEventConsumer{
+		paymentService: svc,
+		retryPolicy: RetryPolicy{
+			MaxAttempts:    4,
+			InitialBackoff: time.Millisecond,
+			MaxBackoff:     5 * time.Millisecond,
+		},
+		logger: logger,
+	}
+}
+
+func cancelledMessage(t *testing.T, bookingID uuid.UUID) kafkago.Message {
+	t.Helper()
+	ce, err := kafka.NewCloudEvent("service-booking", events.BookingCancelled, events.BookingCancelledEvent{
+		BookingID:  bookingID,
+		Reason:     "owner cancelled",
+		OccurredAt: time.Now().UTC(),
+	})
+	require.NoError(t, err)
+	raw, err := json.Marshal(ce)
+	require.NoError(t, err)
+	return kafkago.Message{Value: raw}
+}
+
+func createdMessage(t *testing.T, event BookingCreatedEvent) kafkago.Message {
+	t.Helper()
+	ce, err := kafka.NewCloudEvent("service-booking", BookingCreated, event)
+	require.NoError(t, err)
+	raw, err := json.Marshal(ce)
+	require.NoError(t, err)
+	return kafkago.Message{Value: raw}
+}
+
+// ---- tests ----
+
+// TestHandleMessage_RetriesTransientErrors verifies that a repository which
+// fails twice and then succeeds is retried until the event is handled.
+func TestHandleMessage_RetriesTransientErrors(t *testing.T) {
+	bookingID := uuid.New()
+	p, err := payment.NewPayment(bookingID, uuid.New(), 10000, "MYR", 15.0, payment.PayoutFloors{})
+	require.NoError(t, err)
+	repo := &flakyPaymentRepo{
+		failures: 2,
+		payment:  p,
+	}
+	c := newTestConsumer(repo)
+
+	err = c.handleMessage(context.Background(), cancelledMessage(t, bookingID))
+	require.NoError(t, err)
+	assert.Equal(t, 3, repo.calls, "expected two failures followed by one success")
+}
+
+// TestHandleMessage_GivesUpAfterMaxAttempts verifies the retry loop is bounded.
+func TestHandleMessage_GivesUpAfterMaxAttempts(t *testing.T) {
+	repo := &flakyPaymentRepo{failures: 100}
+	c := newTestConsumer(repo)
+
+	err := c.handleMessage(context.Background(), cancelledMessage(t, uuid.New()))
+	require.Error(t, err)
+	assert.Equal(t, 4, repo.calls)
+}
+
+// TestHandleMessage_ParseErrorIsNotRetried verifies malformed payloads are
+// returned immediately as permanent errors.
+func TestHandleMessage_ParseErrorIsNotRetried(t *testing.T) {
+	repo := &flakyPaymentRepo{}
+	c := newTestConsumer(repo)
+
+	err := c.handleMessage(context.Background(), kafkago.Message{Value: []byte("not json")})
+	require.Error(t, err)
+	assert.False(t, isRetryable(err))
+	assert.Equal(t, 0, repo.calls)
+}
+
+// TestHandleMessage_HonoursContextCancellation verifies that a cancelled
+// context stops the backoff loop instead of delaying shutdown.
+func TestHandleMessage_HonoursContextCancellation(t *testing.T) {
+	repo := &flakyPaymentRepo{failures: 100}
+	c := newTestConsumer(repo)
+	c.retryPolicy.InitialBackoff = time.Minute
+	c.retryPolicy.MaxBackoff = time.Minute
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	msg := cancelledMessage(t, uuid.New())
+	done := make(chan error, 1)
+	go func() { done <- c.handleMessage(ctx, msg) }()
+
+	select {
+	case err := <-done:
+		require.Error(t, err)
+		assert.Equal(t, 1, repo.calls)
+	case <-time.After(5 * time.Second):
+		t.Fatal("handleMessage did not return after context cancellation")
+	}
+}
+
+func TestHandleMessage_PanicIsRecoveredAsPermanent(t *testing.T) {
+	c := newTestConsumer(nil)
+	calls := 0
+	c.paymentService = panicOnCancel{calls: &calls}
+
+	err := c.handleMessage(context.Background(), cancelledMessage(t, uuid.New()))
+	require.ErrorIs(t, err, errHandlerPanicked)
+	assert.False(t, isRetryable(err), "a panicking message must not be retried")
+	assert.Equal(t, 1, calls)
+}
+
+// panicOnCancel panics on every cancellation.
+type panicOnCancel struct {
+	bookingEventHandler
+	calls *int
+}
+
+func (h panicOnCancel) HandleBookingCancelled(context.Context, events.BookingCancelledEvent) error {
+	*h.calls++
+	panic("unexpected payload")
+}
+
+// cancelInitiatorRecorder records who each cancellation is attributed to.
+type cancelInitiatorRecorder struct {
+	bookingEventHandler
+	initiators []payment.Initiator
+}
+
+func (h *cancelInitiatorRecorder) HandleBookingCancelled(ctx context.Context, _ events.BookingCancelledEvent) error {
+	h.initiators = append(h.initiators, payment.ParseInitiator(payment.ActorFromContext(ctx)))
+	return nil
+}
+
+func TestHandleMessage_BookingCancelledIsInitiatedBySystem(t *testing.T) {
+	c := newTestConsumer(nil)
+	h := &cancelInitiatorRecorder{}
+	c.paymentService = h
+
+	require.NoError(t, c.handleMessage(context.Background(), cancelledMessage(t, uuid.New())))
+	assert.Equal(t, []payment.Initiator{{Type: "system", ID: "booking-events"}}, h.initiators,
+		"refunds from booking events are auto-refunds, not admin refunds")
+}
+
+// createdRecorder records the booking totals it is handed.
+type createdRecorder struct {
+	bookingEventHandler
+	totals []booking.Total
+}
+
+func (h *createdRecorder) HandleBookingCreated(_ context.Context, total booking.Total) error {
+	h.totals = append(h.totals, total)
+	return nil
+}
+
+func TestHandleMessage_BookingCreatedCachesTotal(t *testing.T) {
+	c := newTestConsumer(nil)
+	h := &createdRecorder{}
+	c.paymentService = h
+	bookingID, ownerID := uuid.New(), uuid.New()
+
+	msg := createdMessage(t, BookingCreatedEvent{
+		BookingID: bookingID, OwnerID: ownerID, TotalCents: 4500, Currency: "MYR",
+	})
+	require.NoError(t, c.handleMessage(context.Background(), msg))
+	require.Len(t, h.totals, 1)
+	assert.Equal(t, bookingID, h.totals[0].BookingID)
+	assert.Equal(t, ownerID, h.totals[0].OwnerID)
+	assert.Equal(t, int64(4500), h.totals[0].AmountCents)
+	assert.Equal(t, "MYR", h.totals[0].Currency)
+
+	missingTotal := createdMessage(t, BookingCreatedEvent{BookingID: uuid.New(), Currency: "MYR"})
+	err := c.handleMessage(context.Background(), missingTotal)
+	require.Error(t, err)
+	assert.False(t, isRetryable(err), "an event without a total cannot succeed on retry")
+	assert.Len(t, h.totals, 1)
+}
+
+// updatedRecorder records the event IDs and totals of booking updates.
+type updatedRecorder struct {
+	bookingEventHandler
+	eventIDs []string
+	totals   []int64
+}
+
+func (h *updatedRecorder) HandleBookingUpdated(_ context.Context, eventID string, _ uuid.UUID, totalCents int64, _ string) error {
+	h.eventIDs = append(h.eventIDs, eventID)
+	h.totals = append(h.totals, totalCents)
+	return nil
+}
+
+func TestHandleMessage_BookingUpdatedPassesEventID(t *testing.T) {
+	c := newTestConsumer(nil)
+	h := &updatedRecorder{}
+	c.paymentService = h
+
+	ce, err := kafka.NewCloudEvent("service-booking", BookingUpdated, BookingUpdatedEvent{
+		BookingID: uuid.New(), TotalCents: 6200, Currency: "MYR",
+	})
+	require.NoError(t, err)
+	ce.ID = "evt-update-1"
+	raw, err := json.Marshal(ce)
+	require.NoError(t, err)
+	require.NoError(t, c.handleMessage(context.Background(), kafkago.Message{Value: raw}))
+	assert.Equal(t, []string{"evt-update-1"}, h.eventIDs, "the CloudEvent ID keys the adjustment")
+	assert.Equal(t, []int64{6200}, h.totals)
+
+	ce, err = kafka.NewCloudEvent("service-booking", BookingUpdated, BookingUpdatedEvent{BookingID: uuid.New(), Currency: "MYR"})
+	require.NoError(t, err)
+	ce.ID = "evt-update-2"
+	raw, err = json.Marshal(ce)
+	require.NoError(t, err)
+	err = c.handleMessage(context.Background(), kafkago.Message{Value: raw})
+	require.Error(t, err)
+	assert.False(t, isRetryable(err), "an update without a total cannot succeed on retry")
+	assert.Len(t, h.eventIDs, 1)
+}
+
+// typeRecorder records the types of the booking events it handles.
+type typeRecorder struct {
+	bookingEventHandler
+	handled []string
+}
+
+func (h *typeRecorder) HandleBookingCancelled(context.Context, events.BookingCancelledEvent) error {
+	h.handled = append(h.handled, events.BookingCancelled)
+	return nil
+}
+
+func (h *typeRecorder) HandleBookingCreated(context.Context, booking.Total) error {
+	h.handled = append(h.handled, BookingCreated)
+	return nil
+}
+
+// recordingCounter records the type attribute of every increment.
+type recordingCounter struct {
+	noop.Int64Counter
+	types []string
+}
+
+func (c *recordingCounter) Add(_ context.Context, incr int64, opts ...metric.AddOption) {
+	attrs := metric.NewAddConfig(opts).Attributes()
+	eventType, _ := attrs.Value("type")
+	for range incr {
+		c.types = append(c.types, eventType.AsString())
+	}
+}
+
+func TestHandleMessage_EventTypeAllowlist(t *testing.T) {
+	unknown, err := kafka.NewCloudEvent("service-booking", "booking.rescheduled", struct{}{})
+	require.NoError(t, err)
+	raw, err := json.Marshal(unknown)
+	require.NoError(t, err)
+	messages := []kafkago.Message{
+		cancelledMessage(t, uuid.New()),
+		createdMessage(t, BookingCreatedEvent{BookingID: uuid.New(), TotalCents: 4500, Currency: "MYR"}),
+		{Value: raw},
+	}
+
+	tests := []struct {
+		name         string
+		types        []string
+		wantHandled  []string
+		wantDisabled []string
+	}{
+		{"unset handles every type", nil, []string{events.BookingCancelled, BookingCreated}, nil},
+		{"cancellation disabled", []string{BookingCreated, "booking.rescheduled"}, []string{BookingCreated}, []string{events.BookingCancelled}},
+		{"matched case-insensitively", []string{" BOOKING.CANCELLED "}, []string{events.BookingCancelled}, []string{BookingCreated, "booking.rescheduled"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newTestConsumer(nil)
+			h := &typeRecorder{}
+			c.paymentService = h
+			c.SetEventTypes(tt.types)
+			counter := &recordingCounter{}
+			if c.disabledEvents != nil {
+				c.disabledEvents = counter
+			}
+
+			for _, msg := range messages {
+				require.NoError(t, c.handleMessage(context.Background(), msg), "skipped events are done with")
+			}
+			assert.Equal(t, tt.wantHandled, h.handled)
+			assert.Equal(t, tt.wantDisabled, counter.types)
+		})
+	}
+}
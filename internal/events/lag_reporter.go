@@ -0,0 +1,195 @@
+package events
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	kafkago "github.com/segmentio/kafka-go"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+	"go.uber.org/zap"
+)
+
+// lagClient is the subset of kafkago.Client the lag reporter uses.
+type lagClient interface {
+	Metadata(ctx context.Context, req *kafkago.MetadataRequest) (*kafkago.MetadataResponse, error)
+	OffsetFetch(ctx context.Context, req *kafkago.OffsetFetchRequest) (*kafkago.OffsetFetchResponse, error)
+	ListOffsets(ctx context.Context, req *kafkago.ListOffsetsRequest) (*kafkago.ListOffsetsResponse, error)
+}
+
+// PartitionLag is the consumer lag of one partition at the time it was measured.
+type PartitionLag struct {
+	Partition int
+	Committed int64
+	Latest    int64
+	Lag       int64
+}
+
+// LagReporter periodically measures how far a consumer group is behind the
+// latest offset of each partition of a topic. It logs the lag and exposes it
+// as the kafka.consumer.lag gauge.
+type LagReporter struct {
+	client   lagClient
+	groupID  string
+	topic    string
+	interval time.Duration
+	logger   *zap.Logger
+
+	mu   sync.Mutex
+	last []PartitionLag
+}
+
+// NewLagReporter creates a reporter for groupID's progress on topic.
+func NewLagReporter(brokers []string, groupID, topic string, interval time.Duration, logger *zap.Logger) *LagReporter {
+	return &LagReporter{
+		client:   &kafkago.Client{Addr: kafkago.TCP(brokers...)},
+		groupID:  groupID,
+		topic:    topic,
+		interval: interval,
+		logger:   logger,
+	}
+}
+
+// Start registers the lag gauge and measures lag every interval until ctx is cancelled.
+func (r *LagReporter) Start(ctx context.Context) {
+	if err := r.registerGauge(); err != nil {
+		r.logger.Warn("failed to register consumer lag gauge", zap.Error(err))
+	}
+
+	ticker := time.NewTicker(r.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			r.RunOnce(ctx)
+		}
+	}
+}
+
+// RunOnce measures and logs the lag of every partition once.
+func (r *LagReporter) RunOnce(ctx context.Context) {
+	lags, err := r.measure(ctx)
+	if err != nil {
+		r.logger.Warn("failed to measure consumer lag",
+			zap.String("topic", r.topic),
+			zap.String("group_id", r.groupID),
+			zap.Error(err),
+		)
+		return
+	}
+
+	r.mu.Lock()
+	r.last = lags
+	r.mu.Unlock()
+
+	for _, l := range lags {
+		r.logger.Info("consumer lag",
+			zap.String("topic", r.topic),
+			zap.String("group_id", r.groupID),
+			zap.Int("partition", l.Partition),
+			zap.Int64("committed_offset", l.Committed),
+			zap.Int64("latest_offset", l.Latest),
+			zap.Int64("lag", l.Lag),
+		)
+	}
+}
+
+// measure returns the lag of every partition of the topic, sorted by partition.
+// A partition the group has never committed on counts from its first offset.
+func (r *LagReporter) measure(ctx context.Context) ([]PartitionLag, error) {
+	meta, err := r.client.Metadata(ctx, &kafkago.MetadataRequest{Topics: []string{r.topic}})
+	if err != nil {
+		return nil, fmt.Errorf("fetch metadata: %w", err)
+	}
+	var partitions []int
+	for _, t := range meta.Topics {
+		if t.Name != r.topic {
+			continue
+		}
+		if t.Error != nil {
+			return nil, fmt.Errorf("fetch metadata: %w", t.Error)
+		}
+		for _, p := range t.Partitions {
+			partitions = append(partitions, p.ID)
+		}
+	}
+	if len(partitions) == 0 {
+		return nil, fmt.Errorf("topic %s has no partitions", r.topic)
+	}
+	sort.Ints(partitions)
+
+	committed, err := r.client.OffsetFetch(ctx, &kafkago.OffsetFetchRequest{
+		GroupID: r.groupID,
+		Topics:  map[string][]int{r.topic: partitions},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("fetch committed offsets: %w", err)
+	}
+	if committed.Error != nil {
+		return nil, fmt.Errorf("fetch committed offsets: %w", committed.Error)
+	}
+	committedByPartition := make(map[int]int64, len(partitions))
+	for _, p := range committed.Topics[r.topic] {
+		if p.Error != nil {
+			return nil, fmt.Errorf("fetch committed offset of partition %d: %w", p.Partition, p.Error)
+		}
+		committedByPartition[p.Partition] = p.CommittedOffset
+	}
+
+	requests := make([]kafkago.OffsetRequest, 0, len(partitions))
+	for _, p := range partitions {
+		requests = append(requests, kafkago.LastOffsetOf(p))
+	}
+	latest, err := r.client.ListOffsets(ctx, &kafkago.ListOffsetsRequest{
+		Topics: map[string][]kafkago.OffsetRequest{r.topic: requests},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("list latest offsets: %w", err)
+	}
+
+	lags := make([]PartitionLag, 0, len(partitions))
+	for _, p := range latest.Topics[r.topic] {
+		if p.Error != nil {
+			return nil, fmt.Errorf("list latest offset of partition %d: %w", p.Partition, p.Error)
+		}
+		c, ok := committedByPartition[p.Partition]
+		if !ok || c < 0 {
+			c = p.FirstOffset
+		}
+		lag := p.LastOffset - c
+		if lag < 0 {
+			lag = 0
+		}
+		lags = append(lags, PartitionLag{Partition: p.Partition, Committed: c, Latest: p.LastOffset, Lag: lag})
+	}
+	sort.Slice(lags, func(i, j int) bool { return lags[i].Partition < lags[j].Partition })
+	return lags, nil
+}
+
+// registerGauge exposes the most recent measurement as kafka.consumer.lag.
+func (r *LagReporter) registerGauge() error {
+	meter := otel.Meter("github.com/Kilat-Pet-Delivery/service-payment/internal/events")
+	_, err := meter.Int64ObservableGauge("kafka.consumer.lag",
+		metric.WithDescription("Messages between the latest offset and the committed offset of a partition."),
+		metric.WithInt64Callback(func(_ context.Context, o metric.Int64Observer) error {
+			r.mu.Lock()
+			defer r.mu.Unlock()
+			for _, l := range r.last {
+				o.Observe(l.Lag, metric.WithAttributes(
+					attribute.String("topic", r.topic),
+					attribute.String("group_id", r.groupID),
+					attribute.Int("partition", l.Partition),
+				))
+			}
+			return nil
+		}),
+	)
+	return err
+}
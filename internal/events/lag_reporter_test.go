@@ -0,0 +1,106 @@
+package events
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	kafkago "github.com/segmentio/kafka-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+// fakeLagClient serves fixed partition, committed and latest offsets.
+type fakeLagClient struct {
+	partitions []int
+	committed  map[int]int64
+	first      map[int]int64
+	latest     map[int]int64
+	fetchErr   error
+}
+
+func (f *fakeLagClient) Metadata(_ context.Context, req *kafkago.MetadataRequest) (*kafkago.MetadataResponse, error) {
+	topic := kafkago.Topic{Name: req.Topics[0]}
+	for _, p := range f.partitions {
+		topic.Partitions = append(topic.Partitions, kafkago.Partition{Topic: req.Topics[0], ID: p})
+	}
+	return &kafkago.MetadataResponse{Topics: []kafkago.Topic{topic}}, nil
+}
+
+func (f *fakeLagClient) OffsetFetch(_ context.Context, req *kafkago.OffsetFetchRequest) (*kafkago.OffsetFetchResponse, error) {
+	if f.fetchErr != nil {
+		return nil, f.fetchErr
+	}
+	resp := &kafkago.OffsetFetchResponse{Topics: map[string][]kafkago.OffsetFetchPartition{}}
+	for topic, partitions := range req.Topics {
+		for _, p := range partitions {
+			offset, ok := f.committed[p]
+			if !ok {
+				offset = -1
+			}
+			resp.Topics[topic] = append(resp.Topics[topic], kafkago.OffsetFetchPartition{Partition: p, CommittedOffset: offset})
+		}
+	}
+	return resp, nil
+}
+
+func (f *fakeLagClient) ListOffsets(_ context.Context, req *kafkago.ListOffsetsRequest) (*kafkago.ListOffsetsResponse, error) {
+	resp := &kafkago.ListOffsetsResponse{Topics: map[string][]kafkago.PartitionOffsets{}}
+	for topic, requests := range req.Topics {
+		for _, r := range requests {
+			resp.Topics[topic] = append(resp.Topics[topic], kafkago.PartitionOffsets{
+				Partition:   r.Partition,
+				FirstOffset: f.first[r.Partition],
+				LastOffset:  f.latest[r.Partition],
+			})
+		}
+	}
+	return resp, nil
+}
+
+func newTestLagReporter(client lagClient) *LagReporter {
+	return &LagReporter{
+		client:  client,
+		groupID: "payment-service",
+		topic:   "booking.events",
+		logger:  zap.NewNop(),
+	}
+}
+
+func TestLagReporter_MeasuresLagPerPartition(t *testing.T) {
+	r := newTestLagReporter(&fakeLagClient{
+		partitions: []int{1, 0, 2},
+		committed:  map[int]int64{0: 90, 1: 200},
+		first:      map[int]int64{0: 0, 1: 0, 2: 40},
+		latest:     map[int]int64{0: 100, 1: 200, 2: 55},
+	})
+
+	lags, err := r.measure(context.Background())
+	require.NoError(t, err)
+
+	assert.Equal(t, []PartitionLag{
+		{Partition: 0, Committed: 90, Latest: 100, Lag: 10},
+		{Partition: 1, Committed: 200, Latest: 200, Lag: 0},
+		// Nothing committed yet: lag counts from the first retained offset.
+		{Partition: 2, Committed: 40, Latest: 55, Lag: 15},
+	}, lags)
+}
+
+func TestLagReporter_RunOnceKeepsLastMeasurementOnError(t *testing.T) {
+	client := &fakeLagClient{
+		partitions: []int{0},
+		committed:  map[int]int64{0: 5},
+		latest:     map[int]int64{0: 8},
+	}
+	r := newTestLagReporter(client)
+
+	r.RunOnce(context.Background())
+	require.Len(t, r.last, 1)
+	assert.Equal(t, int64(3), r.last[0].Lag)
+
+	client.fetchErr = errors.New("coordinator not available")
+	r.RunOnce(context.Background())
+	require.Len(t, r.last, 1)
+	assert.Equal(t, int64(3), r.last[0].Lag)
+}
@@ -0,0 +1,53 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/Kilat-Pet-Delivery/lib-common/kafka"
+	kafkago "github.com/segmentio/kafka-go"
+)
+
+// ReplayHeader marks a message as a republished historical event so consumers
+// can tell it apart from a new occurrence.
+const ReplayHeader = "x-replay"
+
+// messageWriter is the subset of kafkago.Writer used by KafkaReplayPublisher.
+type messageWriter interface {
+	WriteMessages(ctx context.Context, msgs ...kafkago.Message) error
+	Close() error
+}
+
+// KafkaReplayPublisher writes replayed CloudEvents with the ReplayHeader set.
+// It writes directly with kafka-go because the shared producer cannot set headers.
+type KafkaReplayPublisher struct {
+	writer messageWriter
+}
+
+// NewKafkaReplayPublisher creates a replay publisher for the given brokers.
+// Messages are keyed so replays for one booking stay on one partition.
+func NewKafkaReplayPublisher(brokers []string) *KafkaReplayPublisher {
+	return &KafkaReplayPublisher{writer: &kafkago.Writer{
+		Addr:     kafkago.TCP(brokers...),
+		Balancer: &kafkago.Hash{},
+	}}
+}
+
+// PublishReplay writes ce to topic as a replay.
+func (p *KafkaReplayPublisher) PublishReplay(ctx context.Context, topic, key string, ce kafka.CloudEvent) error {
+	value, err := json.Marshal(ce)
+	if err != nil {
+		return err
+	}
+	return p.writer.WriteMessages(ctx, kafkago.Message{
+		Topic:   topic,
+		Key:     []byte(key),
+		Value:   value,
+		Headers: []kafkago.Header{{Key: ReplayHeader, Value: []byte("true")}},
+	})
+}
+
+// Close flushes and closes the underlying writer.
+func (p *KafkaReplayPublisher) Close() error {
+	return p.writer.Close()
+}
@@ -0,0 +1,42 @@
+package events
+
+import (
+	"context"
+	"testing"
+
+	"github.com/Kilat-Pet-Delivery/lib-common/kafka"
+	"github.com/Kilat-Pet-Delivery/lib-proto/events"
+	kafkago "github.com/segmentio/kafka-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type capturingWriter struct {
+	msgs []kafkago.Message
+}
+
+func (w *capturingWriter) WriteMessages(_ context.Context, msgs ...kafkago.Message) error {
+	w.msgs = append(w.msgs, msgs...)
+	return nil
+}
+
+func (w *capturingWriter) Close() error { return nil }
+
+func TestKafkaReplayPublisher_MarksReplays(t *testing.T) {
+	w := &capturingWriter{}
+	p := &KafkaReplayPublisher{writer: w}
+
+	ce, err := kafka.NewCloudEvent("service-payment", events.PaymentEscrowHeld, events.EscrowHeldEvent{AmountCents: 100})
+	require.NoError(t, err)
+	require.NoError(t, p.PublishReplay(context.Background(), events.TopicPaymentEvents, "booking-1", ce))
+
+	require.Len(t, w.msgs, 1)
+	msg := w.msgs[0]
+	assert.Equal(t, events.TopicPaymentEvents, msg.Topic)
+	assert.Equal(t, "booking-1", string(msg.Key))
+	assert.Contains(t, msg.Headers, kafkago.Header{Key: ReplayHeader, Value: []byte("true")})
+
+	parsed, err := kafka.ParseCloudEvent(msg.Value)
+	require.NoError(t, err)
+	assert.Equal(t, events.PaymentEscrowHeld, parsed.Type)
+}
@@ -0,0 +1,97 @@
+package events
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/Kilat-Pet-Delivery/lib-common/domain"
+	"go.uber.org/zap"
+)
+
+// RetryPolicy configures bounded exponential backoff for in-handler retries.
+type RetryPolicy struct {
+	// MaxAttempts is the total number of attempts, including the first one.
+	MaxAttempts    int
+	InitialBackoff time.Duration
+	MaxBackoff     time.Duration
+}
+
+// DefaultRetryPolicy returns the retry policy used by the booking event consumer.
+func DefaultRetryPolicy() RetryPolicy {
+	return RetryPolicy{
+		MaxAttempts:    4,
+		InitialBackoff: 200 * time.Millisecond,
+		MaxBackoff:     5 * time.Second,
+	}
+}
+
+// permanentError marks an error that must not be retried (e.g. a malformed payload).
+// Such errors are returned to the consumer immediately so the message can be dead-lettered.
+type permanentError struct {
+	err error
+}
+
+func (e *permanentError) Error() string { return e.err.Error() }
+func (e *permanentError) Unwrap() error { return e.err }
+
+// permanent wraps err so that retryWithBackoff does not retry it.
+func permanent(err error) error {
+	return &permanentError{err: err}
+}
+
+// isRetryable reports whether err is worth retrying. Parse failures and domain
+// rule violations are permanent; optimistic-lock conflicts and infrastructure
+// errors (DB, network) are retryable.
+func isRetryable(err error) bool {
+	var permErr *permanentError
+	if errors.As(err, &permErr) {
+		return false
+	}
+	var domErr *domain.DomainError
+	if errors.As(err, &domErr) {
+		return domErr.Err == domain.ErrConflict
+	}
+	return true
+}
+
+// backoff returns the delay before the given retry (1-based).
+func (p RetryPolicy) backoff(retry int) time.Duration {
+	d := p.InitialBackoff
+	for i := 1; i < retry; i++ {
+		d *= 2
+		if d >= p.MaxBackoff {
+			return p.MaxBackoff
+		}
+	}
+	return d
+}
+
+// retryWithBackoff runs fn until it succeeds, returns a non-retryable error, the
+// attempts are exhausted, or ctx is cancelled. Backoff waits are interrupted by
+// ctx cancellation so shutdown is never delayed by a sleeping retry.
+func retryWithBackoff(ctx context.Context, policy RetryPolicy, logger *zap.Logger, op string, fn func(ctx context.Context) error) error {
+	var err error
+	for attempt := 1; ; attempt++ {
+		err = fn(ctx)
+		if err == nil || !isRetryable(err) || attempt >= policy.MaxAttempts {
+			return err
+		}
+
+		delay := policy.backoff(attempt)
+		logger.Warn("retryable error handling event, backing off",
+			zap.String("op", op),
+			zap.Int("attempt", attempt),
+			zap.Duration("backoff", delay),
+			zap.Error(err),
+		)
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return err
+		case <-timer.C:
+		}
+	}
+}
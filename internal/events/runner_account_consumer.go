@@ -0,0 +1,126 @@
+package events
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/Kilat-Pet-Delivery/lib-common/kafka"
+	"github.com/Kilat-Pet-Delivery/service-payment/internal/domain/runner"
+	"github.com/Kilat-Pet-Delivery/service-payment/internal/tracing"
+	"github.com/google/uuid"
+	kafkago "github.com/segmentio/kafka-go"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+)
+
+// RunnerAccountLinked is the CloudEvent type the runner service publishes when
+// a runner links (or relinks) a Stripe Connect account.
+const RunnerAccountLinked = "runner.account_linked"
+
+// RunnerAccountLinkedEvent is the payload of a RunnerAccountLinked event. It
+// is defined here until the contract is added to lib-proto.
+type RunnerAccountLinkedEvent struct {
+	RunnerID        uuid.UUID `json:"runner_id"`
+	StripeAccountID string    `json:"stripe_account_id"`
+	LinkedAt        time.Time `json:"linked_at"`
+}
+
+// RunnerAccountConsumer mirrors runner payout accounts from the runner service.
+type RunnerAccountConsumer struct {
+	consumer    *kafka.Consumer
+	topic       string
+	accounts    runner.AccountRepository
+	retryPolicy RetryPolicy
+	logger      *zap.Logger
+}
+
+// NewRunnerAccountConsumer creates a consumer for RunnerAccountLinked events on topic.
+func NewRunnerAccountConsumer(
+	brokers []string,
+	groupID string,
+	topic string,
+	accounts runner.AccountRepository,
+	logger *zap.Logger,
+) *RunnerAccountConsumer {
+	return &RunnerAccountConsumer{
+		consumer:    kafka.NewConsumer(brokers, groupID, topic, logger),
+		topic:       topic,
+		accounts:    accounts,
+		retryPolicy: DefaultRetryPolicy(),
+		logger:      logger,
+	}
+}
+
+// Start begins consuming runner events. It blocks until the context is cancelled.
+func (c *RunnerAccountConsumer) Start(ctx context.Context) error {
+	return c.consumer.Consume(ctx, c.handleMessage)
+}
+
+// handleMessage stores the account carried by a RunnerAccountLinked event and
+// ignores every other event type on the topic.
+func (c *RunnerAccountConsumer) handleMessage(ctx context.Context, msg kafkago.Message) error {
+	ctx = otel.GetTextMapPropagator().Extract(ctx, tracing.KafkaHeaderCarrier(msg.Headers))
+	ctx, span := tracer.Start(ctx, c.topic+" process",
+		trace.WithSpanKind(trace.SpanKindConsumer),
+		trace.WithAttributes(
+			attribute.String("messaging.destination.name", msg.Topic),
+			attribute.Int("messaging.kafka.partition", msg.Partition),
+			attribute.Int64("messaging.kafka.offset", msg.Offset),
+		),
+	)
+	defer span.End()
+
+	ce, err := kafka.ParseCloudEvent(msg.Value)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "unparseable event")
+		c.logger.Error("failed to parse cloud event from runner topic",
+			zap.Error(err),
+			zap.String("raw", string(msg.Value)),
+		)
+		return permanent(err)
+	}
+
+	span.SetAttributes(attribute.String("cloudevents.event_type", ce.Type))
+	if !strings.EqualFold(ce.Type, RunnerAccountLinked) {
+		c.logger.Debug("ignoring unhandled runner event type", zap.String("type", ce.Type))
+		return nil
+	}
+
+	var event RunnerAccountLinkedEvent
+	if err := ce.ParseData(&event); err != nil {
+		c.logger.Error("failed to parse RunnerAccountLinkedEvent data", zap.Error(err))
+		return permanent(err)
+	}
+	if event.RunnerID == uuid.Nil || event.StripeAccountID == "" {
+		c.logger.Error("RunnerAccountLinkedEvent is missing runner or account ID",
+			zap.String("id", ce.ID),
+		)
+		return permanent(errors.New("runner account event is missing runner_id or stripe_account_id"))
+	}
+	if event.LinkedAt.IsZero() {
+		event.LinkedAt = time.Now().UTC()
+	}
+
+	c.logger.Info("received runner account link",
+		zap.String("runner_id", event.RunnerID.String()),
+		zap.String("id", ce.ID),
+	)
+	return retryWithBackoff(ctx, c.retryPolicy, c.logger, ce.Type, func(ctx context.Context) error {
+		return c.accounts.Upsert(ctx, runner.Account{
+			RunnerID:        event.RunnerID,
+			StripeAccountID: event.StripeAccountID,
+			LinkedAt:        event.LinkedAt,
+		})
+	})
+}
+
+// Close closes the underlying Kafka consumer.
+func (c *RunnerAccountConsumer) Close() error {
+	return c.consumer.Close()
+}
@@ -0,0 +1,113 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/Kilat-Pet-Delivery/lib-common/kafka"
+	"github.com/Kilat-Pet-Delivery/service-payment/internal/domain/runner"
+	"github.com/google/uuid"
+	kafkago "github.com/segmentio/kafka-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+// memoryAccountRepo keeps runner accounts in a map, applying the same
+// newest-link-wins rule as the database upsert.
+type memoryAccountRepo struct {
+	accounts map[uuid.UUID]runner.Account
+	upserts  int
+}
+
+func (r *memoryAccountRepo) Upsert(_ context.Context, account runner.Account) error {
+	r.upserts++
+	if existing, ok := r.accounts[account.RunnerID]; ok && existing.LinkedAt.After(account.LinkedAt) {
+		return nil
+	}
+	r.accounts[account.RunnerID] = account
+	return nil
+}
+
+func (r *memoryAccountRepo) FindByRunnerID(_ context.Context, runnerID uuid.UUID) (*runner.Account, error) {
+	account, ok := r.accounts[runnerID]
+	if !ok {
+		return nil, nil
+	}
+	return &account, nil
+}
+
+func newTestRunnerAccountConsumer(repo runner.AccountRepository) *RunnerAccountConsumer {
+	return &RunnerAccountConsumer{
+		topic:    "runner.events",
+		accounts: repo,
+		retryPolicy: RetryPolicy{
+			MaxAttempts:    2,
+			InitialBackoff: time.Millisecond,
+			MaxBackoff:     time.Millisecond,
+		},
+		logger: zap.NewNop(),
+	}
+}
+
+func runnerEventMessage(t *testing.T, eventType string, data any) kafkago.Message {
+	t.Helper()
+	ce, err := kafka.NewCloudEvent("service-runner", eventType, data)
+	require.NoError(t, err)
+	raw, err := json.Marshal(ce)
+	require.NoError(t, err)
+	return kafkago.Message{Value: raw}
+}
+
+// TestRunnerAccountConsumer_SeedsAndUpdatesMapping verifies that the first
+// event stores the account, a newer event replaces it, and a replayed older
+// event leaves the newer account in place.
+func TestRunnerAccountConsumer_SeedsAndUpdatesMapping(t *testing.T) {
+	repo := &memoryAccountRepo{accounts: map[uuid.UUID]runner.Account{}}
+	c := newTestRunnerAccountConsumer(repo)
+	ctx := context.Background()
+	runnerID := uuid.New()
+	linked := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	first := runnerEventMessage(t, RunnerAccountLinked, RunnerAccountLinkedEvent{
+		RunnerID: runnerID, StripeAccountID: "acct_first", LinkedAt: linked,
+	})
+	require.NoError(t, c.handleMessage(ctx, first))
+	account, err := repo.FindByRunnerID(ctx, runnerID)
+	require.NoError(t, err)
+	require.NotNil(t, account)
+	assert.Equal(t, "acct_first", account.StripeAccountID)
+
+	second := runnerEventMessage(t, RunnerAccountLinked, RunnerAccountLinkedEvent{
+		RunnerID: runnerID, StripeAccountID: "acct_second", LinkedAt: linked.Add(time.Hour),
+	})
+	require.NoError(t, c.handleMessage(ctx, second))
+	account, _ = repo.FindByRunnerID(ctx, runnerID)
+	assert.Equal(t, "acct_second", account.StripeAccountID)
+
+	require.NoError(t, c.handleMessage(ctx, first), "replayed event should be accepted")
+	account, _ = repo.FindByRunnerID(ctx, runnerID)
+	assert.Equal(t, "acct_second", account.StripeAccountID, "older link must not overwrite a newer one")
+}
+
+func TestRunnerAccountConsumer_IgnoresOtherEventTypes(t *testing.T) {
+	repo := &memoryAccountRepo{accounts: map[uuid.UUID]runner.Account{}}
+	c := newTestRunnerAccountConsumer(repo)
+
+	msg := runnerEventMessage(t, "runner.profile_updated", map[string]string{"name": "x"})
+	require.NoError(t, c.handleMessage(context.Background(), msg))
+	assert.Equal(t, 0, repo.upserts)
+}
+
+func TestRunnerAccountConsumer_MissingFieldsArePermanent(t *testing.T) {
+	repo := &memoryAccountRepo{accounts: map[uuid.UUID]runner.Account{}}
+	c := newTestRunnerAccountConsumer(repo)
+
+	msg := runnerEventMessage(t, RunnerAccountLinked, RunnerAccountLinkedEvent{RunnerID: uuid.New()})
+	err := c.handleMessage(context.Background(), msg)
+	require.Error(t, err)
+	assert.False(t, isRetryable(err))
+	assert.Equal(t, 0, repo.upserts)
+}
@@ -0,0 +1,98 @@
+package events
+
+import (
+	"context"
+	"strings"
+
+	"github.com/Kilat-Pet-Delivery/lib-common/kafka"
+	"github.com/Kilat-Pet-Delivery/service-payment/internal/tracing"
+	kafkago "github.com/segmentio/kafka-go"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+)
+
+// RunSubscriptionRenewals is the CloudEvent type an external scheduler
+// publishes to ask for a subscription renewal run. The event carries no data.
+const RunSubscriptionRenewals = "scheduler.run_subscription_renewals"
+
+// RenewalRunner runs one subscription renewal batch.
+type RenewalRunner interface {
+	RunOnce(ctx context.Context) error
+}
+
+// SchedulerConsumer runs scheduled jobs when ticks arrive on the scheduler
+// topic, instead of on an in-process timer. Every instance joins the same
+// consumer group, so each tick is handled by one of them.
+type SchedulerConsumer struct {
+	consumer    *kafka.Consumer
+	topic       string
+	renewals    RenewalRunner
+	retryPolicy RetryPolicy
+	logger      *zap.Logger
+}
+
+// NewSchedulerConsumer creates a consumer for scheduler ticks on topic.
+func NewSchedulerConsumer(
+	brokers []string,
+	groupID string,
+	topic string,
+	renewals RenewalRunner,
+	logger *zap.Logger,
+) *SchedulerConsumer {
+	return &SchedulerConsumer{
+		consumer:    kafka.NewConsumer(brokers, groupID, topic, logger),
+		topic:       topic,
+		renewals:    renewals,
+		retryPolicy: DefaultRetryPolicy(),
+		logger:      logger,
+	}
+}
+
+// Start begins consuming scheduler ticks. It blocks until the context is cancelled.
+func (c *SchedulerConsumer) Start(ctx context.Context) error {
+	return c.consumer.Consume(ctx, c.handleMessage)
+}
+
+// handleMessage runs the renewal batch for a RunSubscriptionRenewals tick and
+// ignores every other event type on the topic. Redelivered ticks are harmless
+// because renewed subscriptions are no longer due.
+func (c *SchedulerConsumer) handleMessage(ctx context.Context, msg kafkago.Message) error {
+	ctx = otel.GetTextMapPropagator().Extract(ctx, tracing.KafkaHeaderCarrier(msg.Headers))
+	ctx, span := tracer.Start(ctx, c.topic+" process",
+		trace.WithSpanKind(trace.SpanKindConsumer),
+		trace.WithAttributes(
+			attribute.String("messaging.destination.name", msg.Topic),
+			attribute.Int("messaging.kafka.partition", msg.Partition),
+			attribute.Int64("messaging.kafka.offset", msg.Offset),
+		),
+	)
+	defer span.End()
+
+	ce, err := kafka.ParseCloudEvent(msg.Value)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "unparseable event")
+		c.logger.Error("failed to parse cloud event from scheduler topic",
+			zap.Error(err),
+			zap.String("raw", string(msg.Value)),
+		)
+		return permanent(err)
+	}
+
+	span.SetAttributes(attribute.String("cloudevents.event_type", ce.Type))
+	if !strings.EqualFold(ce.Type, RunSubscriptionRenewals) {
+		c.logger.Debug("ignoring unhandled scheduler event type", zap.String("type", ce.Type))
+		return nil
+	}
+
+	c.logger.Info("received subscription renewal tick", zap.String("id", ce.ID))
+	return retryWithBackoff(ctx, c.retryPolicy, c.logger, ce.Type, c.renewals.RunOnce)
+}
+
+// Close closes the underlying Kafka consumer.
+func (c *SchedulerConsumer) Close() error {
+	return c.consumer.Close()
+}
@@ -0,0 +1,73 @@
+package events
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	kafkago "github.com/segmentio/kafka-go"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+)
+
+// countingRunner counts renewal runs; the first failures runs return an error.
+type countingRunner struct {
+	runs     int
+	failures int
+}
+
+func (r *countingRunner) RunOnce(context.Context) error {
+	r.runs++
+	if r.runs <= r.failures {
+		return errors.New("database unavailable")
+	}
+	return nil
+}
+
+func newTestSchedulerConsumer(runner RenewalRunner) *SchedulerConsumer {
+	return &SchedulerConsumer{
+		topic:    "scheduler.ticks",
+		renewals: runner,
+		retryPolicy: RetryPolicy{
+			MaxAttempts:    2,
+			InitialBackoff: time.Millisecond,
+			MaxBackoff:     time.Millisecond,
+		},
+		logger: zap.NewNop(),
+	}
+}
+
+func TestSchedulerConsumer_RunsRenewalsOnTick(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("tick runs the batch", func(t *testing.T) {
+		runner := &countingRunner{}
+		c := newTestSchedulerConsumer(runner)
+		assert.NoError(t, c.handleMessage(ctx, runnerEventMessage(t, RunSubscriptionRenewals, struct{}{})))
+		assert.Equal(t, 1, runner.runs)
+	})
+
+	t.Run("failed run is retried", func(t *testing.T) {
+		runner := &countingRunner{failures: 1}
+		c := newTestSchedulerConsumer(runner)
+		assert.NoError(t, c.handleMessage(ctx, runnerEventMessage(t, RunSubscriptionRenewals, struct{}{})))
+		assert.Equal(t, 2, runner.runs)
+	})
+
+	t.Run("other event types are ignored", func(t *testing.T) {
+		runner := &countingRunner{}
+		c := newTestSchedulerConsumer(runner)
+		assert.NoError(t, c.handleMessage(ctx, runnerEventMessage(t, "scheduler.run_something_else", struct{}{})))
+		assert.Zero(t, runner.runs)
+	})
+
+	t.Run("unparseable tick is permanent", func(t *testing.T) {
+		runner := &countingRunner{}
+		c := newTestSchedulerConsumer(runner)
+		err := c.handleMessage(ctx, kafkago.Message{Value: []byte("not json")})
+		assert.Error(t, err)
+		assert.False(t, isRetryable(err))
+		assert.Zero(t, runner.runs)
+	})
+}
@@ -0,0 +1,136 @@
+package events
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	kafkago "github.com/segmentio/kafka-go"
+	"go.uber.org/zap"
+)
+
+// maxTopicCheckBackoff caps the exponential backoff between topic checks.
+const maxTopicCheckBackoff = 30 * time.Second
+
+// topicClient is the subset of kafkago.Client the topic checker uses.
+type topicClient interface {
+	Metadata(ctx context.Context, req *kafkago.MetadataRequest) (*kafkago.MetadataResponse, error)
+	CreateTopics(ctx context.Context, req *kafkago.CreateTopicsRequest) (*kafkago.CreateTopicsResponse, error)
+}
+
+// TopicChecker verifies at startup that the topics the service reads and
+// writes exist, so consumers don't start against a cluster that is still
+// being provisioned. With autoCreate it creates missing topics itself.
+type TopicChecker struct {
+	client     topicClient
+	retries    int
+	backoff    time.Duration
+	autoCreate bool
+	logger     *zap.Logger
+	sleep      func(time.Duration)
+}
+
+// NewTopicChecker creates a checker that retries up to retries times,
+// starting at backoff and doubling between attempts.
+func NewTopicChecker(brokers []string, retries int, backoff time.Duration, autoCreate bool, logger *zap.Logger) *TopicChecker {
+	return &TopicChecker{
+		client:     &kafkago.Client{Addr: kafkago.TCP(brokers...)},
+		retries:    retries,
+		backoff:    backoff,
+		autoCreate: autoCreate,
+		logger:     logger,
+		sleep:      time.Sleep,
+	}
+}
+
+// Ensure blocks until every topic exists, creating missing ones when
+// auto-create is on. It gives up once the retries are spent and returns an
+// error naming the topics that are still missing.
+func (c *TopicChecker) Ensure(ctx context.Context, topics ...string) error {
+	backoff := c.backoff
+	for attempt := 1; ; attempt++ {
+		missing, err := c.missing(ctx, topics)
+		if err == nil && len(missing) == 0 {
+			if attempt > 1 {
+				c.logger.Info("kafka topics available", zap.Strings("topics", topics), zap.Int("attempt", attempt))
+			}
+			return nil
+		}
+
+		if err != nil {
+			c.logger.Warn("failed to read kafka topic metadata",
+				zap.Int("attempt", attempt),
+				zap.Error(err),
+			)
+		} else {
+			c.logger.Warn("kafka topics missing",
+				zap.Strings("missing", missing),
+				zap.Bool("auto_create", c.autoCreate),
+				zap.Int("attempt", attempt),
+			)
+			if c.autoCreate {
+				c.create(ctx, missing)
+			}
+		}
+
+		if attempt > c.retries {
+			if err != nil {
+				return fmt.Errorf("failed to check kafka topics: %w", err)
+			}
+			return fmt.Errorf("kafka topics missing: %s", strings.Join(missing, ", "))
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		default:
+		}
+		c.sleep(backoff)
+		backoff = min(backoff*2, maxTopicCheckBackoff)
+	}
+}
+
+// missing returns the topics the cluster does not report, in the order given.
+func (c *TopicChecker) missing(ctx context.Context, topics []string) ([]string, error) {
+	resp, err := c.client.Metadata(ctx, &kafkago.MetadataRequest{Topics: topics})
+	if err != nil {
+		return nil, err
+	}
+
+	found := make(map[string]bool, len(resp.Topics))
+	for _, t := range resp.Topics {
+		if t.Error == nil {
+			found[t.Name] = true
+		}
+	}
+	var missing []string
+	for _, topic := range topics {
+		if !found[topic] {
+			missing = append(missing, topic)
+		}
+	}
+	return missing, nil
+}
+
+// create asks the cluster to create topics with a single partition and
+// replica, as in local development. Failures are logged; the next check
+// reports whatever is still missing.
+func (c *TopicChecker) create(ctx context.Context, topics []string) {
+	configs := make([]kafkago.TopicConfig, len(topics))
+	for i, topic := range topics {
+		configs[i] = kafkago.TopicConfig{Topic: topic, NumPartitions: 1, ReplicationFactor: 1}
+	}
+
+	resp, err := c.client.CreateTopics(ctx, &kafkago.CreateTopicsRequest{Topics: configs})
+	if err != nil {
+		c.logger.Warn("failed to create kafka topics", zap.Strings("topics", topics), zap.Error(err))
+		return
+	}
+	for _, topic := range topics {
+		if err := resp.Errors[topic]; err != nil {
+			c.logger.Warn("failed to create kafka topic", zap.String("topic", topic), zap.Error(err))
+			continue
+		}
+		c.logger.Info("created kafka topic", zap.String("topic", topic))
+	}
+}
@@ -0,0 +1,83 @@
+package events
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	kafkago "github.com/segmentio/kafka-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+// fakeTopicClient reports the topics in existing and records create requests.
+type fakeTopicClient struct {
+	existing map[string]bool
+	created  [][]string
+}
+
+func (f *fakeTopicClient) Metadata(_ context.Context, req *kafkago.MetadataRequest) (*kafkago.MetadataResponse, error) {
+	resp := &kafkago.MetadataResponse{}
+	for _, name := range req.Topics {
+		topic := kafkago.Topic{Name: name}
+		if !f.existing[name] {
+			topic.Error = kafkago.UnknownTopicOrPartition
+		}
+		resp.Topics = append(resp.Topics, topic)
+	}
+	return resp, nil
+}
+
+func (f *fakeTopicClient) CreateTopics(_ context.Context, req *kafkago.CreateTopicsRequest) (*kafkago.CreateTopicsResponse, error) {
+	var names []string
+	for _, t := range req.Topics {
+		names = append(names, t.Topic)
+		f.existing[t.Topic] = true
+	}
+	f.created = append(f.created, names)
+	return &kafkago.CreateTopicsResponse{}, nil
+}
+
+func newTestTopicChecker(client topicClient, autoCreate bool, sleeps *[]time.Duration) *TopicChecker {
+	return &TopicChecker{
+		client:     client,
+		retries:    3,
+		backoff:    time.Second,
+		autoCreate: autoCreate,
+		logger:     zap.NewNop(),
+		sleep:      func(d time.Duration) { *sleeps = append(*sleeps, d) },
+	}
+}
+
+func TestTopicChecker_ExistingTopicsPassWithoutRetry(t *testing.T) {
+	client := &fakeTopicClient{existing: map[string]bool{"booking.events": true, "payment.events": true}}
+	var sleeps []time.Duration
+
+	err := newTestTopicChecker(client, false, &sleeps).Ensure(context.Background(), "booking.events", "payment.events")
+	require.NoError(t, err)
+	assert.Empty(t, sleeps)
+	assert.Empty(t, client.created)
+}
+
+func TestTopicChecker_MissingTopicFailsAfterRetries(t *testing.T) {
+	client := &fakeTopicClient{existing: map[string]bool{"payment.events": true}}
+	var sleeps []time.Duration
+
+	err := newTestTopicChecker(client, false, &sleeps).Ensure(context.Background(), "booking.events", "payment.events")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "booking.events")
+	assert.NotContains(t, err.Error(), "payment.events")
+	assert.Equal(t, []time.Duration{time.Second, 2 * time.Second, 4 * time.Second}, sleeps)
+	assert.Empty(t, client.created, "topics are not created unless auto-create is on")
+}
+
+func TestTopicChecker_AutoCreatesMissingTopics(t *testing.T) {
+	client := &fakeTopicClient{existing: map[string]bool{"payment.events": true}}
+	var sleeps []time.Duration
+
+	err := newTestTopicChecker(client, true, &sleeps).Ensure(context.Background(), "booking.events", "payment.events")
+	require.NoError(t, err)
+	assert.Equal(t, [][]string{{"booking.events"}}, client.created)
+	assert.Len(t, sleeps, 1)
+}
@@ -0,0 +1,162 @@
+// Package feature gates new behaviors behind flags so they can be rolled out
+// gradually and toggled at runtime without a redeploy.
+package feature
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// Flag names a gated behavior.
+type Flag string
+
+const (
+	// AutoRelease gates the worker that releases escrow after its hold window.
+	AutoRelease Flag = "auto_release"
+	// ConnectTransfers gates Stripe Connect payout transfers on escrow release.
+	ConnectTransfers Flag = "connect_transfers"
+	// RequireBookingTotal rejects payments for bookings whose total has not
+	// been received from the booking service yet.
+	RequireBookingTotal Flag = "require_booking_total"
+)
+
+// defaults lists every known flag and its state when neither the environment
+// nor the store sets it. Behavior that predates the flag defaults to on.
+var defaults = map[Flag]bool{
+	AutoRelease:         true,
+	ConnectTransfers:    false,
+	RequireBookingTotal: false,
+}
+
+// Known returns the names of all known flags, sorted.
+func Known() []Flag {
+	flags := make([]Flag, 0, len(defaults))
+	for f := range defaults {
+		flags = append(flags, f)
+	}
+	sort.Slice(flags, func(i, j int) bool { return flags[i] < flags[j] })
+	return flags
+}
+
+// Parse reads a comma-separated list of name=bool pairs, e.g.
+// "auto_release=false,connect_transfers=true". Unknown names are rejected.
+func Parse(raw string) (map[Flag]bool, error) {
+	values := make(map[Flag]bool)
+	for _, part := range strings.Split(raw, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		name, value, ok := strings.Cut(part, "=")
+		if !ok {
+			return nil, fmt.Errorf("feature flag %q must be written as name=true|false", part)
+		}
+		flag := Flag(strings.TrimSpace(name))
+		if _, known := defaults[flag]; !known {
+			return nil, fmt.Errorf("unknown feature flag %q (known: %v)", flag, Known())
+		}
+		enabled, err := strconv.ParseBool(strings.TrimSpace(value))
+		if err != nil {
+			return nil, fmt.Errorf("feature flag %q: %w", flag, err)
+		}
+		values[flag] = enabled
+	}
+	return values, nil
+}
+
+// Store loads runtime flag overrides, e.g. from a database table.
+type Store interface {
+	LoadFlags(ctx context.Context) (map[Flag]bool, error)
+}
+
+// Flags resolves whether a feature is enabled. A runtime override from the
+// store wins over the environment, which wins over the built-in default.
+// A nil *Flags reports the built-in defaults.
+type Flags struct {
+	env      map[Flag]bool
+	store    Store
+	interval time.Duration
+	logger   *zap.Logger
+
+	mu        sync.RWMutex
+	overrides map[Flag]bool
+}
+
+// New creates a flag set from environment values and an optional store that
+// is polled every interval. store may be nil to use environment values only.
+func New(env map[Flag]bool, store Store, interval time.Duration, logger *zap.Logger) *Flags {
+	return &Flags{env: env, store: store, interval: interval, logger: logger}
+}
+
+// Static returns a flag set with fixed values and no store.
+func Static(values map[Flag]bool) *Flags {
+	return &Flags{env: values}
+}
+
+// Enabled reports whether the flag is on.
+func (f *Flags) Enabled(flag Flag) bool {
+	if f != nil {
+		f.mu.RLock()
+		enabled, ok := f.overrides[flag]
+		f.mu.RUnlock()
+		if ok {
+			return enabled
+		}
+		if enabled, ok := f.env[flag]; ok {
+			return enabled
+		}
+	}
+	return defaults[flag]
+}
+
+// Refresh reloads overrides from the store. Unknown flag names are ignored so
+// rows for retired flags do nothing.
+func (f *Flags) Refresh(ctx context.Context) error {
+	if f.store == nil {
+		return nil
+	}
+	loaded, err := f.store.LoadFlags(ctx)
+	if err != nil {
+		return err
+	}
+
+	overrides := make(map[Flag]bool, len(loaded))
+	for flag, enabled := range loaded {
+		if _, known := defaults[flag]; known {
+			overrides[flag] = enabled
+		}
+	}
+
+	f.mu.Lock()
+	f.overrides = overrides
+	f.mu.Unlock()
+	return nil
+}
+
+// Start refreshes overrides every interval. It blocks until the context is
+// cancelled and returns immediately when there is no store.
+func (f *Flags) Start(ctx context.Context) {
+	if f.store == nil || f.interval <= 0 {
+		return
+	}
+	ticker := time.NewTicker(f.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := f.Refresh(ctx); err != nil && ctx.Err() == nil {
+				f.logger.Error("failed to refresh feature flags", zap.Error(err))
+			}
+		}
+	}
+}
@@ -0,0 +1,53 @@
+package feature
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+type staticStore map[Flag]bool
+
+func (s staticStore) LoadFlags(context.Context) (map[Flag]bool, error) { return s, nil }
+
+func TestParse(t *testing.T) {
+	values, err := Parse(" auto_release=false, connect_transfers=1 ")
+	require.NoError(t, err)
+	assert.Equal(t, map[Flag]bool{AutoRelease: false, ConnectTransfers: true}, values)
+
+	empty, err := Parse("")
+	require.NoError(t, err)
+	assert.Empty(t, empty)
+
+	_, err = Parse("warp_drive=true")
+	assert.ErrorContains(t, err, "unknown feature flag")
+
+	_, err = Parse("auto_release")
+	assert.Error(t, err)
+
+	_, err = Parse("auto_release=maybe")
+	assert.Error(t, err)
+}
+
+func TestEnabled_Precedence(t *testing.T) {
+	var nilFlags *Flags
+	assert.True(t, nilFlags.Enabled(AutoRelease), "nil flags fall back to defaults")
+	assert.False(t, nilFlags.Enabled(ConnectTransfers))
+	assert.False(t, nilFlags.Enabled(Flag("unknown")))
+
+	store := staticStore{ConnectTransfers: false, Flag("retired"): true}
+	flags := New(map[Flag]bool{AutoRelease: false, ConnectTransfers: true}, store, 0, zap.NewNop())
+	assert.False(t, flags.Enabled(AutoRelease), "env overrides default")
+	assert.True(t, flags.Enabled(ConnectTransfers), "env applies before the store is loaded")
+
+	require.NoError(t, flags.Refresh(context.Background()))
+	assert.False(t, flags.Enabled(ConnectTransfers), "store overrides env")
+	assert.False(t, flags.Enabled(Flag("retired")), "unknown stored flags are ignored")
+
+	store[ConnectTransfers] = true
+	require.NoError(t, flags.Refresh(context.Background()))
+	assert.True(t, flags.Enabled(ConnectTransfers), "runtime toggle takes effect on refresh")
+}
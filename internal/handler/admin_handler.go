@@ -1,27 +1,49 @@
 package handler
 
 import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"net/http"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
 
 	"github.com/Kilat-Pet-Delivery/lib-common/auth"
 	"github.com/Kilat-Pet-Delivery/lib-common/middleware"
 	"github.com/Kilat-Pet-Delivery/lib-common/response"
 	"github.com/Kilat-Pet-Delivery/service-payment/internal/application"
+	"github.com/Kilat-Pet-Delivery/service-payment/internal/domain/payment"
+	"github.com/Kilat-Pet-Delivery/service-payment/internal/domain/refund"
+	"github.com/Kilat-Pet-Delivery/service-payment/internal/pagination"
 )
 
 // AdminPaymentHandler handles admin HTTP requests for payment management.
 type AdminPaymentHandler struct {
-	paymentService *application.PaymentService
-	promoService   *application.PromoService
+	paymentService  *application.PaymentService
+	promoService    *application.PromoService
+	replayService   *application.ReplayService
+	callbackService *application.CallbackService
+	pageLimits      pagination.Limits
 }
 
 // NewAdminPaymentHandler creates a new AdminPaymentHandler.
-func NewAdminPaymentHandler(paymentService *application.PaymentService, promoService *application.PromoService) *AdminPaymentHandler {
+func NewAdminPaymentHandler(
+	paymentService *application.PaymentService,
+	promoService *application.PromoService,
+	replayService *application.ReplayService,
+	callbackService *application.CallbackService,
+	pageLimits pagination.Limits,
+) *AdminPaymentHandler {
 	return &AdminPaymentHandler{
-		paymentService: paymentService,
-		promoService:   promoService,
+		paymentService:  paymentService,
+		promoService:    promoService,
+		replayService:   replayService,
+		callbackService: callbackService,
+		pageLimits:      pageLimits,
 	}
 }
 
@@ -31,39 +53,552 @@ func (h *AdminPaymentHandler) RegisterRoutes(r *gin.RouterGroup, jwtManager *aut
 	adminRole := middleware.RequireRole(auth.RoleAdmin)
 
 	admin := r.Group("/admin")
-	admin.Use(authMW, adminRole)
+	admin.Use(authMW, adminRole, actorMiddleware())
 	{
 		admin.GET("/payments", h.ListPayments)
+		admin.GET("/payments/export", h.ExportPayments)
+		admin.GET("/payments/search", h.SearchPayments)
+		admin.GET("/payments/aging", h.EscrowAging)
+		admin.GET("/payments/:id/history", h.PaymentHistory)
+		admin.GET("/payments/:id/timeline", h.PaymentTimeline)
+		admin.GET("/payments/:id/callbacks", h.PaymentCallbacks)
+		admin.PATCH("/payments/:id/fee", h.OverridePaymentFee)
+		admin.POST("/payments/:id/clawback", h.ClawbackPayment)
+		admin.POST("/payments/:id/resolve-dispute", h.ResolveDispute)
+		admin.POST("/payments/:id/refund-request", h.RequestRefund)
+		admin.POST("/refund-requests/:id/approve", h.ApproveRefundRequest)
+		admin.POST("/owners/:ownerId/refund-all", h.RefundOwnerPayments)
+		admin.POST("/payments/replay", h.ReplayPaymentEvents)
+		admin.POST("/payments/recompute-fees", h.RecomputeFees)
+		admin.GET("/escrow/balance", h.EscrowBalance)
 		admin.GET("/stats/payments", h.PaymentStats)
 		admin.GET("/promos", h.ListPromos)
+		admin.GET("/promos/upcoming", h.ListUpcomingPromos)
+		admin.GET("/promos/:id/stats", h.PromoStats)
 	}
 }
 
 // ListPayments handles GET /api/v1/admin/payments.
 func (h *AdminPaymentHandler) ListPayments(c *gin.Context) {
-	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
-	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "20"))
-	if page < 1 {
-		page = 1
+	page := pagination.Parse(c, h.pageLimits)
+
+	filter, err := parsePaymentListFilter(c)
+	if err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+
+	payments, total, err := h.paymentService.ListAllPayments(c.Request.Context(), filter, page.Page, page.Limit)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	response.Paginated(c, payments, total, page.Page, page.Limit)
+}
+
+// SearchPayments handles GET /api/v1/admin/payments/search.
+func (h *AdminPaymentHandler) SearchPayments(c *gin.Context) {
+	payments, err := h.paymentService.SearchPayments(c.Request.Context(), c.Query("q"))
+	if err != nil {
+		if errors.Is(err, application.ErrInvalidSearchQuery) {
+			response.BadRequest(c, err.Error())
+			return
+		}
+		respondError(c, err)
+		return
+	}
+
+	response.Success(c, payments)
+}
+
+// EscrowAging handles GET /api/v1/admin/payments/aging.
+func (h *AdminPaymentHandler) EscrowAging(c *gin.Context) {
+	aging, err := h.paymentService.GetEscrowAging(c.Request.Context())
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	response.Success(c, aging)
+}
+
+// EscrowBalance handles GET /api/v1/admin/escrow/balance.
+func (h *AdminPaymentHandler) EscrowBalance(c *gin.Context) {
+	balance, err := h.paymentService.GetEscrowBalance(c.Request.Context())
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	response.Success(c, balance)
+}
+
+// PaymentHistory handles GET /api/v1/admin/payments/:id/history.
+func (h *AdminPaymentHandler) PaymentHistory(c *gin.Context) {
+	paymentID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		response.BadRequest(c, "invalid payment ID")
+		return
+	}
+
+	history, err := h.paymentService.GetPaymentHistory(c.Request.Context(), paymentID)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	response.Success(c, history)
+}
+
+// PaymentTimeline handles GET /api/v1/admin/payments/:id/timeline.
+func (h *AdminPaymentHandler) PaymentTimeline(c *gin.Context) {
+	paymentID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		response.BadRequest(c, "invalid payment ID")
+		return
+	}
+
+	timeline, err := h.paymentService.GetPaymentTimeline(c.Request.Context(), paymentID)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	response.Success(c, timeline)
+}
+
+// OverridePaymentFee handles PATCH /api/v1/admin/payments/:id/fee.
+func (h *AdminPaymentHandler) OverridePaymentFee(c *gin.Context) {
+	paymentID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		response.BadRequest(c, "invalid payment ID")
+		return
+	}
+
+	var req application.OverrideFeeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+
+	dto, err := h.paymentService.OverridePlatformFee(c.Request.Context(), paymentID, *req.PlatformFeeCents)
+	if err != nil {
+		if errors.Is(err, payment.ErrInvalidFeeSplit) {
+			response.BadRequest(c, err.Error())
+			return
+		}
+		respondError(c, err)
+		return
+	}
+
+	response.Success(c, dto)
+}
+
+// ClawbackPayment handles POST /api/v1/admin/payments/:id/clawback.
+func (h *AdminPaymentHandler) ClawbackPayment(c *gin.Context) {
+	paymentID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		response.BadRequest(c, "invalid payment ID")
+		return
+	}
+
+	var req application.ClawbackRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+
+	dto, err := h.paymentService.ClawbackPayment(c.Request.Context(), paymentID, req)
+	if err != nil {
+		if errors.Is(err, application.ErrInvalidRefundReason) {
+			response.BadRequest(c, err.Error())
+			return
+		}
+		respondError(c, err)
+		return
+	}
+
+	response.Success(c, dto)
+}
+
+// ResolveDispute handles POST /api/v1/admin/payments/:id/resolve-dispute.
+func (h *AdminPaymentHandler) ResolveDispute(c *gin.Context) {
+	paymentID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		response.BadRequest(c, "invalid payment ID")
+		return
+	}
+
+	var req application.ResolveDisputeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+
+	dto, err := h.paymentService.ResolveDispute(c.Request.Context(), paymentID, req)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	response.Success(c, dto)
+}
+
+// RequestRefund handles POST /api/v1/admin/payments/:id/refund-request. It
+// responds 200 with the refunded payment below the approval threshold and 202
+// with the pending request otherwise.
+func (h *AdminPaymentHandler) RequestRefund(c *gin.Context) {
+	paymentID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		response.BadRequest(c, "invalid payment ID")
+		return
+	}
+	adminID, ok := middleware.GetUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	var req application.RefundRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+
+	outcome, err := h.paymentService.RequestRefund(c.Request.Context(), paymentID, adminID, req)
+	if err != nil {
+		if errors.Is(err, application.ErrInvalidRefundReason) || errors.Is(err, application.ErrInvalidRefundMethod) ||
+			errors.Is(err, payment.ErrRefundWindowExpired) {
+			c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+			return
+		}
+		respondError(c, err)
+		return
+	}
+
+	if outcome.Outcome == application.RefundOutcomePendingApproval {
+		c.JSON(http.StatusAccepted, gin.H{"data": outcome})
+		return
+	}
+	response.Success(c, outcome)
+}
+
+// ApproveRefundRequest handles POST /api/v1/admin/refund-requests/:id/approve.
+func (h *AdminPaymentHandler) ApproveRefundRequest(c *gin.Context) {
+	requestID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		response.BadRequest(c, "invalid refund request ID")
+		return
+	}
+	adminID, ok := middleware.GetUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	dto, err := h.paymentService.ApproveRefundRequest(c.Request.Context(), requestID, adminID)
+	if err != nil {
+		switch {
+		case errors.Is(err, refund.ErrSelfApproval):
+			c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		case errors.Is(err, refund.ErrNotPending):
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		case errors.Is(err, payment.ErrRefundWindowExpired):
+			c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+		default:
+			respondError(c, err)
+		}
+		return
 	}
-	if limit < 1 || limit > 100 {
-		limit = 20
+
+	response.Success(c, dto)
+}
+
+// RefundOwnerPayments handles POST /api/v1/admin/owners/:ownerId/refund-all.
+// It refunds every held payment of the owner and responds 200 with the
+// outcome of each, including the ones that failed.
+func (h *AdminPaymentHandler) RefundOwnerPayments(c *gin.Context) {
+	ownerID, err := uuid.Parse(c.Param("ownerId"))
+	if err != nil {
+		response.BadRequest(c, "invalid owner ID")
+		return
+	}
+	adminID, ok := middleware.GetUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
 	}
 
-	payments, total, err := h.paymentService.ListAllPayments(c.Request.Context(), page, limit)
+	var req application.RefundRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+
+	result, err := h.paymentService.RefundOwnerPayments(c.Request.Context(), ownerID, adminID, req)
 	if err != nil {
-		response.Error(c, err)
+		if errors.Is(err, application.ErrInvalidRefundReason) || errors.Is(err, application.ErrInvalidRefundMethod) {
+			c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+			return
+		}
+		respondError(c, err)
 		return
 	}
 
-	response.Paginated(c, payments, total, page, limit)
+	response.Success(c, result)
 }
 
-// PaymentStats handles GET /api/v1/admin/stats/payments.
+// PaymentCallbacks handles GET /api/v1/admin/payments/:id/callbacks.
+func (h *AdminPaymentHandler) PaymentCallbacks(c *gin.Context) {
+	paymentID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		response.BadRequest(c, "invalid payment ID")
+		return
+	}
+
+	callbacks, err := h.callbackService.GetPaymentCallbacks(c.Request.Context(), paymentID)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	response.Success(c, callbacks)
+}
+
+// ReplayPaymentEvents handles POST /api/v1/admin/payments/replay.
+// It republishes the payment events that occurred in [from, to) and requires
+// confirm=true so a stray request cannot flood payment.events.
+func (h *AdminPaymentHandler) ReplayPaymentEvents(c *gin.Context) {
+	if c.Query("confirm") != "true" {
+		response.BadRequest(c, "replay republishes events to downstream consumers; pass confirm=true to proceed")
+		return
+	}
+
+	req, err := parseReplayRequest(c)
+	if err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+
+	result, err := h.replayService.Replay(c.Request.Context(), req)
+	if err != nil {
+		if errors.Is(err, application.ErrInvalidReplayRequest) {
+			response.BadRequest(c, err.Error())
+			return
+		}
+		respondError(c, err)
+		return
+	}
+
+	response.Success(c, result)
+}
+
+// RecomputeFees handles POST /api/v1/admin/payments/recompute-fees. It is a
+// dry run unless apply=true is passed.
+func (h *AdminPaymentHandler) RecomputeFees(c *gin.Context) {
+	filter, err := parsePaymentListFilter(c)
+	if err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+
+	result, err := h.paymentService.RecomputeFees(c.Request.Context(), application.RecomputeFeesRequest{
+		Filter: filter,
+		Apply:  c.Query("apply") == "true",
+	})
+	if err != nil {
+		if errors.Is(err, application.ErrInvalidRecomputeRequest) {
+			response.BadRequest(c, err.Error())
+			return
+		}
+		respondError(c, err)
+		return
+	}
+
+	response.Success(c, result)
+}
+
+// parseReplayRequest reads the required from/to range and the optional
+// comma-separated type filter. A date-only to includes that whole day.
+func parseReplayRequest(c *gin.Context) (application.ReplayRequest, error) {
+	var req application.ReplayRequest
+
+	fromStr, toStr := c.Query("from"), c.Query("to")
+	if fromStr == "" || toStr == "" {
+		return req, fmt.Errorf("from and to are required")
+	}
+	from, _, err := parseFilterTime(fromStr)
+	if err != nil {
+		return req, fmt.Errorf("invalid from (use RFC3339 or YYYY-MM-DD)")
+	}
+	to, dateOnly, err := parseFilterTime(toStr)
+	if err != nil {
+		return req, fmt.Errorf("invalid to (use RFC3339 or YYYY-MM-DD)")
+	}
+	if dateOnly {
+		to = to.AddDate(0, 0, 1)
+	}
+	req.From, req.To = from, to
+
+	if v := c.Query("type"); v != "" {
+		for _, t := range strings.Split(v, ",") {
+			if t = strings.TrimSpace(t); t != "" {
+				req.Types = append(req.Types, t)
+			}
+		}
+	}
+	return req, nil
+}
+
+// csvHeader lists the columns written by ExportPayments.
+var csvHeader = []string{
+	"id", "booking_id", "owner_id", "runner_id", "escrow_status",
+	"amount_cents", "platform_fee_cents", "runner_payout_cents", "currency",
+	"created_at", "escrow_held_at", "escrow_released_at", "refunded_at", "updated_at",
+	"customer_email",
+}
+
+// ExportPayments handles GET /api/v1/admin/payments/export.
+// It accepts the same from/to/status filters as ListPayments and streams a CSV.
+func (h *AdminPaymentHandler) ExportPayments(c *gin.Context) {
+	filter, err := parsePaymentListFilter(c)
+	if err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+
+	c.Header("Content-Type", "text/csv")
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, exportFilename(filter)))
+
+	w := csv.NewWriter(c.Writer)
+	wroteHeader := false
+	err = h.paymentService.ExportPayments(c.Request.Context(), filter, func(p application.AdminPaymentDTO) error {
+		if !wroteHeader {
+			if err := w.Write(csvHeader); err != nil {
+				return err
+			}
+			wroteHeader = true
+		}
+		if err := w.Write(csvSafeRow(paymentCSVRow(p))); err != nil {
+			return err
+		}
+		w.Flush()
+		return w.Error()
+	})
+	if err != nil && !wroteHeader {
+		// Nothing has been written yet, so a normal error response is still possible.
+		c.Writer.Header().Del("Content-Disposition")
+		respondError(c, err)
+		return
+	}
+	if !wroteHeader {
+		_ = w.Write(csvHeader)
+	}
+	w.Flush()
+	if err != nil {
+		// Headers are already sent; abort so the truncated body is not mistaken for a full export.
+		_ = c.Error(err)
+		c.Abort()
+	}
+}
+
+// parsePaymentListFilter reads the from/to/status/include_test query
+// parameters shared by the admin list and export endpoints. Dates may be
+// RFC3339 timestamps or YYYY-MM-DD; a date-only "to" includes the whole day.
+func parsePaymentListFilter(c *gin.Context) (application.PaymentListFilter, error) {
+	filter := application.PaymentListFilter{Status: c.Query("status"), IncludeTest: c.Query("include_test") == "true"}
+
+	if v := c.Query("from"); v != "" {
+		from, _, err := parseFilterTime(v)
+		if err != nil {
+			return filter, fmt.Errorf("invalid from (use RFC3339 or YYYY-MM-DD)")
+		}
+		filter.From = &from
+	}
+	if v := c.Query("to"); v != "" {
+		to, dateOnly, err := parseFilterTime(v)
+		if err != nil {
+			return filter, fmt.Errorf("invalid to (use RFC3339 or YYYY-MM-DD)")
+		}
+		if dateOnly {
+			to = to.AddDate(0, 0, 1)
+		}
+		filter.To = &to
+	}
+	return filter, nil
+}
+
+func parseFilterTime(v string) (time.Time, bool, error) {
+	if t, err := time.Parse(time.DateOnly, v); err == nil {
+		return t.UTC(), true, nil
+	}
+	t, err := time.Parse(time.RFC3339, v)
+	return t.UTC(), false, err
+}
+
+// exportFilename builds the attachment name from the requested date range.
+func exportFilename(filter application.PaymentListFilter) string {
+	from, to := "start", "now"
+	if filter.From != nil {
+		from = filter.From.Format(time.DateOnly)
+	}
+	if filter.To != nil {
+		// To is exclusive, so name the file after the last included day.
+		to = filter.To.Add(-time.Nanosecond).Format(time.DateOnly)
+	}
+	return fmt.Sprintf("payments_%s_%s.csv", from, to)
+}
+
+func paymentCSVRow(p application.AdminPaymentDTO) []string {
+	runnerID := ""
+	if p.RunnerID != nil {
+		runnerID = p.RunnerID.String()
+	}
+	return []string{
+		p.ID.String(),
+		p.BookingID.String(),
+		p.OwnerID.String(),
+		runnerID,
+		p.EscrowStatus,
+		strconv.FormatInt(p.AmountCents, 10),
+		strconv.FormatInt(p.PlatformFeeCents, 10),
+		strconv.FormatInt(p.RunnerPayoutCents, 10),
+		p.Currency,
+		p.CreatedAt.UTC().Format(time.RFC3339),
+		formatOptionalTime(p.EscrowHeldAt),
+		formatOptionalTime(p.EscrowReleasedAt),
+		formatOptionalTime(p.RefundedAt),
+		p.UpdatedAt.UTC().Format(time.RFC3339),
+		p.CustomerEmail,
+	}
+}
+
+// csvSafeRow prefixes cells that a spreadsheet would evaluate as a formula,
+// such as an owner-entered email starting with "=", with a quote so they are
+// read as text.
+func csvSafeRow(row []string) []string {
+	for i, cell := range row {
+		if cell != "" && strings.ContainsRune("=+-@\t\r", rune(cell[0])) {
+			row[i] = "'" + cell
+		}
+	}
+	return row
+}
+
+func formatOptionalTime(t *time.Time) string {
+	if t == nil {
+		return ""
+	}
+	return t.UTC().Format(time.RFC3339)
+}
+
+// PaymentStats handles GET /api/v1/admin/stats/payments. Test payments are
+// left out unless include_test=true.
 func (h *AdminPaymentHandler) PaymentStats(c *gin.Context) {
-	stats, err := h.paymentService.GetPaymentStats(c.Request.Context())
+	stats, err := h.paymentService.GetPaymentStats(c.Request.Context(), c.Query("include_test") == "true")
 	if err != nil {
-		response.Error(c, err)
+		respondError(c, err)
 		return
 	}
 
@@ -71,10 +606,58 @@ func (h *AdminPaymentHandler) PaymentStats(c *gin.Context) {
 }
 
 // ListPromos handles GET /api/v1/admin/promos.
+// With created_by it returns a page of that admin's promos with usage stats;
+// otherwise it returns the currently active promos.
 func (h *AdminPaymentHandler) ListPromos(c *gin.Context) {
+	if raw, ok := c.GetQuery("created_by"); ok {
+		createdBy, err := uuid.Parse(raw)
+		if err != nil {
+			response.BadRequest(c, "invalid created_by: must be a UUID")
+			return
+		}
+
+		page := pagination.Parse(c, h.pageLimits)
+		promos, total, err := h.promoService.ListPromosByCreator(c.Request.Context(), createdBy, page.Page, page.Limit)
+		if err != nil {
+			respondError(c, err)
+			return
+		}
+
+		response.Paginated(c, promos, total, page.Page, page.Limit)
+		return
+	}
+
 	promos, err := h.promoService.GetActivePromos(c.Request.Context())
 	if err != nil {
-		response.Error(c, err)
+		respondError(c, err)
+		return
+	}
+
+	response.Success(c, promos)
+}
+
+// PromoStats handles GET /api/v1/admin/promos/:id/stats.
+func (h *AdminPaymentHandler) PromoStats(c *gin.Context) {
+	promoID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		response.BadRequest(c, "invalid promo ID")
+		return
+	}
+
+	stats, err := h.promoService.GetPromoStats(c.Request.Context(), promoID)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	response.Success(c, stats)
+}
+
+// ListUpcomingPromos handles GET /api/v1/admin/promos/upcoming.
+func (h *AdminPaymentHandler) ListUpcomingPromos(c *gin.Context) {
+	promos, err := h.promoService.GetUpcomingPromos(c.Request.Context())
+	if err != nil {
+		respondError(c, err)
 		return
 	}
 
@@ -0,0 +1,96 @@
+package handler
+
+import (
+	"context"
+	"encoding/csv"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/Kilat-Pet-Delivery/service-payment/internal/application"
+	"github.com/Kilat-Pet-Delivery/service-payment/internal/domain/payment"
+	"github.com/Kilat-Pet-Delivery/service-payment/internal/pagination"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func filterContext(query string) *gin.Context {
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Request = httptest.NewRequest("GET", "/api/v1/admin/payments/export?"+query, nil)
+	return c
+}
+
+func TestParsePaymentListFilter_DateOnlyToIncludesWholeDay(t *testing.T) {
+	filter, err := parsePaymentListFilter(filterContext("from=2026-01-01&to=2026-01-31&status=held"))
+	require.NoError(t, err)
+
+	assert.Equal(t, "held", filter.Status)
+	assert.Equal(t, "2026-01-01T00:00:00Z", filter.From.Format("2006-01-02T15:04:05Z07:00"))
+	assert.Equal(t, "2026-02-01T00:00:00Z", filter.To.Format("2006-01-02T15:04:05Z07:00"))
+	assert.Equal(t, "payments_2026-01-01_2026-01-31.csv", exportFilename(filter))
+}
+
+func TestParsePaymentListFilter_InvalidDate(t *testing.T) {
+	_, err := parsePaymentListFilter(filterContext("from=yesterday"))
+	assert.Error(t, err)
+}
+
+func TestExportFilename_OpenRange(t *testing.T) {
+	filter, err := parsePaymentListFilter(filterContext(""))
+	require.NoError(t, err)
+	assert.Equal(t, "payments_start_now.csv", exportFilename(filter))
+}
+
+func TestListPromos_InvalidCreatedBy(t *testing.T) {
+	rec := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(rec)
+	c.Request = httptest.NewRequest("GET", "/api/v1/admin/promos?created_by=not-a-uuid", nil)
+
+	(&AdminPaymentHandler{}).ListPromos(c)
+
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+}
+
+// exportRepo streams a fixed list of payments.
+type exportRepo struct {
+	payment.PaymentRepository
+	payments []*payment.Payment
+}
+
+func (r *exportRepo) StreamAll(_ context.Context, _ payment.ListFilter, fn func(*payment.Payment) error) error {
+	for _, p := range r.payments {
+		if err := fn(p); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func TestExportPayments_NeutralizesFormulaCells(t *testing.T) {
+	p, err := payment.NewPayment(uuid.New(), uuid.New(), 10000, "MYR", 15.0, payment.PayoutFloors{})
+	require.NoError(t, err)
+	require.NoError(t, p.SetCustomerEmail("=1+2@x.com"))
+	svc := application.NewPaymentService(&exportRepo{payments: []*payment.Payment{p}}, nil, nil, nil, nil, nil, nil, nil, zap.NewNop())
+	h := NewAdminPaymentHandler(svc, nil, nil, nil, pagination.Limits{})
+
+	rec := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(rec)
+	c.Request = httptest.NewRequest("GET", "/api/v1/admin/payments/export", nil)
+	h.ExportPayments(c)
+
+	require.Equal(t, http.StatusOK, rec.Code)
+	rows, err := csv.NewReader(strings.NewReader(rec.Body.String())).ReadAll()
+	require.NoError(t, err)
+	require.Len(t, rows, 2)
+	assert.Equal(t, "'=1+2@x.com", rows[1][len(rows[1])-1], "the email is exported as text")
+	assert.Equal(t, "10000", rows[1][5], "ordinary cells are unchanged")
+}
+
+func TestCSVSafeRow(t *testing.T) {
+	row := csvSafeRow([]string{"=SUM(A1)", "+1", "-1", "@cmd", "\tx", "\rx", "a=b", "", "owner@example.com"})
+	assert.Equal(t, []string{"'=SUM(A1)", "'+1", "'-1", "'@cmd", "'\tx", "'\rx", "a=b", "", "owner@example.com"}, row)
+}
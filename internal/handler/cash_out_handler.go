@@ -95,7 +95,7 @@ func (h *CashOutHandler) CashOut(c *gin.Context) {
 			zap.String("destination_id", destID.String()),
 			zap.Error(err),
 		)
-		response.Error(c, err)
+		respondError(c, err)
 		return
 	}
 	if !owned {
@@ -110,7 +110,7 @@ func (h *CashOutHandler) CashOut(c *gin.Context) {
 			zap.String("runner_id", runnerID.String()),
 			zap.Error(err),
 		)
-		response.Error(c, err)
+		respondError(c, err)
 		return
 	}
 	totalRequired := req.AmountMyrCents + cashOutFeeCents
@@ -136,7 +136,7 @@ func (h *CashOutHandler) CashOut(c *gin.Context) {
 			zap.String("cash_out_id", cashOutID.String()),
 			zap.Error(err),
 		)
-		response.Error(c, err)
+		respondError(c, err)
 		return
 	}
 
@@ -0,0 +1,48 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/Kilat-Pet-Delivery/lib-common/auth"
+	"github.com/Kilat-Pet-Delivery/lib-common/middleware"
+	"github.com/Kilat-Pet-Delivery/lib-common/response"
+	"github.com/Kilat-Pet-Delivery/service-payment/internal/application"
+)
+
+// CreditHandler handles HTTP requests for in-app credit.
+type CreditHandler struct {
+	service *application.CreditService
+}
+
+// NewCreditHandler creates a new CreditHandler.
+func NewCreditHandler(service *application.CreditService) *CreditHandler {
+	return &CreditHandler{service: service}
+}
+
+// RegisterRoutes registers all credit routes.
+func (h *CreditHandler) RegisterRoutes(r *gin.RouterGroup, jwtManager *auth.JWTManager) {
+	credits := r.Group("/payments/credits")
+	credits.Use(middleware.AuthMiddleware(jwtManager))
+	{
+		credits.GET("/me", h.GetMyBalance)
+	}
+}
+
+// GetMyBalance handles GET /api/v1/payments/credits/me.
+func (h *CreditHandler) GetMyBalance(c *gin.Context) {
+	userID, ok := middleware.GetUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	result, err := h.service.Balance(c.Request.Context(), userID)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	response.Success(c, result)
+}
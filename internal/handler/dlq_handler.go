@@ -0,0 +1,64 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/Kilat-Pet-Delivery/lib-common/auth"
+	"github.com/Kilat-Pet-Delivery/lib-common/middleware"
+	"github.com/Kilat-Pet-Delivery/lib-common/response"
+	"github.com/Kilat-Pet-Delivery/service-payment/internal/application"
+)
+
+// dlqReplayer replays dead-lettered booking events.
+type dlqReplayer interface {
+	Replay(ctx context.Context, req application.DLQReplayRequest) (*application.DLQReplayResultDTO, error)
+}
+
+// DLQHandler handles admin HTTP requests for the booking events DLQ.
+type DLQHandler struct {
+	replayer dlqReplayer
+}
+
+// NewDLQHandler creates a new DLQHandler.
+func NewDLQHandler(replayer dlqReplayer) *DLQHandler {
+	return &DLQHandler{replayer: replayer}
+}
+
+// RegisterRoutes registers DLQ admin routes.
+func (h *DLQHandler) RegisterRoutes(r *gin.RouterGroup, jwtManager *auth.JWTManager) {
+	admin := r.Group("/admin/dlq")
+	admin.Use(middleware.AuthMiddleware(jwtManager), middleware.RequireRole(auth.RoleAdmin))
+	{
+		admin.POST("/replay", h.Replay)
+	}
+}
+
+// Replay handles POST /api/v1/admin/dlq/replay. The optional type and limit
+// query parameters select which events and how many are read.
+func (h *DLQHandler) Replay(c *gin.Context) {
+	req := application.DLQReplayRequest{Type: c.Query("type")}
+	if v := c.Query("limit"); v != "" {
+		limit, err := strconv.Atoi(v)
+		if err != nil || limit <= 0 {
+			response.BadRequest(c, "limit must be a positive integer")
+			return
+		}
+		req.Limit = limit
+	}
+
+	result, err := h.replayer.Replay(c.Request.Context(), req)
+	if err != nil {
+		if errors.Is(err, application.ErrInvalidDLQReplayRequest) {
+			response.BadRequest(c, err.Error())
+			return
+		}
+		respondError(c, err)
+		return
+	}
+
+	response.Success(c, result)
+}
@@ -0,0 +1,29 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/Kilat-Pet-Delivery/lib-common/domain"
+	"github.com/Kilat-Pet-Delivery/lib-common/response"
+	"github.com/gin-gonic/gin"
+)
+
+// respondError writes err as an HTTP error. Domain errors are mapped so that
+// callers can tell a stale write (409, safe to retry after re-reading) from a
+// request the resource's current state does not allow (422). Everything else
+// falls through to response.Error.
+func respondError(c *gin.Context, err error) {
+	var domErr *domain.DomainError
+	if errors.As(err, &domErr) {
+		switch domErr.Err {
+		case domain.ErrConflict:
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error(), "retryable": true})
+			return
+		case domain.ErrInvalidState:
+			c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+			return
+		}
+	}
+	response.Error(c, err)
+}
@@ -0,0 +1,44 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Kilat-Pet-Delivery/lib-common/domain"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRespondError_StatusCodes(t *testing.T) {
+	tests := []struct {
+		name      string
+		err       error
+		status    int
+		retryable bool
+	}{
+		{"not found", domain.NewNotFoundError("Payment", "abc"), http.StatusNotFound, false},
+		{"optimistic lock conflict", domain.NewConflictError("payment was modified by another transaction"), http.StatusConflict, true},
+		{"wrapped conflict", fmt.Errorf("refund: %w", domain.NewConflictError("stale")), http.StatusConflict, true},
+		{"invalid state", domain.NewInvalidStateError("released", "refunded"), http.StatusUnprocessableEntity, false},
+		{"unexpected", errors.New("connection reset"), http.StatusInternalServerError, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+
+			respondError(c, tt.err)
+
+			assert.Equal(t, tt.status, w.Code)
+			var body map[string]any
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+			assert.Equal(t, tt.retryable, body["retryable"] == true)
+		})
+	}
+}
@@ -0,0 +1,104 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+
+	"github.com/Kilat-Pet-Delivery/lib-common/auth"
+	"github.com/Kilat-Pet-Delivery/lib-common/middleware"
+	"github.com/Kilat-Pet-Delivery/lib-common/response"
+	"github.com/Kilat-Pet-Delivery/service-payment/internal/application"
+)
+
+// FeeScheduleHandler handles admin HTTP requests for platform fee schedules.
+type FeeScheduleHandler struct {
+	service *application.FeeScheduleService
+}
+
+// NewFeeScheduleHandler creates a new FeeScheduleHandler.
+func NewFeeScheduleHandler(service *application.FeeScheduleService) *FeeScheduleHandler {
+	return &FeeScheduleHandler{service: service}
+}
+
+// RegisterRoutes registers fee schedule admin routes.
+func (h *FeeScheduleHandler) RegisterRoutes(r *gin.RouterGroup, jwtManager *auth.JWTManager) {
+	authMW := middleware.AuthMiddleware(jwtManager)
+	adminRole := middleware.RequireRole(auth.RoleAdmin)
+
+	admin := r.Group("/admin/fee-schedules")
+	admin.Use(authMW, adminRole)
+	{
+		admin.GET("", h.ListFeeSchedules)
+		admin.POST("", h.CreateFeeSchedule)
+		admin.PUT("/:id", h.UpdateFeeSchedule)
+	}
+
+	config := r.Group("/admin/config")
+	config.Use(authMW, adminRole)
+	{
+		config.GET("/fees", h.GetFeeConfig)
+	}
+}
+
+// GetFeeConfig handles GET /api/v1/admin/config/fees.
+func (h *FeeScheduleHandler) GetFeeConfig(c *gin.Context) {
+	response.Success(c, h.service.GetFeeConfig())
+}
+
+// ListFeeSchedules handles GET /api/v1/admin/fee-schedules.
+func (h *FeeScheduleHandler) ListFeeSchedules(c *gin.Context) {
+	result, err := h.service.ListFeeSchedules(c.Request.Context())
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	response.Success(c, result)
+}
+
+// CreateFeeSchedule handles POST /api/v1/admin/fee-schedules.
+func (h *FeeScheduleHandler) CreateFeeSchedule(c *gin.Context) {
+	var req application.FeeScheduleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+
+	result, err := h.service.CreateFeeSchedule(c.Request.Context(), req)
+	if err != nil {
+		if errors.Is(err, application.ErrUnsupportedCurrency) {
+			c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+			return
+		}
+		respondError(c, err)
+		return
+	}
+
+	response.Created(c, result)
+}
+
+// UpdateFeeSchedule handles PUT /api/v1/admin/fee-schedules/:id.
+func (h *FeeScheduleHandler) UpdateFeeSchedule(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		response.BadRequest(c, "invalid fee schedule ID")
+		return
+	}
+
+	var req application.FeeScheduleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+
+	result, err := h.service.UpdateFeeSchedule(c.Request.Context(), id, req)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	response.Success(c, result)
+}
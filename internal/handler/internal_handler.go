@@ -0,0 +1,65 @@
+package handler
+
+import (
+	"crypto/subtle"
+	"net/http"
+
+	"github.com/Kilat-Pet-Delivery/lib-common/response"
+	"github.com/Kilat-Pet-Delivery/service-payment/internal/application"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// ServiceTokenHeader carries the shared token on service-to-service requests.
+const ServiceTokenHeader = "X-Service-Token"
+
+// InternalPaymentHandler serves read-only payment lookups to other services.
+type InternalPaymentHandler struct {
+	service *application.PaymentService
+	token   string
+}
+
+// NewInternalPaymentHandler creates a new InternalPaymentHandler. Requests must
+// present token in the X-Service-Token header; an empty token rejects every request.
+func NewInternalPaymentHandler(service *application.PaymentService, token string) *InternalPaymentHandler {
+	return &InternalPaymentHandler{service: service, token: token}
+}
+
+// RegisterRoutes registers the internal routes on the given router group.
+func (h *InternalPaymentHandler) RegisterRoutes(r *gin.RouterGroup) {
+	payments := r.Group("/payments")
+	payments.Use(serviceTokenAuth(h.token))
+	{
+		payments.GET("/booking/:bookingId/status", h.GetPaymentStatusByBooking)
+	}
+}
+
+// GetPaymentStatusByBooking handles GET /internal/payments/booking/:bookingId/status
+func (h *InternalPaymentHandler) GetPaymentStatusByBooking(c *gin.Context) {
+	bookingID, err := uuid.Parse(c.Param("bookingId"))
+	if err != nil {
+		response.BadRequest(c, "invalid booking ID")
+		return
+	}
+
+	dto, err := h.service.GetPaymentStatusByBooking(c.Request.Context(), bookingID)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	response.Success(c, dto)
+}
+
+// serviceTokenAuth only admits requests carrying the shared service token.
+// User JWTs are not accepted on internal routes.
+func serviceTokenAuth(token string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		presented := c.GetHeader(ServiceTokenHeader)
+		if token == "" || subtle.ConstantTimeCompare([]byte(presented), []byte(token)) != 1 {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "invalid service token"})
+			return
+		}
+		c.Next()
+	}
+}
@@ -0,0 +1,52 @@
+package handler
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func internalRouter(token string) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.GET("/internal/ping", serviceTokenAuth(token), func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+	return r
+}
+
+func TestServiceTokenAuth(t *testing.T) {
+	const userJWT = "eyJhbGciOiJIUzI1NiJ9.eyJzdWIiOiJ1c2VyIiwicm9sZSI6ImFkbWluIn0.sig"
+
+	tests := []struct {
+		name       string
+		configured string
+		header     string
+		value      string
+		status     int
+	}{
+		{"valid service token", "s3cret", ServiceTokenHeader, "s3cret", http.StatusOK},
+		{"missing token", "s3cret", "", "", http.StatusUnauthorized},
+		{"wrong token", "s3cret", ServiceTokenHeader, "guess", http.StatusUnauthorized},
+		{"user JWT as bearer", "s3cret", "Authorization", "Bearer " + userJWT, http.StatusUnauthorized},
+		{"user JWT as service token", "s3cret", ServiceTokenHeader, userJWT, http.StatusUnauthorized},
+		{"no token configured", "", ServiceTokenHeader, "", http.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/internal/ping", nil)
+			if tt.header != "" {
+				req.Header.Set(tt.header, tt.value)
+			}
+			w := httptest.NewRecorder()
+
+			internalRouter(tt.configured).ServeHTTP(w, req)
+
+			assert.Equal(t, tt.status, w.Code)
+		})
+	}
+}
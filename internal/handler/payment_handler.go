@@ -1,34 +1,48 @@
 package handler
 
 import (
+	"errors"
+	"fmt"
 	"net/http"
 
 	"github.com/Kilat-Pet-Delivery/lib-common/auth"
 	"github.com/Kilat-Pet-Delivery/lib-common/middleware"
 	"github.com/Kilat-Pet-Delivery/lib-common/response"
 	"github.com/Kilat-Pet-Delivery/service-payment/internal/application"
+	"github.com/Kilat-Pet-Delivery/service-payment/internal/domain/payment"
+	"github.com/Kilat-Pet-Delivery/service-payment/internal/domain/promo"
+	"github.com/Kilat-Pet-Delivery/service-payment/internal/ratelimit"
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 )
 
 // PaymentHandler handles HTTP requests for payment operations.
 type PaymentHandler struct {
-	service *application.PaymentService
+	service       *application.PaymentService
+	resendLimiter ratelimit.Store
 }
 
-// NewPaymentHandler creates a new PaymentHandler.
-func NewPaymentHandler(service *application.PaymentService) *PaymentHandler {
-	return &PaymentHandler{service: service}
+// NewPaymentHandler creates a new PaymentHandler. resendLimiter bounds receipt
+// resends per payment.
+func NewPaymentHandler(service *application.PaymentService, resendLimiter ratelimit.Store) *PaymentHandler {
+	return &PaymentHandler{service: service, resendLimiter: resendLimiter}
 }
 
 // RegisterRoutes registers all payment routes on the given router group.
 func (h *PaymentHandler) RegisterRoutes(r *gin.RouterGroup, jwtManager *auth.JWTManager) {
 	payments := r.Group("/payments")
-	payments.Use(middleware.AuthMiddleware(jwtManager))
+	payments.Use(middleware.AuthMiddleware(jwtManager), actorMiddleware())
 	{
 		payments.POST("/initiate", middleware.RequireRole(auth.RoleOwner), h.InitiatePayment)
+		payments.POST("/quote", middleware.RequireRole(auth.RoleOwner), h.QuotePayment)
 		payments.GET("/:id", h.GetPayment)
+		payments.GET("/:id/receipt", h.GetReceipt)
+		payments.POST("/:id/resend-receipt", h.ResendReceipt)
 		payments.GET("/booking/:bookingId", h.GetPaymentByBooking)
+		payments.GET("/runner/me/summary", middleware.RequireRole(auth.RoleRunner), h.GetRunnerPayoutSummary)
+		payments.POST("/booking/batch", h.GetPaymentStatusesByBookings)
+		payments.POST("/:id/retry", h.RetryPayment)
+		payments.POST("/:id/cancel", middleware.RequireRole(auth.RoleOwner), h.CancelPayment)
 		payments.POST("/:id/refund", middleware.RequireRole(auth.RoleAdmin), h.RefundPayment)
 	}
 }
@@ -49,13 +63,59 @@ func (h *PaymentHandler) InitiatePayment(c *gin.Context) {
 
 	dto, err := h.service.InitiatePayment(c.Request.Context(), userID, req)
 	if err != nil {
-		response.Error(c, err)
+		if errors.Is(err, payment.ErrAmountBelowFloors) || errors.Is(err, payment.ErrInvalidFeeSplit) ||
+			errors.Is(err, application.ErrInvalidCallbackURL) || errors.Is(err, promo.ErrFirstBookingOnly) ||
+			errors.Is(err, promo.ErrPlanNotEligible) || errors.Is(err, payment.ErrInvalidCustomerEmail) ||
+			errors.Is(err, application.ErrAmountFields) || errors.Is(err, payment.ErrInvalidAmount) ||
+			errors.Is(err, application.ErrTestPaymentsDisabled) {
+			response.BadRequest(c, err.Error())
+			return
+		}
+		if errors.Is(err, application.ErrUnsupportedCurrency) || errors.Is(err, application.ErrBookingAmountMismatch) ||
+			errors.Is(err, application.ErrBookingTotalUnknown) {
+			c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+			return
+		}
+		respondError(c, err)
 		return
 	}
 
 	response.Created(c, dto)
 }
 
+// QuotePayment handles POST /api/v1/payments/quote
+func (h *PaymentHandler) QuotePayment(c *gin.Context) {
+	userID, ok := middleware.GetUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	var req application.QuotePaymentRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+
+	dto, err := h.service.QuotePayment(c.Request.Context(), userID, req)
+	if err != nil {
+		if errors.Is(err, payment.ErrAmountBelowFloors) || errors.Is(err, payment.ErrInvalidFeeSplit) ||
+			errors.Is(err, promo.ErrFirstBookingOnly) || errors.Is(err, promo.ErrPlanNotEligible) ||
+			errors.Is(err, application.ErrAmountFields) || errors.Is(err, payment.ErrInvalidAmount) {
+			response.BadRequest(c, err.Error())
+			return
+		}
+		if errors.Is(err, application.ErrUnsupportedCurrency) {
+			c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+			return
+		}
+		respondError(c, err)
+		return
+	}
+
+	response.Success(c, dto)
+}
+
 // GetPayment handles GET /api/v1/payments/:id
 func (h *PaymentHandler) GetPayment(c *gin.Context) {
 	idStr := c.Param("id")
@@ -67,13 +127,199 @@ func (h *PaymentHandler) GetPayment(c *gin.Context) {
 
 	dto, err := h.service.GetPayment(c.Request.Context(), paymentID)
 	if err != nil {
-		response.Error(c, err)
+		respondError(c, err)
+		return
+	}
+	if f, ok := amountFormat(c); ok {
+		dto.AddFormatted(f)
+	}
+
+	response.Success(c, dto)
+}
+
+// GetReceipt handles GET /api/v1/payments/:id/receipt
+// Only the owner of the payment or an admin may view the receipt.
+func (h *PaymentHandler) GetReceipt(c *gin.Context) {
+	userID, ok := middleware.GetUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	paymentID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		response.BadRequest(c, "invalid payment ID")
+		return
+	}
+
+	receipt, err := h.service.GetReceipt(c.Request.Context(), paymentID)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	if receipt.OwnerID != userID && !isAdmin(c) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "payment does not belong to user"})
+		return
+	}
+	if f, ok := amountFormat(c); ok {
+		receipt.AddFormatted(f)
+	}
+
+	response.Success(c, receipt)
+}
+
+// ResendReceipt handles POST /api/v1/payments/:id/resend-receipt
+// Only the owner of the payment or an admin may have the receipt emailed again,
+// and each payment may only be resent a few times per hour.
+func (h *PaymentHandler) ResendReceipt(c *gin.Context) {
+	userID, ok := middleware.GetUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	paymentID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		response.BadRequest(c, "invalid payment ID")
+		return
+	}
+
+	existing, err := h.service.GetPayment(c.Request.Context(), paymentID)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	if existing.OwnerID != userID && !isAdmin(c) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "payment does not belong to user"})
+		return
+	}
+
+	// A limiter outage lets the resend through rather than failing it.
+	if allowed, retryAfter, err := h.resendLimiter.Allow(c.Request.Context(), paymentID.String()); err == nil && !allowed {
+		ratelimit.AbortTooManyRequests(c, retryAfter)
+		return
+	}
+
+	if err := h.service.ResendReceipt(c.Request.Context(), paymentID, userID); err != nil {
+		if errors.Is(err, application.ErrNoCustomerEmail) {
+			c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+			return
+		}
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusAccepted, gin.H{"data": gin.H{"payment_id": paymentID}})
+}
+
+// RetryPayment handles POST /api/v1/payments/:id/retry
+// Only the owner of the payment or an admin may retry a failed escrow.
+func (h *PaymentHandler) RetryPayment(c *gin.Context) {
+	userID, ok := middleware.GetUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	paymentID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		response.BadRequest(c, "invalid payment ID")
+		return
+	}
+
+	var req application.RetryPaymentRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+
+	existing, err := h.service.GetPayment(c.Request.Context(), paymentID)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	if existing.OwnerID != userID && !isAdmin(c) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "payment does not belong to user"})
+		return
+	}
+
+	dto, err := h.service.RetryPayment(c.Request.Context(), paymentID, req)
+	if err != nil {
+		respondError(c, err)
 		return
 	}
 
 	response.Success(c, dto)
 }
 
+// CancelPayment handles POST /api/v1/payments/:id/cancel
+// The owner may cancel their own held escrow before a runner is assigned.
+func (h *PaymentHandler) CancelPayment(c *gin.Context) {
+	userID, ok := middleware.GetUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	paymentID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		response.BadRequest(c, "invalid payment ID")
+		return
+	}
+
+	existing, err := h.service.GetPayment(c.Request.Context(), paymentID)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	if existing.OwnerID != userID {
+		c.JSON(http.StatusForbidden, gin.H{"error": "payment does not belong to user"})
+		return
+	}
+
+	dto, err := h.service.CancelByOwner(c.Request.Context(), paymentID)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	response.Success(c, dto)
+}
+
+// actorMiddleware attributes payment changes made during the request to the
+// authenticated caller, e.g. "owner:<id>", for the status history.
+// It must run after AuthMiddleware.
+func actorMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID, ok := middleware.GetUserID(c)
+		if ok {
+			role, _ := c.Get(middleware.ContextKeyRole)
+			actor := fmt.Sprintf("%v:%s", role, userID)
+			c.Request = c.Request.WithContext(payment.WithActor(c.Request.Context(), actor))
+		}
+		c.Next()
+	}
+}
+
+// amountFormat returns the display format for the caller's Accept-Language
+// when the request opts in to formatted amounts with format=true.
+func amountFormat(c *gin.Context) (application.AmountFormat, bool) {
+	if c.Query("format") != "true" {
+		return application.AmountFormat{}, false
+	}
+	return application.AmountFormatForLanguage(c.GetHeader("Accept-Language")), true
+}
+
+// isAdmin reports whether the authenticated caller has the admin role.
+func isAdmin(c *gin.Context) bool {
+	role, ok := c.Get(middleware.ContextKeyRole)
+	return ok && role == auth.RoleAdmin
+}
+
 // GetPaymentByBooking handles GET /api/v1/payments/booking/:bookingId
 func (h *PaymentHandler) GetPaymentByBooking(c *gin.Context) {
 	idStr := c.Param("bookingId")
@@ -85,13 +331,68 @@ func (h *PaymentHandler) GetPaymentByBooking(c *gin.Context) {
 
 	dto, err := h.service.GetPaymentByBooking(c.Request.Context(), bookingID)
 	if err != nil {
-		response.Error(c, err)
+		respondError(c, err)
 		return
 	}
+	if f, ok := amountFormat(c); ok {
+		dto.AddFormatted(f)
+	}
 
 	response.Success(c, dto)
 }
 
+// GetRunnerPayoutSummary handles GET /api/v1/payments/runner/me/summary.
+// from and to default to the current month; a date-only to includes that day.
+func (h *PaymentHandler) GetRunnerPayoutSummary(c *gin.Context) {
+	runnerID, ok := middleware.GetUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	window, err := parsePaymentListFilter(c)
+	if err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+
+	summary, err := h.service.GetRunnerPayoutSummary(c.Request.Context(), runnerID, window.From, window.To)
+	if err != nil {
+		if errors.Is(err, application.ErrInvalidSummaryRange) {
+			response.BadRequest(c, err.Error())
+			return
+		}
+		respondError(c, err)
+		return
+	}
+
+	response.Success(c, summary)
+}
+
+// GetPaymentStatusesByBookings handles POST /api/v1/payments/booking/batch
+// Non-admin callers only see their own payments.
+func (h *PaymentHandler) GetPaymentStatusesByBookings(c *gin.Context) {
+	userID, ok := middleware.GetUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	var req application.BatchPaymentStatusRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+
+	statuses, err := h.service.GetPaymentStatusesByBookings(c.Request.Context(), userID, isAdmin(c), req.BookingIDs)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	response.Success(c, statuses)
+}
+
 // RefundPayment handles POST /api/v1/payments/:id/refund
 func (h *PaymentHandler) RefundPayment(c *gin.Context) {
 	idStr := c.Param("id")
@@ -101,17 +402,20 @@ func (h *PaymentHandler) RefundPayment(c *gin.Context) {
 		return
 	}
 
-	var req struct {
-		Reason string `json:"reason" binding:"required"`
-	}
+	var req application.RefundRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
 		response.BadRequest(c, err.Error())
 		return
 	}
 
-	dto, err := h.service.RefundPayment(c.Request.Context(), paymentID, req.Reason)
+	dto, err := h.service.RefundPayment(c.Request.Context(), paymentID, req)
 	if err != nil {
-		response.Error(c, err)
+		if errors.Is(err, application.ErrInvalidRefundReason) || errors.Is(err, application.ErrInvalidRefundMethod) ||
+			errors.Is(err, payment.ErrRefundWindowExpired) || errors.Is(err, application.ErrRefundNeedsApproval) {
+			c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+			return
+		}
+		respondError(c, err)
 		return
 	}
 
@@ -0,0 +1,101 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/Kilat-Pet-Delivery/lib-common/auth"
+	"github.com/Kilat-Pet-Delivery/lib-common/domain"
+	"github.com/Kilat-Pet-Delivery/lib-common/middleware"
+	"github.com/Kilat-Pet-Delivery/service-payment/internal/adapter"
+	"github.com/Kilat-Pet-Delivery/service-payment/internal/application"
+	"github.com/Kilat-Pet-Delivery/service-payment/internal/domain/payment"
+	"github.com/Kilat-Pet-Delivery/service-payment/internal/saga"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+// refundInitiatorRepo holds one payment and records who refunded it, as the
+// GORM repository does from the actor on the update's context.
+type refundInitiatorRepo struct {
+	payment.PaymentRepository
+	p          *payment.Payment
+	refundedBy *payment.Initiator
+}
+
+func (r *refundInitiatorRepo) FindByID(_ context.Context, id uuid.UUID) (*payment.Payment, error) {
+	if r.p.ID() != id {
+		return nil, domain.NewNotFoundError("Payment", id.String())
+	}
+	return r.p, nil
+}
+
+func (r *refundInitiatorRepo) Update(ctx context.Context, p *payment.Payment) error {
+	for _, c := range p.StatusChanges() {
+		if c.To == payment.EscrowRefunded {
+			initiator := payment.ParseInitiator(payment.ActorFromContext(ctx))
+			r.refundedBy = &initiator
+		}
+	}
+	p.ClearStatusChanges()
+	return nil
+}
+
+// newRefundRouter serves the cancel and refund routes to userID with role,
+// attributing changes through actorMiddleware, for one held payment of ownerID.
+func newRefundRouter(t *testing.T, ownerID, userID uuid.UUID, role string) (*gin.Engine, *refundInitiatorRepo) {
+	t.Helper()
+	p, err := payment.NewPayment(uuid.New(), ownerID, 10000, "MYR", 15.0, payment.PayoutFloors{})
+	require.NoError(t, err)
+	require.NoError(t, p.HoldEscrow("pi_1", 0))
+	repo := &refundInitiatorRepo{p: p}
+
+	sagaSvc := saga.NewPaymentSagaService(repo, adapter.NewMockStripeAdapter(zap.NewNop()), nil, nil, nil, nil, nil, nil, 15.0, payment.PayoutFloors{}, 0, 0, zap.NewNop())
+	h := NewPaymentHandler(application.NewPaymentService(repo, nil, nil, nil, sagaSvc, nil, nil, nil, zap.NewNop()), nil)
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(func(c *gin.Context) {
+		c.Set(middleware.ContextKeyUserID, userID)
+		c.Set(middleware.ContextKeyRole, role)
+		c.Next()
+	}, actorMiddleware())
+	r.POST("/api/v1/payments/:id/cancel", h.CancelPayment)
+	r.POST("/api/v1/payments/:id/refund", h.RefundPayment)
+	return r, repo
+}
+
+func TestRefundRoutes_RecordInitiator(t *testing.T) {
+	ownerID, adminID := uuid.New(), uuid.New()
+	tests := []struct {
+		name   string
+		userID uuid.UUID
+		role   string
+		path   string
+		body   string
+		want   payment.Initiator
+	}{
+		{"owner cancellation", ownerID, auth.RoleOwner, "cancel", "", payment.Initiator{Type: "owner", ID: ownerID.String()}},
+		{"admin refund", adminID, auth.RoleAdmin, "refund", `{"reason":"runner no-show"}`, payment.Initiator{Type: "admin", ID: adminID.String()}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router, repo := newRefundRouter(t, ownerID, tt.userID, tt.role)
+
+			rec := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodPost, "/api/v1/payments/"+repo.p.ID().String()+"/"+tt.path, strings.NewReader(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+			router.ServeHTTP(rec, req)
+
+			require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+			require.NotNil(t, repo.refundedBy)
+			assert.Equal(t, tt.want, *repo.refundedBy)
+		})
+	}
+}
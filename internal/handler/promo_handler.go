@@ -1,6 +1,7 @@
 package handler
 
 import (
+	"errors"
 	"net/http"
 
 	"github.com/gin-gonic/gin"
@@ -9,16 +10,21 @@ import (
 	"github.com/Kilat-Pet-Delivery/lib-common/middleware"
 	"github.com/Kilat-Pet-Delivery/lib-common/response"
 	"github.com/Kilat-Pet-Delivery/service-payment/internal/application"
+	"github.com/Kilat-Pet-Delivery/service-payment/internal/domain/promo"
+	"github.com/Kilat-Pet-Delivery/service-payment/internal/domain/subscription"
+	"github.com/Kilat-Pet-Delivery/service-payment/internal/ratelimit"
 )
 
 // PromoHandler handles HTTP requests for promo code operations.
 type PromoHandler struct {
-	service *application.PromoService
+	service         *application.PromoService
+	validateLimiter ratelimit.Store
 }
 
-// NewPromoHandler creates a new PromoHandler.
-func NewPromoHandler(service *application.PromoService) *PromoHandler {
-	return &PromoHandler{service: service}
+// NewPromoHandler creates a new PromoHandler. validateLimiter throttles
+// promo code validation per user to prevent brute-forcing codes.
+func NewPromoHandler(service *application.PromoService, validateLimiter ratelimit.Store) *PromoHandler {
+	return &PromoHandler{service: service, validateLimiter: validateLimiter}
 }
 
 // RegisterRoutes registers all promo routes.
@@ -29,7 +35,7 @@ func (h *PromoHandler) RegisterRoutes(r *gin.RouterGroup, jwtManager *auth.JWTMa
 	promos.Use(authMW)
 	{
 		promos.POST("", middleware.RequireRole(auth.RoleAdmin), h.CreatePromo)
-		promos.POST("/validate", h.ValidatePromo)
+		promos.POST("/validate", ratelimit.PerUser(h.validateLimiter), h.ValidatePromo)
 		promos.GET("/active", h.GetActivePromos)
 	}
 }
@@ -50,7 +56,11 @@ func (h *PromoHandler) CreatePromo(c *gin.Context) {
 
 	result, err := h.service.CreatePromo(c.Request.Context(), userID, req)
 	if err != nil {
-		response.Error(c, err)
+		if errors.Is(err, promo.ErrInvalidDiscountType) || errors.Is(err, subscription.ErrInvalidPlan) {
+			c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+			return
+		}
+		respondError(c, err)
 		return
 	}
 
@@ -74,7 +84,7 @@ func (h *PromoHandler) ValidatePromo(c *gin.Context) {
 
 	result, err := h.service.ValidatePromo(c.Request.Context(), userID, req)
 	if err != nil {
-		response.Error(c, err)
+		respondError(c, err)
 		return
 	}
 
@@ -85,7 +95,7 @@ func (h *PromoHandler) ValidatePromo(c *gin.Context) {
 func (h *PromoHandler) GetActivePromos(c *gin.Context) {
 	result, err := h.service.GetActivePromos(c.Request.Context())
 	if err != nil {
-		response.Error(c, err)
+		respondError(c, err)
 		return
 	}
 
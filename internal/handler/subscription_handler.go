@@ -1,24 +1,30 @@
 package handler
 
 import (
+	"errors"
+	"fmt"
 	"net/http"
 
 	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
 
 	"github.com/Kilat-Pet-Delivery/lib-common/auth"
 	"github.com/Kilat-Pet-Delivery/lib-common/middleware"
 	"github.com/Kilat-Pet-Delivery/lib-common/response"
 	"github.com/Kilat-Pet-Delivery/service-payment/internal/application"
+	"github.com/Kilat-Pet-Delivery/service-payment/internal/domain/subscription"
+	"github.com/Kilat-Pet-Delivery/service-payment/internal/pagination"
 )
 
 // SubscriptionHandler handles HTTP requests for subscription operations.
 type SubscriptionHandler struct {
-	service *application.SubscriptionService
+	service    *application.SubscriptionService
+	pageLimits pagination.Limits
 }
 
 // NewSubscriptionHandler creates a new SubscriptionHandler.
-func NewSubscriptionHandler(service *application.SubscriptionService) *SubscriptionHandler {
-	return &SubscriptionHandler{service: service}
+func NewSubscriptionHandler(service *application.SubscriptionService, pageLimits pagination.Limits) *SubscriptionHandler {
+	return &SubscriptionHandler{service: service, pageLimits: pageLimits}
 }
 
 // RegisterRoutes registers all subscription routes.
@@ -31,6 +37,17 @@ func (h *SubscriptionHandler) RegisterRoutes(r *gin.RouterGroup, jwtManager *aut
 		subs.POST("", authMW, h.Subscribe)
 		subs.GET("/me", authMW, h.GetMySubscription)
 		subs.POST("/me/cancel", authMW, h.CancelSubscription)
+		subs.POST("/me/renew", authMW, h.RenewSubscription)
+		subs.POST("/me/pause", authMW, h.PauseSubscription)
+		subs.POST("/me/resume", authMW, h.ResumeSubscription)
+	}
+
+	admin := r.Group("/admin/subscriptions")
+	admin.Use(authMW, middleware.RequireRole(auth.RoleAdmin))
+	{
+		admin.GET("", h.ListUserSubscriptions)
+		admin.POST("/grant", h.GrantSubscription)
+		admin.GET("/:id", h.GetSubscriptionByID)
 	}
 }
 
@@ -40,7 +57,11 @@ func (h *SubscriptionHandler) GetPlans(c *gin.Context) {
 	response.Success(c, plans)
 }
 
-// Subscribe handles POST /api/v1/subscriptions.
+// maxIdempotencyKeyLength is the longest Idempotency-Key a signup may send.
+const maxIdempotencyKeyLength = 255
+
+// Subscribe handles POST /api/v1/subscriptions. A retry with the same
+// Idempotency-Key header returns the subscription the first request created.
 func (h *SubscriptionHandler) Subscribe(c *gin.Context) {
 	userID, ok := middleware.GetUserID(c)
 	if !ok {
@@ -54,9 +75,24 @@ func (h *SubscriptionHandler) Subscribe(c *gin.Context) {
 		return
 	}
 
-	result, err := h.service.Subscribe(c.Request.Context(), userID, req)
+	idempotencyKey := c.GetHeader("Idempotency-Key")
+	if len(idempotencyKey) > maxIdempotencyKeyLength {
+		response.BadRequest(c, fmt.Sprintf("Idempotency-Key must be at most %d characters", maxIdempotencyKeyLength))
+		return
+	}
+
+	result, err := h.service.Subscribe(c.Request.Context(), userID, req, idempotencyKey)
 	if err != nil {
-		response.Error(c, err)
+		if errors.Is(err, subscription.ErrInvalidPlan) {
+			c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+			return
+		}
+		// Not retryable: the existing subscription must end first.
+		if errors.Is(err, subscription.ErrActiveSubscriptionExists) || errors.Is(err, subscription.ErrPausedSubscriptionExists) {
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+			return
+		}
+		respondError(c, err)
 		return
 	}
 
@@ -73,7 +109,7 @@ func (h *SubscriptionHandler) GetMySubscription(c *gin.Context) {
 
 	result, err := h.service.GetMySubscription(c.Request.Context(), userID)
 	if err != nil {
-		response.Error(c, err)
+		respondError(c, err)
 		return
 	}
 
@@ -90,9 +126,127 @@ func (h *SubscriptionHandler) CancelSubscription(c *gin.Context) {
 
 	result, err := h.service.CancelSubscription(c.Request.Context(), userID)
 	if err != nil {
-		response.Error(c, err)
+		respondError(c, err)
+		return
+	}
+
+	response.Success(c, result)
+}
+
+// PauseSubscription handles POST /api/v1/subscriptions/me/pause.
+func (h *SubscriptionHandler) PauseSubscription(c *gin.Context) {
+	userID, ok := middleware.GetUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	result, err := h.service.PauseSubscription(c.Request.Context(), userID)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	response.Success(c, result)
+}
+
+// ResumeSubscription handles POST /api/v1/subscriptions/me/resume.
+func (h *SubscriptionHandler) ResumeSubscription(c *gin.Context) {
+	userID, ok := middleware.GetUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	result, err := h.service.ResumeSubscription(c.Request.Context(), userID)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	response.Success(c, result)
+}
+
+// RenewSubscription handles POST /api/v1/subscriptions/me/renew.
+func (h *SubscriptionHandler) RenewSubscription(c *gin.Context) {
+	userID, ok := middleware.GetUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	result, err := h.service.RenewSubscription(c.Request.Context(), userID)
+	if err != nil {
+		// Not retryable: retrying would renew a second period.
+		if errors.Is(err, subscription.ErrAlreadyRenewed) {
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+			return
+		}
+		respondError(c, err)
+		return
+	}
+
+	response.Success(c, result)
+}
+
+// GrantSubscription handles POST /api/v1/admin/subscriptions/grant.
+func (h *SubscriptionHandler) GrantSubscription(c *gin.Context) {
+	adminID, ok := middleware.GetUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	var req application.GrantSubscriptionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+
+	result, err := h.service.GrantSubscription(c.Request.Context(), adminID, req)
+	if err != nil {
+		if errors.Is(err, subscription.ErrInvalidPlan) || errors.Is(err, subscription.ErrInvalidGrant) {
+			c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+			return
+		}
+		respondError(c, err)
+		return
+	}
+
+	response.Created(c, result)
+}
+
+// GetSubscriptionByID handles GET /api/v1/admin/subscriptions/:id.
+func (h *SubscriptionHandler) GetSubscriptionByID(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		response.BadRequest(c, "invalid subscription ID")
+		return
+	}
+
+	result, err := h.service.GetSubscriptionByID(c.Request.Context(), id)
+	if err != nil {
+		respondError(c, err)
 		return
 	}
 
 	response.Success(c, result)
 }
+
+// ListUserSubscriptions handles GET /api/v1/admin/subscriptions?user_id=.
+func (h *SubscriptionHandler) ListUserSubscriptions(c *gin.Context) {
+	userID, err := uuid.Parse(c.Query("user_id"))
+	if err != nil {
+		response.BadRequest(c, "user_id query parameter must be a valid UUID")
+		return
+	}
+
+	page := pagination.Parse(c, h.pageLimits)
+	result, total, err := h.service.ListSubscriptionsByUser(c.Request.Context(), userID, page.Page, page.Limit)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	response.Paginated(c, result, total, page.Page, page.Limit)
+}
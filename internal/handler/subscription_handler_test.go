@@ -0,0 +1,122 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/Kilat-Pet-Delivery/lib-common/auth"
+	"github.com/Kilat-Pet-Delivery/lib-common/middleware"
+	"github.com/Kilat-Pet-Delivery/service-payment/internal/application"
+	"github.com/Kilat-Pet-Delivery/service-payment/internal/domain/subscription"
+	"github.com/Kilat-Pet-Delivery/service-payment/internal/pagination"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+// fakeSubRepo holds at most one subscription per user. findErr, when set, is
+// returned by the lookups instead.
+type fakeSubRepo struct {
+	subscription.SubscriptionRepository
+	subs    map[uuid.UUID]*subscription.Subscription
+	findErr error
+}
+
+func (r *fakeSubRepo) find(userID uuid.UUID, status subscription.SubStatus, notFound error) (*subscription.Subscription, error) {
+	if r.findErr != nil {
+		return nil, r.findErr
+	}
+	if s, ok := r.subs[userID]; ok && s.Status() == status {
+		return s, nil
+	}
+	return nil, notFound
+}
+
+func (r *fakeSubRepo) FindActiveByUserID(_ context.Context, userID uuid.UUID) (*subscription.Subscription, error) {
+	return r.find(userID, subscription.StatusActive, subscription.ErrNoActiveSubscription)
+}
+
+func (r *fakeSubRepo) FindPausedByUserID(_ context.Context, userID uuid.UUID) (*subscription.Subscription, error) {
+	return r.find(userID, subscription.StatusPaused, subscription.ErrNoPausedSubscription)
+}
+
+func (r *fakeSubRepo) ExpireLapsed(context.Context, uuid.UUID, time.Time) error { return nil }
+
+// newSubscriptionRouter serves the handler's user routes as userID.
+func newSubscriptionRouter(repo *fakeSubRepo, userID uuid.UUID) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	h := NewSubscriptionHandler(application.NewSubscriptionService(repo, nil, zap.NewNop()), pagination.Limits{Default: 20, Max: 100})
+	r := gin.New()
+	r.Use(func(c *gin.Context) {
+		c.Set(middleware.ContextKeyUserID, userID)
+		c.Set(middleware.ContextKeyRole, auth.RoleOwner)
+		c.Next()
+	})
+	subs := r.Group("/api/v1/subscriptions")
+	subs.POST("", h.Subscribe)
+	subs.GET("/me", h.GetMySubscription)
+	subs.POST("/me/cancel", h.CancelSubscription)
+	subs.POST("/me/pause", h.PauseSubscription)
+	subs.POST("/me/resume", h.ResumeSubscription)
+	subs.POST("/me/renew", h.RenewSubscription)
+	return r
+}
+
+func TestSubscriptionHandler_NoSubscriptionIsNotFound(t *testing.T) {
+	router := newSubscriptionRouter(&fakeSubRepo{}, uuid.New())
+
+	for _, route := range []struct{ method, path string }{
+		{http.MethodGet, "/api/v1/subscriptions/me"},
+		{http.MethodPost, "/api/v1/subscriptions/me/cancel"},
+		{http.MethodPost, "/api/v1/subscriptions/me/pause"},
+		{http.MethodPost, "/api/v1/subscriptions/me/resume"},
+		{http.MethodPost, "/api/v1/subscriptions/me/renew"},
+	} {
+		t.Run(route.path, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, httptest.NewRequest(route.method, route.path, nil))
+			assert.Equal(t, http.StatusNotFound, w.Code, w.Body.String())
+		})
+	}
+}
+
+func TestSubscriptionHandler_LookupFailureIsServerError(t *testing.T) {
+	router := newSubscriptionRouter(&fakeSubRepo{findErr: errors.New("connection reset")}, uuid.New())
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/v1/subscriptions/me", nil))
+
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+}
+
+func TestSubscriptionHandler_SubscribeWithExistingSubscriptionIsConflict(t *testing.T) {
+	userID := uuid.New()
+	active, err := subscription.NewSubscription(userID, subscription.PlanBasic)
+	require.NoError(t, err)
+	paused, err := subscription.NewSubscription(userID, subscription.PlanBasic)
+	require.NoError(t, err)
+	require.NoError(t, paused.Pause(time.Now().UTC()))
+
+	for name, existing := range map[string]*subscription.Subscription{"active": active, "paused": paused} {
+		t.Run(name, func(t *testing.T) {
+			repo := &fakeSubRepo{subs: map[uuid.UUID]*subscription.Subscription{userID: existing}}
+			router := newSubscriptionRouter(repo, userID)
+
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/api/v1/subscriptions", bytes.NewBufferString(`{"plan":"premium"}`)))
+
+			assert.Equal(t, http.StatusConflict, w.Code)
+			var body map[string]any
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+			assert.Nil(t, body["retryable"], "retrying cannot succeed while the subscription exists")
+		})
+	}
+}
@@ -0,0 +1,59 @@
+package handler
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/Kilat-Pet-Delivery/lib-common/response"
+	"github.com/Kilat-Pet-Delivery/service-payment/internal/adapter"
+	"github.com/Kilat-Pet-Delivery/service-payment/internal/application"
+	"github.com/gin-gonic/gin"
+)
+
+// maxWebhookBytes bounds the size of a Stripe webhook payload.
+const maxWebhookBytes = 64 << 10
+
+// StripeWebhookHandler receives Stripe webhooks, authenticated by their signature.
+type StripeWebhookHandler struct {
+	service *application.PaymentService
+	secret  string
+}
+
+// NewStripeWebhookHandler creates a new StripeWebhookHandler. secret is the
+// endpoint's signing secret; while it is empty every webhook is rejected.
+func NewStripeWebhookHandler(service *application.PaymentService, secret string) *StripeWebhookHandler {
+	return &StripeWebhookHandler{service: service, secret: secret}
+}
+
+// RegisterRoutes registers the webhook routes on the given router group.
+func (h *StripeWebhookHandler) RegisterRoutes(r *gin.RouterGroup) {
+	r.POST("/webhooks/stripe", h.HandleStripeWebhook)
+}
+
+// HandleStripeWebhook handles POST /api/v1/webhooks/stripe
+func (h *StripeWebhookHandler) HandleStripeWebhook(c *gin.Context) {
+	payload, err := io.ReadAll(io.LimitReader(c.Request.Body, maxWebhookBytes))
+	if err != nil {
+		response.BadRequest(c, "failed to read body")
+		return
+	}
+
+	event, err := adapter.ParseWebhookEvent(payload, c.GetHeader("Stripe-Signature"), h.secret, time.Now())
+	if err != nil {
+		if errors.Is(err, adapter.ErrInvalidWebhookSignature) {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid signature"})
+			return
+		}
+		response.BadRequest(c, err.Error())
+		return
+	}
+
+	if err := h.service.HandleStripeWebhook(c.Request.Context(), event); err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"received": true})
+}
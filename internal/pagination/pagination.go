@@ -0,0 +1,64 @@
+// Package pagination reads and clamps page/limit query parameters for list endpoints.
+package pagination
+
+import (
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Limits bounds the page size of list endpoints.
+type Limits struct {
+	// Default is used when limit is missing or not a positive integer.
+	Default int
+	// Max caps limit; larger values are clamped to it.
+	Max int
+}
+
+// DefaultLimits is a page of 20, at most 100.
+var DefaultLimits = Limits{Default: 20, Max: 100}
+
+// Normalize fills unset values from DefaultLimits and keeps Default within Max.
+func (l Limits) Normalize() Limits {
+	if l.Max <= 0 {
+		l.Max = DefaultLimits.Max
+	}
+	if l.Default <= 0 {
+		l.Default = DefaultLimits.Default
+	}
+	if l.Default > l.Max {
+		l.Default = l.Max
+	}
+	return l
+}
+
+// Params is a normalized page request. Page is 1-based.
+type Params struct {
+	Page  int
+	Limit int
+}
+
+// Offset returns the number of rows before the page.
+func (p Params) Offset() int {
+	return (p.Page - 1) * p.Limit
+}
+
+// Parse reads page and limit from the query string. A missing or invalid page
+// is the first page, a missing or invalid limit is l.Default, and a limit
+// above l.Max is clamped to it.
+func Parse(c *gin.Context, l Limits) Params {
+	l = l.Normalize()
+
+	page, err := strconv.Atoi(c.Query("page"))
+	if err != nil || page < 1 {
+		page = 1
+	}
+	limit, err := strconv.Atoi(c.Query("limit"))
+	if err != nil || limit < 1 {
+		limit = l.Default
+	}
+	if limit > l.Max {
+		limit = l.Max
+	}
+	return Params{Page: page, Limit: limit}
+}
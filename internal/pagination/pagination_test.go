@@ -0,0 +1,50 @@
+package pagination
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func contextWithQuery(query string) *gin.Context {
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Request = httptest.NewRequest("GET", "/items?"+query, nil)
+	return c
+}
+
+func TestParse(t *testing.T) {
+	limits := Limits{Default: 25, Max: 50}
+
+	tests := []struct {
+		name  string
+		query string
+		want  Params
+	}{
+		{"missing params", "", Params{Page: 1, Limit: 25}},
+		{"valid params", "page=3&limit=10", Params{Page: 3, Limit: 10}},
+		{"limit above max is clamped", "limit=500", Params{Page: 1, Limit: 50}},
+		{"zero limit uses default", "limit=0", Params{Page: 1, Limit: 25}},
+		{"negative limit uses default", "limit=-5", Params{Page: 1, Limit: 25}},
+		{"non-numeric limit uses default", "limit=all", Params{Page: 1, Limit: 25}},
+		{"zero page is first page", "page=0", Params{Page: 1, Limit: 25}},
+		{"negative page is first page", "page=-2", Params{Page: 1, Limit: 25}},
+		{"non-numeric page is first page", "page=last", Params{Page: 1, Limit: 25}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, Parse(contextWithQuery(tt.query), limits))
+		})
+	}
+}
+
+func TestLimitsNormalize(t *testing.T) {
+	assert.Equal(t, DefaultLimits, Limits{}.Normalize())
+	assert.Equal(t, Limits{Default: 10, Max: 10}, Limits{Default: 40, Max: 10}.Normalize())
+}
+
+func TestParamsOffset(t *testing.T) {
+	assert.Equal(t, 0, Params{Page: 1, Limit: 20}.Offset())
+	assert.Equal(t, 40, Params{Page: 3, Limit: 20}.Offset())
+}
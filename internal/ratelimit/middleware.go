@@ -0,0 +1,45 @@
+package ratelimit
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/Kilat-Pet-Delivery/lib-common/middleware"
+)
+
+// PerUser limits requests per authenticated user and responds 429 with a
+// Retry-After header once the limit is exceeded. It must run after
+// AuthMiddleware. Requests are let through if the store fails, so a limiter
+// outage does not take the endpoint down.
+func PerUser(store Store) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID, ok := middleware.GetUserID(c)
+		if !ok {
+			c.Next()
+			return
+		}
+
+		allowed, retryAfter, err := store.Allow(c.Request.Context(), userID.String())
+		if err != nil || allowed {
+			c.Next()
+			return
+		}
+
+		AbortTooManyRequests(c, retryAfter)
+	}
+}
+
+// AbortTooManyRequests responds 429 with a Retry-After header of retryAfter,
+// rounded up to whole seconds.
+func AbortTooManyRequests(c *gin.Context, retryAfter time.Duration) {
+	seconds := int(math.Ceil(retryAfter.Seconds()))
+	if seconds < 1 {
+		seconds = 1
+	}
+	c.Header("Retry-After", strconv.Itoa(seconds))
+	c.AbortWithStatusJSON(http.StatusTooManyRequests, gin.H{"error": "too many requests, please retry later"})
+}
@@ -0,0 +1,99 @@
+// Package ratelimit provides token-bucket rate limiting with a pluggable store.
+package ratelimit
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+)
+
+// Limit describes a token bucket: Rate tokens are added per second up to Burst.
+type Limit struct {
+	Rate  float64
+	Burst int
+}
+
+// PerMinute returns a Limit allowing n requests per minute with the given burst.
+func PerMinute(n int, burst int) Limit {
+	return Limit{Rate: float64(n) / 60, Burst: burst}
+}
+
+// PerHour returns a Limit allowing n requests per hour with the given burst.
+func PerHour(n int, burst int) Limit {
+	return Limit{Rate: float64(n) / 3600, Burst: burst}
+}
+
+// Store decides whether a request identified by key may proceed. When it may
+// not, retryAfter is how long until the next token is available. Implementations
+// must be safe for concurrent use; a Redis-backed store can replace MemoryStore
+// when the service runs with several replicas.
+type Store interface {
+	Allow(ctx context.Context, key string) (allowed bool, retryAfter time.Duration, err error)
+}
+
+// idleEviction is how long an untouched bucket is kept before being dropped.
+const idleEviction = 10 * time.Minute
+
+// MemoryStore is an in-process Store. Limits are per replica.
+type MemoryStore struct {
+	limit Limit
+	now   func() time.Time
+
+	mu        sync.Mutex
+	buckets   map[string]*bucket
+	lastSweep time.Time
+}
+
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// NewMemoryStore creates an in-memory token-bucket store.
+func NewMemoryStore(limit Limit) *MemoryStore {
+	return &MemoryStore{limit: limit, now: time.Now, buckets: make(map[string]*bucket)}
+}
+
+// Allow consumes a token for key if one is available.
+func (s *MemoryStore) Allow(_ context.Context, key string) (bool, time.Duration, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	s.sweep(now)
+
+	b, ok := s.buckets[key]
+	if !ok {
+		b = &bucket{tokens: float64(s.limit.Burst), last: now}
+		s.buckets[key] = b
+	}
+
+	b.tokens = math.Min(float64(s.limit.Burst), b.tokens+now.Sub(b.last).Seconds()*s.limit.Rate)
+	b.last = now
+
+	if b.tokens >= 1 {
+		b.tokens--
+		return true, 0, nil
+	}
+
+	if s.limit.Rate <= 0 {
+		return false, idleEviction, nil
+	}
+	wait := time.Duration((1 - b.tokens) / s.limit.Rate * float64(time.Second))
+	return false, wait, nil
+}
+
+// sweep drops buckets that have been idle long enough to be full again.
+func (s *MemoryStore) sweep(now time.Time) {
+	if now.Sub(s.lastSweep) < idleEviction {
+		return
+	}
+	s.lastSweep = now
+	for key, b := range s.buckets {
+		idle := now.Sub(b.last)
+		if idle >= idleEviction && b.tokens+idle.Seconds()*s.limit.Rate >= float64(s.limit.Burst) {
+			delete(s.buckets, key)
+		}
+	}
+}
@@ -0,0 +1,89 @@
+package ratelimit
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/Kilat-Pet-Delivery/lib-common/middleware"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeClock is a manually advanced time source.
+type fakeClock struct{ t time.Time }
+
+func (f *fakeClock) now() time.Time          { return f.t }
+func (f *fakeClock) advance(d time.Duration) { f.t = f.t.Add(d) }
+
+func newTestStore(limit Limit) (*MemoryStore, *fakeClock) {
+	clock := &fakeClock{t: time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)}
+	s := NewMemoryStore(limit)
+	s.now = clock.now
+	return s, clock
+}
+
+func TestMemoryStore_LimitTriggersAndResets(t *testing.T) {
+	store, clock := newTestStore(PerMinute(6, 2)) // one token every 10s
+	ctx := context.Background()
+
+	for i := 0; i < 2; i++ {
+		ok, _, err := store.Allow(ctx, "user-1")
+		require.NoError(t, err)
+		assert.True(t, ok, "burst request %d", i+1)
+	}
+
+	ok, retryAfter, err := store.Allow(ctx, "user-1")
+	require.NoError(t, err)
+	assert.False(t, ok)
+	assert.Equal(t, 10*time.Second, retryAfter)
+
+	ok, _, _ = store.Allow(ctx, "user-2")
+	assert.True(t, ok, "other users have their own bucket")
+
+	clock.advance(10 * time.Second)
+	ok, _, _ = store.Allow(ctx, "user-1")
+	assert.True(t, ok, "a token is refilled after the retry window")
+}
+
+func TestMemoryStore_SlowLimitSurvivesSweep(t *testing.T) {
+	store, clock := newTestStore(PerHour(1, 1))
+	ctx := context.Background()
+
+	ok, _, _ := store.Allow(ctx, "payment-1")
+	require.True(t, ok)
+
+	// Another key triggers a sweep long after payment-1 went idle, but before
+	// its bucket has refilled.
+	clock.advance(2 * idleEviction)
+	store.Allow(ctx, "payment-2")
+
+	ok, retryAfter, _ := store.Allow(ctx, "payment-1")
+	assert.False(t, ok, "an idle bucket that is not full again must not be dropped")
+	assert.Equal(t, time.Hour-2*idleEviction, retryAfter)
+}
+
+func TestPerUser_Returns429WithRetryAfter(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	store, _ := newTestStore(PerMinute(1, 1))
+	userID := uuid.New()
+
+	r := gin.New()
+	r.POST("/validate", func(c *gin.Context) {
+		c.Set(middleware.ContextKeyUserID, userID)
+		c.Next()
+	}, PerUser(store), func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/validate", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/validate", nil))
+	assert.Equal(t, http.StatusTooManyRequests, w.Code)
+	assert.Equal(t, "60", w.Header().Get("Retry-After"))
+}
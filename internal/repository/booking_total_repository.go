@@ -0,0 +1,61 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	bookingDomain "github.com/Kilat-Pet-Delivery/service-payment/internal/domain/booking"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// BookingTotalModel is the GORM model for the booking_totals table.
+type BookingTotalModel struct {
+	BookingID   uuid.UUID `gorm:"type:uuid;primaryKey"`
+	OwnerID     uuid.UUID `gorm:"type:uuid;not null"`
+	AmountCents int64     `gorm:"not null"`
+	Currency    string    `gorm:"type:varchar(3);not null"`
+	CreatedAt   time.Time `gorm:"type:timestamptz;not null"`
+}
+
+// TableName sets the table name.
+func (BookingTotalModel) TableName() string { return "booking_totals" }
+
+// GormBookingTotalRepository implements TotalRepository using GORM.
+type GormBookingTotalRepository struct {
+	db *gorm.DB
+}
+
+// NewGormBookingTotalRepository creates a new GormBookingTotalRepository.
+func NewGormBookingTotalRepository(db *gorm.DB) *GormBookingTotalRepository {
+	return &GormBookingTotalRepository{db: db}
+}
+
+// Save inserts a booking's total, leaving an existing row untouched so
+// redelivered events are harmless.
+func (r *GormBookingTotalRepository) Save(ctx context.Context, total bookingDomain.Total) error {
+	return r.db.WithContext(ctx).Exec(`
+		INSERT INTO booking_totals (booking_id, owner_id, amount_cents, currency, created_at)
+		VALUES (?, ?, ?, ?, ?)
+		ON CONFLICT (booking_id) DO NOTHING`,
+		total.BookingID, total.OwnerID, total.AmountCents, total.Currency, total.CreatedAt.UTC()).Error
+}
+
+// FindByBookingID returns a booking's total, or nil if none is cached.
+func (r *GormBookingTotalRepository) FindByBookingID(ctx context.Context, bookingID uuid.UUID) (*bookingDomain.Total, error) {
+	var model BookingTotalModel
+	if err := r.db.WithContext(ctx).Where("booking_id = ?", bookingID).First(&model).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &bookingDomain.Total{
+		BookingID:   model.BookingID,
+		OwnerID:     model.OwnerID,
+		AmountCents: model.AmountCents,
+		Currency:    model.Currency,
+		CreatedAt:   model.CreatedAt,
+	}, nil
+}
@@ -0,0 +1,178 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	callbackDomain "github.com/Kilat-Pet-Delivery/service-payment/internal/domain/callback"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// CallbackDeliveryModel is the GORM model for the payment_callbacks table.
+type CallbackDeliveryModel struct {
+	ID            uuid.UUID  `gorm:"type:uuid;primaryKey"`
+	PaymentID     uuid.UUID  `gorm:"type:uuid;not null;index"`
+	URL           string     `gorm:"type:text;not null"`
+	EventType     string     `gorm:"type:varchar(50);not null"`
+	Payload       []byte     `gorm:"type:jsonb;not null"`
+	Status        string     `gorm:"type:varchar(20);not null"`
+	Attempts      int        `gorm:"not null;default:0"`
+	NextAttemptAt time.Time  `gorm:"type:timestamptz;not null"`
+	LastError     string     `gorm:"type:text"`
+	DeliveredAt   *time.Time `gorm:"type:timestamptz"`
+	CreatedAt     time.Time  `gorm:"type:timestamptz;not null"`
+	UpdatedAt     time.Time  `gorm:"type:timestamptz;not null"`
+}
+
+// TableName sets the table name.
+func (CallbackDeliveryModel) TableName() string { return "payment_callbacks" }
+
+// CallbackAttemptModel is the GORM model for the payment_callback_attempts table.
+type CallbackAttemptModel struct {
+	ID          uuid.UUID `gorm:"type:uuid;primaryKey"`
+	DeliveryID  uuid.UUID `gorm:"type:uuid;not null;index"`
+	Number      int       `gorm:"not null"`
+	StatusCode  int       `gorm:"not null;default:0"`
+	Error       string    `gorm:"type:text"`
+	AttemptedAt time.Time `gorm:"type:timestamptz;not null"`
+}
+
+// TableName sets the table name.
+func (CallbackAttemptModel) TableName() string { return "payment_callback_attempts" }
+
+// GormCallbackRepository implements DeliveryRepository using GORM.
+type GormCallbackRepository struct {
+	db *gorm.DB
+}
+
+// NewGormCallbackRepository creates a new GormCallbackRepository.
+func NewGormCallbackRepository(db *gorm.DB) *GormCallbackRepository {
+	return &GormCallbackRepository{db: db}
+}
+
+// Save persists a new delivery.
+func (r *GormCallbackRepository) Save(ctx context.Context, d *callbackDomain.Delivery) error {
+	model := toCallbackDeliveryModel(d)
+	return r.db.WithContext(ctx).Create(&model).Error
+}
+
+// ClaimDue leases due pending deliveries in one statement. SKIP LOCKED lets
+// concurrent workers claim disjoint batches.
+func (r *GormCallbackRepository) ClaimDue(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]*callbackDomain.Delivery, error) {
+	var models []CallbackDeliveryModel
+	if err := r.db.WithContext(ctx).Raw(`
+		UPDATE payment_callbacks
+		SET next_attempt_at = ?
+		WHERE id IN (
+			SELECT id FROM payment_callbacks
+			WHERE status = ? AND next_attempt_at <= ?
+			ORDER BY next_attempt_at
+			LIMIT ?
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING *`,
+		now.Add(lease), string(callbackDomain.StatusPending), now, limit).
+		Scan(&models).Error; err != nil {
+		return nil, err
+	}
+
+	deliveries := make([]*callbackDomain.Delivery, len(models))
+	for i := range models {
+		deliveries[i] = toCallbackDelivery(&models[i])
+	}
+	return deliveries, nil
+}
+
+// RecordAttempt stores the attempt and the delivery's new state in one transaction.
+func (r *GormCallbackRepository) RecordAttempt(ctx context.Context, d *callbackDomain.Delivery, attempt callbackDomain.Attempt) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		model := toCallbackDeliveryModel(d)
+		if err := tx.Model(&CallbackDeliveryModel{}).Where("id = ?", d.ID).Select("*").Updates(&model).Error; err != nil {
+			return err
+		}
+		return tx.Create(&CallbackAttemptModel{
+			ID:          attempt.ID,
+			DeliveryID:  attempt.DeliveryID,
+			Number:      attempt.Number,
+			StatusCode:  attempt.StatusCode,
+			Error:       attempt.Error,
+			AttemptedAt: attempt.AttemptedAt,
+		}).Error
+	})
+}
+
+// FindByPaymentID returns a payment's deliveries, oldest first.
+func (r *GormCallbackRepository) FindByPaymentID(ctx context.Context, paymentID uuid.UUID) ([]*callbackDomain.Delivery, error) {
+	var models []CallbackDeliveryModel
+	if err := r.db.WithContext(ctx).
+		Where("payment_id = ?", paymentID).
+		Order("created_at ASC").
+		Find(&models).Error; err != nil {
+		return nil, err
+	}
+
+	deliveries := make([]*callbackDomain.Delivery, len(models))
+	for i := range models {
+		deliveries[i] = toCallbackDelivery(&models[i])
+	}
+	return deliveries, nil
+}
+
+// FindAttempts returns a delivery's attempts in order.
+func (r *GormCallbackRepository) FindAttempts(ctx context.Context, deliveryID uuid.UUID) ([]callbackDomain.Attempt, error) {
+	var models []CallbackAttemptModel
+	if err := r.db.WithContext(ctx).
+		Where("delivery_id = ?", deliveryID).
+		Order("number ASC").
+		Find(&models).Error; err != nil {
+		return nil, err
+	}
+
+	attempts := make([]callbackDomain.Attempt, len(models))
+	for i, m := range models {
+		attempts[i] = callbackDomain.Attempt{
+			ID:          m.ID,
+			DeliveryID:  m.DeliveryID,
+			Number:      m.Number,
+			StatusCode:  m.StatusCode,
+			Error:       m.Error,
+			AttemptedAt: m.AttemptedAt,
+		}
+	}
+	return attempts, nil
+}
+
+func toCallbackDeliveryModel(d *callbackDomain.Delivery) CallbackDeliveryModel {
+	return CallbackDeliveryModel{
+		ID:            d.ID,
+		PaymentID:     d.PaymentID,
+		URL:           d.URL,
+		EventType:     d.EventType,
+		Payload:       d.Payload,
+		Status:        string(d.Status),
+		Attempts:      d.Attempts,
+		NextAttemptAt: d.NextAttemptAt,
+		LastError:     d.LastError,
+		DeliveredAt:   d.DeliveredAt,
+		CreatedAt:     d.CreatedAt,
+		UpdatedAt:     d.UpdatedAt,
+	}
+}
+
+func toCallbackDelivery(m *CallbackDeliveryModel) *callbackDomain.Delivery {
+	return &callbackDomain.Delivery{
+		ID:            m.ID,
+		PaymentID:     m.PaymentID,
+		URL:           m.URL,
+		EventType:     m.EventType,
+		Payload:       m.Payload,
+		Status:        callbackDomain.Status(m.Status),
+		Attempts:      m.Attempts,
+		NextAttemptAt: m.NextAttemptAt,
+		LastError:     m.LastError,
+		DeliveredAt:   m.DeliveredAt,
+		CreatedAt:     m.CreatedAt,
+		UpdatedAt:     m.UpdatedAt,
+	}
+}
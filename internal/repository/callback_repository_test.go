@@ -0,0 +1,53 @@
+//go:build integration
+
+package repository
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	callbackDomain "github.com/Kilat-Pet-Delivery/service-payment/internal/domain/callback"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCallbackRepo_ClaimDueAndRecordAttempt(t *testing.T) {
+	db := setupRepoTestDB(t)
+	require.NoError(t, db.AutoMigrate(&CallbackDeliveryModel{}, &CallbackAttemptModel{}))
+	repo := NewGormCallbackRepository(db)
+	ctx := context.Background()
+
+	now := time.Now().UTC().Truncate(time.Microsecond)
+	paymentID := uuid.New()
+	due := callbackDomain.NewDelivery(paymentID, "https://example.com/hook", "payment.escrow_released", []byte(`{"a":1}`), now.Add(-time.Minute))
+	later := callbackDomain.NewDelivery(paymentID, "https://example.com/hook", "payment.escrow_refunded", []byte(`{"a":2}`), now.Add(time.Hour))
+	require.NoError(t, repo.Save(ctx, due))
+	require.NoError(t, repo.Save(ctx, later))
+
+	claimed, err := repo.ClaimDue(ctx, now, time.Minute, 10)
+	require.NoError(t, err)
+	require.Len(t, claimed, 1)
+	assert.Equal(t, due.ID, claimed[0].ID)
+	assert.JSONEq(t, `{"a":1}`, string(claimed[0].Payload))
+
+	again, err := repo.ClaimDue(ctx, now, time.Minute, 10)
+	require.NoError(t, err)
+	assert.Empty(t, again, "a claimed delivery is leased")
+
+	d := claimed[0]
+	attempt := d.RecordSuccess(200, now)
+	require.NoError(t, repo.RecordAttempt(ctx, d, attempt))
+
+	deliveries, err := repo.FindByPaymentID(ctx, paymentID)
+	require.NoError(t, err)
+	require.Len(t, deliveries, 2)
+	assert.Equal(t, callbackDomain.StatusDelivered, deliveries[0].Status)
+	assert.Equal(t, 1, deliveries[0].Attempts)
+
+	attempts, err := repo.FindAttempts(ctx, d.ID)
+	require.NoError(t, err)
+	require.Len(t, attempts, 1)
+	assert.Equal(t, 200, attempts[0].StatusCode)
+}
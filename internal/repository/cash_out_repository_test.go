@@ -22,6 +22,15 @@ import (
 // setupRepoTestDB starts a PostgreSQL testcontainer, runs uuid-ossp extension
 // and auto-migrates the models required for cash-out repo tests.
 func setupRepoTestDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	db := startRepoTestDB(t)
+	require.NoError(t, db.AutoMigrate(&PaymentModel{}, &CashOutModel{}))
+	return db
+}
+
+// startRepoTestDB starts a PostgreSQL container and returns a connection to
+// its empty database, with only the uuid-ossp extension installed.
+func startRepoTestDB(t *testing.T) *gorm.DB {
 	t.Helper()
 	ctx := context.Background()
 
@@ -66,7 +75,6 @@ func setupRepoTestDB(t *testing.T) *gorm.DB {
 	}, 30*time.Second, 1*time.Second, "PostgreSQL not ready")
 
 	require.NoError(t, db.Exec(`CREATE EXTENSION IF NOT EXISTS "uuid-ossp"`).Error)
-	require.NoError(t, db.AutoMigrate(&PaymentModel{}, &CashOutModel{}))
 
 	return db
 }
@@ -0,0 +1,102 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	creditDomain "github.com/Kilat-Pet-Delivery/service-payment/internal/domain/credit"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// UserCreditModel is the GORM model for the user_credits table.
+type UserCreditModel struct {
+	UserID       uuid.UUID `gorm:"type:uuid;primaryKey"`
+	Currency     string    `gorm:"type:varchar(3);not null"`
+	BalanceCents int64     `gorm:"not null;default:0;check:balance_cents >= 0"`
+	CreatedAt    time.Time `gorm:"type:timestamptz;not null"`
+	UpdatedAt    time.Time `gorm:"type:timestamptz;not null"`
+}
+
+// TableName sets the table name.
+func (UserCreditModel) TableName() string { return "user_credits" }
+
+// GormCreditRepository implements CreditRepository using GORM.
+type GormCreditRepository struct {
+	db *gorm.DB
+}
+
+// NewGormCreditRepository creates a new GormCreditRepository.
+func NewGormCreditRepository(db *gorm.DB) *GormCreditRepository {
+	return &GormCreditRepository{db: db}
+}
+
+// Grant adds credit to a user's balance in a single upsert.
+func (r *GormCreditRepository) Grant(ctx context.Context, userID uuid.UUID, currency string, amountCents int64) error {
+	if amountCents <= 0 {
+		return creditDomain.ErrInvalidAmount
+	}
+	now := time.Now().UTC()
+	result := r.db.WithContext(ctx).Exec(`
+		INSERT INTO user_credits (user_id, currency, balance_cents, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?)
+		ON CONFLICT (user_id) DO UPDATE
+		SET balance_cents = user_credits.balance_cents + EXCLUDED.balance_cents,
+		    updated_at = EXCLUDED.updated_at
+		WHERE user_credits.currency = EXCLUDED.currency`,
+		userID, currency, amountCents, now, now)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return creditDomain.ErrCurrencyMismatch
+	}
+	return nil
+}
+
+// Deduct removes credit from a user's balance. The balance check and the
+// update happen in one statement so concurrent deductions cannot overdraw it.
+func (r *GormCreditRepository) Deduct(ctx context.Context, userID uuid.UUID, currency string, amountCents int64) error {
+	if amountCents <= 0 {
+		return creditDomain.ErrInvalidAmount
+	}
+	result := r.db.WithContext(ctx).
+		Model(&UserCreditModel{}).
+		Where("user_id = ? AND currency = ? AND balance_cents >= ?", userID, currency, amountCents).
+		Updates(map[string]interface{}{
+			"balance_cents": gorm.Expr("balance_cents - ?", amountCents),
+			"updated_at":    time.Now().UTC(),
+		})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		balance, err := r.FindByUserID(ctx, userID)
+		if err != nil {
+			return err
+		}
+		if balance != nil && balance.Currency != currency {
+			return creditDomain.ErrCurrencyMismatch
+		}
+		return creditDomain.ErrInsufficientCredit
+	}
+	return nil
+}
+
+// FindByUserID returns a user's credit balance, or nil if none exists.
+func (r *GormCreditRepository) FindByUserID(ctx context.Context, userID uuid.UUID) (*creditDomain.Balance, error) {
+	var model UserCreditModel
+	if err := r.db.WithContext(ctx).Where("user_id = ?", userID).First(&model).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &creditDomain.Balance{
+		UserID:       model.UserID,
+		Currency:     model.Currency,
+		BalanceCents: model.BalanceCents,
+		UpdatedAt:    model.UpdatedAt,
+	}, nil
+}
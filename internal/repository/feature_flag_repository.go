@@ -0,0 +1,43 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/Kilat-Pet-Delivery/service-payment/internal/feature"
+	"gorm.io/gorm"
+)
+
+// FeatureFlagModel is the GORM model for the feature_flags table.
+type FeatureFlagModel struct {
+	Name      string    `gorm:"type:varchar(64);primaryKey"`
+	Enabled   bool      `gorm:"not null"`
+	UpdatedAt time.Time `gorm:"type:timestamptz;not null"`
+}
+
+// TableName sets the table name.
+func (FeatureFlagModel) TableName() string { return "feature_flags" }
+
+// GormFeatureFlagRepository implements feature.Store using GORM.
+type GormFeatureFlagRepository struct {
+	db *gorm.DB
+}
+
+// NewGormFeatureFlagRepository creates a new GormFeatureFlagRepository.
+func NewGormFeatureFlagRepository(db *gorm.DB) *GormFeatureFlagRepository {
+	return &GormFeatureFlagRepository{db: db}
+}
+
+// LoadFlags returns every flag override stored in the table.
+func (r *GormFeatureFlagRepository) LoadFlags(ctx context.Context) (map[feature.Flag]bool, error) {
+	var models []FeatureFlagModel
+	if err := r.db.WithContext(ctx).Find(&models).Error; err != nil {
+		return nil, err
+	}
+
+	flags := make(map[feature.Flag]bool, len(models))
+	for _, m := range models {
+		flags[feature.Flag(m.Name)] = m.Enabled
+	}
+	return flags, nil
+}
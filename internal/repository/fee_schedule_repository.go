@@ -0,0 +1,91 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/Kilat-Pet-Delivery/lib-common/domain"
+	feeDomain "github.com/Kilat-Pet-Delivery/service-payment/internal/domain/fee"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// FeeScheduleModel is the GORM model for the fee_schedules table.
+type FeeScheduleModel struct {
+	ID            uuid.UUID `gorm:"type:uuid;primaryKey"`
+	Region        string    `gorm:"type:varchar(20);not null;default:'';uniqueIndex:idx_fee_schedules_lookup"`
+	Currency      string    `gorm:"type:varchar(3);not null;uniqueIndex:idx_fee_schedules_lookup"`
+	FeePercent    float64   `gorm:"type:numeric(5,2);not null"`
+	EffectiveFrom time.Time `gorm:"type:timestamptz;not null;uniqueIndex:idx_fee_schedules_lookup"`
+	CreatedAt     time.Time `gorm:"type:timestamptz;not null"`
+	UpdatedAt     time.Time `gorm:"type:timestamptz;not null"`
+}
+
+// TableName sets the table name.
+func (FeeScheduleModel) TableName() string { return "fee_schedules" }
+
+// GormFeeScheduleRepository implements FeeScheduleRepository using GORM.
+type GormFeeScheduleRepository struct {
+	db *gorm.DB
+}
+
+// NewGormFeeScheduleRepository creates a new GormFeeScheduleRepository.
+func NewGormFeeScheduleRepository(db *gorm.DB) *GormFeeScheduleRepository {
+	return &GormFeeScheduleRepository{db: db}
+}
+
+// Save persists a new fee schedule.
+func (r *GormFeeScheduleRepository) Save(ctx context.Context, f *feeDomain.FeeSchedule) error {
+	model := toFeeScheduleModel(f)
+	return r.db.WithContext(ctx).Create(&model).Error
+}
+
+// Update updates a fee schedule.
+func (r *GormFeeScheduleRepository) Update(ctx context.Context, f *feeDomain.FeeSchedule) error {
+	model := toFeeScheduleModel(f)
+	return r.db.WithContext(ctx).Save(&model).Error
+}
+
+// FindByID returns a fee schedule by ID.
+func (r *GormFeeScheduleRepository) FindByID(ctx context.Context, id uuid.UUID) (*feeDomain.FeeSchedule, error) {
+	var model FeeScheduleModel
+	if err := r.db.WithContext(ctx).Where("id = ?", id).First(&model).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, domain.NewNotFoundError("FeeSchedule", id.String())
+		}
+		return nil, err
+	}
+	return toFeeScheduleDomain(&model), nil
+}
+
+// FindAll returns every fee schedule ordered by currency, region and effective date.
+func (r *GormFeeScheduleRepository) FindAll(ctx context.Context) ([]*feeDomain.FeeSchedule, error) {
+	var models []FeeScheduleModel
+	if err := r.db.WithContext(ctx).
+		Order("currency ASC, region ASC, effective_from DESC").
+		Find(&models).Error; err != nil {
+		return nil, err
+	}
+
+	schedules := make([]*feeDomain.FeeSchedule, len(models))
+	for i := range models {
+		schedules[i] = toFeeScheduleDomain(&models[i])
+	}
+	return schedules, nil
+}
+
+func toFeeScheduleModel(f *feeDomain.FeeSchedule) FeeScheduleModel {
+	return FeeScheduleModel{
+		ID: f.ID(), Region: f.Region(), Currency: f.Currency(),
+		FeePercent: f.FeePercent(), EffectiveFrom: f.EffectiveFrom(),
+		CreatedAt: f.CreatedAt(), UpdatedAt: f.UpdatedAt(),
+	}
+}
+
+func toFeeScheduleDomain(m *FeeScheduleModel) *feeDomain.FeeSchedule {
+	return feeDomain.Reconstruct(
+		m.ID, m.Region, m.Currency, m.FeePercent,
+		m.EffectiveFrom, m.CreatedAt, m.UpdatedAt,
+	)
+}
@@ -0,0 +1,52 @@
+//go:build integration
+
+package repository
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+// TestMigrations_ApplyToEmptyDatabase runs every up migration in order on an
+// empty database, as non-development environments do at startup, and checks
+// that the result has every table and column the models use.
+func TestMigrations_ApplyToEmptyDatabase(t *testing.T) {
+	db := startRepoTestDB(t)
+
+	files, err := filepath.Glob(filepath.Join("..", "..", "migrations", "*.up.sql"))
+	require.NoError(t, err)
+	require.NotEmpty(t, files)
+	sort.Strings(files)
+	for _, file := range files {
+		sql, err := os.ReadFile(file)
+		require.NoError(t, err)
+		require.NoError(t, db.Exec(string(sql)).Error, "migration %s", filepath.Base(file))
+	}
+
+	models := []any{
+		&PaymentModel{}, &PaymentDiscountModel{}, &PaymentStatusHistoryModel{}, &PaymentAmountAdjustmentModel{},
+		&FeeScheduleModel{}, &PromoModel{}, &PromoUsageModel{}, &SubscriptionModel{}, &CashOutModel{},
+		&UserCreditModel{}, &RunnerAccountModel{}, &FeatureFlagModel{}, &CallbackDeliveryModel{},
+		&CallbackAttemptModel{}, &BookingTotalModel{}, &RefundRequestModel{},
+	}
+	for _, model := range models {
+		stmt := &gorm.Statement{DB: db}
+		require.NoError(t, stmt.Parse(model))
+		table := stmt.Schema.Table
+		if !assert.True(t, db.Migrator().HasTable(table), "table %s", table) {
+			continue
+		}
+		for _, field := range stmt.Schema.Fields {
+			if field.DBName == "" {
+				continue
+			}
+			assert.True(t, db.Migrator().HasColumn(model, field.DBName), "column %s.%s", table, field.DBName)
+		}
+	}
+}
@@ -0,0 +1,127 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/Kilat-Pet-Delivery/lib-common/domain"
+	paymentDomain "github.com/Kilat-Pet-Delivery/service-payment/internal/domain/payment"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgconn"
+	"gorm.io/gorm"
+)
+
+// PaymentAmountAdjustmentModel is the GORM persistence model for the
+// payment_amount_adjustments table.
+type PaymentAmountAdjustmentModel struct {
+	ID                      uuid.UUID `gorm:"type:uuid;primaryKey"`
+	PaymentID               uuid.UUID `gorm:"type:uuid;not null;index"`
+	EventID                 string    `gorm:"type:varchar(255);not null;uniqueIndex:idx_payment_amount_adjustments_event"`
+	PreviousAmountCents     int64     `gorm:"not null"`
+	AmountCents             int64     `gorm:"not null"`
+	GrossAmountCents        int64     `gorm:"not null;default:0"`
+	PreviousPaymentIntentID string    `gorm:"type:varchar(255);not null"`
+	PaymentIntentID         string    `gorm:"type:varchar(255);not null"`
+	AdjustedAt              time.Time `gorm:"type:timestamptz;not null"`
+}
+
+// TableName specifies the table name for GORM.
+func (PaymentAmountAdjustmentModel) TableName() string {
+	return "payment_amount_adjustments"
+}
+
+// adjustmentEventIndex enforces that each booking event adjusts a payment at most once.
+const adjustmentEventIndex = "idx_payment_amount_adjustments_event"
+
+// AdjustAmount persists an adjusted payment together with the adjustment, in
+// one transaction with optimistic locking. It returns ErrAdjustmentApplied if
+// the adjustment's event was already applied.
+func (r *PaymentRepositoryImpl) AdjustAmount(ctx context.Context, payment *paymentDomain.Payment, adj paymentDomain.AmountAdjustment) error {
+	model := toModel(payment)
+	previousVersion := payment.Version() - 1
+
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		adjustment := toAdjustmentModel(adj)
+		if err := tx.Create(&adjustment).Error; err != nil {
+			var pgErr *pgconn.PgError
+			if errors.As(err, &pgErr) && pgErr.Code == uniqueViolation && pgErr.ConstraintName == adjustmentEventIndex {
+				return paymentDomain.ErrAdjustmentApplied
+			}
+			return err
+		}
+
+		result := tx.
+			Model(&PaymentModel{}).
+			Where("id = ? AND version = ?", model.ID, previousVersion).
+			Select("*").
+			Omit("created_at", "review_reason").
+			Updates(model)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return domain.NewConflictError("payment was modified by another transaction")
+		}
+
+		return saveStatusChanges(ctx, tx, payment)
+	})
+	if err != nil {
+		return err
+	}
+	payment.ClearStatusChanges()
+	return nil
+}
+
+// HasAmountAdjustment reports whether the event eventID has adjusted a payment.
+func (r *PaymentRepositoryImpl) HasAmountAdjustment(ctx context.Context, eventID string) (bool, error) {
+	var count int64
+	err := r.db.WithContext(ctx).Model(&PaymentAmountAdjustmentModel{}).
+		Where("event_id = ?", eventID).
+		Count(&count).Error
+	return count > 0, err
+}
+
+// FindLatestAmountAdjustment retrieves the most recent amount adjustment of a
+// payment, or nil if it was never adjusted.
+func (r *PaymentRepositoryImpl) FindLatestAmountAdjustment(ctx context.Context, paymentID uuid.UUID) (*paymentDomain.AmountAdjustment, error) {
+	var models []PaymentAmountAdjustmentModel
+	err := r.db.WithContext(ctx).
+		Where("payment_id = ?", paymentID).
+		Order("adjusted_at DESC").
+		Limit(1).
+		Find(&models).Error
+	if err != nil || len(models) == 0 {
+		return nil, err
+	}
+	adj := toAdjustment(models[0])
+	return &adj, nil
+}
+
+func toAdjustmentModel(adj paymentDomain.AmountAdjustment) PaymentAmountAdjustmentModel {
+	return PaymentAmountAdjustmentModel{
+		ID:                      adj.ID,
+		PaymentID:               adj.PaymentID,
+		EventID:                 adj.EventID,
+		PreviousAmountCents:     adj.PreviousAmountCents,
+		AmountCents:             adj.AmountCents,
+		GrossAmountCents:        adj.GrossAmountCents,
+		PreviousPaymentIntentID: adj.PreviousPaymentIntentID,
+		PaymentIntentID:         adj.PaymentIntentID,
+		AdjustedAt:              adj.AdjustedAt,
+	}
+}
+
+func toAdjustment(m PaymentAmountAdjustmentModel) paymentDomain.AmountAdjustment {
+	return paymentDomain.AmountAdjustment{
+		ID:                      m.ID,
+		PaymentID:               m.PaymentID,
+		EventID:                 m.EventID,
+		PreviousAmountCents:     m.PreviousAmountCents,
+		AmountCents:             m.AmountCents,
+		GrossAmountCents:        m.GrossAmountCents,
+		PreviousPaymentIntentID: m.PreviousPaymentIntentID,
+		PaymentIntentID:         m.PaymentIntentID,
+		AdjustedAt:              m.AdjustedAt,
+	}
+}
@@ -0,0 +1,71 @@
+//go:build integration
+
+package repository
+
+import (
+	"context"
+	"testing"
+
+	paymentDomain "github.com/Kilat-Pet-Delivery/service-payment/internal/domain/payment"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPaymentRepo_AdjustAmount_AppliesEachEventOnce(t *testing.T) {
+	db := setupRepoTestDB(t)
+	require.NoError(t, db.AutoMigrate(&PaymentModel{}, &PaymentStatusHistoryModel{}, &PaymentAmountAdjustmentModel{}))
+	repo := NewPaymentRepository(db)
+	ctx := context.Background()
+
+	p, err := paymentDomain.NewPayment(uuid.New(), uuid.New(), 10000, "MYR", 15, paymentDomain.PayoutFloors{})
+	require.NoError(t, err)
+	require.NoError(t, p.HoldEscrow("pi_1", 0))
+	require.NoError(t, repo.Save(ctx, p))
+
+	applied, err := repo.HasAmountAdjustment(ctx, "evt-1")
+	require.NoError(t, err)
+	assert.False(t, applied)
+	latest, err := repo.FindLatestAmountAdjustment(ctx, p.ID())
+	require.NoError(t, err)
+	assert.Nil(t, latest)
+
+	adj, err := p.AdjustAmount(12000, 13000, "pi_2", "evt-1", paymentDomain.PayoutFloors{})
+	require.NoError(t, err)
+	p.IncrementVersion()
+	require.NoError(t, repo.AdjustAmount(ctx, p, adj))
+
+	stored, err := repo.FindByID(ctx, p.ID())
+	require.NoError(t, err)
+	assert.Equal(t, int64(12000), stored.AmountCents())
+	assert.Equal(t, int64(1800), stored.PlatformFeeCents())
+	assert.Equal(t, "pi_2", stored.StripePaymentID())
+	applied, err = repo.HasAmountAdjustment(ctx, "evt-1")
+	require.NoError(t, err)
+	assert.True(t, applied)
+	latest, err = repo.FindLatestAmountAdjustment(ctx, p.ID())
+	require.NoError(t, err)
+	require.NotNil(t, latest)
+	assert.Equal(t, "evt-1", latest.EventID)
+	assert.Equal(t, int64(12000), latest.AmountCents)
+	assert.Equal(t, int64(13000), latest.GrossAmountCents)
+
+	history, err := repo.FindStatusHistory(ctx, p.ID())
+	require.NoError(t, err)
+	require.NotEmpty(t, history)
+	assert.Contains(t, history[len(history)-1].Reason, "amount adjusted")
+
+	// The same event again is refused without changing the payment.
+	again, err := stored.AdjustAmount(15000, 16000, "pi_3", "evt-1", paymentDomain.PayoutFloors{})
+	require.NoError(t, err)
+	stored.IncrementVersion()
+	assert.ErrorIs(t, repo.AdjustAmount(ctx, stored, again), paymentDomain.ErrAdjustmentApplied)
+
+	stored, err = repo.FindByID(ctx, p.ID())
+	require.NoError(t, err)
+	assert.Equal(t, int64(12000), stored.AmountCents())
+	var adjustments []PaymentAmountAdjustmentModel
+	require.NoError(t, db.Where("payment_id = ?", p.ID()).Find(&adjustments).Error)
+	require.Len(t, adjustments, 1)
+	assert.Equal(t, "pi_1", adjustments[0].PreviousPaymentIntentID)
+}
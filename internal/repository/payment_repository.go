@@ -3,6 +3,7 @@ package repository
 import (
 	"context"
 	"errors"
+	"strings"
 	"time"
 
 	"github.com/Kilat-Pet-Delivery/lib-common/domain"
@@ -12,6 +13,7 @@ import (
 )
 
 // PaymentModel is the GORM persistence model for the payments table.
+// The *Cents columns hold amounts in the minor unit of Currency.
 type PaymentModel struct {
 	ID                uuid.UUID  `gorm:"type:uuid;primaryKey;default:uuid_generate_v4()"`
 	BookingID         uuid.UUID  `gorm:"type:uuid;uniqueIndex;not null"`
@@ -27,10 +29,30 @@ type PaymentModel struct {
 	EscrowHeldAt      *time.Time `gorm:"type:timestamptz"`
 	EscrowReleasedAt  *time.Time `gorm:"type:timestamptz"`
 	RefundedAt        *time.Time `gorm:"type:timestamptz"`
+	ReleaseEligibleAt *time.Time `gorm:"type:timestamptz"`
 	RefundReason      string     `gorm:"type:text"`
 	Version           int64      `gorm:"not null;default:1"`
 	CreatedAt         time.Time  `gorm:"type:timestamptz;not null;default:now()"`
 	UpdatedAt         time.Time  `gorm:"type:timestamptz;not null;default:now()"`
+
+	// CreditAppliedCents is the part of AmountCents paid from in-app credit.
+	CreditAppliedCents int64 `gorm:"not null;default:0"`
+	// CallbackURL receives an HTTP callback on release and refund, if set.
+	CallbackURL string `gorm:"type:text"`
+	// AwaitingAuthentication is set while the customer completes 3-D Secure.
+	AwaitingAuthentication bool `gorm:"not null;default:false"`
+	// CustomerEmail is the payer's email; only admin endpoints expose it.
+	CustomerEmail string `gorm:"type:varchar(254)"`
+	// RunnerTransferID is the Connect transfer of the runner's payout, if any.
+	RunnerTransferID string `gorm:"type:varchar(255)"`
+	// ReviewReason is set when the payment needs manual reconciliation.
+	ReviewReason string `gorm:"type:text"`
+	// IsTest marks test payments, which stats and admin lists skip by default.
+	IsTest bool `gorm:"not null;default:false"`
+	// FeeRegion is the region whose fee schedule priced the payment.
+	FeeRegion string `gorm:"type:text;not null;default:''"`
+	// FeeOverridden is set once an admin overrides the platform fee.
+	FeeOverridden bool `gorm:"not null;default:false"`
 }
 
 // TableName specifies the table name for GORM.
@@ -38,6 +60,41 @@ func (PaymentModel) TableName() string {
 	return "payments"
 }
 
+// PaymentDiscountModel is the GORM persistence model for the payment_discounts table.
+type PaymentDiscountModel struct {
+	ID          uuid.UUID `gorm:"type:uuid;primaryKey;default:uuid_generate_v4()"`
+	PaymentID   uuid.UUID `gorm:"type:uuid;not null;index"`
+	Source      string    `gorm:"type:varchar(20);not null"`
+	Code        string    `gorm:"type:varchar(50)"`
+	AmountCents int64     `gorm:"not null"`
+	CreatedAt   time.Time `gorm:"type:timestamptz;not null;default:now()"`
+}
+
+// TableName specifies the table name for GORM.
+func (PaymentDiscountModel) TableName() string {
+	return "payment_discounts"
+}
+
+// PaymentStatusHistoryModel is the GORM persistence model for the payment_status_history table.
+type PaymentStatusHistoryModel struct {
+	ID         uuid.UUID `gorm:"type:uuid;primaryKey;default:uuid_generate_v4()"`
+	PaymentID  uuid.UUID `gorm:"type:uuid;not null;index"`
+	FromStatus string    `gorm:"type:varchar(20)"`
+	ToStatus   string    `gorm:"type:varchar(20);not null"`
+	Reason     string    `gorm:"type:text"`
+	Actor      string    `gorm:"type:varchar(100);not null"`
+	// InitiatedByType and InitiatedByID split Actor so reports can filter on
+	// who made a change, e.g. admin refunds versus event-driven ones.
+	InitiatedByType string    `gorm:"type:varchar(20);not null;default:'system';index"`
+	InitiatedByID   string    `gorm:"type:varchar(100);not null;default:''"`
+	OccurredAt      time.Time `gorm:"type:timestamptz;not null"`
+}
+
+// TableName specifies the table name for GORM.
+func (PaymentStatusHistoryModel) TableName() string {
+	return "payment_status_history"
+}
+
 // PaymentRepositoryImpl is the GORM-based implementation of PaymentRepository.
 type PaymentRepositoryImpl struct {
 	db *gorm.DB
@@ -72,12 +129,35 @@ func (r *PaymentRepositoryImpl) FindByBookingID(ctx context.Context, bookingID u
 	return toDomain(&model), nil
 }
 
-// Save persists a new payment aggregate.
+// FindByBookingIDs retrieves the payments for the given bookings in one query.
+func (r *PaymentRepositoryImpl) FindByBookingIDs(ctx context.Context, bookingIDs []uuid.UUID) ([]*paymentDomain.Payment, error) {
+	if len(bookingIDs) == 0 {
+		return nil, nil
+	}
+	var models []PaymentModel
+	if err := r.db.WithContext(ctx).Where("booking_id IN ?", bookingIDs).Find(&models).Error; err != nil {
+		return nil, err
+	}
+	payments := make([]*paymentDomain.Payment, len(models))
+	for i := range models {
+		payments[i] = toDomain(&models[i])
+	}
+	return payments, nil
+}
+
+// Save persists a new payment aggregate together with its status history.
 func (r *PaymentRepositoryImpl) Save(ctx context.Context, payment *paymentDomain.Payment) error {
 	model := toModel(payment)
-	if err := r.db.WithContext(ctx).Create(model).Error; err != nil {
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(model).Error; err != nil {
+			return err
+		}
+		return saveStatusChanges(ctx, tx, payment)
+	})
+	if err != nil {
 		return err
 	}
+	payment.ClearStatusChanges()
 	return nil
 }
 
@@ -86,30 +166,162 @@ func (r *PaymentRepositoryImpl) Update(ctx context.Context, payment *paymentDoma
 	model := toModel(payment)
 	previousVersion := payment.Version() - 1
 
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		// Select("*") writes zero values too, so fields cleared by a transition
+		// (e.g. ResetForRetry) are persisted. review_reason is only written by
+		// FlagForReview, which does not bump the version, so a stale aggregate
+		// must not clear it.
+		result := tx.
+			Model(&PaymentModel{}).
+			Where("id = ? AND version = ?", model.ID, previousVersion).
+			Select("*").
+			Omit("created_at", "review_reason").
+			Updates(model)
+
+		if result.Error != nil {
+			return result.Error
+		}
+
+		if result.RowsAffected == 0 {
+			return domain.NewConflictError("payment was modified by another transaction")
+		}
+
+		return saveStatusChanges(ctx, tx, payment)
+	})
+	if err != nil {
+		return err
+	}
+	payment.ClearStatusChanges()
+	return nil
+}
+
+// FlagForReview sets review_reason on a payment without a version check.
+func (r *PaymentRepositoryImpl) FlagForReview(ctx context.Context, paymentID uuid.UUID, reason string) error {
 	result := r.db.WithContext(ctx).
 		Model(&PaymentModel{}).
-		Where("id = ? AND version = ?", model.ID, previousVersion).
-		Updates(model)
-
+		Where("id = ?", paymentID).
+		Update("review_reason", reason)
 	if result.Error != nil {
 		return result.Error
 	}
-
 	if result.RowsAffected == 0 {
-		return domain.NewConflictError("payment was modified by another transaction")
+		return domain.NewNotFoundError("Payment", paymentID.String())
 	}
-
 	return nil
 }
 
-// ListAll retrieves all payments with pagination (admin).
-func (r *PaymentRepositoryImpl) ListAll(ctx context.Context, page, limit int) ([]*paymentDomain.Payment, int64, error) {
+// saveStatusChanges writes the payment's queued transitions, attributed to the
+// actor on ctx, inside the caller's transaction.
+func saveStatusChanges(ctx context.Context, tx *gorm.DB, payment *paymentDomain.Payment) error {
+	changes := payment.StatusChanges()
+	if len(changes) == 0 {
+		return nil
+	}
+
+	actor := paymentDomain.ActorFromContext(ctx)
+	models := make([]PaymentStatusHistoryModel, len(changes))
+	initiator := paymentDomain.ParseInitiator(actor)
+	for i, c := range changes {
+		models[i] = PaymentStatusHistoryModel{
+			ID:              uuid.New(),
+			PaymentID:       payment.ID(),
+			FromStatus:      string(c.From),
+			ToStatus:        string(c.To),
+			Reason:          c.Reason,
+			Actor:           actor,
+			InitiatedByType: initiator.Type,
+			InitiatedByID:   initiator.ID,
+			OccurredAt:      c.OccurredAt,
+		}
+	}
+	return tx.Create(&models).Error
+}
+
+// FindStatusHistory returns the recorded status transitions of a payment, oldest first.
+func (r *PaymentRepositoryImpl) FindStatusHistory(ctx context.Context, paymentID uuid.UUID) ([]paymentDomain.StatusChange, error) {
+	var models []PaymentStatusHistoryModel
+	if err := r.db.WithContext(ctx).
+		Where("payment_id = ?", paymentID).
+		Order("occurred_at ASC").
+		Find(&models).Error; err != nil {
+		return nil, err
+	}
+
+	changes := make([]paymentDomain.StatusChange, len(models))
+	for i, m := range models {
+		changes[i] = paymentDomain.StatusChange{
+			From:       paymentDomain.EscrowStatus(m.FromStatus),
+			To:         paymentDomain.EscrowStatus(m.ToStatus),
+			Reason:     m.Reason,
+			Actor:      m.Actor,
+			OccurredAt: m.OccurredAt,
+		}
+	}
+	return changes, nil
+}
+
+// SaveDiscounts persists the discounts applied to a payment.
+func (r *PaymentRepositoryImpl) SaveDiscounts(ctx context.Context, paymentID uuid.UUID, lines []paymentDomain.DiscountLine) error {
+	if len(lines) == 0 {
+		return nil
+	}
+
+	now := time.Now().UTC()
+	models := make([]PaymentDiscountModel, len(lines))
+	for i, l := range lines {
+		models[i] = PaymentDiscountModel{
+			ID:          uuid.New(),
+			PaymentID:   paymentID,
+			Source:      l.Source,
+			Code:        l.Code,
+			AmountCents: l.AmountCents,
+			CreatedAt:   now,
+		}
+	}
+	return r.db.WithContext(ctx).Create(&models).Error
+}
+
+// FindDiscounts retrieves the discounts applied to a payment.
+func (r *PaymentRepositoryImpl) FindDiscounts(ctx context.Context, paymentID uuid.UUID) ([]paymentDomain.DiscountLine, error) {
+	var models []PaymentDiscountModel
+	if err := r.db.WithContext(ctx).Where("payment_id = ?", paymentID).Order("created_at ASC").Find(&models).Error; err != nil {
+		return nil, err
+	}
+
+	lines := make([]paymentDomain.DiscountLine, len(models))
+	for i, m := range models {
+		lines[i] = paymentDomain.DiscountLine{Source: m.Source, Code: m.Code, AmountCents: m.AmountCents}
+	}
+	return lines, nil
+}
+
+// FindReleaseEligible retrieves held payments with an assigned runner whose
+// auto-release time is at or before the given time, oldest first.
+func (r *PaymentRepositoryImpl) FindReleaseEligible(ctx context.Context, before time.Time, limit int) ([]*paymentDomain.Payment, error) {
+	var models []PaymentModel
+	if err := r.db.WithContext(ctx).
+		Where("escrow_status = ? AND runner_id IS NOT NULL AND release_eligible_at <= ?", "held", before).
+		Order("release_eligible_at ASC").
+		Limit(limit).
+		Find(&models).Error; err != nil {
+		return nil, err
+	}
+
+	payments := make([]*paymentDomain.Payment, len(models))
+	for i := range models {
+		payments[i] = toDomain(&models[i])
+	}
+	return payments, nil
+}
+
+// ListAll retrieves all payments matching the filter with pagination (admin).
+func (r *PaymentRepositoryImpl) ListAll(ctx context.Context, filter paymentDomain.ListFilter, page, limit int) ([]*paymentDomain.Payment, int64, error) {
 	var total int64
-	r.db.WithContext(ctx).Model(&PaymentModel{}).Count(&total)
+	applyListFilter(r.db.WithContext(ctx).Model(&PaymentModel{}), filter).Count(&total)
 
 	var models []PaymentModel
 	offset := (page - 1) * limit
-	if err := r.db.WithContext(ctx).Order("created_at DESC").Offset(offset).Limit(limit).Find(&models).Error; err != nil {
+	if err := applyListFilter(r.db.WithContext(ctx), filter).Order("created_at DESC").Offset(offset).Limit(limit).Find(&models).Error; err != nil {
 		return nil, 0, err
 	}
 
@@ -120,11 +332,124 @@ func (r *PaymentRepositoryImpl) ListAll(ctx context.Context, page, limit int) ([
 	return payments, total, nil
 }
 
-// GetRevenueStats returns payment statistics (admin).
-func (r *PaymentRepositoryImpl) GetRevenueStats(ctx context.Context) (int64, map[string]int64, error) {
+// Search returns up to limit payments, newest first, whose Stripe payment ID
+// starts with stripeIDPrefix or that belong to bookingID. The prefix match uses
+// LIKE with wildcards escaped, so it can use the text_pattern_ops index.
+func (r *PaymentRepositoryImpl) Search(ctx context.Context, stripeIDPrefix string, bookingID *uuid.UUID, limit int) ([]*paymentDomain.Payment, error) {
+	q := r.db.WithContext(ctx).Where(`stripe_payment_id LIKE ? ESCAPE '\'`, likePrefix(stripeIDPrefix))
+	if bookingID != nil {
+		q = q.Or("booking_id = ?", *bookingID)
+	}
+
+	var models []PaymentModel
+	if err := q.Order("created_at DESC").Limit(limit).Find(&models).Error; err != nil {
+		return nil, err
+	}
+
+	payments := make([]*paymentDomain.Payment, len(models))
+	for i := range models {
+		payments[i] = toDomain(&models[i])
+	}
+	return payments, nil
+}
+
+// likePrefix returns a LIKE pattern matching values that start with prefix,
+// escaping the pattern's wildcards; the underscore in "pi_" is one.
+func likePrefix(prefix string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(prefix) + "%"
+}
+
+// StreamAll calls fn for every payment matching the filter, oldest first.
+// Rows are scanned one at a time from a cursor rather than loaded up front.
+func (r *PaymentRepositoryImpl) StreamAll(ctx context.Context, filter paymentDomain.ListFilter, fn func(*paymentDomain.Payment) error) error {
+	db := r.db.WithContext(ctx)
+	rows, err := applyListFilter(db.Model(&PaymentModel{}), filter).Order("created_at ASC").Rows()
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var model PaymentModel
+		if err := db.ScanRows(rows, &model); err != nil {
+			return err
+		}
+		if err := fn(toDomain(&model)); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+// StreamByEventTime calls fn for every payment with an escrow held, released
+// or refunded timestamp within [from, to), oldest first.
+func (r *PaymentRepositoryImpl) StreamByEventTime(ctx context.Context, from, to time.Time, fn func(*paymentDomain.Payment) error) error {
+	db := r.db.WithContext(ctx)
+	rows, err := db.Model(&PaymentModel{}).
+		Where("(escrow_held_at >= ? AND escrow_held_at < ?) OR (escrow_released_at >= ? AND escrow_released_at < ?) OR (refunded_at >= ? AND refunded_at < ?)",
+			from, to, from, to, from, to).
+		Order("created_at ASC").
+		Rows()
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var model PaymentModel
+		if err := db.ScanRows(rows, &model); err != nil {
+			return err
+		}
+		if err := fn(toDomain(&model)); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+// applyListFilter adds the WHERE clauses for a ListFilter to the query.
+func applyListFilter(q *gorm.DB, filter paymentDomain.ListFilter) *gorm.DB {
+	if filter.Status != "" {
+		q = q.Where("escrow_status = ?", string(filter.Status))
+	}
+	if filter.OwnerID != nil {
+		q = q.Where("owner_id = ?", *filter.OwnerID)
+	}
+	if filter.From != nil {
+		q = q.Where("created_at >= ?", *filter.From)
+	}
+	if filter.To != nil {
+		q = q.Where("created_at < ?", *filter.To)
+	}
+	if !filter.IncludeTest {
+		q = q.Where("is_test = ?", false)
+	}
+	return q
+}
+
+// CountPaymentsByOwner returns how many payments ownerID has made, in any status.
+func (r *PaymentRepositoryImpl) CountPaymentsByOwner(ctx context.Context, ownerID uuid.UUID) (int64, error) {
+	var count int64
+	err := r.db.WithContext(ctx).Model(&PaymentModel{}).
+		Where("owner_id = ?", ownerID).
+		Count(&count).Error
+	return count, err
+}
+
+// GetRevenueStats returns payment statistics (admin). Test payments are
+// left out unless includeTest is set.
+func (r *PaymentRepositoryImpl) GetRevenueStats(ctx context.Context, includeTest bool) (int64, map[string]int64, error) {
+	payments := func() *gorm.DB {
+		q := r.db.WithContext(ctx).Model(&PaymentModel{})
+		if !includeTest {
+			q = q.Where("is_test = ?", false)
+		}
+		return q
+	}
+
 	// Total revenue from released escrows
 	var totalRevenue int64
-	r.db.WithContext(ctx).Model(&PaymentModel{}).
+	payments().
 		Where("escrow_status = ?", "released").
 		Select("COALESCE(SUM(amount_cents), 0)").
 		Scan(&totalRevenue)
@@ -135,7 +460,7 @@ func (r *PaymentRepositoryImpl) GetRevenueStats(ctx context.Context) (int64, map
 		Count        int64
 	}
 	var results []statusCount
-	if err := r.db.WithContext(ctx).Model(&PaymentModel{}).
+	if err := payments().
 		Select("escrow_status, count(*) as count").
 		Group("escrow_status").
 		Find(&results).Error; err != nil {
@@ -149,6 +474,74 @@ func (r *PaymentRepositoryImpl) GetRevenueStats(ctx context.Context) (int64, map
 	return totalRevenue, counts, nil
 }
 
+// GetEscrowAging aggregates currently held escrows into aging buckets per currency.
+func (r *PaymentRepositoryImpl) GetEscrowAging(ctx context.Context, now time.Time) ([]paymentDomain.AgingBucket, error) {
+	var rows []paymentDomain.AgingBucket
+	err := r.db.WithContext(ctx).Model(&PaymentModel{}).
+		Select(`CASE
+				WHEN escrow_held_at > ? THEN ?
+				WHEN escrow_held_at > ? THEN ?
+				WHEN escrow_held_at > ? THEN ?
+				ELSE ?
+			END AS bucket,
+			currency,
+			COUNT(*) AS count,
+			COALESCE(SUM(amount_cents), 0) AS total_cents`,
+			now.Add(-24*time.Hour), paymentDomain.AgingUnder1Day,
+			now.Add(-3*24*time.Hour), paymentDomain.Aging1To3Days,
+			now.Add(-7*24*time.Hour), paymentDomain.Aging3To7Days,
+			paymentDomain.AgingOver7Days,
+		).
+		Where("escrow_status = ?", string(paymentDomain.EscrowHeld)).
+		Group("bucket, currency").
+		Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+	return rows, nil
+}
+
+// SumHeldByCurrency totals amount_cents of held escrows per currency in a
+// single grouped query.
+func (r *PaymentRepositoryImpl) SumHeldByCurrency(ctx context.Context) (map[string]int64, error) {
+	type currencyTotal struct {
+		Currency   string
+		TotalCents int64
+	}
+	var rows []currencyTotal
+	err := r.db.WithContext(ctx).Model(&PaymentModel{}).
+		Select("currency, COALESCE(SUM(amount_cents), 0) AS total_cents").
+		Where("escrow_status = ?", string(paymentDomain.EscrowHeld)).
+		Group("currency").
+		Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	totals := make(map[string]int64, len(rows))
+	for _, row := range rows {
+		totals[row.Currency] = row.TotalCents
+	}
+	return totals, nil
+}
+
+// SumRunnerPayouts totals runner_payout_cents of a runner's released payments
+// per currency in a single grouped query.
+func (r *PaymentRepositoryImpl) SumRunnerPayouts(ctx context.Context, runnerID uuid.UUID, from, to time.Time) ([]paymentDomain.RunnerPayoutTotal, error) {
+	var rows []paymentDomain.RunnerPayoutTotal
+	err := r.db.WithContext(ctx).Model(&PaymentModel{}).
+		Select("currency, COALESCE(SUM(runner_payout_cents), 0) AS payout_cents, COUNT(*) AS booking_count").
+		Where("runner_id = ? AND escrow_status = ?", runnerID, string(paymentDomain.EscrowReleased)).
+		Where("escrow_released_at >= ? AND escrow_released_at < ?", from, to).
+		Group("currency").
+		Order("currency").
+		Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+	return rows, nil
+}
+
 // toDomain maps a PaymentModel to the domain Payment aggregate.
 func toDomain(model *PaymentModel) *paymentDomain.Payment {
 	return paymentDomain.Reconstitute(
@@ -157,16 +550,25 @@ func toDomain(model *PaymentModel) *paymentDomain.Payment {
 		model.OwnerID,
 		model.RunnerID,
 		paymentDomain.EscrowStatus(model.EscrowStatus),
-		model.AmountCents,
-		model.PlatformFeeCents,
-		model.RunnerPayoutCents,
-		model.Currency,
+		paymentDomain.NewMoney(model.AmountCents, model.Currency),
+		paymentDomain.NewMoney(model.PlatformFeeCents, model.Currency),
+		paymentDomain.NewMoney(model.RunnerPayoutCents, model.Currency),
+		model.CreditAppliedCents,
 		model.PaymentMethod,
 		model.StripePaymentID,
 		model.EscrowHeldAt,
 		model.EscrowReleasedAt,
 		model.RefundedAt,
+		model.ReleaseEligibleAt,
 		model.RefundReason,
+		model.CallbackURL,
+		model.CustomerEmail,
+		model.RunnerTransferID,
+		model.ReviewReason,
+		model.FeeRegion,
+		model.AwaitingAuthentication,
+		model.IsTest,
+		model.FeeOverridden,
 		model.Version,
 		model.CreatedAt,
 		model.UpdatedAt,
@@ -190,9 +592,20 @@ func toModel(p *paymentDomain.Payment) *PaymentModel {
 		EscrowHeldAt:      p.EscrowHeldAt(),
 		EscrowReleasedAt:  p.EscrowReleasedAt(),
 		RefundedAt:        p.RefundedAt(),
+		ReleaseEligibleAt: p.ReleaseEligibleAt(),
 		RefundReason:      p.RefundReason(),
 		Version:           p.Version(),
 		CreatedAt:         p.CreatedAt(),
 		UpdatedAt:         p.UpdatedAt(),
+
+		CreditAppliedCents:     p.CreditAppliedCents(),
+		CallbackURL:            p.CallbackURL(),
+		AwaitingAuthentication: p.AwaitingAuthentication(),
+		CustomerEmail:          p.CustomerEmail(),
+		RunnerTransferID:       p.RunnerTransferID(),
+		ReviewReason:           p.ReviewReason(),
+		IsTest:                 p.IsTest(),
+		FeeRegion:              p.FeeRegion(),
+		FeeOverridden:          p.FeeOverridden(),
 	}
 }
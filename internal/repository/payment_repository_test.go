@@ -0,0 +1,258 @@
+//go:build integration
+
+package repository
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	paymentDomain "github.com/Kilat-Pet-Delivery/service-payment/internal/domain/payment"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPaymentRepo_SumHeldByCurrency_OnlyCountsHeld(t *testing.T) {
+	db := setupRepoTestDB(t)
+	require.NoError(t, db.AutoMigrate(&PaymentModel{}, &PaymentStatusHistoryModel{}))
+	repo := NewPaymentRepository(db)
+	ctx := context.Background()
+
+	seed := func(amountCents int64, currency string, transition func(*paymentDomain.Payment) error) {
+		t.Helper()
+		p, err := paymentDomain.NewPayment(uuid.New(), uuid.New(), amountCents, currency, 10, paymentDomain.PayoutFloors{})
+		require.NoError(t, err)
+		require.NoError(t, p.HoldEscrow("pi_"+uuid.NewString(), 0))
+		if transition != nil {
+			require.NoError(t, transition(p))
+		}
+		require.NoError(t, repo.Save(ctx, p))
+	}
+	release := func(p *paymentDomain.Payment) error { return p.ReleaseToRunner(uuid.New()) }
+	refund := func(p *paymentDomain.Payment) error { return p.Refund("booking cancelled") }
+
+	seed(5000, "MYR", nil)
+	seed(2500, "MYR", nil)
+	seed(1200, "SGD", nil)
+	seed(9000, "MYR", release)
+	seed(7000, "SGD", refund)
+	seed(3000, "IDR", release)
+
+	totals, err := repo.SumHeldByCurrency(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]int64{"MYR": 7500, "SGD": 1200}, totals)
+}
+
+func TestPaymentRepo_Search_PrefixAndBookingMatches(t *testing.T) {
+	db := setupRepoTestDB(t)
+	require.NoError(t, db.AutoMigrate(&PaymentModel{}, &PaymentStatusHistoryModel{}))
+	repo := NewPaymentRepository(db)
+	ctx := context.Background()
+
+	seed := func(stripeID string) *paymentDomain.Payment {
+		t.Helper()
+		p, err := paymentDomain.NewPayment(uuid.New(), uuid.New(), 5000, "MYR", 10, paymentDomain.PayoutFloors{})
+		require.NoError(t, err)
+		require.NoError(t, p.HoldEscrow(stripeID, 0))
+		require.NoError(t, repo.Save(ctx, p))
+		return p
+	}
+	first := seed("pi_3Nabc111")
+	second := seed("pi_3Nabc222")
+	seed("pi_3Nxyz333")
+	// Matches "pi_3Nabc" only if the underscore were a wildcard.
+	seed("piX3Nabc444")
+	other := seed("pi_9Zqrs555")
+
+	found, err := repo.Search(ctx, "pi_3Nabc", nil, 10)
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []uuid.UUID{first.ID(), second.ID()}, paymentIDs(found))
+
+	found, err = repo.Search(ctx, "pi_3Nabc", nil, 1)
+	require.NoError(t, err)
+	assert.Len(t, found, 1, "results are capped at limit")
+
+	bookingID := other.BookingID()
+	found, err = repo.Search(ctx, bookingID.String(), &bookingID, 10)
+	require.NoError(t, err)
+	assert.Equal(t, []uuid.UUID{other.ID()}, paymentIDs(found))
+}
+
+func paymentIDs(payments []*paymentDomain.Payment) []uuid.UUID {
+	ids := make([]uuid.UUID, len(payments))
+	for i, p := range payments {
+		ids[i] = p.ID()
+	}
+	return ids
+}
+
+func TestPaymentRepo_FlagForReview_SurvivesStaleUpdate(t *testing.T) {
+	db := setupRepoTestDB(t)
+	require.NoError(t, db.AutoMigrate(&PaymentModel{}, &PaymentStatusHistoryModel{}))
+	repo := NewPaymentRepository(db)
+	ctx := context.Background()
+
+	p, err := paymentDomain.NewPayment(uuid.New(), uuid.New(), 5000, "MYR", 10, paymentDomain.PayoutFloors{})
+	require.NoError(t, err)
+	require.NoError(t, p.HoldEscrow("pi_review", 0))
+	require.NoError(t, repo.Save(ctx, p))
+
+	require.NoError(t, repo.FlagForReview(ctx, p.ID(), "transfer tr_1 not reversed"))
+
+	// p was loaded before the flag; writing it must not clear the reason.
+	require.NoError(t, p.ReleaseToRunner(uuid.New()))
+	p.IncrementVersion()
+	require.NoError(t, repo.Update(ctx, p))
+
+	stored, err := repo.FindByID(ctx, p.ID())
+	require.NoError(t, err)
+	assert.Equal(t, paymentDomain.EscrowReleased, stored.EscrowStatus())
+	assert.Equal(t, "transfer tr_1 not reversed", stored.ReviewReason())
+
+	assert.Error(t, repo.FlagForReview(ctx, uuid.New(), "unknown"))
+}
+
+func TestPaymentRepo_SumRunnerPayouts_ReleasedInWindow(t *testing.T) {
+	db := setupRepoTestDB(t)
+	require.NoError(t, db.AutoMigrate(&PaymentModel{}, &PaymentStatusHistoryModel{}))
+	repo := NewPaymentRepository(db)
+	ctx := context.Background()
+	runnerID := uuid.New()
+	from := time.Date(2026, time.March, 1, 0, 0, 0, 0, time.UTC)
+	to := from.AddDate(0, 1, 0)
+
+	seed := func(runner uuid.UUID, amountCents int64, currency string, releasedAt *time.Time) {
+		t.Helper()
+		p, err := paymentDomain.NewPayment(uuid.New(), uuid.New(), amountCents, currency, 10, paymentDomain.PayoutFloors{})
+		require.NoError(t, err)
+		require.NoError(t, p.HoldEscrow("pi_"+uuid.NewString(), 0))
+		if releasedAt == nil {
+			require.NoError(t, p.AssignRunner(runner))
+			require.NoError(t, repo.Save(ctx, p))
+			return
+		}
+		require.NoError(t, p.ReleaseToRunner(runner))
+		require.NoError(t, repo.Save(ctx, p))
+		require.NoError(t, db.Model(&PaymentModel{}).Where("id = ?", p.ID()).Update("escrow_released_at", *releasedAt).Error)
+	}
+	at := func(day int) *time.Time {
+		releasedAt := from.AddDate(0, 0, day)
+		return &releasedAt
+	}
+
+	seed(runnerID, 10000, "MYR", at(0))  // payout 9000
+	seed(runnerID, 5000, "MYR", at(20))  // payout 4500
+	seed(runnerID, 2000, "SGD", at(30))  // payout 1800
+	seed(runnerID, 8000, "MYR", at(-1))  // released the month before
+	seed(runnerID, 8000, "MYR", at(31))  // released on to, which is exclusive
+	seed(runnerID, 6000, "MYR", nil)     // still held
+	seed(uuid.New(), 7000, "MYR", at(5)) // another runner's
+
+	totals, err := repo.SumRunnerPayouts(ctx, runnerID, from, to)
+	require.NoError(t, err)
+	assert.Equal(t, []paymentDomain.RunnerPayoutTotal{
+		{Currency: "MYR", PayoutCents: 13500, BookingCount: 2},
+		{Currency: "SGD", PayoutCents: 1800, BookingCount: 1},
+	}, totals)
+}
+
+func TestPaymentRepo_TestPaymentsExcludedByDefault(t *testing.T) {
+	db := setupRepoTestDB(t)
+	require.NoError(t, db.AutoMigrate(&PaymentModel{}, &PaymentStatusHistoryModel{}))
+	repo := NewPaymentRepository(db)
+	ctx := context.Background()
+
+	seed := func(amountCents int64, test bool) *paymentDomain.Payment {
+		t.Helper()
+		p, err := paymentDomain.NewPayment(uuid.New(), uuid.New(), amountCents, "MYR", 10, paymentDomain.PayoutFloors{})
+		require.NoError(t, err)
+		if test {
+			require.NoError(t, p.MarkTest())
+		}
+		require.NoError(t, p.HoldEscrow("pi_"+uuid.NewString(), 0))
+		require.NoError(t, p.ReleaseToRunner(uuid.New()))
+		require.NoError(t, repo.Save(ctx, p))
+		return p
+	}
+	live := seed(5000, false)
+	test := seed(9000, true)
+
+	revenue, counts, err := repo.GetRevenueStats(ctx, false)
+	require.NoError(t, err)
+	assert.Equal(t, int64(5000), revenue)
+	assert.Equal(t, map[string]int64{"released": 1}, counts)
+
+	revenue, counts, err = repo.GetRevenueStats(ctx, true)
+	require.NoError(t, err)
+	assert.Equal(t, int64(14000), revenue)
+	assert.Equal(t, map[string]int64{"released": 2}, counts)
+
+	listed, total, err := repo.ListAll(ctx, paymentDomain.ListFilter{}, 1, 10)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), total)
+	require.Len(t, listed, 1)
+	assert.Equal(t, live.ID(), listed[0].ID())
+
+	listed, total, err = repo.ListAll(ctx, paymentDomain.ListFilter{IncludeTest: true}, 1, 10)
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), total)
+	assert.Len(t, listed, 2)
+	found, err := repo.FindByID(ctx, test.ID())
+	require.NoError(t, err)
+	assert.True(t, found.IsTest(), "the flag survives a round trip")
+}
+
+func TestPaymentRepo_Update_RecordsRefundInitiator(t *testing.T) {
+	db := setupRepoTestDB(t)
+	require.NoError(t, db.AutoMigrate(&PaymentModel{}, &PaymentStatusHistoryModel{}))
+	repo := NewPaymentRepository(db)
+	adminID := uuid.New()
+
+	p, err := paymentDomain.NewPayment(uuid.New(), uuid.New(), 10000, "MYR", 15, paymentDomain.PayoutFloors{})
+	require.NoError(t, err)
+	require.NoError(t, p.HoldEscrow("pi_1", 0))
+	require.NoError(t, repo.Save(paymentDomain.WithActor(context.Background(), "system:booking-events"), p))
+
+	require.NoError(t, p.Refund("runner no-show"))
+	p.IncrementVersion()
+	require.NoError(t, repo.Update(paymentDomain.WithActor(context.Background(), "admin:"+adminID.String()), p))
+
+	var rows []PaymentStatusHistoryModel
+	require.NoError(t, db.Where("payment_id = ?", p.ID()).Find(&rows).Error)
+	initiators := make(map[string]paymentDomain.Initiator, len(rows))
+	for _, row := range rows {
+		initiators[row.ToStatus] = paymentDomain.Initiator{Type: row.InitiatedByType, ID: row.InitiatedByID}
+	}
+	assert.Equal(t, paymentDomain.Initiator{Type: "system", ID: "booking-events"}, initiators["held"])
+	assert.Equal(t, paymentDomain.Initiator{Type: "admin", ID: adminID.String()}, initiators["refunded"])
+}
+
+func TestPaymentRepo_StreamAll_FiltersByOwner(t *testing.T) {
+	db := setupRepoTestDB(t)
+	require.NoError(t, db.AutoMigrate(&PaymentModel{}, &PaymentStatusHistoryModel{}))
+	repo := NewPaymentRepository(db)
+	ctx := context.Background()
+	ownerID := uuid.New()
+
+	seed := func(owner uuid.UUID, hold bool) *paymentDomain.Payment {
+		p, err := paymentDomain.NewPayment(uuid.New(), owner, 5000, "MYR", 15, paymentDomain.PayoutFloors{})
+		require.NoError(t, err)
+		if hold {
+			require.NoError(t, p.HoldEscrow("pi_"+p.ID().String(), 0))
+		}
+		require.NoError(t, repo.Save(ctx, p))
+		return p
+	}
+	held := seed(ownerID, true)
+	seed(ownerID, false)
+	seed(uuid.New(), true)
+
+	var streamed []uuid.UUID
+	filter := paymentDomain.ListFilter{Status: paymentDomain.EscrowHeld, OwnerID: &ownerID}
+	require.NoError(t, repo.StreamAll(ctx, filter, func(p *paymentDomain.Payment) error {
+		streamed = append(streamed, p.ID())
+		return nil
+	}))
+	assert.Equal(t, []uuid.UUID{held.ID()}, streamed)
+}
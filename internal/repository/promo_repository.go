@@ -2,8 +2,10 @@ package repository
 
 import (
 	"context"
+	"errors"
 	"time"
 
+	"github.com/Kilat-Pet-Delivery/lib-common/domain"
 	promoDomain "github.com/Kilat-Pet-Delivery/service-payment/internal/domain/promo"
 	"github.com/google/uuid"
 	"gorm.io/gorm"
@@ -19,6 +21,8 @@ type PromoModel struct {
 	MaxDiscountCents int64     `gorm:"default:0"`
 	MaxUses          int       `gorm:"default:0"`
 	CurrentUses      int       `gorm:"default:0"`
+	FirstBookingOnly bool      `gorm:"not null;default:false"`
+	ApplicablePlans  []string  `gorm:"type:jsonb;serializer:json"`
 	ValidFrom        time.Time `gorm:"not null"`
 	ValidUntil       time.Time `gorm:"not null"`
 	CreatedBy        uuid.UUID `gorm:"type:uuid;not null"`
@@ -77,6 +81,9 @@ func (r *GormPromoRepository) FindByCode(ctx context.Context, code string) (*pro
 func (r *GormPromoRepository) FindByID(ctx context.Context, id uuid.UUID) (*promoDomain.PromoCode, error) {
 	var model PromoModel
 	if err := r.db.WithContext(ctx).Where("id = ?", id).First(&model).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, domain.NewNotFoundError("PromoCode", id.String())
+		}
 		return nil, err
 	}
 	return toPromoDomain(&model), nil
@@ -100,6 +107,76 @@ func (r *GormPromoRepository) FindActive(ctx context.Context) ([]*promoDomain.Pr
 	return promos, nil
 }
 
+// FindUpcoming returns promo codes scheduled to become active in the future.
+func (r *GormPromoRepository) FindUpcoming(ctx context.Context) ([]*promoDomain.PromoCode, error) {
+	var models []PromoModel
+	if err := r.db.WithContext(ctx).
+		Where("valid_from > ?", time.Now().UTC()).
+		Order("valid_from ASC").
+		Find(&models).Error; err != nil {
+		return nil, err
+	}
+
+	promos := make([]*promoDomain.PromoCode, len(models))
+	for i, m := range models {
+		promos[i] = toPromoDomain(&m)
+	}
+	return promos, nil
+}
+
+// FindByCreator returns a page of promo codes created by createdBy, newest first.
+func (r *GormPromoRepository) FindByCreator(ctx context.Context, createdBy uuid.UUID, page, limit int) ([]*promoDomain.PromoCode, int64, error) {
+	var total int64
+	query := r.db.WithContext(ctx).Model(&PromoModel{}).Where("created_by = ?", createdBy)
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var models []PromoModel
+	offset := (page - 1) * limit
+	if err := query.Order("created_at DESC").Offset(offset).Limit(limit).Find(&models).Error; err != nil {
+		return nil, 0, err
+	}
+
+	promos := make([]*promoDomain.PromoCode, len(models))
+	for i, m := range models {
+		promos[i] = toPromoDomain(&m)
+	}
+	return promos, total, nil
+}
+
+// UsageStats aggregates promo_usages for each of promoIDs.
+func (r *GormPromoRepository) UsageStats(ctx context.Context, promoIDs []uuid.UUID) (map[uuid.UUID]promoDomain.UsageStats, error) {
+	stats := make(map[uuid.UUID]promoDomain.UsageStats, len(promoIDs))
+	if len(promoIDs) == 0 {
+		return stats, nil
+	}
+
+	var rows []struct {
+		PromoID            uuid.UUID
+		Uses               int64
+		UniqueUsers        int64
+		TotalDiscountCents int64
+	}
+	if err := r.db.WithContext(ctx).
+		Model(&PromoUsageModel{}).
+		Select("promo_id, COUNT(*) AS uses, COUNT(DISTINCT user_id) AS unique_users, COALESCE(SUM(discount_cents), 0) AS total_discount_cents").
+		Where("promo_id IN ?", promoIDs).
+		Group("promo_id").
+		Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+
+	for _, row := range rows {
+		stats[row.PromoID] = promoDomain.UsageStats{
+			Uses:               row.Uses,
+			UniqueUsers:        row.UniqueUsers,
+			TotalDiscountCents: row.TotalDiscountCents,
+		}
+	}
+	return stats, nil
+}
+
 // SaveUsage persists a promo usage record.
 func (r *GormPromoRepository) SaveUsage(ctx context.Context, usage *promoDomain.PromoUsage) error {
 	model := PromoUsageModel{
@@ -133,6 +210,8 @@ func toPromoModel(p *promoDomain.PromoCode) PromoModel {
 		MaxDiscountCents: p.MaxDiscountCents(),
 		MaxUses:          p.MaxUses(),
 		CurrentUses:      p.CurrentUses(),
+		FirstBookingOnly: p.FirstBookingOnly(),
+		ApplicablePlans:  p.ApplicablePlans(),
 		ValidFrom:        p.ValidFrom(),
 		ValidUntil:       p.ValidUntil(),
 		CreatedBy:        p.CreatedBy(),
@@ -145,7 +224,7 @@ func toPromoDomain(m *PromoModel) *promoDomain.PromoCode {
 	return promoDomain.Reconstruct(
 		m.ID, m.Code, promoDomain.DiscountType(m.DiscountType),
 		m.DiscountValue, m.MinAmountCents, m.MaxDiscountCents,
-		m.MaxUses, m.CurrentUses,
+		m.MaxUses, m.CurrentUses, m.FirstBookingOnly, m.ApplicablePlans,
 		m.ValidFrom, m.ValidUntil, m.CreatedBy,
 		m.CreatedAt, m.UpdatedAt,
 	)
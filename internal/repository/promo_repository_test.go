@@ -0,0 +1,57 @@
+//go:build integration
+
+package repository
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	promoDomain "github.com/Kilat-Pet-Delivery/service-payment/internal/domain/promo"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPromoRepo_FindByCreator_WithUsageStats(t *testing.T) {
+	db := setupRepoTestDB(t)
+	require.NoError(t, db.AutoMigrate(&PromoModel{}, &PromoUsageModel{}))
+	repo := NewGormPromoRepository(db)
+	ctx := context.Background()
+
+	admin, other := uuid.New(), uuid.New()
+	now := time.Now().UTC()
+	save := func(code string, createdBy uuid.UUID) *promoDomain.PromoCode {
+		p, err := promoDomain.NewPromoCode(code, promoDomain.DiscountTypeFixed, 500, 0, 0, 0, now, now.Add(24*time.Hour), createdBy)
+		require.NoError(t, err)
+		require.NoError(t, repo.Save(ctx, p))
+		return p
+	}
+	first := save("ADMIN1", admin)
+	save("ADMIN2", admin)
+	save("ADMIN3", admin)
+	save("OTHER1", other)
+
+	page, total, err := repo.FindByCreator(ctx, admin, 1, 2)
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), total)
+	assert.Len(t, page, 2)
+
+	page, _, err = repo.FindByCreator(ctx, admin, 2, 2)
+	require.NoError(t, err)
+	require.Len(t, page, 1)
+	assert.Equal(t, admin, page[0].CreatedBy())
+
+	user := uuid.New()
+	for _, u := range []uuid.UUID{user, user, uuid.New()} {
+		require.NoError(t, repo.SaveUsage(ctx, &promoDomain.PromoUsage{
+			ID: uuid.New(), PromoID: first.ID(), UserID: u, BookingID: uuid.New(), DiscountCents: 500, UsedAt: now,
+		}))
+	}
+
+	unused := save("UNUSED", admin)
+	stats, err := repo.UsageStats(ctx, []uuid.UUID{first.ID(), unused.ID()})
+	require.NoError(t, err)
+	assert.Equal(t, promoDomain.UsageStats{Uses: 3, UniqueUsers: 2, TotalDiscountCents: 1500}, stats[first.ID()])
+	assert.NotContains(t, stats, unused.ID())
+}
@@ -0,0 +1,122 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/Kilat-Pet-Delivery/lib-common/domain"
+	refundDomain "github.com/Kilat-Pet-Delivery/service-payment/internal/domain/refund"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgconn"
+	"gorm.io/gorm"
+)
+
+// RefundRequestModel is the GORM model for the refund_requests table.
+type RefundRequestModel struct {
+	ID          uuid.UUID  `gorm:"type:uuid;primaryKey"`
+	PaymentID   uuid.UUID  `gorm:"type:uuid;not null;uniqueIndex:idx_refund_requests_one_pending,where:status = 'pending'"`
+	AmountCents int64      `gorm:"not null"`
+	Reason      string     `gorm:"type:text;not null"`
+	Method      string     `gorm:"type:varchar(20);not null"`
+	Status      string     `gorm:"type:varchar(20);not null"`
+	RequestedBy uuid.UUID  `gorm:"type:uuid;not null"`
+	ApprovedBy  *uuid.UUID `gorm:"type:uuid"`
+	ApprovedAt  *time.Time `gorm:"type:timestamptz"`
+	CreatedAt   time.Time  `gorm:"type:timestamptz;not null"`
+	UpdatedAt   time.Time  `gorm:"type:timestamptz;not null"`
+}
+
+// TableName sets the table name.
+func (RefundRequestModel) TableName() string { return "refund_requests" }
+
+// onePendingRefundIndex enforces at most one pending refund request per payment.
+const onePendingRefundIndex = "idx_refund_requests_one_pending"
+
+// GormRefundRequestRepository implements RequestRepository using GORM.
+type GormRefundRequestRepository struct {
+	db *gorm.DB
+}
+
+// NewGormRefundRequestRepository creates a new GormRefundRequestRepository.
+func NewGormRefundRequestRepository(db *gorm.DB) *GormRefundRequestRepository {
+	return &GormRefundRequestRepository{db: db}
+}
+
+// Save persists a new request. It returns a conflict error if the payment
+// already has a pending request.
+func (r *GormRefundRequestRepository) Save(ctx context.Context, req *refundDomain.Request) error {
+	model := toRefundRequestModel(req)
+	if err := r.db.WithContext(ctx).Create(&model).Error; err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == uniqueViolation && pgErr.ConstraintName == onePendingRefundIndex {
+			return domain.NewConflictError("payment already has a pending refund request")
+		}
+		return err
+	}
+	return nil
+}
+
+// FindByID returns a refund request by ID.
+func (r *GormRefundRequestRepository) FindByID(ctx context.Context, id uuid.UUID) (*refundDomain.Request, error) {
+	var model RefundRequestModel
+	if err := r.db.WithContext(ctx).Where("id = ?", id).First(&model).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, domain.NewNotFoundError("RefundRequest", id.String())
+		}
+		return nil, err
+	}
+	return toRefundRequest(&model), nil
+}
+
+// MarkApproved stores the approval only if the request is still pending.
+func (r *GormRefundRequestRepository) MarkApproved(ctx context.Context, req *refundDomain.Request) error {
+	result := r.db.WithContext(ctx).
+		Model(&RefundRequestModel{}).
+		Where("id = ? AND status = ?", req.ID, string(refundDomain.StatusPending)).
+		Updates(map[string]interface{}{
+			"status":      string(req.Status),
+			"approved_by": req.ApprovedBy,
+			"approved_at": req.ApprovedAt,
+			"updated_at":  req.UpdatedAt,
+		})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return domain.NewConflictError("refund request is no longer pending")
+	}
+	return nil
+}
+
+func toRefundRequestModel(req *refundDomain.Request) RefundRequestModel {
+	return RefundRequestModel{
+		ID:          req.ID,
+		PaymentID:   req.PaymentID,
+		AmountCents: req.AmountCents,
+		Reason:      req.Reason,
+		Method:      req.Method,
+		Status:      string(req.Status),
+		RequestedBy: req.RequestedBy,
+		ApprovedBy:  req.ApprovedBy,
+		ApprovedAt:  req.ApprovedAt,
+		CreatedAt:   req.CreatedAt,
+		UpdatedAt:   req.UpdatedAt,
+	}
+}
+
+func toRefundRequest(m *RefundRequestModel) *refundDomain.Request {
+	return &refundDomain.Request{
+		ID:          m.ID,
+		PaymentID:   m.PaymentID,
+		AmountCents: m.AmountCents,
+		Reason:      m.Reason,
+		Method:      m.Method,
+		Status:      refundDomain.Status(m.Status),
+		RequestedBy: m.RequestedBy,
+		ApprovedBy:  m.ApprovedBy,
+		ApprovedAt:  m.ApprovedAt,
+		CreatedAt:   m.CreatedAt,
+		UpdatedAt:   m.UpdatedAt,
+	}
+}
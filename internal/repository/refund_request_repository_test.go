@@ -0,0 +1,49 @@
+//go:build integration
+
+package repository
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/Kilat-Pet-Delivery/lib-common/domain"
+	refundDomain "github.com/Kilat-Pet-Delivery/service-payment/internal/domain/refund"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRefundRequestRepo_OnePendingPerPaymentAndApproveOnce(t *testing.T) {
+	db := setupRepoTestDB(t)
+	require.NoError(t, db.AutoMigrate(&RefundRequestModel{}))
+	repo := NewGormRefundRequestRepository(db)
+	ctx := context.Background()
+
+	now := time.Now().UTC().Truncate(time.Microsecond)
+	paymentID, requester := uuid.New(), uuid.New()
+	req := refundDomain.NewRequest(paymentID, 25000, "damaged carrier", "card", requester, now)
+	require.NoError(t, repo.Save(ctx, req))
+
+	var domErr *domain.DomainError
+	err := repo.Save(ctx, refundDomain.NewRequest(paymentID, 25000, "again", "card", requester, now))
+	require.True(t, errors.As(err, &domErr) && domErr.Err == domain.ErrConflict, "a second pending request conflicts, got %v", err)
+
+	loaded, err := repo.FindByID(ctx, req.ID)
+	require.NoError(t, err)
+	require.NoError(t, loaded.Approve(uuid.New(), now))
+	require.NoError(t, repo.MarkApproved(ctx, loaded))
+
+	err = repo.MarkApproved(ctx, loaded)
+	assert.True(t, errors.As(err, &domErr) && domErr.Err == domain.ErrConflict, "an approved request cannot be approved again")
+
+	fetched, err := repo.FindByID(ctx, req.ID)
+	require.NoError(t, err)
+	assert.Equal(t, refundDomain.StatusApproved, fetched.Status)
+	require.NotNil(t, fetched.ApprovedBy)
+	assert.Equal(t, *loaded.ApprovedBy, *fetched.ApprovedBy)
+
+	require.NoError(t, repo.Save(ctx, refundDomain.NewRequest(paymentID, 25000, "new request", "card", requester, now)),
+		"once approved, the payment may get a new request")
+}
@@ -0,0 +1,66 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	runnerDomain "github.com/Kilat-Pet-Delivery/service-payment/internal/domain/runner"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// RunnerAccountModel is the GORM model for the runner_accounts table.
+type RunnerAccountModel struct {
+	RunnerID        uuid.UUID `gorm:"type:uuid;primaryKey"`
+	StripeAccountID string    `gorm:"type:varchar(255);not null"`
+	LinkedAt        time.Time `gorm:"type:timestamptz;not null"`
+	CreatedAt       time.Time `gorm:"type:timestamptz;not null"`
+	UpdatedAt       time.Time `gorm:"type:timestamptz;not null"`
+}
+
+// TableName sets the table name.
+func (RunnerAccountModel) TableName() string { return "runner_accounts" }
+
+// GormRunnerAccountRepository implements AccountRepository using GORM.
+type GormRunnerAccountRepository struct {
+	db *gorm.DB
+}
+
+// NewGormRunnerAccountRepository creates a new GormRunnerAccountRepository.
+func NewGormRunnerAccountRepository(db *gorm.DB) *GormRunnerAccountRepository {
+	return &GormRunnerAccountRepository{db: db}
+}
+
+// Upsert inserts or updates a runner's account in a single statement. Rows
+// linked later than the incoming account are left untouched, so replayed or
+// out-of-order events are harmless.
+func (r *GormRunnerAccountRepository) Upsert(ctx context.Context, account runnerDomain.Account) error {
+	now := time.Now().UTC()
+	return r.db.WithContext(ctx).Exec(`
+		INSERT INTO runner_accounts (runner_id, stripe_account_id, linked_at, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?)
+		ON CONFLICT (runner_id) DO UPDATE
+		SET stripe_account_id = EXCLUDED.stripe_account_id,
+		    linked_at = EXCLUDED.linked_at,
+		    updated_at = EXCLUDED.updated_at
+		WHERE runner_accounts.linked_at <= EXCLUDED.linked_at`,
+		account.RunnerID, account.StripeAccountID, account.LinkedAt.UTC(), now, now).Error
+}
+
+// FindByRunnerID returns a runner's account, or nil if none is linked.
+func (r *GormRunnerAccountRepository) FindByRunnerID(ctx context.Context, runnerID uuid.UUID) (*runnerDomain.Account, error) {
+	var model RunnerAccountModel
+	if err := r.db.WithContext(ctx).Where("runner_id = ?", runnerID).First(&model).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &runnerDomain.Account{
+		RunnerID:        model.RunnerID,
+		StripeAccountID: model.StripeAccountID,
+		LinkedAt:        model.LinkedAt,
+		UpdatedAt:       model.UpdatedAt,
+	}, nil
+}
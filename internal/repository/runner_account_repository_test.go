@@ -0,0 +1,52 @@
+//go:build integration
+
+package repository
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	runnerDomain "github.com/Kilat-Pet-Delivery/service-payment/internal/domain/runner"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestRunnerAccountRepo_Upsert verifies seeding, updating, replaying and
+// out-of-order updates of a runner's account mapping.
+func TestRunnerAccountRepo_Upsert(t *testing.T) {
+	db := setupRepoTestDB(t)
+	require.NoError(t, db.AutoMigrate(&RunnerAccountModel{}))
+	repo := NewGormRunnerAccountRepository(db)
+	ctx := context.Background()
+
+	runnerID := uuid.New()
+	linked := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	missing, err := repo.FindByRunnerID(ctx, runnerID)
+	require.NoError(t, err)
+	assert.Nil(t, missing)
+
+	first := runnerDomain.Account{RunnerID: runnerID, StripeAccountID: "acct_first", LinkedAt: linked}
+	require.NoError(t, repo.Upsert(ctx, first))
+	require.NoError(t, repo.Upsert(ctx, first), "replaying the same link is a no-op")
+
+	account, err := repo.FindByRunnerID(ctx, runnerID)
+	require.NoError(t, err)
+	require.NotNil(t, account)
+	assert.Equal(t, "acct_first", account.StripeAccountID)
+
+	second := runnerDomain.Account{RunnerID: runnerID, StripeAccountID: "acct_second", LinkedAt: linked.Add(time.Hour)}
+	require.NoError(t, repo.Upsert(ctx, second))
+	require.NoError(t, repo.Upsert(ctx, first), "a stale link is ignored, not rejected")
+
+	account, err = repo.FindByRunnerID(ctx, runnerID)
+	require.NoError(t, err)
+	assert.Equal(t, "acct_second", account.StripeAccountID)
+	assert.True(t, account.LinkedAt.Equal(second.LinkedAt))
+
+	var count int64
+	require.NoError(t, db.Model(&RunnerAccountModel{}).Count(&count).Error)
+	assert.Equal(t, int64(1), count)
+}
@@ -2,25 +2,42 @@ package repository
 
 import (
 	"context"
+	"errors"
 	"time"
 
+	"github.com/Kilat-Pet-Delivery/lib-common/domain"
 	subDomain "github.com/Kilat-Pet-Delivery/service-payment/internal/domain/subscription"
 	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgconn"
 	"gorm.io/gorm"
 )
 
 // SubscriptionModel is the GORM model for the subscriptions table.
 type SubscriptionModel struct {
 	ID         uuid.UUID `gorm:"type:uuid;primaryKey"`
-	UserID     uuid.UUID `gorm:"type:uuid;not null;index"`
+	UserID     uuid.UUID `gorm:"type:uuid;not null;index;uniqueIndex:idx_subscriptions_one_active,where:status IN ('active'\\,'paused');uniqueIndex:idx_subscriptions_idempotency_key,priority:1"`
 	Plan       string    `gorm:"type:varchar(20);not null"`
 	PriceCents int64     `gorm:"not null"`
 	StartedAt  time.Time `gorm:"not null"`
 	ExpiresAt  time.Time `gorm:"not null"`
 	Status     string    `gorm:"type:varchar(20);not null;default:'active'"`
 	AutoRenew  bool      `gorm:"default:true"`
+	Version    int64     `gorm:"not null;default:1"`
 	CreatedAt  time.Time `gorm:"not null"`
 	UpdatedAt  time.Time `gorm:"not null"`
+
+	PausedAt              *time.Time
+	PausedDurationSeconds int64 `gorm:"not null;default:0"`
+
+	Comped     bool       `gorm:"not null;default:false"`
+	CompReason string     `gorm:"type:text"`
+	GrantedBy  *uuid.UUID `gorm:"type:uuid"`
+
+	// IdempotencyKey is unique per user; NULL when the signup sent none.
+	IdempotencyKey *string `gorm:"type:varchar(255);uniqueIndex:idx_subscriptions_idempotency_key,priority:2"`
+
+	CommitmentEndsAt *time.Time
+	CancelledEarly   bool `gorm:"not null;default:false"`
 }
 
 // TableName sets the table name.
@@ -36,16 +53,81 @@ func NewGormSubscriptionRepository(db *gorm.DB) *GormSubscriptionRepository {
 	return &GormSubscriptionRepository{db: db}
 }
 
-// Save persists a new subscription.
+// uniqueViolation is the Postgres error code for a unique constraint violation.
+const uniqueViolation = "23505"
+
+// oneActiveIndex enforces at most one active subscription per user.
+const oneActiveIndex = "idx_subscriptions_one_active"
+
+// idempotencyKeyIndex enforces one subscription per user and signup Idempotency-Key.
+const idempotencyKeyIndex = "idx_subscriptions_idempotency_key"
+
+// Save persists a new subscription. It returns a conflict error if the user
+// already has an active subscription.
 func (r *GormSubscriptionRepository) Save(ctx context.Context, s *subDomain.Subscription) error {
 	model := toSubModel(s)
-	return r.db.WithContext(ctx).Create(&model).Error
+	if err := r.db.WithContext(ctx).Create(&model).Error; err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == uniqueViolation {
+			switch pgErr.ConstraintName {
+			case oneActiveIndex:
+				return subDomain.ErrActiveSubscriptionExists
+			case idempotencyKeyIndex:
+				return subDomain.ErrDuplicateIdempotencyKey
+			}
+		}
+		return err
+	}
+	return nil
+}
+
+// ExpireLapsed marks the user's active subscriptions that expired before now as expired.
+func (r *GormSubscriptionRepository) ExpireLapsed(ctx context.Context, userID uuid.UUID, now time.Time) error {
+	return r.db.WithContext(ctx).
+		Model(&SubscriptionModel{}).
+		Where("user_id = ? AND status = ? AND expires_at <= ?", userID, string(subDomain.StatusActive), now).
+		Updates(map[string]interface{}{
+			"status":     string(subDomain.StatusExpired),
+			"version":    gorm.Expr("version + 1"),
+			"updated_at": now,
+		}).Error
 }
 
-// Update updates a subscription.
+// Update persists changes to an existing subscription with optimistic locking.
 func (r *GormSubscriptionRepository) Update(ctx context.Context, s *subDomain.Subscription) error {
 	model := toSubModel(s)
-	return r.db.WithContext(ctx).Save(&model).Error
+	previousVersion := s.Version() - 1
+
+	result := r.db.WithContext(ctx).
+		Model(&SubscriptionModel{}).
+		Where("id = ? AND version = ?", model.ID, previousVersion).
+		// A map is used so zero values such as auto_renew=false are written.
+		Updates(map[string]interface{}{
+			"plan":        model.Plan,
+			"price_cents": model.PriceCents,
+			"started_at":  model.StartedAt,
+			"expires_at":  model.ExpiresAt,
+			"status":      model.Status,
+			"auto_renew":  model.AutoRenew,
+			"version":     model.Version,
+			"updated_at":  model.UpdatedAt,
+
+			"paused_at":               model.PausedAt,
+			"paused_duration_seconds": model.PausedDurationSeconds,
+
+			"commitment_ends_at": model.CommitmentEndsAt,
+			"cancelled_early":    model.CancelledEarly,
+		})
+
+	if result.Error != nil {
+		return result.Error
+	}
+
+	if result.RowsAffected == 0 {
+		return domain.NewConflictError("subscription was modified by another transaction")
+	}
+
+	return nil
 }
 
 // FindActiveByUserID returns the active subscription for a user.
@@ -56,6 +138,23 @@ func (r *GormSubscriptionRepository) FindActiveByUserID(ctx context.Context, use
 		Where("user_id = ? AND status = ? AND expires_at > ?", userID, "active", now).
 		Order("created_at DESC").
 		First(&model).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, subDomain.ErrNoActiveSubscription
+		}
+		return nil, err
+	}
+	return toSubDomain(&model), nil
+}
+
+// FindPausedByUserID returns the user's paused subscription.
+func (r *GormSubscriptionRepository) FindPausedByUserID(ctx context.Context, userID uuid.UUID) (*subDomain.Subscription, error) {
+	var model SubscriptionModel
+	if err := r.db.WithContext(ctx).
+		Where("user_id = ? AND status = ?", userID, string(subDomain.StatusPaused)).
+		First(&model).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, subDomain.ErrNoPausedSubscription
+		}
 		return nil, err
 	}
 	return toSubDomain(&model), nil
@@ -65,24 +164,104 @@ func (r *GormSubscriptionRepository) FindActiveByUserID(ctx context.Context, use
 func (r *GormSubscriptionRepository) FindByID(ctx context.Context, id uuid.UUID) (*subDomain.Subscription, error) {
 	var model SubscriptionModel
 	if err := r.db.WithContext(ctx).Where("id = ?", id).First(&model).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, domain.NewNotFoundError("Subscription", id.String())
+		}
+		return nil, err
+	}
+	return toSubDomain(&model), nil
+}
+
+// FindByIdempotencyKey returns the user's subscription created with the signup
+// Idempotency-Key key.
+func (r *GormSubscriptionRepository) FindByIdempotencyKey(ctx context.Context, userID uuid.UUID, key string) (*subDomain.Subscription, error) {
+	var model SubscriptionModel
+	if err := r.db.WithContext(ctx).Where("user_id = ? AND idempotency_key = ?", userID, key).First(&model).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, domain.NewNotFoundError("Subscription", key)
+		}
 		return nil, err
 	}
 	return toSubDomain(&model), nil
 }
 
+// FindByUserID returns a page of a user's subscriptions, newest first.
+func (r *GormSubscriptionRepository) FindByUserID(ctx context.Context, userID uuid.UUID, page, limit int) ([]*subDomain.Subscription, int64, error) {
+	var total int64
+	query := r.db.WithContext(ctx).Model(&SubscriptionModel{}).Where("user_id = ?", userID)
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var models []SubscriptionModel
+	offset := (page - 1) * limit
+	if err := query.Order("created_at DESC").Offset(offset).Limit(limit).Find(&models).Error; err != nil {
+		return nil, 0, err
+	}
+
+	subs := make([]*subDomain.Subscription, len(models))
+	for i := range models {
+		subs[i] = toSubDomain(&models[i])
+	}
+	return subs, total, nil
+}
+
+// FindRenewalDue returns active, auto-renewing, paid subscriptions expiring in
+// (now, before], soonest first.
+func (r *GormSubscriptionRepository) FindRenewalDue(ctx context.Context, now, before time.Time, limit int) ([]*subDomain.Subscription, error) {
+	var models []SubscriptionModel
+	if err := r.db.WithContext(ctx).
+		Where("status = ? AND auto_renew AND NOT comped AND expires_at > ? AND expires_at <= ?",
+			string(subDomain.StatusActive), now, before).
+		Order("expires_at ASC").
+		Limit(limit).
+		Find(&models).Error; err != nil {
+		return nil, err
+	}
+
+	subs := make([]*subDomain.Subscription, len(models))
+	for i := range models {
+		subs[i] = toSubDomain(&models[i])
+	}
+	return subs, nil
+}
+
 func toSubModel(s *subDomain.Subscription) SubscriptionModel {
-	return SubscriptionModel{
+	model := SubscriptionModel{
 		ID: s.ID(), UserID: s.UserID(), Plan: string(s.Plan()),
 		PriceCents: s.PriceCents(), StartedAt: s.StartedAt(), ExpiresAt: s.ExpiresAt(),
-		Status: string(s.Status()), AutoRenew: s.AutoRenew(),
+		Status: string(s.Status()), AutoRenew: s.AutoRenew(), Version: s.Version(),
 		CreatedAt: s.CreatedAt(), UpdatedAt: s.UpdatedAt(),
+		PausedAt: s.PausedAt(), PausedDurationSeconds: int64(s.PausedDuration() / time.Second),
+		CommitmentEndsAt: s.CommitmentEndsAt(), CancelledEarly: s.CancelledEarly(),
+	}
+	if g := s.Grant(); g != nil {
+		grantedBy := g.GrantedBy
+		model.Comped, model.CompReason, model.GrantedBy = true, g.Reason, &grantedBy
 	}
+	if key := s.IdempotencyKey(); key != "" {
+		model.IdempotencyKey = &key
+	}
+	return model
 }
 
 func toSubDomain(m *SubscriptionModel) *subDomain.Subscription {
+	var grant *subDomain.Grant
+	if m.Comped {
+		grant = &subDomain.Grant{Reason: m.CompReason}
+		if m.GrantedBy != nil {
+			grant.GrantedBy = *m.GrantedBy
+		}
+	}
+	var idempotencyKey string
+	if m.IdempotencyKey != nil {
+		idempotencyKey = *m.IdempotencyKey
+	}
 	return subDomain.Reconstruct(
 		m.ID, m.UserID, subDomain.PlanType(m.Plan), m.PriceCents,
 		m.StartedAt, m.ExpiresAt, subDomain.SubStatus(m.Status), m.AutoRenew,
+		m.PausedAt, time.Duration(m.PausedDurationSeconds)*time.Second, grant, idempotencyKey,
+		m.CommitmentEndsAt, m.CancelledEarly, m.Version,
 		m.CreatedAt, m.UpdatedAt,
 	)
 }
@@ -0,0 +1,294 @@
+//go:build integration
+
+package repository
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Kilat-Pet-Delivery/lib-common/domain"
+	"github.com/Kilat-Pet-Delivery/service-payment/internal/application"
+	subDomain "github.com/Kilat-Pet-Delivery/service-payment/internal/domain/subscription"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+// TestSubscriptionRepo_Update_ConcurrentWritesConflict verifies that when two
+// callers load the same subscription and both mutate it, exactly one Update
+// succeeds and the other receives a conflict error.
+func TestSubscriptionRepo_Update_ConcurrentWritesConflict(t *testing.T) {
+	db := setupRepoTestDB(t)
+	require.NoError(t, db.AutoMigrate(&SubscriptionModel{}))
+	repo := NewGormSubscriptionRepository(db)
+	ctx := context.Background()
+
+	sub, err := subDomain.NewSubscription(uuid.New(), subDomain.PlanBasic)
+	require.NoError(t, err)
+	require.NoError(t, repo.Save(ctx, sub))
+
+	const writers = 2
+	loaded := make([]*subDomain.Subscription, writers)
+	for i := range loaded {
+		loaded[i], err = repo.FindByID(ctx, sub.ID())
+		require.NoError(t, err)
+	}
+
+	var wg sync.WaitGroup
+	errs := make([]error, writers)
+	for i := range loaded {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			loaded[i].Cancel()
+			errs[i] = repo.Update(ctx, loaded[i])
+		}(i)
+	}
+	wg.Wait()
+
+	var succeeded, conflicted int
+	for _, e := range errs {
+		var domErr *domain.DomainError
+		switch {
+		case e == nil:
+			succeeded++
+		case errors.As(e, &domErr) && domErr.Err == domain.ErrConflict:
+			conflicted++
+		default:
+			t.Fatalf("unexpected update error: %v", e)
+		}
+	}
+	assert.Equal(t, 1, succeeded, "exactly one writer should win")
+	assert.Equal(t, 1, conflicted, "the losing writer should get a conflict")
+
+	fetched, err := repo.FindByID(ctx, sub.ID())
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), fetched.Version())
+	assert.Equal(t, subDomain.StatusCancelled, fetched.Status())
+	assert.False(t, fetched.AutoRenew())
+}
+
+// TestSubscriptionRepo_Update_PersistsEarlyCancel verifies that the
+// commitment and the early-cancel marker round-trip through Save, Update and
+// reload, and that a cancel without a commitment is not marked early.
+func TestSubscriptionRepo_Update_PersistsEarlyCancel(t *testing.T) {
+	db := setupRepoTestDB(t)
+	require.NoError(t, db.AutoMigrate(&SubscriptionModel{}))
+	repo := NewGormSubscriptionRepository(db)
+	ctx := context.Background()
+
+	sub, err := subDomain.NewSubscription(uuid.New(), subDomain.PlanPremium)
+	require.NoError(t, err)
+	sub.SetCommitment(90 * 24 * time.Hour)
+	require.NoError(t, repo.Save(ctx, sub))
+
+	saved, err := repo.FindByID(ctx, sub.ID())
+	require.NoError(t, err)
+	require.NotNil(t, saved.CommitmentEndsAt())
+	assert.WithinDuration(t, *sub.CommitmentEndsAt(), *saved.CommitmentEndsAt(), time.Millisecond)
+	assert.False(t, saved.CancelledEarly())
+
+	sub.CancelWithPolicy(time.Now().UTC())
+	require.NoError(t, repo.Update(ctx, sub))
+
+	fetched, err := repo.FindByID(ctx, sub.ID())
+	require.NoError(t, err)
+	require.NotNil(t, fetched.CommitmentEndsAt())
+	assert.WithinDuration(t, *sub.CommitmentEndsAt(), *fetched.CommitmentEndsAt(), time.Millisecond)
+	assert.True(t, fetched.CancelledEarly())
+
+	uncommitted, err := subDomain.NewSubscription(uuid.New(), subDomain.PlanPremium)
+	require.NoError(t, err)
+	require.NoError(t, repo.Save(ctx, uncommitted))
+	uncommitted.CancelWithPolicy(time.Now().UTC())
+	require.NoError(t, repo.Update(ctx, uncommitted))
+
+	fetched, err = repo.FindByID(ctx, uncommitted.ID())
+	require.NoError(t, err)
+	assert.Nil(t, fetched.CommitmentEndsAt())
+	assert.False(t, fetched.CancelledEarly())
+}
+
+// TestSubscriptionRepo_Save_SecondActiveRejected verifies that the partial
+// unique index rejects a second active subscription even when the caller
+// skipped the application-level check.
+// TestSubscriptionRepo_FindRenewalDue_OnlyRunningAutoRenewingPaid verifies
+// that the renewal batch only sees paid, auto-renewing subscriptions that are
+// still active and expire within the window.
+func TestSubscriptionRepo_FindRenewalDue_OnlyRunningAutoRenewingPaid(t *testing.T) {
+	db := setupRepoTestDB(t)
+	require.NoError(t, db.AutoMigrate(&SubscriptionModel{}))
+	repo := NewGormSubscriptionRepository(db)
+	ctx := context.Background()
+	now := time.Now().UTC().Truncate(time.Microsecond)
+
+	save := func(expiresAt time.Time, status subDomain.SubStatus, autoRenew bool, grant *subDomain.Grant) uuid.UUID {
+		t.Helper()
+		sub := subDomain.Reconstruct(uuid.New(), uuid.New(), subDomain.PlanBasic, 999, now.AddDate(0, -1, 0), expiresAt,
+			status, autoRenew, nil, 0, grant, "", nil, false, 1, now, now)
+		require.NoError(t, repo.Save(ctx, sub))
+		return sub.ID()
+	}
+	dueLater := save(now.Add(20*time.Hour), subDomain.StatusActive, true, nil)
+	dueSoon := save(now.Add(time.Hour), subDomain.StatusActive, true, nil)
+	save(now.Add(48*time.Hour), subDomain.StatusActive, true, nil)
+	save(now.Add(-time.Hour), subDomain.StatusActive, true, nil)
+	save(now.Add(time.Hour), subDomain.StatusActive, false, nil)
+	save(now.Add(time.Hour), subDomain.StatusCancelled, true, nil)
+	save(now.Add(time.Hour), subDomain.StatusActive, true, &subDomain.Grant{GrantedBy: uuid.New(), Reason: "support"})
+
+	due, err := repo.FindRenewalDue(ctx, now, now.Add(24*time.Hour), 10)
+	require.NoError(t, err)
+	require.Len(t, due, 2)
+	assert.Equal(t, dueSoon, due[0].ID(), "soonest expiry comes first")
+	assert.Equal(t, dueLater, due[1].ID())
+
+	limited, err := repo.FindRenewalDue(ctx, now, now.Add(24*time.Hour), 1)
+	require.NoError(t, err)
+	assert.Len(t, limited, 1)
+}
+
+func TestSubscriptionRepo_Save_SecondActiveRejected(t *testing.T) {
+	db := setupRepoTestDB(t)
+	require.NoError(t, db.AutoMigrate(&SubscriptionModel{}))
+	repo := NewGormSubscriptionRepository(db)
+	ctx := context.Background()
+	userID := uuid.New()
+
+	first, err := subDomain.NewSubscription(userID, subDomain.PlanBasic)
+	require.NoError(t, err)
+	require.NoError(t, repo.Save(ctx, first))
+
+	second, err := subDomain.NewSubscription(userID, subDomain.PlanPremium)
+	require.NoError(t, err)
+	err = repo.Save(ctx, second)
+	assert.ErrorIs(t, err, subDomain.ErrActiveSubscriptionExists)
+
+	// Once the first is cancelled a new active subscription is allowed.
+	first.Cancel()
+	require.NoError(t, repo.Update(ctx, first))
+	assert.NoError(t, repo.Save(ctx, second))
+}
+
+// TestSubscriptionRepo_Pause_PersistsAndBlocksNewSubscription verifies the
+// pause fields round-trip and that a paused subscription still counts against
+// the one-active index.
+func TestSubscriptionRepo_Pause_PersistsAndBlocksNewSubscription(t *testing.T) {
+	db := setupRepoTestDB(t)
+	require.NoError(t, db.AutoMigrate(&SubscriptionModel{}))
+	repo := NewGormSubscriptionRepository(db)
+	ctx := context.Background()
+	userID := uuid.New()
+
+	sub, err := subDomain.NewSubscription(userID, subDomain.PlanBasic)
+	require.NoError(t, err)
+	require.NoError(t, repo.Save(ctx, sub))
+
+	pausedAt := time.Now().UTC().Truncate(time.Microsecond)
+	require.NoError(t, sub.Pause(pausedAt))
+	require.NoError(t, repo.Update(ctx, sub))
+
+	paused, err := repo.FindPausedByUserID(ctx, userID)
+	require.NoError(t, err)
+	require.NotNil(t, paused.PausedAt())
+	assert.True(t, paused.PausedAt().Equal(pausedAt))
+	_, err = repo.FindActiveByUserID(ctx, userID)
+	assert.Error(t, err, "a paused subscription is not active")
+
+	second, err := subDomain.NewSubscription(userID, subDomain.PlanPremium)
+	require.NoError(t, err)
+	assert.ErrorIs(t, repo.Save(ctx, second), subDomain.ErrActiveSubscriptionExists)
+
+	require.NoError(t, paused.Resume(pausedAt.Add(time.Hour)))
+	require.NoError(t, repo.Update(ctx, paused))
+	resumed, err := repo.FindActiveByUserID(ctx, userID)
+	require.NoError(t, err)
+	assert.Nil(t, resumed.PausedAt())
+	assert.Equal(t, time.Hour, resumed.PausedDuration())
+}
+
+// TestSubscribe_ConcurrentRequests_OneActive fires two subscribe requests for
+// the same user at once and verifies exactly one active subscription results.
+func TestSubscribe_ConcurrentRequests_OneActive(t *testing.T) {
+	db := setupRepoTestDB(t)
+	require.NoError(t, db.AutoMigrate(&SubscriptionModel{}))
+	repo := NewGormSubscriptionRepository(db)
+	svc := application.NewSubscriptionService(repo, nil, zap.NewNop())
+	ctx := context.Background()
+	userID := uuid.New()
+
+	const requests = 2
+	start := make(chan struct{})
+	var wg sync.WaitGroup
+	errs := make([]error, requests)
+	for i := 0; i < requests; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			<-start
+			_, errs[i] = svc.Subscribe(ctx, userID, application.SubscribeRequest{Plan: string(subDomain.PlanBasic)}, "")
+		}(i)
+	}
+	close(start)
+	wg.Wait()
+
+	var succeeded, conflicted int
+	for _, e := range errs {
+		switch {
+		case e == nil:
+			succeeded++
+		case errors.Is(e, subDomain.ErrActiveSubscriptionExists):
+			conflicted++
+		default:
+			t.Fatalf("unexpected subscribe error: %v", e)
+		}
+	}
+	assert.Equal(t, 1, succeeded, "exactly one subscribe should win")
+	assert.Equal(t, 1, conflicted, "the other should get a conflict")
+
+	var active int64
+	require.NoError(t, db.Model(&SubscriptionModel{}).
+		Where("user_id = ? AND status = ?", userID, "active").
+		Count(&active).Error)
+	assert.Equal(t, int64(1), active)
+}
+
+// TestSubscribe_ConcurrentRequestsSameIdempotencyKey fires two signups with the
+// same Idempotency-Key at once and verifies both return the one subscription.
+func TestSubscribe_ConcurrentRequestsSameIdempotencyKey(t *testing.T) {
+	db := setupRepoTestDB(t)
+	require.NoError(t, db.AutoMigrate(&SubscriptionModel{}))
+	repo := NewGormSubscriptionRepository(db)
+	svc := application.NewSubscriptionService(repo, nil, zap.NewNop())
+	ctx := context.Background()
+	userID := uuid.New()
+
+	const requests = 2
+	start := make(chan struct{})
+	var wg sync.WaitGroup
+	results := make([]*application.SubscriptionDTO, requests)
+	errs := make([]error, requests)
+	for i := 0; i < requests; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			<-start
+			results[i], errs[i] = svc.Subscribe(ctx, userID, application.SubscribeRequest{Plan: string(subDomain.PlanBasic)}, "signup-retry")
+		}(i)
+	}
+	close(start)
+	wg.Wait()
+
+	for _, e := range errs {
+		require.NoError(t, e)
+	}
+	assert.Equal(t, results[0].ID, results[1].ID)
+
+	var count int64
+	require.NoError(t, db.Model(&SubscriptionModel{}).Where("user_id = ?", userID).Count(&count).Error)
+	assert.Equal(t, int64(1), count)
+}
@@ -0,0 +1,172 @@
+package saga
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/Kilat-Pet-Delivery/lib-common/domain"
+	"github.com/Kilat-Pet-Delivery/service-payment/internal/adapter"
+	"github.com/Kilat-Pet-Delivery/service-payment/internal/domain/payment"
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel/attribute"
+	"go.uber.org/zap"
+)
+
+// ErrAdjustmentNeedsAuthentication is returned when the card authorization for
+// an adjusted amount requires 3-D Secure, which the customer cannot complete
+// for a booking change. The payment keeps its current amount.
+var ErrAdjustmentNeedsAuthentication = errors.New("adjusted amount requires customer authentication")
+
+// AdjustEscrowSaga changes the amount of a held payment to amountCents after
+// its booking's price changed to grossCents, as requested by the booking event
+// eventID. A card authorization cannot change amount, so the card part is
+// authorized again with a new PaymentIntent, the fee split is recomputed at
+// the payment's current fee rate, and the previous authorization is cancelled.
+// Each event is applied at most once; repeats return nil. Credit applied to
+// the payment is kept.
+func (s *PaymentSagaService) AdjustEscrowSaga(ctx context.Context, paymentID uuid.UUID, amountCents, grossCents int64, eventID string) error {
+	ctx, span := startSagaSpan(ctx, "adjust_escrow", attribute.String("payment.id", paymentID.String()))
+	defer span.End()
+	ctx, done := s.inflight.start(ctx, "adjust_escrow", paymentID.String())
+	defer done()
+
+	applied, err := s.repo.HasAmountAdjustment(ctx, eventID)
+	if err != nil {
+		return err
+	}
+	if applied {
+		return nil
+	}
+
+	p, err := s.repo.FindByID(ctx, paymentID)
+	if err != nil {
+		return err
+	}
+
+	// Reject early so an adjustment that lost a race never touches Stripe.
+	if p.EscrowStatus() != payment.EscrowHeld {
+		return domain.NewInvalidStateError(string(p.EscrowStatus()), "amount_adjusted")
+	}
+	if p.StripePaymentID() == "" || amountCents <= p.CreditAppliedCents() {
+		return fmt.Errorf("%w: payment %s cannot be reauthorized for %d", payment.ErrInvalidAdjustment, paymentID, amountCents)
+	}
+
+	previousIntentID := p.StripePaymentID()
+	var intentID string
+	var ownIntent bool
+
+	saga := s.newSaga("adjust_escrow", p)
+
+	// Step 1: Authorize the adjusted card amount with a new PaymentIntent. The
+	// event ID and payment version key it, so a redelivered event reuses the
+	// intent while the payment is unchanged and the intent still authorized.
+	saga.AddStep(SagaStep{
+		Name: "authorize_adjusted_amount",
+		Execute: func(ctx context.Context) error {
+			cardCents := amountCents - p.CreditAppliedCents()
+			key := fmt.Sprintf("adjust:%s-v%d", eventID, p.Version())
+			intent, err := s.stripe.CreatePaymentIntent(ctx, cardCents, p.Currency(),
+				p.CustomerEmail(), paymentIntentMetadata(p), key)
+			if err != nil {
+				return err
+			}
+			if intent.Status == adapter.IntentRequiresCapture {
+				// Stripe replays the response cached for a key, so the intent
+				// of an earlier attempt that failed and cancelled it still
+				// reads as authorized. Check its live status and, if it is no
+				// longer usable, authorize under a key of this attempt's own.
+				live, err := s.stripe.GetPaymentIntent(ctx, intent.ID)
+				if err != nil {
+					return err
+				}
+				if live.Status != adapter.IntentRequiresCapture {
+					s.logger.Info("adjustment intent for this event is no longer authorized, creating another",
+						zap.String("payment_intent_id", intent.ID),
+						zap.String("status", live.Status),
+					)
+					intent, err = s.stripe.CreatePaymentIntent(ctx, cardCents, p.Currency(),
+						p.CustomerEmail(), paymentIntentMetadata(p), key+"-"+uuid.NewString())
+					if err != nil {
+						return err
+					}
+					ownIntent = true
+				}
+			}
+			if intent.Status == adapter.IntentRequiresCapture {
+				intentID = intent.ID
+				return nil
+			}
+			// A failed step is not compensated, so cancel the unusable intent here.
+			if err := s.stripe.CancelPaymentIntent(ctx, intent.ID); err != nil {
+				s.logger.Warn("failed to cancel unusable adjustment intent",
+					zap.String("payment_intent_id", intent.ID),
+					zap.Error(err),
+				)
+			}
+			if intent.Status == adapter.IntentRequiresAction {
+				return ErrAdjustmentNeedsAuthentication
+			}
+			return fmt.Errorf("adjusted amount not authorized: intent %s is %s", intent.ID, intent.Status)
+		},
+		Compensate: func(ctx context.Context) error {
+			if intentID == "" {
+				return nil
+			}
+			return s.stripe.CancelPaymentIntent(ctx, intentID)
+		},
+	})
+
+	// Step 2: Adjust the payment to the new intent and amount and persist it
+	// with the adjustment
+	saga.AddStep(SagaStep{
+		Name: "adjust_escrow",
+		Execute: func(ctx context.Context) error {
+			adj, err := p.AdjustAmount(amountCents, grossCents, intentID, eventID, s.floors)
+			if err != nil {
+				return err
+			}
+			p.IncrementVersion()
+			err = s.repo.AdjustAmount(ctx, p, adj)
+			if errors.Is(err, payment.ErrAdjustmentApplied) && !ownIntent {
+				// A concurrent delivery of the same event won and holds the
+				// intent this run reused, so it must not be cancelled.
+				intentID = ""
+			}
+			return err
+		},
+		Compensate: nil, // Last step that can fail
+	})
+
+	// Step 3: Cancel the previous authorization so the customer's card funds
+	// are freed. The payment no longer refers to it, so a failure is only logged.
+	saga.AddStep(SagaStep{
+		Name: "cancel_previous_authorization",
+		Execute: func(ctx context.Context) error {
+			if err := s.stripe.CancelPaymentIntent(ctx, previousIntentID); err != nil {
+				s.logger.Warn("failed to cancel authorization replaced by amount adjustment",
+					zap.String("payment_id", p.ID().String()),
+					zap.String("payment_intent_id", previousIntentID),
+					zap.Error(err),
+				)
+			}
+			return nil
+		},
+		Compensate: nil,
+	})
+
+	if err := saga.Execute(ctx); err != nil {
+		if errors.Is(err, payment.ErrAdjustmentApplied) {
+			return nil
+		}
+		return err
+	}
+
+	s.logger.Info("escrow amount adjusted",
+		zap.String("payment_id", p.ID().String()),
+		zap.String("event_id", eventID),
+		zap.Int64("amount_cents", amountCents),
+		zap.String("payment_intent_id", intentID),
+	)
+	return nil
+}
@@ -0,0 +1,167 @@
+package saga
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"testing"
+
+	"github.com/Kilat-Pet-Delivery/service-payment/internal/adapter"
+	"github.com/Kilat-Pet-Delivery/service-payment/internal/domain/payment"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+// adjustingRepo returns one payment and records its amount adjustments by
+// event ID. adjustErr, if set, fails AdjustAmount.
+type adjustingRepo struct {
+	payment.PaymentRepository
+	p           *payment.Payment
+	adjustments map[string]payment.AmountAdjustment
+	adjustErr   error
+}
+
+func (r *adjustingRepo) FindByID(context.Context, uuid.UUID) (*payment.Payment, error) {
+	return r.p, nil
+}
+
+func (r *adjustingRepo) HasAmountAdjustment(_ context.Context, eventID string) (bool, error) {
+	_, ok := r.adjustments[eventID]
+	return ok, nil
+}
+
+func (r *adjustingRepo) AdjustAmount(_ context.Context, _ *payment.Payment, adj payment.AmountAdjustment) error {
+	if r.adjustErr != nil {
+		return r.adjustErr
+	}
+	r.adjustments[adj.EventID] = adj
+	return nil
+}
+
+// reauthStripe creates intents with a fixed status and records intents
+// created and cancelled. Like Stripe, it replays the creation response for a
+// repeated idempotency key, even once the intent is cancelled.
+type reauthStripe struct {
+	adapter.StripeAdapter
+	status    string
+	created   []int64
+	cancelled []string
+	byKey     map[string]adapter.PaymentIntent
+}
+
+func (s *reauthStripe) CreatePaymentIntent(_ context.Context, amountCents int64, currency, _ string, _ map[string]string, key string) (*adapter.PaymentIntent, error) {
+	if intent, ok := s.byKey[key]; ok {
+		return &intent, nil
+	}
+	s.created = append(s.created, amountCents)
+	intent := adapter.PaymentIntent{ID: fmt.Sprintf("pi_new_%d", len(s.created)), AmountCents: amountCents, Currency: currency, Status: s.status}
+	if s.byKey == nil {
+		s.byKey = map[string]adapter.PaymentIntent{}
+	}
+	s.byKey[key] = intent
+	return &intent, nil
+}
+
+func (s *reauthStripe) GetPaymentIntent(_ context.Context, id string) (*adapter.PaymentIntent, error) {
+	status := s.status
+	if slices.Contains(s.cancelled, id) {
+		status = "canceled"
+	}
+	return &adapter.PaymentIntent{ID: id, Status: status}, nil
+}
+
+func (s *reauthStripe) CancelPaymentIntent(_ context.Context, id string) error {
+	s.cancelled = append(s.cancelled, id)
+	return nil
+}
+
+func newAdjustSaga(t *testing.T, status string) (*PaymentSagaService, *adjustingRepo, *reauthStripe) {
+	t.Helper()
+	p, err := payment.NewPayment(uuid.New(), uuid.New(), 10000, "MYR", 15.0, payment.PayoutFloors{})
+	require.NoError(t, err)
+	require.NoError(t, p.ApplyCredit(1000))
+	require.NoError(t, p.HoldEscrow("pi_1", 0))
+
+	repo := &adjustingRepo{p: p, adjustments: map[string]payment.AmountAdjustment{}}
+	stripe := &reauthStripe{status: status}
+	s := NewPaymentSagaService(repo, stripe, nil, nil, nil, nil, nil, nil, 15.0, payment.PayoutFloors{}, 0, 0, zap.NewNop())
+	return s, repo, stripe
+}
+
+func TestAdjustEscrowSaga_ReauthorizesAndSplitsAgain(t *testing.T) {
+	s, repo, stripe := newAdjustSaga(t, adapter.IntentRequiresCapture)
+	p := repo.p
+
+	require.NoError(t, s.AdjustEscrowSaga(context.Background(), p.ID(), 12000, 12000, "evt-1"))
+	assert.Equal(t, []int64{11000}, stripe.created, "the card is authorized for the new amount less credit")
+	assert.Equal(t, []string{"pi_1"}, stripe.cancelled, "the replaced authorization is released")
+	assert.Equal(t, "pi_new_1", p.StripePaymentID())
+	assert.Equal(t, int64(12000), p.AmountCents())
+	assert.Equal(t, int64(1800), p.PlatformFeeCents())
+	assert.Equal(t, int64(10200), p.RunnerPayoutCents())
+	require.Contains(t, repo.adjustments, "evt-1")
+	assert.Equal(t, int64(10000), repo.adjustments["evt-1"].PreviousAmountCents)
+
+	require.NoError(t, s.AdjustEscrowSaga(context.Background(), p.ID(), 15000, 15000, "evt-1"))
+	assert.Len(t, stripe.created, 1, "a repeated event is not applied again")
+	assert.Equal(t, int64(12000), p.AmountCents())
+}
+
+func TestAdjustEscrowSaga_KeepsOverriddenFee(t *testing.T) {
+	s, repo, _ := newAdjustSaga(t, adapter.IntentRequiresCapture)
+	p := repo.p
+	require.NoError(t, p.OverrideFee(500))
+
+	require.NoError(t, s.AdjustEscrowSaga(context.Background(), p.ID(), 12000, 13000, "evt-1"))
+	assert.Equal(t, int64(600), p.PlatformFeeCents(), "the 5% override survives, not the 15% default")
+	assert.Equal(t, int64(11400), p.RunnerPayoutCents())
+	assert.Equal(t, int64(13000), repo.adjustments["evt-1"].GrossAmountCents)
+}
+
+func TestAdjustEscrowSaga_AuthenticationRequiredKeepsAmount(t *testing.T) {
+	s, repo, stripe := newAdjustSaga(t, adapter.IntentRequiresAction)
+	p := repo.p
+
+	err := s.AdjustEscrowSaga(context.Background(), p.ID(), 12000, 12000, "evt-1")
+	assert.ErrorIs(t, err, ErrAdjustmentNeedsAuthentication)
+	assert.Equal(t, []string{"pi_new_1"}, stripe.cancelled, "only the new intent is cancelled")
+	assert.Equal(t, "pi_1", p.StripePaymentID())
+	assert.Equal(t, int64(10000), p.AmountCents())
+	assert.Empty(t, repo.adjustments)
+}
+
+func TestAdjustEscrowSaga_ConcurrentDeliveryKeepsWinnersIntent(t *testing.T) {
+	s, repo, stripe := newAdjustSaga(t, adapter.IntentRequiresCapture)
+	repo.adjustErr = payment.ErrAdjustmentApplied
+
+	require.NoError(t, s.AdjustEscrowSaga(context.Background(), repo.p.ID(), 12000, 12000, "evt-1"))
+	assert.Empty(t, stripe.cancelled, "the intent belongs to the delivery that applied the event")
+}
+
+func TestAdjustEscrowSaga_RetryAfterFailureDoesNotReuseCancelledIntent(t *testing.T) {
+	s, repo, stripe := newAdjustSaga(t, adapter.IntentRequiresCapture)
+	stored := *repo.p
+	repo.adjustErr = errors.New("connection reset")
+
+	err := s.AdjustEscrowSaga(context.Background(), repo.p.ID(), 12000, 12000, "evt-1")
+	require.Error(t, err)
+	assert.Equal(t, []string{"pi_new_1"}, stripe.cancelled, "the failed attempt cancels its intent")
+
+	// The redelivered event sees the payment as stored, at the same version.
+	repo.p, repo.adjustErr = &stored, nil
+	require.NoError(t, s.AdjustEscrowSaga(context.Background(), repo.p.ID(), 12000, 12000, "evt-1"))
+	assert.Equal(t, "pi_new_2", repo.p.StripePaymentID(), "a fresh intent replaces the cancelled one")
+	assert.Equal(t, []string{"pi_new_1", "pi_1"}, stripe.cancelled)
+	assert.Equal(t, "pi_new_2", repo.adjustments["evt-1"].PaymentIntentID)
+}
+
+func TestAdjustEscrowSaga_RejectsPaymentsNotHeld(t *testing.T) {
+	s, repo, stripe := newAdjustSaga(t, adapter.IntentRequiresCapture)
+	require.NoError(t, repo.p.ReleaseToRunner(uuid.New()))
+
+	assert.Error(t, s.AdjustEscrowSaga(context.Background(), repo.p.ID(), 12000, 12000, "evt-1"))
+	assert.Empty(t, stripe.created, "Stripe is not called for a payment that is not held")
+}
@@ -0,0 +1,71 @@
+package saga
+
+import (
+	"context"
+	"errors"
+
+	"github.com/Kilat-Pet-Delivery/lib-common/domain"
+	"github.com/Kilat-Pet-Delivery/service-payment/internal/domain/payment"
+	"go.uber.org/zap"
+)
+
+// maxConflictAttempts bounds how many times updateOnLatest persists a change.
+const maxConflictAttempts = 3
+
+// paymentSplit is the part of a payment Stripe has been told about: what the
+// card was authorized, captured or refunded for and what the runner was paid.
+type paymentSplit struct {
+	amountCents        int64
+	platformFeeCents   int64
+	runnerPayoutCents  int64
+	creditAppliedCents int64
+	stripePaymentID    string
+}
+
+func splitOf(p *payment.Payment) paymentSplit {
+	return paymentSplit{
+		amountCents:        p.AmountCents(),
+		platformFeeCents:   p.PlatformFeeCents(),
+		runnerPayoutCents:  p.RunnerPayoutCents(),
+		creditAppliedCents: p.CreditAppliedCents(),
+		stripePaymentID:    p.StripePaymentID(),
+	}
+}
+
+// updateOnLatest applies a saga's domain change to p and persists it. If
+// another update of the payment won the optimistic lock, e.g. a review flag
+// or a new customer email, p is replaced in place by the reloaded payment and
+// apply runs again with reloaded set, up to maxConflictAttempts in all. The
+// Stripe steps before it are not repeated, so a reloaded payment whose split
+// no longer matches the one they used is not retried. Other errors, and
+// the conflict of the last attempt, are returned as is.
+func (s *PaymentSagaService) updateOnLatest(ctx context.Context, p *payment.Payment, apply func(reloaded bool) error) error {
+	var want paymentSplit
+	var conflict error
+	for attempt := 1; ; attempt++ {
+		if err := apply(attempt > 1); err != nil {
+			return err
+		}
+		if attempt == 1 {
+			want = splitOf(p)
+		} else if splitOf(p) != want {
+			return conflict
+		}
+		p.IncrementVersion()
+		conflict = s.repo.Update(ctx, p)
+		if conflict == nil || !errors.Is(conflict, domain.ErrConflict) || attempt >= maxConflictAttempts || ctx.Err() != nil {
+			return conflict
+		}
+
+		latest, err := s.repo.FindByID(ctx, p.ID())
+		if err != nil {
+			return conflict
+		}
+		s.logger.Info("optimistic lock conflict, reloading and retrying",
+			zap.String("payment_id", p.ID().String()),
+			zap.Int("attempt", attempt),
+			zap.Error(conflict),
+		)
+		*p = *latest
+	}
+}
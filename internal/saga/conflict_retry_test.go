@@ -0,0 +1,108 @@
+package saga
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/Kilat-Pet-Delivery/lib-common/domain"
+	"github.com/Kilat-Pet-Delivery/service-payment/internal/domain/payment"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// racingRepo loads a fresh copy of one held payment of 10000 on every
+// FindByID, as the database would, and fails the first conflicts updates as
+// if another update of the payment had won. The payment's platform fee is
+// 1500, or reloadFeeCents after the first load if that is set.
+type racingRepo struct {
+	payment.PaymentRepository
+	id             uuid.UUID
+	reloadFeeCents int64
+	conflicts      int
+	loads          int
+	saved          *payment.Payment
+}
+
+func (r *racingRepo) FindByID(context.Context, uuid.UUID) (*payment.Payment, error) {
+	r.loads++
+	fee := int64(1500)
+	if r.loads > 1 && r.reloadFeeCents > 0 {
+		fee = r.reloadFeeCents
+	}
+	now := time.Now().UTC()
+	return payment.Reconstitute(r.id, uuid.New(), uuid.New(), nil, payment.EscrowHeld,
+		payment.NewMoney(10000, "MYR"), payment.NewMoney(fee, "MYR"), payment.NewMoney(10000-fee, "MYR"), 0,
+		"card", "pi_1", &now, nil, nil, nil, "", "", "", "", "", "", false, false, false, int64(r.loads), now, now), nil
+}
+
+func (r *racingRepo) Update(_ context.Context, p *payment.Payment) error {
+	if r.conflicts > 0 {
+		r.conflicts--
+		return domain.NewConflictError("payment was modified by another transaction")
+	}
+	r.saved = p
+	return nil
+}
+
+func (r *racingRepo) FlagForReview(context.Context, uuid.UUID, string) error { return nil }
+
+// cancellingStripe is a ledgerStripe that also records cancelled intents.
+type cancellingStripe struct {
+	ledgerStripe
+	cancelled []string
+}
+
+func (s *cancellingStripe) CancelPaymentIntent(_ context.Context, id string) error {
+	s.cancelled = append(s.cancelled, id)
+	return nil
+}
+
+func TestReleaseEscrowSaga_RetriesReleaseAfterConflict(t *testing.T) {
+	repo := &racingRepo{id: uuid.New(), conflicts: 1}
+	stripe := &ledgerStripe{authorizedCents: 10000}
+	s := newReleaseSaga(repo, stripe)
+	capture := int64(8000)
+
+	require.NoError(t, s.ReleaseEscrowSaga(context.Background(), repo.id, uuid.New(), &capture))
+
+	require.NotNil(t, repo.saved)
+	assert.Equal(t, 2, repo.loads, "the payment is reloaded before the retry")
+	assert.Equal(t, payment.EscrowReleased, repo.saved.EscrowStatus())
+	assert.Equal(t, int64(8000), repo.saved.AmountCents(), "the partial capture is applied to the reloaded payment")
+	assert.Equal(t, int64(6800), repo.saved.RunnerPayoutCents())
+	assert.Equal(t, "tr_1", repo.saved.RunnerTransferID())
+	assert.Equal(t, []int64{6800}, stripe.transferred, "Stripe is not called again")
+	assert.Empty(t, stripe.ops, "nothing is reversed or refunded")
+}
+
+func TestReleaseEscrowSaga_DoesNotRetryWhenSplitChanged(t *testing.T) {
+	// The update that won changed the fee, so the transfer no longer matches.
+	repo := &racingRepo{id: uuid.New(), reloadFeeCents: 1000, conflicts: 1}
+	stripe := &ledgerStripe{authorizedCents: 10000}
+	s := newReleaseSaga(repo, stripe)
+
+	err := s.ReleaseEscrowSaga(context.Background(), repo.id, uuid.New(), nil)
+	assert.ErrorIs(t, err, domain.ErrConflict)
+	assert.Nil(t, repo.saved)
+	assert.Equal(t, []string{"reverse tr_1", "refund 10000"}, stripe.ops, "the release is compensated as before")
+}
+
+func TestRefundEscrowSaga_RetriesRefundAfterConflict(t *testing.T) {
+	repo := &racingRepo{id: uuid.New(), conflicts: 2}
+	stripe := &cancellingStripe{}
+	s := newReleaseSaga(repo, stripe)
+
+	require.NoError(t, s.RefundEscrowSaga(context.Background(), repo.id, "runner no-show", payment.RefundToCard))
+
+	require.NotNil(t, repo.saved)
+	assert.Equal(t, payment.EscrowRefunded, repo.saved.EscrowStatus())
+	assert.Equal(t, "runner no-show", repo.saved.RefundReason())
+	assert.Equal(t, []string{"pi_1"}, stripe.cancelled, "the authorization is cancelled once")
+
+	repo.saved, repo.conflicts = nil, maxConflictAttempts
+	err := s.RefundEscrowSaga(context.Background(), repo.id, "runner no-show", payment.RefundToCard)
+	assert.ErrorIs(t, err, domain.ErrConflict, "the conflict of the last attempt is returned")
+	assert.Nil(t, repo.saved)
+}
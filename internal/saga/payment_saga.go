@@ -2,17 +2,28 @@ package saga
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"strings"
 	"time"
 
+	"github.com/Kilat-Pet-Delivery/lib-common/domain"
 	"github.com/Kilat-Pet-Delivery/lib-common/kafka"
 	"github.com/Kilat-Pet-Delivery/lib-proto/events"
 	"github.com/Kilat-Pet-Delivery/service-payment/internal/adapter"
 	"github.com/Kilat-Pet-Delivery/service-payment/internal/domain/payment"
+	"github.com/Kilat-Pet-Delivery/service-payment/internal/domain/runner"
+	"github.com/Kilat-Pet-Delivery/service-payment/internal/feature"
 	"github.com/google/uuid"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
 	"go.uber.org/zap"
 )
 
+var tracer = otel.Tracer("github.com/Kilat-Pet-Delivery/service-payment/internal/saga")
+
 // SagaStep represents a single step in a saga with execute and compensate actions.
 type SagaStep struct {
 	Name       string
@@ -20,11 +31,17 @@ type SagaStep struct {
 	Compensate func(ctx context.Context) error
 }
 
+// CompensationFailureHandler is called when a compensating step fails, which
+// can leave money in an inconsistent state. It should alert, e.g. publish an
+// event or page on-call.
+type CompensationFailureHandler func(ctx context.Context, sagaName, step string, err error)
+
 // Saga orchestrates a sequence of steps with compensating transactions on failure.
 type Saga struct {
-	name   string
-	steps  []SagaStep
-	logger *zap.Logger
+	name               string
+	steps              []SagaStep
+	compensationFailed CompensationFailureHandler
+	logger             *zap.Logger
 }
 
 // NewSaga creates a new saga orchestrator.
@@ -41,9 +58,17 @@ func (s *Saga) AddStep(step SagaStep) {
 	s.steps = append(s.steps, step)
 }
 
+// OnCompensationFailure sets the handler called for each compensating step
+// that fails. Without one, failures are only logged.
+func (s *Saga) OnCompensationFailure(h CompensationFailureHandler) {
+	s.compensationFailed = h
+}
+
 // Execute runs all saga steps in order. On failure, it compensates executed steps in reverse order.
+// Each step runs in its own span; compensation is recorded as events on the caller's span.
 func (s *Saga) Execute(ctx context.Context) error {
 	s.logger.Info("saga started", zap.String("saga", s.name))
+	parent := trace.SpanFromContext(ctx)
 
 	executedSteps := make([]SagaStep, 0, len(s.steps))
 
@@ -53,12 +78,16 @@ func (s *Saga) Execute(ctx context.Context) error {
 			zap.String("step", step.Name),
 		)
 
-		if err := step.Execute(ctx); err != nil {
-			s.logger.Error("saga step failed, starting compensation",
+		if err := s.executeStep(ctx, step); err != nil {
+			fields := []zap.Field{
 				zap.String("saga", s.name),
 				zap.String("step", step.Name),
 				zap.Error(err),
-			)
+			}
+			if requestID := adapter.ProviderRequestID(err); requestID != "" {
+				fields = append(fields, zap.String("stripe_request_id", requestID))
+			}
+			s.logger.Error("saga step failed, starting compensation", fields...)
 
 			// Compensate executed steps in reverse order
 			for i := len(executedSteps) - 1; i >= 0; i-- {
@@ -74,11 +103,25 @@ func (s *Saga) Execute(ctx context.Context) error {
 							zap.String("step", compensateStep.Name),
 							zap.Error(compErr),
 						)
+						parent.AddEvent("saga.compensation_failed", trace.WithAttributes(
+							attribute.String("saga.step", compensateStep.Name),
+							attribute.String("error", compErr.Error()),
+						))
+						if s.compensationFailed != nil {
+							s.compensationFailed(ctx, s.name, compensateStep.Name, compErr)
+						}
+					} else {
+						parent.AddEvent("saga.compensated", trace.WithAttributes(
+							attribute.String("saga.step", compensateStep.Name),
+						))
 					}
 				}
 			}
 
-			return fmt.Errorf("saga '%s' failed at step '%s': %w", s.name, step.Name, err)
+			sagaErr := fmt.Errorf("saga '%s' failed at step '%s': %w", s.name, step.Name, err)
+			parent.RecordError(sagaErr)
+			parent.SetStatus(codes.Error, "saga failed")
+			return sagaErr
 		}
 
 		executedSteps = append(executedSteps, step)
@@ -88,43 +131,251 @@ func (s *Saga) Execute(ctx context.Context) error {
 	return nil
 }
 
+// executeStep runs a single step inside its own span.
+func (s *Saga) executeStep(ctx context.Context, step SagaStep) error {
+	ctx, span := tracer.Start(ctx, "saga."+s.name+"."+step.Name, trace.WithAttributes(
+		attribute.String("saga.name", s.name),
+		attribute.String("saga.step", step.Name),
+	))
+	defer span.End()
+
+	if err := step.Execute(ctx); err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "step failed")
+		return err
+	}
+	span.AddEvent("saga.step_succeeded")
+	return nil
+}
+
+// FeeResolver resolves the platform fee percentage that applies to a payment.
+type FeeResolver interface {
+	// ResolveFeePercent returns the fee for the region/currency at the given time,
+	// or false if no schedule applies.
+	ResolveFeePercent(region, currency string, at time.Time) (float64, bool)
+}
+
+// CreditLedger grants and deducts users' in-app credit.
+type CreditLedger interface {
+	Grant(ctx context.Context, userID uuid.UUID, currency string, amountCents int64) error
+	Deduct(ctx context.Context, userID uuid.UUID, currency string, amountCents int64) error
+}
+
+// RunnerAccountLookup finds the Stripe Connect account a runner is paid out to.
+type RunnerAccountLookup interface {
+	// FindByRunnerID returns the runner's account, or nil if none is linked.
+	FindByRunnerID(ctx context.Context, runnerID uuid.UUID) (*runner.Account, error)
+}
+
+// CallbackScheduler queues HTTP callbacks to a payment's callback URL.
+type CallbackScheduler interface {
+	// Schedule queues a callback for eventType if p has a callback URL.
+	Schedule(ctx context.Context, p *payment.Payment, eventType string) error
+}
+
+// EventPublisher publishes CloudEvents to a Kafka topic.
+type EventPublisher interface {
+	PublishEvent(ctx context.Context, topic string, ce kafka.CloudEvent) error
+}
+
 // PaymentSagaService orchestrates payment saga workflows.
 type PaymentSagaService struct {
 	repo               payment.PaymentRepository
 	stripe             adapter.StripeAdapter
-	producer           *kafka.Producer
+	producer           EventPublisher
+	fees               FeeResolver
+	credits            CreditLedger
+	runnerAccounts     RunnerAccountLookup
+	callbacks          CallbackScheduler
+	flags              *feature.Flags
 	platformFeePercent float64
+	floors             payment.PayoutFloors
+	autoReleaseAfter   time.Duration
+	refundWindow       time.Duration
+	inflight           *inflightTracker
+	compensationFailed CompensationFailureHandler
+	publishRetry       publishRetryPolicy
 	logger             *zap.Logger
 }
 
 // NewPaymentSagaService creates a new PaymentSagaService.
+// producer may be nil, in which case no events are published.
+// credits may be nil, in which case payments cannot use or be refunded to credit.
+// runnerAccounts may be nil, in which case releases never transfer the runner payout.
+// callbacks may be nil, in which case no HTTP callbacks are sent.
+// flags gates rollout of newer steps; nil uses the built-in defaults.
+// platformFeePercent is the default used when fees is nil or has no applicable schedule.
+// floors are the minimum runner payout and platform fee enforced on every new payment.
+// autoReleaseAfter is the default escrow hold window before automatic release; zero disables it.
+// refundWindow is how long after release a payment may still be refunded.
 func NewPaymentSagaService(
 	repo payment.PaymentRepository,
 	stripe adapter.StripeAdapter,
-	producer *kafka.Producer,
+	producer EventPublisher,
+	fees FeeResolver,
+	credits CreditLedger,
+	runnerAccounts RunnerAccountLookup,
+	callbacks CallbackScheduler,
+	flags *feature.Flags,
 	platformFeePercent float64,
+	floors payment.PayoutFloors,
+	autoReleaseAfter time.Duration,
+	refundWindow time.Duration,
 	logger *zap.Logger,
 ) *PaymentSagaService {
 	return &PaymentSagaService{
 		repo:               repo,
 		stripe:             stripe,
 		producer:           producer,
+		fees:               fees,
+		credits:            credits,
+		runnerAccounts:     runnerAccounts,
+		callbacks:          callbacks,
+		flags:              flags,
 		platformFeePercent: platformFeePercent,
+		floors:             floors,
+		autoReleaseAfter:   autoReleaseAfter,
+		refundWindow:       refundWindow,
+		inflight:           newInflightTracker(),
+		publishRetry:       defaultPublishRetryPolicy(),
 		logger:             logger,
 	}
 }
 
+// SetCompensationFailureHandler replaces the default alert for failed
+// compensations, which publishes a SagaCompensationFailedEvent. The error passed
+// to h names the payment.
+func (s *PaymentSagaService) SetCompensationFailureHandler(h CompensationFailureHandler) {
+	s.compensationFailed = h
+}
+
+// newSaga creates a saga for p that reports failed compensations to the
+// configured handler, or publishes a SagaCompensationFailedEvent.
+func (s *PaymentSagaService) newSaga(name string, p *payment.Payment) *Saga {
+	saga := NewSaga(name, s.logger)
+	saga.OnCompensationFailure(func(ctx context.Context, sagaName, step string, err error) {
+		if s.compensationFailed != nil {
+			s.compensationFailed(ctx, sagaName, step, fmt.Errorf("payment %s: %w", p.ID(), err))
+			return
+		}
+		s.publishCompensationFailedEvent(ctx, p, sagaName, step, err)
+	})
+	return saga
+}
+
+// Drain waits up to timeout for in-flight sagas to complete, then cancels any
+// that remain. It is called during shutdown after new work has stopped arriving
+// and reports whether every saga finished in time.
+func (s *PaymentSagaService) Drain(timeout time.Duration) bool {
+	return s.inflight.drain(timeout, s.logger)
+}
+
+// CreateEscrowParams holds the inputs for CreateEscrowSaga.
+type CreateEscrowParams struct {
+	BookingID     uuid.UUID
+	OwnerID       uuid.UUID
+	RunnerID      *uuid.UUID
+	AmountCents   int64
+	Currency      string
+	Region        string
+	CustomerEmail string
+	// CallbackURL, if set, is notified over HTTP on release and refund.
+	CallbackURL string
+	// CreditCents is the part of AmountCents to pay from the owner's credit.
+	CreditCents int64
+	// AutoReleaseAfter overrides the default hold window when non-nil.
+	AutoReleaseAfter *time.Duration
+	// Test marks the payment as a test payment.
+	Test bool
+}
+
+// resolveFeePercent returns the scheduled fee for the region/currency, falling back to the configured default.
+func (s *PaymentSagaService) resolveFeePercent(region, currency string) float64 {
+	if s.fees != nil {
+		if pct, ok := s.fees.ResolveFeePercent(region, currency, time.Now().UTC()); ok {
+			return pct
+		}
+	}
+	return s.platformFeePercent
+}
+
+// QuoteSplit returns the platform fee and runner payout CreateEscrowSaga would
+// set for amountCents, without persisting anything.
+func (s *PaymentSagaService) QuoteSplit(bookingID, ownerID uuid.UUID, amountCents int64, currency, region string) (platformFeeCents, runnerPayoutCents int64, err error) {
+	p, err := payment.NewPayment(bookingID, ownerID, amountCents, currency, s.resolveFeePercent(region, currency), s.floors)
+	if err != nil {
+		return 0, 0, err
+	}
+	return p.PlatformFeeCents(), p.RunnerPayoutCents(), nil
+}
+
+// RecomputeFee splits p again at the fee currently configured for the region
+// and currency it was priced for and the payout floors, in memory only. A fee
+// an admin overrode is not recomputed; payment.ErrFeeOverridden is returned.
+// The amount authorized with Stripe does not change, so Stripe is not called;
+// the new payout is what a later release transfers.
+func (s *PaymentSagaService) RecomputeFee(p *payment.Payment) (bool, error) {
+	return p.RecomputeFee(s.resolveFeePercent(p.FeeRegion(), p.Currency()), s.floors)
+}
+
 // CreateEscrowSaga creates a payment, authorizes it with Stripe, holds the escrow, and publishes an event.
-func (s *PaymentSagaService) CreateEscrowSaga(
-	ctx context.Context,
-	bookingID, ownerID uuid.UUID,
-	amountCents int64,
-	currency, customerEmail string,
-) (*payment.Payment, error) {
-	p := payment.NewPayment(bookingID, ownerID, amountCents, currency, s.platformFeePercent)
-	var stripePaymentID string
+// If an earlier attempt for the booking was interrupted and left its payment
+// pending, that payment is resumed instead, reusing its PaymentIntent.
+// clientSecret is the PaymentIntent's client secret for the frontend's SCA
+// confirmation; it is empty when credit covers the whole amount. It is never stored.
+// A payment discounts made free is held without a PaymentIntent, as Stripe
+// rejects zero-amount intents.
+func (s *PaymentSagaService) CreateEscrowSaga(ctx context.Context, params CreateEscrowParams) (p *payment.Payment, clientSecret string, err error) {
+	ctx, span := startSagaSpan(ctx, "create_escrow", attribute.String("booking.id", params.BookingID.String()))
+	defer span.End()
+	ctx, done := s.inflight.start(ctx, "create_escrow", params.BookingID.String())
+	defer done()
 
-	saga := NewSaga("create_escrow", s.logger)
+	if resumable := s.findResumablePayment(ctx, params); resumable != nil {
+		return s.resumeEscrow(ctx, resumable, params)
+	}
+
+	currency := params.Currency
+	p, err = payment.NewPayment(params.BookingID, params.OwnerID, params.AmountCents, currency, s.resolveFeePercent(params.Region, currency), s.floors)
+	if err != nil {
+		return nil, "", err
+	}
+	if err := p.SetFeeRegion(params.Region); err != nil {
+		return nil, "", err
+	}
+	if params.RunnerID != nil {
+		if err := p.AssignRunner(*params.RunnerID); err != nil {
+			return nil, "", err
+		}
+	}
+	if params.CallbackURL != "" {
+		if err := p.SetCallbackURL(params.CallbackURL); err != nil {
+			return nil, "", err
+		}
+	}
+	if params.CustomerEmail != "" {
+		if err := p.SetCustomerEmail(params.CustomerEmail); err != nil {
+			return nil, "", err
+		}
+	}
+	if params.Test {
+		if err := p.MarkTest(); err != nil {
+			return nil, "", err
+		}
+	}
+	if params.CreditCents > 0 {
+		if s.credits == nil {
+			return nil, "", fmt.Errorf("credit is not available")
+		}
+		if err := p.ApplyCredit(params.CreditCents); err != nil {
+			return nil, "", err
+		}
+	}
+	autoReleaseAfter := s.autoReleaseAfter
+	if params.AutoReleaseAfter != nil {
+		autoReleaseAfter = *params.AutoReleaseAfter
+	}
+	saga := s.newSaga("create_escrow", p)
 
 	// Step 1: Save payment to database
 	saga.AddStep(SagaStep{
@@ -135,161 +386,684 @@ func (s *PaymentSagaService) CreateEscrowSaga(
 		Compensate: func(ctx context.Context) error {
 			// Mark payment as failed in DB as compensation
 			_ = p.Fail("saga compensation: escrow creation failed")
+			p.IncrementVersion()
 			return s.repo.Update(ctx, p)
 		},
 	})
 
-	// Step 2: Create Stripe PaymentIntent with manual capture
+	// Step 2: Spend the owner's credit, if any is applied
+	if p.CreditAppliedCents() > 0 {
+		saga.AddStep(SagaStep{
+			Name: "deduct_credit",
+			Execute: func(ctx context.Context) error {
+				return s.credits.Deduct(ctx, p.OwnerID(), p.Currency(), p.CreditAppliedCents())
+			},
+			Compensate: func(ctx context.Context) error {
+				return s.credits.Grant(ctx, p.OwnerID(), p.Currency(), p.CreditAppliedCents())
+			},
+		})
+	}
+
+	s.addHoldEscrowSteps(saga, p, params.CustomerEmail, autoReleaseAfter, &clientSecret)
+
+	if err := saga.Execute(ctx); err != nil {
+		// Publish a failure event
+		s.publishFailedEvent(ctx, p.ID(), p.BookingID(), err)
+		return nil, "", err
+	}
+
+	return p, clientSecret, nil
+}
+
+// findResumablePayment returns the pending payment an interrupted
+// CreateEscrowSaga left for the same booking, owner and amount, or nil.
+func (s *PaymentSagaService) findResumablePayment(ctx context.Context, params CreateEscrowParams) *payment.Payment {
+	p, err := s.repo.FindByBookingID(ctx, params.BookingID)
+	if err != nil {
+		return nil
+	}
+	if p.EscrowStatus() != payment.EscrowPending || p.OwnerID() != params.OwnerID ||
+		p.AmountCents() != params.AmountCents || !strings.EqualFold(p.Currency(), params.Currency) {
+		return nil
+	}
+	return p
+}
+
+// resumeEscrow finishes holding the escrow of a payment an interrupted
+// CreateEscrowSaga already saved. Its credit deduction is not repeated.
+// The client secret of an intent the interrupted attempt already stored is
+// looked up from Stripe.
+func (s *PaymentSagaService) resumeEscrow(ctx context.Context, p *payment.Payment, params CreateEscrowParams) (*payment.Payment, string, error) {
+	s.logger.Info("resuming interrupted escrow creation",
+		zap.String("payment_id", p.ID().String()),
+		zap.String("booking_id", p.BookingID().String()),
+		zap.String("stripe_payment_id", p.StripePaymentID()),
+	)
+
+	autoReleaseAfter := s.autoReleaseAfter
+	if params.AutoReleaseAfter != nil {
+		autoReleaseAfter = *params.AutoReleaseAfter
+	}
+	var clientSecret string
+	storedIntent := p.StripePaymentID()
+	saga := s.newSaga("create_escrow", p)
+	s.addHoldEscrowSteps(saga, p, params.CustomerEmail, autoReleaseAfter, &clientSecret)
+
+	if err := saga.Execute(ctx); err != nil {
+		s.publishFailedEvent(ctx, p.ID(), p.BookingID(), err)
+		return nil, "", err
+	}
+	if storedIntent != "" {
+		intent, err := s.stripe.GetPaymentIntent(ctx, storedIntent)
+		if err != nil {
+			s.logger.Warn("failed to load client secret of resumed payment",
+				zap.String("payment_id", p.ID().String()),
+				zap.String("stripe_payment_id", storedIntent),
+				zap.Error(err),
+			)
+			return p, "", nil
+		}
+		clientSecret = intent.ClientSecret
+	}
+	return p, clientSecret, nil
+}
+
+// RetryEscrowSaga re-runs escrow creation for a failed payment: the existing
+// record is reset to pending, a fresh Stripe intent is created, and the escrow is held.
+func (s *PaymentSagaService) RetryEscrowSaga(ctx context.Context, paymentID uuid.UUID, customerEmail string) (*payment.Payment, error) {
+	ctx, span := startSagaSpan(ctx, "retry_escrow", attribute.String("payment.id", paymentID.String()))
+	defer span.End()
+	ctx, done := s.inflight.start(ctx, "retry_escrow", paymentID.String())
+	defer done()
+
+	p, err := s.repo.FindByID(ctx, paymentID)
+	if err != nil {
+		return nil, err
+	}
+
+	saga := s.newSaga("retry_escrow", p)
+
+	// Step 1: Reset the failed payment to pending and persist
 	saga.AddStep(SagaStep{
-		Name: "create_stripe_payment_intent",
+		Name: "reset_payment",
 		Execute: func(ctx context.Context) error {
-			var err error
-			stripePaymentID, _, err = s.stripe.CreatePaymentIntent(ctx, amountCents, currency, customerEmail)
-			return err
+			return s.updateOnLatest(ctx, p, func(bool) error {
+				if err := p.ResetForRetry(); err != nil {
+					return err
+				}
+				if customerEmail != "" {
+					return p.SetCustomerEmail(customerEmail)
+				}
+				return nil
+			})
 		},
 		Compensate: func(ctx context.Context) error {
-			if stripePaymentID != "" {
-				return s.stripe.CancelPaymentIntent(ctx, stripePaymentID)
-			}
-			return nil
+			_ = p.Fail("saga compensation: escrow retry failed")
+			p.IncrementVersion()
+			return s.repo.Update(ctx, p)
 		},
 	})
 
-	// Step 3: Hold escrow in domain model and persist
+	s.addHoldEscrowSteps(saga, p, customerEmail, s.autoReleaseAfter, nil)
+
+	if err := saga.Execute(ctx); err != nil {
+		s.publishFailedEvent(ctx, p.ID(), p.BookingID(), err)
+		return nil, err
+	}
+
+	return p, nil
+}
+
+// CompleteAuthenticationSaga holds the escrow of a payment whose customer has
+// completed 3-D Secure, as reported by the Stripe webhook, and publishes an
+// event. paymentMethod is the webhook's description of how the customer paid
+// and is stored with the hold. A payment that is already held is left as is,
+// so redelivered webhooks are harmless. ErrAuthorizationMismatch is returned if
+// paymentIntentID is not the payment's intent. The default hold window applies.
+func (s *PaymentSagaService) CompleteAuthenticationSaga(ctx context.Context, paymentID uuid.UUID, paymentIntentID, paymentMethod string) (*payment.Payment, error) {
+	ctx, span := startSagaSpan(ctx, "complete_authentication", attribute.String("payment.id", paymentID.String()))
+	defer span.End()
+	ctx, done := s.inflight.start(ctx, "complete_authentication", paymentID.String())
+	defer done()
+
+	p, err := s.repo.FindByID(ctx, paymentID)
+	if err != nil {
+		return nil, err
+	}
+	if p.StripePaymentID() != paymentIntentID {
+		return nil, fmt.Errorf("%w: intent %s is not the payment's intent %s", ErrAuthorizationMismatch, paymentIntentID, p.StripePaymentID())
+	}
+	if p.EscrowStatus() == payment.EscrowHeld {
+		return p, nil
+	}
+	if !p.AwaitingAuthentication() {
+		return nil, domain.NewInvalidStateError(string(p.EscrowStatus()), string(payment.EscrowHeld))
+	}
+
+	saga := s.newSaga("complete_authentication", p)
+
+	// Step 1: Hold escrow in domain model and persist. There is nothing to
+	// compensate: Stripe redelivers the webhook if this fails.
 	saga.AddStep(SagaStep{
 		Name: "hold_escrow",
 		Execute: func(ctx context.Context) error {
-			if err := p.HoldEscrow(stripePaymentID); err != nil {
+			if err := p.HoldEscrow(p.StripePaymentID(), s.autoReleaseAfter); err != nil {
 				return err
 			}
+			p.SetPaymentMethod(paymentMethod)
 			p.IncrementVersion()
 			return s.repo.Update(ctx, p)
 		},
-		Compensate: func(ctx context.Context) error {
-			// Cancel the Stripe intent and mark as failed
-			_ = s.stripe.CancelPaymentIntent(ctx, stripePaymentID)
-			_ = p.Fail("saga compensation: hold escrow failed")
-			return s.repo.Update(ctx, p)
-		},
+		Compensate: nil,
 	})
 
-	// Step 4: Publish EscrowHeldEvent
+	// Step 2: Publish EscrowHeldEvent
+	saga.AddStep(s.escrowHeldEventStep(p))
+
+	if err := saga.Execute(ctx); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// startSagaSpan opens the parent span for one saga run.
+func startSagaSpan(ctx context.Context, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	return tracer.Start(ctx, "saga."+name, trace.WithAttributes(append(attrs, attribute.String("saga.name", name))...))
+}
+
+// paymentIntentIdempotencyKey is the Stripe idempotency key for authorizing p.
+// The first authorization uses the payment ID; a retry of a failed payment has
+// a later version and so gets a fresh intent instead of the cancelled one.
+func paymentIntentIdempotencyKey(p *payment.Payment) string {
+	if p.Version() <= 1 {
+		return p.ID().String()
+	}
+	return fmt.Sprintf("%s-v%d", p.ID(), p.Version())
+}
+
+// paymentIntentMetadata identifies the payment on its Stripe PaymentIntent.
+func paymentIntentMetadata(p *payment.Payment) map[string]string {
+	return map[string]string{
+		"booking_id": p.BookingID().String(),
+		"payment_id": p.ID().String(),
+		"owner_id":   p.OwnerID().String(),
+	}
+}
+
+// addHoldEscrowSteps appends the Stripe authorization, escrow hold, and
+// EscrowHeldEvent steps shared by escrow creation and retry. p must be pending.
+// A PaymentIntent already attached to p is reused rather than created again.
+// If clientSecret is non-nil it receives the secret of a newly created intent.
+func (s *PaymentSagaService) addHoldEscrowSteps(saga *Saga, p *payment.Payment, customerEmail string, autoReleaseAfter time.Duration, clientSecret *string) {
+	stripePaymentID := p.StripePaymentID()
+
+	// Create Stripe PaymentIntent with manual capture, unless credit or
+	// discounts cover the whole amount, and store it on the payment before
+	// holding the escrow
+	if p.CardAmountCents() > 0 && stripePaymentID == "" {
+		saga.AddStep(SagaStep{
+			Name: "create_stripe_payment_intent",
+			Execute: func(ctx context.Context) error {
+				intent, err := s.stripe.CreatePaymentIntent(ctx, p.CardAmountCents(), p.Currency(), customerEmail, paymentIntentMetadata(p), paymentIntentIdempotencyKey(p))
+				if err != nil {
+					return err
+				}
+				stripePaymentID = intent.ID
+				if clientSecret != nil {
+					*clientSecret = intent.ClientSecret
+				}
+				if intent.Status == adapter.IntentRequiresAction {
+					err = p.AwaitAuthentication(intent.ID)
+				} else {
+					err = p.AttachPaymentIntent(intent.ID)
+				}
+				if err != nil {
+					return err
+				}
+				p.IncrementVersion()
+				return s.repo.Update(ctx, p)
+			},
+			Compensate: func(ctx context.Context) error {
+				if stripePaymentID != "" && !s.heldElsewhere(ctx, p.ID()) {
+					return s.stripe.CancelPaymentIntent(ctx, stripePaymentID)
+				}
+				return nil
+			},
+		})
+	}
+
+	// Hold escrow in domain model and persist, unless the customer must first
+	// complete 3-D Secure; CompleteAuthenticationSaga holds it once they have
 	saga.AddStep(SagaStep{
-		Name: "publish_escrow_held_event",
+		Name: "hold_escrow",
 		Execute: func(ctx context.Context) error {
-			event := events.EscrowHeldEvent{
-				PaymentID:       p.ID(),
-				BookingID:       p.BookingID(),
-				StripePaymentID: p.StripePaymentID(),
-				AmountCents:     p.AmountCents(),
-				Currency:        p.Currency(),
-				OccurredAt:      time.Now().UTC(),
+			if p.AwaitingAuthentication() {
+				return nil
 			}
-			cloudEvent, err := kafka.NewCloudEvent("service-payment", events.PaymentEscrowHeld, event)
-			if err != nil {
-				return fmt.Errorf("failed to create cloud event: %w", err)
+			if err := p.HoldEscrow(stripePaymentID, autoReleaseAfter); err != nil {
+				return err
+			}
+			p.IncrementVersion()
+			return s.repo.Update(ctx, p)
+		},
+		Compensate: func(ctx context.Context) error {
+			// Cancel the Stripe intent and mark as failed, unless a concurrent
+			// run for the same payment won the hold with this intent
+			if s.heldElsewhere(ctx, p.ID()) {
+				return nil
 			}
-			return s.producer.PublishEvent(ctx, events.TopicPaymentEvents, cloudEvent)
+			if stripePaymentID != "" {
+				_ = s.stripe.CancelPaymentIntent(ctx, stripePaymentID)
+			}
+			_ = p.Fail("saga compensation: hold escrow failed")
+			return s.repo.Update(ctx, p)
 		},
-		Compensate: nil, // Event publishing has no compensating action
 	})
 
-	if err := saga.Execute(ctx); err != nil {
-		// Publish a failure event
-		s.publishFailedEvent(ctx, p.ID(), p.BookingID(), err.Error())
-		return nil, err
+	// Publish EscrowHeldEvent
+	saga.AddStep(s.escrowHeldEventStep(p))
+}
+
+// escrowHeldEventStep publishes an EscrowHeldEvent for p once its escrow is
+// held; it does nothing while p awaits authentication.
+func (s *PaymentSagaService) escrowHeldEventStep(p *payment.Payment) SagaStep {
+	step := s.publishStep("publish_escrow_held_event", p, func() (kafka.CloudEvent, error) {
+		event := events.EscrowHeldEvent{
+			PaymentID:       p.ID(),
+			BookingID:       p.BookingID(),
+			StripePaymentID: p.StripePaymentID(),
+			AmountCents:     p.AmountCents(),
+			Currency:        p.Currency(),
+			OccurredAt:      time.Now().UTC(),
+		}
+		return kafka.NewCloudEvent("service-payment", events.PaymentEscrowHeld, event)
+	})
+	publish := step.Execute
+	step.Execute = func(ctx context.Context) error {
+		if p.EscrowStatus() != payment.EscrowHeld {
+			return nil
+		}
+		return publish(ctx)
 	}
+	return step
+}
 
-	return p, nil
+// heldElsewhere reports whether the stored payment is already held, i.e. a
+// concurrent run resuming the same payment succeeded with the shared intent.
+func (s *PaymentSagaService) heldElsewhere(ctx context.Context, paymentID uuid.UUID) bool {
+	current, err := s.repo.FindByID(ctx, paymentID)
+	return err == nil && current.EscrowStatus() == payment.EscrowHeld
 }
 
 // ReleaseEscrowSaga captures the Stripe payment, releases funds to the runner, and publishes an event.
-func (s *PaymentSagaService) ReleaseEscrowSaga(ctx context.Context, paymentID, runnerID uuid.UUID) error {
+// A non-nil captureAmountCents captures only that much of the card authorization
+// and recomputes the fee split on the captured amount; nil captures it all.
+func (s *PaymentSagaService) ReleaseEscrowSaga(ctx context.Context, paymentID, runnerID uuid.UUID, captureAmountCents *int64) error {
+	ctx, span := startSagaSpan(ctx, "release_escrow", attribute.String("payment.id", paymentID.String()))
+	defer span.End()
+	ctx, done := s.inflight.start(ctx, "release_escrow", paymentID.String())
+	defer done()
+
 	p, err := s.repo.FindByID(ctx, paymentID)
 	if err != nil {
 		return err
 	}
 
-	saga := NewSaga("release_escrow", s.logger)
+	// Reject early so a release that lost a race never touches Stripe.
+	if p.EscrowStatus() != payment.EscrowHeld {
+		return domain.NewInvalidStateError(string(p.EscrowStatus()), string(payment.EscrowReleased))
+	}
 
-	// Step 1: Capture Stripe payment
-	saga.AddStep(SagaStep{
-		Name: "capture_stripe_payment",
-		Execute: func(ctx context.Context) error {
-			return s.stripe.CapturePaymentIntent(ctx, p.StripePaymentID())
-		},
-		Compensate: func(ctx context.Context) error {
-			// Attempt to create refund if capture succeeded
-			return s.stripe.CreateRefund(ctx, p.StripePaymentID(), p.AmountCents())
-		},
-	})
+	// The card authorization is fixed when escrow is held; read it before a
+	// partial capture reduces the payment.
+	authorizedCents := p.CardAmountCents()
+
+	// Applied in memory only; it is persisted with the release below.
+	var amountToCapture int64
+	if captureAmountCents != nil {
+		if err := p.CapturePartial(*captureAmountCents, s.floors); err != nil {
+			return err
+		}
+		amountToCapture = p.CardAmountCents()
+	}
+
+	account, err := s.findRunnerAccount(ctx, runnerID)
+	if err != nil {
+		return err
+	}
+
+	saga := s.newSaga("release_escrow", p)
+
+	// Compensation runs in reverse, so the transfer is reversed before the
+	// capture is refunded. If the reversal fails the runner keeps the payout,
+	// and refunding the owner as well would pay the booking out twice.
+	var transferID string
+	transferReversalFailed := false
+
+	// Step 1: Verify and capture the Stripe payment; payments made entirely
+	// with credit have none
+	if p.StripePaymentID() != "" {
+		saga.AddStep(SagaStep{
+			Name: "verify_stripe_authorization",
+			Execute: func(ctx context.Context) error {
+				return s.verifyAuthorization(ctx, p, authorizedCents)
+			},
+			Compensate: nil, // Read-only
+		})
+		saga.AddStep(SagaStep{
+			Name: "capture_stripe_payment",
+			Execute: func(ctx context.Context) error {
+				return s.stripe.CapturePaymentIntent(ctx, p.StripePaymentID(), amountToCapture)
+			},
+			Compensate: func(ctx context.Context) error {
+				// If a concurrent release won the version-checked update, the
+				// capture belongs to that release and must not be refunded.
+				if current, err := s.repo.FindByID(ctx, p.ID()); err == nil && current.EscrowStatus() == payment.EscrowReleased {
+					return nil
+				}
+				if transferReversalFailed {
+					return s.withholdRefund(ctx, p, transferID)
+				}
+				// Attempt to create refund if capture succeeded
+				return s.stripe.CreateRefund(ctx, p.StripePaymentID(), p.CardAmountCents())
+			},
+		})
+	}
+
+	// Step 2: Transfer the payout to the runner's connected account, if linked.
+	// Runners without one are paid out through cash-out requests instead. Free
+	// payments have no payout to transfer.
+	if account != nil && !p.IsFree() {
+		saga.AddStep(SagaStep{
+			Name: "transfer_runner_payout",
+			Execute: func(ctx context.Context) error {
+				id, err := s.stripe.CreateTransfer(ctx, account.StripeAccountID, p.RunnerPayoutCents(), p.Currency())
+				if err != nil {
+					return err
+				}
+				transferID = id
+				// Persisted with the release so a clawback can reverse it.
+				return p.RecordRunnerTransfer(id)
+			},
+			Compensate: func(ctx context.Context) error {
+				if err := s.stripe.ReverseTransfer(ctx, transferID); err != nil {
+					transferReversalFailed = true
+					return err
+				}
+				return nil
+			},
+		})
+	}
 
-	// Step 2: Release to runner in domain model and persist
+	// Step 3: Release to runner in domain model and persist. A reloaded
+	// payment gets the partial capture and transfer again first.
 	saga.AddStep(SagaStep{
 		Name: "release_to_runner",
 		Execute: func(ctx context.Context) error {
-			if err := p.ReleaseToRunner(runnerID); err != nil {
-				return err
-			}
-			p.IncrementVersion()
-			return s.repo.Update(ctx, p)
+			return s.updateOnLatest(ctx, p, func(reloaded bool) error {
+				if reloaded && captureAmountCents != nil {
+					if err := p.CapturePartial(*captureAmountCents, s.floors); err != nil {
+						return err
+					}
+				}
+				if reloaded && transferID != "" {
+					if err := p.RecordRunnerTransfer(transferID); err != nil {
+						return err
+					}
+				}
+				return p.ReleaseToRunner(runnerID)
+			})
 		},
 		Compensate: nil, // Cannot undo a domain state change once persisted at this point
 	})
 
-	// Step 3: Publish EscrowReleasedEvent
+	// Step 4: Publish EscrowReleasedEvent
+	saga.AddStep(s.publishStep("publish_escrow_released_event", p, func() (kafka.CloudEvent, error) {
+		event := events.EscrowReleasedEvent{
+			PaymentID:    p.ID(),
+			BookingID:    p.BookingID(),
+			RunnerID:     runnerID,
+			RunnerPayout: p.RunnerPayoutCents(),
+			PlatformFee:  p.PlatformFeeCents(),
+			Currency:     p.Currency(),
+			OccurredAt:   time.Now().UTC(),
+		}
+		return kafka.NewCloudEvent("service-payment", events.PaymentEscrowReleased, event)
+	}))
+
+	if err := saga.Execute(ctx); err != nil {
+		s.publishFailedEvent(ctx, p.ID(), p.BookingID(), err)
+		return err
+	}
+
+	s.scheduleCallback(ctx, p, events.PaymentEscrowReleased)
+	return nil
+}
+
+// ErrRefundWithheld is returned by a release compensation that kept the
+// captured funds because the runner's transfer could not be reversed.
+var ErrRefundWithheld = errors.New("refund withheld: runner transfer was not reversed")
+
+// withholdRefund flags p for manual review instead of refunding a capture
+// whose runner transfer is still paid out. The returned error is reported as
+// a failed compensation.
+func (s *PaymentSagaService) withholdRefund(ctx context.Context, p *payment.Payment, transferID string) error {
+	reason := fmt.Sprintf("release failed after capture and transfer %s could not be reversed; %d captured, not refunded",
+		transferID, p.CardAmountCents())
+	err := fmt.Errorf("%w: transfer %s", ErrRefundWithheld, transferID)
+	if flagErr := s.repo.FlagForReview(ctx, p.ID(), reason); flagErr != nil {
+		return errors.Join(err, fmt.Errorf("flag payment for review: %w", flagErr))
+	}
+	s.logger.Warn("payment flagged for manual review",
+		zap.String("payment_id", p.ID().String()),
+		zap.String("reason", reason),
+	)
+	return err
+}
+
+// scheduleCallback queues the payment's HTTP callback, if it has one. It runs
+// after the saga has completed, and a failure is only logged, so callbacks
+// never affect the outcome of a release or refund.
+func (s *PaymentSagaService) scheduleCallback(ctx context.Context, p *payment.Payment, eventType string) {
+	if s.callbacks == nil || p.CallbackURL() == "" {
+		return
+	}
+	if err := s.callbacks.Schedule(ctx, p, eventType); err != nil {
+		s.logger.Error("failed to schedule payment callback",
+			zap.String("payment_id", p.ID().String()),
+			zap.String("event_type", eventType),
+			zap.Error(err),
+		)
+	}
+}
+
+// findRunnerAccount returns the runner's linked payout account, or nil if none
+// is linked, account lookup is not configured, or Connect transfers are disabled.
+func (s *PaymentSagaService) findRunnerAccount(ctx context.Context, runnerID uuid.UUID) (*runner.Account, error) {
+	if s.runnerAccounts == nil || !s.flags.Enabled(feature.ConnectTransfers) {
+		return nil, nil
+	}
+	account, err := s.runnerAccounts.FindByRunnerID(ctx, runnerID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to look up runner account: %w", err)
+	}
+	if account == nil {
+		s.logger.Info("runner has no linked payout account, skipping transfer",
+			zap.String("runner_id", runnerID.String()))
+	}
+	return account, nil
+}
+
+// RefundEscrowSaga returns the payment to the owner by the given method: a
+// released payment's Connect transfer is reversed, Stripe settles the card
+// part, credit is granted where due, then the refund is recorded in the
+// domain and an event is published.
+func (s *PaymentSagaService) RefundEscrowSaga(ctx context.Context, paymentID uuid.UUID, reason string, method payment.RefundMethod) error {
+	ctx, span := startSagaSpan(ctx, "refund_escrow",
+		attribute.String("payment.id", paymentID.String()),
+		attribute.String("refund.method", string(method)))
+	defer span.End()
+	ctx, done := s.inflight.start(ctx, "refund_escrow", paymentID.String())
+	defer done()
+
+	p, err := s.repo.FindByID(ctx, paymentID)
+	if err != nil {
+		return err
+	}
+
+	if err := p.EnsureRefundable(s.refundWindow, time.Now().UTC()); err != nil {
+		return err
+	}
+
+	// Credit spent on the payment always goes back to credit; with the credit
+	// method the card-charged part does too.
+	creditCents := p.CreditAppliedCents()
+	if method == payment.RefundToCredit {
+		creditCents = p.AmountCents()
+	}
+	if creditCents > 0 && s.credits == nil {
+		return fmt.Errorf("credit is not available")
+	}
+
+	saga := s.newSaga("refund_escrow", p)
+
+	// Step 1: Take a released payment's payout back from the runner's
+	// connected account, so the platform does not pay the booking out twice.
+	if p.RunnerTransferID() != "" {
+		saga.AddStep(SagaStep{
+			Name: "reverse_runner_transfer",
+			Execute: func(ctx context.Context) error {
+				return s.stripe.ReverseTransfer(ctx, p.RunnerTransferID())
+			},
+			Compensate: nil, // Cannot undo a transfer reversal; reconciled manually
+		})
+	}
+
+	// Step 2: Settle the card part with Stripe
+	s.addRefundStripeStep(saga, p, method)
+
+	// Step 3: Grant credit
+	if creditCents > 0 {
+		saga.AddStep(SagaStep{
+			Name: "grant_credit",
+			Execute: func(ctx context.Context) error {
+				return s.credits.Grant(ctx, p.OwnerID(), p.Currency(), creditCents)
+			},
+			Compensate: func(ctx context.Context) error {
+				return s.credits.Deduct(ctx, p.OwnerID(), p.Currency(), creditCents)
+			},
+		})
+	}
+
+	// Step 4: Refund in domain model and persist
 	saga.AddStep(SagaStep{
-		Name: "publish_escrow_released_event",
+		Name: "refund_in_domain",
 		Execute: func(ctx context.Context) error {
-			event := events.EscrowReleasedEvent{
-				PaymentID:    p.ID(),
-				BookingID:    p.BookingID(),
-				RunnerID:     runnerID,
-				RunnerPayout: p.RunnerPayoutCents(),
-				PlatformFee:  p.PlatformFeeCents(),
-				Currency:     p.Currency(),
-				OccurredAt:   time.Now().UTC(),
-			}
-			cloudEvent, err := kafka.NewCloudEvent("service-payment", events.PaymentEscrowReleased, event)
-			if err != nil {
-				return fmt.Errorf("failed to create cloud event: %w", err)
-			}
-			return s.producer.PublishEvent(ctx, events.TopicPaymentEvents, cloudEvent)
+			return s.updateOnLatest(ctx, p, func(bool) error {
+				return p.Refund(reason)
+			})
 		},
 		Compensate: nil,
 	})
 
+	// Step 5: Publish EscrowRefundedEvent
+	saga.AddStep(s.publishStep("publish_escrow_refunded_event", p, func() (kafka.CloudEvent, error) {
+		event := events.EscrowRefundedEvent{
+			PaymentID:    p.ID(),
+			BookingID:    p.BookingID(),
+			OwnerID:      p.OwnerID(),
+			AmountCents:  p.AmountCents(),
+			Currency:     p.Currency(),
+			RefundReason: reason,
+			OccurredAt:   time.Now().UTC(),
+		}
+		return kafka.NewCloudEvent("service-payment", events.PaymentEscrowRefunded, event)
+	}))
+
 	if err := saga.Execute(ctx); err != nil {
-		s.publishFailedEvent(ctx, p.ID(), p.BookingID(), err.Error())
+		s.publishFailedEvent(ctx, p.ID(), p.BookingID(), err)
 		return err
 	}
 
+	s.scheduleCallback(ctx, p, events.PaymentEscrowRefunded)
 	return nil
 }
 
-// RefundEscrowSaga cancels the Stripe payment, refunds in the domain, and publishes an event.
-func (s *PaymentSagaService) RefundEscrowSaga(ctx context.Context, paymentID uuid.UUID, reason string) error {
+// ClawbackSaga reverses a released payment after a dispute is decided in the
+// owner's favour: the runner's Connect transfer is reversed, the card part is
+// refunded, credit spent on the payment is returned, and a
+// PaymentChargedBackEvent is published. A runner paid out through cash-out
+// rather than Connect has no transfer to reverse; the event reports that.
+func (s *PaymentSagaService) ClawbackSaga(ctx context.Context, paymentID uuid.UUID, reason string) error {
+	ctx, span := startSagaSpan(ctx, "clawback", attribute.String("payment.id", paymentID.String()))
+	defer span.End()
+	ctx, done := s.inflight.start(ctx, "clawback", paymentID.String())
+	defer done()
+
 	p, err := s.repo.FindByID(ctx, paymentID)
 	if err != nil {
 		return err
 	}
+	if !payment.CanTransition(p.EscrowStatus(), payment.EscrowChargedBack) || p.EscrowReleasedAt() == nil {
+		return domain.NewInvalidStateError(string(p.EscrowStatus()), string(payment.EscrowChargedBack))
+	}
 
-	saga := NewSaga("refund_escrow", s.logger)
+	creditCents := p.CreditAppliedCents()
+	if creditCents > 0 && s.credits == nil {
+		return fmt.Errorf("credit is not available")
+	}
+	cardCents := p.CardAmountCents()
+	if p.StripePaymentID() == "" {
+		cardCents = 0
+	}
+	var reversedPayoutCents int64
+	if p.RunnerTransferID() != "" {
+		reversedPayoutCents = p.RunnerPayoutCents()
+	}
 
-	// Step 1: Cancel Stripe PaymentIntent
-	saga.AddStep(SagaStep{
-		Name: "cancel_stripe_payment",
-		Execute: func(ctx context.Context) error {
-			return s.stripe.CancelPaymentIntent(ctx, p.StripePaymentID())
-		},
-		Compensate: nil, // Cannot undo a Stripe cancellation
-	})
+	saga := s.newSaga("clawback", p)
+
+	// Step 1: Take the payout back from the runner's connected account first,
+	// so the platform holds the funds it refunds below.
+	if p.RunnerTransferID() != "" {
+		saga.AddStep(SagaStep{
+			Name: "reverse_runner_transfer",
+			Execute: func(ctx context.Context) error {
+				return s.stripe.ReverseTransfer(ctx, p.RunnerTransferID())
+			},
+			Compensate: nil, // Cannot undo a transfer reversal; reconciled manually
+		})
+	}
+
+	// Step 2: Refund the card part to the owner
+	if cardCents > 0 {
+		saga.AddStep(SagaStep{
+			Name: "refund_stripe_payment",
+			Execute: func(ctx context.Context) error {
+				return s.stripe.CreateRefund(ctx, p.StripePaymentID(), cardCents)
+			},
+			Compensate: nil, // Cannot undo a Stripe refund
+		})
+	}
 
-	// Step 2: Refund in domain model and persist
+	// Step 3: Return credit spent on the payment
+	if creditCents > 0 {
+		saga.AddStep(SagaStep{
+			Name: "grant_credit",
+			Execute: func(ctx context.Context) error {
+				return s.credits.Grant(ctx, p.OwnerID(), p.Currency(), creditCents)
+			},
+			Compensate: func(ctx context.Context) error {
+				return s.credits.Deduct(ctx, p.OwnerID(), p.Currency(), creditCents)
+			},
+		})
+	}
+
+	// Step 4: Claw back in domain model and persist
 	saga.AddStep(SagaStep{
-		Name: "refund_in_domain",
+		Name: "clawback_in_domain",
 		Execute: func(ctx context.Context) error {
-			if err := p.Refund(reason); err != nil {
+			if err := p.Clawback(reason); err != nil {
 				return err
 			}
 			p.IncrementVersion()
@@ -298,43 +1072,196 @@ func (s *PaymentSagaService) RefundEscrowSaga(ctx context.Context, paymentID uui
 		Compensate: nil,
 	})
 
-	// Step 3: Publish EscrowRefundedEvent
-	saga.AddStep(SagaStep{
-		Name: "publish_escrow_refunded_event",
+	// Step 5: Publish PaymentChargedBackEvent
+	saga.AddStep(s.publishStep("publish_payment_charged_back_event", p, func() (kafka.CloudEvent, error) {
+		event := PaymentChargedBackEvent{
+			PaymentID:                 p.ID(),
+			BookingID:                 p.BookingID(),
+			OwnerID:                   p.OwnerID(),
+			RunnerID:                  p.RunnerID(),
+			RefundedCents:             cardCents,
+			CreditReturnedCents:       creditCents,
+			RunnerPayoutReversedCents: reversedPayoutCents,
+			Currency:                  p.Currency(),
+			Reason:                    reason,
+			OccurredAt:                time.Now().UTC(),
+		}
+		return kafka.NewCloudEvent("service-payment", PaymentChargedBack, event)
+	}))
+
+	if err := saga.Execute(ctx); err != nil {
+		s.publishFailedEvent(ctx, p.ID(), p.BookingID(), err)
+		return err
+	}
+
+	s.logger.Info("payment charged back",
+		zap.String("payment_id", p.ID().String()),
+		zap.Int64("refunded_cents", cardCents),
+		zap.Int64("credit_returned_cents", creditCents),
+		zap.Int64("runner_payout_reversed_cents", reversedPayoutCents),
+	)
+	s.scheduleCallback(ctx, p, PaymentChargedBack)
+	return nil
+}
+
+// addRefundStripeStep adds the Stripe step for a refund, if the payment has a
+// card part. Refunds to card cancel a held intent or refund a captured one;
+// refunds to credit capture a held intent so the funds back the granted credit.
+func (s *PaymentSagaService) addRefundStripeStep(saga *Saga, p *payment.Payment, method payment.RefundMethod) {
+	if p.StripePaymentID() == "" {
+		return
+	}
+	released := p.EscrowStatus() == payment.EscrowReleased
+
+	switch {
+	case method == payment.RefundToCredit && released:
+		// Already captured; nothing to do with Stripe.
+	case method == payment.RefundToCredit:
+		saga.AddStep(SagaStep{
+			Name: "capture_stripe_payment",
+			Execute: func(ctx context.Context) error {
+				return s.stripe.CapturePaymentIntent(ctx, p.StripePaymentID(), 0)
+			},
+			Compensate: func(ctx context.Context) error {
+				return s.stripe.CreateRefund(ctx, p.StripePaymentID(), p.CardAmountCents())
+			},
+		})
+	case released:
+		saga.AddStep(SagaStep{
+			Name: "refund_stripe_payment",
+			Execute: func(ctx context.Context) error {
+				return s.stripe.CreateRefund(ctx, p.StripePaymentID(), p.CardAmountCents())
+			},
+			Compensate: nil, // Cannot undo a Stripe refund
+		})
+	default:
+		saga.AddStep(SagaStep{
+			Name: "cancel_stripe_payment",
+			Execute: func(ctx context.Context) error {
+				return s.stripe.CancelPaymentIntent(ctx, p.StripePaymentID())
+			},
+			Compensate: nil, // Cannot undo a Stripe cancellation
+		})
+	}
+}
+
+// verifyAuthorization checks that the Stripe PaymentIntent authorizes exactly
+// authorizedCents in p's currency, so a diverged record is never captured. On
+// mismatch it publishes a PaymentAuthorizationMismatchEvent.
+func (s *PaymentSagaService) verifyAuthorization(ctx context.Context, p *payment.Payment, authorizedCents int64) error {
+	intent, err := s.stripe.GetPaymentIntent(ctx, p.StripePaymentID())
+	if err != nil {
+		return err
+	}
+	if intent.AmountCents == authorizedCents && strings.EqualFold(intent.Currency, p.Currency()) {
+		return nil
+	}
+
+	s.logger.Error("payment does not match Stripe authorization, refusing to capture",
+		zap.String("payment_id", p.ID().String()),
+		zap.String("payment_intent_id", p.StripePaymentID()),
+		zap.Int64("expected_cents", authorizedCents),
+		zap.String("expected_currency", p.Currency()),
+		zap.Int64("authorized_cents", intent.AmountCents),
+		zap.String("authorized_currency", intent.Currency),
+	)
+	s.publishAuthorizationMismatchEvent(ctx, p, authorizedCents, intent)
+	return fmt.Errorf("%w: payment %s expects %d %s, PaymentIntent %s authorizes %d %s",
+		ErrAuthorizationMismatch, p.ID(), authorizedCents, p.Currency(),
+		intent.ID, intent.AmountCents, strings.ToUpper(intent.Currency))
+}
+
+// paymentFailedEvent extends PaymentFailedEvent with the provider's reference
+// for the failed call. Consumers that do not know the field ignore it.
+type paymentFailedEvent struct {
+	events.PaymentFailedEvent
+	// ProviderReference is Stripe's request ID when a Stripe call failed.
+	ProviderReference string `json:"provider_reference,omitempty"`
+}
+
+// publishRetryPolicy bounds how long a publish step retries Kafka.
+type publishRetryPolicy struct {
+	attempts       int
+	initialBackoff time.Duration
+	maxBackoff     time.Duration
+}
+
+// defaultPublishRetryPolicy rides out a broker failover of a few seconds.
+func defaultPublishRetryPolicy() publishRetryPolicy {
+	return publishRetryPolicy{attempts: 5, initialBackoff: 200 * time.Millisecond, maxBackoff: 2 * time.Second}
+}
+
+// publishStep returns a saga step that publishes the event built by build.
+// The money operation before it has already succeeded, so a publish that
+// still fails after retries is logged for replay rather than failing the saga
+// and compensating a valid escrow. Steps run in order, so events for p are
+// still published in order.
+func (s *PaymentSagaService) publishStep(name string, p *payment.Payment, build func() (kafka.CloudEvent, error)) SagaStep {
+	return SagaStep{
+		Name: name,
 		Execute: func(ctx context.Context) error {
-			event := events.EscrowRefundedEvent{
-				PaymentID:    p.ID(),
-				BookingID:    p.BookingID(),
-				OwnerID:      p.OwnerID(),
-				AmountCents:  p.AmountCents(),
-				Currency:     p.Currency(),
-				RefundReason: reason,
-				OccurredAt:   time.Now().UTC(),
-			}
-			cloudEvent, err := kafka.NewCloudEvent("service-payment", events.PaymentEscrowRefunded, event)
+			cloudEvent, err := build()
 			if err != nil {
 				return fmt.Errorf("failed to create cloud event: %w", err)
 			}
-			return s.producer.PublishEvent(ctx, events.TopicPaymentEvents, cloudEvent)
+			if err := s.publishWithRetry(ctx, cloudEvent); err != nil {
+				// There is no outbox yet; the admin event replay endpoint
+				// rebuilds held, released and refunded events from the payment.
+				s.logger.Error("failed to publish event after retries, replay it with POST /api/v1/admin/payments/replay",
+					zap.String("event_type", cloudEvent.Type),
+					zap.String("payment_id", p.ID().String()),
+					zap.String("booking_id", p.BookingID().String()),
+					zap.Error(err),
+				)
+			}
+			return nil
 		},
-		Compensate: nil,
-	})
+		Compensate: nil, // Event publishing has no compensating action
+	}
+}
 
-	if err := saga.Execute(ctx); err != nil {
-		s.publishFailedEvent(ctx, p.ID(), p.BookingID(), err.Error())
-		return err
+// publishWithRetry publishes ce, backing off exponentially between attempts.
+func (s *PaymentSagaService) publishWithRetry(ctx context.Context, ce kafka.CloudEvent) error {
+	policy := s.publishRetry
+	backoff := policy.initialBackoff
+	var err error
+	for attempt := 1; ; attempt++ {
+		if err = s.publish(ctx, ce); err == nil || attempt >= policy.attempts {
+			return err
+		}
+		s.logger.Warn("event publish failed, retrying",
+			zap.String("event_type", ce.Type),
+			zap.Int("attempt", attempt),
+			zap.Duration("backoff", backoff),
+			zap.Error(err),
+		)
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(backoff):
+		}
+		backoff = min(backoff*2, policy.maxBackoff)
 	}
+}
 
-	return nil
+// publish sends ce to the payment events topic.
+func (s *PaymentSagaService) publish(ctx context.Context, ce kafka.CloudEvent) error {
+	if s.producer == nil {
+		return nil
+	}
+	return s.producer.PublishEvent(ctx, events.TopicPaymentEvents, ce)
 }
 
 // publishFailedEvent publishes a PaymentFailedEvent to Kafka.
-func (s *PaymentSagaService) publishFailedEvent(ctx context.Context, paymentID, bookingID uuid.UUID, reason string) {
-	event := events.PaymentFailedEvent{
-		PaymentID:  paymentID,
-		BookingID:  bookingID,
-		Reason:     reason,
-		OccurredAt: time.Now().UTC(),
+func (s *PaymentSagaService) publishFailedEvent(ctx context.Context, paymentID, bookingID uuid.UUID, sagaErr error) {
+	event := paymentFailedEvent{
+		PaymentFailedEvent: events.PaymentFailedEvent{
+			PaymentID:  paymentID,
+			BookingID:  bookingID,
+			Reason:     sagaErr.Error(),
+			OccurredAt: time.Now().UTC(),
+		},
+		ProviderReference: adapter.ProviderRequestID(sagaErr),
 	}
 
 	cloudEvent, err := kafka.NewCloudEvent("service-payment", events.PaymentFailed, event)
@@ -343,7 +1270,112 @@ func (s *PaymentSagaService) publishFailedEvent(ctx context.Context, paymentID,
 		return
 	}
 
-	if err := s.producer.PublishEvent(ctx, events.TopicPaymentEvents, cloudEvent); err != nil {
+	if err := s.publish(ctx, cloudEvent); err != nil {
 		s.logger.Error("failed to publish payment failed event", zap.Error(err))
 	}
 }
+
+// SagaCompensationFailed is the CloudEvent type published when a compensating
+// step fails.
+const SagaCompensationFailed = "payment.saga_compensation_failed"
+
+// SagaCompensationFailedEvent reports a compensating step that failed, leaving
+// the payment for manual reconciliation. It is defined here until the contract
+// is added to lib-proto.
+type SagaCompensationFailedEvent struct {
+	PaymentID  uuid.UUID `json:"payment_id"`
+	BookingID  uuid.UUID `json:"booking_id"`
+	Saga       string    `json:"saga"`
+	Step       string    `json:"step"`
+	Error      string    `json:"error"`
+	OccurredAt time.Time `json:"occurred_at"`
+}
+
+// publishCompensationFailedEvent publishes a SagaCompensationFailedEvent to Kafka.
+func (s *PaymentSagaService) publishCompensationFailedEvent(ctx context.Context, p *payment.Payment, sagaName, step string, compErr error) {
+	event := SagaCompensationFailedEvent{
+		PaymentID:  p.ID(),
+		BookingID:  p.BookingID(),
+		Saga:       sagaName,
+		Step:       step,
+		Error:      compErr.Error(),
+		OccurredAt: time.Now().UTC(),
+	}
+
+	cloudEvent, err := kafka.NewCloudEvent("service-payment", SagaCompensationFailed, event)
+	if err != nil {
+		s.logger.Error("failed to create saga compensation failed cloud event", zap.Error(err))
+		return
+	}
+
+	if err := s.publish(ctx, cloudEvent); err != nil {
+		s.logger.Error("failed to publish saga compensation failed event", zap.Error(err))
+	}
+}
+
+// PaymentChargedBack is the CloudEvent type published when a released payment
+// is clawed back.
+const PaymentChargedBack = "payment.charged_back"
+
+// PaymentChargedBackEvent reports a released payment reversed after a dispute.
+// RunnerPayoutReversedCents is zero when the runner was not paid through a
+// Connect transfer and their payout must be recovered another way. It is
+// defined here until the contract is added to lib-proto.
+type PaymentChargedBackEvent struct {
+	PaymentID                 uuid.UUID  `json:"payment_id"`
+	BookingID                 uuid.UUID  `json:"booking_id"`
+	OwnerID                   uuid.UUID  `json:"owner_id"`
+	RunnerID                  *uuid.UUID `json:"runner_id,omitempty"`
+	RefundedCents             int64      `json:"refunded_cents"`
+	CreditReturnedCents       int64      `json:"credit_returned_cents"`
+	RunnerPayoutReversedCents int64      `json:"runner_payout_reversed_cents"`
+	Currency                  string     `json:"currency"`
+	Reason                    string     `json:"reason"`
+	OccurredAt                time.Time  `json:"occurred_at"`
+}
+
+// ErrAuthorizationMismatch is returned when a payment's card amount or currency
+// differs from what Stripe authorized.
+var ErrAuthorizationMismatch = errors.New("payment does not match the Stripe authorization")
+
+// PaymentAuthorizationMismatch is the CloudEvent type published when a release
+// is refused because the payment and its Stripe authorization diverge.
+const PaymentAuthorizationMismatch = "payment.authorization_mismatch"
+
+// PaymentAuthorizationMismatchEvent reports a payment whose record and Stripe
+// authorization diverge. It is defined here until the contract is added to
+// lib-proto.
+type PaymentAuthorizationMismatchEvent struct {
+	PaymentID          uuid.UUID `json:"payment_id"`
+	BookingID          uuid.UUID `json:"booking_id"`
+	PaymentIntentID    string    `json:"payment_intent_id"`
+	ExpectedCents      int64     `json:"expected_cents"`
+	ExpectedCurrency   string    `json:"expected_currency"`
+	AuthorizedCents    int64     `json:"authorized_cents"`
+	AuthorizedCurrency string    `json:"authorized_currency"`
+	OccurredAt         time.Time `json:"occurred_at"`
+}
+
+// publishAuthorizationMismatchEvent publishes a PaymentAuthorizationMismatchEvent to Kafka.
+func (s *PaymentSagaService) publishAuthorizationMismatchEvent(ctx context.Context, p *payment.Payment, expectedCents int64, intent *adapter.PaymentIntent) {
+	event := PaymentAuthorizationMismatchEvent{
+		PaymentID:          p.ID(),
+		BookingID:          p.BookingID(),
+		PaymentIntentID:    intent.ID,
+		ExpectedCents:      expectedCents,
+		ExpectedCurrency:   p.Currency(),
+		AuthorizedCents:    intent.AmountCents,
+		AuthorizedCurrency: strings.ToUpper(intent.Currency),
+		OccurredAt:         time.Now().UTC(),
+	}
+
+	cloudEvent, err := kafka.NewCloudEvent("service-payment", PaymentAuthorizationMismatch, event)
+	if err != nil {
+		s.logger.Error("failed to create authorization mismatch cloud event", zap.Error(err))
+		return
+	}
+
+	if err := s.publish(ctx, cloudEvent); err != nil {
+		s.logger.Error("failed to publish authorization mismatch event", zap.Error(err))
+	}
+}
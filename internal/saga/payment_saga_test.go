@@ -0,0 +1,578 @@
+package saga
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/Kilat-Pet-Delivery/lib-common/domain"
+	"github.com/Kilat-Pet-Delivery/lib-common/kafka"
+	"github.com/Kilat-Pet-Delivery/lib-proto/events"
+	"github.com/Kilat-Pet-Delivery/service-payment/internal/adapter"
+	"github.com/Kilat-Pet-Delivery/service-payment/internal/domain/payment"
+	"github.com/Kilat-Pet-Delivery/service-payment/internal/domain/runner"
+	"github.com/Kilat-Pet-Delivery/service-payment/internal/feature"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func TestPaymentIntentMetadata(t *testing.T) {
+	p, err := payment.NewPayment(uuid.New(), uuid.New(), 10000, "MYR", 15.0, payment.PayoutFloors{})
+	require.NoError(t, err)
+
+	assert.Equal(t, map[string]string{
+		"booking_id": p.BookingID().String(),
+		"payment_id": p.ID().String(),
+		"owner_id":   p.OwnerID().String(),
+	}, paymentIntentMetadata(p))
+}
+
+// countingAccountLookup returns a linked account and counts lookups.
+type countingAccountLookup struct {
+	calls int
+}
+
+func (l *countingAccountLookup) FindByRunnerID(_ context.Context, runnerID uuid.UUID) (*runner.Account, error) {
+	l.calls++
+	return &runner.Account{RunnerID: runnerID, StripeAccountID: "acct_test"}, nil
+}
+
+func TestFindRunnerAccount_GatedByConnectTransfersFlag(t *testing.T) {
+	tests := []struct {
+		name      string
+		flags     *feature.Flags
+		wantFound bool
+	}{
+		{"off by default", nil, false},
+		{"disabled", feature.Static(map[feature.Flag]bool{feature.ConnectTransfers: false}), false},
+		{"enabled", feature.Static(map[feature.Flag]bool{feature.ConnectTransfers: true}), true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			lookup := &countingAccountLookup{}
+			s := NewPaymentSagaService(nil, nil, nil, nil, nil, lookup, nil, tt.flags, 15.0, payment.PayoutFloors{}, 0, 0, zap.NewNop())
+
+			account, err := s.findRunnerAccount(context.Background(), uuid.New())
+			require.NoError(t, err)
+
+			if tt.wantFound {
+				require.NotNil(t, account)
+				assert.Equal(t, 1, lookup.calls)
+			} else {
+				assert.Nil(t, account)
+				assert.Equal(t, 0, lookup.calls, "lookup must be skipped while the flag is off")
+			}
+		})
+	}
+}
+
+// failingCallbacks counts Schedule calls and always fails.
+type failingCallbacks struct {
+	calls int
+}
+
+func (f *failingCallbacks) Schedule(context.Context, *payment.Payment, string) error {
+	f.calls++
+	return errors.New("callback store unavailable")
+}
+
+func TestScheduleCallback_FailureDoesNotPropagate(t *testing.T) {
+	callbacks := &failingCallbacks{}
+	s := &PaymentSagaService{callbacks: callbacks, logger: zap.NewNop()}
+
+	p, err := payment.NewPayment(uuid.New(), uuid.New(), 10000, "MYR", 15.0, payment.PayoutFloors{})
+	require.NoError(t, err)
+
+	s.scheduleCallback(context.Background(), p, "payment.escrow_released")
+	assert.Zero(t, callbacks.calls, "payments without a callback URL are skipped")
+
+	require.NoError(t, p.SetCallbackURL("https://partner.example.com/hook"))
+	assert.NotPanics(t, func() { s.scheduleCallback(context.Background(), p, "payment.escrow_released") })
+	assert.Equal(t, 1, callbacks.calls)
+}
+
+// heldPaymentRepo returns one held payment.
+type heldPaymentRepo struct {
+	payment.PaymentRepository
+	p *payment.Payment
+}
+
+func (r *heldPaymentRepo) FindByID(context.Context, uuid.UUID) (*payment.Payment, error) {
+	return r.p, nil
+}
+
+func TestReleaseEscrowSaga_RejectsCaptureAboveAuthorization(t *testing.T) {
+	p, err := payment.NewPayment(uuid.New(), uuid.New(), 10000, "MYR", 15.0, payment.PayoutFloors{})
+	require.NoError(t, err)
+	require.NoError(t, p.HoldEscrow("pi_1", 0))
+
+	// stripe is nil: the saga must reject the capture before calling it.
+	s := &PaymentSagaService{repo: &heldPaymentRepo{p: p}, inflight: newInflightTracker(), logger: zap.NewNop()}
+	over := int64(10001)
+	err = s.ReleaseEscrowSaga(context.Background(), p.ID(), uuid.New(), &over)
+	assert.ErrorIs(t, err, payment.ErrInvalidCaptureAmount)
+	assert.Equal(t, payment.EscrowHeld, p.EscrowStatus())
+}
+
+// divergentStripe reports a fixed PaymentIntent and counts captures.
+type divergentStripe struct {
+	adapter.StripeAdapter
+	intent   adapter.PaymentIntent
+	captures int
+}
+
+func (s *divergentStripe) GetPaymentIntent(context.Context, string) (*adapter.PaymentIntent, error) {
+	intent := s.intent
+	return &intent, nil
+}
+
+func (s *divergentStripe) CapturePaymentIntent(context.Context, string, int64) error {
+	s.captures++
+	return nil
+}
+
+func TestReleaseEscrowSaga_RefusesCaptureWhenAuthorizationDiverges(t *testing.T) {
+	tests := []struct {
+		name   string
+		intent adapter.PaymentIntent
+	}{
+		{"amount", adapter.PaymentIntent{ID: "pi_1", AmountCents: 9000, Currency: "myr"}},
+		{"currency", adapter.PaymentIntent{ID: "pi_1", AmountCents: 10000, Currency: "sgd"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, err := payment.NewPayment(uuid.New(), uuid.New(), 10000, "MYR", 15.0, payment.PayoutFloors{})
+			require.NoError(t, err)
+			require.NoError(t, p.HoldEscrow("pi_1", 0))
+
+			core, logs := observer.New(zap.ErrorLevel)
+			stripe := &divergentStripe{intent: tt.intent}
+			s := &PaymentSagaService{
+				repo:     &heldPaymentRepo{p: p},
+				stripe:   stripe,
+				inflight: newInflightTracker(),
+				logger:   zap.New(core),
+			}
+
+			err = s.ReleaseEscrowSaga(context.Background(), p.ID(), uuid.New(), nil)
+			assert.ErrorIs(t, err, ErrAuthorizationMismatch)
+			assert.Zero(t, stripe.captures, "a diverged authorization must not be captured")
+			assert.Equal(t, payment.EscrowHeld, p.EscrowStatus())
+			assert.Len(t, logs.FilterMessage("payment does not match Stripe authorization, refusing to capture").All(), 1)
+		})
+	}
+}
+
+// bookingPaymentRepo stores payments by booking and rejects stale updates
+// like the GORM repository.
+type bookingPaymentRepo struct {
+	payment.PaymentRepository
+	byBooking map[uuid.UUID]*payment.Payment
+	versions  map[uuid.UUID]int64
+}
+
+func newBookingPaymentRepo() *bookingPaymentRepo {
+	return &bookingPaymentRepo{byBooking: map[uuid.UUID]*payment.Payment{}, versions: map[uuid.UUID]int64{}}
+}
+
+func (r *bookingPaymentRepo) FindByBookingID(_ context.Context, bookingID uuid.UUID) (*payment.Payment, error) {
+	if p, ok := r.byBooking[bookingID]; ok {
+		return p, nil
+	}
+	return nil, domain.NewNotFoundError("payment", bookingID.String())
+}
+
+func (r *bookingPaymentRepo) FindByID(_ context.Context, id uuid.UUID) (*payment.Payment, error) {
+	for _, p := range r.byBooking {
+		if p.ID() == id {
+			return p, nil
+		}
+	}
+	return nil, domain.NewNotFoundError("payment", id.String())
+}
+
+func (r *bookingPaymentRepo) Save(_ context.Context, p *payment.Payment) error {
+	if _, ok := r.byBooking[p.BookingID()]; ok {
+		return errors.New("duplicate key value violates unique constraint")
+	}
+	r.byBooking[p.BookingID()] = p
+	r.versions[p.ID()] = p.Version()
+	return nil
+}
+
+func (r *bookingPaymentRepo) Update(_ context.Context, p *payment.Payment) error {
+	if r.versions[p.ID()] != p.Version()-1 {
+		return domain.NewConflictError("payment was modified by another transaction")
+	}
+	r.versions[p.ID()] = p.Version()
+	return nil
+}
+
+// keyedStripe dedupes PaymentIntents by idempotency key like Stripe and
+// counts the intents it actually created.
+type keyedStripe struct {
+	adapter.StripeAdapter
+	byKey   map[string]string
+	created int
+}
+
+func (s *keyedStripe) CreatePaymentIntent(_ context.Context, _ int64, _, _ string, _ map[string]string, key string) (*adapter.PaymentIntent, error) {
+	id, ok := s.byKey[key]
+	if !ok {
+		s.created++
+		id = fmt.Sprintf("pi_%d", s.created)
+		s.byKey[key] = id
+	}
+	return &adapter.PaymentIntent{ID: id, Status: adapter.IntentRequiresCapture, ClientSecret: id + "_secret"}, nil
+}
+
+func (s *keyedStripe) GetPaymentIntent(_ context.Context, id string) (*adapter.PaymentIntent, error) {
+	return &adapter.PaymentIntent{ID: id, ClientSecret: id + "_secret"}, nil
+}
+
+func TestCreateEscrowSaga_RetryAfterInterruptionCreatesOneIntent(t *testing.T) {
+	tests := []struct {
+		name string
+		// intentStored reports whether the interrupted attempt persisted the
+		// intent ID before stopping.
+		intentStored bool
+	}{
+		{"interrupted after Stripe call", false},
+		{"interrupted after storing intent", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			repo := newBookingPaymentRepo()
+			stripe := &keyedStripe{byKey: map[string]string{}}
+			s := &PaymentSagaService{
+				repo:               repo,
+				stripe:             stripe,
+				platformFeePercent: 15.0,
+				inflight:           newInflightTracker(),
+				logger:             zap.NewNop(),
+			}
+			params := CreateEscrowParams{BookingID: uuid.New(), OwnerID: uuid.New(), AmountCents: 10000, Currency: "MYR"}
+
+			// The interrupted attempt saved the payment and authorized the card.
+			interrupted, err := payment.NewPayment(params.BookingID, params.OwnerID, params.AmountCents, params.Currency, 15.0, payment.PayoutFloors{})
+			require.NoError(t, err)
+			require.NoError(t, repo.Save(ctx, interrupted))
+			intent, err := stripe.CreatePaymentIntent(ctx, interrupted.CardAmountCents(), "MYR", "", nil, paymentIntentIdempotencyKey(interrupted))
+			require.NoError(t, err)
+			intentID := intent.ID
+			if tt.intentStored {
+				require.NoError(t, interrupted.AttachPaymentIntent(intentID))
+				interrupted.IncrementVersion()
+				require.NoError(t, repo.Update(ctx, interrupted))
+			}
+
+			p, clientSecret, err := s.CreateEscrowSaga(ctx, params)
+			require.NoError(t, err)
+
+			assert.Equal(t, 1, stripe.created, "the retry must reuse the first PaymentIntent")
+			assert.Equal(t, interrupted.ID(), p.ID(), "the retry must resume the saved payment")
+			assert.Equal(t, payment.EscrowHeld, p.EscrowStatus())
+			assert.Equal(t, intentID, p.StripePaymentID())
+			assert.Equal(t, intentID+"_secret", clientSecret, "the frontend still gets the intent's client secret")
+		})
+	}
+}
+
+// failingPublisher rejects every event, like a producer during a Kafka outage.
+type failingPublisher struct {
+	attempts []string
+}
+
+func (f *failingPublisher) PublishEvent(_ context.Context, _ string, ce kafka.CloudEvent) error {
+	f.attempts = append(f.attempts, ce.Type)
+	return errors.New("kafka: leader not available")
+}
+
+func TestCreateEscrowSaga_PublishFailureKeepsEscrowHeld(t *testing.T) {
+	repo := newBookingPaymentRepo()
+	publisher := &failingPublisher{}
+	// keyedStripe has no CancelPaymentIntent, so a compensation would panic.
+	s := &PaymentSagaService{
+		repo:               repo,
+		stripe:             &keyedStripe{byKey: map[string]string{}},
+		producer:           publisher,
+		platformFeePercent: 15.0,
+		inflight:           newInflightTracker(),
+		publishRetry:       publishRetryPolicy{attempts: 3, initialBackoff: time.Millisecond, maxBackoff: time.Millisecond},
+		logger:             zap.NewNop(),
+	}
+
+	p, _, err := s.CreateEscrowSaga(context.Background(), CreateEscrowParams{
+		BookingID: uuid.New(), OwnerID: uuid.New(), AmountCents: 10000, Currency: "MYR",
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, payment.EscrowHeld, p.EscrowStatus())
+	stored, err := repo.FindByID(context.Background(), p.ID())
+	require.NoError(t, err)
+	assert.Equal(t, payment.EscrowHeld, stored.EscrowStatus())
+	assert.Equal(t, []string{events.PaymentEscrowHeld, events.PaymentEscrowHeld, events.PaymentEscrowHeld}, publisher.attempts,
+		"the publish is retried up to the policy's attempts")
+}
+
+// ledgerStripe authorizes a fixed amount and records captures, transfers,
+// transfer reversals and refunds. Reversals fail with reverseErr if it is set.
+type ledgerStripe struct {
+	adapter.StripeAdapter
+	authorizedCents int64
+	reverseErr      error
+	transferred     []int64
+	reversed        []string
+	refunded        []int64
+	ops             []string
+}
+
+func (s *ledgerStripe) GetPaymentIntent(_ context.Context, id string) (*adapter.PaymentIntent, error) {
+	return &adapter.PaymentIntent{ID: id, AmountCents: s.authorizedCents, Currency: "myr"}, nil
+}
+
+func (s *ledgerStripe) CapturePaymentIntent(context.Context, string, int64) error { return nil }
+
+func (s *ledgerStripe) CreateTransfer(_ context.Context, _ string, amountCents int64, _ string) (string, error) {
+	s.transferred = append(s.transferred, amountCents)
+	return fmt.Sprintf("tr_%d", len(s.transferred)), nil
+}
+
+func (s *ledgerStripe) ReverseTransfer(_ context.Context, transferID string) error {
+	if s.reverseErr != nil {
+		return s.reverseErr
+	}
+	s.reversed = append(s.reversed, transferID)
+	s.ops = append(s.ops, "reverse "+transferID)
+	return nil
+}
+
+func (s *ledgerStripe) CreateRefund(_ context.Context, _ string, amountCents int64) error {
+	s.refunded = append(s.refunded, amountCents)
+	s.ops = append(s.ops, fmt.Sprintf("refund %d", amountCents))
+	return nil
+}
+
+// recordingCredits records credit grants.
+type recordingCredits struct {
+	granted []int64
+}
+
+func (c *recordingCredits) Grant(_ context.Context, _ uuid.UUID, _ string, amountCents int64) error {
+	c.granted = append(c.granted, amountCents)
+	return nil
+}
+
+func (c *recordingCredits) Deduct(context.Context, uuid.UUID, string, int64) error { return nil }
+
+// recordingPublisher records published events.
+type recordingPublisher struct {
+	published []kafka.CloudEvent
+}
+
+func (p *recordingPublisher) PublishEvent(_ context.Context, _ string, ce kafka.CloudEvent) error {
+	p.published = append(p.published, ce)
+	return nil
+}
+
+func TestClawbackSaga_ReversesReleasedAmounts(t *testing.T) {
+	ctx := context.Background()
+	repo := newBookingPaymentRepo()
+	stripe := &ledgerStripe{authorizedCents: 8000}
+	credits := &recordingCredits{}
+	publisher := &recordingPublisher{}
+	s := NewPaymentSagaService(repo, stripe, publisher, nil, credits, &countingAccountLookup{}, nil,
+		feature.Static(map[feature.Flag]bool{feature.ConnectTransfers: true}), 15.0, payment.PayoutFloors{}, 0, 0, zap.NewNop())
+
+	// 100.00 MYR, 20.00 of it paid with credit: fee 15.00, payout 85.00.
+	p, err := payment.NewPayment(uuid.New(), uuid.New(), 10000, "MYR", 15.0, payment.PayoutFloors{})
+	require.NoError(t, err)
+	require.NoError(t, p.ApplyCredit(2000))
+	require.NoError(t, p.HoldEscrow("pi_1", 0))
+	require.NoError(t, repo.Save(ctx, p))
+
+	runnerID := uuid.New()
+	require.NoError(t, s.ReleaseEscrowSaga(ctx, p.ID(), runnerID, nil))
+	assert.Equal(t, "tr_1", p.RunnerTransferID(), "the release records its transfer")
+	require.NoError(t, p.OpenDispute("item not delivered"))
+	p.IncrementVersion()
+	require.NoError(t, repo.Update(ctx, p))
+
+	require.NoError(t, s.ClawbackSaga(ctx, p.ID(), "dispute decided for owner"))
+
+	assert.Equal(t, payment.EscrowChargedBack, p.EscrowStatus())
+	assert.Equal(t, []int64{8500}, stripe.transferred)
+	assert.Equal(t, []string{"tr_1"}, stripe.reversed)
+	assert.Equal(t, []int64{8000}, stripe.refunded, "only the card part is refunded to the card")
+	assert.Equal(t, []int64{2000}, credits.granted, "credit spent on the payment is returned as credit")
+
+	last := publisher.published[len(publisher.published)-1]
+	require.Equal(t, PaymentChargedBack, last.Type)
+	var event PaymentChargedBackEvent
+	require.NoError(t, last.ParseData(&event))
+	assert.Equal(t, int64(8000), event.RefundedCents)
+	assert.Equal(t, int64(2000), event.CreditReturnedCents)
+	assert.Equal(t, int64(8500), event.RunnerPayoutReversedCents)
+	assert.Equal(t, &runnerID, event.RunnerID)
+	assert.Equal(t, "dispute decided for owner", event.Reason)
+}
+
+func TestRefundEscrowSaga_ReversesRunnerTransferOfReleasedPayment(t *testing.T) {
+	ctx := context.Background()
+	repo := newBookingPaymentRepo()
+	stripe := &ledgerStripe{authorizedCents: 10000}
+	s := NewPaymentSagaService(repo, stripe, &recordingPublisher{}, nil, nil, &countingAccountLookup{}, nil,
+		feature.Static(map[feature.Flag]bool{feature.ConnectTransfers: true}), 15.0, payment.PayoutFloors{}, 0, time.Hour, zap.NewNop())
+
+	p, err := payment.NewPayment(uuid.New(), uuid.New(), 10000, "MYR", 15.0, payment.PayoutFloors{})
+	require.NoError(t, err)
+	require.NoError(t, p.HoldEscrow("pi_1", 0))
+	require.NoError(t, repo.Save(ctx, p))
+	require.NoError(t, s.ReleaseEscrowSaga(ctx, p.ID(), uuid.New(), nil))
+	require.Equal(t, "tr_1", p.RunnerTransferID())
+
+	require.NoError(t, s.RefundEscrowSaga(ctx, p.ID(), "item damaged", payment.RefundToCard))
+
+	assert.Equal(t, payment.EscrowRefunded, p.EscrowStatus())
+	assert.Equal(t, []string{"tr_1"}, stripe.reversed, "the runner's payout is taken back")
+	assert.Equal(t, []string{"reverse tr_1", "refund 10000"}, stripe.ops, "the transfer is reversed before the owner is refunded")
+}
+
+func TestRefundEscrowSaga_RefundsPaymentDisputedBeforeRelease(t *testing.T) {
+	ctx := context.Background()
+	repo := newBookingPaymentRepo()
+	stripe := &cancellingStripe{}
+	s := NewPaymentSagaService(repo, stripe, &recordingPublisher{}, nil, nil, nil, nil, nil,
+		15.0, payment.PayoutFloors{}, 0, 0, zap.NewNop())
+
+	p, err := payment.NewPayment(uuid.New(), uuid.New(), 10000, "MYR", 15.0, payment.PayoutFloors{})
+	require.NoError(t, err)
+	require.NoError(t, p.HoldEscrow("pi_1", 0))
+	require.NoError(t, p.OpenDispute("item not delivered"))
+	require.NoError(t, repo.Save(ctx, p))
+
+	require.NoError(t, s.RefundEscrowSaga(ctx, p.ID(), "dispute decided for owner", payment.RefundToCard))
+
+	assert.Equal(t, payment.EscrowRefunded, p.EscrowStatus())
+	assert.Equal(t, []string{"pi_1"}, stripe.cancelled, "the uncaptured authorization is cancelled")
+	assert.Empty(t, stripe.ops, "nothing was paid out or captured")
+}
+
+func TestReleaseEscrowSaga_FreePaymentTransfersNothing(t *testing.T) {
+	ctx := context.Background()
+	repo := newBookingPaymentRepo()
+	stripe := &ledgerStripe{}
+	publisher := &recordingPublisher{}
+	s := NewPaymentSagaService(repo, stripe, publisher, nil, nil, &countingAccountLookup{}, nil,
+		feature.Static(map[feature.Flag]bool{feature.ConnectTransfers: true}), 15.0, payment.PayoutFloors{}, 0, 0, zap.NewNop())
+
+	p, err := payment.NewPayment(uuid.New(), uuid.New(), 0, "MYR", 15.0, payment.PayoutFloors{})
+	require.NoError(t, err)
+	require.NoError(t, p.HoldEscrow("", 0))
+	require.NoError(t, repo.Save(ctx, p))
+
+	require.NoError(t, s.ReleaseEscrowSaga(ctx, p.ID(), uuid.New(), nil))
+
+	assert.Equal(t, payment.EscrowReleased, p.EscrowStatus())
+	assert.Empty(t, stripe.transferred, "a zero payout is not transferred")
+	assert.Empty(t, p.RunnerTransferID())
+	last := publisher.published[len(publisher.published)-1]
+	assert.Equal(t, events.PaymentEscrowReleased, last.Type)
+}
+
+func TestClawbackSaga_RejectsIneligiblePayments(t *testing.T) {
+	held, err := payment.NewPayment(uuid.New(), uuid.New(), 10000, "MYR", 15.0, payment.PayoutFloors{})
+	require.NoError(t, err)
+	require.NoError(t, held.HoldEscrow("pi_1", 0))
+
+	disputedBeforeRelease, err := payment.NewPayment(uuid.New(), uuid.New(), 10000, "MYR", 15.0, payment.PayoutFloors{})
+	require.NoError(t, err)
+	require.NoError(t, disputedBeforeRelease.HoldEscrow("pi_2", 0))
+	require.NoError(t, disputedBeforeRelease.OpenDispute("damaged"))
+
+	for name, p := range map[string]*payment.Payment{"held": held, "disputed before release": disputedBeforeRelease} {
+		t.Run(name, func(t *testing.T) {
+			// stripe is nil: the saga must refuse before calling it.
+			s := &PaymentSagaService{repo: &heldPaymentRepo{p: p}, inflight: newInflightTracker(), logger: zap.NewNop()}
+			from := p.EscrowStatus()
+			err := s.ClawbackSaga(context.Background(), p.ID(), "chargeback")
+			assert.ErrorIs(t, err, domain.ErrInvalidState)
+			assert.Equal(t, from, p.EscrowStatus())
+		})
+	}
+}
+
+// unwritableRepo loads a fresh copy of one held payment, as the database
+// would, rejects every update and records payments flagged for review.
+type unwritableRepo struct {
+	payment.PaymentRepository
+	id, bookingID, ownerID uuid.UUID
+	heldAt                 time.Time
+	flagged                map[uuid.UUID]string
+}
+
+func newUnwritableRepo() *unwritableRepo {
+	return &unwritableRepo{
+		id: uuid.New(), bookingID: uuid.New(), ownerID: uuid.New(),
+		heldAt: time.Now().UTC(), flagged: map[uuid.UUID]string{},
+	}
+}
+
+func (r *unwritableRepo) FindByID(context.Context, uuid.UUID) (*payment.Payment, error) {
+	return payment.Reconstitute(r.id, r.bookingID, r.ownerID, nil, payment.EscrowHeld,
+		payment.NewMoney(10000, "MYR"), payment.NewMoney(1500, "MYR"), payment.NewMoney(8500, "MYR"), 0,
+		"card", "pi_1", &r.heldAt, nil, nil, nil, "", "", "", "", "", "", false, false, false, 1, r.heldAt, r.heldAt), nil
+}
+
+func (r *unwritableRepo) Update(context.Context, *payment.Payment) error {
+	return errors.New("connection reset by peer")
+}
+
+func (r *unwritableRepo) FlagForReview(_ context.Context, id uuid.UUID, reason string) error {
+	r.flagged[id] = reason
+	return nil
+}
+
+func newReleaseSaga(repo payment.PaymentRepository, stripe adapter.StripeAdapter) *PaymentSagaService {
+	return NewPaymentSagaService(repo, stripe, nil, nil, nil, &countingAccountLookup{}, nil,
+		feature.Static(map[feature.Flag]bool{feature.ConnectTransfers: true}), 15.0, payment.PayoutFloors{}, 0, 0, zap.NewNop())
+}
+
+func TestReleaseEscrowSaga_UpdateFailureReversesTransferBeforeRefund(t *testing.T) {
+	repo := newUnwritableRepo()
+	stripe := &ledgerStripe{authorizedCents: 10000}
+	s := newReleaseSaga(repo, stripe)
+
+	err := s.ReleaseEscrowSaga(context.Background(), repo.id, uuid.New(), nil)
+
+	require.Error(t, err)
+	assert.Equal(t, []int64{8500}, stripe.transferred)
+	assert.Equal(t, []string{"reverse tr_1", "refund 10000"}, stripe.ops)
+	assert.Empty(t, repo.flagged)
+}
+
+func TestReleaseEscrowSaga_FailedReversalWithholdsRefund(t *testing.T) {
+	repo := newUnwritableRepo()
+	stripe := &ledgerStripe{authorizedCents: 10000, reverseErr: errors.New("transfer already paid out")}
+	s := newReleaseSaga(repo, stripe)
+	failed := map[string]error{}
+	s.SetCompensationFailureHandler(func(_ context.Context, _, step string, err error) {
+		failed[step] = err
+	})
+
+	err := s.ReleaseEscrowSaga(context.Background(), repo.id, uuid.New(), nil)
+
+	require.Error(t, err)
+	assert.Equal(t, []int64{8500}, stripe.transferred)
+	assert.Empty(t, stripe.refunded, "the owner is not refunded while the runner keeps the payout")
+	assert.Contains(t, repo.flagged[repo.id], "tr_1")
+	assert.Contains(t, failed, "transfer_runner_payout")
+	assert.ErrorIs(t, failed["capture_stripe_payment"], ErrRefundWithheld)
+}
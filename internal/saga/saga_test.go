@@ -0,0 +1,124 @@
+package saga
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/Kilat-Pet-Delivery/service-payment/internal/adapter"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+// TestSagaExecute_StepSpansNestUnderSagaSpan verifies that step spans are
+// children of the saga span, which is itself a child of the inbound request
+// span, and that compensation is recorded on the saga span.
+func TestSagaExecute_StepSpansNestUnderSagaSpan(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	previous := otel.GetTracerProvider()
+	otel.SetTracerProvider(provider)
+	t.Cleanup(func() { otel.SetTracerProvider(previous) })
+
+	ctx, request := provider.Tracer("test").Start(context.Background(), "POST /api/v1/payments/initiate")
+	ctx, sagaSpan := startSagaSpan(ctx, "create_escrow")
+
+	compensated := false
+	s := NewSaga("create_escrow", zap.NewNop())
+	s.AddStep(SagaStep{
+		Name:       "save_payment",
+		Execute:    func(context.Context) error { return nil },
+		Compensate: func(context.Context) error { compensated = true; return nil },
+	})
+	s.AddStep(SagaStep{
+		Name:    "create_stripe_payment_intent",
+		Execute: func(context.Context) error { return errors.New("card declined") },
+	})
+
+	require.Error(t, s.Execute(ctx))
+	sagaSpan.End()
+	request.End()
+	assert.True(t, compensated)
+
+	spans := map[string]sdktrace.ReadOnlySpan{}
+	for _, sp := range recorder.Ended() {
+		spans[sp.Name()] = sp
+	}
+	require.Len(t, spans, 4)
+
+	root := spans["POST /api/v1/payments/initiate"]
+	sagaRO := spans["saga.create_escrow"]
+	assert.Equal(t, root.SpanContext().SpanID(), sagaRO.Parent().SpanID())
+	for _, name := range []string{"saga.create_escrow.save_payment", "saga.create_escrow.create_stripe_payment_intent"} {
+		assert.Equal(t, sagaRO.SpanContext().SpanID(), spans[name].Parent().SpanID(), name)
+		assert.Equal(t, root.SpanContext().TraceID(), spans[name].SpanContext().TraceID(), name)
+	}
+
+	var events []string
+	for _, e := range sagaRO.Events() {
+		events = append(events, e.Name)
+	}
+	assert.Contains(t, events, "saga.compensated")
+}
+
+// TestSagaExecute_LogsStripeRequestID verifies a failed Stripe step logs the
+// provider request ID and keeps it reachable on the returned error.
+func TestSagaExecute_LogsStripeRequestID(t *testing.T) {
+	core, logs := observer.New(zap.ErrorLevel)
+	s := NewSaga("create_escrow", zap.New(core))
+	s.AddStep(SagaStep{
+		Name: "create_payment_intent",
+		Execute: func(context.Context) error {
+			return &adapter.ProviderError{Op: "create_payment_intent", RequestID: "req_abc", Err: errors.New("api_error")}
+		},
+	})
+
+	err := s.Execute(context.Background())
+	require.Error(t, err)
+	assert.Equal(t, "req_abc", adapter.ProviderRequestID(err))
+
+	failed := logs.FilterMessage("saga step failed, starting compensation").All()
+	require.Len(t, failed, 1)
+	assert.Equal(t, "req_abc", failed[0].ContextMap()["stripe_request_id"])
+}
+
+// TestSagaExecute_CompensationFailureFiresHandler verifies a failing
+// compensating step is reported to the handler with the saga, step and error.
+func TestSagaExecute_CompensationFailureFiresHandler(t *testing.T) {
+	type report struct {
+		saga, step string
+		err        error
+	}
+	var reports []report
+
+	compErr := errors.New("refund API unavailable")
+	s := NewSaga("release_escrow", zap.NewNop())
+	s.OnCompensationFailure(func(_ context.Context, sagaName, step string, err error) {
+		reports = append(reports, report{sagaName, step, err})
+	})
+	s.AddStep(SagaStep{
+		Name:       "capture_payment",
+		Execute:    func(context.Context) error { return nil },
+		Compensate: func(context.Context) error { return compErr },
+	})
+	s.AddStep(SagaStep{
+		Name:       "update_payment",
+		Execute:    func(context.Context) error { return nil },
+		Compensate: func(context.Context) error { return nil },
+	})
+	s.AddStep(SagaStep{
+		Name:    "publish_event",
+		Execute: func(context.Context) error { return errors.New("broker down") },
+	})
+
+	require.Error(t, s.Execute(context.Background()))
+	require.Len(t, reports, 1, "only the failed compensation is reported")
+	assert.Equal(t, "release_escrow", reports[0].saga)
+	assert.Equal(t, "capture_payment", reports[0].step)
+	assert.ErrorIs(t, reports[0].err, compErr)
+}
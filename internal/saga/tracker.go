@@ -0,0 +1,92 @@
+package saga
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// inflightTracker records running sagas so shutdown can wait for them to finish.
+// Sagas run on a context detached from the caller's cancellation, so stopping the
+// Kafka consumer or an HTTP client disconnecting does not interrupt a saga between
+// its Stripe call and the database write. They are only cancelled once the drain
+// deadline passes.
+type inflightTracker struct {
+	wg sync.WaitGroup
+
+	mu      sync.Mutex
+	nextID  uint64
+	running map[uint64]inflightSaga
+
+	abandonCtx context.Context
+	abandon    context.CancelFunc
+}
+
+type inflightSaga struct {
+	name      string
+	subjectID string
+	startedAt time.Time
+}
+
+func newInflightTracker() *inflightTracker {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &inflightTracker{
+		running:    make(map[uint64]inflightSaga),
+		abandonCtx: ctx,
+		abandon:    cancel,
+	}
+}
+
+// start registers a saga and returns the context it must run on, plus a function
+// to call when it completes.
+func (t *inflightTracker) start(ctx context.Context, name, subjectID string) (context.Context, func()) {
+	t.mu.Lock()
+	id := t.nextID
+	t.nextID++
+	t.running[id] = inflightSaga{name: name, subjectID: subjectID, startedAt: time.Now()}
+	t.wg.Add(1)
+	t.mu.Unlock()
+
+	runCtx, cancel := context.WithCancel(context.WithoutCancel(ctx))
+	stop := context.AfterFunc(t.abandonCtx, cancel)
+
+	return runCtx, func() {
+		stop()
+		cancel()
+		t.mu.Lock()
+		delete(t.running, id)
+		t.mu.Unlock()
+		t.wg.Done()
+	}
+}
+
+// drain waits up to timeout for running sagas to finish. Sagas still running at
+// the deadline are logged and cancelled. It reports whether all sagas completed.
+func (t *inflightTracker) drain(timeout time.Duration, logger *zap.Logger) bool {
+	done := make(chan struct{})
+	go func() {
+		t.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return true
+	case <-time.After(timeout):
+	}
+
+	t.mu.Lock()
+	for _, s := range t.running {
+		logger.Error("abandoning in-flight saga at shutdown deadline",
+			zap.String("saga", s.name),
+			zap.String("subject_id", s.subjectID),
+			zap.Duration("running_for", time.Since(s.startedAt)),
+		)
+	}
+	t.mu.Unlock()
+
+	t.abandon()
+	return false
+}
@@ -0,0 +1,38 @@
+package saga
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+)
+
+func TestInflightTracker_CallerCancellationDoesNotInterruptSaga(t *testing.T) {
+	tr := newInflightTracker()
+	callerCtx, cancel := context.WithCancel(context.Background())
+
+	runCtx, done := tr.start(callerCtx, "release_escrow", "p-1")
+	cancel()
+	assert.NoError(t, runCtx.Err(), "saga context must outlive the caller")
+
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		done()
+	}()
+	assert.True(t, tr.drain(time.Second, zap.NewNop()))
+}
+
+func TestInflightTracker_DrainCancelsSagasAtDeadline(t *testing.T) {
+	tr := newInflightTracker()
+	runCtx, done := tr.start(context.Background(), "create_escrow", "b-1")
+	defer done()
+
+	assert.False(t, tr.drain(10*time.Millisecond, zap.NewNop()))
+	select {
+	case <-runCtx.Done():
+	case <-time.After(time.Second):
+		t.Fatal("saga context was not cancelled after the drain deadline")
+	}
+}
@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_payment_discounts_payment;
+DROP TABLE IF EXISTS payment_discounts;
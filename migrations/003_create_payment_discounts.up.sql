@@ -0,0 +1,18 @@
+-- payment_discounts itemizes the promo and subscription discounts applied to a
+-- payment. payments.amount_cents is the amount charged after these discounts.
+
+CREATE TABLE payment_discounts (
+    id            UUID         PRIMARY KEY DEFAULT uuid_generate_v4(),
+    payment_id    UUID         NOT NULL REFERENCES payments(id) ON DELETE CASCADE,
+    source        VARCHAR(20)  NOT NULL,
+    code          VARCHAR(50),
+    amount_cents  BIGINT       NOT NULL,
+    created_at    TIMESTAMPTZ  NOT NULL DEFAULT NOW(),
+
+    CONSTRAINT chk_payment_discounts_source
+        CHECK (source IN ('promo', 'subscription')),
+    CONSTRAINT chk_payment_discounts_amount_positive
+        CHECK (amount_cents > 0)
+);
+
+CREATE INDEX idx_payment_discounts_payment ON payment_discounts(payment_id);
@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_fee_schedules_lookup;
+DROP TABLE IF EXISTS fee_schedules;
@@ -0,0 +1,17 @@
+-- fee_schedules holds the platform fee percentage per region/currency, effective
+-- from a given timestamp. An empty region is the default for the currency.
+
+CREATE TABLE fee_schedules (
+    id              UUID          PRIMARY KEY DEFAULT uuid_generate_v4(),
+    region          VARCHAR(20)   NOT NULL DEFAULT '',
+    currency        VARCHAR(3)    NOT NULL,
+    fee_percent     NUMERIC(5,2)  NOT NULL,
+    effective_from  TIMESTAMPTZ   NOT NULL,
+    created_at      TIMESTAMPTZ   NOT NULL DEFAULT NOW(),
+    updated_at      TIMESTAMPTZ   NOT NULL DEFAULT NOW(),
+
+    CONSTRAINT chk_fee_schedules_percent_range
+        CHECK (fee_percent >= 0 AND fee_percent <= 100)
+);
+
+CREATE UNIQUE INDEX idx_fee_schedules_lookup ON fee_schedules(region, currency, effective_from);
@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_payments_release_eligible;
+ALTER TABLE payments DROP COLUMN IF EXISTS release_eligible_at;
@@ -0,0 +1,7 @@
+-- release_eligible_at is set when escrow is held with an auto-release window.
+-- The auto-release worker releases held escrows once this time has passed.
+
+ALTER TABLE payments ADD COLUMN release_eligible_at TIMESTAMPTZ;
+
+CREATE INDEX idx_payments_release_eligible ON payments(release_eligible_at)
+    WHERE escrow_status = 'held';
@@ -0,0 +1,14 @@
+-- payment_status_history records every escrow status transition of a payment,
+-- including who or what triggered it, for dispute investigations.
+
+CREATE TABLE payment_status_history (
+    id           UUID          PRIMARY KEY DEFAULT uuid_generate_v4(),
+    payment_id   UUID          NOT NULL REFERENCES payments(id) ON DELETE CASCADE,
+    from_status  VARCHAR(20),
+    to_status    VARCHAR(20)   NOT NULL,
+    reason       TEXT,
+    actor        VARCHAR(100)  NOT NULL,
+    occurred_at  TIMESTAMPTZ   NOT NULL
+);
+
+CREATE INDEX idx_payment_status_history_payment ON payment_status_history(payment_id, occurred_at);
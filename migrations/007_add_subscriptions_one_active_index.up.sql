@@ -0,0 +1,26 @@
+-- subscriptions was previously created only by dev auto-migrate; create it here
+-- so the index below can be applied in every environment.
+CREATE TABLE IF NOT EXISTS subscriptions (
+    id           UUID          PRIMARY KEY,
+    user_id      UUID          NOT NULL,
+    plan         VARCHAR(20)   NOT NULL,
+    price_cents  BIGINT        NOT NULL,
+    started_at   TIMESTAMPTZ   NOT NULL,
+    expires_at   TIMESTAMPTZ   NOT NULL,
+    status       VARCHAR(20)   NOT NULL DEFAULT 'active',
+    auto_renew   BOOLEAN       DEFAULT TRUE,
+    version      BIGINT        NOT NULL DEFAULT 1,
+    created_at   TIMESTAMPTZ   NOT NULL,
+    updated_at   TIMESTAMPTZ   NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_subscriptions_user_id ON subscriptions(user_id);
+
+-- Lapsed subscriptions were never moved out of 'active'; expire them so the
+-- unique index can be built and only genuinely active rows are constrained.
+UPDATE subscriptions SET status = 'expired', updated_at = NOW()
+WHERE status = 'active' AND expires_at <= NOW();
+
+-- At most one active subscription per user, enforced by the database so
+-- concurrent subscribe requests cannot both succeed.
+CREATE UNIQUE INDEX idx_subscriptions_one_active ON subscriptions(user_id) WHERE status = 'active';
@@ -0,0 +1,2 @@
+ALTER TABLE payments DROP COLUMN IF EXISTS credit_applied_cents;
+DROP TABLE IF EXISTS user_credits;
@@ -0,0 +1,13 @@
+-- user_credits holds each user's in-app credit balance, granted by refunds to
+-- credit and spent automatically on new payments.
+
+CREATE TABLE user_credits (
+    user_id        UUID          PRIMARY KEY,
+    currency       VARCHAR(3)    NOT NULL,
+    balance_cents  BIGINT        NOT NULL DEFAULT 0 CHECK (balance_cents >= 0),
+    created_at     TIMESTAMPTZ   NOT NULL,
+    updated_at     TIMESTAMPTZ   NOT NULL
+);
+
+-- credit_applied_cents is the part of amount_cents paid from credit rather than the card.
+ALTER TABLE payments ADD COLUMN credit_applied_cents BIGINT NOT NULL DEFAULT 0;
@@ -0,0 +1,10 @@
+-- runner_accounts mirrors each runner's Stripe Connect account from the
+-- runner service's RunnerAccountLinked events; releases transfer payouts to it.
+
+CREATE TABLE runner_accounts (
+    runner_id          UUID          PRIMARY KEY,
+    stripe_account_id  VARCHAR(255)  NOT NULL,
+    linked_at          TIMESTAMPTZ   NOT NULL,
+    created_at         TIMESTAMPTZ   NOT NULL,
+    updated_at         TIMESTAMPTZ   NOT NULL
+);
@@ -0,0 +1,8 @@
+-- feature_flags holds runtime overrides for feature flags. A row wins over the
+-- FEATURE_FLAGS environment variable and is picked up without a redeploy.
+
+CREATE TABLE feature_flags (
+    name        VARCHAR(64)   PRIMARY KEY,
+    enabled     BOOLEAN       NOT NULL,
+    updated_at  TIMESTAMPTZ   NOT NULL DEFAULT NOW()
+);
@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS payment_callback_attempts;
+DROP TABLE IF EXISTS payment_callbacks;
+ALTER TABLE payments DROP COLUMN IF EXISTS callback_url;
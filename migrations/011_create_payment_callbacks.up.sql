@@ -0,0 +1,39 @@
+-- callback_url lets integrations that cannot consume Kafka receive an HTTP
+-- callback when a payment's escrow is released or refunded.
+
+ALTER TABLE payments ADD COLUMN callback_url TEXT;
+
+-- payment_callbacks queues each callback; the callback worker retries
+-- failed deliveries with backoff until they succeed or run out of attempts.
+
+CREATE TABLE payment_callbacks (
+    id               UUID          PRIMARY KEY,
+    payment_id       UUID          NOT NULL REFERENCES payments(id) ON DELETE CASCADE,
+    url              TEXT          NOT NULL,
+    event_type       VARCHAR(50)   NOT NULL,
+    payload          JSONB         NOT NULL,
+    status           VARCHAR(20)   NOT NULL,
+    attempts         INTEGER       NOT NULL DEFAULT 0,
+    next_attempt_at  TIMESTAMPTZ   NOT NULL,
+    last_error       TEXT,
+    delivered_at     TIMESTAMPTZ,
+    created_at       TIMESTAMPTZ   NOT NULL,
+    updated_at       TIMESTAMPTZ   NOT NULL
+);
+
+CREATE INDEX idx_payment_callbacks_payment ON payment_callbacks(payment_id);
+CREATE INDEX idx_payment_callbacks_due ON payment_callbacks(next_attempt_at)
+    WHERE status = 'pending';
+
+-- payment_callback_attempts records the outcome of every delivery attempt.
+
+CREATE TABLE payment_callback_attempts (
+    id            UUID          PRIMARY KEY,
+    delivery_id   UUID          NOT NULL REFERENCES payment_callbacks(id) ON DELETE CASCADE,
+    number        INTEGER       NOT NULL,
+    status_code   INTEGER       NOT NULL DEFAULT 0,
+    error         TEXT,
+    attempted_at  TIMESTAMPTZ   NOT NULL
+);
+
+CREATE INDEX idx_payment_callback_attempts_delivery ON payment_callback_attempts(delivery_id, number);
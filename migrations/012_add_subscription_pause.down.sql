@@ -0,0 +1,8 @@
+UPDATE subscriptions SET status = 'active', paused_at = NULL, updated_at = NOW()
+WHERE status = 'paused';
+
+DROP INDEX IF EXISTS idx_subscriptions_one_active;
+CREATE UNIQUE INDEX idx_subscriptions_one_active ON subscriptions(user_id) WHERE status = 'active';
+
+ALTER TABLE subscriptions DROP COLUMN IF EXISTS paused_duration_seconds;
+ALTER TABLE subscriptions DROP COLUMN IF EXISTS paused_at;
@@ -0,0 +1,9 @@
+-- Users can pause a subscription instead of cancelling it. paused_at is set
+-- while paused; paused_duration_seconds is the total time spent paused.
+ALTER TABLE subscriptions ADD COLUMN paused_at TIMESTAMPTZ;
+ALTER TABLE subscriptions ADD COLUMN paused_duration_seconds BIGINT NOT NULL DEFAULT 0;
+
+-- A paused subscription is resumed rather than replaced, so it counts against
+-- the one-active limit too.
+DROP INDEX IF EXISTS idx_subscriptions_one_active;
+CREATE UNIQUE INDEX idx_subscriptions_one_active ON subscriptions(user_id) WHERE status IN ('active', 'paused');
@@ -0,0 +1 @@
+ALTER TABLE promos DROP COLUMN IF EXISTS first_booking_only;
@@ -0,0 +1,34 @@
+-- promos and promo_usages were previously created only by dev auto-migrate;
+-- create them here so the column below can be added in every environment.
+CREATE TABLE IF NOT EXISTS promos (
+    id                 UUID          PRIMARY KEY,
+    code               VARCHAR(50)   NOT NULL,
+    discount_type      VARCHAR(20)   NOT NULL,
+    discount_value     BIGINT        NOT NULL,
+    min_amount_cents   BIGINT        DEFAULT 0,
+    max_discount_cents BIGINT        DEFAULT 0,
+    max_uses           BIGINT        DEFAULT 0,
+    current_uses       BIGINT        DEFAULT 0,
+    valid_from         TIMESTAMPTZ   NOT NULL,
+    valid_until        TIMESTAMPTZ   NOT NULL,
+    created_by         UUID          NOT NULL,
+    created_at         TIMESTAMPTZ   NOT NULL,
+    updated_at         TIMESTAMPTZ   NOT NULL
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_promos_code ON promos(code);
+
+CREATE TABLE IF NOT EXISTS promo_usages (
+    id             UUID          PRIMARY KEY,
+    promo_id       UUID          NOT NULL,
+    user_id        UUID          NOT NULL,
+    booking_id     UUID          NOT NULL,
+    discount_cents BIGINT        NOT NULL,
+    used_at        TIMESTAMPTZ   NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_promo_usages_promo_id ON promo_usages(promo_id);
+CREATE INDEX IF NOT EXISTS idx_promo_usages_user_id ON promo_usages(user_id);
+
+-- "New customer" promos are only valid when the user has no prior payment.
+ALTER TABLE promos ADD COLUMN IF NOT EXISTS first_booking_only BOOLEAN NOT NULL DEFAULT FALSE;
@@ -0,0 +1,2 @@
+-- Pending payments whose PaymentIntent needs the customer to complete 3-D Secure.
+ALTER TABLE payments ADD COLUMN awaiting_authentication BOOLEAN NOT NULL DEFAULT FALSE;
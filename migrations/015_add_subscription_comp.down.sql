@@ -0,0 +1,3 @@
+ALTER TABLE subscriptions DROP COLUMN IF EXISTS granted_by;
+ALTER TABLE subscriptions DROP COLUMN IF EXISTS comp_reason;
+ALTER TABLE subscriptions DROP COLUMN IF EXISTS comped;
@@ -0,0 +1,5 @@
+-- Admins can comp a subscription. Comped subscriptions are free, never
+-- renewed, and record who granted them and why.
+ALTER TABLE subscriptions ADD COLUMN comped BOOLEAN NOT NULL DEFAULT FALSE;
+ALTER TABLE subscriptions ADD COLUMN comp_reason TEXT;
+ALTER TABLE subscriptions ADD COLUMN granted_by UUID;
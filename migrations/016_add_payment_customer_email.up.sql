@@ -0,0 +1,3 @@
+-- The payer's email, kept so support can reach them about refunds. Only
+-- admin endpoints expose it.
+ALTER TABLE payments ADD COLUMN customer_email VARCHAR(254);
@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_subscriptions_idempotency_key;
+ALTER TABLE subscriptions DROP COLUMN IF EXISTS idempotency_key;
@@ -0,0 +1,4 @@
+-- Signups may send an Idempotency-Key; a retried signup with the same key
+-- returns the subscription the first one created.
+ALTER TABLE subscriptions ADD COLUMN idempotency_key VARCHAR(255);
+CREATE UNIQUE INDEX idx_subscriptions_idempotency_key ON subscriptions (user_id, idempotency_key);
@@ -0,0 +1,3 @@
+-- The Connect transfer that paid out the runner on release, kept so a
+-- clawback can reverse it.
+ALTER TABLE payments ADD COLUMN runner_transfer_id VARCHAR(255);
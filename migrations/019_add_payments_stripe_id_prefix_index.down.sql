@@ -0,0 +1 @@
+DROP INDEX IF EXISTS idx_payments_stripe_payment_id_prefix;
@@ -0,0 +1,3 @@
+-- Admin search matches Stripe payment IDs by prefix (LIKE 'pi_xxx%');
+-- text_pattern_ops lets that use an index regardless of collation.
+CREATE INDEX idx_payments_stripe_payment_id_prefix ON payments (stripe_payment_id text_pattern_ops);
@@ -0,0 +1,3 @@
+-- The renewal batch scans active, auto-renewing subscriptions by expiry.
+CREATE INDEX idx_subscriptions_renewal_due ON subscriptions (expires_at)
+    WHERE status = 'active' AND auto_renew AND NOT comped;
@@ -0,0 +1,10 @@
+-- booking_totals mirrors each booking's price from the booking service's
+-- BookingCreated events; initiated payments are checked against it.
+
+CREATE TABLE booking_totals (
+    booking_id    UUID          PRIMARY KEY,
+    owner_id      UUID          NOT NULL,
+    amount_cents  BIGINT        NOT NULL,
+    currency      VARCHAR(3)    NOT NULL,
+    created_at    TIMESTAMPTZ   NOT NULL
+);
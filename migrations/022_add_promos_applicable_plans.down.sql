@@ -0,0 +1 @@
+ALTER TABLE promos DROP COLUMN IF EXISTS applicable_plans;
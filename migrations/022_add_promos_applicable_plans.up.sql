@@ -0,0 +1,2 @@
+-- Promos can be limited to subscribers of specific plans; NULL means anyone.
+ALTER TABLE promos ADD COLUMN IF NOT EXISTS applicable_plans JSONB;
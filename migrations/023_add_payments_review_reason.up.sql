@@ -0,0 +1,2 @@
+-- Set when a failed saga leaves a payment needing manual reconciliation.
+ALTER TABLE payments ADD COLUMN review_reason TEXT;
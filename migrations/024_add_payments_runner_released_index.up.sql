@@ -0,0 +1,3 @@
+-- Runner payout summaries total a runner's released payments by release time.
+CREATE INDEX idx_payments_runner_released ON payments(runner_id, escrow_released_at)
+    WHERE escrow_status = 'released';
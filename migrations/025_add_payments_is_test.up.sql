@@ -0,0 +1,3 @@
+-- is_test marks payments made against Stripe test keys or flagged as tests
+-- outside production. Stats and admin lists leave them out by default.
+ALTER TABLE payments ADD COLUMN is_test BOOLEAN NOT NULL DEFAULT FALSE;
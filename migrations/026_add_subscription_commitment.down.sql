@@ -0,0 +1,2 @@
+ALTER TABLE subscriptions DROP COLUMN IF EXISTS cancelled_early;
+ALTER TABLE subscriptions DROP COLUMN IF EXISTS commitment_ends_at;
@@ -0,0 +1,4 @@
+-- commitment_ends_at is set for plans taken with a minimum commitment;
+-- cancelling before it forfeits the refund and sets cancelled_early.
+ALTER TABLE subscriptions ADD COLUMN commitment_ends_at TIMESTAMPTZ;
+ALTER TABLE subscriptions ADD COLUMN cancelled_early BOOLEAN NOT NULL DEFAULT FALSE;
@@ -0,0 +1,20 @@
+-- refund_requests holds refunds at or above the approval threshold until an
+-- admin other than the requester approves them.
+
+CREATE TABLE refund_requests (
+    id            UUID          PRIMARY KEY,
+    payment_id    UUID          NOT NULL REFERENCES payments(id) ON DELETE CASCADE,
+    amount_cents  BIGINT        NOT NULL,
+    reason        TEXT          NOT NULL,
+    method        VARCHAR(20)   NOT NULL,
+    status        VARCHAR(20)   NOT NULL,
+    requested_by  UUID          NOT NULL,
+    approved_by   UUID,
+    approved_at   TIMESTAMPTZ,
+    created_at    TIMESTAMPTZ   NOT NULL,
+    updated_at    TIMESTAMPTZ   NOT NULL
+);
+
+-- At most one pending request per payment, so a refund is not queued twice.
+CREATE UNIQUE INDEX idx_refund_requests_one_pending ON refund_requests(payment_id)
+    WHERE status = 'pending';
@@ -0,0 +1,16 @@
+-- payment_amount_adjustments records changes to held payments' amounts after
+-- their booking's price changed. Each booking event applies at most once.
+
+CREATE TABLE payment_amount_adjustments (
+    id                          UUID          PRIMARY KEY,
+    payment_id                  UUID          NOT NULL REFERENCES payments(id) ON DELETE CASCADE,
+    event_id                    VARCHAR(255)  NOT NULL,
+    previous_amount_cents       BIGINT        NOT NULL,
+    amount_cents                BIGINT        NOT NULL,
+    previous_payment_intent_id  VARCHAR(255)  NOT NULL,
+    payment_intent_id           VARCHAR(255)  NOT NULL,
+    adjusted_at                 TIMESTAMPTZ   NOT NULL
+);
+
+CREATE UNIQUE INDEX idx_payment_amount_adjustments_event ON payment_amount_adjustments(event_id);
+CREATE INDEX idx_payment_amount_adjustments_payment_id ON payment_amount_adjustments(payment_id);
@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS idx_payment_status_history_initiated_by_type;
+
+ALTER TABLE payment_status_history
+    DROP COLUMN IF EXISTS initiated_by_id,
+    DROP COLUMN IF EXISTS initiated_by_type;
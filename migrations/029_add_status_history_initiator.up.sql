@@ -0,0 +1,14 @@
+-- Split the actor of each status change into the initiator's type and ID, so
+-- reports can tell admin refunds from refunds triggered by owners or events.
+
+ALTER TABLE payment_status_history
+    ADD COLUMN initiated_by_type VARCHAR(20) NOT NULL DEFAULT 'system',
+    ADD COLUMN initiated_by_id   VARCHAR(100) NOT NULL DEFAULT '';
+
+UPDATE payment_status_history
+SET initiated_by_type = split_part(actor, ':', 1),
+    initiated_by_id   = CASE WHEN position(':' IN actor) > 0
+                             THEN substring(actor FROM position(':' IN actor) + 1)
+                             ELSE '' END;
+
+CREATE INDEX idx_payment_status_history_initiated_by_type ON payment_status_history(initiated_by_type);
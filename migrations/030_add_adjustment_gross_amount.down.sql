@@ -0,0 +1 @@
+ALTER TABLE payment_amount_adjustments DROP COLUMN IF EXISTS gross_amount_cents;
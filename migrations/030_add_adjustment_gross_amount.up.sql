@@ -0,0 +1,6 @@
+-- Record the booking total each amount adjustment was derived from, so
+-- receipts show the adjusted gross rather than rebuilding it from discounts.
+-- Adjustments made before this column existed keep 0.
+
+ALTER TABLE payment_amount_adjustments
+    ADD COLUMN gross_amount_cents BIGINT NOT NULL DEFAULT 0;
@@ -0,0 +1,15 @@
+-- fee_region is the region whose fee schedule priced a payment, so a fee
+-- recompute looks up the same schedule. fee_overridden marks payments whose
+-- fee an admin set by hand, which a recompute must not replace; existing
+-- overrides are found in the status history. Payments created before this
+-- migration keep an empty region and are recomputed at the currency-wide fee.
+
+ALTER TABLE payments
+    ADD COLUMN fee_region TEXT NOT NULL DEFAULT '',
+    ADD COLUMN fee_overridden BOOLEAN NOT NULL DEFAULT FALSE;
+
+UPDATE payments SET fee_overridden = TRUE
+WHERE id IN (
+    SELECT payment_id FROM payment_status_history
+    WHERE reason LIKE 'platform fee overridden %'
+);
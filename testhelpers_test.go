@@ -9,8 +9,10 @@ import (
 	"time"
 
 	"github.com/Kilat-Pet-Delivery/lib-common/kafka"
+	"github.com/Kilat-Pet-Delivery/lib-proto/events"
 	"github.com/Kilat-Pet-Delivery/service-payment/internal/adapter"
 	"github.com/Kilat-Pet-Delivery/service-payment/internal/application"
+	"github.com/Kilat-Pet-Delivery/service-payment/internal/domain/payment"
 	paymentEvents "github.com/Kilat-Pet-Delivery/service-payment/internal/events"
 	"github.com/Kilat-Pet-Delivery/service-payment/internal/repository"
 	"github.com/Kilat-Pet-Delivery/service-payment/internal/saga"
@@ -38,6 +40,7 @@ type testInfra struct {
 type paymentStack struct {
 	Service         *application.PaymentService
 	Consumer        *paymentEvents.BookingEventConsumer
+	Stripe          *adapter.MockStripeAdapter
 	CleanupProducer func()
 }
 
@@ -89,7 +92,12 @@ func setupContainers(t *testing.T) *testInfra {
 
 	// Enable uuid-ossp extension and auto-migrate.
 	require.NoError(t, db.Exec(`CREATE EXTENSION IF NOT EXISTS "uuid-ossp"`).Error)
-	require.NoError(t, db.AutoMigrate(&repository.PaymentModel{}))
+	require.NoError(t, db.AutoMigrate(
+		&repository.PaymentModel{},
+		&repository.PaymentDiscountModel{},
+		&repository.PaymentStatusHistoryModel{},
+		&repository.PaymentAmountAdjustmentModel{},
+	))
 
 	// Start Kafka container using confluent-local (supports KRaft natively).
 	kafkaContainer, err := kafkamodule.Run(ctx, "confluentinc/confluent-local:7.5.0")
@@ -125,23 +133,39 @@ func setupPaymentStack(t *testing.T, db *gorm.DB, brokers []string) *paymentStac
 	paymentRepo := repository.NewPaymentRepository(db)
 	mockStripe := adapter.NewMockStripeAdapter(logger)
 	producer := kafka.NewProducer(brokers, logger)
-	sagaSvc := saga.NewPaymentSagaService(paymentRepo, mockStripe, producer, 15.0, logger)
-	paymentSvc := application.NewPaymentService(paymentRepo, sagaSvc, logger)
+	sagaSvc := saga.NewPaymentSagaService(paymentRepo, mockStripe, producer, nil, nil, nil, nil, nil, 15.0, payment.PayoutFloors{}, 0, 30*24*time.Hour, logger)
+	discountEngine := application.NewDiscountEngine(application.DiscountPolicy{Stacking: application.StackingBestOf})
+	paymentSvc := application.NewPaymentService(
+		paymentRepo,
+		repository.NewGormPromoRepository(db),
+		repository.NewGormSubscriptionRepository(db),
+		nil,
+		sagaSvc,
+		discountEngine,
+		application.NewCurrencyAllowlist([]string{"MYR"}),
+		producer,
+		logger,
+	)
 
 	groupID := fmt.Sprintf("test-payment-%s", uuid.New().String()[:8])
-	consumer := paymentEvents.NewBookingEventConsumer(brokers, groupID, paymentSvc, logger)
+	consumer := paymentEvents.NewBookingEventConsumer(brokers, groupID, []string{events.TopicBookingEvents}, paymentSvc, nil, 1, kafkago.FirstOffset, logger)
 
 	return &paymentStack{
 		Service:         paymentSvc,
 		Consumer:        consumer,
+		Stripe:          mockStripe,
 		CleanupProducer: func() { _ = producer.Close() },
 	}
 }
 
-// seedPaymentInHeldState inserts a payment in "held" state for testing.
-func seedPaymentInHeldState(t *testing.T, db *gorm.DB, bookingID, ownerID uuid.UUID) uuid.UUID {
+// seedPaymentInHeldState inserts a payment in "held" state for testing, with
+// its card authorization created on stripe.
+func seedPaymentInHeldState(t *testing.T, db *gorm.DB, stripe *adapter.MockStripeAdapter, bookingID, ownerID uuid.UUID) uuid.UUID {
 	t.Helper()
 	paymentID := uuid.New()
+	intent, err := stripe.CreatePaymentIntent(context.Background(), 150000, "MYR", "owner@example.com", nil, paymentID.String())
+	require.NoError(t, err)
+	intentID := intent.ID
 	now := time.Now().UTC()
 	model := repository.PaymentModel{
 		ID:                paymentID,
@@ -152,7 +176,7 @@ func seedPaymentInHeldState(t *testing.T, db *gorm.DB, bookingID, ownerID uuid.U
 		PlatformFeeCents:  22500,
 		RunnerPayoutCents: 127500,
 		Currency:          "MYR",
-		StripePaymentID:   fmt.Sprintf("pi_mock_%s", uuid.New().String()[:8]),
+		StripePaymentID:   intentID,
 		EscrowHeldAt:      &now,
 		Version:           2,
 		CreatedAt:         now,
@@ -198,6 +222,17 @@ func publishTestEvent(t *testing.T, brokers []string, topic, source, eventType s
 	require.NoError(t, err, "failed to publish event")
 }
 
+// publishCloudEvent publishes an already built CloudEvent to Kafka, e.g. to
+// redeliver one with the same ID.
+func publishCloudEvent(t *testing.T, brokers []string, topic string, ce kafka.CloudEvent) {
+	t.Helper()
+	logger, _ := zap.NewDevelopment()
+	producer := kafka.NewProducer(brokers, logger)
+	defer func() { _ = producer.Close() }()
+
+	require.NoError(t, producer.PublishEvent(context.Background(), topic, ce), "failed to publish event")
+}
+
 // waitForDBStatus polls the payments table until the escrow_status matches.
 func waitForDBStatus(t *testing.T, db *gorm.DB, bookingID uuid.UUID, expectedStatus string, timeout time.Duration) repository.PaymentModel {
 	t.Helper()
@@ -252,6 +287,43 @@ func consumeOneEvent(t *testing.T, brokers []string, topic, expectedType string,
 	}
 }
 
+// countEvents reads a Kafka topic from the beginning for the given window and
+// counts the events of the expected type.
+func countEvents(t *testing.T, brokers []string, topic, expectedType string, window time.Duration) int {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), window)
+	defer cancel()
+
+	groupID := fmt.Sprintf("test-count-%s", uuid.New().String()[:8])
+	reader := kafkago.NewReader(kafkago.ReaderConfig{
+		Brokers:     brokers,
+		GroupID:     groupID,
+		Topic:       topic,
+		MinBytes:    1,
+		MaxBytes:    10e6,
+		StartOffset: kafkago.FirstOffset,
+	})
+	defer func() { _ = reader.Close() }()
+
+	count := 0
+	for {
+		msg, err := reader.ReadMessage(ctx)
+		if err != nil {
+			if ctx.Err() != nil {
+				return count
+			}
+			continue
+		}
+		ce, err := kafka.ParseCloudEvent(msg.Value)
+		if err != nil {
+			continue
+		}
+		if ce.Type == expectedType {
+			count++
+		}
+	}
+}
+
 // createTopics pre-creates Kafka topics so producers don't fail with "Unknown Topic".
 func createTopics(t *testing.T, brokers []string, topics ...string) {
 	t.Helper()